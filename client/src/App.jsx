@@ -1,8 +1,11 @@
-import { useCallback, useState } from 'react';
+import { useCallback, useEffect, useMemo, useState } from 'react';
 import { useGridWebSocket } from './hooks/useGridWebSocket';
 import { VirtualGrid } from './components/VirtualGrid';
+import { ChatPanel } from './components/ChatPanel';
+import { AnnouncementBanner } from './components/AnnouncementBanner';
+import { TeamPanel } from './components/TeamPanel';
 
-// 7 predefined colors matching backend validation
+// 7 default colors matching backend validation (replaced by the server palette when received)
 const COLORS = [
   { hex: '#FF0000', name: 'Red' },
   { hex: '#FF8000', name: 'Orange' },
@@ -14,9 +17,33 @@ const COLORS = [
 ];
 
 function App() {
-  const { activeCells, gridSize, isConnected, connectedClients, toggleCell, isCellActive } = useGridWebSocket();
+  const { activeCells, gridWidth, gridHeight, palette, anyColor, isConnected, connectedClients, onlineUsers, me, cursors, chatMessages, frozen, announcement, dismissAnnouncement, lockedRegions, teamScores, team, joinTeam, undo, toggleCell, sendCursor, sendChat, isCellActive } = useGridWebSocket();
   const [selectedColor, setSelectedColor] = useState(COLORS[0].hex);
 
+  // Use the server palette if it has been received
+  const colors = useMemo(() => (
+    palette ? palette.map(hex => COLORS.find(c => c.hex === hex) || { hex, name: hex }) : COLORS
+  ), [palette]);
+
+  // Keep the selection valid when the palette changes (any color is valid if the server allows it)
+  useEffect(() => {
+    if (!anyColor && !colors.some(c => c.hex === selectedColor)) {
+      setSelectedColor(colors[0].hex);
+    }
+  }, [colors, selectedColor, anyColor]);
+
+  // Ctrl+Z (Cmd+Z on macOS) reverts the last placement, unless typing
+  useEffect(() => {
+    const handleKeyDown = (e) => {
+      if ((e.ctrlKey || e.metaKey) && e.key === 'z' && !['INPUT', 'TEXTAREA'].includes(e.target.tagName)) {
+        e.preventDefault();
+        undo();
+      }
+    };
+    window.addEventListener('keydown', handleKeyDown);
+    return () => window.removeEventListener('keydown', handleKeyDown);
+  }, [undo]);
+
   // Handle cell click from grid - pass selected color
   const handleCellClick = useCallback((x, y) => {
     toggleCell(x, y, selectedColor);
@@ -29,11 +56,16 @@ function App() {
         <div>
           <h1 className="text-2xl font-bold text-white drop-shadow-md">Million Grids</h1>
           <p className="text-gray-300 text-sm drop-shadow-md">
-            {gridSize.toLocaleString()}×{gridSize.toLocaleString()} • {isConnected ? 'Connected' : 'Disconnected'}
+            {gridWidth.toLocaleString()}×{gridHeight.toLocaleString()} • {isConnected ? 'Connected' : 'Disconnected'}
           </p>
           {isConnected && connectedClients > 0 && (
-            <p className="text-gray-300 text-sm drop-shadow-md">
-              {connectedClients} {connectedClients === 1 ? 'user' : 'users'} online
+            <p className="text-gray-300 text-sm drop-shadow-md" title={[...onlineUsers.values()].join(', ')}>
+              {connectedClients} {connectedClients === 1 ? 'user' : 'users'} online{me && ` • you are ${me.name}`}
+            </p>
+          )}
+          {frozen && (
+            <p className="text-amber-400 text-sm drop-shadow-md">
+              Canvas frozen{frozen.reason && `: ${frozen.reason}`}
             </p>
           )}
         </div>
@@ -47,16 +79,27 @@ function App() {
       {/* Grid */}
       <div className="flex-1 w-full h-full">
         <VirtualGrid
-          gridSize={gridSize}
+          gridWidth={gridWidth}
+          gridHeight={gridHeight}
           activeCells={activeCells}
           isCellActive={isCellActive}
           onCellClick={handleCellClick}
+          cursors={cursors}
+          lockedRegions={lockedRegions}
+          onCursorMove={sendCursor}
         />
       </div>
 
+      {/* Chat */}
+      <AnnouncementBanner announcement={announcement} onDismiss={dismissAnnouncement} />
+
+      <TeamPanel scores={teamScores} team={team} onJoin={joinTeam} disabled={!isConnected} />
+
+      <ChatPanel messages={chatMessages} onSend={sendChat} disabled={!isConnected} />
+
       {/* Color Picker */}
       <div className="absolute bottom-14 left-4 z-10 flex flex-col gap-1 bg-black/70 p-1.5 rounded-lg backdrop-blur-sm">
-        {COLORS.map((color) => (
+        {colors.map((color) => (
           <button
             key={color.hex}
             onClick={() => setSelectedColor(color.hex)}
@@ -67,11 +110,20 @@ function App() {
             title={color.name}
           />
         ))}
+        {anyColor && (
+          <input
+            type="color"
+            value={selectedColor.toLowerCase()}
+            onChange={(e) => setSelectedColor(e.target.value.toUpperCase())}
+            className="w-5 h-5 p-0 border-0 bg-transparent cursor-pointer"
+            title="Custom color"
+          />
+        )}
       </div>
 
       {/* Instructions Overlay */}
       <div className="absolute bottom-4 left-4 z-10 pointer-events-none text-gray-400 text-xs bg-black/50 p-2 rounded backdrop-blur-sm">
-        <p><strong>Click</strong> to toggle • <strong>Scroll</strong> zoom • <strong>Drag</strong> pan</p>
+        <p><strong>Click</strong> to toggle • <strong>Scroll</strong> zoom • <strong>Drag</strong> pan • <strong>Ctrl+Z</strong> undo</p>
       </div>
     </div>
   );
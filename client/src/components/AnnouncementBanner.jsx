@@ -0,0 +1,45 @@
+import { useState, useEffect } from 'react';
+
+// Banner colors by severity
+const SEVERITY_STYLES = {
+  info: 'bg-blue-600/90',
+  warning: 'bg-amber-600/90',
+  critical: 'bg-red-700/90',
+};
+
+/**
+ * AnnouncementBanner - Admin announcement shown over the canvas, with a
+ * countdown to the announced event if it has one
+ */
+export function AnnouncementBanner({ announcement, onDismiss }) {
+  const [now, setNow] = useState(Date.now());
+
+  const deadline = announcement?.countdown
+    ? new Date(announcement.at).getTime() + announcement.countdown * 1000
+    : null;
+
+  // Tick once a second while counting down
+  useEffect(() => {
+    if (!deadline) return;
+    const interval = setInterval(() => setNow(Date.now()), 1000);
+    return () => clearInterval(interval);
+  }, [deadline]);
+
+  if (!announcement) return null;
+
+  let remaining = null;
+  if (deadline) {
+    const seconds = Math.max(0, Math.ceil((deadline - now) / 1000));
+    remaining = `${Math.floor(seconds / 60)}:${String(seconds % 60).padStart(2, '0')}`;
+  }
+
+  return (
+    <div className={`absolute top-4 left-1/2 -translate-x-1/2 z-20 max-w-xl flex items-center gap-3 px-4 py-2 rounded-lg shadow-lg text-white text-sm ${SEVERITY_STYLES[announcement.severity] || SEVERITY_STYLES.info}`}>
+      <span>{announcement.text}</span>
+      {remaining && <span className="font-mono font-bold">{remaining}</span>}
+      <button onClick={onDismiss} className="ml-2 text-white/80 hover:text-white" aria-label="Dismiss">
+        ×
+      </button>
+    </div>
+  );
+}
@@ -0,0 +1,50 @@
+import { useState, useRef, useEffect } from 'react';
+
+// Longest message the server accepts by default
+const MAX_LENGTH = 200;
+
+/**
+ * ChatPanel - Canvas chat with the latest messages and an input box
+ */
+export function ChatPanel({ messages, onSend, disabled }) {
+  const [text, setText] = useState('');
+  const listRef = useRef(null);
+
+  // Keep the newest message in view
+  useEffect(() => {
+    if (listRef.current) {
+      listRef.current.scrollTop = listRef.current.scrollHeight;
+    }
+  }, [messages]);
+
+  const handleSubmit = (e) => {
+    e.preventDefault();
+    if (text.trim()) {
+      onSend(text);
+      setText('');
+    }
+  };
+
+  return (
+    <div className="absolute bottom-28 right-4 z-10 w-72 bg-black/70 rounded-lg backdrop-blur-sm text-sm text-white flex flex-col">
+      <div ref={listRef} className="max-h-48 overflow-y-auto p-2 space-y-1">
+        {messages.map((msg, i) => (
+          <p key={i} className="break-words">
+            <span className="font-semibold text-gray-300">{msg.name}:</span> {msg.text}
+          </p>
+        ))}
+      </div>
+      <form onSubmit={handleSubmit} className="border-t border-gray-700 p-1.5">
+        <input
+          type="text"
+          value={text}
+          maxLength={MAX_LENGTH}
+          disabled={disabled}
+          onChange={(e) => setText(e.target.value)}
+          placeholder={disabled ? 'Disconnected' : 'Say something…'}
+          className="w-full bg-transparent outline-none px-1 placeholder-gray-500"
+        />
+      </form>
+    </div>
+  );
+}
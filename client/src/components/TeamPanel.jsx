@@ -0,0 +1,27 @@
+/**
+ * TeamPanel - Cells held by each team, most first. Clicking a team joins it,
+ * clicking our own team leaves it.
+ */
+export function TeamPanel({ scores, team, onJoin, disabled }) {
+  if (!scores.length) return null;
+
+  return (
+    <div className="absolute top-4 right-4 z-10 w-48 bg-black/70 rounded-lg backdrop-blur-sm text-sm text-white p-2 space-y-1">
+      <p className="text-gray-400 text-xs">Teams</p>
+      {scores.map((score) => (
+        <button
+          key={score.team}
+          onClick={() => onJoin(score.team === team ? '' : score.team)}
+          disabled={disabled}
+          className={`w-full flex justify-between px-1.5 py-0.5 rounded hover:bg-white/10 disabled:opacity-50 ${
+            score.team === team ? 'bg-white/20 font-semibold' : ''
+          }`}
+          title={score.team === team ? 'Leave team' : 'Join team'}
+        >
+          <span>{score.team}</span>
+          <span className="font-mono">{score.pixels.toLocaleString()}</span>
+        </button>
+      ))}
+    </div>
+  );
+}
@@ -6,9 +6,9 @@ const DEFAULT_CELL_SIZE = 20;
 
 /**
  * VirtualGrid - Viewport-based grid rendering
- * Only renders visible cells for performance with 1000x1000 grid
+ * Only renders visible cells for performance with large grids
  */
-export function VirtualGrid({ gridSize, activeCells, isCellActive, onCellClick }) {
+export function VirtualGrid({ gridWidth, gridHeight, activeCells, isCellActive, onCellClick, cursors, lockedRegions, onCursorMove }) {
   const containerRef = useRef(null);
   const canvasRef = useRef(null);
   
@@ -32,8 +32,8 @@ export function VirtualGrid({ gridSize, activeCells, isCellActive, onCellClick }
   useEffect(() => {
     if (!initialized && containerSize.width > 0 && containerSize.height > 0) {
       // Calculate total grid size in pixels
-      const totalGridWidth = gridSize * cellSize;
-      const totalGridHeight = gridSize * cellSize;
+      const totalGridWidth = gridWidth * cellSize;
+      const totalGridHeight = gridHeight * cellSize;
       
       // Center position
       const centerX = (containerSize.width - totalGridWidth) / 2;
@@ -42,17 +42,17 @@ export function VirtualGrid({ gridSize, activeCells, isCellActive, onCellClick }
       setOffset({ x: centerX, y: centerY });
       setInitialized(true);
     }
-  }, [containerSize, gridSize, cellSize, initialized]);
+  }, [containerSize, gridWidth, gridHeight, cellSize, initialized]);
 
   // Calculate visible cell range based on viewport
   const visibleRange = useMemo(() => {
     const startX = Math.max(0, Math.floor(-offset.x / cellSize));
     const startY = Math.max(0, Math.floor(-offset.y / cellSize));
-    const endX = Math.min(gridSize, Math.ceil((containerSize.width - offset.x) / cellSize));
-    const endY = Math.min(gridSize, Math.ceil((containerSize.height - offset.y) / cellSize));
+    const endX = Math.min(gridWidth, Math.ceil((containerSize.width - offset.x) / cellSize));
+    const endY = Math.min(gridHeight, Math.ceil((containerSize.height - offset.y) / cellSize));
     
     return { startX, startY, endX, endY };
-  }, [offset, cellSize, containerSize, gridSize]);
+  }, [offset, cellSize, containerSize, gridWidth, gridHeight]);
 
   // Track container size
   useEffect(() => {
@@ -136,14 +136,34 @@ export function VirtualGrid({ gridSize, activeCells, isCellActive, onCellClick }
       }
     });
 
+    // Shade and outline the regions only moderators may paint in
+    ctx.fillStyle = 'rgba(255, 255, 255, 0.08)';
+    ctx.strokeStyle = '#F5A524';
+    ctx.lineWidth = 1;
+    lockedRegions?.forEach(({ x1, y1, x2, y2 }) => {
+      const screenX = x1 * cellSize + offset.x;
+      const screenY = y1 * cellSize + offset.y;
+      ctx.fillRect(screenX, screenY, (x2 - x1) * cellSize, (y2 - y1) * cellSize);
+      ctx.strokeRect(screenX, screenY, (x2 - x1) * cellSize, (y2 - y1) * cellSize);
+    });
+
+    // Outline the cells under other users' pointers
+    ctx.strokeStyle = '#FFFFFF';
+    ctx.lineWidth = 2;
+    cursors?.forEach(({ x, y }) => {
+      if (x >= startX && x < endX && y >= startY && y < endY) {
+        ctx.strokeRect(x * cellSize + offset.x, y * cellSize + offset.y, cellSize, cellSize);
+      }
+    });
+
     // Draw border around visible grid area
     ctx.strokeStyle = '#4a4a6a';
     ctx.lineWidth = 2;
-    const gridScreenWidth = gridSize * cellSize;
-    const gridScreenHeight = gridSize * cellSize;
+    const gridScreenWidth = gridWidth * cellSize;
+    const gridScreenHeight = gridHeight * cellSize;
     ctx.strokeRect(offset.x, offset.y, gridScreenWidth, gridScreenHeight);
 
-  }, [activeCells, cellSize, offset, containerSize, visibleRange, gridSize]);
+  }, [activeCells, cursors, lockedRegions, cellSize, offset, containerSize, visibleRange, gridWidth, gridHeight]);
 
   // Handle mouse wheel for zoom
   const handleWheel = useCallback((e) => {
@@ -190,6 +210,18 @@ export function VirtualGrid({ gridSize, activeCells, isCellActive, onCellClick }
     });
   }, [isDragging]);
 
+  // Report the cell under the pointer (the hook throttles what is sent)
+  const handlePointerMove = useCallback((e) => {
+    if (!onCursorMove || isDragging) return;
+
+    const rect = containerRef.current.getBoundingClientRect();
+    const cellX = Math.floor((e.clientX - rect.left - offset.x) / cellSize);
+    const cellY = Math.floor((e.clientY - rect.top - offset.y) / cellSize);
+    if (cellX >= 0 && cellX < gridWidth && cellY >= 0 && cellY < gridHeight) {
+      onCursorMove(cellX, cellY);
+    }
+  }, [onCursorMove, isDragging, offset, cellSize, gridWidth, gridHeight]);
+
   // Handle mouse up
   const handleMouseUp = useCallback((e) => {
     const wasDragging = isDragging;
@@ -213,11 +245,11 @@ export function VirtualGrid({ gridSize, activeCells, isCellActive, onCellClick }
       
       console.log('Click at cell:', cellX, cellY);
       
-      if (cellX >= 0 && cellX < gridSize && cellY >= 0 && cellY < gridSize) {
+      if (cellX >= 0 && cellX < gridWidth && cellY >= 0 && cellY < gridHeight) {
         onCellClick(cellX, cellY);
       }
     }
-  }, [isDragging, cellSize, gridSize, onCellClick]);
+  }, [isDragging, cellSize, gridWidth, gridHeight, onCellClick]);
 
   // Handle touch start for dragging (mobile) and pinch-to-zoom
   const handleTouchStart = useCallback((e) => {
@@ -328,7 +360,7 @@ export function VirtualGrid({ gridSize, activeCells, isCellActive, onCellClick }
           
           console.log('Touch tap at cell:', cellX, cellY);
           
-          if (cellX >= 0 && cellX < gridSize && cellY >= 0 && cellY < gridSize) {
+          if (cellX >= 0 && cellX < gridWidth && cellY >= 0 && cellY < gridHeight) {
             onCellClick(cellX, cellY);
           }
         }
@@ -336,7 +368,7 @@ export function VirtualGrid({ gridSize, activeCells, isCellActive, onCellClick }
     }
     isDraggingRef.current = false;
     setIsDragging(false);
-  }, [cellSize, gridSize, onCellClick]);
+  }, [cellSize, gridWidth, gridHeight, onCellClick]);
 
   // Global mouse and touch listeners for dragging
   useEffect(() => {
@@ -382,6 +414,7 @@ export function VirtualGrid({ gridSize, activeCells, isCellActive, onCellClick }
         className="w-full h-full bg-gray-900 cursor-crosshair overflow-hidden"
         style={{ touchAction: 'none' }}
         onMouseDown={handleMouseDown}
+        onMouseMove={handlePointerMove}
         onTouchStart={handleTouchStart}
         onTouchEnd={handleTouchEnd}
       >
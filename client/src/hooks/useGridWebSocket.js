@@ -1,29 +1,98 @@
 import { useState, useEffect, useRef, useCallback } from 'react';
 
-const WS_URL = import.meta.env.PROD 
-  ? `ws://${window.location.host}/ws` 
-  : `ws://${window.location.hostname}:8080/ws`;
+// Use wss:// when the page is served over HTTPS
+const WS_SCHEME = window.location.protocol === 'https:' ? 'wss' : 'ws';
+
+const WS_BASE_URL = import.meta.env.PROD 
+  ? `${WS_SCHEME}://${window.location.host}/ws` 
+  : `${WS_SCHEME}://${window.location.hostname}:8080/ws`;
+
+// Canvas to join, taken from the page URL (?canvas=art); the server picks its default when absent
+const CANVAS = new URLSearchParams(window.location.search).get('canvas');
+
+// Minimum delay between cursor positions sent, below the server's per-client cap
+const CURSOR_INTERVAL_MS = 100;
+
+// Cursors not moved for this long are hidden
+const CURSOR_TIMEOUT_MS = 10000;
+
+// Chat messages kept for display
+const MAX_CHAT_MESSAGES = 100;
+
+const WS_URL = CANVAS ? `${WS_BASE_URL}?canvas=${encodeURIComponent(CANVAS)}` : WS_BASE_URL;
+
+/**
+ * Apply cell updates ({x, y, a: 0|1, color}) to a Map of "x,y" -> color in place
+ */
+function applyCellUpdates(cellMap, cells) {
+  for (const cell of cells) {
+    const key = `${cell.x},${cell.y}`;
+    if (cell.a === 1) {
+      cellMap.set(key, cell.color || '#FFFFFF');
+    } else {
+      cellMap.delete(key);
+    }
+  }
+}
 
 /**
  * Custom hook to manage WebSocket connection for the grid
  * Uses sparse format - only tracks active cells with their colors
- * @returns {Object} { activeCells, gridSize, isConnected, toggleCell }
+ * @returns {Object} { activeCells, gridWidth, gridHeight, palette, anyColor, isConnected, onlineUsers, me, cursors, chatMessages, toggleCell, sendCursor, sendChat }
  */
 export function useGridWebSocket() {
   // Active cells stored as a Map of "x,y" -> color for O(1) lookup
   const [activeCells, setActiveCells] = useState(new Map());
   
-  // Grid size from server
-  const [gridSize, setGridSize] = useState(1000);
+  // Grid dimensions from server
+  const [gridWidth, setGridWidth] = useState(1000);
+  const [gridHeight, setGridHeight] = useState(1000);
   
+  // Allowed colors from server (null until init)
+  const [palette, setPalette] = useState(null);
+
+  // Whether the server accepts colors outside the palette
+  const [anyColor, setAnyColor] = useState(false);
+
   // Connection status
   const [isConnected, setIsConnected] = useState(false);
   
   // Connected clients count
   const [connectedClients, setConnectedClients] = useState(0);
+
+  // Connected clients as a Map of connection id -> display name, and our own entry
+  const [onlineUsers, setOnlineUsers] = useState(new Map());
+  const [me, setMe] = useState(null);
+
+  // Other users' pointers as a Map of connection id -> { x, y, at }
+  const [cursors, setCursors] = useState(new Map());
+
+  // Latest chat messages, oldest first: [{ id, name, text, at }, ...]
+  const [chatMessages, setChatMessages] = useState([]);
+
+  // Read-only mode set by an admin: { reason } while frozen, null otherwise
+  const [frozen, setFrozen] = useState(null);
+
+  // Latest admin announcement: { text, severity, countdown, at } (null for none)
+  const [announcement, setAnnouncement] = useState(null);
+
+  // Regions only moderators may paint in: [{ id, x1, y1, x2, y2, label? }, ...]
+  const [lockedRegions, setLockedRegions] = useState([]);
+
+  // Cells held by each team, most first: [{ team, pixels }, ...] (empty without teams)
+  const [teamScores, setTeamScores] = useState([]);
+
+  // Team our placements count for ('' for none)
+  const [team, setTeam] = useState('');
+
+  // Time the last cursor position was sent
+  const lastCursorSentRef = useRef(0);
   
   // WebSocket reference
   const wsRef = useRef(null);
+
+  // Active cells accumulated while the initial state is streaming in (null when idle)
+  const pendingInitRef = useRef(null);
   
   // Reconnection state
   const reconnectTimeoutRef = useRef(null);
@@ -63,33 +132,107 @@ export function useGridWebSocket() {
           const data = JSON.parse(msg);
           
           if (data.type === 'init') {
-            // Initial state: { type: 'init', size: 1000, active: [{x, y, color}, ...] }
-            console.log('Received initial state:', data.active?.length || 0, 'active cells');
-            setGridSize(data.size || 1000);
-            
-            // Convert active array to Map with colors
-            const activeMap = new Map();
-            if (data.active) {
+            // Start of initial state stream: { type: 'init', width: 1000, height: 1000, chunk: 100, palette: [...] }
+            console.log('Receiving initial state...');
+            setGridWidth(data.width || 1000);
+            setGridHeight(data.height || 1000);
+            if (data.palette?.length) {
+              setPalette(data.palette);
+            }
+            setAnyColor(Boolean(data.any_color));
+            setFrozen(data.frozen ? { reason: data.frozen_reason || '' } : null);
+            pendingInitRef.current = new Map();
+          } else if (data.type === 'init_chunk') {
+            // One region of the initial state: { type: 'init_chunk', x, y, active: [{x, y, color}, ...] }
+            const pending = pendingInitRef.current;
+            if (pending && data.active) {
               data.active.forEach(cell => {
-                activeMap.set(`${cell.x},${cell.y}`, cell.color || '#FFFFFF');
+                pending.set(`${cell.x},${cell.y}`, cell.color || '#FFFFFF');
               });
             }
-            setActiveCells(activeMap);
-          } else if (data.t === 'u') {
+          } else if (data.type === 'init_done') {
+            // End of initial state stream: { type: 'init_done', total: N }
+            console.log('Received initial state:', data.total || 0, 'active cells');
+            setActiveCells(pendingInitRef.current || new Map());
+            pendingInitRef.current = null;
+          } else if (data.t === 'u' || data.t === 'b') {
             // Cell update: { t: 'u', x, y, a: 0|1, color }
+            // Batched update: { t: 'b', cells: [{x, y, a: 0|1, color}, ...] }
+            const cells = data.t === 'b' ? (data.cells || []) : [data];
+            const pending = pendingInitRef.current;
+            if (pending) {
+              // Still streaming the initial state, apply on top of it
+              applyCellUpdates(pending, cells);
+              continue;
+            }
             setActiveCells(prev => {
               const newMap = new Map(prev);
-              const key = `${data.x},${data.y}`;
-              if (data.a === 1) {
-                newMap.set(key, data.color || '#FFFFFF');
-              } else {
-                newMap.delete(key);
-              }
+              applyCellUpdates(newMap, cells);
               return newMap;
             });
           } else if (data.t === 'c') {
             // Connection count update: { t: 'c', count: N }
             setConnectedClients(data.count || 0);
+          } else if (data.t === 'roster') {
+            // Clients online when we joined: { t: 'roster', you: {id, name}, users: [{id, name}, ...] }
+            setMe(data.you || null);
+            setOnlineUsers(new Map((data.users || []).map(u => [u.id, u.name])));
+          } else if (data.t === 'join') {
+            // Client connected: { t: 'join', id, name }
+            setOnlineUsers(prev => new Map(prev).set(data.id, data.name));
+          } else if (data.t === 'leave') {
+            // Client disconnected: { t: 'leave', id }
+            setOnlineUsers(prev => {
+              const next = new Map(prev);
+              next.delete(data.id);
+              return next;
+            });
+            setCursors(prev => {
+              const next = new Map(prev);
+              next.delete(data.id);
+              return next;
+            });
+          } else if (data.t === 'cursor') {
+            // Another user's pointer moved: { t: 'cursor', id, x, y }
+            setCursors(prev => new Map(prev).set(data.id, { x: data.x, y: data.y, at: Date.now() }));
+          } else if (data.t === 'chat_history') {
+            // Latest chat messages, sent on connect: { t: 'chat_history', messages: [...] }
+            setChatMessages(data.messages || []);
+          } else if (data.t === 'chat') {
+            // Chat message: { t: 'chat', id, name, text, at }
+            setChatMessages(prev => [...prev, data].slice(-MAX_CHAT_MESSAGES));
+          } else if (data.t === 'frozen') {
+            // Canvas frozen or thawed: { t: 'frozen', enabled, reason? }
+            setFrozen(data.enabled ? { reason: data.reason || '' } : null);
+          } else if (data.t === 'reset') {
+            // Canvas reset by an admin, every cell cleared: { t: 'reset', archive?, at }
+            console.log('Canvas reset, previous state archived as', data.archive);
+            pendingInitRef.current = pendingInitRef.current && new Map();
+            setActiveCells(new Map());
+          } else if (data.t === 'announce') {
+            // Admin announcement: { t: 'announce', text, severity, countdown?, at }
+            setAnnouncement(data);
+          } else if (data.t === 'palette') {
+            // Palette changed by an admin: { t: 'palette', version, colors: [...], any_color }
+            if (data.colors?.length) {
+              setPalette(data.colors);
+            }
+            setAnyColor(Boolean(data.any_color));
+          } else if (data.t === 'locks') {
+            // Locked regions, sent on connect and when they change: { t: 'locks', regions: [...] }
+            setLockedRegions(data.regions || []);
+          } else if (data.t === 'teams') {
+            // Team territory, sent on connect and periodically: { t: 'teams', scores: [...] }
+            setTeamScores(data.scores || []);
+          } else if (data.t === 'team') {
+            // Team we joined: { t: 'team', team }
+            setTeam(data.team);
+          } else if (data.t === 'protected') {
+            // Cell placed by someone else too recently: { t: 'protected', x, y, remaining_ms }
+            console.warn(`Cell (${data.x}, ${data.y}) is protected for another ${data.remaining_ms}ms`);
+          } else if (data.t === 'err') {
+            // Rejected message: { t: 'err', code, msg }
+            console.warn('Server rejected message:', data.code, data.msg);
           }
         } catch (err) {
           console.error('Error parsing WebSocket message:', err, msg);
@@ -123,7 +266,7 @@ export function useGridWebSocket() {
   // Send a cell toggle to the server with color
   const toggleCell = useCallback((x, y, color = '#FF0000') => {
     if (wsRef.current?.readyState === WebSocket.OPEN) {
-      const message = JSON.stringify({ x, y, color });
+      const message = JSON.stringify({ type: 'toggle', x, y, color });
       console.log('Sending toggle message:', message);
       wsRef.current.send(message);
     } else {
@@ -131,6 +274,54 @@ export function useGridWebSocket() {
     }
   }, []);
 
+  // Share the cell under our pointer, at most once per CURSOR_INTERVAL_MS
+  const sendCursor = useCallback((x, y) => {
+    const now = Date.now();
+    if (now - lastCursorSentRef.current < CURSOR_INTERVAL_MS || wsRef.current?.readyState !== WebSocket.OPEN) {
+      return;
+    }
+    lastCursorSentRef.current = now;
+    wsRef.current.send(JSON.stringify({ type: 'cursor', x, y }));
+  }, []);
+
+  // Post a chat message to the canvas
+  const sendChat = useCallback((text) => {
+    if (wsRef.current?.readyState === WebSocket.OPEN) {
+      wsRef.current.send(JSON.stringify({ type: 'chat', text }));
+    }
+  }, []);
+
+  // Paint for a team ('' to leave it)
+  const joinTeam = useCallback((name) => {
+    if (wsRef.current?.readyState === WebSocket.OPEN) {
+      wsRef.current.send(JSON.stringify({ type: 'team', team: name }));
+    }
+  }, []);
+
+  // Revert our most recent placement
+  const undo = useCallback(() => {
+    if (wsRef.current?.readyState === WebSocket.OPEN) {
+      wsRef.current.send(JSON.stringify({ type: 'undo' }));
+    }
+  }, []);
+
+  // Hide the current announcement
+  const dismissAnnouncement = useCallback(() => setAnnouncement(null), []);
+
+  // Drop cursors that stopped moving
+  useEffect(() => {
+    const interval = setInterval(() => {
+      setCursors(prev => {
+        const cutoff = Date.now() - CURSOR_TIMEOUT_MS;
+        if (![...prev.values()].some(c => c.at < cutoff)) {
+          return prev;
+        }
+        return new Map([...prev].filter(([, c]) => c.at >= cutoff));
+      });
+    }, CURSOR_TIMEOUT_MS / 2);
+    return () => clearInterval(interval);
+  }, []);
+
   // Check if a cell is active (returns color string or false)
   const isCellActive = useCallback((x, y) => {
     const key = `${x},${y}`;
@@ -154,10 +345,27 @@ export function useGridWebSocket() {
 
   return {
     activeCells,
-    gridSize,
+    gridWidth,
+    gridHeight,
+    palette,
+    anyColor,
     isConnected,
     connectedClients,
+    onlineUsers,
+    me,
+    cursors,
+    chatMessages,
+    frozen,
+    announcement,
+    dismissAnnouncement,
+    lockedRegions,
+    teamScores,
+    team,
+    joinTeam,
+    undo,
     toggleCell,
+    sendCursor,
+    sendChat,
     isCellActive,
   };
 }
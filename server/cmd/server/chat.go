@@ -0,0 +1,36 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// chatStore saves the chat messages of the hubs in the database
+type chatStore struct{}
+
+func (chatStore) SaveChatMessage(canvas string, msg ws.BroadcastChat) error {
+	return db.SaveChatMessage(db.ChatMessage{Canvas: canvas, Name: msg.Name, Text: msg.Text, CreatedAt: msg.At})
+}
+
+// loadChat restores the latest chat messages of a canvas and deletes the
+// older ones from the database
+func loadChat(canvas string, limit int) []ws.BroadcastChat {
+	stored, err := db.LoadChatMessages(canvas, limit)
+	if err != nil {
+		slog.Warn("Failed to load chat history", "canvas", canvas, "err", err)
+		return nil
+	}
+	if pruned, err := db.PruneChatMessages(canvas, limit); err != nil {
+		slog.Warn("Failed to prune chat history", "canvas", canvas, "err", err)
+	} else if pruned > 0 {
+		slog.Info("Pruned chat history", "canvas", canvas, "deleted", pruned)
+	}
+
+	msgs := make([]ws.BroadcastChat, len(stored))
+	for i, msg := range stored {
+		msgs[i] = ws.BroadcastChat{Type: "chat", Name: msg.Name, Text: msg.Text, At: msg.CreatedAt.UTC()}
+	}
+	return msgs
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// followerReads are the endpoints a follower serves to other methods than GET
+// and HEAD, which only read the canvas
+var followerReads = map[string]bool{
+	"POST /timelapse": true,
+}
+
+// followerHandler rejects the requests that would change a canvas or its
+// settings, which must go to the instances the follower follows
+func followerHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if !followerReads[r.Method+" "+r.URL.Path] {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(map[string]string{"error": "this server is a read-only replica", "code": "read_only_replica"})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
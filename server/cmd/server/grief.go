@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/million_grids/server/internal/config"
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// Reporter the regions changed by quarantined actors are filed as
+const griefReporterName = "grief-detection"
+
+// griefReporter files the regions changed by quarantined actors in the report
+// queue, where moderators find the rollbacks reverting them
+type griefReporter struct{}
+
+func (griefReporter) ReportGrief(canvas string, q ws.Quarantine) {
+	report := db.Report{
+		Canvas: canvas,
+		X1:     q.Region.X1,
+		Y1:     q.Region.Y1,
+		X2:     q.Region.X2,
+		Y2:     q.Region.Y2,
+		Reason: fmt.Sprintf("%s quarantined for %s", q.Actor, q.Reason),
+	}
+	// Hubs call reporters while applying changes, so don't hold them up
+	go func() {
+		if _, err := db.FileReport(&report, griefReporterName); err != nil {
+			slog.Error("Failed to report quarantined actor", "canvas", canvas, "actor", q.Actor, "err", err)
+		}
+	}()
+}
+
+// griefPolicy returns the grief detection policy of the hubs, detecting
+// nothing when it is disabled
+func griefPolicy(cfg config.GriefConfig) ws.GriefPolicy {
+	if !cfg.Enabled {
+		return ws.GriefPolicy{}
+	}
+	return ws.GriefPolicy{
+		Window:             cfg.Window,
+		Overwrites:         cfg.Overwrites,
+		ColorShare:         cfg.ColorShare,
+		ScriptSamples:      cfg.ScriptSamples,
+		ScriptJitter:       cfg.ScriptJitter,
+		Quarantine:         cfg.Quarantine,
+		QuarantineCooldown: cfg.QuarantineCooldown,
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/million_grids/server/internal/config"
+	"github.com/million_grids/server/internal/gridpb"
+	"github.com/million_grids/server/internal/rpc"
+	"github.com/million_grids/server/internal/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// startGRPC serves the gRPC API on the configured address, with the same TLS
+// settings as the HTTP server
+func startGRPC(cfg *config.Config, srv *http.Server) *grpc.Server {
+	var opts []grpc.ServerOption
+	if cfg.TLS.Enabled() {
+		tlsConfig := srv.TLSConfig.Clone()
+		if cfg.TLS.CertFile != "" {
+			cert, err := tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+			if err != nil {
+				fatal("Failed to load TLS certificate for gRPC", "err", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	if cfg.Tracing.Enabled() {
+		opts = append(opts, grpc.ChainUnaryInterceptor(tracing.UnaryServerInterceptor()))
+	}
+
+	grpcSrv := grpc.NewServer(opts...)
+	gridpb.RegisterGridServer(grpcSrv, rpc.NewServer(canvases, tokenValidator, authRequired, trustedProxies))
+
+	lis, err := listen("grpc", cfg.GRPCListen)
+	if err != nil {
+		fatal("Failed to listen for gRPC", "addr", cfg.GRPCListen, "err", err)
+	}
+	go func() {
+		slog.Info("gRPC server listening", "addr", cfg.GRPCListen, "tls", cfg.TLS.Enabled())
+		if err := grpcSrv.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			fatal("gRPC server failed", "err", err)
+		}
+	}()
+	return grpcSrv
+}
+
+// stopGRPC waits for in-flight calls to finish, cancelling those still running
+// when ctx expires. Update streams end when the hubs shut down.
+func stopGRPC(ctx context.Context, grpcSrv *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		grpcSrv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		grpcSrv.Stop()
+	}
+}
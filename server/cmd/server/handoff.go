@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/million_grids/server/internal/snapshot"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// handoffEnv tells a process started by a handoff the names of the listeners
+// it inherits, in the order of their file descriptors
+const handoffEnv = "MILLION_GRIDS_HANDOFF"
+
+// File descriptors of the pipes a new process gets the grids through and
+// reports it is ready on, followed by those of the listeners
+const (
+	handoffStateFD     = 3
+	handoffReadyFD     = 4
+	handoffListenersFD = 5
+)
+
+// Time the new process has to get ready to take over
+const handoffTimeout = time.Minute
+
+// Time the requests already accepted have to be read when a handoff stops
+// accepting new ones, before the HTTP servers shut down and drop those unread
+const handoffGrace = time.Second
+
+// Largest encoded grid accepted from the old process
+const maxHandoffGrid = 1 << 30
+
+// namedListener is a listener of this process, passed on at a handoff
+type namedListener struct {
+	name string
+	ln   net.Listener
+}
+
+// Listeners of this process, in the order they were opened
+var listeners []namedListener
+
+// What this process inherited from the process it took over from (nil when
+// it wasn't started by a handoff)
+var inherited *inheritance
+
+// inheritance holds the listeners and pipes passed by the old process
+type inheritance struct {
+	listeners map[string]net.Listener
+	state     *os.File
+	ready     *os.File
+
+	// Grids received from the old process by canvas
+	grids map[string]*snapshot.Snapshot
+}
+
+// inheritHandoff picks up the listeners and pipes passed by the old process
+// when this one was started by a handoff
+func inheritHandoff() error {
+	names := os.Getenv(handoffEnv)
+	if names == "" {
+		return nil
+	}
+	os.Unsetenv(handoffEnv)
+
+	in := &inheritance{
+		listeners: make(map[string]net.Listener),
+		state:     os.NewFile(handoffStateFD, "handoff-state"),
+		ready:     os.NewFile(handoffReadyFD, "handoff-ready"),
+	}
+	for i, name := range strings.Split(names, ",") {
+		f := os.NewFile(uintptr(handoffListenersFD+i), name)
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to inherit %s listener: %w", name, err)
+		}
+		in.listeners[name] = ln
+	}
+	inherited = in
+	slog.Info("Taking over from the previous process", "listeners", names)
+	return nil
+}
+
+// listen returns the listener inherited under name, or opens one on addr
+func listen(name, addr string) (net.Listener, error) {
+	ln, ok := inherited.listener(name)
+	if !ok {
+		var err error
+		if ln, err = net.Listen("tcp", addr); err != nil {
+			return nil, err
+		}
+	}
+	listeners = append(listeners, namedListener{name: name, ln: ln})
+	return ln, nil
+}
+
+// listener returns the listener inherited under name
+func (in *inheritance) listener(name string) (net.Listener, bool) {
+	if in == nil {
+		return nil, false
+	}
+	ln, ok := in.listeners[name]
+	return ln, ok
+}
+
+// receiveGrids tells the old process this one is ready to take over, then
+// reads the grids it sends once it has stopped changing them. The canvases
+// it sends none for are loaded as on a normal start.
+func (in *inheritance) receiveGrids() {
+	if in == nil {
+		return
+	}
+	in.ready.Write([]byte{1})
+	in.ready.Close()
+
+	start := time.Now()
+	in.grids = make(map[string]*snapshot.Snapshot)
+	r := bufio.NewReader(in.state)
+	defer in.state.Close()
+	for {
+		size, err := binary.ReadUvarint(r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err == nil && size > maxHandoffGrid {
+			err = errors.New("grid too large")
+		}
+		var grid *snapshot.Snapshot
+		if err == nil {
+			data := make([]byte, size)
+			if _, err = io.ReadFull(r, data); err == nil {
+				grid, err = snapshot.Decode(bytes.NewReader(data))
+			}
+		}
+		if err != nil {
+			slog.Error("Failed to receive grids from the previous process", "err", err)
+			break
+		}
+		in.grids[grid.Canvas] = grid
+	}
+	slog.Info("Received grids from the previous process", "canvases", len(in.grids), "duration", time.Since(start))
+}
+
+// loadGrid fills the grid of a canvas with the state received from the old
+// process, reporting false if there was none for it
+func (in *inheritance) loadGrid(canvas string, grid *ws.GridState) bool {
+	if in == nil {
+		return false
+	}
+	snap, ok := in.grids[canvas]
+	if !ok {
+		return false
+	}
+	if snap.Width != grid.Width() || snap.Height != grid.Height() {
+		slog.Warn("Ignoring handed off grid of resized canvas", "canvas", canvas,
+			"handed_off", fmt.Sprintf("%dx%d", snap.Width, snap.Height))
+		return false
+	}
+	grid.LoadFromDB(snap.Cells)
+	slog.Info("Loaded handed off grid", "canvas", canvas, "count", len(snap.Cells))
+	return true
+}
+
+// handoff is a restart in progress, with the pipe the grids are sent to the
+// new process through
+type handoff struct {
+	state *os.File
+}
+
+// startHandoff starts the new binary at the path of ours with our arguments
+// and listeners, and waits until it is ready to take over. The new process
+// shares the listeners, so connections queue instead of being refused while
+// neither process accepts them.
+func startHandoff() (*handoff, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate executable: %w", err)
+	}
+	stateR, stateW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		stateR.Close()
+		stateW.Close()
+		return nil, err
+	}
+
+	// Files the new process gets, closed here once it has them
+	files := []*os.File{stateR, readyW}
+	closeFiles := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	fail := func(err error) (*handoff, error) {
+		closeFiles()
+		stateW.Close()
+		readyR.Close()
+		return nil, err
+	}
+	names := make([]string, 0, len(listeners))
+	for _, l := range listeners {
+		tcp, ok := l.ln.(*net.TCPListener)
+		if !ok {
+			return fail(fmt.Errorf("%s listener can't be handed off", l.name))
+		}
+		f, err := tcp.File()
+		if err != nil {
+			return fail(fmt.Errorf("failed to hand off %s listener: %w", l.name, err))
+		}
+		files = append(files, f)
+		names = append(names, l.name)
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(os.Environ(), handoffEnv+"="+strings.Join(names, ","))
+	if err := cmd.Start(); err != nil {
+		return fail(fmt.Errorf("failed to start new process: %w", err))
+	}
+	slog.Info("Started new process", "pid", cmd.Process.Pid)
+
+	// Our end of the ready pipe must be closed for reads to end when the new
+	// process exits
+	closeFiles()
+
+	// The ready pipe ends without a byte if the new process exits first
+	ready := make(chan bool, 1)
+	go func() {
+		var b [1]byte
+		n, _ := readyR.Read(b[:])
+		readyR.Close()
+		ready <- n == 1
+	}()
+	select {
+	case ok := <-ready:
+		if ok {
+			return &handoff{state: stateW}, nil
+		}
+		err = errors.New("new process exited before taking over")
+	case <-time.After(handoffTimeout):
+		err = errors.New("new process not ready in time")
+	}
+	stateW.Close()
+	cmd.Process.Kill()
+	cmd.Wait()
+	return nil, err
+}
+
+// stopAccepting closes our HTTP listeners, leaving the connections to the new
+// process, and waits for the requests already accepted to be read
+func stopAccepting() {
+	for _, l := range listeners {
+		if l.name != "grpc" {
+			l.ln.Close()
+		}
+	}
+	time.Sleep(handoffGrace)
+}
+
+// sendGrids sends the grid of every canvas to the new process, once they no
+// longer change
+func (h *handoff) sendGrids(canvases *ws.Canvases) error {
+	defer h.state.Close()
+	w := bufio.NewWriter(h.state)
+	var buf bytes.Buffer
+	var size [binary.MaxVarintLen64]byte
+	for _, hub := range canvases.Hubs() {
+		grid := hub.Grid()
+		buf.Reset()
+		err := snapshot.Encode(&buf, &snapshot.Snapshot{
+			Canvas:  hub.Canvas(),
+			Width:   grid.Width(),
+			Height:  grid.Height(),
+			TakenAt: time.Now(),
+			Cells:   grid.GetActiveCells(),
+		})
+		if err != nil {
+			return err
+		}
+		w.Write(size[:binary.PutUvarint(size[:], uint64(buf.Len()))])
+		w.Write(buf.Bytes())
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to send grids to new process: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+)
+
+// Time allowed for each readiness or liveness check
+const probeTimeout = 2 * time.Second
+
+// ProbeCheck is the outcome of one readiness or liveness check
+type ProbeCheck struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+
+	// Check-specific details
+	Details any `json:"details,omitempty"`
+}
+
+// ProbeResponse is the body of /livez and /readyz
+type ProbeResponse struct {
+	Status string                `json:"status"`
+	Checks map[string]ProbeCheck `json:"checks"`
+}
+
+// handleHealth is a simple health check endpoint, kept for existing monitors
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	var failedWrites int64
+	if queue := db.Queue(); queue != nil {
+		failedWrites = queue.Stats().FailedChanges
+	}
+	var waiting int
+	if waitingRoom != nil {
+		waiting = waitingRoom.Stats().Waiting
+	}
+	w.Write([]byte(fmt.Sprintf(`{"status": "ok", "clients": %d, "waiting": %d, "failed_writes": %d}`, canvases.ClientCount(), waiting, failedWrites)))
+}
+
+// handleLivez reports whether the process should be restarted: it fails only
+// when a hub's event loop is stuck
+func handleLivez(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), probeTimeout)
+	defer cancel()
+
+	writeProbe(w, map[string]ProbeCheck{"hubs": checkHubs(ctx)})
+}
+
+// handleReadyz reports whether the instance should receive traffic: the
+// database is reachable, every hub is responsive, the write queue is keeping
+// up and the server isn't shutting down
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), probeTimeout)
+	defer cancel()
+
+	checks := map[string]ProbeCheck{
+		"hubs":     checkHubs(ctx),
+		"database": checkDatabase(ctx),
+	}
+	if queue := db.Queue(); queue != nil {
+		stats := queue.Stats()
+		check := ProbeCheck{OK: !stats.Saturated, Details: stats}
+		if stats.Saturated {
+			check.Error = "write queue is saturated"
+		}
+		checks["write_queue"] = check
+	}
+	if canvases.ShuttingDown() {
+		checks["shutdown"] = ProbeCheck{OK: false, Error: "server is shutting down"}
+	}
+	writeProbe(w, checks)
+}
+
+// checkHubs pings the event loop of every hub
+func checkHubs(ctx context.Context) ProbeCheck {
+	clients := make(map[string]int)
+	for _, hub := range canvases.Hubs() {
+		if err := hub.Ping(ctx); err != nil {
+			return ProbeCheck{OK: false, Error: err.Error()}
+		}
+		clients[hub.Canvas()] = hub.ClientCount()
+	}
+	return ProbeCheck{OK: true, Details: map[string]any{"clients": clients}}
+}
+
+// checkDatabase pings the database
+func checkDatabase(ctx context.Context) ProbeCheck {
+	start := time.Now()
+	if err := db.Ping(ctx); err != nil {
+		return ProbeCheck{OK: false, Error: err.Error()}
+	}
+	return ProbeCheck{OK: true, Details: map[string]any{"latency_ms": time.Since(start).Milliseconds()}}
+}
+
+// writeProbe writes the checks with 200 if they all passed and 503 otherwise
+func writeProbe(w http.ResponseWriter, checks map[string]ProbeCheck) {
+	status, resp := http.StatusOK, ProbeResponse{Status: "ok", Checks: checks}
+	for _, check := range checks {
+		if !check.OK {
+			status, resp.Status = http.StatusServiceUnavailable, "unavailable"
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("Failed to write probe response", "err", err)
+	}
+}
@@ -1,119 +1,927 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"image/png"
+	"log/slog"
 	"net"
 	"net/http"
-	"strings"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/million_grids/server/internal/api"
+	"github.com/million_grids/server/internal/audit"
+	"github.com/million_grids/server/internal/auth"
+	"github.com/million_grids/server/internal/broker"
+	"github.com/million_grids/server/internal/captcha"
+	"github.com/million_grids/server/internal/config"
+	"github.com/million_grids/server/internal/crash"
 	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/decay"
+	"github.com/million_grids/server/internal/discord"
+	"github.com/million_grids/server/internal/export"
+	"github.com/million_grids/server/internal/geoip"
+	"github.com/million_grids/server/internal/leaderboard"
+	"github.com/million_grids/server/internal/model"
+	"github.com/million_grids/server/internal/oauth"
+	"github.com/million_grids/server/internal/readmodel"
+	"github.com/million_grids/server/internal/schedule"
+	"github.com/million_grids/server/internal/snapshot"
+	"github.com/million_grids/server/internal/tracing"
+	"github.com/million_grids/server/internal/webhook"
 	"github.com/million_grids/server/internal/ws"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	// Allow all origins for development (configure properly in production)
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
+var upgrader websocket.Upgrader
+
+// Hubs of the canvases served by this instance
+var canvases *ws.Canvases
+
+// Per-IP and overall caps on concurrent WebSocket connections across all canvases
+var connLimit *ws.ConnLimit
+
+// Queues the clients refused while every connection slot is taken (nil when disabled)
+var waitingRoom *ws.WaitingRoom
+
+// Refuses new WebSocket connections while the server is saturated (nil when
+// disabled), and the Retry-After header value of refused upgrades
+var loadShedder *ws.LoadShedder
+var retryAfter string
+
+// Writes periodic grid snapshots (nil when snapshots are disabled)
+var snapshots *snapshot.Snapshotter
+
+// Exports the pixel events to Kafka (nil when the export is disabled)
+var kafkaExporter *export.Kafka
+
+// Validates client tokens (nil when authentication is disabled)
+var tokenValidator *auth.Validator
 
-var hub *ws.Hub
+// Reject connections without a valid token
+var authRequired bool
+
+// Proxies whose forwarding headers give the client IP
+var trustedProxies ws.TrustedProxies
+
+// Flushes and stops the trace export (nil when tracing is disabled)
+var stopTracing func(context.Context) error
+
+// Waits for the panic reports not yet sent (nil when Sentry is disabled)
+var flushReports func(time.Duration) bool
+
+// Time allowed for draining connections and flushing writes on shutdown
+const shutdownTimeout = 15 * time.Second
 
 func main() {
-	log.Println("Starting Million Grids Server...")
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fatal("Failed to load configuration", "err", err)
+	}
+	setupLogger(cfg.Log)
+
+	slog.Info("Starting Million Grids Server")
+	if cfg.Tracing.Enabled() {
+		if stopTracing, err = tracing.Setup(cfg.Tracing); err != nil {
+			fatal("Failed to set up tracing", "err", err)
+		}
+		slog.Info("Exporting traces", "endpoint", cfg.Tracing.Endpoint, "sample_ratio", cfg.Tracing.SampleRatio)
+	}
+	if cfg.Sentry.Enabled() {
+		if flushReports, err = crash.Setup(cfg.Sentry); err != nil {
+			fatal("Failed to set up Sentry", "err", err)
+		}
+		slog.Info("Reporting panics to Sentry", "environment", cfg.Sentry.Environment)
+	}
+	if err := inheritHandoff(); err != nil {
+		fatal("Failed to take over from the previous process", "err", err)
+	}
+
+	upgrader = websocket.Upgrader{
+		ReadBufferSize:  cfg.Buffers.Read,
+		WriteBufferSize: cfg.Buffers.Write,
+		Subprotocols:    ws.Subprotocols,
+		CheckOrigin:     newOriginChecker(cfg.AllowedOrigins, cfg.AllowAnyOrigin),
+	}
+	if trustedProxies, err = ws.ParseTrustedProxies(cfg.TrustedProxies); err != nil {
+		fatal("Invalid trusted proxies", "err", err)
+	}
+	api.SetTrustedProxies(trustedProxies)
+
+	// Optional JWT authentication, and API keys for bot clients when there is
+	// a database to keep them in
+	var apiKeys *auth.Keys
+	if cfg.Database.Driver != "none" {
+		apiKeys = auth.NewKeys()
+	}
+	if cfg.Auth.JWTSecret != "" || apiKeys != nil {
+		tokenValidator = auth.NewValidator(cfg.Auth.JWTSecret, apiKeys)
+		authRequired = cfg.Auth.Required
+	}
+
+	// Optional custom color palette, and whether pixels are limited to it
+	if len(cfg.Palette) > 0 {
+		if err := model.SetPalette(cfg.Palette); err != nil {
+			fatal("Invalid palette", "err", err)
+		}
+	}
+	model.SetRestrictToPalette(cfg.ColorPolicy == "palette")
 
 	// Initialize database connection
-	if err := db.InitDB(); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+	if err := db.InitDB(cfg.Database); err != nil {
+		fatal("Failed to initialize database", "err", err)
 	}
 
-	// Initialize the in-memory grid with default colors
-	ws.Grid.Initialize()
+	// A palette set through the admin API replaces the configured one
+	if stored, err := db.LoadLatestPalette(); err != nil {
+		slog.Warn("Failed to load palette from database", "err", err)
+	} else if stored != nil {
+		colors, err := stored.ColorList()
+		if err != nil {
+			fatal("Invalid stored palette", "version", stored.Version, "err", err)
+		}
+		model.ActivatePalette(stored.Version, colors)
+		slog.Info("Loaded palette from database", "version", stored.Version, "colors", len(colors))
+	}
 
-	// Load existing pixels from database into memory
-	pixels, err := db.LoadAllPixels()
+	// Optional GeoIP database locating the clients that paint
+	var locator *geoip.Locator
+	if cfg.GeoIP.Database != "" {
+		if locator, err = geoip.Open(cfg.GeoIP.Database); err != nil {
+			fatal("Failed to load GeoIP database", "err", err)
+		}
+		slog.Info("Recording placement locations", "database", cfg.GeoIP.Database)
+	}
+
+	var anonymizer *ws.IPAnonymizer
+	if cfg.Privacy.HashIPs {
+		anonymizer = ws.NewIPAnonymizer(cfg.Privacy.IPSalt)
+	}
+
+	// Unauthenticated clients solve CAPTCHA challenges when a provider is configured
+	var captchaVerifier ws.CaptchaVerifier
+	if cfg.Captcha.Enabled() {
+		captchaVerifier = captcha.NewVerifier(cfg.Captcha)
+		slog.Info("Challenging unauthenticated clients", "provider", cfg.Captcha.Provider, "on_connect", cfg.Captcha.OnConnect, "placements", cfg.Captcha.Placements)
+	}
+	captchaPolicy := ws.CaptchaPolicy{
+		Provider:   cfg.Captcha.Provider,
+		SiteKey:    cfg.Captcha.SiteKey,
+		OnConnect:  cfg.Captcha.OnConnect,
+		Placements: cfg.Captcha.Placements,
+	}
+
+	// Account ages come from the users table, so tiers by age need a database
+	var accounts ws.AccountDirectory
+	if cfg.Database.Driver != "none" {
+		accounts = accountDirectory{}
+	}
+	tiers := placementTiers(cfg.Tiers)
+
+	// Webhooks receive the events of every canvas, and the Discord publisher
+	// the milestones of its canvas
+	canvases = ws.NewCanvases()
+	webhooks := webhook.NewDispatcher()
+	if err := webhooks.Load(); err != nil {
+		slog.Warn("Failed to load webhooks from database", "err", err)
+	}
+	events := ws.MultiSink{webhooks}
+	var discordPublisher *discord.Publisher
+	if cfg.Discord.WebhookURL != "" && !cfg.Follower {
+		canvas := cmp.Or(cfg.Discord.Canvas, cfg.Canvases[0].Name)
+		if discordPublisher, err = discord.NewPublisher(cfg.Discord, canvases, canvas); err != nil {
+			fatal("Failed to set up discord publisher", "err", err)
+		}
+		events = append(events, discordPublisher)
+	}
+
+	// The audit log records the paints and resets of every canvas, and the admin requests
+	var auditLog *audit.Log
+	if cfg.Audit.Enabled && !cfg.Follower {
+		if cfg.Database.Driver == "none" {
+			slog.Warn("The audit log requires a database, disabling it")
+		} else {
+			auditLog = audit.NewLog(cfg.Audit.Retention)
+			events = append(events, auditLog)
+			go auditLog.Run()
+		}
+	}
+
+	// Every pixel event is exported to Kafka when brokers are configured
+	if len(cfg.Kafka.Brokers) > 0 {
+		if kafkaExporter, err = export.NewKafka(cfg.Kafka); err != nil {
+			fatal("Failed to set up kafka export", "err", err)
+		}
+		events = append(events, kafkaExporter)
+		go kafkaExporter.Run()
+		slog.Info("Exporting pixel events to kafka", "topic", cfg.Kafka.Topic)
+	}
+
+	// Chat messages are masked with the blocked words and kept in the database when there is one
+	var chatFilter ws.ChatFilter
+	if len(cfg.Chat.BlockedWords) > 0 {
+		chatFilter = ws.NewWordFilter(cfg.Chat.BlockedWords)
+	}
+	var chatSaver ws.ChatStore
+	if cfg.Chat.History > 0 && cfg.Database.Driver != "none" {
+		chatSaver = chatStore{}
+	}
+
+	// Create and start a grid and hub per canvas
+	connLimit = ws.NewConnLimit(cfg.MaxConnectionsPerIP, cfg.Admission.MaxConnections)
+	retryAfter = strconv.Itoa(int(cfg.Admission.RetryAfter.Seconds()))
+	if cfg.Admission.MaxWaiting > 0 {
+		waitingRoom = ws.NewWaitingRoom(connLimit, cfg.Admission.MaxWaiting, cfg.MaxConnectionsPerIP, cfg.Admission.TicketTTL)
+		go waitingRoom.Run()
+	}
+	if cfg.Snapshots.Dir != "" && !cfg.Follower {
+		snapshotStore, err := snapshot.NewStore(cfg.Snapshots.Dir)
+		if err != nil {
+			fatal("Failed to open snapshot directory", "err", err)
+		}
+		snapshots = snapshot.NewSnapshotter(snapshotStore, canvases, cfg.Snapshots.Interval, cfg.Snapshots.Keep)
+	}
+
+	// After a handoff, the grids come from the previous process once it has
+	// stopped serving them. Only then is the write-ahead log, which it writes
+	// until it has flushed its queue, ours to replay and append to.
+	inherited.receiveGrids()
+	if err := db.StartWriteQueue(cfg.Database); err != nil {
+		fatal("Failed to start write queue", "err", err)
+	}
+
+	// Persist cell changes through the write queue when a database is
+	// configured. Followers only read it, at startup.
+	var store ws.PixelStore
+	if queue := db.Queue(); queue != nil && !cfg.Follower {
+		store = queue
+	}
+
+	// The read models start where the stream ends now, before the grids they
+	// are created from are loaded
+	var readModels *readmodel.Projector
+	if cfg.ReadModels.Interval > 0 && cfg.Database.Driver != "none" {
+		if readModels, err = readmodel.NewProjector(cfg.ReadModels.Interval); err != nil {
+			fatal("Failed to set up read models", "err", err)
+		}
+	}
+	for _, canvas := range cfg.Canvases {
+		width, height, err := canvasDimensions(canvas)
+		if err != nil {
+			fatal("Failed to load canvas", "canvas", canvas.Name, "err", err)
+		}
+		grid := ws.NewGridState(width, height)
+		if !inherited.loadGrid(canvas.Name, grid) {
+			loadGrid(canvas.Name, grid)
+		}
+		if readModels != nil {
+			readModels.Add(canvas.Name, width, height, grid.GetActiveCells())
+		}
+		placements, err := db.HistoryCount(canvas.Name)
+		if err != nil {
+			slog.Warn("Failed to count placements, milestones start from zero", "canvas", canvas.Name, "err", err)
+		}
+		var chatHistory []ws.BroadcastChat
+		if chatSaver != nil {
+			chatHistory = loadChat(canvas.Name, cfg.Chat.History)
+		}
+
+		// Optional Redis or NATS broker for sharing the canvas across instances
+		var hubBroker ws.Broker
+		if cfg.Redis.URL != "" {
+			redisBroker, err := broker.NewRedisBroker(cfg.Redis.URL, canvasChannel(cfg.Redis.Channel, canvas.Name))
+			if err != nil {
+				fatal("Failed to initialize redis broker", "canvas", canvas.Name, "err", err)
+			}
+			hubBroker = redisBroker
+		}
+		if cfg.NATS.URL != "" {
+			natsBroker, err := broker.NewNATSBroker(cfg.NATS.URL, cfg.NATS.Stream, cfg.NATS.Subject, cfg.NATS.Subject+"."+canvas.Name, cfg.NATS.MaxAge)
+			if err != nil {
+				fatal("Failed to initialize nats broker", "canvas", canvas.Name, "err", err)
+			}
+			hubBroker = natsBroker
+		}
+
+		// Optional replication of the canvas to the other regions, whose
+		// changes one instance of our region applies
+		var replication ws.Broker
+		if r := cfg.Replication; r.Region != "" {
+			replicator, err := broker.NewNATSBroker(r.URL, r.Stream, r.Subject, r.Subject+"."+canvas.Name, r.MaxAge)
+			if err != nil {
+				fatal("Failed to initialize replication", "canvas", canvas.Name, "err", err)
+			}
+			replicator.ShareConsumer(r.Stream + "_" + r.Region + "_" + canvas.Name)
+			replication = replicator
+		}
+
+		var claims []ws.Claim
+		if cfg.Economy.Enabled {
+			claims = loadClaims(canvas.Name)
+		}
+		hub := ws.NewHub(grid, ws.HubConfig{
+			Canvas:              canvas.Name,
+			Store:               store,
+			Broker:              hubBroker,
+			Replication:         replication,
+			Region:              cfg.Replication.Region,
+			PlacementCooldown:   cfg.Cooldown,
+			Tiers:               tiers,
+			Accounts:            accounts,
+			OverwriteProtection: cfg.OverwriteProtection,
+			MessageRate:         cfg.RateLimit.Rate,
+			MessageBurst:        cfg.RateLimit.Burst,
+			CursorRate:          cfg.RateLimit.Cursor,
+			BandwidthLimit:      cfg.RateLimit.Bandwidth,
+			ChatRate:            cfg.Chat.Rate,
+			ChatBurst:           cfg.Chat.Burst,
+			ChatMaxLength:       cfg.Chat.MaxLength,
+			ChatFilter:          chatFilter,
+			ChatHistory:         cfg.Chat.History,
+			ChatRestored:        chatHistory,
+			ChatStore:           chatSaver,
+			SendBuffer:          cfg.Buffers.Send,
+			SlowClient:          ws.SlowClientPolicy(cfg.Buffers.SlowClient),
+			FanoutWorkers:       cfg.FanoutWorkers,
+			Connections:         connLimit,
+			IdleTimeout:         cfg.IdleTimeout,
+			GeoIP:               locator,
+			Anonymizer:          anonymizer,
+			ReadOnly:            canvas.ReadOnly,
+			ReadOnlyReason:      canvas.ReadOnlyReason,
+			Follower:            cfg.Follower,
+			Locks:               loadLocks(canvas.Name),
+			Teams:               cfg.Teams.Names,
+			TeamPixels:          loadTeamPixels(canvas.Name, cfg.Teams.Names),
+			TeamScoreInterval:   cfg.Teams.ScoreInterval,
+			Grief:               griefPolicy(cfg.Grief),
+			GriefReporter:       griefReporter{},
+			Captcha:             captchaPolicy,
+			CaptchaVerifier:     captchaVerifier,
+			Templates:           loadTemplates(canvas.Name),
+			CellMeta:            loadCellMeta(canvas.Name),
+			CellMetaStore:       cellMetaStore{},
+			Economy:             cfg.Economy.Enabled,
+			ClaimDuration:       cfg.Economy.ClaimDuration,
+			MaxClaimCells:       cfg.Economy.MaxCells,
+			Claims:              claims,
+			Events:              events,
+			Placements:          placements,
+			MilestoneEvery:      cfg.MilestoneEvery,
+		})
+		go hub.Run(context.Background())
+		canvases.Add(hub)
+	}
+	if snapshots != nil {
+		go snapshots.Run()
+	}
+	if discordPublisher != nil {
+		go discordPublisher.Run()
+	}
+	if cfg.Follower {
+		slog.Info("Serving canvases read-only as a follower")
+	}
+	if cfg.Admission.ShedsLoad() {
+		loadShedder = ws.NewLoadShedder(canvases, cfg.Admission.MaxCPU, cfg.Admission.MaxQueue, cfg.Admission.Interval)
+		go loadShedder.Run()
+	}
+
+	// Followers leave the scheduled events, aggregation and decay, which
+	// write, to the instances they follow
+	if len(cfg.Events) > 0 && !cfg.Follower {
+		go newScheduler(cfg.Events).Run()
+	}
+	if cfg.Leaderboard.Interval > 0 && cfg.Database.Driver != "none" && !cfg.Follower {
+		go leaderboard.NewAggregator(cfg.Leaderboard.Interval).Run()
+	}
+	if readModels != nil {
+		go readModels.Run()
+	}
+	if cfg.Decay.After > 0 && !cfg.Follower {
+		if cfg.Database.Driver == "none" {
+			slog.Warn("Pixel decay requires a database, disabling it")
+		} else {
+			go decay.NewSweeper(canvases, cfg.Decay.After, cfg.Decay.Interval).Run()
+		}
+	}
+	if cfg.Redis.URL != "" {
+		slog.Info("Sharing updates with other instances through redis")
+	}
+	if cfg.NATS.URL != "" {
+		slog.Info("Sharing updates with other instances through nats", "stream", cfg.NATS.Stream, "subject", cfg.NATS.Subject)
+	}
+	if cfg.Replication.Region != "" {
+		slog.Info("Replicating changes with other regions", "region", cfg.Replication.Region, "stream", cfg.Replication.Stream)
+	}
+
+	// Restore persisted bans
+	bans, err := db.LoadBans()
+	if err != nil {
+		slog.Warn("Failed to load bans from database", "err", err)
+	}
+	for _, ban := range bans {
+		network, err := ws.ParseBanTarget(ban.Target)
+		if err != nil {
+			slog.Warn("Skipping invalid ban", "target", ban.Target, "err", err)
+			continue
+		}
+		canvases.Ban(network)
+	}
+	shadowBans, err := db.LoadShadowBans()
 	if err != nil {
-		log.Printf("Warning: Failed to load pixels from database: %v", err)
-	} else {
-		ws.Grid.LoadFromDB(pixels)
-		log.Printf("Loaded %d pixels into memory", len(pixels))
+		slog.Warn("Failed to load shadow bans from database", "err", err)
+	}
+	for _, ban := range shadowBans {
+		canvases.ShadowBan(ban.Target)
 	}
 
-	// Create and start the WebSocket hub
-	hub = ws.NewHub()
-	go hub.Run()
+	// Restore the API keys of bot clients
+	if apiKeys != nil {
+		keys, err := db.LoadAPIKeys()
+		if err != nil {
+			slog.Warn("Failed to load api keys from database", "err", err)
+		}
+		for _, key := range keys {
+			api.ActivateAPIKey(apiKeys, key)
+		}
+	}
 
 	// Set up HTTP routes
 	http.HandleFunc("/ws", handleWebSocket)
+	if waitingRoom != nil {
+		http.HandleFunc("/ws/queue", handleWaitingRoom)
+	}
 	http.HandleFunc("/health", handleHealth)
+	http.HandleFunc("GET /livez", handleLivez)
+	http.HandleFunc("GET /readyz", handleReadyz)
+	apiOpts := api.Options{
+		Validator:    tokenValidator,
+		AuthRequired: authRequired,
+		PaintRate:    cfg.RateLimit.Rate,
+		PaintBurst:   cfg.RateLimit.Burst,
+		ReadModels:   readModels,
+	}
+	if snapshots != nil {
+		apiOpts.Snapshots = snapshots.Store()
+	}
+	api.RegisterRoutes(http.DefaultServeMux, canvases, apiOpts)
+	prometheus.MustRegister(ws.QueueCollector(canvases))
+	api.RegisterAdminRoutes(http.DefaultServeMux, canvases, api.AdminOptions{
+		Token:      cfg.Admin.Token,
+		Anonymizer: anonymizer,
+		Webhooks:   webhooks,
+		Snapshots:  snapshots,
+		Audit:      auditLog,
+		APIKeys:    apiKeys,
+	})
+	if cfg.Auth.OAuth.Enabled() {
+		api.RegisterAuthRoutes(http.DefaultServeMux, api.AuthOptions{
+			Validator:  tokenValidator,
+			Providers:  oauth.Providers(cfg.Auth.OAuth),
+			BaseURL:    cfg.Auth.OAuth.BaseURL,
+			SessionTTL: cfg.Auth.OAuth.SessionTTL,
+		})
+	}
 
-	// Start the HTTP server
-	addr := ":8080"
-	log.Printf("Server listening on %s", addr)
-	if err := http.ListenAndServe(addr, nil); err != nil {
-		log.Fatalf("Server failed: %v", err)
+	// Start the HTTP server, and the HTTP to HTTPS redirect when configured
+	var handler http.Handler = http.DefaultServeMux
+	if cfg.Follower {
+		handler = followerHandler(handler)
+	}
+	if cfg.Tracing.Enabled() {
+		handler = tracing.Middleware(handler)
+	}
+	srv := &http.Server{Addr: cfg.Listen, Handler: handler}
+	servers := []*http.Server{srv}
+	ln, err := listen("http", cfg.Listen)
+	if err != nil {
+		fatal("Failed to listen", "addr", cfg.Listen, "err", err)
+	}
+	if cfg.TLS.Enabled() {
+		redirect := configureTLS(srv, cfg)
+		if cfg.TLS.RedirectListen != "" {
+			redirectSrv := &http.Server{Addr: cfg.TLS.RedirectListen, Handler: redirect}
+			servers = append(servers, redirectSrv)
+			redirectLn, err := listen("redirect", cfg.TLS.RedirectListen)
+			if err != nil {
+				fatal("Failed to listen for HTTP redirect", "addr", cfg.TLS.RedirectListen, "err", err)
+			}
+			go func() {
+				slog.Info("Redirecting HTTP to HTTPS", "addr", cfg.TLS.RedirectListen)
+				if err := redirectSrv.Serve(redirectLn); err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+					fatal("Redirect server failed", "err", err)
+				}
+			}()
+		}
+	}
+	go func() {
+		slog.Info("Server listening", "addr", cfg.Listen, "tls", cfg.TLS.Enabled())
+		if err := serve(srv, ln, cfg.TLS); err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+			fatal("Server failed", "err", err)
+		}
+	}()
+	if cfg.Admin.DebugListen != "" {
+		debugLn, err := listen("debug", cfg.Admin.DebugListen)
+		if err != nil {
+			fatal("Failed to listen for debug endpoints", "addr", cfg.Admin.DebugListen, "err", err)
+		}
+		debugSrv := &http.Server{Addr: cfg.Admin.DebugListen, Handler: api.DebugHandler(canvases)}
+		servers = append(servers, debugSrv)
+		go func() {
+			slog.Info("Serving debug endpoints to loopback clients", "addr", cfg.Admin.DebugListen)
+			if err := debugSrv.Serve(debugLn); err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+				fatal("Debug server failed", "err", err)
+			}
+		}()
+	}
+	var grpcSrv *grpc.Server
+	if cfg.GRPCListen != "" {
+		grpcSrv = startGRPC(cfg, srv)
+	}
+
+	// Wait for SIGINT/SIGTERM, then drain connections and flush pending
+	// writes. On SIGUSR2, a new process started from the binary at our path
+	// takes over the listeners and, once we are drained, the grids.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	restarts := make(chan os.Signal, 1)
+	signal.Notify(restarts, syscall.SIGUSR2)
+	for {
+		select {
+		case <-ctx.Done():
+			stop()
+			shutdown(servers, grpcSrv)
+			return
+		case <-restarts:
+			slog.Info("Restarting with listener handoff")
+			next, err := startHandoff()
+			if err != nil {
+				slog.Error("Restart failed, still serving", "err", err)
+				continue
+			}
+			stopAccepting()
+			shutdown(servers, grpcSrv)
+			if err := next.sendGrids(canvases); err != nil {
+				slog.Error("Failed to hand off grids, the new process loads them from the database", "err", err)
+			}
+			return
+		}
 	}
 }
 
-// handleWebSocket upgrades HTTP connections to WebSocket
-func handleWebSocket(w http.ResponseWriter, r *http.Request) {
+// acquireSlot reserves a connection slot for the IP, through the waiting room
+// when there is one so clients can't jump its queue
+func acquireSlot(r *http.Request, ip string) error {
+	if waitingRoom == nil {
+		return connLimit.Acquire(ip)
+	}
+	return waitingRoom.Acquire(r.URL.Query().Get("ticket"), ip)
+}
+
+// handleWaitingRoom upgrades the connections of clients waiting for a slot,
+// and queues them in the waiting room until they are admitted
+func handleWaitingRoom(w http.ResponseWriter, r *http.Request) {
+	if canvases.ShuttingDown() {
+		http.Error(w, "server restarting", http.StatusServiceUnavailable)
+		return
+	}
+	ipAddress := api.ClientIP(r)
+	if canvases.IsBanned(ipAddress) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if waitingRoom.Full(ipAddress) {
+		refuseSaturated(w)
+		return
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade failed: %v", err)
+		slog.Warn("WebSocket upgrade failed", "err", err)
 		return
 	}
+	waitingRoom.Wait(conn, ipAddress)
+}
 
-	// Extract client IP address
-	ipAddress := getClientIP(r)
+// refuseSaturated answers an upgrade refused because the server is saturated,
+// telling the client when to retry
+func refuseSaturated(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", retryAfter)
+	http.Error(w, "server busy, retry later", http.StatusServiceUnavailable)
+}
 
-	// Create new client with IP address
-	client := ws.NewClient(hub, conn, ipAddress)
+// canvasDimensions resolves the size of a canvas from the configuration and
+// the database, storing it when it is new or has been changed
+func canvasDimensions(canvas config.CanvasConfig) (int, int, error) {
+	stored, err := db.LoadCanvas(canvas.Name)
+	if err != nil {
+		return 0, 0, err
+	}
 
-	// Register the client with the hub
-	hub.Register(client)
+	width, height := model.DefaultGridSize, model.DefaultGridSize
+	if stored != nil {
+		width, height = stored.Width, stored.Height
+	}
+	if canvas.Width > 0 {
+		width = canvas.Width
+	}
+	if canvas.Height > 0 {
+		height = canvas.Height
+	}
 
-	// Send the current grid state to the new client
-	if err := client.SendInitialState(); err != nil {
-		log.Printf("Failed to send initial state: %v", err)
+	if stored != nil && stored.Width == width && stored.Height == height {
+		return width, height, nil
+	}
+	if stored != nil {
+		slog.Warn("Canvas resized", "canvas", canvas.Name,
+			"from", fmt.Sprintf("%dx%d", stored.Width, stored.Height), "to", fmt.Sprintf("%dx%d", width, height))
 	}
 
-	// Start the client's read/write pumps
-	client.Start()
+	record := db.Canvas{Name: canvas.Name, Width: width, Height: height, CreatedAt: time.Now()}
+	if stored != nil {
+		record.CreatedAt = stored.CreatedAt
+	}
+	if err := db.SaveCanvas(record); err != nil {
+		return 0, 0, err
+	}
+	return width, height, nil
 }
 
-// getClientIP extracts the client's IP address from the request
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header first (for proxies/load balancers)
-	xff := r.Header.Get("X-Forwarded-For")
-	if xff != "" {
-		// X-Forwarded-For can contain multiple IPs, take the first one
-		ips := strings.Split(xff, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
+// loadLocks restores the locked regions of a canvas from the database
+func loadLocks(canvas string) []ws.LockedRegion {
+	stored, err := db.LoadLockedRegions(canvas)
+	if err != nil {
+		slog.Warn("Failed to load locked regions", "canvas", canvas, "err", err)
+		return nil
+	}
+	locks := make([]ws.LockedRegion, len(stored))
+	for i, lock := range stored {
+		locks[i] = ws.LockedRegion{ID: lock.ID, Region: ws.Region{X1: lock.X1, Y1: lock.Y1, X2: lock.X2, Y2: lock.Y2}, Label: lock.Label}
+	}
+	return locks
+}
+
+// loadClaims restores the unexpired claims of a canvas from the database
+func loadClaims(canvas string) []ws.Claim {
+	stored, err := db.LoadClaims(canvas, time.Now())
+	if err != nil {
+		slog.Warn("Failed to load claims", "canvas", canvas, "err", err)
+		return nil
+	}
+	claims := make([]ws.Claim, len(stored))
+	for i, claim := range stored {
+		claims[i] = ws.Claim{ID: claim.ID, Region: ws.Region{X1: claim.X1, Y1: claim.Y1, X2: claim.X2, Y2: claim.Y2}, Owner: claim.Owner, ExpiresAt: claim.ExpiresAt}
+	}
+	return claims
+}
+
+// loadTeamPixels restores the cells held by the teams of a canvas from the
+// database, dropping those of teams no longer configured
+func loadTeamPixels(canvas string, teams []string) []model.Pixel {
+	if len(teams) == 0 {
+		return nil
+	}
+	stored, err := db.LoadTeamPixels(canvas)
+	if err != nil {
+		slog.Warn("Failed to load team territory", "canvas", canvas, "err", err)
+		return nil
+	}
+	return slices.DeleteFunc(stored, func(p model.Pixel) bool { return !slices.Contains(teams, p.Team) })
+}
+
+// loadTemplates restores the templates of a canvas from the database
+func loadTemplates(canvas string) []*ws.Template {
+	stored, err := db.LoadTemplates(canvas)
+	if err != nil {
+		slog.Warn("Failed to load templates", "canvas", canvas, "err", err)
+		return nil
+	}
+	templates := make([]*ws.Template, 0, len(stored))
+	for _, t := range stored {
+		img, err := png.Decode(bytes.NewReader(t.Image))
+		if err != nil {
+			slog.Warn("Skipping unreadable template", "canvas", canvas, "id", t.ID, "err", err)
+			continue
 		}
+		templates = append(templates, ws.NewTemplate(t.ID, t.Name, t.X, t.Y, img))
 	}
+	return templates
+}
 
-	// Check X-Real-IP header
-	xri := r.Header.Get("X-Real-IP")
-	if xri != "" {
-		return xri
+// loadGrid fills the grid of a canvas from its latest snapshot and the history
+// since, or from the pixels table when there is no usable snapshot
+func loadGrid(canvas string, grid *ws.GridState) {
+	if snapshots != nil {
+		restored, err := snapshots.Restore(canvas, grid)
+		if err != nil {
+			slog.Warn("Failed to restore snapshot, loading pixels from database", "canvas", canvas, "err", err)
+		}
+		if restored {
+			return
+		}
 	}
 
-	// Fall back to RemoteAddr
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	// Load existing pixels from database into memory
+	pixels, err := db.LoadAllPixels(canvas)
 	if err != nil {
-		return r.RemoteAddr
+		slog.Warn("Failed to load pixels from database", "canvas", canvas, "err", err)
+		return
+	}
+	grid.LoadFromDB(pixels)
+	slog.Info("Loaded pixels into memory", "canvas", canvas, "width", grid.Width(), "height", grid.Height(), "count", len(pixels))
+}
+
+// canvasChannel returns the broker channel of a canvas. The default canvas keeps
+// the configured channel so single-canvas deployments are unaffected.
+func canvasChannel(channel, canvas string) string {
+	if canvas == model.DefaultCanvas {
+		return channel
+	}
+	return channel + ":" + canvas
+}
+
+// setupLogger installs the default slog logger with the configured level and format
+func setupLogger(cfg config.LogConfig) {
+	level, _ := cfg.SlogLevel() // Validated by config.Load
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler = slog.NewJSONHandler(os.Stderr, opts)
+	if cfg.Format == "text" {
+		handler = slog.NewTextHandler(os.Stderr, opts)
 	}
-	return ip
+	slog.SetDefault(slog.New(handler))
 }
 
-// handleHealth is a simple health check endpoint
-func handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(fmt.Sprintf(`{"status": "ok", "clients": %d}`, hub.ClientCount())))
+// newScheduler creates the scheduler of the configured events, archiving
+// their final states as snapshots when snapshots are enabled
+func newScheduler(events []config.EventConfig) *schedule.Scheduler {
+	var archiver schedule.Archiver
+	if snapshots != nil {
+		archiver = snapshots
+	}
+	scheduled := make([]schedule.Event, len(events))
+	for i, event := range events {
+		scheduled[i] = schedule.Event{
+			Name:       event.Name,
+			Canvas:     event.Canvas,
+			Start:      event.Start,
+			End:        event.End,
+			Countdowns: event.Countdowns,
+		}
+	}
+	return schedule.NewScheduler(canvases, archiver, scheduled)
+}
+
+// fatal logs an error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// shutdown stops accepting connections, closes all clients with a restart
+// reason, persists any pixels still waiting in the write queue and takes a
+// final snapshot. grpcSrv is nil when the gRPC API is disabled.
+func shutdown(servers []*http.Server, grpcSrv *grpc.Server) {
+	slog.Info("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Stop accepting new connections and upgrades, after ending the event
+	// streams the HTTP servers would otherwise wait for
+	canvases.CloseListeners()
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			slog.Error("HTTP server shutdown failed", "addr", srv.Addr, "err", err)
+		}
+	}
+
+	// Close WebSocket clients (hijacked connections aren't tracked by the server)
+	if err := canvases.Shutdown(ctx); err != nil {
+		slog.Error("Hub shutdown failed", "err", err)
+	}
+	canvases.Close()
+	if grpcSrv != nil {
+		stopGRPC(ctx, grpcSrv)
+	}
+
+	if kafkaExporter != nil {
+		if err := kafkaExporter.Close(); err != nil {
+			slog.Error("Failed to close kafka export", "err", err)
+		}
+	}
+
+	if err := db.FlushPending(); err != nil {
+		slog.Error("Failed to flush pending pixel writes", "err", err)
+	}
+
+	// A final snapshot keeps the history replayed on the next start short
+	if snapshots != nil {
+		snapshots.TakeAll()
+	}
+
+	// Export the spans of the shutdown too
+	if stopTracing != nil {
+		if err := stopTracing(ctx); err != nil {
+			slog.Error("Failed to flush traces", "err", err)
+		}
+	}
+	if flushReports != nil && !flushReports(2*time.Second) {
+		slog.Warn("Panic reports not sent before shutdown")
+	}
+	slog.Info("Shutdown complete")
+}
+
+// handleWebSocket upgrades HTTP connections to WebSocket on the canvas given by
+// ?canvas=, including the cell metadata in the initial state with ?meta=1
+func handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if canvases.ShuttingDown() {
+		http.Error(w, "server restarting", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Shed new connections first while saturated, before spending anything on them
+	if reason, saturated := loadShedder.Saturated(); saturated {
+		slog.Debug("Refusing connection, server saturated", "reason", reason)
+		refuseSaturated(w)
+		return
+	}
+
+	hub, ok := canvases.Get(r.URL.Query().Get("canvas"))
+	if !ok {
+		http.Error(w, "unknown canvas", http.StatusNotFound)
+		return
+	}
+
+	// Extract client IP address and refuse banned IPs
+	ipAddress := api.ClientIP(r)
+	if canvases.IsBanned(ipAddress) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Authenticate the user if a token is provided (or required)
+	var identity *auth.Identity
+	if tokenValidator != nil {
+		var err error
+		identity, err = tokenValidator.Authenticate(r)
+		if err != nil && (authRequired || !errors.Is(err, auth.ErrNoToken)) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	// Unauthenticated clients may have to solve a CAPTCHA challenge to connect
+	if policy, challenged := hub.Captcha(); challenged && policy.OnConnect && identity == nil {
+		if err := hub.SolveCaptcha(r.Context(), hub.AnonymousActor(ipAddress), ipAddress, r.URL.Query().Get("captcha")); err != nil {
+			http.Error(w, "captcha required", http.StatusForbidden)
+			return
+		}
+	}
+
+	// Reserve a connection slot, released by the hub when the client
+	// unregisters. Clients admitted from the waiting room hold one already.
+	if err := acquireSlot(r, ipAddress); err != nil {
+		if errors.Is(err, ws.ErrServerFull) {
+			slog.Debug("Refusing connection, server full", "ip", ipAddress)
+			refuseSaturated(w)
+			return
+		}
+		slog.Warn("Too many connections from IP", "ip", ipAddress)
+		http.Error(w, "too many connections", http.StatusTooManyRequests)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		connLimit.Release(ipAddress)
+		slog.Warn("WebSocket upgrade failed", "err", err)
+		return
+	}
+
+	// Create new client with IP address and user identity
+	client := ws.NewClient(hub, conn, ipAddress, identity)
+	if meta, _ := strconv.ParseBool(r.URL.Query().Get("meta")); meta {
+		client.IncludeCellMeta()
+	}
+
+	// Register the client with the hub
+	hub.Register(client)
+
+	// Queue the current grid state, written first by the write pump
+	if err := client.SendInitialState(); err != nil {
+		slog.Error("Failed to send initial state", "conn", client.ConnID(), "err", err)
+	}
+
+	// Start the client's read/write pumps
+	client.Start()
 }
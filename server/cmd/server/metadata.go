@@ -0,0 +1,29 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// cellMetaStore deletes the cell metadata the hubs drop from the database
+type cellMetaStore struct{}
+
+func (cellMetaStore) DeleteCellMeta(canvas string, cells [][2]int) error {
+	return db.DeleteCellMetadata(canvas, cells)
+}
+
+// loadCellMeta restores the cell metadata of a canvas from the database
+func loadCellMeta(canvas string) []ws.CellMeta {
+	stored, err := db.LoadCellMetadata(canvas)
+	if err != nil {
+		slog.Warn("Failed to load cell metadata", "canvas", canvas, "err", err)
+		return nil
+	}
+	metadata := make([]ws.CellMeta, len(stored))
+	for i, m := range stored {
+		metadata[i] = ws.CellMeta{X: m.X, Y: m.Y, Text: m.Text, URL: m.URL, Actor: m.Actor}
+	}
+	return metadata
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// originPattern is an allowed origin, with an optional subdomain wildcard
+type originPattern struct {
+	scheme string
+	port   string
+
+	// Exact hostname, or the suffix (".example.com") any subdomain must end with
+	host     string
+	wildcard bool
+}
+
+// newOriginChecker returns the upgrader's CheckOrigin. Connections without an
+// Origin header (non-browser clients) and from the server's own origin are
+// always accepted; other origins must match one of the patterns, which
+// config.ValidateOriginPattern has validated. With allowAny, every origin is accepted.
+func newOriginChecker(patterns []string, allowAny bool) func(*http.Request) bool {
+	if allowAny {
+		slog.Warn("Accepting WebSocket connections from any origin")
+		return func(*http.Request) bool { return true }
+	}
+
+	allowed := make([]originPattern, 0, len(patterns))
+	for _, p := range patterns {
+		u, _ := url.Parse(p)
+		pattern := originPattern{scheme: u.Scheme, port: u.Port(), host: strings.ToLower(u.Hostname())}
+		if suffix, ok := strings.CutPrefix(pattern.host, "*"); ok {
+			pattern.host, pattern.wildcard = suffix, true
+		}
+		allowed = append(allowed, pattern)
+	}
+
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		if strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+		for _, pattern := range allowed {
+			if pattern.matches(u) {
+				return true
+			}
+		}
+		slog.Debug("Rejected WebSocket origin", "origin", origin)
+		return false
+	}
+}
+
+// matches reports whether an origin matches the pattern
+func (p originPattern) matches(origin *url.URL) bool {
+	if !strings.EqualFold(origin.Scheme, p.scheme) || origin.Port() != p.port {
+		return false
+	}
+	host := strings.ToLower(origin.Hostname())
+	if p.wildcard {
+		return len(host) > len(p.host) && strings.HasSuffix(host, p.host)
+	}
+	return host == p.host
+}
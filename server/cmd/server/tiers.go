@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/million_grids/server/internal/config"
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// accountDirectory looks up the ages of accounts in the users table, which
+// records the first OAuth login of each user
+type accountDirectory struct{}
+
+func (accountDirectory) AccountCreated(userID string) (time.Time, bool) {
+	user, err := db.LoadUser(userID)
+	if err != nil {
+		slog.Error("Failed to load user", "user", userID, "err", err)
+		return time.Time{}, false
+	}
+	if user == nil {
+		return time.Time{}, false
+	}
+	return user.CreatedAt, true
+}
+
+// placementTiers returns the cooldown and quota tiers of the hubs
+func placementTiers(cfg []config.TierConfig) []ws.Tier {
+	tiers := make([]ws.Tier, len(cfg))
+	for i, t := range cfg {
+		tiers[i] = ws.Tier{
+			Name:          t.Name,
+			Roles:         t.Roles,
+			MinAccountAge: t.MinAccountAge,
+			Cooldown:      t.Cooldown,
+			Quota:         t.Quota,
+			QuotaWindow:   t.QuotaWindow,
+		}
+	}
+	return tiers
+}
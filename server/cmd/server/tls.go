@@ -0,0 +1,62 @@
+package main
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"github.com/million_grids/server/internal/config"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// configureTLS sets up HTTPS on the server, with certificates from Let's
+// Encrypt when autocert hosts are configured, and returns the handler for the
+// plain HTTP redirect listener
+func configureTLS(srv *http.Server, cfg *config.Config) http.Handler {
+	redirect := redirectHandler(cfg.Listen)
+	if len(cfg.TLS.AutocertHosts) == 0 {
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		return redirect
+	}
+
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.TLS.AutocertHosts...),
+		Cache:      autocert.DirCache(cfg.TLS.AutocertCacheDir),
+		Email:      cfg.TLS.AutocertEmail,
+	}
+	srv.TLSConfig = manager.TLSConfig()
+	srv.TLSConfig.MinVersion = tls.VersionTLS12
+
+	// Answers HTTP-01 challenges and redirects everything else
+	return manager.HTTPHandler(redirect)
+}
+
+// serve serves on the listener, over HTTPS when TLS is enabled
+func serve(srv *http.Server, ln net.Listener, cfg config.TLSConfig) error {
+	switch {
+	case cfg.CertFile != "":
+		return srv.ServeTLS(ln, cfg.CertFile, cfg.KeyFile)
+	case len(cfg.AutocertHosts) > 0:
+		// Certificates come from the autocert manager in srv.TLSConfig
+		return srv.ServeTLS(ln, "", "")
+	default:
+		return srv.Serve(ln)
+	}
+}
+
+// redirectHandler redirects plain HTTP requests to the same URL over HTTPS on
+// the port of the listen address
+func redirectHandler(listen string) http.Handler {
+	_, port, _ := net.SplitHostPort(listen)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
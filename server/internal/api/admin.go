@@ -0,0 +1,687 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/million_grids/server/internal/audit"
+	"github.com/million_grids/server/internal/auth"
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/metrics"
+	"github.com/million_grids/server/internal/model"
+	"github.com/million_grids/server/internal/snapshot"
+	"github.com/million_grids/server/internal/webhook"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// Actor recorded for changes made through the admin API
+const adminActor = "admin"
+
+// Longest announcement text in characters
+const maxAnnouncementLength = 500
+
+// AdminOptions configures the admin API
+type AdminOptions struct {
+	// Bearer token required by every request (empty disables the admin API)
+	Token string
+
+	// Hashes the IPs anonymous changes are attributed to (nil when they are stored as is)
+	Anonymizer *ws.IPAnonymizer
+
+	// Delivers events to the registered webhooks
+	Webhooks *webhook.Dispatcher
+
+	// Archives canvases before they are reset (nil when snapshots are disabled)
+	Snapshots *snapshot.Snapshotter
+
+	// Records the admin requests changing anything (nil when the audit log is disabled)
+	Audit *audit.Log
+
+	// Active API keys of bot clients, which keys created and revoked update
+	// (nil when there is no database to keep them)
+	APIKeys *auth.Keys
+}
+
+// adminHandler serves the moderation endpoints under /admin
+type adminHandler struct {
+	canvases *ws.Canvases
+	token    string
+
+	// Hashes the IPs anonymous changes are attributed to (nil when they are stored as is)
+	anonymizer *ws.IPAnonymizer
+
+	// Hashes the client IPs in the stats (never nil)
+	statsIPs *ws.IPAnonymizer
+
+	webhooks *webhook.Dispatcher
+
+	// Archives canvases before they are reset (nil when snapshots are disabled)
+	snapshots *snapshot.Snapshotter
+
+	// Records the admin requests changing anything (nil when the audit log is disabled)
+	audit *audit.Log
+
+	apiKeys *auth.Keys
+}
+
+// RegisterAdminRoutes adds the admin API handlers to the mux. Every request
+// must carry "Authorization: Bearer <token>"; an empty token disables the API.
+func RegisterAdminRoutes(mux *http.ServeMux, canvases *ws.Canvases, opts AdminOptions) {
+	if opts.Token == "" {
+		return
+	}
+	h := &adminHandler{canvases: canvases, token: opts.Token, anonymizer: opts.Anonymizer, statsIPs: statsAnonymizer(opts.Anonymizer), webhooks: opts.Webhooks, snapshots: opts.Snapshots, audit: opts.Audit, apiKeys: opts.APIKeys}
+
+	mux.HandleFunc("PUT /admin/cell/{x}/{y}", h.requireAuth(h.audited("set_cell", h.handleSetCell)))
+	mux.HandleFunc("DELETE /admin/cell/{x}/{y}", h.requireAuth(h.audited("clear_cell", h.handleClearCell)))
+	mux.HandleFunc("POST /admin/wipe", h.requireAuth(h.audited("wipe", h.handleWipe)))
+	mux.HandleFunc("POST /admin/import", h.requireAuth(h.audited("import", h.handleImport)))
+	mux.HandleFunc("POST /admin/reset", h.requireAuth(h.audited("reset", h.handleReset)))
+	mux.HandleFunc("POST /admin/rollback", h.requireAuth(h.audited("rollback", h.handleRollback)))
+	mux.HandleFunc("POST /admin/revert", h.requireAuth(h.audited("revert", h.handleRevert)))
+	mux.HandleFunc("POST /admin/rebuild", h.requireAuth(h.audited("rebuild_pixels", h.handleRebuildPixels)))
+	mux.HandleFunc("GET /admin/events", h.requireAuth(h.handleListCanvasEvents))
+	mux.HandleFunc("GET /admin/bans", h.requireAuth(h.handleListBans))
+	mux.HandleFunc("POST /admin/bans", h.requireAuth(h.audited("ban", h.handleBan)))
+	mux.HandleFunc("DELETE /admin/bans/{target...}", h.requireAuth(h.audited("unban", h.handleUnban)))
+	mux.HandleFunc("GET /admin/shadow-bans", h.requireAuth(h.handleListShadowBans))
+	mux.HandleFunc("POST /admin/shadow-bans", h.requireAuth(h.audited("shadow_ban", h.handleShadowBan)))
+	mux.HandleFunc("DELETE /admin/shadow-bans/{target...}", h.requireAuth(h.audited("shadow_unban", h.handleShadowUnban)))
+	mux.HandleFunc("GET /admin/api-keys", h.requireAuth(h.handleListAPIKeys))
+	mux.HandleFunc("POST /admin/api-keys", h.requireAuth(h.audited("create_api_key", h.handleCreateAPIKey)))
+	mux.HandleFunc("DELETE /admin/api-keys/{id}", h.requireAuth(h.audited("revoke_api_key", h.handleRevokeAPIKey)))
+	mux.HandleFunc("GET /admin/quarantine", h.requireAuth(h.handleListQuarantine))
+	mux.HandleFunc("DELETE /admin/quarantine/{actor...}", h.requireAuth(h.audited("release_quarantine", h.handleReleaseQuarantine)))
+	mux.HandleFunc("POST /admin/announce", h.requireAuth(h.audited("announce", h.handleAnnounce)))
+	mux.HandleFunc("GET /admin/palette", h.requireAuth(h.handleGetPalette))
+	mux.HandleFunc("PUT /admin/palette", h.requireAuth(h.audited("set_palette", h.handleSetPalette)))
+	mux.HandleFunc("GET /admin/locks", h.requireAuth(h.handleListLocks))
+	mux.HandleFunc("POST /admin/locks", h.requireAuth(h.audited("lock", h.handleLock)))
+	mux.HandleFunc("DELETE /admin/locks/{id}", h.requireAuth(h.audited("unlock", h.handleUnlock)))
+	mux.HandleFunc("GET /admin/claims", h.requireAuth(h.handleListClaims))
+	mux.HandleFunc("POST /admin/claims", h.requireAuth(h.audited("grant_claim", h.handleGrantClaim)))
+	mux.HandleFunc("DELETE /admin/claims/{id}", h.requireAuth(h.audited("revoke_claim", h.handleRevokeClaim)))
+	mux.HandleFunc("GET /admin/reports", h.requireAuth(h.handleListReports))
+	mux.HandleFunc("GET /admin/reports/{id}", h.requireAuth(h.handleReportDetail))
+	mux.HandleFunc("PUT /admin/reports/{id}", h.requireAuth(h.audited("set_report_status", h.handleSetReportStatus)))
+	mux.HandleFunc("DELETE /admin/templates/{id}", h.requireAuth(h.audited("remove_template", h.handleRemoveTemplate)))
+	mux.HandleFunc("GET /admin/readonly", h.requireAuth(h.handleGetReadOnly))
+	mux.HandleFunc("PUT /admin/readonly", h.requireAuth(h.audited("set_readonly", h.handleSetReadOnly)))
+	mux.HandleFunc("GET /admin/stats", h.requireAuth(h.handleStats))
+	mux.HandleFunc("GET /admin/clients", h.requireAuth(h.handleClients))
+	mux.HandleFunc("GET /admin/rooms", h.requireAuth(h.handleRooms))
+	mux.HandleFunc("GET /admin/slow-clients", h.requireAuth(h.handleSlowClients))
+	mux.HandleFunc("GET /admin/broadcasts", h.requireAuth(h.handleBroadcasts))
+	mux.HandleFunc("GET /admin/metrics", h.requireAuth(h.handleMetrics))
+	mux.HandleFunc("GET /admin/writes", h.requireAuth(h.handleWrites))
+	mux.HandleFunc("POST /admin/erase", h.requireAuth(h.audited("erase", h.handleErase)))
+	mux.HandleFunc("GET /admin/webhooks", h.requireAuth(h.handleListWebhooks))
+	mux.HandleFunc("POST /admin/webhooks", h.requireAuth(h.audited("add_webhook", h.handleAddWebhook)))
+	mux.HandleFunc("DELETE /admin/webhooks/{id}", h.requireAuth(h.audited("remove_webhook", h.handleRemoveWebhook)))
+	mux.HandleFunc("GET /admin/audit", h.requireAuth(h.handleAuditLog))
+	registerDebugRoutes(mux, "/admin/debug", canvases, h.requireAuth)
+}
+
+// requireAuth rejects requests without the admin token in the Authorization
+// header; unlike other routes, it is not accepted in the query string
+func (h *adminHandler) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := auth.BearerToken(r)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleSetCell activates a cell with the color from the body {"color": "#RRGGBB"}
+func (h *adminHandler) handleSetCell(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	x, y, ok := cellCoords(w, r, hub.Grid())
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Color string `json:"color"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	color, err := model.ParseColor(body.Color)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "color must be #RRGGBB")
+		return
+	}
+	if !model.IsValidColor(color) {
+		writeError(w, http.StatusBadRequest, "color is not in the palette")
+		return
+	}
+
+	changed, err := hub.SetCells(r.Context(), []model.Pixel{{X: x, Y: y, Active: true, Color: color}}, adminActor)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "failed to save the changes, try again")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"changed": len(changed)})
+}
+
+// handleClearCell deactivates a cell
+func (h *adminHandler) handleClearCell(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	x, y, ok := cellCoords(w, r, hub.Grid())
+	if !ok {
+		return
+	}
+
+	changed, err := hub.SetCells(r.Context(), []model.Pixel{{X: x, Y: y, Active: false, Color: model.White}}, adminActor)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "failed to save the changes, try again")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"changed": len(changed)})
+}
+
+// handleWipe clears every cell in the region from the body {"x1", "y1", "x2", "y2"}
+func (h *adminHandler) handleWipe(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	var region ws.Region
+	if !decodeBody(w, r, &region) {
+		return
+	}
+	region = region.Clamp(hub.Grid().Width(), hub.Grid().Height())
+	if region.Empty() {
+		writeError(w, http.StatusBadRequest, "region is empty")
+		return
+	}
+
+	cleared, err := hub.ClearRegion(r.Context(), region, adminActor)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "failed to save the changes, try again")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"cleared": cleared})
+}
+
+// handleReset archives the canvas under the name from the body
+// {"archive": "spring-2026"}, clears every cell and tells the clients to
+// reload. The cells are archived in the pixel_archives table and, when
+// snapshots are enabled, as a named snapshot. The history is kept, and the
+// reset is recorded in the event stream.
+func (h *adminHandler) handleReset(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	var body struct {
+		Archive string `json:"archive"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if !snapshot.ValidArchiveName.MatchString(body.Archive) {
+		writeError(w, http.StatusBadRequest, "archive must be up to 64 letters, digits, _ and -")
+		return
+	}
+
+	// Changes made meanwhile would survive the reset in the database, or on
+	// the grid after the clients dropped their copy
+	resume := hub.PausePlacements("the canvas is being reset")
+	defer resume()
+
+	if h.snapshots != nil {
+		if h.snapshots.Archived(hub.Canvas(), body.Archive) {
+			writeError(w, http.StatusConflict, "archive already exists")
+			return
+		}
+		if err := h.snapshots.Archive(hub, body.Archive); err != nil {
+			slog.Error("Failed to archive canvas before reset", "canvas", hub.Canvas(), "err", err)
+			writeError(w, http.StatusInternalServerError, "failed to archive canvas")
+			return
+		}
+	}
+
+	// Changes still waiting in the write queue must be written before they can be archived
+	if err := db.FlushPending(); err != nil {
+		slog.Error("Failed to flush pending writes before reset", "err", err)
+		writeError(w, http.StatusServiceUnavailable, "failed to write pending changes, try again")
+		return
+	}
+	cleared := hub.Grid().ActiveCount()
+	archived, err := db.ResetCanvas(hub.Canvas(), body.Archive)
+	if err != nil {
+		slog.Error("Failed to reset canvas", "canvas", hub.Canvas(), "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to reset canvas")
+		return
+	}
+	hub.Reset(body.Archive)
+
+	// Restarts must not restore the cells from an older snapshot
+	if h.snapshots != nil {
+		if err := h.snapshots.Replace(hub); err != nil {
+			slog.Error("Failed to snapshot canvas after reset", "canvas", hub.Canvas(), "err", err)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"archive": body.Archive, "cleared": cleared, "archived": archived})
+}
+
+// handleRollback reverts the changes made by an actor (IP or user ID) within a
+// time window, from the body {"actor": "...", "from": RFC3339, "to": RFC3339}.
+// An IP matches its changes whether they were recorded before or after IP
+// hashing was enabled.
+func (h *adminHandler) handleRollback(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	var body struct {
+		Actor string    `json:"actor"`
+		From  time.Time `json:"from"`
+		To    time.Time `json:"to"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.Actor == "" || body.To.Before(body.From) {
+		writeError(w, http.StatusBadRequest, "actor and a valid time range are required")
+		return
+	}
+
+	// Make sure the history includes changes still waiting in the write queue
+	if err := db.FlushPending(); err != nil {
+		slog.Error("Failed to flush pending writes before rollback", "err", err)
+		writeError(w, http.StatusServiceUnavailable, "failed to write pending changes, try again")
+		return
+	}
+
+	states, err := db.RollbackStates(hub.Canvas(), h.actorIDs(body.Actor), body.From, body.To)
+	if err != nil {
+		slog.Error("Failed to compute rollback", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to compute rollback")
+		return
+	}
+
+	changed, err := hub.SetCells(r.Context(), states, adminActor)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "failed to save the changes, try again")
+		return
+	}
+	slog.Info("Rolled back cells", "canvas", hub.Canvas(), "reverted", len(changed), "actor", body.Actor, "from", body.From, "to", body.To)
+	writeJSON(w, http.StatusOK, map[string]int{"cells": len(states), "reverted": len(changed)})
+}
+
+// handleErase removes the attribution of every change made from an IP or by a
+// user, on all canvases, for data deletion requests. The body is {"ip": "1.2.3.4"}
+// or {"user": "<user ID>"}. An IP matches its changes whether they were
+// recorded before or after IP hashing was enabled.
+func (h *adminHandler) handleErase(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		IP   string `json:"ip"`
+		User string `json:"user"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	var actors []string
+	switch {
+	case body.IP != "" && body.User == "":
+		if net.ParseIP(body.IP) == nil {
+			writeError(w, http.StatusBadRequest, "ip must be an IP address")
+			return
+		}
+		actors = h.actorIDs(body.IP)
+	case body.User != "" && body.IP == "":
+		actors = append(actors, body.User)
+	default:
+		writeError(w, http.StatusBadRequest, "exactly one of ip and user is required")
+		return
+	}
+
+	// Changes still waiting in the write queue must be written before they can be erased
+	if err := db.FlushPending(); err != nil {
+		slog.Error("Failed to flush pending writes before erasure", "err", err)
+		writeError(w, http.StatusServiceUnavailable, "failed to write pending changes, try again")
+		return
+	}
+
+	erased, err := db.EraseActors(actors)
+	if err != nil {
+		slog.Error("Failed to erase attribution", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to erase attribution")
+		return
+	}
+	slog.Info("Erased attribution", "pixels", erased.Pixels, "history", erased.History, "contributors", erased.Contributors)
+	writeJSON(w, http.StatusOK, erased)
+}
+
+// actorIDs returns the IDs the changes of an actor may be recorded under: an
+// IP is recorded as its hash once IP hashing is enabled, and as itself before
+func (h *adminHandler) actorIDs(actor string) []string {
+	actors := []string{actor}
+	if net.ParseIP(actor) != nil {
+		if hashed := h.anonymizer.Actor(actor); hashed != actor {
+			actors = append(actors, hashed)
+		}
+	}
+	return actors
+}
+
+// WritesResponse describes the database write queue and the changes it gave up on
+type WritesResponse struct {
+	Stats       db.WriteQueueStats `json:"stats"`
+	DeadLetters []db.DeadLetter    `json:"dead_letters"`
+}
+
+// handleClients lists the clients connected to the canvas with their outbound traffic
+func (h *adminHandler) handleClients(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]ws.ClientStats{"clients": hub.ClientStats()})
+}
+
+// handleRooms lists the rooms of the canvas with their members and traffic
+func (h *adminHandler) handleRooms(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]ws.RoomStats{"rooms": hub.Rooms()})
+}
+
+// handleSlowClients reports the canvas's slow client policy and how often it applied
+func (h *adminHandler) handleSlowClients(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, hub.SlowClients())
+}
+
+// handleBroadcasts summarizes the latency and fan-out of the canvas's cell
+// updates and the send queues of its clients
+func (h *adminHandler) handleBroadcasts(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, hub.BroadcastStats())
+}
+
+// handleMetrics serves the Prometheus metrics of every canvas
+func (h *adminHandler) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	metrics.Handler().ServeHTTP(w, r)
+}
+
+// handleWrites reports the state of the write queue, including the dead-letter buffer
+func (h *adminHandler) handleWrites(w http.ResponseWriter, r *http.Request) {
+	queue := db.Queue()
+	if queue == nil {
+		writeError(w, http.StatusNotFound, "no database configured")
+		return
+	}
+	deadLetters := queue.DeadLetters()
+	if deadLetters == nil {
+		deadLetters = []db.DeadLetter{}
+	}
+	writeJSON(w, http.StatusOK, WritesResponse{Stats: queue.Stats(), DeadLetters: deadLetters})
+}
+
+// handleListBans returns the persisted bans
+func (h *adminHandler) handleListBans(w http.ResponseWriter, r *http.Request) {
+	bans, err := db.LoadBans()
+	if err != nil {
+		slog.Error("Failed to load bans", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to load bans")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]db.Ban{"bans": bans})
+}
+
+// handleBan bans the IP or CIDR range from the body {"target": "1.2.3.4", "reason": "..."}
+// from every canvas and disconnects matching clients
+func (h *adminHandler) handleBan(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Target string `json:"target"`
+		Reason string `json:"reason"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	network, err := ws.ParseBanTarget(body.Target)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "target must be an IP address or CIDR range")
+		return
+	}
+
+	ban := db.Ban{Target: network.String(), Reason: body.Reason, CreatedAt: time.Now()}
+	if err := db.SaveBan(ban); err != nil {
+		slog.Error("Failed to save ban", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to save ban")
+		return
+	}
+
+	h.canvases.Ban(network)
+	writeJSON(w, http.StatusOK, ban)
+}
+
+// handleUnban removes an IP or CIDR range from the ban list
+func (h *adminHandler) handleUnban(w http.ResponseWriter, r *http.Request) {
+	network, err := ws.ParseBanTarget(r.PathValue("target"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "target must be an IP address or CIDR range")
+		return
+	}
+
+	if err := db.DeleteBan(network.String()); err != nil {
+		slog.Error("Failed to delete ban", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete ban")
+		return
+	}
+
+	h.canvases.Unban(network)
+	writeJSON(w, http.StatusOK, map[string]string{"unbanned": network.String()})
+}
+
+// handleListShadowBans returns the persisted shadow bans
+func (h *adminHandler) handleListShadowBans(w http.ResponseWriter, r *http.Request) {
+	bans, err := db.LoadShadowBans()
+	if err != nil {
+		slog.Error("Failed to load shadow bans", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to load shadow bans")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]db.ShadowBan{"shadow_bans": bans})
+}
+
+// handleShadowBan shadow-bans the IP, CIDR range or user ID from the body
+// {"target": "1.2.3.4", "reason": "..."} on every canvas. Their clients stay
+// connected and see their own paints, nobody else does.
+func (h *adminHandler) handleShadowBan(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Target string `json:"target"`
+		Reason string `json:"reason"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	target := ws.ShadowBanTarget(strings.TrimSpace(body.Target))
+	if target == "" {
+		writeError(w, http.StatusBadRequest, "target must be an IP address, CIDR range or user ID")
+		return
+	}
+
+	ban := db.ShadowBan{Target: target, Reason: body.Reason, CreatedAt: time.Now()}
+	if err := db.SaveShadowBan(ban); err != nil {
+		slog.Error("Failed to save shadow ban", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to save shadow ban")
+		return
+	}
+
+	h.canvases.ShadowBan(target)
+	writeJSON(w, http.StatusOK, ban)
+}
+
+// handleShadowUnban lifts the shadow ban of an IP, CIDR range or user ID
+func (h *adminHandler) handleShadowUnban(w http.ResponseWriter, r *http.Request) {
+	target := ws.ShadowBanTarget(r.PathValue("target"))
+	if err := db.DeleteShadowBan(target); err != nil {
+		slog.Error("Failed to delete shadow ban", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete shadow ban")
+		return
+	}
+
+	h.canvases.ShadowUnban(target)
+	writeJSON(w, http.StatusOK, map[string]string{"unbanned": target})
+}
+
+// handleGetPalette returns the active palette
+func (h *adminHandler) handleGetPalette(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, currentPalette())
+}
+
+// handleSetPalette stores a new palette version from the body {"colors": ["#RRGGBB", ...]},
+// activates it and sends it to every client
+func (h *adminHandler) handleSetPalette(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Colors []string `json:"colors"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	colors, err := model.ParsePalette(body.Colors)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	version := model.PaletteVersion() + 1
+	if err := db.SavePalette(db.NewPalette(version, colors)); err != nil {
+		slog.Error("Failed to save palette", "version", version, "err", err)
+		writeError(w, http.StatusConflict, "failed to save palette, try again")
+		return
+	}
+
+	model.ActivatePalette(version, colors)
+	h.canvases.BroadcastPalette()
+	slog.Info("Palette changed", "version", version, "colors", len(colors))
+	writeJSON(w, http.StatusOK, currentPalette())
+}
+
+// handleAnnounce pushes an announcement {"text", "severity", "countdown"} to
+// the clients of the canvas named by ?canvas=, or of every canvas. Severity
+// defaults to "info", countdown is the optional number of seconds until the
+// announced event.
+func (h *adminHandler) handleAnnounce(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Text      string `json:"text"`
+		Severity  string `json:"severity"`
+		Countdown int    `json:"countdown"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	body.Text = strings.TrimSpace(body.Text)
+	if body.Text == "" || utf8.RuneCountInString(body.Text) > maxAnnouncementLength {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("text must be 1 to %d characters", maxAnnouncementLength))
+		return
+	}
+	if body.Severity == "" {
+		body.Severity = "info"
+	}
+	if !ws.ValidSeverity(body.Severity) {
+		writeError(w, http.StatusBadRequest, "severity must be one of "+strings.Join(ws.Severities, ", "))
+		return
+	}
+	if body.Countdown < 0 {
+		writeError(w, http.StatusBadRequest, "countdown must not be negative")
+		return
+	}
+
+	msg := ws.AnnounceMessage{
+		Type:      "announce",
+		Text:      body.Text,
+		Severity:  body.Severity,
+		Countdown: body.Countdown,
+		At:        time.Now().UTC(),
+	}
+	if r.URL.Query().Has("canvas") {
+		hub, ok := canvasHub(w, r, h.canvases)
+		if !ok {
+			return
+		}
+		hub.Announce(msg)
+	} else {
+		h.canvases.Announce(msg)
+	}
+	writeJSON(w, http.StatusOK, msg)
+}
+
+// ReadOnlyResponse describes whether a canvas is frozen, and why
+type ReadOnlyResponse struct {
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// handleGetReadOnly reports whether the canvas is read-only, and why
+func (h *adminHandler) handleGetReadOnly(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	enabled, reason := hub.ReadOnly()
+	writeJSON(w, http.StatusOK, ReadOnlyResponse{Enabled: enabled, Reason: reason})
+}
+
+// handleSetReadOnly freezes or thaws the canvas from the body
+// {"enabled": true, "reason": "the event is over"}, the reason being optional
+func (h *adminHandler) handleSetReadOnly(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	var body ReadOnlyResponse
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	body.Reason = strings.TrimSpace(body.Reason)
+	if utf8.RuneCountInString(body.Reason) > maxAnnouncementLength {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("reason must be at most %d characters", maxAnnouncementLength))
+		return
+	}
+
+	hub.SetReadOnly(body.Enabled, body.Reason)
+	enabled, reason := hub.ReadOnly()
+	writeJSON(w, http.StatusOK, ReadOnlyResponse{Enabled: enabled, Reason: reason})
+}
+
+// decodeBody parses a JSON request body, writing an error response and
+// returning false if it is malformed
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return false
+	}
+	return true
+}
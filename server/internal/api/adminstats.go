@@ -0,0 +1,88 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// AdminStatsResponse describes the internals of a canvas's hub, the clients
+// connected to it and the database write queue, for an ops dashboard
+type AdminStatsResponse struct {
+	Canvas string    `json:"canvas"`
+	At     time.Time `json:"at"`
+
+	// Connected clients by connection ID, their IPs hashed
+	Clients []ws.ClientStats `json:"clients"`
+
+	Grid        GridTotals         `json:"grid"`
+	Broadcasts  ws.BroadcastStats  `json:"broadcasts"`
+	SlowClients ws.SlowClientStats `json:"slow_clients"`
+
+	// Database write queue (nil without a database)
+	WriteQueue *db.WriteQueueStats `json:"write_queue,omitempty"`
+}
+
+// GridTotals counts the cells of a canvas and the changes made to it
+type GridTotals struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	Active int `json:"active"`
+
+	// Cell changes since the start of the canvas's history (or of the server
+	// without a database), and in the last hour across all instances
+	Placements         int64 `json:"placements"`
+	PlacementsLastHour int   `json:"placements_last_hour"`
+}
+
+// handleStats returns the AdminStatsResponse of the canvas. Unlike
+// /admin/clients, it never exposes the IPs of the clients: they are hashed
+// like the IPs changes are attributed to, or with a key of the process's own
+// when those are stored as is, so a client can be followed across refreshes
+// without being identified.
+func (h *adminHandler) handleStats(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+
+	clients := hub.ClientStats()
+	for i := range clients {
+		clients[i].IP = h.statsIPs.Actor(clients[i].IP)
+	}
+	now := time.Now()
+	stats := AdminStatsResponse{
+		Canvas:  hub.Canvas(),
+		At:      now,
+		Clients: clients,
+		Grid: GridTotals{
+			Width:              hub.Grid().Width(),
+			Height:             hub.Grid().Height(),
+			Active:             hub.Grid().ActiveCount(),
+			Placements:         hub.Placements(),
+			PlacementsLastHour: hub.Activity().Since(time.Hour, now),
+		},
+		Broadcasts:  hub.BroadcastStats(),
+		SlowClients: hub.SlowClients(),
+	}
+	if queue := db.Queue(); queue != nil {
+		queueStats := queue.Stats()
+		stats.WriteQueue = &queueStats
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// statsAnonymizer returns the anonymizer hashing the client IPs in the stats:
+// the one of the attributions when they are hashed, else one with a random salt
+func statsAnonymizer(anonymizer *ws.IPAnonymizer) *ws.IPAnonymizer {
+	if anonymizer != nil {
+		return anonymizer
+	}
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	return ws.NewIPAnonymizer(hex.EncodeToString(salt))
+}
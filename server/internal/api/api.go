@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/million_grids/server/internal/auth"
+	"github.com/million_grids/server/internal/readmodel"
+	"github.com/million_grids/server/internal/snapshot"
+	"github.com/million_grids/server/internal/timelapse"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// Options configures the public REST API
+type Options struct {
+	// Grid snapshots time travel starts from (nil when snapshots are disabled)
+	Snapshots *snapshot.Store
+
+	// Validates the tokens of painting requests (nil when authentication is
+	// disabled), and whether anonymous requests are refused
+	Validator    *auth.Validator
+	AuthRequired bool
+
+	// Sustained painting requests per second per IP (0 disables) and burst
+	PaintRate  float64
+	PaintBurst int
+
+	// Read models the statistics are served from (nil when they are disabled)
+	ReadModels *readmodel.Projector
+}
+
+// handler serves the public REST API for the canvases
+type handler struct {
+	canvases   *ws.Canvases
+	timelapses *timelapse.Manager
+
+	// Grid snapshots time travel starts from (nil when snapshots are disabled)
+	snapshots *snapshot.Store
+
+	// Token validation (nil when authentication is disabled)
+	validator    *auth.Validator
+	authRequired bool
+
+	// Per-IP limit on painting requests, and per-key limits of API keys with their own rate
+	paintLimits *ipLimiter
+	keyLimits   *keyLimiter
+
+	// Rendered map tiles
+	tiles *tileCache
+
+	// Read models the statistics are served from (nil when they are disabled)
+	readModels *readmodel.Projector
+
+	// When the API was set up, for the reported uptime
+	started time.Time
+}
+
+// RegisterRoutes adds the REST API handlers to the mux. Endpoints that act on
+// a canvas take it from ?canvas= (default canvas when absent).
+func RegisterRoutes(mux *http.ServeMux, canvases *ws.Canvases, opts Options) {
+	h := &handler{
+		canvases:     canvases,
+		snapshots:    opts.Snapshots,
+		validator:    opts.Validator,
+		authRequired: opts.AuthRequired,
+		paintLimits:  newIPLimiter(opts.PaintRate, opts.PaintBurst),
+		keyLimits:    newKeyLimiter(),
+		tiles:        newTileCache(tileCacheSize),
+		readModels:   opts.ReadModels,
+		started:      time.Now(),
+		// Rendering replays history from the database, so only a couple run at once
+		timelapses: timelapse.NewManager(2, 16, 10*time.Minute),
+	}
+
+	mux.HandleFunc("GET /api/canvases", h.handleListCanvases)
+	mux.HandleFunc("GET /api/palette", h.handlePalette)
+	mux.HandleFunc("GET /api/cell/{x}/{y}", h.handleCell)
+	mux.HandleFunc("GET /api/cell/{x}/{y}/history", h.handleCellHistory)
+	mux.HandleFunc("PUT /api/cell/{x}/{y}/meta", h.handleSetCellMeta)
+	mux.HandleFunc("DELETE /api/cell/{x}/{y}/meta", h.handleRemoveCellMeta)
+	mux.HandleFunc("POST /api/pixel", h.handlePaintPixel)
+	mux.HandleFunc("GET /api/captcha", h.handleCaptcha)
+	mux.HandleFunc("GET /api/tier", h.handleTier)
+	mux.HandleFunc("POST /api/draw", h.handleDraw)
+	mux.HandleFunc("GET /api/draw/{id}", h.handleDrawStatus)
+	mux.HandleFunc("DELETE /api/draw/{id}", h.handleCancelDraw)
+	mux.HandleFunc("GET /api/grid", h.handleGrid)
+	mux.HandleFunc("GET /api/region", h.handleRegion)
+	mux.HandleFunc("GET /api/locks", h.handleLocks)
+	mux.HandleFunc("POST /api/reports", h.handleReport)
+	mux.HandleFunc("GET /api/claims", h.handleClaims)
+	mux.HandleFunc("POST /api/claims", h.handleClaim)
+	mux.HandleFunc("DELETE /api/claims/{id}", h.handleReleaseClaim)
+	mux.HandleFunc("GET /api/templates", h.handleTemplates)
+	mux.HandleFunc("POST /api/templates", h.handleUploadTemplate)
+	mux.HandleFunc("GET /api/templates/{id}", h.handleTemplate)
+	mux.HandleFunc("GET /api/templates/{id}/image", h.handleTemplateImage)
+	mux.HandleFunc("GET /api/templates/{id}/diff", h.handleTemplateDiff)
+	mux.HandleFunc("GET /api/stats", h.handleStats)
+	mux.HandleFunc("GET /api/leaderboard", h.handleLeaderboard)
+	mux.HandleFunc("GET /api/leaderboard/teams", h.handleTeamLeaderboard)
+	mux.HandleFunc("GET /api/teams", h.handleTeams)
+	mux.HandleFunc("GET /api/heatmap", h.handleHeatmap)
+	mux.HandleFunc("GET /events", h.handleEvents)
+	mux.HandleFunc("GET /snapshot.png", h.handleSnapshot)
+	mux.HandleFunc("GET /tiles/{z}/{x}/{y}", h.handleTile)
+	mux.HandleFunc("POST /timelapse", h.handleCreateTimelapse)
+	mux.HandleFunc("GET /timelapse/{id}", h.handleTimelapseStatus)
+	mux.HandleFunc("GET /timelapse/{id}/gif", h.handleTimelapseGIF)
+}
+
+// readModel returns the read model of a canvas, or nil when read models are disabled
+func (h *handler) readModel(canvas string) *readmodel.Model {
+	if h.readModels == nil {
+		return nil
+	}
+	return h.readModels.Model(canvas)
+}
+
+// canvasHub looks up the hub of the canvas named by ?canvas= (the default
+// canvas when absent), writing an error response and returning false if there
+// is no such canvas
+func canvasHub(w http.ResponseWriter, r *http.Request, canvases *ws.Canvases) (*ws.Hub, bool) {
+	hub, ok := canvases.Get(r.URL.Query().Get("canvas"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown canvas")
+		return nil, false
+	}
+	return hub, true
+}
+
+// Proxies whose forwarding headers ClientIP believes
+var trustedProxies ws.TrustedProxies
+
+// SetTrustedProxies sets the proxies whose X-Forwarded-For and X-Real-IP
+// headers ClientIP believes
+func SetTrustedProxies(proxies ws.TrustedProxies) {
+	trustedProxies = proxies
+}
+
+// ClientIP returns the address of the client that sent the request, as
+// reported in X-Forwarded-For or X-Real-IP when it came through a trusted proxy
+func ClientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return trustedProxies.ClientIP(ip, r.Header.Values("X-Forwarded-For"), r.Header.Get("X-Real-IP"))
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Failed to write JSON response", "err", err)
+	}
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// queryInt parses an integer query parameter, returning def if it is absent
+func queryInt(r *http.Request, key string, def int) (int, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/million_grids/server/internal/auth"
+	"github.com/million_grids/server/internal/db"
+)
+
+// Characters of a new API key kept to recognize it by
+const apiKeyPrefixLength = 12
+
+// APIKeyRequest creates an API key for a bot client. Rate and burst limit its
+// requests per second instead of the per-IP limits (0 keeps those).
+type APIKeyRequest struct {
+	Name  string  `json:"name"`
+	Role  string  `json:"role,omitempty"`
+	Team  string  `json:"team,omitempty"`
+	Rate  float64 `json:"rate,omitempty"`
+	Burst int     `json:"burst,omitempty"`
+}
+
+// APIKeyResponse is a created API key, the only time the key itself is shown
+type APIKeyResponse struct {
+	db.APIKey
+	Key string `json:"key"`
+}
+
+// ActivateAPIKey makes a stored API key authenticate, unless it is revoked
+func ActivateAPIKey(keys *auth.Keys, key db.APIKey) {
+	if key.RevokedAt != nil {
+		return
+	}
+	keys.Add(key.Hash, auth.APIKey{ID: key.ID, Name: key.Name, Role: key.Role, Team: key.Team, Rate: key.Rate, Burst: key.Burst})
+}
+
+// handleListAPIKeys returns every API key, revoked ones included, without the keys themselves
+func (h *adminHandler) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if !h.apiKeysEnabled(w) {
+		return
+	}
+	keys, err := db.LoadAPIKeys()
+	if err != nil {
+		slog.Error("Failed to load api keys", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to load api keys")
+		return
+	}
+	if keys == nil {
+		keys = []db.APIKey{}
+	}
+	writeJSON(w, http.StatusOK, map[string][]db.APIKey{"api_keys": keys})
+}
+
+// handleCreateAPIKey creates an API key from an APIKeyRequest, replying 201
+// with the key. Clients present it as "Authorization: Bearer" or "X-API-Key",
+// and their changes are attributed to "bot:<id>".
+func (h *adminHandler) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !h.apiKeysEnabled(w) {
+		return
+	}
+	var body APIKeyRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" || len(body.Name) > 64 {
+		writeError(w, http.StatusBadRequest, "a name of up to 64 bytes is required")
+		return
+	}
+	if body.Role != "" && body.Role != "moderator" && body.Role != "admin" {
+		writeError(w, http.StatusBadRequest, "role must be moderator or admin")
+		return
+	}
+	if len(body.Team) > 32 {
+		writeError(w, http.StatusBadRequest, "team is too long")
+		return
+	}
+	if body.Rate < 0 || (body.Rate > 0 && body.Burst < 1) || (body.Rate == 0 && body.Burst != 0) {
+		writeError(w, http.StatusBadRequest, "rate must not be negative, and burst at least 1 with a rate")
+		return
+	}
+
+	secret, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		slog.Error("Failed to generate api key", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to create api key")
+		return
+	}
+	key := db.APIKey{
+		Name:      body.Name,
+		Hash:      hash,
+		Prefix:    secret[:apiKeyPrefixLength],
+		Role:      body.Role,
+		Team:      body.Team,
+		Rate:      body.Rate,
+		Burst:     body.Burst,
+		CreatedAt: time.Now(),
+	}
+	if err := db.SaveAPIKey(&key); err != nil {
+		slog.Error("Failed to save api key", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to create api key")
+		return
+	}
+
+	ActivateAPIKey(h.apiKeys, key)
+	slog.Info("API key created", "id", key.ID, "name", key.Name)
+	writeJSON(w, http.StatusCreated, APIKeyResponse{APIKey: key, Key: secret})
+}
+
+// handleRevokeAPIKey revokes an API key, which stops authenticating at once,
+// and disconnects the clients using it
+func (h *adminHandler) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !h.apiKeysEnabled(w) {
+		return
+	}
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid api key id")
+		return
+	}
+	revoked, err := db.RevokeAPIKey(uint(id))
+	if err != nil {
+		slog.Error("Failed to revoke api key", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to revoke api key")
+		return
+	}
+	if !revoked {
+		writeError(w, http.StatusNotFound, "unknown api key")
+		return
+	}
+
+	key := auth.APIKey{ID: uint(id)}
+	h.apiKeys.Remove(key.ID)
+	disconnected := h.canvases.DisconnectUser(key.UserID(), "api key revoked")
+	slog.Info("API key revoked", "id", id, "disconnected", disconnected)
+	writeJSON(w, http.StatusOK, map[string]any{"revoked": id, "disconnected": disconnected})
+}
+
+// apiKeysEnabled writes a 404 response and returns false when API keys can't
+// be stored
+func (h *adminHandler) apiKeysEnabled(w http.ResponseWriter) bool {
+	if h.apiKeys == nil || db.Queue() == nil {
+		writeError(w, http.StatusNotFound, "no database configured")
+		return false
+	}
+	return true
+}
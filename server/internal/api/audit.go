@@ -0,0 +1,169 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+)
+
+const (
+	// Number of audit log entries returned when no limit is given
+	defaultAuditLimit = 100
+
+	// Maximum number of audit log entries returned per request
+	maxAuditLimit = 1000
+
+	// Largest admin request body recorded in the audit log, larger or non-JSON
+	// bodies (e.g. imported images) are recorded by size
+	maxAuditBody = 64 << 10
+)
+
+// Admin actions whose body isn't recorded, as it names the people whose
+// traces they remove
+var unrecordedBodies = map[string]bool{"erase": true}
+
+// AuditEntry is an audit log entry with its payload
+type AuditEntry struct {
+	db.AuditEntry
+	Payload json.RawMessage `json:"payload"`
+}
+
+// AuditLogResponse lists audit log entries, newest first. Pass Next as
+// ?before= to get the older ones.
+type AuditLogResponse struct {
+	Entries []AuditEntry `json:"entries"`
+	Next    uint64       `json:"next,omitempty"`
+}
+
+// adminPayload is the payload of the audit log entry of an admin request
+type adminPayload struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+
+	// JSON body of the request, or its size when it isn't recorded
+	Body      json.RawMessage `json:"body,omitempty"`
+	BodyBytes int             `json:"body_bytes,omitempty"`
+
+	// Status the request was answered with
+	Status int `json:"status"`
+}
+
+// statusRecorder remembers the status a handler answered with
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// audited records admin requests to next in the audit log as action, with
+// their body and the status they were answered with
+func (h *adminHandler) audited(action string, next http.HandlerFunc) http.HandlerFunc {
+	if h.audit == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Keep the start of the body for the log, handing the whole body on
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxAuditBody+1))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		next(recorder, r)
+
+		payload := adminPayload{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Status: recorder.status}
+		if len(body) <= maxAuditBody && json.Valid(body) && !unrecordedBodies[action] {
+			payload.Body = body
+		} else if len(body) > 0 {
+			payload.BodyBytes = len(body)
+		}
+		data, err := json.Marshal(payload)
+		if err != nil {
+			slog.Error("Failed to marshal audit admin request", "err", err)
+			return
+		}
+		h.audit.Record(db.AuditEntry{
+			Canvas:  r.URL.Query().Get("canvas"),
+			Actor:   adminActor,
+			IP:      ClientIP(r),
+			Action:  "admin." + action,
+			Payload: string(data),
+		})
+	}
+}
+
+// handleAuditLog returns the audit log entries matching ?canvas=, ?actor=,
+// ?action=, ?from= and ?to= (RFC 3339), newest first, up to ?limit= of them
+// older than ?before=
+func (h *adminHandler) handleAuditLog(w http.ResponseWriter, r *http.Request) {
+	if h.audit == nil {
+		writeError(w, http.StatusNotFound, "audit log is disabled")
+		return
+	}
+	query := r.URL.Query()
+	filter := db.AuditFilter{Canvas: query.Get("canvas"), Action: query.Get("action")}
+	if actor := query.Get("actor"); actor != "" {
+		filter.Actors = h.actorIDs(actor)
+	}
+	var err error
+	if filter.From, err = queryTime(r, "from", time.Time{}); err != nil {
+		writeError(w, http.StatusBadRequest, "from must be an RFC 3339 time")
+		return
+	}
+	if filter.To, err = queryTime(r, "to", time.Time{}); err != nil {
+		writeError(w, http.StatusBadRequest, "to must be an RFC 3339 time")
+		return
+	}
+	if before := query.Get("before"); before != "" {
+		if filter.Before, err = strconv.ParseUint(before, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid before")
+			return
+		}
+	}
+	limit, err := queryInt(r, "limit", defaultAuditLimit)
+	if err != nil || limit < 1 {
+		writeError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+	filter.Limit = min(limit, maxAuditLimit)
+
+	entries, err := db.QueryAuditLog(filter)
+	if err != nil {
+		slog.Error("Failed to query audit log", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to query audit log")
+		return
+	}
+	resp := AuditLogResponse{Entries: make([]AuditEntry, len(entries))}
+	for i, entry := range entries {
+		resp.Entries[i] = AuditEntry{AuditEntry: entry, Payload: json.RawMessage(entry.Payload)}
+	}
+	if len(entries) == filter.Limit {
+		resp.Next = entries[len(entries)-1].ID
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,26 @@
+package api
+
+import "net/http"
+
+// CanvasInfo describes one canvas served by the server
+type CanvasInfo struct {
+	Name    string `json:"name"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	Clients int    `json:"clients"`
+}
+
+// handleListCanvases returns the available canvases and their sizes, default canvas first
+func (h *handler) handleListCanvases(w http.ResponseWriter, r *http.Request) {
+	hubs := h.canvases.Hubs()
+	canvases := make([]CanvasInfo, 0, len(hubs))
+	for _, hub := range hubs {
+		canvases = append(canvases, CanvasInfo{
+			Name:    hub.Canvas(),
+			Width:   hub.Grid().Width(),
+			Height:  hub.Grid().Height(),
+			Clients: hub.ClientCount(),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string][]CanvasInfo{"canvases": canvases})
+}
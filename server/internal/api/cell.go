@@ -0,0 +1,225 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/model"
+	"github.com/million_grids/server/internal/ws"
+)
+
+const (
+	// Number of history records returned when no limit is given
+	defaultHistoryLimit = 100
+
+	// Maximum number of history records returned per request
+	maxHistoryLimit = 1000
+)
+
+// CellHistoryResponse lists the recent changes to a single cell
+type CellHistoryResponse struct {
+	X       int               `json:"x"`
+	Y       int               `json:"y"`
+	History []db.PixelHistory `json:"history"`
+}
+
+// CellResponse is the state of a cell with its metadata
+type CellResponse struct {
+	X      int         `json:"x"`
+	Y      int         `json:"y"`
+	Active bool        `json:"active"`
+	Color  model.Color `json:"color"`
+	Meta   *CellMeta   `json:"meta,omitempty"`
+
+	// Claim reserving the cell, in economy mode
+	Claim *ws.Claim `json:"claim,omitempty"`
+}
+
+// CellMeta is the text and link attached to a cell, the body of
+// PUT /api/cell/{x}/{y}/meta
+type CellMeta struct {
+	Text string `json:"text,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// handleCell returns the state of a cell, its metadata and the claim
+// reserving it, if any
+func (h *handler) handleCell(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	x, y, ok := cellCoords(w, r, hub.Grid())
+	if !ok {
+		return
+	}
+	state := hub.Grid().GetCell(x, y)
+	resp := CellResponse{X: x, Y: y, Active: state.Active, Color: state.Color}
+	if meta, ok := hub.CellMeta(x, y); ok {
+		resp.Meta = &CellMeta{Text: meta.Text, URL: meta.URL}
+	}
+	if claim, ok := hub.ClaimAt(x, y); ok {
+		resp.Claim = &claim
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleSetCellMeta attaches a short text and link to a cell. Only the
+// painter of the cell (or a moderator) may, and the metadata is removed once
+// someone else paints the cell or it is cleared. Requests count against the
+// per-IP paint rate limit.
+func (h *handler) handleSetCellMeta(w http.ResponseWriter, r *http.Request) {
+	target, ok := h.cellMetaRequest(w, r)
+	if !ok {
+		return
+	}
+	hub, x, y := target.hub, target.x, target.y
+	var body CellMeta
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	if !hub.Grid().GetCell(x, y).Active {
+		writeCodedError(w, http.StatusConflict, "cell_inactive", "only painted cells can have metadata")
+		return
+	}
+	if !target.moderator {
+		// The latest history record names the painter, so write out the pending changes first
+		if err := db.FlushPending(); err != nil {
+			slog.Error("Failed to flush pending pixel writes", "err", err)
+		}
+		history, err := db.GetPixelHistory(hub.Canvas(), x, y, 1)
+		if err != nil {
+			slog.Error("Failed to load cell history", "err", err)
+			writeError(w, http.StatusInternalServerError, "failed to load history")
+			return
+		}
+		if len(history) == 0 || history[0].Actor != target.actor {
+			writeCodedError(w, http.StatusForbidden, "not_painter", "only the painter of a cell can attach metadata to it")
+			return
+		}
+	}
+
+	meta, err := hub.SetCellMeta(ws.CellMeta{X: x, Y: y, Text: body.Text, URL: body.URL, Actor: target.actor})
+	var rejected *ws.PlacementError
+	if errors.As(err, &rejected) {
+		writePlacementError(w, rejected)
+		return
+	}
+	if err := db.SaveCellMetadata(db.CellMetadata{Canvas: hub.Canvas(), X: x, Y: y, Text: meta.Text, URL: meta.URL, Actor: target.actor, UpdatedAt: time.Now()}); err != nil {
+		slog.Error("Failed to save cell metadata", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to save cell metadata")
+		return
+	}
+	writeJSON(w, http.StatusOK, CellMeta{Text: meta.Text, URL: meta.URL})
+}
+
+// handleRemoveCellMeta removes the metadata of a cell, for the painter that
+// attached it or a moderator
+func (h *handler) handleRemoveCellMeta(w http.ResponseWriter, r *http.Request) {
+	target, ok := h.cellMetaRequest(w, r)
+	if !ok {
+		return
+	}
+	hub, x, y := target.hub, target.x, target.y
+	meta, ok := hub.CellMeta(x, y)
+	if !ok {
+		writeError(w, http.StatusNotFound, "cell has no metadata")
+		return
+	}
+	if meta.Actor != target.actor && !target.moderator {
+		writeCodedError(w, http.StatusForbidden, "not_painter", "only the painter of a cell can remove its metadata")
+		return
+	}
+	hub.RemoveCellMeta(x, y)
+	if err := db.DeleteCellMetadata(hub.Canvas(), [][2]int{{x, y}}); err != nil {
+		slog.Error("Failed to delete cell metadata", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete cell metadata")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// cellMetaTarget is the cell a metadata change applies to, and who makes it
+type cellMetaTarget struct {
+	hub       *ws.Hub
+	x, y      int
+	actor     string
+	moderator bool
+}
+
+// cellMetaRequest resolves the canvas, cell and actor of a cell metadata
+// change and applies the paint rate limit, writing an error response and
+// returning false if the request can't proceed
+func (h *handler) cellMetaRequest(w http.ResponseWriter, r *http.Request) (cellMetaTarget, bool) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return cellMetaTarget{}, false
+	}
+	x, y, ok := cellCoords(w, r, hub.Grid())
+	if !ok {
+		return cellMetaTarget{}, false
+	}
+	identity, ok := h.authenticate(w, r)
+	if !ok {
+		return cellMetaTarget{}, false
+	}
+	ip := ClientIP(r)
+	if !h.allowPaint(identity, ip) {
+		w.Header().Set("Retry-After", "1")
+		writeCodedError(w, http.StatusTooManyRequests, "rate_limited", "too many requests, slow down")
+		return cellMetaTarget{}, false
+	}
+	target := cellMetaTarget{hub: hub, x: x, y: y, actor: hub.AnonymousActor(ip)}
+	if identity != nil {
+		target.actor, target.moderator = identity.UserID, identity.Moderator()
+	}
+	return target, true
+}
+
+// handleCellHistory returns who painted a cell and when, newest first
+func (h *handler) handleCellHistory(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	x, y, ok := cellCoords(w, r, hub.Grid())
+	if !ok {
+		return
+	}
+
+	limit, err := queryInt(r, "limit", defaultHistoryLimit)
+	if err != nil || limit < 1 {
+		writeError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+	limit = min(limit, maxHistoryLimit)
+
+	history, err := db.GetPixelHistory(hub.Canvas(), x, y, limit)
+	if err != nil {
+		slog.Error("Failed to load cell history", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to load history")
+		return
+	}
+
+	if history == nil {
+		history = []db.PixelHistory{}
+	}
+	writeJSON(w, http.StatusOK, CellHistoryResponse{X: x, Y: y, History: history})
+}
+
+// cellCoords parses and validates the {x} and {y} path values, writing an
+// error response and returning false if they are not cells of the grid
+func cellCoords(w http.ResponseWriter, r *http.Request, grid *ws.GridState) (int, int, bool) {
+	x, errX := strconv.Atoi(r.PathValue("x"))
+	y, errY := strconv.Atoi(r.PathValue("y"))
+	if errX != nil || errY != nil || !grid.InBounds(x, y) {
+		writeError(w, http.StatusBadRequest, "invalid cell coordinates")
+		return 0, 0, false
+	}
+	return x, y, true
+}
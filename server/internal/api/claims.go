@@ -0,0 +1,226 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// ClaimRequest claims a region of a canvas for the authenticated account
+type ClaimRequest struct {
+	ws.Region
+}
+
+// GrantClaimRequest claims a region of a canvas for an account, by an admin
+type GrantClaimRequest struct {
+	ws.Region
+	Owner string `json:"owner"`
+
+	// End of the claim (defaults to the claim duration from now)
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// handleClaims returns the claims of the canvas named by ?canvas=, only those
+// of ?owner= if given, so clients can render them as reserved
+func (h *handler) handleClaims(w http.ResponseWriter, r *http.Request) {
+	hub, ok := economyHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	claims := hub.Claims()
+	if owner := r.URL.Query().Get("owner"); owner != "" {
+		owned := claims[:0]
+		for _, claim := range claims {
+			if claim.Owner == owner {
+				owned = append(owned, claim)
+			}
+		}
+		claims = owned
+	}
+	writeJSON(w, http.StatusOK, map[string][]ws.Claim{"claims": claims})
+}
+
+// handleClaim reserves the region from a ClaimRequest body for the
+// authenticated account for the claim duration, up to the per-account cell
+// quota. Requests count against the per-IP paint rate limit.
+func (h *handler) handleClaim(w http.ResponseWriter, r *http.Request) {
+	hub, ok := economyHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	identity, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if identity == nil {
+		writeError(w, http.StatusUnauthorized, "claiming requires authentication")
+		return
+	}
+	if !h.allowPaint(identity, ClientIP(r)) {
+		w.Header().Set("Retry-After", "1")
+		writeCodedError(w, http.StatusTooManyRequests, "rate_limited", "too many requests, slow down")
+		return
+	}
+
+	var body ClaimRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	region := body.Region.Clamp(hub.Grid().Width(), hub.Grid().Height())
+	if region.Empty() {
+		writeError(w, http.StatusBadRequest, "region is empty")
+		return
+	}
+	if rejected := hub.CheckClaim(region, identity.UserID); rejected != nil {
+		writePlacementError(w, rejected)
+		return
+	}
+	saveClaim(w, hub, region, identity.UserID, time.Now().Add(hub.ClaimDuration()))
+}
+
+// handleReleaseClaim releases a claim of the authenticated account before it
+// expires
+func (h *handler) handleReleaseClaim(w http.ResponseWriter, r *http.Request) {
+	hub, id, ok := claimRequest(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	identity, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if identity == nil {
+		writeError(w, http.StatusUnauthorized, "claiming requires authentication")
+		return
+	}
+	if claim, ok := hub.Claim(id); !ok || claim.Owner != identity.UserID {
+		writeError(w, http.StatusNotFound, "unknown claim")
+		return
+	}
+	removeClaim(w, hub, id)
+}
+
+// handleListClaims returns every claim of the canvas
+func (h *adminHandler) handleListClaims(w http.ResponseWriter, r *http.Request) {
+	hub, ok := economyHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]ws.Claim{"claims": hub.Claims()})
+}
+
+// handleGrantClaim reserves the region from a GrantClaimRequest body for an
+// account, regardless of its quota
+func (h *adminHandler) handleGrantClaim(w http.ResponseWriter, r *http.Request) {
+	hub, ok := economyHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	var body GrantClaimRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	region := body.Region.Clamp(hub.Grid().Width(), hub.Grid().Height())
+	if region.Empty() {
+		writeError(w, http.StatusBadRequest, "region is empty")
+		return
+	}
+	if body.Owner == "" {
+		writeError(w, http.StatusBadRequest, "owner is required")
+		return
+	}
+	expiresAt := body.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(hub.ClaimDuration())
+	} else if !expiresAt.After(time.Now()) {
+		writeError(w, http.StatusBadRequest, "expires_at must be in the future")
+		return
+	}
+	saveClaim(w, hub, region, body.Owner, expiresAt)
+}
+
+// handleRevokeClaim releases any claim by ID
+func (h *adminHandler) handleRevokeClaim(w http.ResponseWriter, r *http.Request) {
+	hub, id, ok := claimRequest(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	if _, ok := hub.Claim(id); !ok {
+		writeError(w, http.StatusNotFound, "unknown claim")
+		return
+	}
+	removeClaim(w, hub, id)
+}
+
+// saveClaim persists a claim of a region by owner and reserves it on the
+// canvas, replying 201 with the claim
+func saveClaim(w http.ResponseWriter, hub *ws.Hub, region ws.Region, owner string, expiresAt time.Time) {
+	record := db.Claim{Canvas: hub.Canvas(), X1: region.X1, Y1: region.Y1, X2: region.X2, Y2: region.Y2, Owner: owner, ExpiresAt: expiresAt}
+	if err := db.SaveClaim(&record); err != nil {
+		slog.Error("Failed to save claim", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to save claim")
+		return
+	}
+	claim, err := hub.AddClaim(ws.Claim{ID: record.ID, Region: region, Owner: owner, ExpiresAt: expiresAt})
+	var rejected *ws.PlacementError
+	if errors.As(err, &rejected) {
+		// Another claim of the region won the race
+		if err := db.DeleteClaim(record.ID); err != nil {
+			slog.Error("Failed to delete claim", "err", err)
+		}
+		writePlacementError(w, rejected)
+		return
+	}
+	slog.Info("Claimed region", "canvas", hub.Canvas(), "id", claim.ID, "region", region, "owner", owner, "expires_at", expiresAt)
+	writeJSON(w, http.StatusCreated, claim)
+}
+
+// removeClaim releases a claim and deletes it from the database
+func removeClaim(w http.ResponseWriter, hub *ws.Hub, id uint) {
+	if !hub.RemoveClaim(id) {
+		writeError(w, http.StatusNotFound, "unknown claim")
+		return
+	}
+	if err := db.DeleteClaim(id); err != nil {
+		slog.Error("Failed to delete claim", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete claim")
+		return
+	}
+	slog.Info("Released claim", "canvas", hub.Canvas(), "id", id)
+	writeJSON(w, http.StatusOK, map[string]uint{"removed": id})
+}
+
+// economyHub looks up the hub of the canvas named by ?canvas=, writing an
+// error response and returning false if it is unknown or not in economy mode
+func economyHub(w http.ResponseWriter, r *http.Request, canvases *ws.Canvases) (*ws.Hub, bool) {
+	hub, ok := canvasHub(w, r, canvases)
+	if !ok {
+		return nil, false
+	}
+	if !hub.Economy() {
+		writeError(w, http.StatusNotFound, "economy mode is disabled")
+		return nil, false
+	}
+	return hub, true
+}
+
+// claimRequest looks up the hub of the canvas named by ?canvas= and parses the
+// claim ID in the path, writing an error response and returning false if
+// either is invalid
+func claimRequest(w http.ResponseWriter, r *http.Request, canvases *ws.Canvases) (*ws.Hub, uint, bool) {
+	hub, ok := economyHub(w, r, canvases)
+	if !ok {
+		return nil, 0, false
+	}
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid claim id")
+		return nil, 0, false
+	}
+	return hub, uint(id), true
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/metrics"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// Longest CPU profile or execution trace a request may take
+const maxProfileDuration = 5 * time.Minute
+
+// Number of recent GC pauses in RuntimeStats
+const recentPauses = 16
+
+// RuntimeStats describes the state of the Go runtime and of the queues the
+// hubs and the database writes go through, for diagnosing stalls
+type RuntimeStats struct {
+	Goroutines int `json:"goroutines"`
+	GOMAXPROCS int `json:"gomaxprocs"`
+
+	// Heap in use and reserved from the OS, and objects allocated and not freed
+	HeapAlloc   uint64 `json:"heap_alloc"`
+	HeapSys     uint64 `json:"heap_sys"`
+	HeapObjects uint64 `json:"heap_objects"`
+
+	// Garbage collections since the server started, their total pause, the
+	// pauses of the last ones, most recent first, and when the last one ended
+	NumGC      int64           `json:"num_gc"`
+	PauseTotal time.Duration   `json:"pause_total_ns"`
+	Pauses     []time.Duration `json:"pauses_ns"`
+	LastGC     *time.Time      `json:"last_gc,omitempty"`
+
+	// Share of the broadcast queues of each canvas in use (see Hub.QueueDepth)
+	BroadcastQueues map[string]float64 `json:"broadcast_queues"`
+
+	// Database write queue (nil without a database)
+	WriteQueue *db.WriteQueueStats `json:"write_queue,omitempty"`
+}
+
+// debugHandler serves the pprof profiles and runtime stats. They are written
+// with runtime/pprof rather than registered by net/http/pprof, which would
+// expose them on the default mux the public routes are served from.
+type debugHandler struct {
+	canvases *ws.Canvases
+}
+
+// registerDebugRoutes adds the debug endpoints under prefix, guarded by guard:
+// the pprof index at <prefix>/pprof/, CPU profiles at <prefix>/pprof/profile
+// (?seconds=, default 30), execution traces at <prefix>/pprof/trace
+// (?seconds=, default 1), the runtime profiles such as goroutine or heap at
+// <prefix>/pprof/<name> (?debug=1 or 2 for text, ?debug=2 dumping every
+// goroutine's stack, ?gc=1 collecting garbage before a heap profile), and
+// RuntimeStats at <prefix>/runtime.
+func registerDebugRoutes(mux *http.ServeMux, prefix string, canvases *ws.Canvases, guard func(http.HandlerFunc) http.HandlerFunc) {
+	h := &debugHandler{canvases: canvases}
+	mux.HandleFunc("GET "+prefix+"/pprof/{$}", guard(h.handleIndex))
+	mux.HandleFunc("GET "+prefix+"/pprof/profile", guard(h.handleCPUProfile))
+	mux.HandleFunc("GET "+prefix+"/pprof/trace", guard(h.handleTrace))
+	mux.HandleFunc("GET "+prefix+"/pprof/{profile}", guard(h.handleProfile))
+	mux.HandleFunc("GET "+prefix+"/runtime", guard(h.handleRuntime))
+}
+
+// DebugHandler serves the debug endpoints under /debug, and the Prometheus
+// metrics at /debug/metrics, to loopback clients only, for a listener of its
+// own no admin token is needed on
+func DebugHandler(canvases *ws.Canvases) http.Handler {
+	mux := http.NewServeMux()
+	registerDebugRoutes(mux, "/debug", canvases, loopbackOnly)
+	mux.HandleFunc("GET /debug/metrics", loopbackOnly(metrics.Handler().ServeHTTP))
+	return mux
+}
+
+// loopbackOnly rejects requests not coming from the host itself. The peer
+// address is used rather than forwarded headers, which a proxy in front of
+// the listener would make loopback.
+func loopbackOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+			writeError(w, http.StatusForbidden, "debug endpoints are only served to loopback clients")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleIndex lists the runtime profiles with their current counts
+func (h *debugHandler) handleIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "profile        CPU profile, ?seconds=30")
+	fmt.Fprintln(w, "trace          execution trace, ?seconds=1")
+	for _, p := range pprof.Profiles() {
+		fmt.Fprintf(w, "%-14s %d\n", p.Name(), p.Count())
+	}
+}
+
+// handleCPUProfile profiles the CPU usage for ?seconds=
+func (h *debugHandler) handleCPUProfile(w http.ResponseWriter, r *http.Request) {
+	duration, ok := profileDuration(w, r, 30)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="profile"`)
+	if err := pprof.StartCPUProfile(w); err != nil {
+		// Another profile is running
+		w.Header().Del("Content-Disposition")
+		writeError(w, http.StatusConflict, "could not start CPU profile: "+err.Error())
+		return
+	}
+	sleep(r, duration)
+	pprof.StopCPUProfile()
+}
+
+// handleTrace traces the execution for ?seconds=
+func (h *debugHandler) handleTrace(w http.ResponseWriter, r *http.Request) {
+	duration, ok := profileDuration(w, r, 1)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="trace"`)
+	if err := trace.Start(w); err != nil {
+		w.Header().Del("Content-Disposition")
+		writeError(w, http.StatusConflict, "could not start trace: "+err.Error())
+		return
+	}
+	sleep(r, duration)
+	trace.Stop()
+}
+
+// handleProfile writes a runtime profile, in the pprof format or as text with ?debug=
+func (h *debugHandler) handleProfile(w http.ResponseWriter, r *http.Request) {
+	profile := pprof.Lookup(r.PathValue("profile"))
+	if profile == nil {
+		writeError(w, http.StatusNotFound, "unknown profile")
+		return
+	}
+	level, err := queryInt(r, "debug", 0)
+	if err != nil || level < 0 || level > 2 {
+		writeError(w, http.StatusBadRequest, "debug must be 0, 1 or 2")
+		return
+	}
+	if gc, _ := strconv.ParseBool(r.URL.Query().Get("gc")); gc && profile.Name() == "heap" {
+		runtime.GC()
+	}
+	if level > 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, profile.Name()))
+	}
+	profile.WriteTo(w, level)
+}
+
+// handleRuntime returns the RuntimeStats
+func (h *debugHandler) handleRuntime(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	var gc debug.GCStats
+	debug.ReadGCStats(&gc)
+
+	stats := RuntimeStats{
+		Goroutines:      runtime.NumGoroutine(),
+		GOMAXPROCS:      runtime.GOMAXPROCS(0),
+		HeapAlloc:       mem.HeapAlloc,
+		HeapSys:         mem.HeapSys,
+		HeapObjects:     mem.HeapObjects,
+		NumGC:           gc.NumGC,
+		PauseTotal:      gc.PauseTotal,
+		Pauses:          gc.Pause[:min(len(gc.Pause), recentPauses)],
+		BroadcastQueues: make(map[string]float64),
+	}
+	if gc.NumGC > 0 {
+		stats.LastGC = &gc.LastGC
+	}
+	for _, hub := range h.canvases.Hubs() {
+		stats.BroadcastQueues[hub.Canvas()] = hub.QueueDepth()
+	}
+	if queue := db.Queue(); queue != nil {
+		queueStats := queue.Stats()
+		stats.WriteQueue = &queueStats
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// profileDuration parses ?seconds=, answering the request if it is invalid
+func profileDuration(w http.ResponseWriter, r *http.Request, def int) (time.Duration, bool) {
+	seconds, err := queryInt(r, "seconds", def)
+	duration := time.Duration(seconds) * time.Second
+	if err != nil || seconds < 1 || duration > maxProfileDuration {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("seconds must be between 1 and %d", int(maxProfileDuration.Seconds())))
+		return 0, false
+	}
+	return duration, true
+}
+
+// sleep waits for d or until the client goes away
+func sleep(r *http.Request, d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-r.Context().Done():
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/million_grids/server/internal/ws"
+)
+
+// handleDraw starts painting the drawing primitive in the body on the canvas
+// named by ?canvas= for an authenticated bot, replying 202 with its status.
+// The drawing is painted server-side in batches paced by the cooldown; poll
+// GET /api/draw/{id} for its progress.
+func (h *handler) handleDraw(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	identity, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if identity == nil {
+		writeError(w, http.StatusUnauthorized, "drawing requires authentication")
+		return
+	}
+	ip := ClientIP(r)
+	if !h.allowPaint(identity, ip) {
+		w.Header().Set("Retry-After", "1")
+		writeCodedError(w, http.StatusTooManyRequests, "rate_limited", "too many requests, slow down")
+		return
+	}
+
+	var body ws.DrawRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	placement := ws.Placement{IP: ip, Actor: identity.UserID, Moderator: identity.Moderator(), Role: identity.Role}
+	if hub.HasTeam(identity.Team) {
+		placement.Team = identity.Team
+	}
+	status, err := hub.Draw(body, placement)
+	var rejected *ws.PlacementError
+	if errors.As(err, &rejected) {
+		writePlacementError(w, rejected)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+// handleDrawStatus returns the progress of a drawing
+func (h *handler) handleDrawStatus(w http.ResponseWriter, r *http.Request) {
+	hub, id, ok := drawingHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	status, ok := hub.Drawing(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown drawing")
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleCancelDraw stops a drawing of the authenticated bot, leaving the cells
+// painted so far
+func (h *handler) handleCancelDraw(w http.ResponseWriter, r *http.Request) {
+	hub, id, ok := drawingHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	identity, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if identity == nil {
+		writeError(w, http.StatusUnauthorized, "drawing requires authentication")
+		return
+	}
+	if !hub.CancelDrawing(id, identity.UserID) {
+		writeError(w, http.StatusNotFound, "unknown drawing")
+		return
+	}
+	status, _ := hub.Drawing(id)
+	writeJSON(w, http.StatusOK, status)
+}
+
+// drawingHub looks up the hub of the canvas named by ?canvas= and parses the
+// drawing ID in the path, writing an error response and returning false if
+// either is invalid
+func drawingHub(w http.ResponseWriter, r *http.Request, canvases *ws.Canvases) (*ws.Hub, uint64, bool) {
+	hub, ok := canvasHub(w, r, canvases)
+	if !ok {
+		return nil, 0, false
+	}
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid drawing id")
+		return nil, 0, false
+	}
+	return hub, id, true
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/million_grids/server/internal/ws"
+)
+
+// Interval of the comments that keep idle event streams open through proxies
+const eventKeepAlive = 25 * time.Second
+
+// handleEvents streams a canvas as Server-Sent Events for read-only viewers
+// that can't open a WebSocket. Each event carries one message of the JSON
+// WebSocket protocol: the initial state, then every broadcast. Query params:
+// canvas, and x1, y1, x2, y2 to only receive updates inside a region.
+func (h *handler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	if hub.ShuttingDown() {
+		writeError(w, http.StatusServiceUnavailable, "server restarting")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	var region *ws.Region
+	query := r.URL.Query()
+	if query.Has("x1") || query.Has("y1") || query.Has("x2") || query.Has("y2") {
+		bounds, ok := queryRegion(w, r, hub.Grid())
+		if !ok {
+			return
+		}
+		region = &bounds
+	}
+
+	// Listen first so no update is missed while the initial state is sent
+	l := hub.Listen(region, ws.EncodingJSON)
+	defer hub.Unlisten(l)
+
+	frames, err := hub.InitialState(ws.EncodingJSON)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to encode initial state")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx response buffering
+	w.WriteHeader(http.StatusOK)
+	for _, frame := range frames {
+		if err := writeEvent(w, frame); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(eventKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case frame, ok := <-l.C:
+			// Closed when the stream falls behind or the server shuts down;
+			// EventSource reconnects and receives the current state again
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, frame); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes a JSON message as a Server-Sent Event
+func writeEvent(w io.Writer, data []byte) error {
+	_, err := fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"image/png"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/snapshot"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// GridResponse is the state of (a region of) a canvas at a point in time
+type GridResponse struct {
+	Canvas string          `json:"canvas"`
+	At     time.Time       `json:"at"`
+	Region ws.Region       `json:"region"`
+	Active []ws.ActiveCell `json:"active"`
+}
+
+// handleGrid returns a canvas as of a past moment, reconstructed from its
+// event stream. Query params: canvas, at (RFC 3339, default now), x1, y1, x2, y2
+// (half-open bounds, default whole grid), format (json or png) and scale (png only).
+func (h *handler) handleGrid(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	region, ok := queryRegion(w, r, hub.Grid())
+	if !ok {
+		return
+	}
+	at, err := queryTime(r, "at", time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "at must be an RFC 3339 time")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	scale, err := queryInt(r, "scale", 1)
+	switch {
+	case format != "json" && format != "png":
+		writeError(w, http.StatusBadRequest, "format must be json or png")
+		return
+	case err != nil || scale < 1 || scale > maxSnapshotScale:
+		writeError(w, http.StatusBadRequest, "scale must be between 1 and "+strconv.Itoa(maxSnapshotScale))
+		return
+	case format == "png" && max(region.X2-region.X1, region.Y2-region.Y1)*scale > maxSnapshotDimension:
+		writeError(w, http.StatusBadRequest, "snapshot too large, reduce the region or scale")
+		return
+	}
+
+	grid, err := gridAt(h.snapshots, hub, region, at)
+	if err != nil {
+		slog.Error("Failed to reconstruct grid", "canvas", hub.Canvas(), "at", at, "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to load history")
+		return
+	}
+
+	if format == "png" {
+		w.Header().Set("Content-Type", "image/png")
+		if err := png.Encode(w, renderRegion(grid, region, scale)); err != nil {
+			slog.Error("Failed to encode grid", "err", err)
+		}
+		return
+	}
+
+	cells := grid.GetActiveCellsInRegion(region.X1, region.Y1, region.X2, region.Y2)
+	active := make([]ws.ActiveCell, len(cells))
+	for i, cell := range cells {
+		active[i] = ws.ActiveCell{X: cell.X, Y: cell.Y, Color: cell.Color}
+	}
+	writeJSON(w, http.StatusOK, GridResponse{Canvas: hub.Canvas(), At: at, Region: region, Active: active})
+}
+
+// gridAt rebuilds the state of a region of a canvas at a point in time by
+// replaying its event stream onto the latest snapshot taken before, or onto an
+// empty grid of the same size if there is none
+func gridAt(snapshots *snapshot.Store, hub *ws.Hub, region ws.Region, at time.Time) (*ws.GridState, error) {
+	// The history log must include the writes still queued
+	if err := db.FlushPending(); err != nil {
+		return nil, err
+	}
+
+	grid := ws.NewGridState(hub.Grid().Width(), hub.Grid().Height())
+	var from db.StreamPosition
+	if snapshots != nil {
+		snap, err := snapshots.Latest(hub.Canvas(), at)
+		if err != nil {
+			return nil, err
+		}
+		if snap != nil && snap.Width == grid.Width() && snap.Height == grid.Height() {
+			for _, cell := range snap.Cells {
+				if region.Contains(cell.X, cell.Y) {
+					grid.SetCell(cell.X, cell.Y, true, cell.Color)
+				}
+			}
+			from = db.StreamPosition{HistoryID: snap.HistoryID, EventID: snap.EventID}
+		}
+	}
+
+	err := db.ReplayStream(hub.Canvas(), region.X1, region.Y1, region.X2, region.Y2, from, at, func(records []db.PixelHistory) error {
+		for _, rec := range records {
+			grid.SetCell(rec.X, rec.Y, rec.Active, rec.Color)
+		}
+		return nil
+	}, func(db.CanvasEvent) error {
+		grid.Initialize()
+		return nil
+	})
+	return grid, err
+}
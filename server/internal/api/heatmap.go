@@ -0,0 +1,89 @@
+package api
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/million_grids/server/internal/readmodel"
+)
+
+// HeatmapResponse counts the placements in each block of a canvas over a window
+type HeatmapResponse struct {
+	Canvas string `json:"canvas"`
+	Window string `json:"window"`
+	readmodel.Heatmap
+}
+
+// handleHeatmap returns where a canvas was painted over the last hour or day,
+// as the placements in each block of readmodel.HeatmapBlock cells. Counts are
+// kept per hour, so a window starts at the top of an hour. Query params:
+// canvas, window (hour or day, default day), format (json or png) and scale
+// (output pixels per block side, png only). It needs the read models.
+func (h *handler) handleHeatmap(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	m := h.readModel(hub.Canvas())
+	if m == nil {
+		writeError(w, http.StatusNotFound, "heatmaps are disabled")
+		return
+	}
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		window = "day"
+	}
+	length, ok := leaderboardWindows[window]
+	if !ok || length == 0 {
+		writeError(w, http.StatusBadRequest, "window must be hour or day")
+		return
+	}
+	heatmap := m.Heatmap(time.Now().Add(-length))
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		writeJSON(w, http.StatusOK, HeatmapResponse{Canvas: hub.Canvas(), Window: window, Heatmap: heatmap})
+	case "png":
+		scale, err := queryInt(r, "scale", 1)
+		if err != nil || scale < 1 || scale > maxSnapshotScale {
+			writeError(w, http.StatusBadRequest, "scale must be between 1 and "+strconv.Itoa(maxSnapshotScale))
+			return
+		}
+		if heatmap.Columns*scale > maxSnapshotDimension || heatmap.Rows*scale > maxSnapshotDimension {
+			writeError(w, http.StatusBadRequest, "heatmap too large, reduce the scale")
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "no-cache")
+		if err := png.Encode(w, renderHeatmap(heatmap, scale)); err != nil {
+			slog.Error("Failed to encode heatmap", "err", err)
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "format must be json or png")
+	}
+}
+
+// renderHeatmap draws a heatmap scale pixels per block, from white for blocks
+// nobody painted to red for the most painted one
+func renderHeatmap(heatmap readmodel.Heatmap, scale int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, heatmap.Columns*scale, heatmap.Rows*scale))
+	for i, n := range heatmap.Counts {
+		level := uint8(0xFF)
+		if heatmap.Max > 0 {
+			level = uint8(0xFF - n*0xFF/heatmap.Max)
+		}
+		c := color.RGBA{0xFF, level, level, 0xFF}
+		px, py := (i%heatmap.Columns)*scale, (i/heatmap.Columns)*scale
+		for dx := 0; dx < scale; dx++ {
+			for dy := 0; dy < scale; dy++ {
+				img.SetRGBA(px+dx, py+dy, c)
+			}
+		}
+	}
+	return img
+}
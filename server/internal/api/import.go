@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bytes"
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/million_grids/server/internal/db"
+)
+
+const (
+	// Largest imported image width and height in pixels
+	maxImportSide = 4096
+
+	// Largest image upload in bytes
+	maxImportBytes = 32 << 20
+
+	// Cells applied and written to the database at a time
+	importBatchSize = 10000
+)
+
+// ImportResponse reports the outcome of an image import
+type ImportResponse struct {
+	// Cells the image covers on the canvas, and of those the ones that changed
+	Pixels  int `json:"pixels"`
+	Changed int `json:"changed"`
+}
+
+// handleImport paints the PNG in the request body onto the canvas with its
+// top-left corner at ?x=&y=. Each pixel becomes its nearest paintable color
+// (white clearing the cell), mostly transparent pixels and those off the
+// canvas are skipped. The cells are applied, broadcast and written to the
+// database in batches.
+func (h *adminHandler) handleImport(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	x, errX := queryInt(r, "x", 0)
+	y, errY := queryInt(r, "y", 0)
+	if errX != nil || errY != nil {
+		writeError(w, http.StatusBadRequest, "invalid offset")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "image must be at most "+strconv.Itoa(maxImportBytes>>20)+" MiB")
+		return
+	}
+	config, err := png.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "body must be a PNG image")
+		return
+	}
+	if config.Width > maxImportSide || config.Height > maxImportSide {
+		writeError(w, http.StatusBadRequest, "image must be at most "+strconv.Itoa(maxImportSide)+" pixels wide and high")
+		return
+	}
+	img, err := png.Decode(bytes.NewReader(body))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "body must be a PNG image")
+		return
+	}
+
+	pixels := hub.Grid().ImagePixels(img, x, y)
+	if len(pixels) == 0 {
+		writeError(w, http.StatusBadRequest, "image has no opaque pixels on the canvas")
+		return
+	}
+	changed := 0
+	for start := 0; start < len(pixels); start += importBatchSize {
+		batch := pixels[start:min(start+importBatchSize, len(pixels))]
+		applied, err := hub.SetCells(r.Context(), batch, adminActor)
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, "failed to save the changes, try again")
+			return
+		}
+		changed += len(applied)
+		// Write each batch before the next, so a large import doesn't pile up in the write queue
+		if err := db.FlushPending(); err != nil {
+			slog.Error("Failed to write imported image", "canvas", hub.Canvas(), "applied", start+len(batch), "err", err)
+			writeError(w, http.StatusInternalServerError, "failed to write imported image")
+			return
+		}
+	}
+	slog.Info("Imported image", "canvas", hub.Canvas(), "x", x, "y", y, "width", config.Width, "height", config.Height, "pixels", len(pixels), "changed", changed)
+	writeJSON(w, http.StatusOK, ImportResponse{Pixels: len(pixels), Changed: changed})
+}
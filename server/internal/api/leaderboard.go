@@ -0,0 +1,92 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+)
+
+const (
+	// Number of contributors returned when no limit is given
+	defaultLeaderboardLimit = 10
+
+	// Maximum number of contributors returned per request
+	maxLeaderboardLimit = 100
+)
+
+// leaderboardWindows maps the accepted ?window= values to their length (0 for all time)
+var leaderboardWindows = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+	"all":  0,
+}
+
+// LeaderboardResponse lists the top contributors of a canvas over a window
+type LeaderboardResponse struct {
+	Canvas       string           `json:"canvas"`
+	Window       string           `json:"window"`
+	Contributors []db.Contributor `json:"contributors"`
+}
+
+// handleLeaderboard returns the contributors who changed the most cells,
+// identified by a hash of their user ID or IP. Counts are kept per hour (so a
+// window starts at the top of an hour). The hour and day windows come from the
+// read model when read models are enabled, trailing the live canvas by up to
+// their interval; otherwise, and for all time, counts trail it by up to the
+// leaderboard interval. Query params: canvas, window (hour, day or all,
+// default day) and limit.
+func (h *handler) handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+
+	window, since, limit, ok := leaderboardQuery(w, r)
+	if !ok {
+		return
+	}
+	if m := h.readModel(hub.Canvas()); m != nil && window != "all" {
+		writeJSON(w, http.StatusOK, LeaderboardResponse{Canvas: hub.Canvas(), Window: window, Contributors: m.TopContributors(since, limit)})
+		return
+	}
+	top, err := db.TopContributors(hub.Canvas(), since, limit)
+	if err != nil {
+		slog.Error("Failed to load leaderboard", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to load leaderboard")
+		return
+	}
+
+	if top == nil {
+		top = []db.Contributor{}
+	}
+	writeJSON(w, http.StatusOK, LeaderboardResponse{Canvas: hub.Canvas(), Window: window, Contributors: top})
+}
+
+// leaderboardQuery parses the window and limit of a leaderboard request,
+// returning the start of the window (zero for all time). It writes an error
+// response and returns false if they are invalid.
+func leaderboardQuery(w http.ResponseWriter, r *http.Request) (window string, since time.Time, limit int, ok bool) {
+	window = r.URL.Query().Get("window")
+	if window == "" {
+		window = "day"
+	}
+	length, ok := leaderboardWindows[window]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "window must be hour, day or all")
+		return "", time.Time{}, 0, false
+	}
+
+	limit, err := queryInt(r, "limit", defaultLeaderboardLimit)
+	if err != nil || limit < 1 {
+		writeError(w, http.StatusBadRequest, "invalid limit")
+		return "", time.Time{}, 0, false
+	}
+	limit = min(limit, maxLeaderboardLimit)
+
+	if length > 0 {
+		since = time.Now().Add(-length)
+	}
+	return window, since, limit, true
+}
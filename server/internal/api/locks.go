@@ -0,0 +1,96 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// Longest locked region label in characters
+const maxLockLabelLength = 128
+
+// LockRequest locks a region of a canvas
+type LockRequest struct {
+	ws.Region
+
+	// Shown to clients, e.g. "logo" (optional)
+	Label string `json:"label"`
+}
+
+// handleLocks returns the locked regions of the canvas named by ?canvas=, so
+// clients can render them as locked
+func (h *handler) handleLocks(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]ws.LockedRegion{"regions": hub.Locks()})
+}
+
+// handleListLocks returns the locked regions of the canvas
+func (h *adminHandler) handleListLocks(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]ws.LockedRegion{"regions": hub.Locks()})
+}
+
+// handleLock locks the region from a LockRequest body, leaving it to moderators
+func (h *adminHandler) handleLock(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	var body LockRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	region := body.Region.Clamp(hub.Grid().Width(), hub.Grid().Height())
+	if region.Empty() {
+		writeError(w, http.StatusBadRequest, "region is empty")
+		return
+	}
+	if utf8.RuneCountInString(body.Label) > maxLockLabelLength {
+		writeError(w, http.StatusBadRequest, "label is too long")
+		return
+	}
+
+	record := db.LockedRegion{Canvas: hub.Canvas(), X1: region.X1, Y1: region.Y1, X2: region.X2, Y2: region.Y2, Label: body.Label}
+	if err := db.SaveLockedRegion(&record); err != nil {
+		slog.Error("Failed to save locked region", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to save locked region")
+		return
+	}
+	lock := hub.AddLock(ws.LockedRegion{ID: record.ID, Region: region, Label: body.Label})
+	slog.Info("Locked region", "canvas", hub.Canvas(), "id", lock.ID, "region", region, "label", lock.Label)
+	writeJSON(w, http.StatusCreated, lock)
+}
+
+// handleUnlock unlocks a region by ID
+func (h *adminHandler) handleUnlock(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid region id")
+		return
+	}
+	if !hub.RemoveLock(uint(id)) {
+		writeError(w, http.StatusNotFound, "unknown region")
+		return
+	}
+	if err := db.DeleteLockedRegion(uint(id)); err != nil {
+		slog.Error("Failed to delete locked region", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete locked region")
+		return
+	}
+	slog.Info("Unlocked region", "canvas", hub.Canvas(), "id", id)
+	writeJSON(w, http.StatusOK, map[string]uint{"removed": uint(id)})
+}
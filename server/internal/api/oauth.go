@@ -0,0 +1,211 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/million_grids/server/internal/auth"
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/oauth"
+)
+
+const (
+	// Cookie remembering the provider, state and redirect of a login in progress
+	loginCookie = "mg_login"
+
+	// Time a user has to complete a login at the provider
+	loginTimeout = 10 * time.Minute
+)
+
+// AuthOptions configures the OAuth2 login endpoints
+type AuthOptions struct {
+	// Issues the session tokens, which it validates afterwards
+	Validator *auth.Validator
+
+	// Providers users can log in with, by name (none disables the endpoints)
+	Providers map[string]*oauth.Provider
+
+	// Public URL of the server, and the time a login lasts
+	BaseURL    string
+	SessionTTL time.Duration
+}
+
+// MeResponse is the identity of the user a request authenticates
+type MeResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	Role string `json:"role,omitempty"`
+	Team string `json:"team,omitempty"`
+
+	// Account of users logged in with a provider
+	User *db.User `json:"user,omitempty"`
+}
+
+// authHandler serves the login endpoints under /auth
+type authHandler struct {
+	AuthOptions
+
+	// Where providers send users back to
+	callbackURL string
+
+	// Whether cookies are only sent over HTTPS
+	secure bool
+}
+
+// RegisterAuthRoutes adds the OAuth2 login handlers to the mux. Logged in
+// users get a session cookie holding a token, which the WebSocket handshake
+// and API requests are authenticated with.
+func RegisterAuthRoutes(mux *http.ServeMux, opts AuthOptions) {
+	if len(opts.Providers) == 0 {
+		return
+	}
+	base := strings.TrimSuffix(opts.BaseURL, "/")
+	h := &authHandler{AuthOptions: opts, callbackURL: base + "/auth/callback", secure: strings.HasPrefix(base, "https://")}
+
+	mux.HandleFunc("GET /auth/login", h.handleLogin)
+	mux.HandleFunc("GET /auth/callback", h.handleCallback)
+	mux.HandleFunc("POST /auth/logout", h.handleLogout)
+	mux.HandleFunc("GET /auth/me", h.handleMe)
+}
+
+// handleLogin sends the user to the consent page of ?provider= (optional
+// with a single provider), to come back to the path in ?redirect= once logged in
+func (h *authHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	name := query.Get("provider")
+	if name == "" && len(h.Providers) == 1 {
+		for only := range h.Providers {
+			name = only
+		}
+	}
+	provider, ok := h.Providers[name]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "unknown provider")
+		return
+	}
+
+	state, err := randomState()
+	if err != nil {
+		slog.Error("Failed to generate login state", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to start login")
+		return
+	}
+	login := url.Values{"provider": {provider.Name()}, "state": {state}, "redirect": {safeRedirect(query.Get("redirect"))}}
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCookie,
+		Value:    login.Encode(),
+		Path:     "/auth",
+		MaxAge:   int(loginTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   h.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, provider.AuthCodeURL(h.callbackURL, state), http.StatusFound)
+}
+
+// handleCallback completes a login the provider sent the user back from:
+// it records the user, sets the session cookie and redirects to the path
+// the login was started for
+func (h *authHandler) handleCallback(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(loginCookie)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "no login in progress")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: loginCookie, Path: "/auth", MaxAge: -1, HttpOnly: true, Secure: h.secure})
+	login, err := url.ParseQuery(cookie.Value)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "no login in progress")
+		return
+	}
+	query := r.URL.Query()
+	state := query.Get("state")
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(login.Get("state"))) != 1 {
+		writeError(w, http.StatusBadRequest, "invalid login state")
+		return
+	}
+	provider, ok := h.Providers[login.Get("provider")]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "unknown provider")
+		return
+	}
+	if query.Get("error") != "" || query.Get("code") == "" {
+		writeError(w, http.StatusUnauthorized, "login was denied")
+		return
+	}
+
+	account, err := provider.Exchange(r.Context(), query.Get("code"), h.callbackURL)
+	if err != nil {
+		slog.Warn("Login failed", "provider", provider.Name(), "err", err)
+		writeError(w, http.StatusBadGateway, "login failed")
+		return
+	}
+	user := db.User{ID: provider.Name() + ":" + account.Subject, Provider: provider.Name(), Name: account.Name}
+	if err := db.SaveLogin(&user); err != nil {
+		slog.Error("Failed to save login", "err", err)
+		writeError(w, http.StatusInternalServerError, "login failed")
+		return
+	}
+	token, err := h.Validator.Issue(&auth.Identity{UserID: user.ID, Name: user.Name}, h.SessionTTL)
+	if err != nil {
+		slog.Error("Failed to issue session token", "err", err)
+		writeError(w, http.StatusInternalServerError, "login failed")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.SessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(h.SessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   h.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	slog.Info("User logged in", "user", user.ID)
+	http.Redirect(w, r, login.Get("redirect"), http.StatusFound)
+}
+
+// handleLogout ends the session of the user by clearing its cookie
+func (h *authHandler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: auth.SessionCookie, Path: "/", MaxAge: -1, HttpOnly: true, Secure: h.secure, SameSite: http.SameSiteLaxMode})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMe returns the identity of the logged in user, or of the request's token
+func (h *authHandler) handleMe(w http.ResponseWriter, r *http.Request) {
+	identity, err := h.Validator.Authenticate(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "not logged in")
+		return
+	}
+	resp := MeResponse{ID: identity.UserID, Name: identity.Name, Role: identity.Role, Team: identity.Team}
+	if resp.User, err = db.LoadUser(identity.UserID); err != nil {
+		slog.Error("Failed to load user", "err", err)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// randomState returns an unguessable login state
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// safeRedirect returns target if it is a path on this server, and "/"
+// otherwise, so logins can't send users to other sites
+func safeRedirect(target string) string {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return "/"
+	}
+	return target
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// PaletteResponse describes the active palette
+type PaletteResponse struct {
+	Version  int           `json:"version"`
+	Colors   []model.Color `json:"colors"`
+	AnyColor bool          `json:"any_color"`
+}
+
+// currentPalette returns the active palette
+func currentPalette() PaletteResponse {
+	return PaletteResponse{
+		Version:  model.PaletteVersion(),
+		Colors:   model.Palette(),
+		AnyColor: !model.RestrictedToPalette(),
+	}
+}
+
+// handlePalette returns the active palette
+func (h *handler) handlePalette(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, currentPalette())
+}
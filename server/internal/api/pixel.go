@@ -0,0 +1,179 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/million_grids/server/internal/auth"
+	"github.com/million_grids/server/internal/metrics"
+	"github.com/million_grids/server/internal/model"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// PixelRequest is the body of POST /api/pixel, the same as a WebSocket cell
+// operation: type is one of "toggle", "set" or "clear". team defaults to the
+// team of the token. captcha is a solved CAPTCHA token, for anonymous
+// requests rejected with the code "captcha_required".
+type PixelRequest struct {
+	Type    string `json:"type"`
+	X       int    `json:"x"`
+	Y       int    `json:"y"`
+	Color   string `json:"color,omitempty"`
+	Team    string `json:"team,omitempty"`
+	Captcha string `json:"captcha,omitempty"`
+}
+
+// PixelResponse is the state of a cell after a placement
+type PixelResponse struct {
+	X      int         `json:"x"`
+	Y      int         `json:"y"`
+	Active bool        `json:"active"`
+	Color  model.Color `json:"color"`
+}
+
+// handlePaintPixel applies a single-cell operation on the canvas named by
+// ?canvas=, subject to the same authentication, validation, bans and cooldown
+// as WebSocket placements and to a per-IP request rate limit. Rejections carry
+// the WebSocket error code in "code".
+func (h *handler) handlePaintPixel(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	identity, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	ip := ClientIP(r)
+	if !h.allowPaint(identity, ip) {
+		w.Header().Set("Retry-After", "1")
+		writeCodedError(w, http.StatusTooManyRequests, "rate_limited", "too many requests, slow down")
+		return
+	}
+
+	var body PixelRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.Type == "" {
+		writeCodedError(w, http.StatusBadRequest, "missing_type", `request has no "type"`)
+		return
+	}
+
+	placement := ws.Placement{Op: body.Type, X: body.X, Y: body.Y, Color: body.Color, IP: ip, Actor: hub.AnonymousActor(ip), Team: body.Team, Anonymous: identity == nil}
+	if placement.Anonymous && body.Captcha != "" && !solveCaptcha(w, r, hub, placement.Actor, body.Captcha) {
+		return
+	}
+	if identity != nil {
+		placement.Actor = identity.UserID
+		placement.Moderator = identity.Moderator()
+		placement.Role = identity.Role
+		if placement.Team == "" && hub.HasTeam(identity.Team) {
+			placement.Team = identity.Team
+		}
+	}
+	pixel, err := hub.Place(r.Context(), placement)
+	var rejected *ws.PlacementError
+	if errors.As(err, &rejected) {
+		metrics.Rejections.WithLabelValues(hub.Canvas(), "http", rejected.Code).Inc()
+		writePlacementError(w, rejected)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, PixelResponse{X: pixel.X, Y: pixel.Y, Active: pixel.Active, Color: pixel.Color})
+}
+
+// authenticate validates the request's token, writing a 401 response and
+// returning false if it is invalid, or missing while authentication is
+// required. Anonymous requests get a nil identity.
+func (h *handler) authenticate(w http.ResponseWriter, r *http.Request) (*auth.Identity, bool) {
+	if h.validator == nil {
+		return nil, true
+	}
+	identity, err := h.validator.Authenticate(r)
+	if err != nil && (h.authRequired || !errors.Is(err, auth.ErrNoToken)) {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+	return identity, true
+}
+
+// solveCaptcha verifies a CAPTCHA token solved by actor, writing an error
+// response and returning false if it is rejected
+func solveCaptcha(w http.ResponseWriter, r *http.Request, hub *ws.Hub, actor, token string) bool {
+	err := hub.SolveCaptcha(r.Context(), actor, ClientIP(r), token)
+	switch {
+	case errors.Is(err, ws.ErrCaptchaInvalid):
+		writeCodedError(w, http.StatusForbidden, "captcha_invalid", "the CAPTCHA token is invalid or expired")
+		return false
+	case err != nil:
+		writeCodedError(w, http.StatusServiceUnavailable, "captcha_unavailable", "failed to verify the CAPTCHA, try again")
+		return false
+	}
+	return true
+}
+
+// handleCaptcha returns the CAPTCHA challenge anonymous clients of the canvas
+// must solve, for them to render the provider's widget
+func (h *handler) handleCaptcha(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	policy, challenged := hub.Captcha()
+	if !challenged {
+		writeError(w, http.StatusNotFound, "captcha challenges are disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, policy)
+}
+
+// handleTier returns the placement tier of the requester on the canvas and
+// what is left of its quota
+func (h *handler) handleTier(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	identity, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if identity == nil {
+		writeJSON(w, http.StatusOK, hub.Tier(hub.AnonymousActor(ClientIP(r)), "", true))
+		return
+	}
+	writeJSON(w, http.StatusOK, hub.Tier(identity.UserID, identity.Role, false))
+}
+
+// writeCodedError writes a JSON error response with a machine-readable code
+func writeCodedError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]string{"error": message, "code": code})
+}
+
+// writePlacementError writes the response rejecting a placement, with the
+// WebSocket error code in "code"
+func writePlacementError(w http.ResponseWriter, rejected *ws.PlacementError) {
+	status := http.StatusBadRequest
+	switch rejected.Code {
+	case "cooldown", "quota_exceeded":
+		status = http.StatusTooManyRequests
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rejected.RetryAfter.Seconds()))))
+	case "banned", "captcha_required", "read_only_replica":
+		status = http.StatusForbidden
+	case "canvas_frozen":
+		status = http.StatusConflict
+	case "region_locked", "cell_claimed":
+		status = http.StatusForbidden
+	case "cell_protected":
+		status = http.StatusConflict
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rejected.RetryAfter.Seconds()))))
+	case "drawing_in_progress", "region_claimed", "claim_limit":
+		status = http.StatusConflict
+	case "write_failed":
+		status = http.StatusServiceUnavailable
+	}
+	writeCodedError(w, status, rejected.Code, rejected.Message)
+}
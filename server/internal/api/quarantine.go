@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/million_grids/server/internal/ws"
+)
+
+// handleListQuarantine returns the actors the grief detection throttles on
+// the canvas, the latest caught first
+func (h *adminHandler) handleListQuarantine(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]ws.Quarantine{"quarantined": hub.Quarantined()})
+}
+
+// handleReleaseQuarantine lifts the quarantine of an actor on the canvas. An
+// IP also releases its hash when IP hashing is enabled.
+func (h *adminHandler) handleReleaseQuarantine(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	actor := r.PathValue("actor")
+	released := false
+	for _, id := range h.actorIDs(actor) {
+		if hub.ReleaseQuarantine(id) {
+			released = true
+		}
+	}
+	if !released {
+		writeError(w, http.StatusNotFound, "actor is not quarantined")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"released": actor})
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/million_grids/server/internal/auth"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// ipLimiter throttles requests with a token bucket per client IP, like the
+// per-connection limit of WebSocket clients
+type ipLimiter struct {
+	rate  float64
+	burst int
+
+	// Buckets keyed by IP, and when each was last used
+	buckets map[string]*ipBucket
+
+	// Time of the last sweep of refilled buckets
+	lastSweep time.Time
+
+	mu sync.Mutex
+}
+
+type ipBucket struct {
+	limiter  *ws.RateLimiter
+	lastUsed time.Time
+}
+
+// newIPLimiter allows rate requests per second per IP with the given burst. A
+// non-positive rate disables limiting.
+func newIPLimiter(rate float64, burst int) *ipLimiter {
+	return &ipLimiter{rate: rate, burst: burst, buckets: make(map[string]*ipBucket)}
+}
+
+// Allow takes a token from the IP's bucket and reports whether the request may proceed
+func (l *ipLimiter) Allow(ip string) bool {
+	if l.rate <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	bucket, ok := l.buckets[ip]
+	if !ok {
+		bucket = &ipBucket{limiter: ws.NewRateLimiter(l.rate, l.burst)}
+		l.buckets[ip] = bucket
+	}
+	bucket.lastUsed = now
+
+	// Periodically drop buckets that have refilled, which a new bucket replaces exactly
+	if now.Sub(l.lastSweep) > time.Minute {
+		refill := time.Duration(float64(l.burst) / l.rate * float64(time.Second))
+		for key, b := range l.buckets {
+			if now.Sub(b.lastUsed) > refill {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastSweep = now
+	}
+	return bucket.limiter.Allow()
+}
+
+// keyLimiter throttles the requests of each API key with a token bucket at
+// the key's own rate
+type keyLimiter struct {
+	buckets map[uint]*ws.RateLimiter
+	mu      sync.Mutex
+}
+
+func newKeyLimiter() *keyLimiter {
+	return &keyLimiter{buckets: make(map[uint]*ws.RateLimiter)}
+}
+
+// Allow takes a token from the key's bucket and reports whether the request may proceed
+func (l *keyLimiter) Allow(key *auth.APIKey) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	bucket, ok := l.buckets[key.ID]
+	if !ok {
+		bucket = ws.NewRateLimiter(key.Rate, key.Burst)
+		l.buckets[key.ID] = bucket
+	}
+	return bucket.Allow()
+}
+
+// allowPaint takes a token from the bucket of the request's API key when it
+// has its own rate limit, and from the bucket of its IP otherwise
+func (h *handler) allowPaint(identity *auth.Identity, ip string) bool {
+	if identity != nil && identity.APIKey != nil && identity.APIKey.Rate > 0 {
+		return h.keyLimits.Allow(identity.APIKey)
+	}
+	return h.paintLimits.Allow(ip)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/binary"
+	"log/slog"
+	"net/http"
+
+	"github.com/million_grids/server/internal/ws"
+)
+
+// RegionResponse lists the active cells of a region of a canvas
+type RegionResponse struct {
+	Canvas string          `json:"canvas"`
+	Region ws.Region       `json:"region"`
+	Active []ws.ActiveCell `json:"active"`
+}
+
+// Size of the header and of each cell of the binary region format
+const (
+	regionHeaderSize = 5 * 4
+	regionCellSize   = 2*2 + 3
+)
+
+// handleRegion returns the current active cells of a region without going
+// through the hub. Query params: canvas, x1, y1, x2, y2 (half-open bounds,
+// default whole grid) and format: json (sparse cell list, the default) or
+// binary, a big-endian blob of uint32 x1, y1, x2, y2 and cell count followed
+// by each cell as uint16 x-x1, uint16 y-y1 and its R, G, B bytes.
+func (h *handler) handleRegion(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	region, ok := queryRegion(w, r, hub.Grid())
+	if !ok {
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "binary" {
+		writeError(w, http.StatusBadRequest, "format must be json or binary")
+		return
+	}
+
+	cells := hub.Grid().GetActiveCellsInRegion(region.X1, region.Y1, region.X2, region.Y2)
+
+	if format == "binary" {
+		buf := make([]byte, regionHeaderSize, regionHeaderSize+len(cells)*regionCellSize)
+		for i, v := range []int{region.X1, region.Y1, region.X2, region.Y2, len(cells)} {
+			binary.BigEndian.PutUint32(buf[i*4:], uint32(v))
+		}
+		for _, cell := range cells {
+			buf = binary.BigEndian.AppendUint16(buf, uint16(cell.X-region.X1))
+			buf = binary.BigEndian.AppendUint16(buf, uint16(cell.Y-region.Y1))
+			buf = append(buf, byte(cell.Color>>16), byte(cell.Color>>8), byte(cell.Color))
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		if _, err := w.Write(buf); err != nil {
+			slog.Debug("Failed to write region", "err", err)
+		}
+		return
+	}
+
+	active := make([]ws.ActiveCell, len(cells))
+	for i, cell := range cells {
+		active[i] = ws.ActiveCell{X: cell.X, Y: cell.Y, Color: cell.Color}
+	}
+	writeJSON(w, http.StatusOK, RegionResponse{Canvas: hub.Canvas(), Region: region, Active: active})
+}
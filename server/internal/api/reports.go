@@ -0,0 +1,260 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/ws"
+)
+
+const (
+	// Largest region a report may cover, in cells
+	maxReportCells = 256 * 256
+
+	// Longest report reason in characters
+	maxReportReason = 500
+
+	// Number of reports returned when no limit is given, and at most
+	defaultReportLimit = 100
+	maxReportLimit     = 1000
+
+	// Time before a report whose changes to the region are listed with it
+	reportHistoryWindow = 24 * time.Hour
+)
+
+// ReportRequest flags a cell ("x" and "y") or a region ("x1" to "y2") of a
+// canvas as offensive
+type ReportRequest struct {
+	X *int `json:"x"`
+	Y *int `json:"y"`
+	ws.Region
+
+	Reason string `json:"reason"`
+}
+
+// ReportDetail is a report with the recent changes to its region, and the
+// rollbacks that would revert them
+type ReportDetail struct {
+	db.Report
+	History []db.PixelHistory `json:"history"`
+
+	// One per actor that changed the region, each the body of a POST /admin/rollback
+	Rollbacks []ReportRollback `json:"rollbacks"`
+}
+
+// ReportRollback reverts the changes an actor made to a reported region, and
+// any others they made on the canvas meanwhile
+type ReportRollback struct {
+	Actor string    `json:"actor"`
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+
+	// Changes by the actor in the listed history
+	Changes int `json:"changes"`
+}
+
+// handleReport files a report of a cell or region of the canvas named by
+// ?canvas= with a reason, replying 201 with the report, or 200 with the open
+// report of the same region it was merged into. Each reporter counts once per
+// report. Requests count against the per-IP paint rate limit.
+func (h *handler) handleReport(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	if db.Queue() == nil {
+		writeError(w, http.StatusNotFound, "no database configured")
+		return
+	}
+	identity, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	ip := ClientIP(r)
+	if !h.allowPaint(identity, ip) {
+		w.Header().Set("Retry-After", "1")
+		writeCodedError(w, http.StatusTooManyRequests, "rate_limited", "too many requests, slow down")
+		return
+	}
+
+	var body ReportRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	region := body.Region
+	if body.X != nil || body.Y != nil {
+		if body.X == nil || body.Y == nil || region != (ws.Region{}) {
+			writeError(w, http.StatusBadRequest, "report either a cell or a region")
+			return
+		}
+		if !hub.Grid().InBounds(*body.X, *body.Y) {
+			writeError(w, http.StatusBadRequest, "cell is outside the grid")
+			return
+		}
+		region = ws.CellRegion(*body.X, *body.Y)
+	}
+	region = region.Clamp(hub.Grid().Width(), hub.Grid().Height())
+	if region.Empty() {
+		writeError(w, http.StatusBadRequest, "region is empty")
+		return
+	}
+	if (region.X2-region.X1)*(region.Y2-region.Y1) > maxReportCells {
+		writeError(w, http.StatusBadRequest, "region is too large")
+		return
+	}
+	reason := strings.TrimSpace(body.Reason)
+	if reason == "" || utf8.RuneCountInString(reason) > maxReportReason {
+		writeError(w, http.StatusBadRequest, "a reason of up to 500 characters is required")
+		return
+	}
+
+	reporter := hub.AnonymousActor(ip)
+	if identity != nil {
+		reporter = identity.UserID
+	}
+	report := db.Report{Canvas: hub.Canvas(), X1: region.X1, Y1: region.Y1, X2: region.X2, Y2: region.Y2, Reason: reason}
+	created, err := db.FileReport(&report, reporter)
+	if err != nil {
+		slog.Error("Failed to file report", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to file report")
+		return
+	}
+	if !created {
+		writeJSON(w, http.StatusOK, report)
+		return
+	}
+	slog.Info("Region reported", "canvas", hub.Canvas(), "id", report.ID, "region", region)
+	writeJSON(w, http.StatusCreated, report)
+}
+
+// handleListReports returns the report queue of the canvas: the reports with
+// ?status= (open by default), the most reported first, up to ?limit= of them
+func (h *adminHandler) handleListReports(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = db.ReportOpen
+	}
+	if !validReportStatus(status) {
+		writeError(w, http.StatusBadRequest, "status must be open, resolved or dismissed")
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultReportLimit)
+	if err != nil || limit < 1 {
+		writeError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+
+	reports, err := db.LoadReports(hub.Canvas(), status, min(limit, maxReportLimit))
+	if err != nil {
+		slog.Error("Failed to load reports", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to load reports")
+		return
+	}
+	if reports == nil {
+		reports = []db.Report{}
+	}
+	writeJSON(w, http.StatusOK, map[string][]db.Report{"reports": reports})
+}
+
+// handleReportDetail returns a report with the changes made to its region
+// since shortly before it was filed, and per actor the rollback reverting them
+func (h *adminHandler) handleReportDetail(w http.ResponseWriter, r *http.Request) {
+	report, ok := loadReport(w, r)
+	if !ok {
+		return
+	}
+
+	// Make sure the history includes changes still waiting in the write queue
+	if err := db.FlushPending(); err != nil {
+		slog.Error("Failed to flush pending writes before loading report history", "err", err)
+	}
+	history, err := db.RegionHistory(report.Canvas, report.X1, report.Y1, report.X2, report.Y2, report.CreatedAt.Add(-reportHistoryWindow), maxHistoryLimit)
+	if err != nil {
+		slog.Error("Failed to load report history", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to load history")
+		return
+	}
+	detail := ReportDetail{Report: *report, History: history, Rollbacks: []ReportRollback{}}
+	if detail.History == nil {
+		detail.History = []db.PixelHistory{}
+	}
+
+	// History is newest first, so an actor's first record is its latest change
+	rollbacks := make(map[string]int)
+	for _, rec := range history {
+		if rec.Actor == "" {
+			continue
+		}
+		i, ok := rollbacks[rec.Actor]
+		if !ok {
+			i = len(detail.Rollbacks)
+			rollbacks[rec.Actor] = i
+			detail.Rollbacks = append(detail.Rollbacks, ReportRollback{Actor: rec.Actor, To: rec.CreatedAt})
+		}
+		detail.Rollbacks[i].From = rec.CreatedAt
+		detail.Rollbacks[i].Changes++
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+// handleSetReportStatus closes or reopens a report from the body
+// {"status": "resolved" | "dismissed" | "open"}
+func (h *adminHandler) handleSetReportStatus(w http.ResponseWriter, r *http.Request) {
+	report, ok := loadReport(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Status string `json:"status"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if !validReportStatus(body.Status) {
+		writeError(w, http.StatusBadRequest, "status must be open, resolved or dismissed")
+		return
+	}
+	if err := db.SetReportStatus(report.ID, body.Status); err != nil {
+		slog.Error("Failed to update report", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to update report")
+		return
+	}
+	slog.Info("Report status changed", "canvas", report.Canvas, "id", report.ID, "status", body.Status)
+	report.Status, report.UpdatedAt = body.Status, time.Now()
+	writeJSON(w, http.StatusOK, report)
+}
+
+// loadReport loads the report whose ID is in the path, writing an error
+// response and returning false if it is invalid or unknown
+func loadReport(w http.ResponseWriter, r *http.Request) (*db.Report, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid report id")
+		return nil, false
+	}
+	report, err := db.LoadReport(uint(id))
+	if err != nil {
+		slog.Error("Failed to load report", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to load report")
+		return nil, false
+	}
+	if report == nil {
+		writeError(w, http.StatusNotFound, "unknown report")
+		return nil, false
+	}
+	return report, true
+}
+
+// validReportStatus reports whether status is one of the report statuses
+func validReportStatus(status string) bool {
+	return status == db.ReportOpen || status == db.ReportResolved || status == db.ReportDismissed
+}
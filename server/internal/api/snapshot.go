@@ -0,0 +1,101 @@
+package api
+
+import (
+	"image"
+	"image/png"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/million_grids/server/internal/ws"
+)
+
+const (
+	// Maximum scale factor (output pixels per cell side)
+	maxSnapshotScale = 16
+
+	// Maximum width or height of a rendered snapshot in pixels
+	maxSnapshotDimension = 4096
+)
+
+// handleSnapshot renders the live grid (or a bounding box of it) as a PNG.
+// Query params: canvas, x1, y1, x2, y2 (half-open bounds, default whole grid) and scale.
+func (h *handler) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	region, ok := queryRegion(w, r, hub.Grid())
+	if !ok {
+		return
+	}
+
+	scale, err := queryInt(r, "scale", 1)
+	if err != nil || scale < 1 || scale > maxSnapshotScale {
+		writeError(w, http.StatusBadRequest, "scale must be between 1 and "+strconv.Itoa(maxSnapshotScale))
+		return
+	}
+
+	width := (region.X2 - region.X1) * scale
+	height := (region.Y2 - region.Y1) * scale
+	if width > maxSnapshotDimension || height > maxSnapshotDimension {
+		writeError(w, http.StatusBadRequest, "snapshot too large, reduce the region or scale")
+		return
+	}
+
+	img := renderRegion(hub.Grid(), region, scale)
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := png.Encode(w, img); err != nil {
+		slog.Error("Failed to encode snapshot", "err", err)
+	}
+}
+
+// renderRegion draws the cells of a region onto a white image, scale pixels per cell
+func renderRegion(grid *ws.GridState, region ws.Region, scale int) *image.RGBA {
+	width := (region.X2 - region.X1) * scale
+	height := (region.Y2 - region.Y1) * scale
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	// Inactive cells are white
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+
+	for _, cell := range grid.GetActiveCellsInRegion(region.X1, region.Y1, region.X2, region.Y2) {
+		c := cell.Color.RGBA()
+		px := (cell.X - region.X1) * scale
+		py := (cell.Y - region.Y1) * scale
+		for dx := 0; dx < scale; dx++ {
+			for dy := 0; dy < scale; dy++ {
+				img.SetRGBA(px+dx, py+dy, c)
+			}
+		}
+	}
+	return img
+}
+
+// queryRegion parses the x1, y1, x2, y2 query params (defaulting to the whole
+// grid), writing an error response and returning false if they are invalid
+func queryRegion(w http.ResponseWriter, r *http.Request, grid *ws.GridState) (ws.Region, bool) {
+	var region ws.Region
+	var errs [4]error
+	region.X1, errs[0] = queryInt(r, "x1", 0)
+	region.Y1, errs[1] = queryInt(r, "y1", 0)
+	region.X2, errs[2] = queryInt(r, "x2", grid.Width())
+	region.Y2, errs[3] = queryInt(r, "y2", grid.Height())
+	for _, err := range errs {
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid region coordinates")
+			return region, false
+		}
+	}
+
+	region = region.Clamp(grid.Width(), grid.Height())
+	if region.Empty() {
+		writeError(w, http.StatusBadRequest, "region is empty")
+		return region, false
+	}
+	return region, true
+}
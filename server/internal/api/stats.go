@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// StatsResponse summarizes the state and recent activity of a canvas
+type StatsResponse struct {
+	Canvas string `json:"canvas"`
+
+	// Number of active cells, in total and per "#RRGGBB" color
+	Active int            `json:"active"`
+	Colors map[string]int `json:"colors"`
+
+	// Cells changed in the last hour and day, across all instances
+	PlacementsLastHour int `json:"placements_last_hour"`
+	PlacementsLastDay  int `json:"placements_last_day"`
+
+	// Cells changed in the last day per country code of the painter, for
+	// changes made through this instance from a known location
+	Countries map[string]int `json:"countries_last_day"`
+
+	// Clients connected to this instance
+	Clients int `json:"clients"`
+
+	// Seconds since the server started
+	Uptime int64 `json:"uptime"`
+}
+
+// handleStats returns the statistics of a canvas. The color counts come from
+// its read model when read models are enabled, and are otherwise kept up to
+// date by the grid as cells change, so this doesn't scan the grid.
+// Query param: canvas.
+func (h *handler) handleStats(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+
+	var counts map[model.Color]int
+	if m := h.readModel(hub.Canvas()); m != nil {
+		counts = m.ColorCounts()
+	} else {
+		counts = hub.Grid().ColorCounts()
+	}
+	colors := make(map[string]int, len(counts))
+	active := 0
+	for color, n := range counts {
+		colors[color.String()] = n
+		active += n
+	}
+
+	now := time.Now()
+	writeJSON(w, http.StatusOK, StatsResponse{
+		Canvas:             hub.Canvas(),
+		Active:             active,
+		Colors:             colors,
+		PlacementsLastHour: hub.Activity().Since(time.Hour, now),
+		PlacementsLastDay:  hub.Activity().Since(24*time.Hour, now),
+		Countries:          hub.Activity().CountriesSince(24*time.Hour, now),
+		Clients:            hub.ClientCount(),
+		Uptime:             int64(now.Sub(h.started).Seconds()),
+	})
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/model"
+	"github.com/million_grids/server/internal/snapshot"
+	"github.com/million_grids/server/internal/ws"
+)
+
+const (
+	// Default and maximum number of canvas events listed per request
+	defaultEventsLimit = 100
+	maxEventsLimit     = 1000
+)
+
+// handleListCanvasEvents returns the events of the canvas other than
+// placements (resets, bans and shadow bans) recorded after the ID in ?after=,
+// oldest first, up to ?limit=. Replicas tailing the stream page through it
+// with the ID of the last event.
+func (h *adminHandler) handleListCanvasEvents(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	after, err := queryInt(r, "after", 0)
+	if err != nil || after < 0 {
+		writeError(w, http.StatusBadRequest, "after must be an event ID")
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultEventsLimit)
+	if err != nil || limit < 1 || limit > maxEventsLimit {
+		writeError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+		return
+	}
+
+	events, err := db.CanvasEvents(hub.Canvas(), uint64(after), limit)
+	if err != nil {
+		slog.Error("Failed to load canvas events", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to load events")
+		return
+	}
+	if events == nil {
+		events = []db.CanvasEvent{}
+	}
+	writeJSON(w, http.StatusOK, map[string][]db.CanvasEvent{"events": events})
+}
+
+// handleRevert brings the canvas back to its state at the time from the body
+// {"at": RFC3339}, replayed from its event stream. The cells that differ are
+// painted back as new placements, so the revert itself can be reverted.
+func (h *adminHandler) handleRevert(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	var body struct {
+		At time.Time `json:"at"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.At.IsZero() || body.At.After(time.Now()) {
+		writeError(w, http.StatusBadRequest, "at must be a past time")
+		return
+	}
+
+	var snapshots *snapshot.Store
+	if h.snapshots != nil {
+		snapshots = h.snapshots.Store()
+	}
+	grid := hub.Grid()
+	region := ws.Region{X1: 0, Y1: 0, X2: grid.Width(), Y2: grid.Height()}
+	past, err := gridAt(snapshots, hub, region, body.At)
+	if err != nil {
+		slog.Error("Failed to replay canvas for revert", "canvas", hub.Canvas(), "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to replay canvas")
+		return
+	}
+
+	// Cells active now or then whose state changed since
+	var states []model.Pixel
+	for _, cell := range grid.GetActiveCells() {
+		if was := past.GetCell(cell.X, cell.Y); !was.Active {
+			states = append(states, model.Pixel{Canvas: hub.Canvas(), X: cell.X, Y: cell.Y, Active: false, Color: model.White})
+		}
+	}
+	for _, cell := range past.GetActiveCells() {
+		if now := grid.GetCell(cell.X, cell.Y); !now.Active || now.Color != cell.Color {
+			states = append(states, model.Pixel{Canvas: hub.Canvas(), X: cell.X, Y: cell.Y, Active: true, Color: cell.Color})
+		}
+	}
+
+	changed, err := hub.SetCells(r.Context(), states, adminActor)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "failed to save the changes, try again")
+		return
+	}
+	slog.Info("Reverted canvas", "canvas", hub.Canvas(), "at", body.At, "reverted", len(changed))
+	writeJSON(w, http.StatusOK, map[string]int{"reverted": len(changed)})
+}
+
+// handleRebuildPixels rebuilds the pixels table of the canvas, a projection of
+// its event stream, e.g. after restoring the history from a backup
+func (h *adminHandler) handleRebuildPixels(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+
+	// The stream must include the changes still waiting in the write queue
+	if err := db.FlushPending(); err != nil {
+		slog.Error("Failed to flush pending writes before rebuild", "err", err)
+		writeError(w, http.StatusServiceUnavailable, "failed to write pending changes, try again")
+		return
+	}
+	start := time.Now()
+	cells, err := db.RebuildPixels(hub.Canvas())
+	if err != nil {
+		slog.Error("Failed to rebuild pixels", "canvas", hub.Canvas(), "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to rebuild pixels")
+		return
+	}
+	slog.Info("Rebuilt pixels from event stream", "canvas", hub.Canvas(), "cells", cells, "duration", time.Since(start))
+	writeJSON(w, http.StatusOK, map[string]int{"cells": cells})
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// TeamLeaderboardResponse lists the teams of a canvas with the most placements over a window
+type TeamLeaderboardResponse struct {
+	Canvas string         `json:"canvas"`
+	Window string         `json:"window"`
+	Teams  []db.TeamScore `json:"teams"`
+}
+
+// handleTeams returns the teams of the canvas named by ?canvas= with the
+// number of cells each one currently holds, most first
+func (h *handler) handleTeams(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]ws.TeamScore{"teams": hub.TeamScores()})
+}
+
+// handleTeamLeaderboard returns the teams that changed the most cells, with
+// the same windows, limits and read models as handleLeaderboard
+func (h *handler) handleTeamLeaderboard(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	window, since, limit, ok := leaderboardQuery(w, r)
+	if !ok {
+		return
+	}
+	if m := h.readModel(hub.Canvas()); m != nil && window != "all" {
+		writeJSON(w, http.StatusOK, TeamLeaderboardResponse{Canvas: hub.Canvas(), Window: window, Teams: m.TopTeams(since, limit)})
+		return
+	}
+	top, err := db.TopTeams(hub.Canvas(), since, limit)
+	if err != nil {
+		slog.Error("Failed to load team leaderboard", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to load team leaderboard")
+		return
+	}
+
+	if top == nil {
+		top = []db.TeamScore{}
+	}
+	writeJSON(w, http.StatusOK, TeamLeaderboardResponse{Canvas: hub.Canvas(), Window: window, Teams: top})
+}
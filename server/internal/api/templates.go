@@ -0,0 +1,222 @@
+package api
+
+import (
+	"bytes"
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/ws"
+)
+
+const (
+	// Largest template width and height in cells
+	maxTemplateSide = 512
+
+	// Largest template upload in bytes
+	maxTemplateBytes = 4 << 20
+
+	// Maximum number of templates per canvas
+	maxTemplatesPerCanvas = 50
+
+	// Longest template name in characters
+	maxTemplateNameLength = 64
+
+	// Number of differing cells returned when no limit is given, and at most
+	defaultTemplateDiffLimit = 1000
+	maxTemplateDiffLimit     = 10000
+)
+
+// TemplateDiffResponse lists the cells of the canvas differing from a template
+type TemplateDiffResponse struct {
+	ID uint `json:"id"`
+
+	// Number of cells differing, of which the first ones in row order are in cells
+	Wrong int               `json:"wrong"`
+	Cells []ws.TemplateCell `json:"cells"`
+}
+
+// handleTemplates returns the templates of the canvas named by ?canvas= with
+// their completion
+func (h *handler) handleTemplates(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]ws.TemplateInfo{"templates": hub.Templates()})
+}
+
+// handleTemplate returns the completion of a template
+func (h *handler) handleTemplate(w http.ResponseWriter, r *http.Request) {
+	hub, id, ok := templateHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	info, ok := hub.Template(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown template")
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// handleTemplateImage returns the target image of a template as a PNG, one
+// pixel per cell, for clients to overlay on the canvas
+func (h *handler) handleTemplateImage(w http.ResponseWriter, r *http.Request) {
+	hub, id, ok := templateHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	img, ok := hub.TemplateImage(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown template")
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	if err := png.Encode(w, img); err != nil {
+		slog.Error("Failed to encode template", "err", err)
+	}
+}
+
+// handleTemplateDiff returns the cells of the canvas that differ from a
+// template, with the color they should have. Query params: canvas and limit.
+func (h *handler) handleTemplateDiff(w http.ResponseWriter, r *http.Request) {
+	hub, id, ok := templateHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultTemplateDiffLimit)
+	if err != nil || limit < 1 {
+		writeError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+	cells, wrong, ok := hub.TemplateDiff(id, min(limit, maxTemplateDiffLimit))
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown template")
+		return
+	}
+	writeJSON(w, http.StatusOK, TemplateDiffResponse{ID: id, Wrong: wrong, Cells: cells})
+}
+
+// handleUploadTemplate maps the PNG in the request body onto the canvas with
+// its top-left corner at ?x=&y=, under ?name=. Transparent pixels are left out
+// of the template and the others become their nearest paintable color.
+// Uploads count against the per-IP paint rate limit.
+func (h *handler) handleUploadTemplate(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	identity, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	ip := ClientIP(r)
+	if !h.allowPaint(identity, ip) {
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusTooManyRequests, "too many requests, slow down")
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" || utf8.RuneCountInString(name) > maxTemplateNameLength {
+		writeError(w, http.StatusBadRequest, "name must be between 1 and "+strconv.Itoa(maxTemplateNameLength)+" characters")
+		return
+	}
+	x, errX := queryInt(r, "x", 0)
+	y, errY := queryInt(r, "y", 0)
+	if errX != nil || errY != nil {
+		writeError(w, http.StatusBadRequest, "invalid position")
+		return
+	}
+	if len(hub.Templates()) >= maxTemplatesPerCanvas {
+		writeError(w, http.StatusConflict, "the canvas has too many templates")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTemplateBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "image must be at most "+strconv.Itoa(maxTemplateBytes>>20)+" MiB")
+		return
+	}
+	// Check the dimensions before decoding, so a small file can't claim a huge image
+	config, err := png.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "body must be a PNG image")
+		return
+	}
+	if config.Width > maxTemplateSide || config.Height > maxTemplateSide {
+		writeError(w, http.StatusBadRequest, "image must be at most "+strconv.Itoa(maxTemplateSide)+" pixels wide and high")
+		return
+	}
+	img, err := png.Decode(bytes.NewReader(body))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "body must be a PNG image")
+		return
+	}
+	template := ws.NewTemplate(0, name, x, y, img)
+	if template.Region.Empty() || template.Region.Clamp(hub.Grid().Width(), hub.Grid().Height()) != template.Region {
+		writeError(w, http.StatusBadRequest, "template must fit on the canvas")
+		return
+	}
+	if template.Empty() {
+		writeError(w, http.StatusBadRequest, "template has no opaque pixels")
+		return
+	}
+
+	encoded, err := template.EncodePNG()
+	if err != nil {
+		slog.Error("Failed to encode template", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to save template")
+		return
+	}
+	record := db.Template{Canvas: hub.Canvas(), Name: name, X: x, Y: y, Image: encoded, CreatedBy: hub.AnonymousActor(ip)}
+	if identity != nil {
+		record.CreatedBy = identity.UserID
+	}
+	if err := db.SaveTemplate(&record); err != nil {
+		slog.Error("Failed to save template", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to save template")
+		return
+	}
+	template.ID = record.ID
+	writeJSON(w, http.StatusCreated, hub.AddTemplate(template))
+}
+
+// handleRemoveTemplate removes a template by ID
+func (h *adminHandler) handleRemoveTemplate(w http.ResponseWriter, r *http.Request) {
+	hub, id, ok := templateHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	if !hub.RemoveTemplate(id) {
+		writeError(w, http.StatusNotFound, "unknown template")
+		return
+	}
+	if err := db.DeleteTemplate(id); err != nil {
+		slog.Error("Failed to delete template", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete template")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]uint{"removed": id})
+}
+
+// templateHub looks up the hub of the canvas named by ?canvas= and parses
+// the template ID in the path, writing an error response and returning false
+// if either is invalid
+func templateHub(w http.ResponseWriter, r *http.Request, canvases *ws.Canvases) (*ws.Hub, uint, bool) {
+	hub, ok := canvasHub(w, r, canvases)
+	if !ok {
+		return nil, 0, false
+	}
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid template id")
+		return nil, 0, false
+	}
+	return hub, uint(id), true
+}
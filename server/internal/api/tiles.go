@@ -0,0 +1,227 @@
+package api
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/million_grids/server/internal/ws"
+)
+
+const (
+	// Width and height of a tile in pixels
+	tileSize = 256
+
+	// Zoom levels served past the native zoom, where a cell becomes 2^level pixels wide
+	maxOverzoom = 4
+
+	// Maximum number of rendered tiles kept in memory
+	tileCacheSize = 4096
+)
+
+// tileKey identifies a tile of a canvas
+type tileKey struct {
+	canvas  string
+	z, x, y int
+}
+
+// cachedTile is a rendered tile and the grid version it was rendered from
+type cachedTile struct {
+	key     tileKey
+	version uint64
+	png     []byte
+}
+
+// tileCache keeps the most recently used rendered tiles. A tile stays valid
+// until the grid version of the region it covers changes.
+type tileCache struct {
+	max int
+
+	// Start time of the process, in ETags so they don't match tiles rendered
+	// before a restart (versions start over)
+	epoch int64
+
+	// Tiles keyed by position, and their elements in order (most recently used first)
+	entries map[tileKey]*list.Element
+	order   *list.List
+
+	mu sync.Mutex
+}
+
+func newTileCache(max int) *tileCache {
+	return &tileCache{max: max, epoch: time.Now().UnixNano(), entries: make(map[tileKey]*list.Element), order: list.New()}
+}
+
+// get returns the tile if it was rendered from the given version
+func (c *tileCache) get(key tileKey, version uint64) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok || elem.Value.(*cachedTile).version != version {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cachedTile).png, true
+}
+
+// put stores a rendered tile, evicting the least recently used one if the cache is full
+func (c *tileCache) put(key tileKey, version uint64, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = &cachedTile{key: key, version: version, png: data}
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cachedTile{key: key, version: version, png: data})
+	if c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedTile).key)
+	}
+}
+
+// tileGeometry is the part of the grid a tile covers and how cells map to its pixels
+type tileGeometry struct {
+	// Cells covered by the tile, clamped to the grid
+	region ws.Region
+
+	// Top-left cell of the tile
+	x0, y0 int
+
+	// Each pixel covers 2^shift cells per side when zoomed out, and each cell
+	// is scale pixels wide when zoomed in
+	shift, scale int
+}
+
+// nativeZoom returns the zoom level at which a cell is one pixel, the lowest
+// at which the tiles cover the whole grid
+func nativeZoom(width, height int) int {
+	zoom := 0
+	for tileSize<<zoom < max(width, height) {
+		zoom++
+	}
+	return zoom
+}
+
+// tileAt returns the geometry of a tile, or false if it lies outside the grid
+// or the served zoom levels
+func tileAt(grid *ws.GridState, z, x, y int) (tileGeometry, bool) {
+	native := nativeZoom(grid.Width(), grid.Height())
+	if z < 0 || z > native+maxOverzoom || x < 0 || y < 0 {
+		return tileGeometry{}, false
+	}
+
+	t := tileGeometry{scale: 1}
+	span := tileSize
+	if z <= native {
+		t.shift = native - z
+		span <<= t.shift
+	} else {
+		t.scale = 1 << (z - native)
+		span /= t.scale
+	}
+
+	t.x0, t.y0 = x*span, y*span
+	if t.x0 >= grid.Width() || t.y0 >= grid.Height() {
+		return tileGeometry{}, false
+	}
+	t.region = ws.Region{X1: t.x0, Y1: t.y0, X2: t.x0 + span, Y2: t.y0 + span}.Clamp(grid.Width(), grid.Height())
+	return t, true
+}
+
+// renderTile draws the tile's cells. When zoomed out, a pixel takes the color
+// of an active cell it covers so sparse pixels stay visible; the part of the
+// tile past the grid's edge is transparent.
+func renderTile(grid *ws.GridState, t tileGeometry) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, tileSize, tileSize))
+
+	// Inactive cells are white
+	cellsPerPixel := 1 << t.shift
+	width := (t.region.X2 - t.x0 + cellsPerPixel - 1) >> t.shift * t.scale
+	height := (t.region.Y2 - t.y0 + cellsPerPixel - 1) >> t.shift * t.scale
+	for py := 0; py < height; py++ {
+		for px := 0; px < width; px++ {
+			img.SetRGBA(px, py, color.RGBA{0xFF, 0xFF, 0xFF, 0xFF})
+		}
+	}
+
+	region := t.region
+	for _, cell := range grid.GetActiveCellsInRegion(region.X1, region.Y1, region.X2, region.Y2) {
+		c := cell.Color.RGBA()
+		px := (cell.X - t.x0) >> t.shift * t.scale
+		py := (cell.Y - t.y0) >> t.shift * t.scale
+		for dx := 0; dx < t.scale; dx++ {
+			for dy := 0; dy < t.scale; dy++ {
+				img.SetRGBA(px+dx, py+dy, c)
+			}
+		}
+	}
+	return img
+}
+
+// handleTile serves a 256x256 PNG slippy-map tile of the canvas, for map
+// viewers such as Leaflet or OpenLayers. At the lowest zoom one tile covers the
+// whole grid; zoom levels double the resolution up to one cell per pixel and
+// then magnify the cells a further maxOverzoom levels. Tiles are cached until
+// a cell they cover changes, and revalidated by clients through their ETag.
+// Query param: canvas.
+func (h *handler) handleTile(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+
+	z, errZ := strconv.Atoi(r.PathValue("z"))
+	x, errX := strconv.Atoi(r.PathValue("x"))
+	name, isPNG := strings.CutSuffix(r.PathValue("y"), ".png")
+	y, errY := strconv.Atoi(name)
+	if errZ != nil || errX != nil || errY != nil || !isPNG {
+		writeError(w, http.StatusBadRequest, "tile path must be /tiles/{z}/{x}/{y}.png")
+		return
+	}
+
+	grid := hub.Grid()
+	t, ok := tileAt(grid, z, x, y)
+	if !ok {
+		writeError(w, http.StatusNotFound, "tile outside the canvas")
+		return
+	}
+
+	// Read the version before rendering, so a change made meanwhile
+	// invalidates the tile on the next request
+	version := grid.Version(t.region)
+	etag := fmt.Sprintf(`"%x-%x"`, h.tiles.epoch, version)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	key := tileKey{canvas: hub.Canvas(), z: z, x: x, y: y}
+	data, ok := h.tiles.get(key, version)
+	if !ok {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, renderTile(grid, t)); err != nil {
+			slog.Error("Failed to encode tile", "err", err)
+			writeError(w, http.StatusInternalServerError, "failed to render tile")
+			return
+		}
+		data = buf.Bytes()
+		h.tiles.put(key, version, data)
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+}
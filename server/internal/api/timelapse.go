@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/million_grids/server/internal/timelapse"
+)
+
+const (
+	// Frames in a timelapse when no interval is given
+	defaultTimelapseFrames = 100
+
+	// Time range of a timelapse when no start is given
+	defaultTimelapseRange = time.Hour
+)
+
+// handleCreateTimelapse queues a timelapse GIF of a canvas for rendering.
+// Query params: canvas, x1, y1, x2, y2 (half-open bounds, default whole grid),
+// scale, from and to (RFC 3339, default the last hour), interval (canvas time
+// per frame, e.g. "5m", default a hundredth of the range) and delay_ms
+// (display time per frame, default 100).
+func (h *handler) handleCreateTimelapse(w http.ResponseWriter, r *http.Request) {
+	hub, ok := canvasHub(w, r, h.canvases)
+	if !ok {
+		return
+	}
+	region, ok := queryRegion(w, r, hub.Grid())
+	if !ok {
+		return
+	}
+
+	req := timelapse.Request{
+		Canvas: hub.Canvas(),
+		X1:     region.X1,
+		Y1:     region.Y1,
+		X2:     region.X2,
+		Y2:     region.Y2,
+	}
+	var err error
+	if req.Scale, err = queryInt(r, "scale", 1); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid scale")
+		return
+	}
+	delay, err := queryInt(r, "delay_ms", 100)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid delay_ms")
+		return
+	}
+	req.Delay = time.Duration(delay) * time.Millisecond
+
+	if req.To, err = queryTime(r, "to", time.Now()); err != nil {
+		writeError(w, http.StatusBadRequest, "to must be an RFC 3339 time")
+		return
+	}
+	if req.From, err = queryTime(r, "from", req.To.Add(-defaultTimelapseRange)); err != nil {
+		writeError(w, http.StatusBadRequest, "from must be an RFC 3339 time")
+		return
+	}
+
+	req.Interval = max(req.To.Sub(req.From)/defaultTimelapseFrames, time.Second)
+	if value := r.URL.Query().Get("interval"); value != "" {
+		if req.Interval, err = time.ParseDuration(value); err != nil {
+			writeError(w, http.StatusBadRequest, "interval must be a duration such as 5m")
+			return
+		}
+	}
+
+	job, err := h.timelapses.Submit(req)
+	if errors.Is(err, timelapse.ErrQueueFull) {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Location", "/timelapse/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// handleTimelapseStatus reports the progress of a timelapse job
+func (h *handler) handleTimelapseStatus(w http.ResponseWriter, r *http.Request) {
+	job, _, ok := h.timelapses.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown timelapse")
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// handleTimelapseGIF serves the rendered GIF of a finished timelapse job
+func (h *handler) handleTimelapseGIF(w http.ResponseWriter, r *http.Request) {
+	job, gif, ok := h.timelapses.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown timelapse")
+		return
+	}
+
+	switch job.Status {
+	case timelapse.StatusDone:
+		w.Header().Set("Content-Type", "image/gif")
+		w.Write(gif)
+	case timelapse.StatusFailed:
+		writeError(w, http.StatusInternalServerError, "timelapse failed: "+job.Error)
+	default:
+		writeError(w, http.StatusConflict, "timelapse is not ready yet")
+	}
+}
+
+// queryTime parses an RFC 3339 query parameter, returning def if it is absent
+func queryTime(r *http.Request, key string, def time.Time) (time.Time, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return def, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/webhook"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// WebhookRequest registers a webhook
+type WebhookRequest struct {
+	URL string `json:"url"`
+
+	// Event types to deliver (default all)
+	Events []string `json:"events"`
+
+	// Canvas the events come from (empty for every canvas)
+	Canvas string `json:"canvas"`
+
+	// Region pixel events are limited to (optional)
+	Region *ws.Region `json:"region"`
+
+	// Maximum deliveries per minute (0 for no limit)
+	MaxPerMinute int `json:"max_per_minute"`
+
+	// Signing key (generated when empty)
+	Secret string `json:"secret"`
+}
+
+// WebhookCreated is the registered webhook, with the secret its payloads are
+// signed with (only returned at registration)
+type WebhookCreated struct {
+	webhook.Info
+	Secret string `json:"secret"`
+}
+
+// handleListWebhooks returns the registered webhooks and their delivery stats
+func (h *adminHandler) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string][]webhook.Info{"webhooks": h.webhooks.List()})
+}
+
+// handleAddWebhook registers a webhook from a WebhookRequest body
+func (h *adminHandler) handleAddWebhook(w http.ResponseWriter, r *http.Request) {
+	var body WebhookRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if u, err := url.Parse(body.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		writeError(w, http.StatusBadRequest, "url must be an http or https URL")
+		return
+	}
+	if len(body.Events) == 0 {
+		body.Events = webhook.EventTypes
+	}
+	for _, event := range body.Events {
+		if !slices.Contains(webhook.EventTypes, event) {
+			writeError(w, http.StatusBadRequest, "events must be among "+strings.Join(webhook.EventTypes, ", "))
+			return
+		}
+	}
+	if body.Canvas != "" {
+		if _, ok := h.canvases.Get(body.Canvas); !ok {
+			writeError(w, http.StatusBadRequest, "unknown canvas")
+			return
+		}
+	}
+	if body.Region != nil && body.Region.Empty() {
+		writeError(w, http.StatusBadRequest, "region is empty")
+		return
+	}
+	if body.MaxPerMinute < 0 {
+		writeError(w, http.StatusBadRequest, "max_per_minute must not be negative")
+		return
+	}
+	if body.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			slog.Error("Failed to generate webhook secret", "err", err)
+			writeError(w, http.StatusInternalServerError, "failed to generate secret")
+			return
+		}
+		body.Secret = hex.EncodeToString(secret)
+	}
+
+	hook := db.Webhook{
+		URL:          body.URL,
+		Secret:       body.Secret,
+		Events:       strings.Join(body.Events, ","),
+		Canvas:       body.Canvas,
+		MaxPerMinute: body.MaxPerMinute,
+		CreatedAt:    time.Now(),
+	}
+	if body.Region != nil {
+		hook.HasRegion = true
+		hook.X1, hook.Y1, hook.X2, hook.Y2 = body.Region.X1, body.Region.Y1, body.Region.X2, body.Region.Y2
+	}
+	if err := h.webhooks.Add(&hook); err != nil {
+		slog.Error("Failed to add webhook", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to save webhook")
+		return
+	}
+
+	slog.Info("Registered webhook", "id", hook.ID, "url", hook.URL, "events", hook.Events)
+	writeJSON(w, http.StatusCreated, WebhookCreated{Info: webhook.Info{Webhook: hook, Region: body.Region}, Secret: hook.Secret})
+}
+
+// handleRemoveWebhook unregisters a webhook
+func (h *adminHandler) handleRemoveWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid webhook id")
+		return
+	}
+	found, err := h.webhooks.Remove(uint(id))
+	if err != nil {
+		slog.Error("Failed to delete webhook", "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete webhook")
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "unknown webhook")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]uint{"removed": uint(id)})
+}
@@ -0,0 +1,160 @@
+// Package audit records every mutation of the canvases (paints, admin
+// requests and resets) with who made it, for accountability and abuse
+// investigations.
+package audit
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/model"
+)
+
+const (
+	// Entries waiting to be written, further ones are dropped
+	queueSize = 4096
+
+	// Entries written per insert, and the longest an entry waits for it
+	batchSize     = 256
+	flushInterval = time.Second
+
+	// Time between deletions of the entries past retention
+	pruneInterval = time.Hour
+
+	// Cells listed in the payload of a paint entry, larger changes are counted
+	maxPaintCells = 100
+)
+
+// Log writes audit entries to the database in the background. It is a
+// ws.EventSink recording the paints and resets of every canvas; admin
+// requests are recorded with Record.
+type Log struct {
+	entries   chan db.AuditEntry
+	retention time.Duration
+
+	// Entries dropped because the queue was full
+	dropped atomic.Int64
+}
+
+// paintPayload is the payload of a paint entry
+type paintPayload struct {
+	// Number of cells changed
+	Count int `json:"count"`
+
+	// The first maxPaintCells of them
+	Cells []paintCell `json:"cells"`
+}
+
+// paintCell is a cell changed by a paint
+type paintCell struct {
+	X      int         `json:"x"`
+	Y      int         `json:"y"`
+	Active bool        `json:"active"`
+	Color  model.Color `json:"color"`
+	Team   string      `json:"team,omitempty"`
+}
+
+// NewLog creates a Log deleting the entries older than retention (0 keeps
+// them forever)
+func NewLog(retention time.Duration) *Log {
+	return &Log{entries: make(chan db.AuditEntry, queueSize), retention: retention}
+}
+
+// Record queues an entry for writing, stamping it with the current time if
+// it has none. It never blocks: entries are dropped while the queue is full.
+func (l *Log) Record(entry db.AuditEntry) {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	if entry.Payload == "" {
+		entry.Payload = "{}"
+	}
+	select {
+	case l.entries <- entry:
+	default:
+		if l.dropped.Add(1) == 1 {
+			slog.Warn("Audit log queue is full, dropping entries")
+		}
+	}
+}
+
+// Run writes the queued entries in batches and deletes those past retention
+func (l *Log) Run() {
+	flush := time.NewTicker(flushInterval)
+	defer flush.Stop()
+	var prune <-chan time.Time
+	if l.retention > 0 {
+		l.prune()
+		ticker := time.NewTicker(pruneInterval)
+		defer ticker.Stop()
+		prune = ticker.C
+	}
+
+	batch := make([]db.AuditEntry, 0, batchSize)
+	for {
+		select {
+		case entry := <-l.entries:
+			if batch = append(batch, entry); len(batch) >= batchSize {
+				batch = l.write(batch)
+			}
+		case <-flush.C:
+			batch = l.write(batch)
+		case <-prune:
+			l.prune()
+		}
+	}
+}
+
+// write saves a batch of entries, returning it emptied
+func (l *Log) write(batch []db.AuditEntry) []db.AuditEntry {
+	if dropped := l.dropped.Swap(0); dropped > 0 {
+		slog.Warn("Dropped audit log entries", "dropped", dropped)
+	}
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := db.SaveAuditEntries(batch); err != nil {
+		slog.Error("Failed to write audit log", "entries", len(batch), "err", err)
+	}
+	return batch[:0]
+}
+
+// prune deletes the entries past retention
+func (l *Log) prune() {
+	deleted, err := db.PruneAuditLog(time.Now().Add(-l.retention))
+	if err != nil {
+		slog.Error("Failed to prune audit log", "err", err)
+		return
+	}
+	if deleted > 0 {
+		slog.Info("Pruned audit log", "deleted", deleted)
+	}
+}
+
+// PixelsChanged records a paint: cells changed together by one actor
+func (l *Log) PixelsChanged(canvas string, changed []model.Pixel) {
+	if len(changed) == 0 {
+		return
+	}
+	listed := changed[:min(len(changed), maxPaintCells)]
+	payload := paintPayload{Count: len(changed), Cells: make([]paintCell, len(listed))}
+	for i, p := range listed {
+		payload.Cells[i] = paintCell{X: p.X, Y: p.Y, Active: p.Active, Color: p.Color, Team: p.Team}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("Failed to marshal audit paint", "err", err)
+		return
+	}
+	l.Record(db.AuditEntry{Canvas: canvas, Actor: changed[0].ModifyBy, Action: "paint", Payload: string(data)})
+}
+
+// CanvasReset records that every cell of a canvas was cleared
+func (l *Log) CanvasReset(canvas string) {
+	l.Record(db.AuditEntry{Canvas: canvas, Action: "reset"})
+}
+
+func (l *Log) Milestone(string, int64) {}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// APIKeyPrefix starts every API key, telling keys apart from JWTs
+const APIKeyPrefix = "mgk_"
+
+// APIKey is an active API key of a bot client
+type APIKey struct {
+	ID   uint
+	Name string
+
+	// Role and team of the clients using the key, like those of a token
+	Role string
+	Team string
+
+	// Sustained requests per second and burst (0 applies the per-IP limits)
+	Rate  float64
+	Burst int
+}
+
+// UserID returns the ID changes made with the key are attributed to
+func (k *APIKey) UserID() string {
+	return fmt.Sprintf("bot:%d", k.ID)
+}
+
+// identity returns the identity of the clients using the key
+func (k *APIKey) identity() *Identity {
+	return &Identity{UserID: k.UserID(), Name: k.Name, Role: k.Role, Team: k.Team, APIKey: k}
+}
+
+// Keys holds the active API keys by hash. Revoking a key removes it, so it
+// stops authenticating at once.
+type Keys struct {
+	byHash map[string]*APIKey
+	mu     sync.RWMutex
+}
+
+// NewKeys creates an empty set of API keys
+func NewKeys() *Keys {
+	return &Keys{byHash: make(map[string]*APIKey)}
+}
+
+// Add activates the key with the given hash
+func (k *Keys) Add(hash string, key APIKey) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.byHash[hash] = &key
+}
+
+// Remove deactivates the key with the given ID
+func (k *Keys) Remove(id uint) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for hash, key := range k.byHash {
+		if key.ID == id {
+			delete(k.byHash, hash)
+		}
+	}
+}
+
+// Lookup returns the active key a client presented, reporting false if it
+// is unknown or revoked
+func (k *Keys) Lookup(key string) (*APIKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	found, ok := k.byHash[HashAPIKey(key)]
+	return found, ok
+}
+
+// GenerateAPIKey returns a new random API key and its hash
+func GenerateAPIKey() (key, hash string, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return key, HashAPIKey(key), nil
+}
+
+// HashAPIKey returns the hex SHA-256 of an API key, which is all that is stored of it
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// isAPIKey reports whether a token presented by a client is an API key
+func isAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
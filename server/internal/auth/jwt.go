@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrNoToken is returned when a request carries no token
+var ErrNoToken = errors.New("no token provided")
+
+// SessionCookie is the cookie holding the token of a logged in user
+const SessionCookie = "mg_session"
+
+// Identity is the authenticated user attached to a connection
+type Identity struct {
+	// Stable user ID from the token subject
+	UserID string
+
+	// Optional display name
+	Name string
+
+	// Optional role, "moderator" or "admin" allow painting in locked regions
+	Role string
+
+	// Optional team the user paints for, when the canvas has that team
+	Team string
+
+	// API key the client authenticated with (nil for tokens)
+	APIKey *APIKey
+}
+
+// Moderator reports whether the identity may paint in locked regions
+func (id *Identity) Moderator() bool {
+	return id != nil && (id.Role == "moderator" || id.Role == "admin")
+}
+
+// Claims are the JWT claims accepted by the server
+type Claims struct {
+	Name string `json:"name,omitempty"`
+	Role string `json:"role,omitempty"`
+	Team string `json:"team,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Validator verifies HS256-signed JWTs against a shared signing key, and the
+// API keys of bot clients
+type Validator struct {
+	key []byte
+
+	// Active API keys (nil accepts none)
+	keys *Keys
+}
+
+// NewValidator creates a Validator for the given signing key (empty accepts
+// no JWTs) and API keys
+func NewValidator(signingKey string, keys *Keys) *Validator {
+	return &Validator{key: []byte(signingKey), keys: keys}
+}
+
+// Validate parses and verifies a token or API key, returning the identity it carries
+func (v *Validator) Validate(token string) (*Identity, error) {
+	if isAPIKey(token) {
+		if v.keys != nil {
+			if key, ok := v.keys.Lookup(token); ok {
+				return key.identity(), nil
+			}
+		}
+		return nil, errors.New("invalid api key")
+	}
+	if len(v.key) == 0 {
+		return nil, errors.New("invalid token: tokens are not accepted")
+	}
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return v.key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("invalid token: missing subject")
+	}
+	return &Identity{UserID: claims.Subject, Name: claims.Name, Role: claims.Role, Team: claims.Team}, nil
+}
+
+// Authenticate validates the token from the request's ?token= query param,
+// "Authorization: Bearer" header or, for API keys, "X-API-Key" header, and
+// otherwise the session cookie. It returns ErrNoToken if none is present; an
+// invalid or expired session cookie counts as none, so the request stays
+// anonymous.
+func (v *Validator) Authenticate(r *http.Request) (*Identity, error) {
+	token := TokenFromRequest(r)
+	if token == "" {
+		if cookie, err := r.Cookie(SessionCookie); err == nil && cookie.Value != "" {
+			if identity, err := v.Validate(cookie.Value); err == nil {
+				return identity, nil
+			}
+		}
+		return nil, ErrNoToken
+	}
+	return v.Validate(token)
+}
+
+// Issue signs a token for the identity expiring after ttl, as Validate accepts it
+func (v *Validator) Issue(identity *Identity, ttl time.Duration) (string, error) {
+	if len(v.key) == 0 {
+		return "", errors.New("tokens are not accepted")
+	}
+	now := time.Now()
+	claims := Claims{
+		Name: identity.Name,
+		Role: identity.Role,
+		Team: identity.Team,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   identity.UserID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(v.key)
+}
+
+// TokenFromRequest extracts a bearer token from the query string or Authorization
+// header, or an API key from the X-API-Key header
+func TokenFromRequest(r *http.Request) string {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+	if token := BearerToken(r); token != "" {
+		return token
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// BearerToken extracts a bearer token from the Authorization header only, for
+// credentials that must not end up in URLs and the logs recording them
+func BearerToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	}
+	return ""
+}
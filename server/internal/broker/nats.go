@@ -0,0 +1,165 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Time allowed for setting up the stream and the consumer
+const natsSetupTimeout = 10 * time.Second
+
+// Number of publishes awaiting the stream's acknowledgement before Publish
+// waits for some to complete
+const natsMaxPending = 4096
+
+// NATSBroker propagates updates between instances over a NATS JetStream
+// stream. Each canvas publishes on its own subject, and each instance
+// consumes it through its own consumer acknowledging every message, so the
+// stream redelivers the updates an instance misses while reconnecting.
+type NATSBroker struct {
+	conn    *nats.Conn
+	js      jetstream.JetStream
+	subject string
+	stream  string
+	maxAge  time.Duration
+
+	// Random ID of this instance, used to skip our own messages
+	origin string
+
+	// Durable consumer shared with other instances, empty for a consumer of our own
+	consumer string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewNATSBroker connects to the NATS servers at url (comma-separated, e.g.
+// "nats://localhost:4222"), creates or updates the stream keeping messages
+// on "<prefix>.>" for maxAge, and uses subject for the canvas
+func NewNATSBroker(url, stream, prefix, subject string, maxAge time.Duration) (*NATSBroker, error) {
+	conn, err := nats.Connect(url,
+		nats.Name("million_grids"),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				slog.Warn("Disconnected from nats", "subject", subject, "err", err)
+			}
+		}),
+		nats.ReconnectHandler(func(*nats.Conn) {
+			slog.Info("Reconnected to nats", "subject", subject)
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	js, err := jetstream.New(conn,
+		jetstream.WithPublishAsyncMaxPending(natsMaxPending),
+		jetstream.WithPublishAsyncErrHandler(func(_ jetstream.JetStream, msg *nats.Msg, err error) {
+			slog.Error("Failed to publish update to nats", "subject", msg.Subject, "err", err)
+		}),
+	)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open nats jetstream: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), natsSetupTimeout)
+	defer cancel()
+	if _, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     stream,
+		Subjects: []string{prefix + ".>"},
+		MaxAge:   maxAge,
+		Storage:  jetstream.FileStorage,
+	}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create nats stream %s: %w", stream, err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	return &NATSBroker{
+		conn:    conn,
+		js:      js,
+		subject: subject,
+		stream:  stream,
+		maxAge:  maxAge,
+		origin:  newOriginID(),
+		ctx:     ctx,
+		cancel:  cancel,
+	}, nil
+}
+
+// Publish sends a broadcast message to the other instances. The stream
+// acknowledges it asynchronously, and failures are logged.
+func (b *NATSBroker) Publish(message []byte) error {
+	payload, err := json.Marshal(envelope{Origin: b.origin, Data: message})
+	if err != nil {
+		return err
+	}
+	_, err = b.js.PublishAsync(b.subject, payload)
+	return err
+}
+
+// ShareConsumer makes Subscribe bind to the durable consumer name, shared by
+// every instance using it: each message goes to one of them, and the messages
+// published while all of them are gone wait for the next one. It must be
+// called before Subscribe.
+func (b *NATSBroker) ShareConsumer(name string) {
+	b.consumer = name
+}
+
+// Subscribe delivers messages from other instances to handler until Close is
+// called, starting with the messages published after it is called, or after
+// the last one acknowledged on a shared consumer
+func (b *NATSBroker) Subscribe(handler func(message []byte)) error {
+	ctx, cancel := context.WithTimeout(b.ctx, natsSetupTimeout)
+	defer cancel()
+
+	// The server drops the consumer once its instances are gone for longer
+	// than the stream keeps messages
+	consumer, err := b.js.CreateOrUpdateConsumer(ctx, b.stream, jetstream.ConsumerConfig{
+		Durable:           b.consumer,
+		FilterSubject:     b.subject,
+		DeliverPolicy:     jetstream.DeliverNewPolicy,
+		AckPolicy:         jetstream.AckExplicitPolicy,
+		InactiveThreshold: b.maxAge,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create nats consumer: %w", err)
+	}
+
+	consuming, err := consumer.Consume(func(msg jetstream.Msg) {
+		var env envelope
+		if err := json.Unmarshal(msg.Data(), &env); err != nil {
+			slog.Warn("Error parsing nats message", "err", err)
+		} else if env.Origin != b.origin {
+			handler(env.Data)
+		}
+		if err := msg.Ack(); err != nil {
+			slog.Warn("Failed to acknowledge nats message", "err", err)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to consume nats stream: %w", err)
+	}
+	<-b.ctx.Done()
+	consuming.Stop()
+	return nil
+}
+
+// Close stops the subscription, waits for pending publishes and closes the
+// NATS connection
+func (b *NATSBroker) Close() error {
+	b.cancel()
+	select {
+	case <-b.js.PublishAsyncComplete():
+	case <-time.After(time.Second):
+	}
+	b.conn.Close()
+	return nil
+}
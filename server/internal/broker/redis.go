@@ -0,0 +1,104 @@
+package broker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// envelope wraps a published message with the ID of the instance that sent it
+type envelope struct {
+	Origin string          `json:"o"`
+	Data   json.RawMessage `json:"d"`
+}
+
+// RedisBroker propagates updates between instances over Redis pub/sub
+type RedisBroker struct {
+	client  *redis.Client
+	channel string
+
+	// Random ID of this instance, used to skip our own messages
+	origin string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewRedisBroker connects to Redis at url (e.g. "redis://localhost:6379/0")
+// and uses the given pub/sub channel
+func NewRedisBroker(url, channel string) (*RedisBroker, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		cancel()
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisBroker{
+		client:  client,
+		channel: channel,
+		origin:  newOriginID(),
+		ctx:     ctx,
+		cancel:  cancel,
+	}, nil
+}
+
+// Publish sends a broadcast message to the other instances
+func (b *RedisBroker) Publish(message []byte) error {
+	payload, err := json.Marshal(envelope{Origin: b.origin, Data: message})
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(b.ctx, b.channel, payload).Err()
+}
+
+// Subscribe delivers messages from other instances to handler until Close is called
+func (b *RedisBroker) Subscribe(handler func(message []byte)) error {
+	sub := b.client.Subscribe(b.ctx, b.channel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-b.ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			var env envelope
+			if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+				slog.Warn("Error parsing redis message", "err", err)
+				continue
+			}
+			if env.Origin == b.origin {
+				continue
+			}
+			handler(env.Data)
+		}
+	}
+}
+
+// Close stops the subscription and closes the Redis connection
+func (b *RedisBroker) Close() error {
+	b.cancel()
+	return b.client.Close()
+}
+
+// newOriginID generates a random identifier for this instance
+func newOriginID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
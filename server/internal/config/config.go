@@ -0,0 +1,1196 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the server settings loaded from the config file and command-line flags
+type Config struct {
+	// Address the HTTP server listens on
+	Listen string `yaml:"listen"`
+
+	// Optional HTTPS serving on the listen address (and TLS for the gRPC API)
+	TLS TLSConfig `yaml:"tls"`
+
+	// Address the gRPC API listens on (empty disables it)
+	GRPCListen string `yaml:"grpc_listen"`
+
+	// Browser origins allowed to open WebSocket connections besides the
+	// server's own, e.g. "https://grid.example.com" or "https://*.example.com"
+	AllowedOrigins []string `yaml:"allowed_origins"`
+
+	// Accept WebSocket connections from any origin (development only)
+	AllowAnyOrigin bool `yaml:"allow_any_origin"`
+
+	// Networks of the reverse proxies and load balancers in front of the
+	// server, e.g. "10.0.0.0/8". Only their X-Forwarded-For and X-Real-IP
+	// headers are believed; the IP of any other client is its own address.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+
+	// Canvases served by the server; the first one is the default for clients that don't pick one
+	Canvases []CanvasConfig `yaml:"canvases"`
+
+	Database    DatabaseConfig    `yaml:"database"`
+	Redis       RedisConfig       `yaml:"redis"`
+	NATS        NATSConfig        `yaml:"nats"`
+	Replication ReplicationConfig `yaml:"replication"`
+
+	// Serve the canvases as a read-only replica: the grids are loaded from
+	// the database and follow the updates of the other instances through the
+	// broker, and every write from clients and the APIs is rejected
+	Follower bool `yaml:"follower"`
+
+	// Palette colors in "#RRGGBB" form (empty keeps the default palette)
+	Palette []string `yaml:"palette"`
+
+	// Which colors pixels may use: "palette" (palette colors only) or "any" (any #RRGGBB color)
+	ColorPolicy string `yaml:"color_policy"`
+
+	// Minimum delay between placements from the same IP (0 disables)
+	Cooldown time.Duration `yaml:"cooldown"`
+
+	// Cooldowns and placement quotas of authenticated actors by role or
+	// account age, the first matching tier applying. Actors matching none
+	// keep the per-IP cooldown.
+	Tiers []TierConfig `yaml:"tiers"`
+
+	// Time a placed cell can't be changed by anyone but who placed it (0 disables)
+	OverwriteProtection time.Duration `yaml:"overwrite_protection"`
+
+	// Maximum concurrent WebSocket connections from the same IP (0 disables)
+	MaxConnectionsPerIP int `yaml:"max_connections_per_ip"`
+
+	// Time a WebSocket client may send nothing before it is disconnected,
+	// answering pings not counting (0 disables)
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+
+	Admission AdmissionConfig `yaml:"admission"`
+
+	// Workers per canvas queueing broadcasts on the clients (0 for one per CPU)
+	FanoutWorkers int `yaml:"fanout_workers"`
+
+	// Placements between milestone events of a canvas (0 disables them)
+	MilestoneEvery int64 `yaml:"milestone_every"`
+
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Chat        ChatConfig        `yaml:"chat"`
+	Snapshots   SnapshotConfig    `yaml:"snapshots"`
+	Leaderboard LeaderboardConfig `yaml:"leaderboard"`
+	ReadModels  ReadModelsConfig  `yaml:"read_models"`
+	Decay       DecayConfig       `yaml:"decay"`
+	Teams       TeamConfig        `yaml:"teams"`
+	Economy     EconomyConfig     `yaml:"economy"`
+	Audit       AuditConfig       `yaml:"audit"`
+	Grief       GriefConfig       `yaml:"grief"`
+	Captcha     CaptchaConfig     `yaml:"captcha"`
+	GeoIP       GeoIPConfig       `yaml:"geoip"`
+	Privacy     PrivacyConfig     `yaml:"privacy"`
+	Discord     DiscordConfig     `yaml:"discord"`
+	Kafka       KafkaConfig       `yaml:"kafka"`
+	Buffers     BufferConfig      `yaml:"buffers"`
+	Auth        AuthConfig        `yaml:"auth"`
+	Admin       AdminConfig       `yaml:"admin"`
+	Log         LogConfig         `yaml:"log"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Sentry      SentryConfig      `yaml:"sentry"`
+
+	// Timed events opening and freezing canvases
+	Events []EventConfig `yaml:"events"`
+}
+
+// TLSConfig holds the HTTPS settings. Either a certificate and key pair or a
+// list of autocert hosts enables TLS.
+type TLSConfig struct {
+	// PEM certificate chain and private key files
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+
+	// Hostnames to obtain Let's Encrypt certificates for automatically
+	AutocertHosts []string `yaml:"autocert_hosts"`
+
+	// Directory caching the obtained certificates and the ACME account key
+	AutocertCacheDir string `yaml:"autocert_cache_dir"`
+
+	// Contact address registered with the ACME account (optional)
+	AutocertEmail string `yaml:"autocert_email"`
+
+	// Plain HTTP address redirecting to HTTPS and answering ACME HTTP-01
+	// challenges, e.g. ":80" (empty disables)
+	RedirectListen string `yaml:"redirect_listen"`
+}
+
+// Enabled reports whether HTTPS is configured
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || len(t.AutocertHosts) > 0
+}
+
+// CanvasConfig holds the settings of one named canvas
+type CanvasConfig struct {
+	// Name used in ?canvas= and as the database partition key
+	Name string `yaml:"name"`
+
+	// Grid dimensions in cells. Zero keeps the dimensions stored in the
+	// database, or the default size for a new canvas.
+	Width  int `yaml:"width"`
+	Height int `yaml:"height"`
+
+	// Start frozen, rejecting paint operations for the reason until an admin
+	// thaws the canvas, e.g. to serve an archived event
+	ReadOnly       bool   `yaml:"read_only"`
+	ReadOnlyReason string `yaml:"read_only_reason"`
+}
+
+// TracingConfig holds the export of OpenTelemetry traces of the message
+// pipeline, from a placement's receipt through the grid and the database to
+// its broadcast
+type TracingConfig struct {
+	// OTLP/HTTP endpoint the spans are sent to, e.g. "http://localhost:4318"
+	// for Jaeger or Tempo (empty disables tracing)
+	Endpoint string `yaml:"endpoint"`
+
+	// Service the spans are reported under
+	ServiceName string `yaml:"service_name"`
+
+	// Share of the traces started here that are recorded. Traces continued
+	// from a caller keep its decision.
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// Enabled reports whether traces are exported
+func (c TracingConfig) Enabled() bool {
+	return c.Endpoint != ""
+}
+
+// SentryConfig holds the reporting of the panics the server recovers from to
+// Sentry
+type SentryConfig struct {
+	// DSN of the Sentry project the panics are reported to (empty disables
+	// reporting, they are still logged)
+	DSN string `yaml:"dsn"`
+
+	// Environment the reports are filed under, e.g. "production"
+	Environment string `yaml:"environment"`
+}
+
+// Enabled reports whether panics are reported to Sentry
+func (c SentryConfig) Enabled() bool {
+	return c.DSN != ""
+}
+
+// LogConfig holds the logging settings
+type LogConfig struct {
+	// Minimum level: "debug", "info", "warn" or "error"
+	Level string `yaml:"level"`
+
+	// Output format: "json" or "text"
+	Format string `yaml:"format"`
+}
+
+// AdminConfig holds the admin API settings
+type AdminConfig struct {
+	// Bearer token required by /admin endpoints (empty disables the admin API)
+	Token string `yaml:"token"`
+
+	// Address of a listener serving the pprof profiles and runtime stats
+	// under /debug to loopback clients without the token, e.g.
+	// "127.0.0.1:6060" (empty disables it)
+	DebugListen string `yaml:"debug_listen"`
+}
+
+// AuthConfig holds the optional JWT authentication settings
+type AuthConfig struct {
+	// HS256 signing key for client tokens (empty disables authentication)
+	JWTSecret string `yaml:"jwt_secret"`
+
+	// Reject connections without a valid token
+	Required bool `yaml:"required"`
+
+	// Login with Google or GitHub accounts, issuing tokens signed with JWTSecret
+	OAuth OAuthConfig `yaml:"oauth"`
+}
+
+// OAuthConfig holds the settings of the OAuth2 login. Logged in users get a
+// session cookie holding a token, so their pixels are attributed to their
+// account.
+type OAuthConfig struct {
+	// Public URL of the server, providers redirect to <base_url>/auth/callback
+	BaseURL string `yaml:"base_url"`
+
+	// Time a login lasts
+	SessionTTL time.Duration `yaml:"session_ttl"`
+
+	// Providers users can log in with (those without a client ID are disabled)
+	Google OAuthProviderConfig `yaml:"google"`
+	GitHub OAuthProviderConfig `yaml:"github"`
+}
+
+// OAuthProviderConfig holds the OAuth2 client credentials of a login provider
+type OAuthProviderConfig struct {
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+}
+
+// Enabled reports whether users can log in with any provider
+func (c OAuthConfig) Enabled() bool {
+	return c.Google.ClientID != "" || c.GitHub.ClientID != ""
+}
+
+// DatabaseConfig holds the database connection and write queue settings
+type DatabaseConfig struct {
+	// Database driver: "mysql", "postgres", "sqlite" or "none" (in-memory only, nothing persisted)
+	Driver string `yaml:"driver"`
+
+	// Data source name for the driver
+	DSN string `yaml:"dsn"`
+
+	// Maximum time pixel changes wait in the write queue
+	FlushInterval time.Duration `yaml:"flush_interval"`
+
+	// Number of pending pixels that triggers an immediate flush
+	FlushBatch int `yaml:"flush_batch"`
+
+	// Directory of the write-ahead log, which makes pixel changes durable
+	// before they are queued and replays unsaved ones on startup (empty disables)
+	WALDir string `yaml:"wal_dir"`
+}
+
+// RedisConfig holds the optional Redis broker settings
+type RedisConfig struct {
+	// Redis URL, e.g. "redis://localhost:6379/0" (empty disables the broker)
+	URL string `yaml:"url"`
+
+	// Pub/sub channel shared by all instances
+	Channel string `yaml:"channel"`
+}
+
+// NATSConfig holds the optional NATS broker settings, an alternative to Redis
+// delivering updates at least once through a JetStream stream
+type NATSConfig struct {
+	// Comma-separated server URLs, e.g. "nats://localhost:4222" (empty
+	// disables the broker)
+	URL string `yaml:"url"`
+
+	// Subject prefix, each canvas publishing on "<subject>.<canvas>"
+	Subject string `yaml:"subject"`
+
+	// JetStream stream keeping the updates, created when missing, and the time
+	// it keeps them for instances that lose their connection to catch up
+	Stream string        `yaml:"stream"`
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+// ReplicationConfig holds the settings of multi-region replication. Each
+// region runs its own database and broker, and replicates its cell changes to
+// the other regions over a JetStream stream on NATS servers they share. Every
+// change carries a hybrid logical clock value, and a cell keeps the change
+// with the latest one, the greater region name breaking ties.
+type ReplicationConfig struct {
+	// Name of this region, e.g. "eu-west" (empty disables replication)
+	Region string `yaml:"region"`
+
+	// Comma-separated URLs of the NATS servers shared by the regions
+	URL string `yaml:"url"`
+
+	// Subject prefix, each canvas replicating on "<subject>.<canvas>"
+	Subject string `yaml:"subject"`
+
+	// JetStream stream keeping the changes, created when missing, and the time
+	// it keeps them for a region that is unreachable to catch up
+	Stream string        `yaml:"stream"`
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+// validRegionName matches region names, which name the consumers of a region
+var validRegionName = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// overlappingSubjects reports whether the streams of two subject prefixes
+// would overlap, which NATS refuses
+func overlappingSubjects(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+".") || strings.HasPrefix(b, a+".")
+}
+
+// KafkaConfig holds the settings of the export of every pixel event to a
+// Kafka topic, for external analytics and replicas
+type KafkaConfig struct {
+	// Bootstrap brokers, e.g. "localhost:9092" (none disables the export)
+	Brokers []string `yaml:"brokers"`
+
+	Topic string `yaml:"topic"`
+
+	// Connect over TLS, and authenticate with SASL/PLAIN when a username is set
+	TLS      bool   `yaml:"tls"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// SnapshotConfig holds the periodic full-grid snapshot settings
+type SnapshotConfig struct {
+	// Directory snapshots are written to (empty disables snapshots)
+	Dir string `yaml:"dir"`
+
+	// Time between snapshots of each canvas
+	Interval time.Duration `yaml:"interval"`
+
+	// Number of snapshots kept per canvas
+	Keep int `yaml:"keep"`
+}
+
+// EventConfig is a timed event: its canvas stays frozen until start, is
+// frozen again at end and its final state is archived under the event's name
+type EventConfig struct {
+	// Shown in announcements and naming the archive, letters, digits, '-' and '_'
+	Name string `yaml:"name"`
+
+	// Canvas the event takes place on (empty for the default canvas)
+	Canvas string `yaml:"canvas"`
+
+	// When painting opens (omit to open it from startup) and closes
+	Start time.Time `yaml:"start"`
+	End   time.Time `yaml:"end"`
+
+	// How long before the start and the end countdowns are announced
+	Countdowns []time.Duration `yaml:"countdowns"`
+}
+
+// validEventName matches event names, which name the archive files
+var validEventName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// DefaultTier names the tier of actors matching no configured tier
+const DefaultTier = "default"
+
+// TierConfig is a placement cooldown and quota applying to the authenticated
+// actors matching it, e.g. verified users painting faster
+type TierConfig struct {
+	// Shown to clients
+	Name string `yaml:"name"`
+
+	// Roles the actor must have one of (empty matches any role), and the
+	// least age of its account, counted from its first OAuth login (0
+	// matches any account)
+	Roles         []string      `yaml:"roles"`
+	MinAccountAge time.Duration `yaml:"min_account_age"`
+
+	// Minimum delay between placements of the same actor (0 disables)
+	Cooldown time.Duration `yaml:"cooldown"`
+
+	// Placements allowed per quota window (0 for no quota)
+	Quota       int           `yaml:"quota"`
+	QuotaWindow time.Duration `yaml:"quota_window"`
+}
+
+// LeaderboardConfig holds the contributor leaderboard settings
+type LeaderboardConfig struct {
+	// Time between aggregations of the pixel history into the leaderboard (0 disables them)
+	Interval time.Duration `yaml:"interval"`
+}
+
+// ReadModelsConfig holds the settings of the read models the statistics
+// endpoints are served from
+type ReadModelsConfig struct {
+	// Time between reads of the event stream into the read models (0 disables
+	// them, and the endpoints read the grids and the database instead)
+	Interval time.Duration `yaml:"interval"`
+}
+
+// AdmissionConfig holds the admission control of new WebSocket connections,
+// which are refused with 503 and Retry-After while the server is saturated
+type AdmissionConfig struct {
+	// Maximum concurrent WebSocket connections across all canvases (0 disables)
+	MaxConnections int `yaml:"max_connections"`
+
+	// Share of its CPUs the process may use, and share of a canvas's
+	// broadcast queues that may be filled, before new connections are
+	// refused (0 disables each check)
+	MaxCPU   float64 `yaml:"max_cpu"`
+	MaxQueue float64 `yaml:"max_queue"`
+
+	// Time between samples of the CPU usage and the queues
+	Interval time.Duration `yaml:"interval"`
+
+	// Delay refused clients are told to wait before reconnecting
+	RetryAfter time.Duration `yaml:"retry_after"`
+
+	// Clients queued in the waiting room while max_connections are open (0
+	// disables it), and the time an admitted client has to connect
+	MaxWaiting int           `yaml:"max_waiting"`
+	TicketTTL  time.Duration `yaml:"ticket_ttl"`
+}
+
+// Saturation reports whether new connections are refused on load
+func (c AdmissionConfig) ShedsLoad() bool {
+	return c.MaxCPU > 0 || c.MaxQueue > 0
+}
+
+// DecayConfig holds the settings of pixel decay, which clears cells nobody
+// changed for a while so the canvas keeps making room for fresh content
+type DecayConfig struct {
+	// Time after its last change a cell is cleared (0 disables decay)
+	After time.Duration `yaml:"after"`
+
+	// Time between sweeps for expired cells
+	Interval time.Duration `yaml:"interval"`
+}
+
+// TeamConfig holds the settings of teams, which users join to paint for and
+// compete on territory, the cells their members painted last
+type TeamConfig struct {
+	// Teams users may join, letters, digits, '-' and '_' (empty disables teams)
+	Names []string `yaml:"names"`
+
+	// Time between broadcasts of the team scores, sent only when they changed
+	ScoreInterval time.Duration `yaml:"score_interval"`
+}
+
+// EconomyConfig holds the settings of economy mode, where accounts claim
+// regions of the canvas for a while and only they may paint there
+type EconomyConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Time a claim reserves its region for
+	ClaimDuration time.Duration `yaml:"claim_duration"`
+
+	// Cells an account may hold through its own claims at once (0 leaves
+	// granting claims to admins, e.g. once they were paid for)
+	MaxCells int `yaml:"max_cells"`
+}
+
+// AuditConfig holds the settings of the audit log, which records every
+// paint, admin action and reset with its actor for abuse investigations
+type AuditConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Time entries are kept for (0 keeps them forever)
+	Retention time.Duration `yaml:"retention"`
+}
+
+// GriefConfig holds the settings of grief detection, which quarantines the
+// actors mass-overwriting painted cells with one color or placing like a
+// script, and flags the regions they changed in the report queue
+type GriefConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Time overwrites are counted over
+	Window time.Duration `yaml:"window"`
+
+	// Painted cells overwritten within the window, and the share of them in
+	// a single color, detected as a mass overwrite
+	Overwrites int     `yaml:"overwrites"`
+	ColorShare float64 `yaml:"color_share"`
+
+	// Consecutive placements checked for scripted timing (0 disables it), and
+	// the largest deviation of their intervals, relative to the mean, detected
+	ScriptSamples int     `yaml:"script_samples"`
+	ScriptJitter  float64 `yaml:"script_jitter"`
+
+	// Time detected actors are quarantined for, and the cooldown they are
+	// throttled to meanwhile
+	Quarantine         time.Duration `yaml:"quarantine"`
+	QuarantineCooldown time.Duration `yaml:"quarantine_cooldown"`
+}
+
+// CAPTCHA providers the challenge can be verified with
+const (
+	CaptchaHCaptcha  = "hcaptcha"
+	CaptchaTurnstile = "turnstile"
+)
+
+// CaptchaConfig holds the settings of the CAPTCHA challenge unauthenticated
+// clients must pass, when they connect or every few placements
+type CaptchaConfig struct {
+	// CaptchaHCaptcha or CaptchaTurnstile (empty disables the challenge)
+	Provider string `yaml:"provider"`
+
+	// Key solved tokens are verified with, and the key clients render the
+	// widget with
+	SecretKey string `yaml:"secret_key"`
+	SiteKey   string `yaml:"site_key"`
+
+	// Whether WebSocket clients must present a solved token to connect
+	OnConnect bool `yaml:"on_connect"`
+
+	// Placements allowed between challenges (0 never challenges placements)
+	Placements int `yaml:"placements"`
+
+	// Verification endpoint replacing the provider's (e.g. a proxy)
+	VerifyURL string `yaml:"verify_url"`
+}
+
+// Enabled reports whether unauthenticated clients are challenged
+func (c CaptchaConfig) Enabled() bool {
+	return c.Provider != ""
+}
+
+// validTeamName matches team names, which are stored with every pixel
+var validTeamName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
+// GeoIPConfig holds the settings of the client location lookup
+type GeoIPConfig struct {
+	// MaxMind GeoIP2/GeoLite2 Country or City database file (empty disables lookups)
+	Database string `yaml:"database"`
+}
+
+// PrivacyConfig holds the settings protecting the identity of painters
+type PrivacyConfig struct {
+	// Attribute anonymous changes to salted hashes of their IPs instead of the IPs
+	HashIPs bool `yaml:"hash_ips"`
+
+	// Key of the IP hashes, shared by every instance and kept across restarts
+	IPSalt string `yaml:"ip_salt"`
+}
+
+// DiscordConfig holds the settings of the canvas posts to a Discord webhook
+type DiscordConfig struct {
+	// Discord webhook URL (empty disables posting)
+	WebhookURL string `yaml:"webhook_url"`
+
+	// Canvas posted (empty for the default canvas)
+	Canvas string `yaml:"canvas"`
+
+	// Time between periodic posts (0 disables them)
+	Interval time.Duration `yaml:"interval"`
+
+	// Also post when the canvas reaches a placement milestone
+	OnMilestones bool `yaml:"on_milestones"`
+
+	// text/template messages of periodic and milestone posts
+	Message          string `yaml:"message"`
+	MilestoneMessage string `yaml:"milestone_message"`
+}
+
+// RateLimitConfig holds the per-connection inbound message limits, also
+// applied per IP to POST /api/pixel requests
+type RateLimitConfig struct {
+	// Sustained messages per second (0 disables rate limiting)
+	Rate float64 `yaml:"rate"`
+
+	// Messages allowed in a burst
+	Burst int `yaml:"burst"`
+
+	// Sustained cursor positions relayed per second (0 disables cursor sharing)
+	Cursor float64 `yaml:"cursor"`
+
+	// Outbound bytes per second per WebSocket connection (0 for no cap)
+	Bandwidth int64 `yaml:"bandwidth"`
+}
+
+// ChatConfig holds the settings of the in-canvas chat
+type ChatConfig struct {
+	// Sustained messages per second per client (0 disables chat), and the
+	// number allowed in a burst
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+
+	// Longest message in characters
+	MaxLength int `yaml:"max_length"`
+
+	// Latest messages of each canvas sent to newly connected clients, and
+	// kept in the database when there is one (0 keeps none)
+	History int `yaml:"history"`
+
+	// Words masked with asterisks in messages, matched case-insensitively
+	BlockedWords []string `yaml:"blocked_words"`
+}
+
+// BufferConfig holds WebSocket buffer sizes
+type BufferConfig struct {
+	// Upgrader read and write buffer sizes in bytes
+	Read  int `yaml:"read"`
+	Write int `yaml:"write"`
+
+	// Number of outbound messages queued per client, in each priority lane
+	Send int `yaml:"send"`
+
+	// What happens to broadcasts for a client whose send queue is full:
+	// "drop_oldest", "drop_updates" or "close"
+	SlowClient string `yaml:"slow_client"`
+}
+
+// minSendBuffer is the smallest send lane, which must hold the roster and the
+// replies queued while a new client is still receiving its initial state
+const minSendBuffer = 16
+
+// maxChatLength is the highest chat max_length, the most characters the
+// chat_messages table holds
+const maxChatLength = 1000
+
+// EphemeralDSN is the SQLite DSN of a shared in-memory database, used by -ephemeral
+const EphemeralDSN = "file::memory:?cache=shared"
+
+// Default returns the built-in configuration
+func Default() *Config {
+	return &Config{
+		Listen: ":8080",
+		TLS: TLSConfig{
+			AutocertCacheDir: "autocert-cache",
+		},
+		Canvases:            []CanvasConfig{{Name: model.DefaultCanvas}},
+		ColorPolicy:         "palette",
+		MaxConnectionsPerIP: 5,
+		MilestoneEvery:      100000,
+		Database: DatabaseConfig{
+			Driver:        "mysql",
+			DSN:           "root:@tcp(localhost:3306)/million_grids?charset=utf8mb4&parseTime=True&loc=Local",
+			FlushInterval: 500 * time.Millisecond,
+			FlushBatch:    500,
+		},
+		Redis: RedisConfig{
+			Channel: "million_grids:updates",
+		},
+		Kafka: KafkaConfig{
+			Topic: "million_grids.pixels",
+		},
+		NATS: NATSConfig{
+			Subject: "million_grids.updates",
+			Stream:  "MILLION_GRIDS",
+			MaxAge:  time.Minute,
+		},
+		Replication: ReplicationConfig{
+			Subject: "million_grids.replication",
+			Stream:  "MILLION_GRIDS_REPLICATION",
+			MaxAge:  24 * time.Hour,
+		},
+		RateLimit: RateLimitConfig{
+			Rate:   20,
+			Burst:  40,
+			Cursor: 10,
+		},
+		Chat: ChatConfig{
+			Rate:      0.5,
+			Burst:     3,
+			MaxLength: 200,
+			History:   50,
+		},
+		Snapshots: SnapshotConfig{
+			Interval: 10 * time.Minute,
+			Keep:     24,
+		},
+		Leaderboard: LeaderboardConfig{
+			Interval: time.Minute,
+		},
+		Admission: AdmissionConfig{
+			Interval:   time.Second,
+			RetryAfter: 10 * time.Second,
+			TicketTTL:  30 * time.Second,
+		},
+		ReadModels: ReadModelsConfig{
+			Interval: time.Second,
+		},
+		Decay: DecayConfig{
+			Interval: 10 * time.Minute,
+		},
+		Teams: TeamConfig{
+			ScoreInterval: 10 * time.Second,
+		},
+		Economy: EconomyConfig{
+			ClaimDuration: 7 * 24 * time.Hour,
+			MaxCells:      100,
+		},
+		Audit: AuditConfig{
+			Retention: 90 * 24 * time.Hour,
+		},
+		Grief: GriefConfig{
+			Window:             time.Minute,
+			Overwrites:         100,
+			ColorShare:         0.9,
+			ScriptSamples:      30,
+			ScriptJitter:       0.03,
+			Quarantine:         time.Hour,
+			QuarantineCooldown: 30 * time.Second,
+		},
+		Captcha: CaptchaConfig{
+			Placements: 50,
+		},
+		Discord: DiscordConfig{
+			Interval:         time.Hour,
+			OnMilestones:     true,
+			Message:          "**{{.Canvas}}**: {{.Active}} pixels active, {{.Placements}} placed so far",
+			MilestoneMessage: "**{{.Canvas}}** just reached {{.Milestone}} placements!",
+		},
+		Auth: AuthConfig{
+			OAuth: OAuthConfig{
+				SessionTTL: 30 * 24 * time.Hour,
+			},
+		},
+		Buffers: BufferConfig{
+			Read:       1024,
+			Write:      1024,
+			Send:       256,
+			SlowClient: "close",
+		},
+		Tracing: TracingConfig{
+			ServiceName: "million-grids",
+			SampleRatio: 0.1,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the YAML file given by
+// -config (if any) and finally the command-line flags, which take precedence.
+// Environment variables referenced as ${VAR} in the file are expanded.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	path := fs.String("config", "", "path to YAML config file")
+	listen := fs.String("listen", cfg.Listen, "HTTP listen address")
+	grpcListen := fs.String("grpc-listen", cfg.GRPCListen, "gRPC listen address (empty disables)")
+	driver := fs.String("db-driver", cfg.Database.Driver, "database driver (mysql, postgres, sqlite or none)")
+	dsn := fs.String("db-dsn", cfg.Database.DSN, "database data source name")
+	cooldown := fs.Duration("cooldown", cfg.Cooldown, "minimum delay between placements per IP (0 disables)")
+	maxConns := fs.Int("max-conns-per-ip", cfg.MaxConnectionsPerIP, "maximum concurrent WebSocket connections per IP (0 disables)")
+	maxConnsTotal := fs.Int("max-conns", cfg.Admission.MaxConnections, "maximum concurrent WebSocket connections across all canvases (0 disables)")
+	rate := fs.Float64("rate", cfg.RateLimit.Rate, "sustained inbound messages per second per client (0 disables)")
+	burst := fs.Int("burst", cfg.RateLimit.Burst, "inbound message burst per client")
+	colorPolicy := fs.String("color-policy", cfg.ColorPolicy, "allowed pixel colors (palette or any)")
+	snapshotDir := fs.String("snapshot-dir", cfg.Snapshots.Dir, "directory for periodic grid snapshots (empty disables)")
+	redisURL := fs.String("redis-url", cfg.Redis.URL, "Redis URL for sharing updates across instances")
+	natsURL := fs.String("nats-url", cfg.NATS.URL, "NATS URLs for sharing updates across instances")
+	follower := fs.Bool("follower", cfg.Follower, "serve the canvases read-only, following the other instances through the broker")
+	dev := fs.Bool("dev", false, "development mode: accept WebSocket connections from any origin")
+	ephemeral := fs.Bool("ephemeral", false, "use a throwaway in-memory SQLite database")
+	logLevel := fs.String("log-level", cfg.Log.Level, "minimum log level (debug, info, warn, error)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *path != "" {
+		if err := cfg.loadFile(*path); err != nil {
+			return nil, err
+		}
+	}
+
+	// An empty driver (e.g. an unset ${DB_DRIVER}) means MySQL
+	if cfg.Database.Driver == "" {
+		cfg.Database.Driver = "mysql"
+	}
+
+	// Explicitly set flags override the file
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "listen":
+			cfg.Listen = *listen
+		case "grpc-listen":
+			cfg.GRPCListen = *grpcListen
+		case "db-driver":
+			cfg.Database.Driver = *driver
+		case "db-dsn":
+			cfg.Database.DSN = *dsn
+		case "cooldown":
+			cfg.Cooldown = *cooldown
+		case "max-conns-per-ip":
+			cfg.MaxConnectionsPerIP = *maxConns
+		case "max-conns":
+			cfg.Admission.MaxConnections = *maxConnsTotal
+		case "rate":
+			cfg.RateLimit.Rate = *rate
+		case "burst":
+			cfg.RateLimit.Burst = *burst
+		case "color-policy":
+			cfg.ColorPolicy = *colorPolicy
+		case "snapshot-dir":
+			cfg.Snapshots.Dir = *snapshotDir
+		case "redis-url":
+			cfg.Redis.URL = *redisURL
+		case "nats-url":
+			cfg.NATS.URL = *natsURL
+		case "follower":
+			cfg.Follower = *follower
+		case "log-level":
+			cfg.Log.Level = *logLevel
+		}
+	})
+
+	if *dev {
+		cfg.AllowAnyOrigin = true
+	}
+	if *ephemeral {
+		cfg.Database.Driver = "sqlite"
+		cfg.Database.DSN = EphemeralDSN
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile merges the YAML file at path into the configuration
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	dec := yaml.NewDecoder(strings.NewReader(os.ExpandEnv(string(data))))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate checks the configuration for invalid values
+func (c *Config) Validate() error {
+	if c.Listen == "" {
+		return errors.New("listen address must be set")
+	}
+	if c.GRPCListen != "" && c.GRPCListen == c.Listen {
+		return errors.New("grpc_listen must differ from listen")
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return errors.New("tls cert_file and key_file must be set together")
+	}
+	if c.TLS.CertFile != "" && len(c.TLS.AutocertHosts) > 0 {
+		return errors.New("tls autocert_hosts can't be combined with cert_file")
+	}
+	if len(c.TLS.AutocertHosts) > 0 && c.TLS.AutocertCacheDir == "" {
+		return errors.New("tls autocert_cache_dir must be set")
+	}
+	if c.TLS.RedirectListen != "" && !c.TLS.Enabled() {
+		return errors.New("tls redirect_listen requires a certificate or autocert_hosts")
+	}
+	for _, origin := range c.AllowedOrigins {
+		if err := ValidateOriginPattern(origin); err != nil {
+			return err
+		}
+	}
+	for _, proxy := range c.TrustedProxies {
+		if _, err := netip.ParsePrefix(proxy); err != nil {
+			return fmt.Errorf("invalid trusted proxy %q (use a CIDR such as 10.0.0.0/8)", proxy)
+		}
+	}
+	if len(c.Canvases) == 0 {
+		return errors.New("at least one canvas must be configured")
+	}
+	seen := make(map[string]bool)
+	for _, canvas := range c.Canvases {
+		if !model.ValidCanvasName(canvas.Name) {
+			return fmt.Errorf("invalid canvas name %q (use up to 64 of a-z, 0-9, _ and -)", canvas.Name)
+		}
+		if seen[canvas.Name] {
+			return fmt.Errorf("duplicate canvas %q", canvas.Name)
+		}
+		seen[canvas.Name] = true
+		if canvas.Width < 0 || canvas.Width > model.MaxGridDimension ||
+			canvas.Height < 0 || canvas.Height > model.MaxGridDimension {
+			return fmt.Errorf("canvas %q dimensions must be between 1 and %d", canvas.Name, model.MaxGridDimension)
+		}
+	}
+	events := make(map[string]bool)
+	for _, event := range c.Events {
+		if !validEventName.MatchString(event.Name) {
+			return fmt.Errorf("invalid event name %q (use up to 64 letters, digits, _ and -)", event.Name)
+		}
+		if events[event.Name] {
+			return fmt.Errorf("duplicate event %q", event.Name)
+		}
+		events[event.Name] = true
+		if event.Canvas != "" && !seen[event.Canvas] {
+			return fmt.Errorf("event %q is on unknown canvas %q", event.Name, event.Canvas)
+		}
+		if event.End.IsZero() || !event.End.After(event.Start) {
+			return fmt.Errorf("event %q must end after it starts", event.Name)
+		}
+		for _, d := range event.Countdowns {
+			if d <= 0 {
+				return fmt.Errorf("event %q countdowns must be positive", event.Name)
+			}
+		}
+	}
+	switch c.Database.Driver {
+	case "mysql", "postgres", "sqlite", "none":
+	default:
+		return fmt.Errorf("unsupported database driver %q", c.Database.Driver)
+	}
+	if c.Database.Driver != "none" && c.Database.DSN == "" {
+		return errors.New("database dsn must be set")
+	}
+	if c.Database.FlushInterval <= 0 {
+		return errors.New("database flush_interval must be positive")
+	}
+	if c.Database.FlushBatch < 1 {
+		return errors.New("database flush_batch must be at least 1")
+	}
+	if c.Redis.URL != "" && c.NATS.URL != "" {
+		return errors.New("configure either the redis or the nats broker, not both")
+	}
+	if n := c.NATS; n.URL != "" {
+		if n.Subject == "" || strings.ContainsAny(n.Subject, "*> \t") || strings.HasSuffix(n.Subject, ".") {
+			return fmt.Errorf("invalid nats subject %q", n.Subject)
+		}
+		if n.Stream == "" || strings.ContainsAny(n.Stream, ".*> \t/\\") {
+			return fmt.Errorf("invalid nats stream %q", n.Stream)
+		}
+		if n.MaxAge <= 0 {
+			return errors.New("nats max_age must be positive")
+		}
+	}
+	if c.Follower {
+		if c.Redis.URL == "" && c.NATS.URL == "" {
+			return errors.New("follower mode requires a redis or nats broker to follow")
+		}
+		if c.Replication.Region != "" {
+			return errors.New("followers can't replicate, the instances they follow do")
+		}
+	}
+	if r := c.Replication; r.Region != "" {
+		if !validRegionName.MatchString(r.Region) {
+			return fmt.Errorf("invalid replication region %q (use up to 32 of a-z, 0-9, _ and -)", r.Region)
+		}
+		if r.URL == "" {
+			return errors.New("replication url must be set when a region is")
+		}
+		if r.Subject == "" || strings.ContainsAny(r.Subject, "*> \t") || strings.HasSuffix(r.Subject, ".") {
+			return fmt.Errorf("invalid replication subject %q", r.Subject)
+		}
+		if r.Stream == "" || strings.ContainsAny(r.Stream, ".*> \t/\\") {
+			return fmt.Errorf("invalid replication stream %q", r.Stream)
+		}
+		if r.URL == c.NATS.URL && (r.Stream == c.NATS.Stream || overlappingSubjects(r.Subject, c.NATS.Subject)) {
+			return errors.New("replication must use a stream and subject of its own")
+		}
+		if r.MaxAge <= 0 {
+			return errors.New("replication max_age must be positive")
+		}
+	}
+	if k := c.Kafka; len(k.Brokers) > 0 {
+		if k.Topic == "" {
+			return errors.New("kafka topic must be set when brokers are")
+		}
+		if k.Password != "" && k.Username == "" {
+			return errors.New("kafka username must be set with a password")
+		}
+	}
+	if c.ColorPolicy != "palette" && c.ColorPolicy != "any" {
+		return fmt.Errorf("invalid color_policy %q (use palette or any)", c.ColorPolicy)
+	}
+	if c.Cooldown < 0 {
+		return errors.New("cooldown must not be negative")
+	}
+	tiers := make(map[string]bool)
+	for _, tier := range c.Tiers {
+		if tier.Name == "" || tier.Name == DefaultTier {
+			return fmt.Errorf("tiers must be named, other than %q", DefaultTier)
+		}
+		if tiers[tier.Name] {
+			return fmt.Errorf("duplicate tier %q", tier.Name)
+		}
+		tiers[tier.Name] = true
+		if tier.Cooldown < 0 || tier.MinAccountAge < 0 || tier.Quota < 0 {
+			return fmt.Errorf("tier %q cooldown, min_account_age and quota must not be negative", tier.Name)
+		}
+		if tier.Quota > 0 && tier.QuotaWindow <= 0 {
+			return fmt.Errorf("tier %q quota_window must be positive when it has a quota", tier.Name)
+		}
+		if tier.MinAccountAge > 0 && c.Database.Driver == "none" {
+			return fmt.Errorf("tier %q min_account_age needs a database to know account ages", tier.Name)
+		}
+	}
+	if c.MaxConnectionsPerIP < 0 {
+		return errors.New("max_connections_per_ip must not be negative")
+	}
+	if c.IdleTimeout != 0 && c.IdleTimeout < time.Second {
+		return errors.New("idle_timeout must be at least 1s")
+	}
+	if c.Admission.MaxConnections < 0 {
+		return errors.New("admission max_connections must not be negative")
+	}
+	if c.Admission.MaxCPU < 0 || c.Admission.MaxCPU > 1 || c.Admission.MaxQueue < 0 || c.Admission.MaxQueue > 1 {
+		return errors.New("admission max_cpu and max_queue must be between 0 and 1")
+	}
+	if c.Admission.ShedsLoad() && c.Admission.Interval <= 0 {
+		return errors.New("admission interval must be positive when max_cpu or max_queue is set")
+	}
+	if c.Admission.MaxWaiting < 0 {
+		return errors.New("admission max_waiting must not be negative")
+	}
+	if c.Admission.MaxWaiting > 0 && c.Admission.MaxConnections == 0 {
+		return errors.New("admission max_waiting needs max_connections")
+	}
+	if c.Admission.MaxWaiting > 0 && c.Admission.TicketTTL < time.Second {
+		return errors.New("admission ticket_ttl must be at least 1s")
+	}
+	if c.Admission.RetryAfter < time.Second {
+		return errors.New("admission retry_after must be at least 1s")
+	}
+	if c.FanoutWorkers < 0 {
+		return errors.New("fanout_workers must not be negative")
+	}
+	if c.MilestoneEvery < 0 {
+		return errors.New("milestone_every must not be negative")
+	}
+	if c.RateLimit.Rate < 0 || c.RateLimit.Burst < 1 {
+		return errors.New("rate_limit rate must not be negative and burst must be at least 1")
+	}
+	if c.RateLimit.Bandwidth < 0 {
+		return errors.New("rate_limit bandwidth must not be negative")
+	}
+	if c.RateLimit.Cursor < 0 {
+		return errors.New("rate_limit cursor must not be negative")
+	}
+	if c.Chat.Rate < 0 || c.Chat.Burst < 1 {
+		return errors.New("chat rate must not be negative and burst must be at least 1")
+	}
+	if c.Chat.MaxLength < 1 || c.Chat.MaxLength > maxChatLength {
+		return fmt.Errorf("chat max_length must be between 1 and %d", maxChatLength)
+	}
+	if c.Chat.History < 0 {
+		return errors.New("chat history must not be negative")
+	}
+	if c.Snapshots.Dir != "" && (c.Snapshots.Interval <= 0 || c.Snapshots.Keep < 1) {
+		return errors.New("snapshots interval must be positive and keep at least 1")
+	}
+	if c.OverwriteProtection < 0 {
+		return errors.New("overwrite protection must not be negative")
+	}
+	if c.Leaderboard.Interval < 0 {
+		return errors.New("leaderboard interval must not be negative")
+	}
+	if c.ReadModels.Interval < 0 {
+		return errors.New("read_models interval must not be negative")
+	}
+	if c.Decay.After < 0 || (c.Decay.After > 0 && c.Decay.Interval <= 0) {
+		return errors.New("decay after must not be negative and its interval must be positive")
+	}
+	teams := make(map[string]bool)
+	for _, team := range c.Teams.Names {
+		if !validTeamName.MatchString(team) {
+			return fmt.Errorf("invalid team name %q (use up to 32 letters, digits, _ and -)", team)
+		}
+		if teams[team] {
+			return fmt.Errorf("duplicate team %q", team)
+		}
+		teams[team] = true
+	}
+	if len(c.Teams.Names) > 0 && c.Teams.ScoreInterval <= 0 {
+		return errors.New("teams score interval must be positive")
+	}
+	if c.Economy.Enabled && (c.Economy.ClaimDuration <= 0 || c.Economy.MaxCells < 0) {
+		return errors.New("economy claim duration must be positive and max cells must not be negative")
+	}
+	if g := c.Grief; g.Enabled {
+		if g.Window <= 0 || g.Overwrites < 1 || g.ColorShare <= 0 || g.ColorShare > 1 {
+			return errors.New("grief window and overwrites must be positive and color share in (0, 1]")
+		}
+		if g.ScriptSamples < 0 || g.ScriptSamples == 1 || g.ScriptSamples == 2 || g.ScriptJitter < 0 {
+			return errors.New("grief script samples must be 0 or at least 3 and script jitter must not be negative")
+		}
+		if g.Quarantine <= 0 || g.QuarantineCooldown <= 0 {
+			return errors.New("grief quarantine and quarantine cooldown must be positive")
+		}
+	}
+	if cc := c.Captcha; cc.Enabled() {
+		if cc.Provider != CaptchaHCaptcha && cc.Provider != CaptchaTurnstile {
+			return fmt.Errorf("captcha provider must be %q or %q", CaptchaHCaptcha, CaptchaTurnstile)
+		}
+		if cc.SecretKey == "" {
+			return errors.New("captcha secret_key must be set when a provider is")
+		}
+		if cc.Placements < 0 || (!cc.OnConnect && cc.Placements == 0) {
+			return errors.New("captcha placements must be positive unless on_connect is enabled, and never negative")
+		}
+	}
+	if c.Audit.Retention < 0 {
+		return errors.New("audit retention must not be negative")
+	}
+	if c.Discord.Interval < 0 {
+		return errors.New("discord interval must not be negative")
+	}
+	if c.Discord.WebhookURL != "" && c.Discord.Canvas != "" && !slices.ContainsFunc(c.Canvases, func(canvas CanvasConfig) bool {
+		return canvas.Name == c.Discord.Canvas
+	}) {
+		return fmt.Errorf("discord canvas %q is not configured", c.Discord.Canvas)
+	}
+	if c.Privacy.HashIPs && c.Privacy.IPSalt == "" {
+		return errors.New("privacy ip_salt must be set when hash_ips is enabled")
+	}
+	if c.Auth.Required && c.Auth.JWTSecret == "" {
+		return errors.New("auth jwt_secret must be set when auth is required")
+	}
+	if o := c.Auth.OAuth; o.Enabled() {
+		if c.Auth.JWTSecret == "" {
+			return errors.New("auth jwt_secret must be set to sign oauth sessions")
+		}
+		if _, err := url.ParseRequestURI(o.BaseURL); err != nil || !strings.HasPrefix(o.BaseURL, "http") {
+			return errors.New("auth oauth base_url must be the http(s) URL of the server")
+		}
+		if o.SessionTTL <= 0 {
+			return errors.New("auth oauth session_ttl must be positive")
+		}
+		for _, p := range []OAuthProviderConfig{o.Google, o.GitHub} {
+			if p.ClientID != "" && p.ClientSecret == "" {
+				return errors.New("auth oauth client_secret must be set with its client_id")
+			}
+		}
+	}
+	if t := c.Tracing; t.Enabled() {
+		if u, err := url.Parse(t.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("tracing endpoint must be the http(s) URL of an OTLP collector")
+		}
+		if t.ServiceName == "" {
+			return errors.New("tracing service_name must be set")
+		}
+		if t.SampleRatio < 0 || t.SampleRatio > 1 {
+			return errors.New("tracing sample_ratio must be between 0 and 1")
+		}
+	}
+	if s := c.Sentry; s.Enabled() {
+		if u, err := url.Parse(s.DSN); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.User == nil || u.Host == "" {
+			return errors.New("sentry dsn must be the DSN of a Sentry project, e.g. https://<key>@o0.ingest.sentry.io/<project>")
+		}
+	}
+	if _, err := c.Log.SlogLevel(); err != nil {
+		return err
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		return fmt.Errorf("invalid log format %q", c.Log.Format)
+	}
+	if c.Buffers.Read < 1 || c.Buffers.Write < 1 {
+		return errors.New("buffer sizes must be at least 1")
+	}
+	if c.Buffers.Send < minSendBuffer {
+		return fmt.Errorf("buffers send must be at least %d", minSendBuffer)
+	}
+	if !slices.Contains([]string{"drop_oldest", "drop_updates", "close"}, c.Buffers.SlowClient) {
+		return fmt.Errorf("invalid buffers slow_client policy %q", c.Buffers.SlowClient)
+	}
+	return nil
+}
+
+// ValidateOriginPattern checks an allowed origin: scheme://host[:port], where
+// the host may start with "*." to match any subdomain
+func ValidateOriginPattern(pattern string) error {
+	u, err := url.Parse(pattern)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		(u.Path != "" && u.Path != "/") || u.RawQuery != "" || strings.Contains(strings.TrimPrefix(u.Host, "*."), "*") {
+		return fmt.Errorf("invalid allowed origin %q (use scheme://host[:port], optionally with a *. subdomain wildcard)", pattern)
+	}
+	return nil
+}
+
+// SlogLevel parses the configured level
+func (l LogConfig) SlogLevel() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(l.Level)); err != nil {
+		return level, fmt.Errorf("invalid log level %q", l.Level)
+	}
+	return level, nil
+}
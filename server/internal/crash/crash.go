@@ -0,0 +1,99 @@
+// Package crash recovers from the panics of the server's long-running
+// goroutines, so a bug triggered by one client or one batch of writes doesn't
+// take the whole server down. Panics are logged with their stack trace, and
+// reported to Sentry once Setup was called.
+package crash
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/million_grids/server/internal/config"
+)
+
+// Delay before a supervised function that panicked runs again, so one
+// panicking on every run doesn't spin
+const restartDelay = time.Second
+
+// Setup reports the recovered panics to the configured Sentry project,
+// returning the function waiting up to a timeout for the reports not yet
+// sent at shutdown
+func Setup(cfg config.SentryConfig) (func(time.Duration) bool, error) {
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:              cfg.DSN,
+		Environment:      cfg.Environment,
+		Release:          release(),
+		AttachStacktrace: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up sentry: %w", err)
+	}
+	return sentry.Flush, nil
+}
+
+// Recover recovers from a panic of the calling goroutine and reports it. It
+// must be deferred directly, and ends the goroutine normally: the deferred
+// calls before it still run. attrs are slog key-value pairs describing where
+// the panic happened.
+func Recover(name string, attrs ...any) {
+	if r := recover(); r != nil {
+		report(name, r, attrs)
+	}
+}
+
+// Supervise runs fn until it returns normally, reporting its panics and
+// running it again restartDelay after each
+func Supervise(name string, fn func(), attrs ...any) {
+	for panicked(name, fn, attrs) {
+		time.Sleep(restartDelay)
+	}
+}
+
+// panicked runs fn, reporting whether it panicked
+func panicked(name string, fn func(), attrs []any) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			report(name, r, attrs)
+			panicked = true
+		}
+	}()
+	fn()
+	return false
+}
+
+// report logs a recovered panic with the stack of the goroutine, and sends
+// it to Sentry when reporting is set up
+func report(name string, r any, attrs []any) {
+	slog.Error("Recovered from panic", append([]any{"in", name, "panic", r, "stack", string(debug.Stack())}, attrs...)...)
+
+	hub := sentry.CurrentHub()
+	if hub.Client() == nil {
+		return
+	}
+	hub = hub.Clone()
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetTag("goroutine", name)
+		for i := 0; i+1 < len(attrs); i += 2 {
+			scope.SetExtra(fmt.Sprint(attrs[i]), attrs[i+1])
+		}
+	})
+	hub.Recover(r)
+}
+
+// release returns the VCS revision the binary was built from, which the
+// reports are filed under
+func release() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			return s.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// APIKey lets a bot client authenticate with its own rate limit and
+// attribution. Only the hash of the key is stored; revoked keys are kept so
+// their changes stay attributable.
+type APIKey struct {
+	ID   uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name string `gorm:"size:64;not null" json:"name"`
+
+	// Hex SHA-256 of the key, and its first characters to recognize it by
+	Hash   string `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Prefix string `gorm:"size:16;not null" json:"prefix"`
+
+	// Role and team the key paints with, like those of a token
+	Role string `gorm:"size:16" json:"role,omitempty"`
+	Team string `gorm:"size:32" json:"team,omitempty"`
+
+	// Sustained requests per second and burst (0 applies the per-IP limits)
+	Rate  float64 `gorm:"not null;default:0" json:"rate"`
+	Burst int     `gorm:"not null;default:0" json:"burst"`
+
+	CreatedAt time.Time  `gorm:"not null" json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// TableName specifies the table name for APIKey
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// LoadAPIKeys retrieves every API key, revoked ones included, oldest first
+func (s *GormStore) LoadAPIKeys() ([]APIKey, error) {
+	var keys []APIKey
+	if err := s.db.Order("id").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to load api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// SaveAPIKey inserts a new API key, setting its ID
+func (s *GormStore) SaveAPIKey(key *APIKey) error {
+	return s.db.Create(key).Error
+}
+
+// RevokeAPIKey marks an API key as revoked, reporting false if there is no
+// such key or it was already revoked
+func (s *GormStore) RevokeAPIKey(id uint) (bool, error) {
+	result := s.db.Model(&APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to revoke api key %d: %w", id, result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+	"gorm.io/gorm"
+)
+
+// ArchivedPixel is an active cell of a canvas at the time it was reset,
+// kept under the name of the archive
+type ArchivedPixel struct {
+	ID      uint64 `gorm:"primaryKey;autoIncrement"`
+	Archive string `gorm:"size:64;not null;index:idx_archive_canvas"`
+	Canvas  string `gorm:"size:64;not null;index:idx_archive_canvas"`
+
+	X         int         `gorm:"not null"`
+	Y         int         `gorm:"not null"`
+	Color     model.Color `gorm:"not null"`
+	CreatedBy string      `gorm:"size:64"`
+	ModifyAt  *time.Time
+	ModifyBy  string `gorm:"size:64"`
+
+	ArchivedAt time.Time `gorm:"not null"`
+}
+
+// TableName specifies the table name for ArchivedPixel
+func (ArchivedPixel) TableName() string {
+	return "pixel_archives"
+}
+
+// ResetCanvas copies the active pixels of a canvas into the archive table
+// under the given name, deletes all its pixels and cell metadata and records
+// the reset in the event stream, in one transaction. It returns the number of
+// pixels archived. The history is kept.
+func (s *GormStore) ResetCanvas(canvas, archive string) (int64, error) {
+	var archived int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(`INSERT INTO pixel_archives (archive, canvas, x, y, color, created_by, modify_at, modify_by, archived_at)
+			SELECT ?, canvas, x, y, color, created_by, modify_at, modify_by, ? FROM pixels WHERE canvas = ? AND active = ?`,
+			archive, time.Now(), canvas, true)
+		if result.Error != nil {
+			return result.Error
+		}
+		archived = result.RowsAffected
+
+		if err := tx.Where("canvas = ?", canvas).Delete(&CellMetadata{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("canvas = ?", canvas).Delete(&model.Pixel{}).Error; err != nil {
+			return err
+		}
+		return appendEvent(tx, CanvasEvent{Canvas: canvas, Kind: EventReset, Target: archive})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to reset canvas %s: %w", canvas, err)
+	}
+	return archived, nil
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// AuditEntry records a mutation of the canvases: a paint, an admin request or
+// a reset, with who made it
+type AuditEntry struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement" json:"id"`
+
+	// Canvas changed (empty for actions on every canvas, e.g. bans)
+	Canvas string `gorm:"size:64;not null;default:'';index:idx_audit_log_canvas" json:"canvas,omitempty"`
+
+	// Who made the change: the painter, or "admin" for admin requests
+	Actor string `gorm:"size:64;not null;default:'';index:idx_audit_log_actor" json:"actor,omitempty"`
+
+	// IP the change came from, when known
+	IP string `gorm:"size:64;not null;default:''" json:"ip,omitempty"`
+
+	// Kind of change, e.g. "paint", "reset" or "admin.ban"
+	Action string `gorm:"size:64;not null;index:idx_audit_log_action" json:"action"`
+
+	// Details of the change, as JSON
+	Payload string `gorm:"type:text;not null" json:"-"`
+
+	CreatedAt time.Time `gorm:"not null;index:idx_audit_log_created_at" json:"at"`
+}
+
+// TableName specifies the table name for AuditEntry
+func (AuditEntry) TableName() string {
+	return "audit_log"
+}
+
+// AuditFilter selects audit log entries, empty fields match every entry
+type AuditFilter struct {
+	Canvas string
+	Action string
+
+	// IDs the actor is recorded under
+	Actors []string
+
+	// Entries made in [From, To)
+	From, To time.Time
+
+	// Entries older than this ID, to page through the log
+	Before uint64
+
+	Limit int
+}
+
+// SaveAuditEntries appends entries to the audit log
+func (s *GormStore) SaveAuditEntries(entries []AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := s.db.Create(&entries).Error; err != nil {
+		return fmt.Errorf("failed to save audit log entries: %w", err)
+	}
+	return nil
+}
+
+// QueryAuditLog returns the audit log entries matching filter, newest first
+func (s *GormStore) QueryAuditLog(filter AuditFilter) ([]AuditEntry, error) {
+	query := s.db.Model(&AuditEntry{})
+	if filter.Canvas != "" {
+		query = query.Where("canvas = ?", filter.Canvas)
+	}
+	if len(filter.Actors) > 0 {
+		query = query.Where("actor IN ?", filter.Actors)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	if filter.Before > 0 {
+		query = query.Where("id < ?", filter.Before)
+	}
+	var entries []AuditEntry
+	if err := query.Order("id DESC").Limit(filter.Limit).Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// PruneAuditLog deletes the audit log entries made before a time, returning
+// how many it deleted
+func (s *GormStore) PruneAuditLog(before time.Time) (int64, error) {
+	result := s.db.Where("created_at < ?", before).Delete(&AuditEntry{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to prune audit log: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
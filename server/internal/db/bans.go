@@ -0,0 +1,93 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Ban is a banned IP address or CIDR range
+type Ban struct {
+	// Canonical CIDR notation, e.g. "1.2.3.4/32" or "10.0.0.0/8"
+	Target    string    `gorm:"size:64;primaryKey" json:"target"`
+	Reason    string    `gorm:"size:255;null" json:"reason,omitempty"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
+
+// TableName specifies the table name for Ban
+func (Ban) TableName() string {
+	return "bans"
+}
+
+// LoadBans retrieves all bans from the database
+func (s *GormStore) LoadBans() ([]Ban, error) {
+	var bans []Ban
+	if err := s.db.Order("created_at").Find(&bans).Error; err != nil {
+		return nil, fmt.Errorf("failed to load bans: %w", err)
+	}
+	return bans, nil
+}
+
+// SaveBan inserts or updates a ban, recording it in the event stream
+func (s *GormStore) SaveBan(ban Ban) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&ban).Error; err != nil {
+			return err
+		}
+		return appendEvent(tx, CanvasEvent{Kind: EventBan, Target: ban.Target, Reason: ban.Reason, CreatedAt: ban.CreatedAt})
+	})
+}
+
+// DeleteBan removes a ban by target, recording it in the event stream
+func (s *GormStore) DeleteBan(target string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&Ban{}, "target = ?", target).Error; err != nil {
+			return err
+		}
+		return appendEvent(tx, CanvasEvent{Kind: EventUnban, Target: target})
+	})
+}
+
+// ShadowBan is a shadow-banned IP address, CIDR range or user, whose paints
+// are only shown back to them
+type ShadowBan struct {
+	// Canonical CIDR notation for networks, the user ID otherwise
+	Target    string    `gorm:"size:128;primaryKey" json:"target"`
+	Reason    string    `gorm:"size:255;null" json:"reason,omitempty"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
+
+// TableName specifies the table name for ShadowBan
+func (ShadowBan) TableName() string {
+	return "shadow_bans"
+}
+
+// LoadShadowBans retrieves all shadow bans from the database
+func (s *GormStore) LoadShadowBans() ([]ShadowBan, error) {
+	var bans []ShadowBan
+	if err := s.db.Order("created_at").Find(&bans).Error; err != nil {
+		return nil, fmt.Errorf("failed to load shadow bans: %w", err)
+	}
+	return bans, nil
+}
+
+// SaveShadowBan inserts or updates a shadow ban, recording it in the event stream
+func (s *GormStore) SaveShadowBan(ban ShadowBan) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&ban).Error; err != nil {
+			return err
+		}
+		return appendEvent(tx, CanvasEvent{Kind: EventShadowBan, Target: ban.Target, Reason: ban.Reason, CreatedAt: ban.CreatedAt})
+	})
+}
+
+// DeleteShadowBan removes a shadow ban by target, recording it in the event stream
+func (s *GormStore) DeleteShadowBan(target string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&ShadowBan{}, "target = ?", target).Error; err != nil {
+			return err
+		}
+		return appendEvent(tx, CanvasEvent{Kind: EventShadowUnban, Target: target})
+	})
+}
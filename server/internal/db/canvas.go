@@ -0,0 +1,40 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Canvas records the dimensions of a named canvas
+type Canvas struct {
+	Name      string    `gorm:"size:64;primaryKey" json:"name"`
+	Width     int       `gorm:"not null" json:"width"`
+	Height    int       `gorm:"not null" json:"height"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
+
+// TableName specifies the table name for Canvas
+func (Canvas) TableName() string {
+	return "canvases"
+}
+
+// LoadCanvas retrieves a canvas by name, returning nil if it has not been stored yet
+func (s *GormStore) LoadCanvas(name string) (*Canvas, error) {
+	var canvas Canvas
+	err := s.db.First(&canvas, "name = ?", name).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load canvas %s: %w", name, err)
+	}
+	return &canvas, nil
+}
+
+// SaveCanvas inserts or updates a canvas
+func (s *GormStore) SaveCanvas(canvas Canvas) error {
+	return s.db.Save(&canvas).Error
+}
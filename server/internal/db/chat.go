@@ -0,0 +1,61 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ChatMessage is a chat message posted on a canvas, kept so the latest ones
+// survive restarts
+type ChatMessage struct {
+	ID     uint64 `gorm:"primaryKey;autoIncrement"`
+	Canvas string `gorm:"size:64;not null;index:idx_chat_canvas"`
+
+	// Display name of the author
+	Name string `gorm:"size:128;not null"`
+	Text string `gorm:"size:4096;not null"`
+
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+// TableName specifies the table name for ChatMessage
+func (ChatMessage) TableName() string {
+	return "chat_messages"
+}
+
+// SaveChatMessage appends a chat message
+func (s *GormStore) SaveChatMessage(msg ChatMessage) error {
+	if err := s.db.Create(&msg).Error; err != nil {
+		return fmt.Errorf("failed to save chat message: %w", err)
+	}
+	return nil
+}
+
+// LoadChatMessages returns the latest limit chat messages of a canvas, oldest first
+func (s *GormStore) LoadChatMessages(canvas string, limit int) ([]ChatMessage, error) {
+	var msgs []ChatMessage
+	if err := s.db.Where("canvas = ?", canvas).Order("id DESC").Limit(limit).Find(&msgs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load chat messages of canvas %s: %w", canvas, err)
+	}
+	slices.Reverse(msgs)
+	return msgs, nil
+}
+
+// PruneChatMessages deletes the chat messages of a canvas older than the
+// latest keep, returning how many it deleted
+func (s *GormStore) PruneChatMessages(canvas string, keep int) (int64, error) {
+	var oldest ChatMessage
+	result := s.db.Where("canvas = ?", canvas).Order("id DESC").Offset(keep).Limit(1).Find(&oldest)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to find chat messages to prune: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return 0, nil
+	}
+	result = s.db.Where("canvas = ? AND id <= ?", canvas, oldest.ID).Delete(&ChatMessage{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to prune chat messages: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Claim reserves a rectangle of a canvas for an account until it expires,
+// in economy mode
+type Claim struct {
+	ID     uint   `gorm:"primaryKey;autoIncrement"`
+	Canvas string `gorm:"size:64;not null;index:idx_claim_canvas"`
+
+	// Half-open bounds of the region
+	X1 int `gorm:"not null"`
+	Y1 int `gorm:"not null"`
+	X2 int `gorm:"not null"`
+	Y2 int `gorm:"not null"`
+
+	// Account the region is reserved for
+	Owner string `gorm:"size:64;not null;index:idx_claim_owner"`
+
+	ExpiresAt time.Time `gorm:"not null;index:idx_claim_expires_at"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+// TableName specifies the table name for Claim
+func (Claim) TableName() string {
+	return "claims"
+}
+
+// LoadClaims retrieves the claims of a canvas that haven't expired at now,
+// oldest first
+func (s *GormStore) LoadClaims(canvas string, now time.Time) ([]Claim, error) {
+	var claims []Claim
+	if err := s.db.Where("canvas = ? AND expires_at > ?", canvas, now).Order("id").Find(&claims).Error; err != nil {
+		return nil, fmt.Errorf("failed to load claims of canvas %s: %w", canvas, err)
+	}
+	return claims, nil
+}
+
+// SaveClaim inserts a claim, setting its ID
+func (s *GormStore) SaveClaim(claim *Claim) error {
+	return s.db.Create(claim).Error
+}
+
+// DeleteClaim removes a claim by ID
+func (s *GormStore) DeleteClaim(id uint) error {
+	return s.db.Delete(&Claim{}, id).Error
+}
@@ -0,0 +1,23 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// DecayActor is the actor the history attributes cells cleared by decay to
+const DecayActor = "decay"
+
+// ExpiredPixels returns up to limit active pixels of a canvas last changed
+// before before, oldest first. Pixels without a modification time never expire.
+func (s *GormStore) ExpiredPixels(canvas string, before time.Time, limit int) ([]model.Pixel, error) {
+	var pixels []model.Pixel
+	result := s.db.Where("canvas = ? AND active = ? AND modify_at < ?", canvas, true, before).
+		Order("modify_at").Limit(limit).Find(&pixels)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to load expired pixels of canvas %s: %w", canvas, result.Error)
+	}
+	return pixels, nil
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/million_grids/server/internal/model"
+	"gorm.io/gorm"
+)
+
+// ErasureResult counts the records whose attribution an erasure removed
+type ErasureResult struct {
+	// Pixels created or last modified by the actors
+	Pixels int64 `json:"pixels"`
+
+	// History records of their changes
+	History int64 `json:"history"`
+
+	// Leaderboard rows counting their placements
+	Contributors int64 `json:"contributors"`
+
+	// Audit log entries of their changes
+	Audit int64 `json:"audit"`
+
+	// Reports they filed, which keep counting them anonymously
+	Reports int64 `json:"reports"`
+}
+
+// EraseActors removes every trace of who the actors are from the pixels, the
+// history (including the location changes were made from), the leaderboard,
+// the audit log and the reports they filed.
+// The changes themselves are kept, attributed to no one.
+func (s *GormStore) EraseActors(actors []string) (ErasureResult, error) {
+	var erased ErasureResult
+	if len(actors) == 0 {
+		return erased, nil
+	}
+
+	// Leaderboard rows are keyed by salted hashes of the actors
+	var state LeaderboardState
+	err := s.db.First(&state, leaderboardStateID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return erased, fmt.Errorf("failed to load leaderboard state: %w", err)
+	}
+	var contributors []string
+	if err == nil {
+		for _, actor := range actors {
+			contributors = append(contributors, hashActor(state.Salt, actor))
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Pixel{}).
+			Where("created_by IN ? OR modify_by IN ?", actors, actors).
+			Updates(map[string]interface{}{
+				"created_by": gorm.Expr("CASE WHEN created_by IN ? THEN '' ELSE created_by END", actors),
+				"modify_by":  gorm.Expr("CASE WHEN modify_by IN ? THEN '' ELSE modify_by END", actors),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		erased.Pixels = result.RowsAffected
+
+		result = tx.Model(&PixelHistory{}).
+			Where("actor IN ?", actors).
+			Updates(map[string]interface{}{"actor": "", "country": "", "region": ""})
+		if result.Error != nil {
+			return result.Error
+		}
+		erased.History = result.RowsAffected
+
+		result = tx.Model(&AuditEntry{}).Where("actor IN ?", actors).Update("actor", "")
+		if result.Error != nil {
+			return result.Error
+		}
+		erased.Audit = result.RowsAffected
+
+		result = tx.Where("reporter IN ?", actors).Delete(&ReportReporter{})
+		if result.Error != nil {
+			return result.Error
+		}
+		erased.Reports = result.RowsAffected
+
+		if len(contributors) > 0 {
+			result = tx.Where("contributor IN ?", contributors).Delete(&ContributorStats{})
+			if result.Error != nil {
+				return result.Error
+			}
+			erased.Contributors = result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return ErasureResult{}, fmt.Errorf("failed to erase attribution: %w", err)
+	}
+	return erased, nil
+}
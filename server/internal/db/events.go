@@ -0,0 +1,273 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+	"gorm.io/gorm"
+)
+
+// Kinds of canvas events
+const (
+	EventReset       = "reset"
+	EventBan         = "ban"
+	EventUnban       = "unban"
+	EventShadowBan   = "shadow_ban"
+	EventShadowUnban = "shadow_unban"
+)
+
+// CanvasEvent is an append-only record of a change other than a placement.
+// The pixel history records the placements; interleaved with the canvas
+// events at their HistoryID, it forms the event stream every state of a
+// canvas derives from. The pixels, bans and shadow_bans tables and the
+// snapshots are projections of the stream.
+type CanvasEvent struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement" json:"id"`
+
+	// Canvas reset, empty for the events applying to every canvas such as bans
+	Canvas string `gorm:"size:64;not null;default:'';index:idx_canvas_events_canvas" json:"canvas,omitempty"`
+	Kind   string `gorm:"size:16;not null" json:"kind"`
+
+	// ID of the newest history record of the canvas when the event happened,
+	// which replays apply the event after
+	HistoryID uint64 `gorm:"not null" json:"history_id"`
+
+	// Archive name of a reset, or the IP, CIDR range or user a ban applies to
+	Target string `gorm:"size:128;null" json:"target,omitempty"`
+	Reason string `gorm:"size:255;null" json:"reason,omitempty"`
+
+	CreatedAt time.Time `gorm:"not null;index:idx_canvas_events_created_at" json:"at"`
+}
+
+// TableName specifies the table name for CanvasEvent
+func (CanvasEvent) TableName() string {
+	return "canvas_events"
+}
+
+// StreamPosition is a position in the event stream of a canvas: the newest
+// history record and canvas event it includes
+type StreamPosition struct {
+	HistoryID uint64
+	EventID   uint64
+}
+
+// appendEvent records a canvas event in the transaction, positioned after the
+// newest history record of its canvas. Pending writes must be flushed first
+// for the position to include them.
+func appendEvent(tx *gorm.DB, event CanvasEvent) error {
+	query := tx.Model(&PixelHistory{})
+	if event.Canvas != "" {
+		query = query.Where("canvas = ?", event.Canvas)
+	}
+	if err := query.Select("COALESCE(MAX(id), 0)").Scan(&event.HistoryID).Error; err != nil {
+		return err
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	return tx.Create(&event).Error
+}
+
+// CanvasEvents returns up to limit events of a canvas, including those
+// applying to every canvas, recorded after the event with ID after, oldest first
+func (s *GormStore) CanvasEvents(canvas string, after uint64, limit int) ([]CanvasEvent, error) {
+	var events []CanvasEvent
+	result := s.db.Where("(canvas = ? OR canvas = '') AND id > ?", canvas, after).
+		Order("id").
+		Limit(limit).
+		Find(&events)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to load events of canvas %s: %w", canvas, result.Error)
+	}
+	return events, nil
+}
+
+// LatestPosition returns the position of the end of the event stream of a canvas
+func (s *GormStore) LatestPosition(canvas string) (StreamPosition, error) {
+	var pos StreamPosition
+	var err error
+	if pos.HistoryID, err = s.LatestHistoryID(canvas); err != nil {
+		return pos, err
+	}
+	result := s.db.Model(&CanvasEvent{}).Where("canvas = ?", canvas).Select("COALESCE(MAX(id), 0)").Scan(&pos.EventID)
+	if result.Error != nil {
+		return pos, fmt.Errorf("failed to load latest event of canvas %s: %w", canvas, result.Error)
+	}
+	return pos, nil
+}
+
+// ReplayStream replays the event stream of a canvas after a position, up to
+// and including the time to: fn is called with the changes to the cells in
+// [x1, x2) x [y1, y2), oldest first in batches, and reset at each reset of the
+// canvas, between the changes made before and after it
+func (s *GormStore) ReplayStream(canvas string, x1, y1, x2, y2 int, from StreamPosition, to time.Time, fn func([]PixelHistory) error, reset func(CanvasEvent) error) error {
+	var resets []CanvasEvent
+	result := s.db.Where("canvas = ? AND kind = ? AND id > ? AND created_at <= ?", canvas, EventReset, from.EventID, to).
+		Order("id").
+		Find(&resets)
+	if result.Error != nil {
+		return fmt.Errorf("failed to load resets of canvas %s: %w", canvas, result.Error)
+	}
+
+	after := from.HistoryID
+	for _, event := range resets {
+		// Resets older than the newest change of the position are already
+		// reflected in it, e.g. in snapshots recording no event ID
+		if event.HistoryID < after {
+			continue
+		}
+		if event.HistoryID > after {
+			if err := s.replayRange(canvas, x1, y1, x2, y2, after, event.HistoryID, to, fn); err != nil {
+				return err
+			}
+			after = event.HistoryID
+		}
+		if err := reset(event); err != nil {
+			return err
+		}
+	}
+	return s.replayRange(canvas, x1, y1, x2, y2, after, 0, to, fn)
+}
+
+// replayRange calls fn with the changes to the cells in [x1, x2) x [y1, y2) of
+// a canvas recorded after the record with ID after, up to the one with ID
+// upTo (0 for no limit), made up to and including the time to
+func (s *GormStore) replayRange(canvas string, x1, y1, x2, y2 int, after, upTo uint64, to time.Time, fn func([]PixelHistory) error) error {
+	if upTo == 0 {
+		return s.ReplayHistory(canvas, x1, y1, x2, y2, after, to, fn)
+	}
+	var batch []PixelHistory
+	result := s.db.Where("canvas = ? AND x >= ? AND x < ? AND y >= ? AND y < ? AND id > ? AND id <= ? AND created_at <= ?",
+		canvas, x1, x2, y1, y2, after, upTo, to).
+		FindInBatches(&batch, replayBatchSize, func(tx *gorm.DB, n int) error {
+			return fn(batch)
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to replay history of canvas %s: %w", canvas, result.Error)
+	}
+	return nil
+}
+
+// Number of pixels inserted per statement while rebuilding the projection
+const rebuildBatchSize = 1000
+
+// RebuildPixels rebuilds the pixels of a canvas from its event stream: the
+// changes recorded since its last reset, applied in order. The stream keeps
+// no cell metadata, which the rebuild leaves alone. It returns the number of
+// cells written.
+func (s *GormStore) RebuildPixels(canvas string) (int, error) {
+	var last CanvasEvent
+	err := s.db.Where("canvas = ? AND kind = ?", canvas, EventReset).Order("id DESC").Limit(1).Find(&last).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to load last reset of canvas %s: %w", canvas, err)
+	}
+
+	// Latest state of each changed cell, in the order first changed
+	pixels := make(map[cellKey]*model.Pixel)
+	var cells []cellKey
+	err = s.replayRange(canvas, 0, 0, model.MaxGridDimension, model.MaxGridDimension, last.HistoryID, 0, time.Now(), func(records []PixelHistory) error {
+		for _, rec := range records {
+			key := cellKey{canvas, rec.X, rec.Y}
+			p, ok := pixels[key]
+			if !ok {
+				p = &model.Pixel{Canvas: canvas, X: rec.X, Y: rec.Y, CreatedBy: rec.Actor}
+				pixels[key] = p
+				cells = append(cells, key)
+			}
+			at := rec.CreatedAt
+			p.Active, p.Color, p.ModifyAt, p.ModifyBy, p.Team = rec.Active, rec.Color, &at, rec.Actor, rec.Team
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("canvas = ?", canvas).Delete(&model.Pixel{}).Error; err != nil {
+			return err
+		}
+		batch := make([]model.Pixel, 0, rebuildBatchSize)
+		for i, key := range cells {
+			batch = append(batch, *pixels[key])
+			if len(batch) == rebuildBatchSize || i == len(cells)-1 {
+				if err := tx.Create(&batch).Error; err != nil {
+					return err
+				}
+				batch = batch[:0]
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to rebuild pixels of canvas %s: %w", canvas, err)
+	}
+	return len(cells), nil
+}
+
+// StreamEntry is an entry of the event stream: a placement or a canvas event
+type StreamEntry struct {
+	Change *PixelHistory
+	Event  *CanvasEvent
+}
+
+// ReadStream returns up to limit placements of every canvas recorded after a
+// position, oldest first, with the canvas events recorded between them
+// interleaved, and the position after the entries returned
+func (s *GormStore) ReadStream(from StreamPosition, limit int) ([]StreamEntry, StreamPosition, error) {
+	var records []PixelHistory
+	if err := s.db.Where("id > ?", from.HistoryID).Order("id").Limit(limit).Find(&records).Error; err != nil {
+		return nil, from, fmt.Errorf("failed to read history stream: %w", err)
+	}
+
+	// Events after the last record returned come with the next records
+	query := s.db.Where("id > ?", from.EventID)
+	if len(records) == limit {
+		query = query.Where("history_id < ?", records[len(records)-1].ID)
+	}
+	var events []CanvasEvent
+	if err := query.Order("id").Find(&events).Error; err != nil {
+		return nil, from, fmt.Errorf("failed to read event stream: %w", err)
+	}
+
+	entries := make([]StreamEntry, 0, len(records)+len(events))
+	pos := from
+	next := 0
+	for i := range records {
+		for ; next < len(events) && events[next].HistoryID < records[i].ID; next++ {
+			entries = append(entries, StreamEntry{Event: &events[next]})
+			pos.EventID = events[next].ID
+		}
+		entries = append(entries, StreamEntry{Change: &records[i]})
+		pos.HistoryID = records[i].ID
+	}
+	for ; next < len(events); next++ {
+		entries = append(entries, StreamEntry{Event: &events[next]})
+		pos.EventID = events[next].ID
+	}
+	return entries, pos, nil
+}
+
+// StreamHead returns the position of the end of the event stream of every canvas
+func (s *GormStore) StreamHead() (StreamPosition, error) {
+	var pos StreamPosition
+	if err := s.db.Model(&PixelHistory{}).Select("COALESCE(MAX(id), 0)").Scan(&pos.HistoryID).Error; err != nil {
+		return pos, fmt.Errorf("failed to load latest history: %w", err)
+	}
+	if err := s.db.Model(&CanvasEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&pos.EventID).Error; err != nil {
+		return pos, fmt.Errorf("failed to load latest event: %w", err)
+	}
+	return pos, nil
+}
+
+// HistoryIDBefore returns the ID of the newest history record of any canvas
+// made before a time (0 if there is none)
+func (s *GormStore) HistoryIDBefore(t time.Time) (uint64, error) {
+	var id uint64
+	result := s.db.Model(&PixelHistory{}).Where("created_at < ?", t).Select("COALESCE(MAX(id), 0)").Scan(&id)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to load history before %s: %w", t, result.Error)
+	}
+	return id, nil
+}
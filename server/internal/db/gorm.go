@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/glebarez/sqlite"
+	"github.com/million_grids/server/internal/config"
+	"github.com/million_grids/server/internal/model"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+// GormStore persists pixels, history and bans in a SQL database through GORM
+type GormStore struct {
+	db *gorm.DB
+}
+
+// OpenGormStore connects to the configured database and runs migrations
+func OpenGormStore(cfg config.DatabaseConfig) (*GormStore, error) {
+	// Only log every SQL statement at debug level
+	logLevel := logger.Warn
+	if slog.Default().Enabled(context.Background(), slog.LevelDebug) {
+		logLevel = logger.Info
+	}
+
+	var dialector gorm.Dialector
+	switch cfg.Driver {
+	case "mysql":
+		dialector = mysql.Open(cfg.DSN)
+	case "postgres":
+		dialector = postgres.Open(cfg.DSN)
+	case "sqlite":
+		dialector = sqlite.Open(cfg.DSN)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.Default.LogMode(logLevel),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	if cfg.Driver == "sqlite" {
+		// SQLite allows a single writer, and each connection to an in-memory
+		// database would otherwise see its own empty database
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure sqlite: %w", err)
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+
+	// Auto-migrate the schema
+	if err := db.AutoMigrate(&model.Pixel{}, &PixelHistory{}, &CanvasEvent{}, &Ban{}, &ShadowBan{}, &APIKey{}, &User{}, &Canvas{}, &Palette{}, &ContributorStats{}, &LeaderboardState{}, &Webhook{}, &ChatMessage{}, &ArchivedPixel{}, &LockedRegion{}, &TeamStats{}, &Template{}, &CellMetadata{}, &Claim{}, &AuditEntry{}, &Report{}, &ReportReporter{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	return &GormStore{db: db}, nil
+}
+
+// Ping checks the database connection
+func (s *GormStore) Ping(ctx context.Context) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// LoadAllPixels retrieves all pixels of a canvas from the database
+func (s *GormStore) LoadAllPixels(canvas string) ([]model.Pixel, error) {
+	var pixels []model.Pixel
+	result := s.db.Where("canvas = ?", canvas).Find(&pixels)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to load pixels of canvas %s: %w", canvas, result.Error)
+	}
+	slog.Debug("Loaded pixels from database", "canvas", canvas, "count", len(pixels))
+	return pixels, nil
+}
+
+// SavePixels upserts a batch of pixels with a single multi-row statement,
+// keeping the original creator of cells that already exist. GORM renders the
+// conflict clause as ON DUPLICATE KEY UPDATE on MySQL and ON CONFLICT on Postgres.
+func (s *GormStore) SavePixels(pixels []model.Pixel) error {
+	if len(pixels) == 0 {
+		return nil
+	}
+	result := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "canvas"}, {Name: "x"}, {Name: "y"}},
+		DoUpdates: clause.AssignmentColumns([]string{"active", "color", "modify_at", "modify_by", "team"}),
+	}).Create(&pixels)
+	return result.Error
+}
@@ -0,0 +1,199 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+	"gorm.io/gorm"
+)
+
+// PixelHistory is an append-only record of a single cell change
+type PixelHistory struct {
+	ID        uint64      `gorm:"primaryKey;autoIncrement" json:"-"`
+	Canvas    string      `gorm:"size:64;not null;default:'default';index:idx_pixel_history_cell,priority:1" json:"-"`
+	X         int         `gorm:"not null;index:idx_pixel_history_cell,priority:2" json:"x"`
+	Y         int         `gorm:"not null;index:idx_pixel_history_cell,priority:3" json:"y"`
+	Active    bool        `gorm:"not null" json:"a"`
+	Color     model.Color `gorm:"not null" json:"color"`
+	Actor     string      `gorm:"size:64;null;index:idx_pixel_history_actor" json:"actor,omitempty"`
+	Country   string      `gorm:"size:2;null;index:idx_pixel_history_country" json:"country,omitempty"`
+	Region    string      `gorm:"size:3;null" json:"region,omitempty"`
+	Team      string      `gorm:"size:32;null" json:"team,omitempty"`
+	CreatedAt time.Time   `gorm:"not null;index:idx_pixel_history_created_at" json:"at"`
+}
+
+// TableName specifies the table name for PixelHistory
+func (PixelHistory) TableName() string {
+	return "pixel_history"
+}
+
+// historyFromPixel builds the history record for a pixel change
+func historyFromPixel(p model.Pixel) PixelHistory {
+	at := time.Now()
+	if p.ModifyAt != nil {
+		at = *p.ModifyAt
+	}
+	return PixelHistory{
+		Canvas:    p.Canvas,
+		X:         p.X,
+		Y:         p.Y,
+		Active:    p.Active,
+		Color:     p.Color,
+		Actor:     p.ModifyBy,
+		Country:   p.Country,
+		Region:    p.Region,
+		Team:      p.Team,
+		CreatedAt: at,
+	}
+}
+
+// SaveHistory appends a batch of history records
+func (s *GormStore) SaveHistory(records []PixelHistory) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return s.db.Create(&records).Error
+}
+
+// GetPixelHistory returns the most recent changes to a cell of a canvas, newest first
+func (s *GormStore) GetPixelHistory(canvas string, x, y, limit int) ([]PixelHistory, error) {
+	var records []PixelHistory
+	result := s.db.Where("canvas = ? AND x = ? AND y = ?", canvas, x, y).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&records)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to load history for (%d, %d): %w", x, y, result.Error)
+	}
+	return records, nil
+}
+
+// RegionHistory returns up to limit changes to the cells in [x1, x2) x
+// [y1, y2) of a canvas made since a time, newest first
+func (s *GormStore) RegionHistory(canvas string, x1, y1, x2, y2 int, since time.Time, limit int) ([]PixelHistory, error) {
+	var records []PixelHistory
+	result := s.db.Where("canvas = ? AND x >= ? AND x < ? AND y >= ? AND y < ? AND created_at >= ?", canvas, x1, x2, y1, y2, since).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&records)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to load history of region (%d, %d)-(%d, %d): %w", x1, y1, x2, y2, result.Error)
+	}
+	return records, nil
+}
+
+// Number of history records loaded per query while replaying
+const replayBatchSize = 1000
+
+// ReplayHistory calls fn with the changes to the cells in [x1, x2) x [y1, y2)
+// of a canvas recorded after the record with ID after and made up to and
+// including the time to, oldest first, in batches
+func (s *GormStore) ReplayHistory(canvas string, x1, y1, x2, y2 int, after uint64, to time.Time, fn func([]PixelHistory) error) error {
+	var batch []PixelHistory
+	result := s.db.Where("canvas = ? AND x >= ? AND x < ? AND y >= ? AND y < ? AND id > ? AND created_at <= ?",
+		canvas, x1, x2, y1, y2, after, to).
+		FindInBatches(&batch, replayBatchSize, func(tx *gorm.DB, n int) error {
+			return fn(batch)
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to replay history of canvas %s: %w", canvas, result.Error)
+	}
+	return nil
+}
+
+// LatestHistoryID returns the ID of the newest history record of a canvas (0 if there is none)
+func (s *GormStore) LatestHistoryID(canvas string) (uint64, error) {
+	var id uint64
+	result := s.db.Model(&PixelHistory{}).Where("canvas = ?", canvas).Select("COALESCE(MAX(id), 0)").Scan(&id)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to load latest history of canvas %s: %w", canvas, result.Error)
+	}
+	return id, nil
+}
+
+// HistoryCount returns the number of changes recorded on a canvas
+func (s *GormStore) HistoryCount(canvas string) (int64, error) {
+	var count int64
+	if err := s.db.Model(&PixelHistory{}).Where("canvas = ?", canvas).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count history of canvas %s: %w", canvas, err)
+	}
+	return count, nil
+}
+
+// Number of cells whose history is loaded per query during rollback
+const rollbackChunkSize = 500
+
+// RollbackStates computes the states that revert the changes made by an actor,
+// recorded under any of actors, on a canvas between from and to. Each touched
+// cell is restored to its state before the actor's first change in the window
+// (inactive if there was none). Cells that someone else changed after the
+// actor are left alone.
+func (s *GormStore) RollbackStates(canvas string, actors []string, from, to time.Time) ([]model.Pixel, error) {
+	var touched []PixelHistory
+	result := s.db.Where("canvas = ? AND actor IN ? AND created_at BETWEEN ? AND ?", canvas, actors, from, to).
+		Order("id").
+		Find(&touched)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to load changes by %s: %w", strings.Join(actors, ", "), result.Error)
+	}
+
+	// First and last change by the actor per cell, in first-touched order
+	type span struct{ first, last uint64 }
+	spans := make(map[cellKey]*span)
+	var cells []cellKey
+	for _, rec := range touched {
+		key := cellKey{canvas, rec.X, rec.Y}
+		if s, ok := spans[key]; ok {
+			s.last = rec.ID
+			continue
+		}
+		spans[key] = &span{first: rec.ID, last: rec.ID}
+		cells = append(cells, key)
+	}
+
+	var states []model.Pixel
+	for start := 0; start < len(cells); start += rollbackChunkSize {
+		chunk := cells[start:min(start+rollbackChunkSize, len(cells))]
+
+		coords := make([][]interface{}, len(chunk))
+		for i, key := range chunk {
+			coords[i] = []interface{}{key.X, key.Y}
+		}
+		var records []PixelHistory
+		if err := s.db.Where("canvas = ? AND (x, y) IN ?", canvas, coords).Order("id").Find(&records).Error; err != nil {
+			return nil, fmt.Errorf("failed to load cell history: %w", err)
+		}
+
+		// Latest record before the actor's first change, and whether anyone
+		// else changed the cell after the actor's last change
+		prior := make(map[cellKey]*PixelHistory)
+		overwritten := make(map[cellKey]bool)
+		for i := range records {
+			rec := &records[i]
+			key := cellKey{canvas, rec.X, rec.Y}
+			s := spans[key]
+			switch {
+			case rec.ID < s.first:
+				prior[key] = rec
+			case rec.ID > s.last && !slices.Contains(actors, rec.Actor):
+				overwritten[key] = true
+			}
+		}
+
+		for _, key := range chunk {
+			if overwritten[key] {
+				continue
+			}
+			state := model.Pixel{Canvas: canvas, X: key.X, Y: key.Y, Active: false, Color: model.White}
+			if rec := prior[key]; rec != nil {
+				state.Active = rec.Active
+				state.Color = rec.Color
+			}
+			states = append(states, state)
+		}
+	}
+	return states, nil
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/million_grids/server/internal/config"
+	"github.com/million_grids/server/internal/model"
+)
+
+// openTestStore opens a store on an SQLite database in memory
+func openTestStore(t *testing.T) *GormStore {
+	t.Helper()
+	s, err := OpenGormStore(config.DatabaseConfig{Driver: "sqlite", DSN: ":memory:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := s.db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return s
+}
+
+func TestRollbackStates(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	change := func(minutes, x, y int, color model.Color, actor string) PixelHistory {
+		return PixelHistory{Canvas: "c", X: x, Y: y, Active: color != model.White, Color: color, Actor: actor, CreatedAt: at(minutes)}
+	}
+
+	for _, tc := range []struct {
+		name    string
+		history []PixelHistory
+		actors  []string
+		from    time.Time
+		to      time.Time
+		want    map[[2]int]model.Color // Restored color per cell, white for inactive
+	}{
+		{
+			name:    "cell painted on a blank canvas is cleared",
+			history: []PixelHistory{change(1, 1, 1, 0xFF0000, "griefer")},
+			actors:  []string{"griefer"},
+			from:    at(0), to: at(10),
+			want: map[[2]int]model.Color{{1, 1}: model.White},
+		},
+		{
+			name:    "cell restored to its state before the first change",
+			history: []PixelHistory{change(1, 1, 1, 0x00FF00, "artist"), change(2, 1, 1, 0xFF0000, "griefer"), change(3, 1, 1, 0x0000FF, "griefer")},
+			actors:  []string{"griefer"},
+			from:    at(0), to: at(10),
+			want: map[[2]int]model.Color{{1, 1}: 0x00FF00},
+		},
+		{
+			name:    "cell changed by someone else since is kept",
+			history: []PixelHistory{change(1, 1, 1, 0xFF0000, "griefer"), change(2, 1, 1, 0x00FF00, "artist"), change(3, 2, 2, 0xFF0000, "griefer")},
+			actors:  []string{"griefer"},
+			from:    at(0), to: at(10),
+			want: map[[2]int]model.Color{{2, 2}: model.White},
+		},
+		{
+			name:    "changes outside the window are ignored",
+			history: []PixelHistory{change(1, 1, 1, 0xFF0000, "griefer"), change(20, 2, 2, 0xFF0000, "griefer")},
+			actors:  []string{"griefer"},
+			from:    at(10), to: at(30),
+			want: map[[2]int]model.Color{{2, 2}: model.White},
+		},
+		{
+			name:    "raw and hashed actor are one",
+			history: []PixelHistory{change(1, 1, 1, 0xFF0000, "192.0.2.1"), change(2, 1, 1, 0x0000FF, "hashed"), change(3, 2, 2, 0xFF0000, "other")},
+			actors:  []string{"192.0.2.1", "hashed"},
+			from:    at(0), to: at(10),
+			want: map[[2]int]model.Color{{1, 1}: model.White},
+		},
+		{
+			name:    "other canvases are ignored",
+			history: []PixelHistory{{Canvas: "d", X: 1, Y: 1, Active: true, Color: 0xFF0000, Actor: "griefer", CreatedAt: at(1)}},
+			actors:  []string{"griefer"},
+			from:    at(0), to: at(10),
+			want: map[[2]int]model.Color{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := openTestStore(t)
+			if err := s.SaveHistory(tc.history); err != nil {
+				t.Fatal(err)
+			}
+
+			states, err := s.RollbackStates("c", tc.actors, tc.from, tc.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[[2]int]model.Color)
+			for _, p := range states {
+				if p.Active == (p.Color == model.White) {
+					t.Errorf("cell (%d, %d) restored active %t with color %v", p.X, p.Y, p.Active, p.Color)
+				}
+				got[[2]int{p.X, p.Y}] = p.Color
+			}
+			if len(got) != len(tc.want) {
+				t.Errorf("restored %v, want %v", got, tc.want)
+			}
+			for cell, color := range tc.want {
+				if c, ok := got[cell]; !ok || c != color {
+					t.Errorf("cell %v restored to %v (restored: %t), want %v", cell, c, ok, color)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ContributorStats counts the changes a contributor made to a canvas in one hour
+type ContributorStats struct {
+	Canvas string `gorm:"size:64;primaryKey"`
+
+	// Keyed hash of the actor (user ID or IP), so the table doesn't reveal who painted
+	Contributor string `gorm:"size:32;primaryKey"`
+
+	// Start of the hour, in UTC
+	Hour time.Time `gorm:"primaryKey;index:idx_contributor_stats_hour"`
+
+	Placements int `gorm:"not null"`
+}
+
+// TableName specifies the table name for ContributorStats
+func (ContributorStats) TableName() string {
+	return "contributor_stats"
+}
+
+// LeaderboardState is the single row tracking the aggregation of the history
+// into contributor_stats
+type LeaderboardState struct {
+	ID uint `gorm:"primaryKey"`
+
+	// Key hashing actors into contributors, generated on first use
+	Salt string `gorm:"size:64;not null"`
+
+	// ID of the last history record counted
+	LastHistoryID uint64 `gorm:"not null"`
+}
+
+// TableName specifies the table name for LeaderboardState
+func (LeaderboardState) TableName() string {
+	return "leaderboard_state"
+}
+
+// Contributor is a leaderboard entry
+type Contributor struct {
+	Contributor string `json:"contributor"`
+	Placements  int    `json:"placements"`
+}
+
+// leaderboardStateID is the primary key of the LeaderboardState row
+const leaderboardStateID = 1
+
+// errAggregationRace aborts an aggregation that another instance ran concurrently
+var errAggregationRace = errors.New("history aggregated concurrently")
+
+// leaderboardState loads the aggregation state, creating it with a new salt
+// the first time
+func (s *GormStore) leaderboardState() (*LeaderboardState, error) {
+	var state LeaderboardState
+	err := s.db.First(&state, leaderboardStateID).Error
+	if err == nil {
+		return &state, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to load leaderboard state: %w", err)
+	}
+
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate leaderboard salt: %w", err)
+	}
+	state = LeaderboardState{ID: leaderboardStateID, Salt: hex.EncodeToString(salt)}
+	// Another instance may have created it first, in which case its salt wins
+	if err := s.db.FirstOrCreate(&state, LeaderboardState{ID: leaderboardStateID}).Error; err != nil {
+		return nil, fmt.Errorf("failed to create leaderboard state: %w", err)
+	}
+	return &state, nil
+}
+
+// hashActor returns the contributor an actor is counted as
+func hashActor(salt, actor string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(actor))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// AggregateContributions counts up to limit history records not yet counted
+// into contributor_stats, and those of teams into team_stats, and returns how
+// many it counted. Records without an actor and cells cleared by decay are
+// skipped. Instances sharing the database may run it concurrently; a run that
+// loses the race counts nothing.
+func (s *GormStore) AggregateContributions(limit int) (int, error) {
+	state, err := s.leaderboardState()
+	if err != nil {
+		return 0, err
+	}
+
+	var records []PixelHistory
+	if err := s.db.Where("id > ?", state.LastHistoryID).Order("id").Limit(limit).Find(&records).Error; err != nil {
+		return 0, fmt.Errorf("failed to load history to aggregate: %w", err)
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	counts := make(map[ContributorStats]int)
+	teamCounts := make(map[TeamStats]int)
+	for _, rec := range records {
+		if rec.Actor == "" || rec.Actor == DecayActor {
+			continue
+		}
+		hour := rec.CreatedAt.UTC().Truncate(time.Hour)
+		key := ContributorStats{Canvas: rec.Canvas, Contributor: hashActor(state.Salt, rec.Actor), Hour: hour}
+		counts[key]++
+		if rec.Team != "" {
+			teamCounts[TeamStats{Canvas: rec.Canvas, Team: rec.Team, Hour: hour}]++
+		}
+	}
+	lastID := records[len(records)-1].ID
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Claim the records first, so a concurrent run rolls back instead of counting them twice
+		result := tx.Model(&LeaderboardState{}).
+			Where("id = ? AND last_history_id = ?", leaderboardStateID, state.LastHistoryID).
+			Update("last_history_id", lastID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errAggregationRace
+		}
+
+		for key, n := range counts {
+			result := tx.Model(&ContributorStats{}).
+				Where("canvas = ? AND contributor = ? AND hour = ?", key.Canvas, key.Contributor, key.Hour).
+				Update("placements", gorm.Expr("placements + ?", n))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				key.Placements = n
+				if err := tx.Create(&key).Error; err != nil {
+					return err
+				}
+			}
+		}
+		for key, n := range teamCounts {
+			result := tx.Model(&TeamStats{}).
+				Where("canvas = ? AND team = ? AND hour = ?", key.Canvas, key.Team, key.Hour).
+				Update("placements", gorm.Expr("placements + ?", n))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				key.Placements = n
+				if err := tx.Create(&key).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errAggregationRace) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to aggregate contributions: %w", err)
+	}
+	return len(records), nil
+}
+
+// TopContributors returns the contributors with the most placements on a
+// canvas since the start of the hour of since (zero for all time), most first
+func (s *GormStore) TopContributors(canvas string, since time.Time, limit int) ([]Contributor, error) {
+	query := s.db.Model(&ContributorStats{}).Where("canvas = ?", canvas)
+	if !since.IsZero() {
+		query = query.Where("hour >= ?", since.UTC().Truncate(time.Hour))
+	}
+	var top []Contributor
+	result := query.Select("contributor, SUM(placements) AS placements").
+		Group("contributor").
+		Order("placements DESC, contributor").
+		Limit(limit).
+		Scan(&top)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to load leaderboard of canvas %s: %w", canvas, result.Error)
+	}
+	return top, nil
+}
+
+// ContributorHasher returns the function hashing actors into the
+// contributors the leaderboard counts
+func (s *GormStore) ContributorHasher() (func(actor string) string, error) {
+	state, err := s.leaderboardState()
+	if err != nil {
+		return nil, err
+	}
+	return func(actor string) string { return hashActor(state.Salt, actor) }, nil
+}
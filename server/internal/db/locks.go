@@ -0,0 +1,47 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// LockedRegion is a rectangle of a canvas only moderators may paint in
+type LockedRegion struct {
+	ID     uint   `gorm:"primaryKey;autoIncrement"`
+	Canvas string `gorm:"size:64;not null;index:idx_locked_region_canvas"`
+
+	// Half-open bounds of the region
+	X1 int `gorm:"not null"`
+	Y1 int `gorm:"not null"`
+	X2 int `gorm:"not null"`
+	Y2 int `gorm:"not null"`
+
+	// Shown to clients, e.g. "logo"
+	Label string `gorm:"size:128;not null;default:''"`
+
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+// TableName specifies the table name for LockedRegion
+func (LockedRegion) TableName() string {
+	return "locked_regions"
+}
+
+// LoadLockedRegions retrieves the locked regions of a canvas, oldest first
+func (s *GormStore) LoadLockedRegions(canvas string) ([]LockedRegion, error) {
+	var regions []LockedRegion
+	if err := s.db.Where("canvas = ?", canvas).Order("id").Find(&regions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load locked regions of canvas %s: %w", canvas, err)
+	}
+	return regions, nil
+}
+
+// SaveLockedRegion inserts a locked region, setting its ID
+func (s *GormStore) SaveLockedRegion(region *LockedRegion) error {
+	return s.db.Create(region).Error
+}
+
+// DeleteLockedRegion removes a locked region by ID
+func (s *GormStore) DeleteLockedRegion(id uint) error {
+	return s.db.Delete(&LockedRegion{}, id).Error
+}
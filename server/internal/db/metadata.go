@@ -0,0 +1,61 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CellMetadata is a short text and link a painter attached to a cell
+type CellMetadata struct {
+	Canvas string `gorm:"primaryKey;size:64"`
+	X      int    `gorm:"primaryKey;autoIncrement:false"`
+	Y      int    `gorm:"primaryKey;autoIncrement:false"`
+
+	Text string `gorm:"size:280;not null;default:''"`
+	URL  string `gorm:"size:512;not null;default:''"`
+
+	// Painter that attached the metadata
+	Actor string `gorm:"size:64;not null"`
+
+	UpdatedAt time.Time `gorm:"not null"`
+}
+
+// TableName specifies the table name for CellMetadata
+func (CellMetadata) TableName() string {
+	return "cell_metadata"
+}
+
+// LoadCellMetadata retrieves the metadata of every cell of a canvas
+func (s *GormStore) LoadCellMetadata(canvas string) ([]CellMetadata, error) {
+	var metadata []CellMetadata
+	if err := s.db.Where("canvas = ?", canvas).Find(&metadata).Error; err != nil {
+		return nil, fmt.Errorf("failed to load cell metadata of canvas %s: %w", canvas, err)
+	}
+	return metadata, nil
+}
+
+// SaveCellMetadata inserts or replaces the metadata of a cell
+func (s *GormStore) SaveCellMetadata(metadata CellMetadata) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "canvas"}, {Name: "x"}, {Name: "y"}},
+		DoUpdates: clause.AssignmentColumns([]string{"text", "url", "actor", "updated_at"}),
+	}).Create(&metadata).Error
+}
+
+// DeleteCellMetadata removes the metadata of cells of a canvas
+func (s *GormStore) DeleteCellMetadata(canvas string, cells [][2]int) error {
+	if len(cells) == 0 {
+		return nil
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, cell := range cells {
+			if err := tx.Where("canvas = ? AND x = ? AND y = ?", canvas, cell[0], cell[1]).Delete(&CellMetadata{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
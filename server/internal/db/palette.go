@@ -0,0 +1,57 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+	"gorm.io/gorm"
+)
+
+// Palette is a stored version of the color palette
+type Palette struct {
+	Version int `gorm:"primaryKey;autoIncrement:false" json:"version"`
+
+	// Comma-separated "#RRGGBB" colors in display order
+	Colors    string    `gorm:"type:text;not null" json:"-"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
+
+// TableName specifies the table name for Palette
+func (Palette) TableName() string {
+	return "palettes"
+}
+
+// NewPalette builds the stored form of a palette version
+func NewPalette(version int, colors []model.Color) Palette {
+	hex := make([]string, len(colors))
+	for i, c := range colors {
+		hex[i] = c.String()
+	}
+	return Palette{Version: version, Colors: strings.Join(hex, ","), CreatedAt: time.Now()}
+}
+
+// ColorList parses the stored colors
+func (p Palette) ColorList() ([]model.Color, error) {
+	return model.ParsePalette(strings.Split(p.Colors, ","))
+}
+
+// LoadLatestPalette retrieves the newest palette version, returning nil if none has been stored
+func (s *GormStore) LoadLatestPalette() (*Palette, error) {
+	var palette Palette
+	err := s.db.Order("version DESC").First(&palette).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load palette: %w", err)
+	}
+	return &palette, nil
+}
+
+// SavePalette inserts a new palette version. It fails if the version already exists.
+func (s *GormStore) SavePalette(palette Palette) error {
+	return s.db.Create(&palette).Error
+}
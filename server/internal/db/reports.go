@@ -0,0 +1,137 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Statuses of a report
+const (
+	ReportOpen      = "open"
+	ReportResolved  = "resolved"
+	ReportDismissed = "dismissed"
+)
+
+// Report flags a cell or region of a canvas as offensive, for moderators to
+// review. Reports of the same region are merged while it is open.
+type Report struct {
+	ID     uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	Canvas string `gorm:"size:64;not null;index:idx_report_region,priority:1" json:"canvas"`
+
+	// Half-open bounds of the region
+	X1 int `gorm:"not null;index:idx_report_region,priority:2" json:"x1"`
+	Y1 int `gorm:"not null;index:idx_report_region,priority:3" json:"y1"`
+	X2 int `gorm:"not null" json:"x2"`
+	Y2 int `gorm:"not null" json:"y2"`
+
+	// Reason given by the first reporter
+	Reason string `gorm:"size:500;not null" json:"reason"`
+
+	// Number of distinct reporters
+	Reporters int `gorm:"not null;default:1" json:"reporters"`
+
+	Status string `gorm:"size:16;not null;index:idx_report_status" json:"status"`
+
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+
+	// Time of the latest report, or of the status change once closed
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+}
+
+// TableName specifies the table name for Report
+func (Report) TableName() string {
+	return "reports"
+}
+
+// ReportReporter is someone who filed a report, so reporting twice counts once
+type ReportReporter struct {
+	ReportID uint   `gorm:"primaryKey;autoIncrement:false"`
+	Reporter string `gorm:"primaryKey;size:64"`
+
+	Reason    string    `gorm:"size:500;not null"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+// TableName specifies the table name for ReportReporter
+func (ReportReporter) TableName() string {
+	return "report_reporters"
+}
+
+// FileReport records a report of its region by reporter. An open report of
+// the same region absorbs it, counting every reporter once; report is then
+// replaced with that report. It returns whether a new report was created.
+func (s *GormStore) FileReport(report *Report, reporter string) (bool, error) {
+	created := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing Report
+		err := tx.Where("canvas = ? AND x1 = ? AND y1 = ? AND x2 = ? AND y2 = ? AND status = ?",
+			report.Canvas, report.X1, report.Y1, report.X2, report.Y2, ReportOpen).
+			First(&existing).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			report.Status = ReportOpen
+			report.Reporters = 1
+			if err := tx.Create(report).Error; err != nil {
+				return err
+			}
+			created = true
+			return tx.Create(&ReportReporter{ReportID: report.ID, Reporter: reporter, Reason: report.Reason}).Error
+		case err != nil:
+			return err
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&ReportReporter{ReportID: existing.ID, Reporter: reporter, Reason: report.Reason})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			existing.Reporters++
+			if err := tx.Model(&existing).Updates(map[string]interface{}{"reporters": existing.Reporters, "updated_at": time.Now()}).Error; err != nil {
+				return err
+			}
+		}
+		*report = existing
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to file report: %w", err)
+	}
+	return created, nil
+}
+
+// LoadReports returns up to limit reports of a canvas with a status, the most
+// reported first and then the oldest
+func (s *GormStore) LoadReports(canvas, status string, limit int) ([]Report, error) {
+	var reports []Report
+	err := s.db.Where("canvas = ? AND status = ?", canvas, status).
+		Order("reporters DESC, id").
+		Limit(limit).
+		Find(&reports).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reports of canvas %s: %w", canvas, err)
+	}
+	return reports, nil
+}
+
+// LoadReport retrieves a report by ID, returning nil if there is none
+func (s *GormStore) LoadReport(id uint) (*Report, error) {
+	var report Report
+	err := s.db.First(&report, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load report %d: %w", id, err)
+	}
+	return &report, nil
+}
+
+// SetReportStatus changes the status of a report
+func (s *GormStore) SetReportStatus(id uint, status string) error {
+	return s.db.Model(&Report{ID: id}).Updates(map[string]interface{}{"status": status, "updated_at": time.Now()}).Error
+}
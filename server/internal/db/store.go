@@ -0,0 +1,576 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/million_grids/server/internal/config"
+	"github.com/million_grids/server/internal/crash"
+	"github.com/million_grids/server/internal/model"
+)
+
+// Store is a persistence backend for pixels, history and bans. Pixels and
+// history are partitioned by canvas name.
+type Store interface {
+	Ping(ctx context.Context) error
+	LoadAllPixels(canvas string) ([]model.Pixel, error)
+	SavePixels(pixels []model.Pixel) error
+	SaveHistory(records []PixelHistory) error
+	GetPixelHistory(canvas string, x, y, limit int) ([]PixelHistory, error)
+	RollbackStates(canvas string, actors []string, from, to time.Time) ([]model.Pixel, error)
+	ReplayHistory(canvas string, x1, y1, x2, y2 int, after uint64, to time.Time, fn func([]PixelHistory) error) error
+	LatestHistoryID(canvas string) (uint64, error)
+	HistoryCount(canvas string) (int64, error)
+	CanvasEvents(canvas string, after uint64, limit int) ([]CanvasEvent, error)
+	LatestPosition(canvas string) (StreamPosition, error)
+	ReplayStream(canvas string, x1, y1, x2, y2 int, from StreamPosition, to time.Time, fn func([]PixelHistory) error, reset func(CanvasEvent) error) error
+	RebuildPixels(canvas string) (int, error)
+	ReadStream(from StreamPosition, limit int) ([]StreamEntry, StreamPosition, error)
+	StreamHead() (StreamPosition, error)
+	HistoryIDBefore(t time.Time) (uint64, error)
+	LoadBans() ([]Ban, error)
+	SaveBan(ban Ban) error
+	DeleteBan(target string) error
+	LoadShadowBans() ([]ShadowBan, error)
+	SaveShadowBan(ban ShadowBan) error
+	DeleteShadowBan(target string) error
+
+	LoadAPIKeys() ([]APIKey, error)
+	SaveAPIKey(key *APIKey) error
+	RevokeAPIKey(id uint) (bool, error)
+
+	SaveLogin(user *User) error
+	LoadUser(id string) (*User, error)
+	LoadCanvas(name string) (*Canvas, error)
+	SaveCanvas(canvas Canvas) error
+	LoadLatestPalette() (*Palette, error)
+	SavePalette(palette Palette) error
+	AggregateContributions(limit int) (int, error)
+	TopContributors(canvas string, since time.Time, limit int) ([]Contributor, error)
+	ContributorHasher() (func(actor string) string, error)
+	EraseActors(actors []string) (ErasureResult, error)
+	LoadWebhooks() ([]Webhook, error)
+	SaveWebhook(hook *Webhook) error
+	DeleteWebhook(id uint) error
+	SaveChatMessage(msg ChatMessage) error
+	LoadChatMessages(canvas string, limit int) ([]ChatMessage, error)
+	PruneChatMessages(canvas string, keep int) (int64, error)
+	ResetCanvas(canvas, archive string) (int64, error)
+	ExpiredPixels(canvas string, before time.Time, limit int) ([]model.Pixel, error)
+	LoadLockedRegions(canvas string) ([]LockedRegion, error)
+	SaveLockedRegion(region *LockedRegion) error
+	DeleteLockedRegion(id uint) error
+	TopTeams(canvas string, since time.Time, limit int) ([]TeamScore, error)
+	LoadTeamPixels(canvas string) ([]model.Pixel, error)
+	LoadTemplates(canvas string) ([]Template, error)
+	SaveTemplate(template *Template) error
+	DeleteTemplate(id uint) error
+	LoadCellMetadata(canvas string) ([]CellMetadata, error)
+	SaveCellMetadata(metadata CellMetadata) error
+	DeleteCellMetadata(canvas string, cells [][2]int) error
+	LoadClaims(canvas string, now time.Time) ([]Claim, error)
+	SaveClaim(claim *Claim) error
+	DeleteClaim(id uint) error
+	SaveAuditEntries(entries []AuditEntry) error
+	QueryAuditLog(filter AuditFilter) ([]AuditEntry, error)
+	PruneAuditLog(before time.Time) (int64, error)
+	RegionHistory(canvas string, x1, y1, x2, y2 int, since time.Time, limit int) ([]PixelHistory, error)
+	FileReport(report *Report, reporter string) (bool, error)
+	LoadReports(canvas, status string, limit int) ([]Report, error)
+	LoadReport(id uint) (*Report, error)
+	SetReportStatus(id uint, status string) error
+}
+
+// store is the backend used by the package functions. It defaults to a no-op
+// store so the server can run without a database.
+var store Store = NopStore{}
+
+// InitDB connects to the configured database and runs migrations. With the
+// "none" driver it leaves persistence disabled.
+func InitDB(cfg config.DatabaseConfig) error {
+	if cfg.Driver == "none" {
+		slog.Warn("No database configured, pixels will not be persisted")
+		return nil
+	}
+
+	gormStore, err := OpenGormStore(cfg)
+	if err != nil {
+		return err
+	}
+	store = gormStore
+	slog.Info("Database connected and migrated successfully")
+	return nil
+}
+
+// StartWriteQueue writes the changes a crash or failed flush left in the
+// write-ahead log, then starts the write queue. After a handoff it must only
+// be called once the previous process has flushed its queue, as both would
+// otherwise use the same segments.
+func StartWriteQueue(cfg config.DatabaseConfig) error {
+	if cfg.Driver == "none" {
+		return nil
+	}
+
+	var wal *WAL
+	if cfg.WALDir != "" {
+		var err error
+		if wal, err = OpenWAL(cfg.WALDir); err != nil {
+			return err
+		}
+		recovery := NewWriteQueue(cfg.FlushInterval, cfg.FlushBatch, nil)
+		recovered, err := wal.Recover(func(pixels []model.Pixel, written bool) error {
+			if err := recovery.SavePixelsAsync(context.Background(), pixels); err != nil {
+				return err
+			}
+			if written {
+				// Only the pixel states need replaying, the history is already saved
+				recovery.history = nil
+			}
+			return recovery.Flush()
+		})
+		if err != nil {
+			return fmt.Errorf("failed to replay write-ahead log: %w", err)
+		}
+		if recovered > 0 {
+			slog.Warn("Recovered unsaved pixel changes from write-ahead log", "count", recovered)
+		}
+	}
+
+	// Start the write-behind queue for pixel saves. A batch lost to a panic
+	// stays in the write-ahead log, replayed on the next start.
+	queue = NewWriteQueue(cfg.FlushInterval, cfg.FlushBatch, wal)
+	go crash.Supervise("write queue", queue.Run)
+
+	return nil
+}
+
+// Ping checks that the store is reachable
+func Ping(ctx context.Context) error {
+	return store.Ping(ctx)
+}
+
+// LoadAllPixels retrieves all pixels of a canvas from the store
+func LoadAllPixels(canvas string) ([]model.Pixel, error) {
+	return store.LoadAllPixels(canvas)
+}
+
+// SavePixel saves or updates a pixel in the store
+func SavePixel(pixel model.Pixel) error {
+	return store.SavePixels([]model.Pixel{pixel})
+}
+
+// SavePixels upserts a batch of pixels
+func SavePixels(pixels []model.Pixel) error {
+	return store.SavePixels(pixels)
+}
+
+// SaveHistory appends a batch of history records
+func SaveHistory(records []PixelHistory) error {
+	return store.SaveHistory(records)
+}
+
+// GetPixelHistory returns the most recent changes to a cell of a canvas, newest first
+func GetPixelHistory(canvas string, x, y, limit int) ([]PixelHistory, error) {
+	return store.GetPixelHistory(canvas, x, y, limit)
+}
+
+// RollbackStates computes the states that revert the changes made by any of
+// actors on a canvas between from and to
+func RollbackStates(canvas string, actors []string, from, to time.Time) ([]model.Pixel, error) {
+	return store.RollbackStates(canvas, actors, from, to)
+}
+
+// ReplayHistory calls fn with the changes to the cells in [x1, x2) x [y1, y2) of
+// a canvas recorded after the record with ID after and up to to, oldest first
+func ReplayHistory(canvas string, x1, y1, x2, y2 int, after uint64, to time.Time, fn func([]PixelHistory) error) error {
+	return store.ReplayHistory(canvas, x1, y1, x2, y2, after, to, fn)
+}
+
+// LatestHistoryID returns the ID of the newest history record of a canvas
+func LatestHistoryID(canvas string) (uint64, error) {
+	return store.LatestHistoryID(canvas)
+}
+
+// HistoryCount returns the number of changes recorded on a canvas
+func HistoryCount(canvas string) (int64, error) {
+	return store.HistoryCount(canvas)
+}
+
+// CanvasEvents returns up to limit events of a canvas, including those
+// applying to every canvas, recorded after the event with ID after, oldest first
+func CanvasEvents(canvas string, after uint64, limit int) ([]CanvasEvent, error) {
+	return store.CanvasEvents(canvas, after, limit)
+}
+
+// LatestPosition returns the position of the end of the event stream of a canvas
+func LatestPosition(canvas string) (StreamPosition, error) {
+	return store.LatestPosition(canvas)
+}
+
+// ReplayStream replays the event stream of a canvas after a position up to to:
+// the changes to the cells in [x1, x2) x [y1, y2) and the resets between them
+func ReplayStream(canvas string, x1, y1, x2, y2 int, from StreamPosition, to time.Time, fn func([]PixelHistory) error, reset func(CanvasEvent) error) error {
+	return store.ReplayStream(canvas, x1, y1, x2, y2, from, to, fn, reset)
+}
+
+// RebuildPixels rebuilds the pixels of a canvas from its event stream
+func RebuildPixels(canvas string) (int, error) {
+	return store.RebuildPixels(canvas)
+}
+
+// ReadStream returns up to limit placements of every canvas recorded after a
+// position, with the canvas events between them, and the position after them
+func ReadStream(from StreamPosition, limit int) ([]StreamEntry, StreamPosition, error) {
+	return store.ReadStream(from, limit)
+}
+
+// StreamHead returns the position of the end of the event stream of every canvas
+func StreamHead() (StreamPosition, error) {
+	return store.StreamHead()
+}
+
+// HistoryIDBefore returns the ID of the newest history record made before a time
+func HistoryIDBefore(t time.Time) (uint64, error) {
+	return store.HistoryIDBefore(t)
+}
+
+// ContributorHasher returns the function hashing actors into leaderboard contributors
+func ContributorHasher() (func(actor string) string, error) {
+	return store.ContributorHasher()
+}
+
+// LoadBans retrieves all bans
+func LoadBans() ([]Ban, error) {
+	return store.LoadBans()
+}
+
+// SaveBan inserts or updates a ban
+func SaveBan(ban Ban) error {
+	return store.SaveBan(ban)
+}
+
+// DeleteBan removes a ban by target
+func DeleteBan(target string) error {
+	return store.DeleteBan(target)
+}
+
+// LoadShadowBans retrieves all shadow bans
+func LoadShadowBans() ([]ShadowBan, error) {
+	return store.LoadShadowBans()
+}
+
+// SaveShadowBan inserts or updates a shadow ban
+func SaveShadowBan(ban ShadowBan) error {
+	return store.SaveShadowBan(ban)
+}
+
+// DeleteShadowBan removes a shadow ban by target
+func DeleteShadowBan(target string) error {
+	return store.DeleteShadowBan(target)
+}
+
+// LoadAPIKeys retrieves every API key, revoked ones included
+func LoadAPIKeys() ([]APIKey, error) {
+	return store.LoadAPIKeys()
+}
+
+// SaveAPIKey inserts a new API key, setting its ID
+func SaveAPIKey(key *APIKey) error {
+	return store.SaveAPIKey(key)
+}
+
+// RevokeAPIKey marks an API key as revoked, reporting false if there is no
+// such key or it was already revoked
+func RevokeAPIKey(id uint) (bool, error) {
+	return store.RevokeAPIKey(id)
+}
+
+// LoadCanvas retrieves a canvas by name, returning nil if it has not been stored yet
+func LoadCanvas(name string) (*Canvas, error) {
+	return store.LoadCanvas(name)
+}
+
+// SaveCanvas inserts or updates a canvas
+func SaveCanvas(canvas Canvas) error {
+	return store.SaveCanvas(canvas)
+}
+
+// LoadLatestPalette retrieves the newest palette version, returning nil if none has been stored
+func LoadLatestPalette() (*Palette, error) {
+	return store.LoadLatestPalette()
+}
+
+// SavePalette inserts a new palette version
+func SavePalette(palette Palette) error {
+	return store.SavePalette(palette)
+}
+
+// AggregateContributions counts up to limit new history records into the
+// leaderboard and returns how many it counted
+func AggregateContributions(limit int) (int, error) {
+	return store.AggregateContributions(limit)
+}
+
+// TopContributors returns the contributors with the most placements on a canvas since since (zero for all time)
+func TopContributors(canvas string, since time.Time, limit int) ([]Contributor, error) {
+	return store.TopContributors(canvas, since, limit)
+}
+
+// EraseActors removes the attribution of every change made by the actors
+func EraseActors(actors []string) (ErasureResult, error) {
+	return store.EraseActors(actors)
+}
+
+// LoadWebhooks retrieves all webhooks
+func LoadWebhooks() ([]Webhook, error) {
+	return store.LoadWebhooks()
+}
+
+// SaveWebhook inserts a webhook, setting its ID
+func SaveWebhook(hook *Webhook) error {
+	return store.SaveWebhook(hook)
+}
+
+// DeleteWebhook removes a webhook by ID
+func DeleteWebhook(id uint) error {
+	return store.DeleteWebhook(id)
+}
+
+// SaveChatMessage appends a chat message
+func SaveChatMessage(msg ChatMessage) error {
+	return store.SaveChatMessage(msg)
+}
+
+// LoadChatMessages returns the latest chat messages of a canvas, oldest first
+func LoadChatMessages(canvas string, limit int) ([]ChatMessage, error) {
+	return store.LoadChatMessages(canvas, limit)
+}
+
+// PruneChatMessages deletes the chat messages of a canvas older than the latest keep
+func PruneChatMessages(canvas string, keep int) (int64, error) {
+	return store.PruneChatMessages(canvas, keep)
+}
+
+// ResetCanvas archives the active pixels of a canvas under the given name and
+// deletes all its pixels
+func ResetCanvas(canvas, archive string) (int64, error) {
+	return store.ResetCanvas(canvas, archive)
+}
+
+// ExpiredPixels returns up to limit active pixels of a canvas last changed before before
+func ExpiredPixels(canvas string, before time.Time, limit int) ([]model.Pixel, error) {
+	return store.ExpiredPixels(canvas, before, limit)
+}
+
+// LoadLockedRegions retrieves the locked regions of a canvas
+func LoadLockedRegions(canvas string) ([]LockedRegion, error) {
+	return store.LoadLockedRegions(canvas)
+}
+
+// SaveLockedRegion inserts a locked region, setting its ID
+func SaveLockedRegion(region *LockedRegion) error {
+	return store.SaveLockedRegion(region)
+}
+
+// DeleteLockedRegion removes a locked region by ID
+func DeleteLockedRegion(id uint) error {
+	return store.DeleteLockedRegion(id)
+}
+
+// TopTeams returns the teams with the most placements on a canvas since since (zero for all time)
+func TopTeams(canvas string, since time.Time, limit int) ([]TeamScore, error) {
+	return store.TopTeams(canvas, since, limit)
+}
+
+// LoadTeamPixels retrieves the active pixels of a canvas last changed by a team
+func LoadTeamPixels(canvas string) ([]model.Pixel, error) {
+	return store.LoadTeamPixels(canvas)
+}
+
+// LoadTemplates retrieves the templates of a canvas
+func LoadTemplates(canvas string) ([]Template, error) {
+	return store.LoadTemplates(canvas)
+}
+
+// SaveTemplate inserts a template, setting its ID
+func SaveTemplate(template *Template) error {
+	return store.SaveTemplate(template)
+}
+
+// DeleteTemplate removes a template by ID
+func DeleteTemplate(id uint) error {
+	return store.DeleteTemplate(id)
+}
+
+// LoadCellMetadata retrieves the metadata of every cell of a canvas
+func LoadCellMetadata(canvas string) ([]CellMetadata, error) {
+	return store.LoadCellMetadata(canvas)
+}
+
+// SaveCellMetadata inserts or replaces the metadata of a cell
+func SaveCellMetadata(metadata CellMetadata) error {
+	return store.SaveCellMetadata(metadata)
+}
+
+// DeleteCellMetadata removes the metadata of cells of a canvas
+func DeleteCellMetadata(canvas string, cells [][2]int) error {
+	return store.DeleteCellMetadata(canvas, cells)
+}
+
+// LoadClaims retrieves the claims of a canvas that haven't expired at now,
+// oldest first
+func LoadClaims(canvas string, now time.Time) ([]Claim, error) {
+	return store.LoadClaims(canvas, now)
+}
+
+// SaveClaim inserts a claim, setting its ID
+func SaveClaim(claim *Claim) error {
+	return store.SaveClaim(claim)
+}
+
+// DeleteClaim removes a claim by ID
+func DeleteClaim(id uint) error {
+	return store.DeleteClaim(id)
+}
+
+// SaveAuditEntries appends entries to the audit log
+func SaveAuditEntries(entries []AuditEntry) error {
+	return store.SaveAuditEntries(entries)
+}
+
+// QueryAuditLog returns the audit log entries matching filter, newest first
+func QueryAuditLog(filter AuditFilter) ([]AuditEntry, error) {
+	return store.QueryAuditLog(filter)
+}
+
+// PruneAuditLog deletes the audit log entries made before a time
+func PruneAuditLog(before time.Time) (int64, error) {
+	return store.PruneAuditLog(before)
+}
+
+// RegionHistory returns up to limit changes to the cells of a region of a
+// canvas made since a time, newest first
+func RegionHistory(canvas string, x1, y1, x2, y2 int, since time.Time, limit int) ([]PixelHistory, error) {
+	return store.RegionHistory(canvas, x1, y1, x2, y2, since, limit)
+}
+
+// FileReport records a report by reporter, merging it into an open report of
+// the same region, and returns whether a new report was created
+func FileReport(report *Report, reporter string) (bool, error) {
+	return store.FileReport(report, reporter)
+}
+
+// LoadReports returns up to limit reports of a canvas with a status, the most
+// reported first
+func LoadReports(canvas, status string, limit int) ([]Report, error) {
+	return store.LoadReports(canvas, status, limit)
+}
+
+// LoadReport retrieves a report by ID, returning nil if there is none
+func LoadReport(id uint) (*Report, error) {
+	return store.LoadReport(id)
+}
+
+// SetReportStatus changes the status of a report
+func SetReportStatus(id uint, status string) error {
+	return store.SetReportStatus(id, status)
+}
+
+// SaveLogin records a login of a user, creating its record on the first one
+func SaveLogin(user *User) error {
+	return store.SaveLogin(user)
+}
+
+// LoadUser retrieves a user by ID, returning nil if there is none
+func LoadUser(id string) (*User, error) {
+	return store.LoadUser(id)
+}
+
+// NopStore is a Store that persists nothing, for running without a database
+type NopStore struct{}
+
+func (NopStore) Ping(context.Context) error                                    { return nil }
+func (NopStore) LoadAllPixels(string) ([]model.Pixel, error)                   { return nil, nil }
+func (NopStore) SavePixels([]model.Pixel) error                                { return nil }
+func (NopStore) SaveHistory([]PixelHistory) error                              { return nil }
+func (NopStore) GetPixelHistory(string, int, int, int) ([]PixelHistory, error) { return nil, nil }
+func (NopStore) RollbackStates(string, []string, time.Time, time.Time) ([]model.Pixel, error) {
+	return nil, nil
+}
+func (NopStore) ReplayHistory(string, int, int, int, int, uint64, time.Time, func([]PixelHistory) error) error {
+	return nil
+}
+func (NopStore) LatestHistoryID(string) (uint64, error)                  { return 0, nil }
+func (NopStore) HistoryCount(string) (int64, error)                      { return 0, nil }
+func (NopStore) CanvasEvents(string, uint64, int) ([]CanvasEvent, error) { return nil, nil }
+func (NopStore) LatestPosition(string) (StreamPosition, error)           { return StreamPosition{}, nil }
+func (NopStore) ReplayStream(string, int, int, int, int, StreamPosition, time.Time, func([]PixelHistory) error, func(CanvasEvent) error) error {
+	return nil
+}
+func (NopStore) RebuildPixels(string) (int, error) { return 0, nil }
+func (NopStore) ReadStream(from StreamPosition, _ int) ([]StreamEntry, StreamPosition, error) {
+	return nil, from, nil
+}
+func (NopStore) StreamHead() (StreamPosition, error)       { return StreamPosition{}, nil }
+func (NopStore) HistoryIDBefore(time.Time) (uint64, error) { return 0, nil }
+func (NopStore) LoadBans() ([]Ban, error)                  { return nil, nil }
+func (NopStore) SaveBan(Ban) error                         { return nil }
+func (NopStore) DeleteBan(string) error                    { return nil }
+func (NopStore) LoadShadowBans() ([]ShadowBan, error)      { return nil, nil }
+func (NopStore) SaveShadowBan(ShadowBan) error             { return nil }
+func (NopStore) DeleteShadowBan(string) error              { return nil }
+func (NopStore) LoadAPIKeys() ([]APIKey, error)            { return nil, nil }
+func (NopStore) SaveAPIKey(*APIKey) error                  { return nil }
+func (NopStore) RevokeAPIKey(uint) (bool, error)           { return false, nil }
+func (NopStore) SaveLogin(*User) error                     { return nil }
+func (NopStore) LoadUser(string) (*User, error)            { return nil, nil }
+func (NopStore) LoadCanvas(string) (*Canvas, error)        { return nil, nil }
+func (NopStore) SaveCanvas(Canvas) error                   { return nil }
+func (NopStore) LoadLatestPalette() (*Palette, error)      { return nil, nil }
+func (NopStore) SavePalette(Palette) error                 { return nil }
+func (NopStore) AggregateContributions(int) (int, error)   { return 0, nil }
+func (NopStore) TopContributors(string, time.Time, int) ([]Contributor, error) {
+	return nil, nil
+}
+func (NopStore) ContributorHasher() (func(string) string, error) {
+	return func(actor string) string { return actor }, nil
+}
+func (NopStore) EraseActors([]string) (ErasureResult, error) { return ErasureResult{}, nil }
+func (NopStore) LoadWebhooks() ([]Webhook, error)            { return nil, nil }
+func (NopStore) SaveWebhook(*Webhook) error                  { return nil }
+func (NopStore) DeleteWebhook(uint) error                    { return nil }
+func (NopStore) SaveChatMessage(ChatMessage) error           { return nil }
+func (NopStore) LoadChatMessages(string, int) ([]ChatMessage, error) {
+	return nil, nil
+}
+func (NopStore) PruneChatMessages(string, int) (int64, error) { return 0, nil }
+func (NopStore) ResetCanvas(string, string) (int64, error)    { return 0, nil }
+func (NopStore) ExpiredPixels(string, time.Time, int) ([]model.Pixel, error) {
+	return nil, nil
+}
+func (NopStore) LoadLockedRegions(string) ([]LockedRegion, error)     { return nil, nil }
+func (NopStore) SaveLockedRegion(*LockedRegion) error                 { return nil }
+func (NopStore) DeleteLockedRegion(uint) error                        { return nil }
+func (NopStore) TopTeams(string, time.Time, int) ([]TeamScore, error) { return nil, nil }
+func (NopStore) LoadTeamPixels(string) ([]model.Pixel, error)         { return nil, nil }
+func (NopStore) LoadTemplates(string) ([]Template, error)             { return nil, nil }
+func (NopStore) SaveTemplate(*Template) error                         { return nil }
+func (NopStore) DeleteTemplate(uint) error                            { return nil }
+func (NopStore) LoadCellMetadata(string) ([]CellMetadata, error)      { return nil, nil }
+func (NopStore) SaveCellMetadata(CellMetadata) error                  { return nil }
+func (NopStore) DeleteCellMetadata(string, [][2]int) error            { return nil }
+func (NopStore) LoadClaims(string, time.Time) ([]Claim, error)        { return nil, nil }
+func (NopStore) SaveClaim(*Claim) error                               { return nil }
+func (NopStore) DeleteClaim(uint) error                               { return nil }
+func (NopStore) SaveAuditEntries([]AuditEntry) error                  { return nil }
+func (NopStore) QueryAuditLog(AuditFilter) ([]AuditEntry, error)      { return nil, nil }
+func (NopStore) PruneAuditLog(time.Time) (int64, error)               { return 0, nil }
+func (NopStore) RegionHistory(string, int, int, int, int, time.Time, int) ([]PixelHistory, error) {
+	return nil, nil
+}
+func (NopStore) FileReport(*Report, string) (bool, error)          { return false, nil }
+func (NopStore) LoadReports(string, string, int) ([]Report, error) { return nil, nil }
+func (NopStore) LoadReport(uint) (*Report, error)                  { return nil, nil }
+func (NopStore) SetReportStatus(uint, string) error                { return nil }
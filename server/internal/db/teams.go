@@ -0,0 +1,61 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// TeamStats counts the changes a team made to a canvas in one hour
+type TeamStats struct {
+	Canvas string `gorm:"size:64;primaryKey"`
+	Team   string `gorm:"size:32;primaryKey"`
+
+	// Start of the hour, in UTC
+	Hour time.Time `gorm:"primaryKey;index:idx_team_stats_hour"`
+
+	Placements int `gorm:"not null"`
+}
+
+// TableName specifies the table name for TeamStats
+func (TeamStats) TableName() string {
+	return "team_stats"
+}
+
+// TeamScore is a team leaderboard entry
+type TeamScore struct {
+	Team       string `json:"team"`
+	Placements int    `json:"placements"`
+}
+
+// TopTeams returns the teams with the most placements on a canvas since the
+// start of the hour of since (zero for all time), most first
+func (s *GormStore) TopTeams(canvas string, since time.Time, limit int) ([]TeamScore, error) {
+	query := s.db.Model(&TeamStats{}).Where("canvas = ?", canvas)
+	if !since.IsZero() {
+		query = query.Where("hour >= ?", since.UTC().Truncate(time.Hour))
+	}
+	var top []TeamScore
+	result := query.Select("team, SUM(placements) AS placements").
+		Group("team").
+		Order("placements DESC, team").
+		Limit(limit).
+		Scan(&top)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to load team leaderboard of canvas %s: %w", canvas, result.Error)
+	}
+	return top, nil
+}
+
+// LoadTeamPixels retrieves the active pixels of a canvas last changed by a team
+func (s *GormStore) LoadTeamPixels(canvas string) ([]model.Pixel, error) {
+	var pixels []model.Pixel
+	result := s.db.Select("x", "y", "active", "team").
+		Where("canvas = ? AND active = ? AND team <> ''", canvas, true).
+		Find(&pixels)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to load team pixels of canvas %s: %w", canvas, result.Error)
+	}
+	return pixels, nil
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Template is a target image mapped onto a canvas, as uploaded by a user
+type Template struct {
+	ID     uint   `gorm:"primaryKey;autoIncrement"`
+	Canvas string `gorm:"size:64;not null;index:idx_template_canvas"`
+	Name   string `gorm:"size:64;not null"`
+
+	// Cell the top-left corner of the image is mapped to
+	X int `gorm:"not null"`
+	Y int `gorm:"not null"`
+
+	// PNG of the target colors, transparent where the template sets no color
+	Image []byte `gorm:"not null"`
+
+	// User ID or IP of the uploader
+	CreatedBy string    `gorm:"size:64;null"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+// TableName specifies the table name for Template
+func (Template) TableName() string {
+	return "templates"
+}
+
+// LoadTemplates retrieves the templates of a canvas, oldest first
+func (s *GormStore) LoadTemplates(canvas string) ([]Template, error) {
+	var templates []Template
+	if err := s.db.Where("canvas = ?", canvas).Order("id").Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("failed to load templates of canvas %s: %w", canvas, err)
+	}
+	return templates, nil
+}
+
+// SaveTemplate inserts a template, setting its ID
+func (s *GormStore) SaveTemplate(template *Template) error {
+	return s.db.Create(template).Error
+}
+
+// DeleteTemplate removes a template by ID
+func (s *GormStore) DeleteTemplate(id uint) error {
+	return s.db.Delete(&Template{}, id).Error
+}
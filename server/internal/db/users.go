@@ -0,0 +1,51 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// User is an account logged in with an OAuth2 provider. Its ID, the provider
+// and the user's ID there, is what its pixels are attributed to.
+type User struct {
+	ID       string `gorm:"size:64;primaryKey" json:"id"`
+	Provider string `gorm:"size:16;not null" json:"provider"`
+	Name     string `gorm:"size:64" json:"name,omitempty"`
+
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	LastLogin time.Time `gorm:"not null" json:"last_login"`
+}
+
+// TableName specifies the table name for User
+func (User) TableName() string {
+	return "users"
+}
+
+// SaveLogin records a login of a user, creating its record on the first one,
+// and replaces user with the stored record
+func (s *GormStore) SaveLogin(user *User) error {
+	now := time.Now()
+	user.CreatedAt, user.LastLogin = now, now
+	err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "last_login"}),
+	}).Create(user).Error
+	if err != nil {
+		return fmt.Errorf("failed to save login of user %s: %w", user.ID, err)
+	}
+	return s.db.First(user, "id = ?", user.ID).Error
+}
+
+// LoadUser retrieves a user by ID, returning nil if there is none
+func (s *GormStore) LoadUser(id string) (*User, error) {
+	var users []User
+	if err := s.db.Where("id = ?", id).Limit(1).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to load user %s: %w", id, err)
+	}
+	if len(users) == 0 {
+		return nil, nil
+	}
+	return &users[0], nil
+}
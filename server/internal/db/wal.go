@@ -0,0 +1,268 @@
+package db
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// Prefix and extensions of write-ahead log segment files
+const (
+	walPrefix = "wal-"
+	walExt    = ".log"
+
+	// Segments written to the database but kept so they replay in order after
+	// an earlier failed one
+	walWrittenExt = ".written"
+)
+
+// walRecord is a pixel change as written to the write-ahead log, one JSON object per line
+type walRecord struct {
+	Canvas  string      `json:"canvas"`
+	X       int         `json:"x"`
+	Y       int         `json:"y"`
+	Active  bool        `json:"a"`
+	Color   model.Color `json:"color"`
+	Actor   string      `json:"actor,omitempty"`
+	Country string      `json:"country,omitempty"`
+	Region  string      `json:"region,omitempty"`
+	Team    string      `json:"team,omitempty"`
+	At      time.Time   `json:"at"`
+}
+
+// WAL is an append-only log of the pixel changes not yet written to the
+// database. The write queue appends and fsyncs changes before queueing them,
+// starts a new segment at every flush and deletes a segment once its flush
+// succeeded, so the segments left on disk hold exactly the changes a crash or
+// a failed flush would otherwise lose. After a failed flush, the later segments
+// changing the same cells are kept as well (marked written) so replaying them
+// restores the latest states of those cells.
+type WAL struct {
+	dir string
+
+	// Current segment and its sequence number
+	f   *os.File
+	seq int
+}
+
+// OpenWAL opens the write-ahead log in dir, starting a new segment after any
+// existing ones (which Recover replays)
+func OpenWAL(dir string) (*WAL, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create write-ahead log directory: %w", err)
+	}
+	segments, err := walSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &WAL{dir: dir}
+	if len(segments) > 0 {
+		w.seq = segments[len(segments)-1].seq
+	}
+	if err := w.openNext(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Append writes pixel changes to the current segment and syncs it to disk
+func (w *WAL) Append(pixels []model.Pixel) error {
+	var buf []byte
+	for _, p := range pixels {
+		rec := walRecord{Canvas: p.Canvas, X: p.X, Y: p.Y, Active: p.Active, Color: p.Color, Actor: p.ModifyBy,
+			Country: p.Country, Region: p.Region, Team: p.Team, At: time.Now()}
+		if p.ModifyAt != nil {
+			rec.At = *p.ModifyAt
+		}
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+	if _, err := w.f.Write(buf); err != nil {
+		return fmt.Errorf("failed to append to write-ahead log: %w", err)
+	}
+	if err := w.f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync write-ahead log: %w", err)
+	}
+	return nil
+}
+
+// Rotate closes the current segment and starts a new one, returning the
+// sequence number of the closed segment
+func (w *WAL) Rotate() (int, error) {
+	closed := w.seq
+	if err := w.openNext(); err != nil {
+		return 0, err
+	}
+	return closed, nil
+}
+
+// Remove deletes a closed segment whose changes have been written to the database
+func (w *WAL) Remove(seq int) error {
+	if err := os.Remove(w.path(seq)); err != nil {
+		return fmt.Errorf("failed to delete write-ahead log segment: %w", err)
+	}
+	return nil
+}
+
+// MarkWritten flags a closed segment as written to the database while keeping
+// it for replay, because an earlier segment failed
+func (w *WAL) MarkWritten(seq int) error {
+	if err := os.Rename(w.path(seq), w.writtenPath(seq)); err != nil {
+		return fmt.Errorf("failed to mark write-ahead log segment written: %w", err)
+	}
+	return nil
+}
+
+// Recover calls fn with the changes of each segment left by a previous run,
+// oldest first, and whether they were already written (so their history must
+// not be recorded again), deleting a segment once fn succeeds for it. Of a
+// segment marked written, only the changes to cells of an earlier unwritten
+// segment are replayed; its other cells are up to date in the database. It
+// returns the number of changes recovered. A torn last line, from a crash
+// mid-append, is skipped.
+func (w *WAL) Recover(fn func(pixels []model.Pixel, written bool) error) (int, error) {
+	segments, err := walSegments(w.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	recovered := 0
+	unwritten := make(map[cellKey]bool)
+	for _, seg := range segments {
+		if seg.seq >= w.seq {
+			break
+		}
+		path := w.path(seg.seq)
+		if seg.written {
+			path = w.writtenPath(seg.seq)
+		}
+		pixels, err := readWALSegment(path)
+		if err != nil {
+			return recovered, err
+		}
+		if seg.written {
+			pixels = slices.DeleteFunc(pixels, func(p model.Pixel) bool { return !unwritten[cellKey{p.Canvas, p.X, p.Y}] })
+		} else {
+			for _, p := range pixels {
+				unwritten[cellKey{p.Canvas, p.X, p.Y}] = true
+			}
+		}
+		if len(pixels) > 0 {
+			if err := fn(pixels, seg.written); err != nil {
+				return recovered, err
+			}
+		}
+		if err := os.Remove(path); err != nil {
+			return recovered, fmt.Errorf("failed to delete write-ahead log segment: %w", err)
+		}
+		recovered += len(pixels)
+	}
+	return recovered, nil
+}
+
+// openNext closes the current segment, if any, and creates the next one
+func (w *WAL) openNext() error {
+	f, err := os.OpenFile(w.path(w.seq+1), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create write-ahead log segment: %w", err)
+	}
+	if w.f != nil {
+		if err := w.f.Close(); err != nil {
+			slog.Warn("Failed to close write-ahead log segment", "err", err)
+		}
+	}
+	w.f = f
+	w.seq++
+	return nil
+}
+
+// readWALSegment loads the changes recorded in a segment file
+func readWALSegment(path string) ([]model.Pixel, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open write-ahead log segment: %w", err)
+	}
+	defer f.Close()
+
+	var pixels []model.Pixel
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec walRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			slog.Warn("Skipping unreadable write-ahead log record", "segment", f.Name(), "err", err)
+			continue
+		}
+		at := rec.At
+		pixels = append(pixels, model.Pixel{
+			Canvas:    rec.Canvas,
+			X:         rec.X,
+			Y:         rec.Y,
+			Active:    rec.Active,
+			Color:     rec.Color,
+			CreatedBy: rec.Actor,
+			ModifyAt:  &at,
+			ModifyBy:  rec.Actor,
+			Country:   rec.Country,
+			Region:    rec.Region,
+			Team:      rec.Team,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read write-ahead log segment %s: %w", f.Name(), err)
+	}
+	return pixels, nil
+}
+
+// path returns the file name of a segment
+func (w *WAL) path(seq int) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%s%016d%s", walPrefix, seq, walExt))
+}
+
+// writtenPath returns the file name of a segment marked written
+func (w *WAL) writtenPath(seq int) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%s%016d%s", walPrefix, seq, walWrittenExt))
+}
+
+// walSegment identifies a segment file
+type walSegment struct {
+	seq     int
+	written bool
+}
+
+// walSegments returns the segments in dir, oldest first
+func walSegments(dir string) ([]walSegment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list write-ahead log: %w", err)
+	}
+	var segments []walSegment
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), walPrefix)
+		if !ok {
+			continue
+		}
+		var seg walSegment
+		if trimmed, ok := strings.CutSuffix(name, walWrittenExt); ok {
+			name, seg.written = trimmed, true
+		} else if name, ok = strings.CutSuffix(name, walExt); !ok {
+			continue
+		}
+		if _, err := fmt.Sscanf(name, "%d", &seg.seq); err == nil {
+			segments = append(segments, seg)
+		}
+	}
+	slices.SortFunc(segments, func(a, b walSegment) int { return a.seq - b.seq })
+	return segments, nil
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+func TestWALRecover(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// Appended to the first segment after its changes, as a crash mid-append leaves it
+		torn     string
+		replayed []model.Color
+	}{
+		{"complete segments", "", []model.Color{1, 2, 3}},
+		{"torn mid-value", `{"canvas":"c","x":9,"y":9,"a":tr`, []model.Color{1, 2, 3}},
+		{"torn after a key", `{"canvas":"c","x":9`, []model.Color{1, 2, 3}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			wal, err := OpenWAL(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := wal.Append([]model.Pixel{{Canvas: "c", X: 1, Y: 1, Active: true, Color: 1}, {Canvas: "c", X: 2, Y: 2, Active: true, Color: 2}}); err != nil {
+				t.Fatal(err)
+			}
+			first, err := wal.Rotate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.torn != "" {
+				f, err := os.OpenFile(wal.path(first), os.O_APPEND|os.O_WRONLY, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				f.WriteString(tc.torn)
+				f.Close()
+			}
+			if err := wal.Append([]model.Pixel{{Canvas: "c", X: 3, Y: 3, Active: true, Color: 3}}); err != nil {
+				t.Fatal(err)
+			}
+
+			// The next start replays both segments, then deletes them
+			reopened, err := OpenWAL(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var replayed []model.Color
+			recovered, err := reopened.Recover(func(pixels []model.Pixel, written bool) error {
+				if written {
+					t.Error("segment replayed as written")
+				}
+				for _, p := range pixels {
+					replayed = append(replayed, p.Color)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(replayed, tc.replayed) || recovered != len(tc.replayed) {
+				t.Errorf("recovered %d changes with colors %v, want %v", recovered, replayed, tc.replayed)
+			}
+			if segments, _ := walSegments(dir); len(segments) != 1 {
+				t.Errorf("%d segments left after recovery, want only the new one", len(segments))
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Webhook is a URL registered to receive canvas events
+type Webhook struct {
+	ID  uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	URL string `gorm:"size:2048;not null" json:"url"`
+
+	// Key the payloads are signed with
+	Secret string `gorm:"size:128;not null" json:"-"`
+
+	// Comma-separated event types delivered ("pixel", "reset", "milestone")
+	Events string `gorm:"size:64;not null" json:"events"`
+
+	// Canvas the events come from (empty for every canvas)
+	Canvas string `gorm:"size:64;null" json:"canvas,omitempty"`
+
+	// Half-open region pixel events are limited to, when HasRegion is set
+	HasRegion bool `gorm:"not null;default:false" json:"-"`
+	X1        int  `gorm:"not null;default:0" json:"-"`
+	Y1        int  `gorm:"not null;default:0" json:"-"`
+	X2        int  `gorm:"not null;default:0" json:"-"`
+	Y2        int  `gorm:"not null;default:0" json:"-"`
+
+	// Maximum deliveries per minute, further events are dropped (0 for no limit)
+	MaxPerMinute int `gorm:"not null;default:0" json:"max_per_minute,omitempty"`
+
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
+
+// TableName specifies the table name for Webhook
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// LoadWebhooks retrieves all webhooks from the database
+func (s *GormStore) LoadWebhooks() ([]Webhook, error) {
+	var hooks []Webhook
+	if err := s.db.Order("id").Find(&hooks).Error; err != nil {
+		return nil, fmt.Errorf("failed to load webhooks: %w", err)
+	}
+	return hooks, nil
+}
+
+// SaveWebhook inserts a webhook, setting its ID
+func (s *GormStore) SaveWebhook(hook *Webhook) error {
+	return s.db.Create(hook).Error
+}
+
+// DeleteWebhook removes a webhook by ID
+func (s *GormStore) DeleteWebhook(id uint) error {
+	return s.db.Delete(&Webhook{}, id).Error
+}
@@ -0,0 +1,419 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/million_grids/server/internal/metrics"
+	"github.com/million_grids/server/internal/model"
+	"github.com/million_grids/server/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// cellKey identifies a pixel by its canvas and coordinates
+type cellKey struct {
+	Canvas string
+	X, Y   int
+}
+
+// WriteQueue is a write-behind buffer that coalesces pending pixel changes and
+// flushes them with a single multi-row UPSERT every interval or maxBatch pixels
+type WriteQueue struct {
+	// Latest pending state per cell
+	pending map[cellKey]model.Pixel
+
+	// Every change since the last flush, in order, for the history log
+	history []PixelHistory
+
+	// Traces of the changes since the last flush, linked from its span (at
+	// most maxFlushLinks)
+	links []trace.Link
+
+	mu sync.Mutex
+
+	// Serializes flushes between the worker and explicit Flush calls
+	flushMu sync.Mutex
+
+	// Signals the worker that the batch size was reached
+	flushNow chan struct{}
+
+	interval time.Duration
+	maxBatch int
+
+	// Consecutive failed flushes, and when the worker may retry (guarded by mu)
+	failures int
+	retryAt  time.Time
+
+	// Changes given up on after maxFlushAttempts, newest last, and their total
+	// count since startup (guarded by mu)
+	deadLetters   []DeadLetter
+	failedChanges int64
+
+	// Log of the changes not yet written (nil when disabled)
+	wal *WAL
+
+	// Segments of the failed flushes being retried (guarded by flushMu)
+	walFailed []int
+
+	// Cells of the changes given up on, whose segments are kept for replay on
+	// the next start along with the later ones changing the same cells, so
+	// the replay ends on their latest states (guarded by flushMu)
+	walRetained map[cellKey]bool
+}
+
+const (
+	// Flush attempts before the pending changes are moved to the dead-letter buffer
+	maxFlushAttempts = 8
+
+	// Upper bound of the delay between retries of a failed flush
+	maxRetryBackoff = time.Minute
+
+	// Changes kept in the dead-letter buffer; older batches are dropped first
+	maxDeadLetterChanges = 10000
+
+	// Backlog, in flush batches, beyond which the queue reports itself saturated
+	saturationBatches = 20
+
+	// Traces of queued changes a flush's span links to
+	maxFlushLinks = 128
+)
+
+// DeadLetter is a batch of changes that could not be written to the database
+type DeadLetter struct {
+	FailedAt time.Time `json:"failed_at"`
+	Error    string    `json:"error"`
+
+	// Changes per canvas, oldest first
+	Changes map[string][]PixelHistory `json:"changes"`
+}
+
+// WriteQueueStats describes the state of the write queue
+type WriteQueueStats struct {
+	// Pixels waiting to be written
+	Pending int `json:"pending"`
+
+	// Changes waiting to be written, and whether that is more than the queue
+	// can be expected to catch up with
+	Backlog   int  `json:"backlog"`
+	Saturated bool `json:"saturated"`
+
+	// Consecutive failed flushes (0 when writes are going through)
+	Failures int `json:"failures"`
+
+	// Changes permanently failed since startup
+	FailedChanges int64 `json:"failed_changes"`
+
+	// Changes currently held in the dead-letter buffer
+	DeadLetterChanges int `json:"dead_letter_changes"`
+}
+
+// queue is the write queue started by StartWriteQueue (nil when no database is configured)
+var queue *WriteQueue
+
+// NewWriteQueue creates a write queue flushing every interval or maxBatch
+// pixels. The write-ahead log may be nil.
+func NewWriteQueue(interval time.Duration, maxBatch int, wal *WAL) *WriteQueue {
+	if maxBatch < 1 {
+		maxBatch = 1
+	}
+	return &WriteQueue{
+		pending:  make(map[cellKey]model.Pixel),
+		flushNow: make(chan struct{}, 1),
+		interval: interval,
+		maxBatch: maxBatch,
+		wal:      wal,
+	}
+}
+
+// SavePixelsAsync schedules pixels to be written, replacing any pending writes
+// for the same cells. With a write-ahead log, the changes are appended to it
+// and synced to disk first, and if that fails nothing is queued and the error
+// is returned. The flush writing them is linked to the trace of ctx.
+func (q *WriteQueue) SavePixelsAsync(ctx context.Context, pixels []model.Pixel) error {
+	_, span := tracing.Tracer().Start(ctx, "db.enqueue",
+		trace.WithAttributes(attribute.Int("pixels", len(pixels)), attribute.Bool("wal", q.wal != nil)))
+	defer span.End()
+
+	q.mu.Lock()
+	if q.wal != nil {
+		if err := q.wal.Append(pixels); err != nil {
+			q.mu.Unlock()
+			span.SetStatus(codes.Error, err.Error())
+			metrics.DBWriteFailures.WithLabelValues("wal").Inc()
+			slog.Error("Failed to log pixel changes", "count", len(pixels), "err", err)
+			return err
+		}
+	}
+	for _, pixel := range pixels {
+		q.pending[cellKey{pixel.Canvas, pixel.X, pixel.Y}] = pixel
+		q.history = append(q.history, historyFromPixel(pixel))
+	}
+	if sc := span.SpanContext(); sc.IsSampled() && len(q.links) < maxFlushLinks {
+		q.links = append(q.links, trace.Link{SpanContext: sc})
+	}
+	full := len(q.history) >= q.maxBatch
+	q.mu.Unlock()
+
+	if full {
+		select {
+		case q.flushNow <- struct{}{}:
+		default:
+			// A flush is already scheduled
+		}
+	}
+	return nil
+}
+
+// Len returns the number of pixels waiting to be written
+func (q *WriteQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// Stats returns the current state of the queue
+func (q *WriteQueue) Stats() WriteQueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := WriteQueueStats{
+		Pending:       len(q.pending),
+		Backlog:       len(q.history),
+		Saturated:     len(q.history) >= saturationBatches*q.maxBatch,
+		Failures:      q.failures,
+		FailedChanges: q.failedChanges,
+	}
+	for _, dl := range q.deadLetters {
+		stats.DeadLetterChanges += dl.count()
+	}
+	return stats
+}
+
+// DeadLetters returns the batches of changes that could not be written, oldest first
+func (q *WriteQueue) DeadLetters() []DeadLetter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]DeadLetter(nil), q.deadLetters...)
+}
+
+// Run flushes the queue periodically or when the batch size is reached, backing
+// off exponentially while flushes fail
+func (q *WriteQueue) Run() {
+	ticker := time.NewTicker(q.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-q.flushNow:
+		}
+
+		q.mu.Lock()
+		backingOff := time.Now().Before(q.retryAt)
+		q.mu.Unlock()
+		if backingOff {
+			continue
+		}
+
+		if err := q.Flush(); err != nil {
+			slog.Error("Error flushing pixel writes", "err", err)
+		}
+	}
+}
+
+// Flush writes all pending pixels and history records to the database. On
+// failure the changes are put back to be retried by the next flush, until
+// maxFlushAttempts have failed and they are moved to the dead-letter buffer.
+func (q *WriteQueue) Flush() error {
+	q.flushMu.Lock()
+	defer q.flushMu.Unlock()
+
+	q.mu.Lock()
+	if len(q.pending) == 0 && len(q.history) == 0 {
+		q.mu.Unlock()
+		return nil
+	}
+	batch := make([]model.Pixel, 0, len(q.pending))
+	for _, p := range q.pending {
+		batch = append(batch, p)
+	}
+	history, links := q.history, q.links
+	q.pending = make(map[cellKey]model.Pixel)
+	q.history, q.links = nil, nil
+
+	// The batch is exactly the changes logged in the current segment (plus
+	// those of the failed segments being retried)
+	segment := 0
+	if q.wal != nil {
+		var err error
+		if segment, err = q.wal.Rotate(); err != nil {
+			slog.Error("Failed to rotate write-ahead log", "err", err)
+		}
+	}
+	q.mu.Unlock()
+
+	// The flush starts a trace of its own, linked to those of the changes
+	_, span := tracing.Tracer().Start(context.Background(), "db.flush", trace.WithLinks(links...),
+		trace.WithAttributes(attribute.Int("pixels", len(batch)), attribute.Int("history", len(history))))
+	defer span.End()
+
+	saved, err := q.write(batch, history)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		metrics.DBWriteFailures.WithLabelValues("flush").Inc()
+		q.retry(batch, history[saved:], segment, err)
+		return err
+	}
+
+	q.mu.Lock()
+	q.failures = 0
+	q.retryAt = time.Time{}
+	q.mu.Unlock()
+
+	return q.releaseSegments(segment, batch)
+}
+
+// retry puts the changes of a failed flush back under any newer ones, or moves
+// them to the dead-letter buffer once maxFlushAttempts have failed. Callers
+// must hold flushMu.
+func (q *WriteQueue) retry(batch []model.Pixel, history []PixelHistory, segment int, err error) {
+	if segment != 0 {
+		q.walFailed = append(q.walFailed, segment)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.failures++
+	if q.failures >= maxFlushAttempts {
+		q.addDeadLetter(history, err)
+		q.failures = 0
+		q.retryAt = time.Time{}
+
+		// Kept segments are written on the next start instead
+		if len(q.walFailed) > 0 {
+			if q.walRetained == nil {
+				q.walRetained = make(map[cellKey]bool)
+			}
+			for _, p := range batch {
+				q.walRetained[cellKey{p.Canvas, p.X, p.Y}] = true
+			}
+			q.walFailed = nil
+		}
+		return
+	}
+
+	for _, p := range batch {
+		key := cellKey{p.Canvas, p.X, p.Y}
+		if _, ok := q.pending[key]; !ok {
+			q.pending[key] = p
+		}
+	}
+	q.history = append(history, q.history...)
+
+	backoff := min(q.interval<<(q.failures-1), maxRetryBackoff)
+	q.retryAt = time.Now().Add(backoff)
+	slog.Warn("Retrying failed pixel writes", "attempt", q.failures, "changes", len(q.history), "backoff", backoff)
+}
+
+// addDeadLetter records changes that will not be retried, dropping the oldest
+// batches beyond maxDeadLetterChanges. Callers must hold mu.
+func (q *WriteQueue) addDeadLetter(history []PixelHistory, err error) {
+	dl := DeadLetter{FailedAt: time.Now(), Error: err.Error(), Changes: make(map[string][]PixelHistory)}
+	for _, rec := range history {
+		dl.Changes[rec.Canvas] = append(dl.Changes[rec.Canvas], rec)
+	}
+	q.deadLetters = append(q.deadLetters, dl)
+	q.failedChanges += int64(len(history))
+	metrics.DBChangesDropped.Add(float64(len(history)))
+	slog.Error("Giving up on pixel writes", "changes", len(history), "attempts", maxFlushAttempts, "err", err)
+
+	total := 0
+	for i := len(q.deadLetters) - 1; i >= 0; i-- {
+		total += q.deadLetters[i].count()
+		if total > maxDeadLetterChanges && i < len(q.deadLetters)-1 {
+			q.deadLetters = q.deadLetters[i+1:]
+			break
+		}
+	}
+}
+
+// releaseSegments deletes the write-ahead log segments of a successful flush
+// of batch, or marks them written when they change cells of earlier changes
+// kept for replay. Callers must hold flushMu.
+func (q *WriteQueue) releaseSegments(segment int, batch []model.Pixel) error {
+	if q.wal == nil {
+		return nil
+	}
+	segments := q.walFailed
+	if segment != 0 {
+		segments = append(segments, segment)
+	}
+	q.walFailed = nil
+
+	retained := false
+	for _, p := range batch {
+		if q.walRetained[cellKey{p.Canvas, p.X, p.Y}] {
+			retained = true
+			break
+		}
+	}
+	for _, seg := range segments {
+		var err error
+		if retained {
+			err = q.wal.MarkWritten(seg)
+		} else {
+			err = q.wal.Remove(seg)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write saves pixels and history records in batches of at most maxBatch rows.
+// It returns the number of history records saved, which on failure is the
+// prefix that doesn't need to be written again.
+func (q *WriteQueue) write(batch []model.Pixel, history []PixelHistory) (int, error) {
+	for start := 0; start < len(batch); start += q.maxBatch {
+		end := min(start+q.maxBatch, len(batch))
+		if err := SavePixels(batch[start:end]); err != nil {
+			return 0, fmt.Errorf("failed to save %d pixels: %w", end-start, err)
+		}
+	}
+	for start := 0; start < len(history); start += q.maxBatch {
+		end := min(start+q.maxBatch, len(history))
+		if err := SaveHistory(history[start:end]); err != nil {
+			return start, fmt.Errorf("failed to save %d history records: %w", end-start, err)
+		}
+	}
+	return len(history), nil
+}
+
+// count returns the number of changes in the batch
+func (dl DeadLetter) count() int {
+	n := 0
+	for _, changes := range dl.Changes {
+		n += len(changes)
+	}
+	return n
+}
+
+// Queue returns the write queue started by StartWriteQueue, or nil when no database is configured
+func Queue() *WriteQueue {
+	return queue
+}
+
+// FlushPending writes any pixels still waiting in the write queue
+func FlushPending() error {
+	if queue == nil {
+		return nil
+	}
+	return queue.Flush()
+}
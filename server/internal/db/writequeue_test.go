@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// failingStore fails pixel writes while failing is set
+type failingStore struct {
+	NopStore
+	failing bool
+}
+
+func (s *failingStore) SavePixels([]model.Pixel) error {
+	if s.failing {
+		return errors.New("database unavailable")
+	}
+	return nil
+}
+
+// useStore replaces the package store for the duration of a test
+func useStore(t *testing.T, s Store) {
+	t.Helper()
+	prev := store
+	store = s
+	t.Cleanup(func() { store = prev })
+}
+
+func TestWriteQueueRetainsOnlySegmentsOfDeadLetteredCells(t *testing.T) {
+	failing := &failingStore{failing: true}
+	useStore(t, failing)
+	dir := t.TempDir()
+	wal, err := OpenWAL(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := NewWriteQueue(0, 100, wal)
+
+	// Give up on a change to (1, 1)
+	q.SavePixelsAsync(context.Background(), []model.Pixel{{Canvas: "c", X: 1, Y: 1, Active: true, Color: 1}})
+	for range maxFlushAttempts {
+		if err := q.Flush(); err == nil {
+			t.Fatal("flush succeeded against a failing store")
+		}
+	}
+	failing.failing = false
+
+	// A later change to another cell no longer needs its segment, one to the
+	// same cell must be replayed after the given up one
+	q.SavePixelsAsync(context.Background(), []model.Pixel{{Canvas: "c", X: 2, Y: 2, Active: true, Color: 2}})
+	if err := q.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	q.SavePixelsAsync(context.Background(), []model.Pixel{{Canvas: "c", X: 1, Y: 1, Active: true, Color: 3}})
+	if err := q.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next start replays the given up change, then the later one to its cell
+	reopened, err := OpenWAL(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var replayed []model.Color
+	if _, err := reopened.Recover(func(pixels []model.Pixel, written bool) error {
+		for _, p := range pixels {
+			replayed = append(replayed, p.Color)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(replayed, []model.Color{1, 3}) {
+		t.Errorf("replayed colors %v, want [1 3]", replayed)
+	}
+}
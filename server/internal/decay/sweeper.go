@@ -0,0 +1,91 @@
+// Package decay clears cells nobody changed for a while, keeping the canvases
+// alive with fresh content
+package decay
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/model"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// Number of expired cells cleared per batch
+const sweepBatchSize = 1000
+
+// Sweeper periodically clears the cells of every canvas whose last change is
+// older than a given age. The cleared cells are broadcast like any change and
+// recorded in the history as changes by db.DecayActor.
+type Sweeper struct {
+	canvases *ws.Canvases
+	after    time.Duration
+	interval time.Duration
+}
+
+// NewSweeper creates a sweeper clearing cells unchanged for after, every interval
+func NewSweeper(canvases *ws.Canvases, after, interval time.Duration) *Sweeper {
+	return &Sweeper{canvases: canvases, after: after, interval: interval}
+}
+
+// Run sweeps every canvas every interval
+func (s *Sweeper) Run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.SweepAll()
+	}
+}
+
+// SweepAll clears the expired cells of every canvas that isn't frozen,
+// logging failures
+func (s *Sweeper) SweepAll() {
+	for _, hub := range s.canvases.Hubs() {
+		if frozen, _ := hub.ReadOnly(); frozen {
+			continue
+		}
+		cleared, err := s.Sweep(hub)
+		if err != nil {
+			slog.Error("Failed to sweep decayed cells", "canvas", hub.Canvas(), "err", err)
+		}
+		if cleared > 0 {
+			slog.Info("Cleared decayed cells", "canvas", hub.Canvas(), "cleared", cleared)
+		}
+	}
+}
+
+// Sweep clears the expired cells of a hub's canvas and returns how many it cleared
+func (s *Sweeper) Sweep(hub *ws.Hub) (int, error) {
+	cutoff := time.Now().Add(-s.after)
+	cleared := 0
+	for {
+		// Changes still in the write queue would otherwise look expired
+		if err := db.FlushPending(); err != nil {
+			return cleared, err
+		}
+		expired, err := db.ExpiredPixels(hub.Canvas(), cutoff, sweepBatchSize)
+		if err != nil {
+			return cleared, err
+		}
+
+		// Skip cells changed since their row was read
+		grid := hub.Grid()
+		pixels := make([]model.Pixel, 0, len(expired))
+		for _, p := range expired {
+			if grid.GetCell(p.X, p.Y) == (ws.CellState{Active: true, Color: p.Color}) {
+				pixels = append(pixels, model.Pixel{X: p.X, Y: p.Y, Active: false, Color: model.White})
+			}
+		}
+		changed, err := hub.SetCells(context.Background(), pixels, db.DecayActor)
+		if err != nil {
+			return cleared, err
+		}
+		cleared += len(changed)
+
+		if len(expired) < sweepBatchSize || len(changed) == 0 {
+			return cleared, nil
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/million_grids/server/internal/config"
+	"github.com/million_grids/server/internal/model"
+	"github.com/million_grids/server/internal/ws"
+)
+
+const (
+	// Largest width or height of a posted image; bigger grids are downsampled
+	maxImageDimension = 1024
+
+	// Largest scale small grids are magnified by
+	maxImageScale = 8
+
+	// Time allowed for each post to Discord
+	postTimeout = 30 * time.Second
+
+	// Milestones waiting to be posted, further ones are skipped
+	milestoneQueue = 8
+)
+
+// MessageData is the data message templates are executed with
+type MessageData struct {
+	Canvas        string
+	Width, Height int
+
+	// Active cells, placements made so far and connected clients of this instance
+	Active     int
+	Placements int64
+	Clients    int
+
+	// Placements reached (milestone messages only)
+	Milestone int64
+
+	Time time.Time
+}
+
+// Publisher posts a snapshot of a canvas to a Discord webhook periodically
+// and when it reaches placement milestones. It is a ws.EventSink for the
+// milestones; the other events are ignored.
+type Publisher struct {
+	url      string
+	canvases *ws.Canvases
+	canvas   string
+	interval time.Duration
+
+	// Message templates, milestone is nil when milestones aren't posted
+	message, milestone *template.Template
+
+	milestones chan int64
+	client     *http.Client
+}
+
+// NewPublisher creates a publisher for the named canvas of canvases, parsing
+// the message templates. The canvas may be added after the publisher is created.
+func NewPublisher(cfg config.DiscordConfig, canvases *ws.Canvases, canvas string) (*Publisher, error) {
+	p := &Publisher{
+		url:        cfg.WebhookURL,
+		canvases:   canvases,
+		canvas:     canvas,
+		interval:   cfg.Interval,
+		milestones: make(chan int64, milestoneQueue),
+		client:     &http.Client{Timeout: postTimeout},
+	}
+	var err error
+	if p.message, err = template.New("message").Parse(cfg.Message); err != nil {
+		return nil, fmt.Errorf("invalid discord message template: %w", err)
+	}
+	if cfg.OnMilestones {
+		if p.milestone, err = template.New("milestone_message").Parse(cfg.MilestoneMessage); err != nil {
+			return nil, fmt.Errorf("invalid discord milestone_message template: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// Run posts every interval (when positive) and at every milestone
+func (p *Publisher) Run() {
+	var tick <-chan time.Time
+	if p.interval > 0 {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-tick:
+			p.post(p.message, 0)
+		case milestone := <-p.milestones:
+			p.post(p.milestone, milestone)
+		}
+	}
+}
+
+func (p *Publisher) PixelsChanged(string, []model.Pixel) {}
+func (p *Publisher) CanvasReset(string)                  {}
+
+// Milestone queues a post for milestones of the publisher's canvas
+func (p *Publisher) Milestone(canvas string, placements int64) {
+	if p.milestone == nil || canvas != p.canvas {
+		return
+	}
+	select {
+	case p.milestones <- placements:
+	default:
+		slog.Warn("Skipping discord milestone post, too many pending", "canvas", canvas, "placements", placements)
+	}
+}
+
+// post renders the message and the snapshot and sends them, logging failures
+func (p *Publisher) post(tmpl *template.Template, milestone int64) {
+	hub, ok := p.canvases.Get(p.canvas)
+	if !ok {
+		slog.Error("Discord canvas not found", "canvas", p.canvas)
+		return
+	}
+	grid := hub.Grid()
+	data := MessageData{
+		Canvas:     hub.Canvas(),
+		Width:      grid.Width(),
+		Height:     grid.Height(),
+		Active:     grid.ActiveCount(),
+		Placements: hub.Placements(),
+		Clients:    hub.ClientCount(),
+		Milestone:  milestone,
+		Time:       time.Now(),
+	}
+	var content strings.Builder
+	if err := tmpl.Execute(&content, data); err != nil {
+		slog.Error("Failed to render discord message", "err", err)
+		return
+	}
+
+	var snapshot bytes.Buffer
+	if err := png.Encode(&snapshot, render(grid)); err != nil {
+		slog.Error("Failed to encode discord snapshot", "err", err)
+		return
+	}
+
+	if err := p.send(content.String(), snapshot.Bytes()); err != nil {
+		slog.Error("Failed to post to discord", "canvas", data.Canvas, "err", err)
+		return
+	}
+	slog.Info("Posted canvas to discord", "canvas", data.Canvas, "milestone", milestone)
+}
+
+// send posts a message with the snapshot attached, as a multipart webhook execution
+func (p *Publisher) send(content string, snapshot []byte) error {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	payload, _ := json.Marshal(map[string]string{"content": content})
+	if err := form.WriteField("payload_json", string(payload)); err != nil {
+		return err
+	}
+	file, err := form.CreateFormFile("files[0]", "canvas.png")
+	if err != nil {
+		return err
+	}
+	file.Write(snapshot)
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), postTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("discord responded %s: %s", resp.Status, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+// render draws the whole grid on a white image at most maxImageDimension
+// pixels wide and high. Small grids are magnified; on large ones each pixel
+// covers a square of cells and shows one of its active cells.
+func render(grid *ws.GridState) *image.RGBA {
+	side := max(grid.Width(), grid.Height())
+	scale, shift := 1, 0
+	if side <= maxImageDimension {
+		scale = min(maxImageScale, maxImageDimension/side)
+	} else {
+		for side>>shift > maxImageDimension {
+			shift++
+		}
+	}
+
+	cellsPerPixel := 1 << shift
+	width := (grid.Width() + cellsPerPixel - 1) >> shift * scale
+	height := (grid.Height() + cellsPerPixel - 1) >> shift * scale
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	// Inactive cells are white
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+
+	for _, cell := range grid.GetActiveCells() {
+		c := cell.Color.RGBA()
+		px := cell.X >> shift * scale
+		py := cell.Y >> shift * scale
+		for dx := 0; dx < scale; dx++ {
+			for dy := 0; dy < scale; dy++ {
+				img.SetRGBA(px+dx, py+dy, c)
+			}
+		}
+	}
+	return img
+}
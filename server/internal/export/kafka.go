@@ -0,0 +1,219 @@
+// Package export publishes the pixel events of every canvas to Kafka, for
+// analytics, anti-abuse models or replicas built outside the primary database.
+package export
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/million_grids/server/internal/config"
+	"github.com/million_grids/server/internal/model"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+)
+
+// SchemaVersion is the "v" of the exported events, incremented on
+// incompatible changes to Event
+const SchemaVersion = 1
+
+// Event types
+const (
+	EventPixel = "pixel"
+	EventReset = "reset"
+)
+
+const (
+	// Messages waiting to be written, further ones are dropped
+	queueSize = 16384
+
+	// Messages written per request, and the longest a message waits for it
+	batchSize     = 1000
+	flushInterval = 500 * time.Millisecond
+
+	// Time allowed for writing a batch, retries included
+	writeTimeout = 30 * time.Second
+)
+
+// Event is the JSON value of each exported message. Pixel events are keyed
+// "<canvas>/<x>/<y>", so the changes of a cell stay in order within its
+// partition; reset events, which clear a whole canvas, are keyed "<canvas>".
+type Event struct {
+	// SchemaVersion, EventPixel or EventReset, and the canvas changed
+	V      int    `json:"v"`
+	Type   string `json:"type"`
+	Canvas string `json:"canvas"`
+
+	// Time of the change
+	At time.Time `json:"at"`
+
+	// Cell changed and whether it is now active, with its "#RRGGBB" color
+	// when it is (pixel events)
+	X      int          `json:"x"`
+	Y      int          `json:"y"`
+	Active bool         `json:"active"`
+	Color  *model.Color `json:"color,omitempty"`
+
+	// Who made the change, as recorded in the history: a user ID, or the
+	// possibly hashed IP of an anonymous client
+	Actor string `json:"actor,omitempty"`
+
+	// Team the change counted for, and the location of the client
+	Team    string `json:"team,omitempty"`
+	Country string `json:"country,omitempty"`
+	Region  string `json:"region,omitempty"`
+}
+
+// Kafka writes pixel events to a Kafka topic in the background. It is a
+// ws.EventSink of every hub.
+type Kafka struct {
+	writer   *kafka.Writer
+	messages chan kafka.Message
+
+	// Messages dropped because the queue was full
+	dropped atomic.Int64
+
+	// Closed to stop Run, which closes finished once the queue is written
+	done     chan struct{}
+	finished chan struct{}
+}
+
+// NewKafka creates a Kafka exporter writing to the configured brokers and topic
+func NewKafka(cfg config.KafkaConfig) (*Kafka, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errors.New("no kafka brokers configured")
+	}
+	transport := &kafka.Transport{ClientID: "million_grids"}
+	if cfg.TLS {
+		transport.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if cfg.Username != "" {
+		transport.SASL = plain.Mechanism{Username: cfg.Username, Password: cfg.Password}
+	}
+	return &Kafka{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(cfg.Brokers...),
+			Topic:        cfg.Topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			// Batches are already formed by Run
+			BatchSize:    batchSize,
+			BatchTimeout: 10 * time.Millisecond,
+			Transport:    transport,
+		},
+		messages: make(chan kafka.Message, queueSize),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}, nil
+}
+
+// Run writes the queued messages in batches until Close is called
+func (k *Kafka) Run() {
+	defer close(k.finished)
+	flush := time.NewTicker(flushInterval)
+	defer flush.Stop()
+
+	batch := make([]kafka.Message, 0, batchSize)
+	for {
+		select {
+		case msg := <-k.messages:
+			if batch = append(batch, msg); len(batch) >= batchSize {
+				batch = k.write(batch)
+			}
+		case <-flush.C:
+			batch = k.write(batch)
+		case <-k.done:
+			// Write what is still queued before stopping
+			for {
+				select {
+				case msg := <-k.messages:
+					if batch = append(batch, msg); len(batch) >= batchSize {
+						batch = k.write(batch)
+					}
+				default:
+					k.write(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// Close writes the queued messages and closes the connections to the brokers
+func (k *Kafka) Close() error {
+	close(k.done)
+	<-k.finished
+	return k.writer.Close()
+}
+
+// write sends a batch of messages, returning it emptied
+func (k *Kafka) write(batch []kafka.Message) []kafka.Message {
+	if dropped := k.dropped.Swap(0); dropped > 0 {
+		slog.Warn("Dropped kafka events", "dropped", dropped)
+	}
+	if len(batch) == 0 {
+		return batch
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+	if err := k.writer.WriteMessages(ctx, batch...); err != nil {
+		slog.Error("Failed to export events to kafka", "events", len(batch), "err", err)
+	}
+	return batch[:0]
+}
+
+// enqueue queues an event for writing. It never blocks: events are dropped
+// while the queue is full.
+func (k *Kafka) enqueue(key string, event Event) {
+	value, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("Failed to marshal kafka event", "err", err)
+		return
+	}
+	select {
+	case k.messages <- kafka.Message{Key: []byte(key), Value: value, Time: event.At}:
+	default:
+		if k.dropped.Add(1) == 1 {
+			slog.Warn("Kafka export queue is full, dropping events")
+		}
+	}
+}
+
+// PixelsChanged exports a pixel event per changed cell
+func (k *Kafka) PixelsChanged(canvas string, changed []model.Pixel) {
+	for _, p := range changed {
+		event := Event{
+			V:       SchemaVersion,
+			Type:    EventPixel,
+			Canvas:  canvas,
+			At:      time.Now(),
+			X:       p.X,
+			Y:       p.Y,
+			Active:  p.Active,
+			Actor:   p.ModifyBy,
+			Team:    p.Team,
+			Country: p.Country,
+			Region:  p.Region,
+		}
+		if p.ModifyAt != nil {
+			event.At = *p.ModifyAt
+		}
+		if p.Active {
+			color := p.Color
+			event.Color = &color
+		}
+		k.enqueue(canvas+"/"+strconv.Itoa(p.X)+"/"+strconv.Itoa(p.Y), event)
+	}
+}
+
+// CanvasReset exports a reset event
+func (k *Kafka) CanvasReset(canvas string) {
+	k.enqueue(canvas, Event{V: SchemaVersion, Type: EventReset, Canvas: canvas, At: time.Now()})
+}
+
+func (k *Kafka) Milestone(string, int64) {}
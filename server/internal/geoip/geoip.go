@@ -0,0 +1,63 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// Location is where a client connects from, coarse enough not to identify it
+type Location struct {
+	// ISO 3166-1 country code, e.g. "US" (empty when unknown)
+	Country string
+
+	// ISO 3166-2 subdivision code within the country, e.g. "CA" (empty when
+	// unknown or when the database has no subdivisions)
+	Region string
+}
+
+// Locator looks up client locations in a MaxMind GeoIP2 or GeoLite2 database
+// (Country or City edition). A nil Locator knows no locations.
+type Locator struct {
+	reader *geoip2.Reader
+}
+
+// Open loads the database file at path
+func Open(path string) (*Locator, error) {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	return &Locator{reader: reader}, nil
+}
+
+// Lookup returns the location of an IP, or the zero Location if it is unknown
+func (l *Locator) Lookup(ip string) Location {
+	if l == nil {
+		return Location{}
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return Location{}
+	}
+
+	// Country editions can be read as City ones, without subdivisions
+	city, err := l.reader.City(addr)
+	if err != nil {
+		return Location{}
+	}
+	loc := Location{Country: city.Country.IsoCode}
+	if len(city.Subdivisions) > 0 {
+		loc.Region = city.Subdivisions[0].IsoCode
+	}
+	return loc
+}
+
+// Close releases the database
+func (l *Locator) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.reader.Close()
+}
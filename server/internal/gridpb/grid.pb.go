@@ -0,0 +1,4172 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: million_grids/v1/grid.proto
+
+// Wire protocol of /ws for clients that negotiate the
+// "million-grids.v1.protobuf" subprotocol. Every frame is a binary WebSocket
+// message holding exactly one ClientMessage (client to server) or
+// ServerMessage (server to client). The messages mirror the JSON protocol;
+// colors are 0xRRGGBB integers.
+
+package gridpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ClientMessage is a frame sent by the client
+type ClientMessage struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Msg:
+	//	*ClientMessage_Toggle
+	//	*ClientMessage_Set
+	//	*ClientMessage_Clear
+	//	*ClientMessage_Paint
+	//	*ClientMessage_Subscribe
+	//	*ClientMessage_Unsubscribe
+	//	*ClientMessage_Cursor
+	//	*ClientMessage_Chat
+	//	*ClientMessage_JoinChannel
+	//	*ClientMessage_LeaveChannel
+	//	*ClientMessage_Team
+	//	*ClientMessage_Undo
+	//	*ClientMessage_Captcha
+	Msg isClientMessage_Msg `protobuf_oneof:"msg"`
+}
+
+func (x *ClientMessage) Reset() {
+	*x = ClientMessage{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClientMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientMessage) ProtoMessage() {}
+
+func (x *ClientMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientMessage.ProtoReflect.Descriptor instead.
+func (*ClientMessage) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{0}
+}
+
+func (m *ClientMessage) GetMsg() isClientMessage_Msg {
+	if m != nil {
+		return m.Msg
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetToggle() *CellOp {
+	if x, ok := x.GetMsg().(*ClientMessage_Toggle); ok {
+		return x.Toggle
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetSet() *CellOp {
+	if x, ok := x.GetMsg().(*ClientMessage_Set); ok {
+		return x.Set
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetClear() *CellOp {
+	if x, ok := x.GetMsg().(*ClientMessage_Clear); ok {
+		return x.Clear
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetPaint() *Paint {
+	if x, ok := x.GetMsg().(*ClientMessage_Paint); ok {
+		return x.Paint
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetSubscribe() *Region {
+	if x, ok := x.GetMsg().(*ClientMessage_Subscribe); ok {
+		return x.Subscribe
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetUnsubscribe() *Unsubscribe {
+	if x, ok := x.GetMsg().(*ClientMessage_Unsubscribe); ok {
+		return x.Unsubscribe
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetCursor() *Cursor {
+	if x, ok := x.GetMsg().(*ClientMessage_Cursor); ok {
+		return x.Cursor
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetChat() *ChatPost {
+	if x, ok := x.GetMsg().(*ClientMessage_Chat); ok {
+		return x.Chat
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetJoinChannel() *Channel {
+	if x, ok := x.GetMsg().(*ClientMessage_JoinChannel); ok {
+		return x.JoinChannel
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetLeaveChannel() *Channel {
+	if x, ok := x.GetMsg().(*ClientMessage_LeaveChannel); ok {
+		return x.LeaveChannel
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetTeam() *JoinTeam {
+	if x, ok := x.GetMsg().(*ClientMessage_Team); ok {
+		return x.Team
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetUndo() *Undo {
+	if x, ok := x.GetMsg().(*ClientMessage_Undo); ok {
+		return x.Undo
+	}
+	return nil
+}
+
+func (x *ClientMessage) GetCaptcha() *CaptchaSolution {
+	if x, ok := x.GetMsg().(*ClientMessage_Captcha); ok {
+		return x.Captcha
+	}
+	return nil
+}
+
+type isClientMessage_Msg interface {
+	isClientMessage_Msg()
+}
+
+type ClientMessage_Toggle struct {
+	Toggle *CellOp `protobuf:"bytes,1,opt,name=toggle,proto3,oneof"` // Flip a cell
+}
+
+type ClientMessage_Set struct {
+	Set *CellOp `protobuf:"bytes,2,opt,name=set,proto3,oneof"` // Activate a cell with a color
+}
+
+type ClientMessage_Clear struct {
+	Clear *CellOp `protobuf:"bytes,3,opt,name=clear,proto3,oneof"` // Deactivate a cell
+}
+
+type ClientMessage_Paint struct {
+	Paint *Paint `protobuf:"bytes,4,opt,name=paint,proto3,oneof"` // Activate a batch of cells atomically
+}
+
+type ClientMessage_Subscribe struct {
+	Subscribe *Region `protobuf:"bytes,5,opt,name=subscribe,proto3,oneof"`
+}
+
+type ClientMessage_Unsubscribe struct {
+	Unsubscribe *Unsubscribe `protobuf:"bytes,6,opt,name=unsubscribe,proto3,oneof"`
+}
+
+type ClientMessage_Cursor struct {
+	Cursor *Cursor `protobuf:"bytes,7,opt,name=cursor,proto3,oneof"` // Share the pointer position with other clients
+}
+
+type ClientMessage_Chat struct {
+	Chat *ChatPost `protobuf:"bytes,8,opt,name=chat,proto3,oneof"` // Post a chat message to the canvas or a channel
+}
+
+type ClientMessage_JoinChannel struct {
+	JoinChannel *Channel `protobuf:"bytes,9,opt,name=join_channel,json=joinChannel,proto3,oneof"`
+}
+
+type ClientMessage_LeaveChannel struct {
+	LeaveChannel *Channel `protobuf:"bytes,10,opt,name=leave_channel,json=leaveChannel,proto3,oneof"`
+}
+
+type ClientMessage_Team struct {
+	Team *JoinTeam `protobuf:"bytes,11,opt,name=team,proto3,oneof"` // Paint for a team, or for none
+}
+
+type ClientMessage_Undo struct {
+	Undo *Undo `protobuf:"bytes,12,opt,name=undo,proto3,oneof"` // Revert the most recent placement
+}
+
+type ClientMessage_Captcha struct {
+	Captcha *CaptchaSolution `protobuf:"bytes,13,opt,name=captcha,proto3,oneof"`
+}
+
+func (*ClientMessage_Toggle) isClientMessage_Msg() {}
+
+func (*ClientMessage_Set) isClientMessage_Msg() {}
+
+func (*ClientMessage_Clear) isClientMessage_Msg() {}
+
+func (*ClientMessage_Paint) isClientMessage_Msg() {}
+
+func (*ClientMessage_Subscribe) isClientMessage_Msg() {}
+
+func (*ClientMessage_Unsubscribe) isClientMessage_Msg() {}
+
+func (*ClientMessage_Cursor) isClientMessage_Msg() {}
+
+func (*ClientMessage_Chat) isClientMessage_Msg() {}
+
+func (*ClientMessage_JoinChannel) isClientMessage_Msg() {}
+
+func (*ClientMessage_LeaveChannel) isClientMessage_Msg() {}
+
+func (*ClientMessage_Team) isClientMessage_Msg() {}
+
+func (*ClientMessage_Undo) isClientMessage_Msg() {}
+
+func (*ClientMessage_Captcha) isClientMessage_Msg() {}
+
+// CellOp targets a single cell
+type CellOp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	X uint32 `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y uint32 `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+	// Defaults to the first palette color; ignored by clear
+	Color *uint32 `protobuf:"varint,3,opt,name=color,proto3,oneof" json:"color,omitempty"`
+}
+
+func (x *CellOp) Reset() {
+	*x = CellOp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CellOp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CellOp) ProtoMessage() {}
+
+func (x *CellOp) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CellOp.ProtoReflect.Descriptor instead.
+func (*CellOp) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CellOp) GetX() uint32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *CellOp) GetY() uint32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *CellOp) GetColor() uint32 {
+	if x != nil && x.Color != nil {
+		return *x.Color
+	}
+	return 0
+}
+
+// Paint activates up to 256 cells as a single placement
+type Paint struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Cells []*Cell `protobuf:"bytes,1,rep,name=cells,proto3" json:"cells,omitempty"`
+}
+
+func (x *Paint) Reset() {
+	*x = Paint{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Paint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Paint) ProtoMessage() {}
+
+func (x *Paint) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Paint.ProtoReflect.Descriptor instead.
+func (*Paint) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Paint) GetCells() []*Cell {
+	if x != nil {
+		return x.Cells
+	}
+	return nil
+}
+
+// Cursor is the cell under the client's pointer
+type Cursor struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	X uint32 `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y uint32 `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+}
+
+func (x *Cursor) Reset() {
+	*x = Cursor{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Cursor) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cursor) ProtoMessage() {}
+
+func (x *Cursor) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cursor.ProtoReflect.Descriptor instead.
+func (*Cursor) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Cursor) GetX() uint32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *Cursor) GetY() uint32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+// ChatPost is a chat message sent by the client
+type ChatPost struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Text string `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	// Channel to post to, empty for the canvas-wide chat
+	Channel string `protobuf:"bytes,2,opt,name=channel,proto3" json:"channel,omitempty"`
+}
+
+func (x *ChatPost) Reset() {
+	*x = ChatPost{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ChatPost) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatPost) ProtoMessage() {}
+
+func (x *ChatPost) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatPost.ProtoReflect.Descriptor instead.
+func (*ChatPost) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ChatPost) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *ChatPost) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+// Channel names a chat channel to join or leave
+type Channel struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (x *Channel) Reset() {
+	*x = Channel{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Channel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Channel) ProtoMessage() {}
+
+func (x *Channel) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Channel.ProtoReflect.Descriptor instead.
+func (*Channel) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Channel) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// JoinTeam switches the team the client's placements count for (empty for none)
+type JoinTeam struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (x *JoinTeam) Reset() {
+	*x = JoinTeam{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *JoinTeam) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JoinTeam) ProtoMessage() {}
+
+func (x *JoinTeam) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JoinTeam.ProtoReflect.Descriptor instead.
+func (*JoinTeam) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *JoinTeam) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// Unsubscribe receives updates for the whole grid again
+type Unsubscribe struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *Unsubscribe) Reset() {
+	*x = Unsubscribe{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Unsubscribe) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Unsubscribe) ProtoMessage() {}
+
+func (x *Unsubscribe) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Unsubscribe.ProtoReflect.Descriptor instead.
+func (*Unsubscribe) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{7}
+}
+
+// Undo reverts the client's most recent placement that still stands
+type Undo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *Undo) Reset() {
+	*x = Undo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Undo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Undo) ProtoMessage() {}
+
+func (x *Undo) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Undo.ProtoReflect.Descriptor instead.
+func (*Undo) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{8}
+}
+
+// CaptchaSolution presents a solved CAPTCHA token, when the server challenges
+// unauthenticated clients
+type CaptchaSolution struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+}
+
+func (x *CaptchaSolution) Reset() {
+	*x = CaptchaSolution{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CaptchaSolution) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CaptchaSolution) ProtoMessage() {}
+
+func (x *CaptchaSolution) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CaptchaSolution.ProtoReflect.Descriptor instead.
+func (*CaptchaSolution) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CaptchaSolution) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+// Region is a half-open rectangle of cells [x1, x2) x [y1, y2)
+type Region struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	X1 uint32 `protobuf:"varint,1,opt,name=x1,proto3" json:"x1,omitempty"`
+	Y1 uint32 `protobuf:"varint,2,opt,name=y1,proto3" json:"y1,omitempty"`
+	X2 uint32 `protobuf:"varint,3,opt,name=x2,proto3" json:"x2,omitempty"`
+	Y2 uint32 `protobuf:"varint,4,opt,name=y2,proto3" json:"y2,omitempty"`
+}
+
+func (x *Region) Reset() {
+	*x = Region{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Region) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Region) ProtoMessage() {}
+
+func (x *Region) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Region.ProtoReflect.Descriptor instead.
+func (*Region) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Region) GetX1() uint32 {
+	if x != nil {
+		return x.X1
+	}
+	return 0
+}
+
+func (x *Region) GetY1() uint32 {
+	if x != nil {
+		return x.Y1
+	}
+	return 0
+}
+
+func (x *Region) GetX2() uint32 {
+	if x != nil {
+		return x.X2
+	}
+	return 0
+}
+
+func (x *Region) GetY2() uint32 {
+	if x != nil {
+		return x.Y2
+	}
+	return 0
+}
+
+// Cell is an active cell and its color
+type Cell struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	X     uint32 `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y     uint32 `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+	Color uint32 `protobuf:"varint,3,opt,name=color,proto3" json:"color,omitempty"`
+}
+
+func (x *Cell) Reset() {
+	*x = Cell{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Cell) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cell) ProtoMessage() {}
+
+func (x *Cell) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cell.ProtoReflect.Descriptor instead.
+func (*Cell) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Cell) GetX() uint32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *Cell) GetY() uint32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *Cell) GetColor() uint32 {
+	if x != nil {
+		return x.Color
+	}
+	return 0
+}
+
+// ServerMessage is a frame sent by the server
+type ServerMessage struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Msg:
+	//	*ServerMessage_Init
+	//	*ServerMessage_InitChunk
+	//	*ServerMessage_InitDone
+	//	*ServerMessage_Update
+	//	*ServerMessage_Batch
+	//	*ServerMessage_ClientCount
+	//	*ServerMessage_Palette
+	//	*ServerMessage_Error
+	//	*ServerMessage_Cooldown
+	//	*ServerMessage_Region
+	//	*ServerMessage_Roster
+	//	*ServerMessage_Join
+	//	*ServerMessage_Leave
+	//	*ServerMessage_Cursor
+	//	*ServerMessage_Chat
+	//	*ServerMessage_ChatHistory
+	//	*ServerMessage_Announce
+	//	*ServerMessage_Frozen
+	//	*ServerMessage_Reset_
+	//	*ServerMessage_Protected
+	//	*ServerMessage_Locks
+	//	*ServerMessage_Team
+	//	*ServerMessage_Teams
+	//	*ServerMessage_Meta
+	//	*ServerMessage_Claims
+	//	*ServerMessage_Captcha
+	//	*ServerMessage_Tier
+	Msg isServerMessage_Msg `protobuf_oneof:"msg"`
+}
+
+func (x *ServerMessage) Reset() {
+	*x = ServerMessage{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ServerMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServerMessage) ProtoMessage() {}
+
+func (x *ServerMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServerMessage.ProtoReflect.Descriptor instead.
+func (*ServerMessage) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{12}
+}
+
+func (m *ServerMessage) GetMsg() isServerMessage_Msg {
+	if m != nil {
+		return m.Msg
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetInit() *Init {
+	if x, ok := x.GetMsg().(*ServerMessage_Init); ok {
+		return x.Init
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetInitChunk() *InitChunk {
+	if x, ok := x.GetMsg().(*ServerMessage_InitChunk); ok {
+		return x.InitChunk
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetInitDone() *InitDone {
+	if x, ok := x.GetMsg().(*ServerMessage_InitDone); ok {
+		return x.InitDone
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetUpdate() *CellUpdate {
+	if x, ok := x.GetMsg().(*ServerMessage_Update); ok {
+		return x.Update
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetBatch() *BatchUpdate {
+	if x, ok := x.GetMsg().(*ServerMessage_Batch); ok {
+		return x.Batch
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetClientCount() *ClientCount {
+	if x, ok := x.GetMsg().(*ServerMessage_ClientCount); ok {
+		return x.ClientCount
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetPalette() *Palette {
+	if x, ok := x.GetMsg().(*ServerMessage_Palette); ok {
+		return x.Palette
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetError() *Error {
+	if x, ok := x.GetMsg().(*ServerMessage_Error); ok {
+		return x.Error
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetCooldown() *Cooldown {
+	if x, ok := x.GetMsg().(*ServerMessage_Cooldown); ok {
+		return x.Cooldown
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetRegion() *RegionState {
+	if x, ok := x.GetMsg().(*ServerMessage_Region); ok {
+		return x.Region
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetRoster() *Roster {
+	if x, ok := x.GetMsg().(*ServerMessage_Roster); ok {
+		return x.Roster
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetJoin() *Presence {
+	if x, ok := x.GetMsg().(*ServerMessage_Join); ok {
+		return x.Join
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetLeave() *Leave {
+	if x, ok := x.GetMsg().(*ServerMessage_Leave); ok {
+		return x.Leave
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetCursor() *CursorPosition {
+	if x, ok := x.GetMsg().(*ServerMessage_Cursor); ok {
+		return x.Cursor
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetChat() *Chat {
+	if x, ok := x.GetMsg().(*ServerMessage_Chat); ok {
+		return x.Chat
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetChatHistory() *ChatHistory {
+	if x, ok := x.GetMsg().(*ServerMessage_ChatHistory); ok {
+		return x.ChatHistory
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetAnnounce() *Announcement {
+	if x, ok := x.GetMsg().(*ServerMessage_Announce); ok {
+		return x.Announce
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetFrozen() *Frozen {
+	if x, ok := x.GetMsg().(*ServerMessage_Frozen); ok {
+		return x.Frozen
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetReset_() *Reset {
+	if x, ok := x.GetMsg().(*ServerMessage_Reset_); ok {
+		return x.Reset_
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetProtected() *Protected {
+	if x, ok := x.GetMsg().(*ServerMessage_Protected); ok {
+		return x.Protected
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetLocks() *Locks {
+	if x, ok := x.GetMsg().(*ServerMessage_Locks); ok {
+		return x.Locks
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetTeam() *Team {
+	if x, ok := x.GetMsg().(*ServerMessage_Team); ok {
+		return x.Team
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetTeams() *Teams {
+	if x, ok := x.GetMsg().(*ServerMessage_Teams); ok {
+		return x.Teams
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetMeta() *CellMeta {
+	if x, ok := x.GetMsg().(*ServerMessage_Meta); ok {
+		return x.Meta
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetClaims() *Claims {
+	if x, ok := x.GetMsg().(*ServerMessage_Claims); ok {
+		return x.Claims
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetCaptcha() *CaptchaAccepted {
+	if x, ok := x.GetMsg().(*ServerMessage_Captcha); ok {
+		return x.Captcha
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetTier() *Tier {
+	if x, ok := x.GetMsg().(*ServerMessage_Tier); ok {
+		return x.Tier
+	}
+	return nil
+}
+
+type isServerMessage_Msg interface {
+	isServerMessage_Msg()
+}
+
+type ServerMessage_Init struct {
+	Init *Init `protobuf:"bytes,1,opt,name=init,proto3,oneof"`
+}
+
+type ServerMessage_InitChunk struct {
+	InitChunk *InitChunk `protobuf:"bytes,2,opt,name=init_chunk,json=initChunk,proto3,oneof"`
+}
+
+type ServerMessage_InitDone struct {
+	InitDone *InitDone `protobuf:"bytes,3,opt,name=init_done,json=initDone,proto3,oneof"`
+}
+
+type ServerMessage_Update struct {
+	Update *CellUpdate `protobuf:"bytes,4,opt,name=update,proto3,oneof"`
+}
+
+type ServerMessage_Batch struct {
+	Batch *BatchUpdate `protobuf:"bytes,5,opt,name=batch,proto3,oneof"`
+}
+
+type ServerMessage_ClientCount struct {
+	ClientCount *ClientCount `protobuf:"bytes,6,opt,name=client_count,json=clientCount,proto3,oneof"`
+}
+
+type ServerMessage_Palette struct {
+	Palette *Palette `protobuf:"bytes,7,opt,name=palette,proto3,oneof"`
+}
+
+type ServerMessage_Error struct {
+	Error *Error `protobuf:"bytes,8,opt,name=error,proto3,oneof"`
+}
+
+type ServerMessage_Cooldown struct {
+	Cooldown *Cooldown `protobuf:"bytes,9,opt,name=cooldown,proto3,oneof"`
+}
+
+type ServerMessage_Region struct {
+	Region *RegionState `protobuf:"bytes,10,opt,name=region,proto3,oneof"`
+}
+
+type ServerMessage_Roster struct {
+	Roster *Roster `protobuf:"bytes,11,opt,name=roster,proto3,oneof"`
+}
+
+type ServerMessage_Join struct {
+	Join *Presence `protobuf:"bytes,12,opt,name=join,proto3,oneof"` // A client connected
+}
+
+type ServerMessage_Leave struct {
+	Leave *Leave `protobuf:"bytes,13,opt,name=leave,proto3,oneof"` // A client disconnected
+}
+
+type ServerMessage_Cursor struct {
+	Cursor *CursorPosition `protobuf:"bytes,14,opt,name=cursor,proto3,oneof"`
+}
+
+type ServerMessage_Chat struct {
+	Chat *Chat `protobuf:"bytes,15,opt,name=chat,proto3,oneof"`
+}
+
+type ServerMessage_ChatHistory struct {
+	ChatHistory *ChatHistory `protobuf:"bytes,16,opt,name=chat_history,json=chatHistory,proto3,oneof"`
+}
+
+type ServerMessage_Announce struct {
+	Announce *Announcement `protobuf:"bytes,17,opt,name=announce,proto3,oneof"`
+}
+
+type ServerMessage_Frozen struct {
+	Frozen *Frozen `protobuf:"bytes,18,opt,name=frozen,proto3,oneof"`
+}
+
+type ServerMessage_Reset_ struct {
+	Reset_ *Reset `protobuf:"bytes,19,opt,name=reset,proto3,oneof"`
+}
+
+type ServerMessage_Protected struct {
+	Protected *Protected `protobuf:"bytes,20,opt,name=protected,proto3,oneof"`
+}
+
+type ServerMessage_Locks struct {
+	Locks *Locks `protobuf:"bytes,21,opt,name=locks,proto3,oneof"`
+}
+
+type ServerMessage_Team struct {
+	Team *Team `protobuf:"bytes,22,opt,name=team,proto3,oneof"`
+}
+
+type ServerMessage_Teams struct {
+	Teams *Teams `protobuf:"bytes,23,opt,name=teams,proto3,oneof"`
+}
+
+type ServerMessage_Meta struct {
+	Meta *CellMeta `protobuf:"bytes,24,opt,name=meta,proto3,oneof"`
+}
+
+type ServerMessage_Claims struct {
+	Claims *Claims `protobuf:"bytes,25,opt,name=claims,proto3,oneof"`
+}
+
+type ServerMessage_Captcha struct {
+	Captcha *CaptchaAccepted `protobuf:"bytes,26,opt,name=captcha,proto3,oneof"`
+}
+
+type ServerMessage_Tier struct {
+	Tier *Tier `protobuf:"bytes,27,opt,name=tier,proto3,oneof"`
+}
+
+func (*ServerMessage_Init) isServerMessage_Msg() {}
+
+func (*ServerMessage_InitChunk) isServerMessage_Msg() {}
+
+func (*ServerMessage_InitDone) isServerMessage_Msg() {}
+
+func (*ServerMessage_Update) isServerMessage_Msg() {}
+
+func (*ServerMessage_Batch) isServerMessage_Msg() {}
+
+func (*ServerMessage_ClientCount) isServerMessage_Msg() {}
+
+func (*ServerMessage_Palette) isServerMessage_Msg() {}
+
+func (*ServerMessage_Error) isServerMessage_Msg() {}
+
+func (*ServerMessage_Cooldown) isServerMessage_Msg() {}
+
+func (*ServerMessage_Region) isServerMessage_Msg() {}
+
+func (*ServerMessage_Roster) isServerMessage_Msg() {}
+
+func (*ServerMessage_Join) isServerMessage_Msg() {}
+
+func (*ServerMessage_Leave) isServerMessage_Msg() {}
+
+func (*ServerMessage_Cursor) isServerMessage_Msg() {}
+
+func (*ServerMessage_Chat) isServerMessage_Msg() {}
+
+func (*ServerMessage_ChatHistory) isServerMessage_Msg() {}
+
+func (*ServerMessage_Announce) isServerMessage_Msg() {}
+
+func (*ServerMessage_Frozen) isServerMessage_Msg() {}
+
+func (*ServerMessage_Reset_) isServerMessage_Msg() {}
+
+func (*ServerMessage_Protected) isServerMessage_Msg() {}
+
+func (*ServerMessage_Locks) isServerMessage_Msg() {}
+
+func (*ServerMessage_Team) isServerMessage_Msg() {}
+
+func (*ServerMessage_Teams) isServerMessage_Msg() {}
+
+func (*ServerMessage_Meta) isServerMessage_Msg() {}
+
+func (*ServerMessage_Claims) isServerMessage_Msg() {}
+
+func (*ServerMessage_Captcha) isServerMessage_Msg() {}
+
+func (*ServerMessage_Tier) isServerMessage_Msg() {}
+
+// Init starts the initial state stream sent to new clients
+type Init struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Canvas string `protobuf:"bytes,1,opt,name=canvas,proto3" json:"canvas,omitempty"`
+	Width  uint32 `protobuf:"varint,2,opt,name=width,proto3" json:"width,omitempty"`
+	Height uint32 `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
+	// Side length of the regions the active cells are streamed in
+	Chunk          uint32   `protobuf:"varint,4,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	Palette        []uint32 `protobuf:"varint,5,rep,packed,name=palette,proto3" json:"palette,omitempty"`
+	PaletteVersion uint32   `protobuf:"varint,6,opt,name=palette_version,json=paletteVersion,proto3" json:"palette_version,omitempty"`
+	// Colors outside the palette are allowed
+	AnyColor bool `protobuf:"varint,7,opt,name=any_color,json=anyColor,proto3" json:"any_color,omitempty"`
+	// Paint operations are rejected, see Frozen
+	Frozen       bool   `protobuf:"varint,8,opt,name=frozen,proto3" json:"frozen,omitempty"`
+	FrozenReason string `protobuf:"bytes,9,opt,name=frozen_reason,json=frozenReason,proto3" json:"frozen_reason,omitempty"`
+	// The server is a read-only replica: paint operations, chat and cursors
+	// are rejected whether or not the canvas is frozen
+	Follower bool `protobuf:"varint,10,opt,name=follower,proto3" json:"follower,omitempty"`
+}
+
+func (x *Init) Reset() {
+	*x = Init{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Init) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Init) ProtoMessage() {}
+
+func (x *Init) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Init.ProtoReflect.Descriptor instead.
+func (*Init) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Init) GetCanvas() string {
+	if x != nil {
+		return x.Canvas
+	}
+	return ""
+}
+
+func (x *Init) GetWidth() uint32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *Init) GetHeight() uint32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *Init) GetChunk() uint32 {
+	if x != nil {
+		return x.Chunk
+	}
+	return 0
+}
+
+func (x *Init) GetPalette() []uint32 {
+	if x != nil {
+		return x.Palette
+	}
+	return nil
+}
+
+func (x *Init) GetPaletteVersion() uint32 {
+	if x != nil {
+		return x.PaletteVersion
+	}
+	return 0
+}
+
+func (x *Init) GetAnyColor() bool {
+	if x != nil {
+		return x.AnyColor
+	}
+	return false
+}
+
+func (x *Init) GetFrozen() bool {
+	if x != nil {
+		return x.Frozen
+	}
+	return false
+}
+
+func (x *Init) GetFrozenReason() string {
+	if x != nil {
+		return x.FrozenReason
+	}
+	return ""
+}
+
+func (x *Init) GetFollower() bool {
+	if x != nil {
+		return x.Follower
+	}
+	return false
+}
+
+// InitChunk carries the active cells of one region of the initial state
+type InitChunk struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Region origin
+	X      uint32  `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y      uint32  `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+	Active []*Cell `protobuf:"bytes,3,rep,name=active,proto3" json:"active,omitempty"`
+}
+
+func (x *InitChunk) Reset() {
+	*x = InitChunk{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InitChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InitChunk) ProtoMessage() {}
+
+func (x *InitChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InitChunk.ProtoReflect.Descriptor instead.
+func (*InitChunk) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *InitChunk) GetX() uint32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *InitChunk) GetY() uint32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *InitChunk) GetActive() []*Cell {
+	if x != nil {
+		return x.Active
+	}
+	return nil
+}
+
+// InitDone ends the initial state stream
+type InitDone struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Total number of active cells sent
+	Total uint32 `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+}
+
+func (x *InitDone) Reset() {
+	*x = InitDone{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InitDone) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InitDone) ProtoMessage() {}
+
+func (x *InitDone) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InitDone.ProtoReflect.Descriptor instead.
+func (*InitDone) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *InitDone) GetTotal() uint32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+// CellUpdate is the new state of a changed cell
+type CellUpdate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	X      uint32 `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y      uint32 `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+	Active bool   `protobuf:"varint,3,opt,name=active,proto3" json:"active,omitempty"`
+	Color  uint32 `protobuf:"varint,4,opt,name=color,proto3" json:"color,omitempty"`
+	// Team of the actor that changed the cell (empty outside teams)
+	Team string `protobuf:"bytes,5,opt,name=team,proto3" json:"team,omitempty"`
+}
+
+func (x *CellUpdate) Reset() {
+	*x = CellUpdate{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CellUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CellUpdate) ProtoMessage() {}
+
+func (x *CellUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CellUpdate.ProtoReflect.Descriptor instead.
+func (*CellUpdate) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *CellUpdate) GetX() uint32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *CellUpdate) GetY() uint32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *CellUpdate) GetActive() bool {
+	if x != nil {
+		return x.Active
+	}
+	return false
+}
+
+func (x *CellUpdate) GetColor() uint32 {
+	if x != nil {
+		return x.Color
+	}
+	return 0
+}
+
+func (x *CellUpdate) GetTeam() string {
+	if x != nil {
+		return x.Team
+	}
+	return ""
+}
+
+// BatchUpdate carries several cells changed at once
+type BatchUpdate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Cells []*CellUpdate `protobuf:"bytes,1,rep,name=cells,proto3" json:"cells,omitempty"`
+	// Team of the actor that changed the cells (empty outside teams)
+	Team string `protobuf:"bytes,2,opt,name=team,proto3" json:"team,omitempty"`
+}
+
+func (x *BatchUpdate) Reset() {
+	*x = BatchUpdate{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchUpdate) ProtoMessage() {}
+
+func (x *BatchUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchUpdate.ProtoReflect.Descriptor instead.
+func (*BatchUpdate) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *BatchUpdate) GetCells() []*CellUpdate {
+	if x != nil {
+		return x.Cells
+	}
+	return nil
+}
+
+func (x *BatchUpdate) GetTeam() string {
+	if x != nil {
+		return x.Team
+	}
+	return ""
+}
+
+// ClientCount is the number of clients connected to the canvas
+type ClientCount struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Count uint32 `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+}
+
+func (x *ClientCount) Reset() {
+	*x = ClientCount{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClientCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientCount) ProtoMessage() {}
+
+func (x *ClientCount) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientCount.ProtoReflect.Descriptor instead.
+func (*ClientCount) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ClientCount) GetCount() uint32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+// Palette is sent when an admin changes the palette
+type Palette struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Version  uint32   `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Colors   []uint32 `protobuf:"varint,2,rep,packed,name=colors,proto3" json:"colors,omitempty"`
+	AnyColor bool     `protobuf:"varint,3,opt,name=any_color,json=anyColor,proto3" json:"any_color,omitempty"`
+}
+
+func (x *Palette) Reset() {
+	*x = Palette{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Palette) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Palette) ProtoMessage() {}
+
+func (x *Palette) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Palette.ProtoReflect.Descriptor instead.
+func (*Palette) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Palette) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Palette) GetColors() []uint32 {
+	if x != nil {
+		return x.Colors
+	}
+	return nil
+}
+
+func (x *Palette) GetAnyColor() bool {
+	if x != nil {
+		return x.AnyColor
+	}
+	return false
+}
+
+// Error reports a rejected message
+type Error struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code string `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Msg  string `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty"`
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *Error) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Error) GetMsg() string {
+	if x != nil {
+		return x.Msg
+	}
+	return ""
+}
+
+// Cooldown reports a placement rejected by the cooldown
+type Cooldown struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Time left before the next placement is allowed
+	RemainingMs int64 `protobuf:"varint,1,opt,name=remaining_ms,json=remainingMs,proto3" json:"remaining_ms,omitempty"`
+}
+
+func (x *Cooldown) Reset() {
+	*x = Cooldown{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Cooldown) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cooldown) ProtoMessage() {}
+
+func (x *Cooldown) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cooldown.ProtoReflect.Descriptor instead.
+func (*Cooldown) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *Cooldown) GetRemainingMs() int64 {
+	if x != nil {
+		return x.RemainingMs
+	}
+	return 0
+}
+
+// CaptchaAccepted reports a CAPTCHA token was accepted
+type CaptchaAccepted struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Placements allowed before the next challenge (0 when placements aren't challenged)
+	Remaining uint32 `protobuf:"varint,1,opt,name=remaining,proto3" json:"remaining,omitempty"`
+}
+
+func (x *CaptchaAccepted) Reset() {
+	*x = CaptchaAccepted{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CaptchaAccepted) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CaptchaAccepted) ProtoMessage() {}
+
+func (x *CaptchaAccepted) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CaptchaAccepted.ProtoReflect.Descriptor instead.
+func (*CaptchaAccepted) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *CaptchaAccepted) GetRemaining() uint32 {
+	if x != nil {
+		return x.Remaining
+	}
+	return 0
+}
+
+// Tier reports the placement tier of the client and what is left of its quota
+type Tier struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name       string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	CooldownMs int64  `protobuf:"varint,2,opt,name=cooldown_ms,json=cooldownMs,proto3" json:"cooldown_ms,omitempty"`
+	// Placements allowed per window (0 for no quota), left in the current
+	// window, and the time until the window resets
+	Quota     uint32 `protobuf:"varint,3,opt,name=quota,proto3" json:"quota,omitempty"`
+	Remaining uint32 `protobuf:"varint,4,opt,name=remaining,proto3" json:"remaining,omitempty"`
+	ResetMs   int64  `protobuf:"varint,5,opt,name=reset_ms,json=resetMs,proto3" json:"reset_ms,omitempty"`
+}
+
+func (x *Tier) Reset() {
+	*x = Tier{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Tier) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Tier) ProtoMessage() {}
+
+func (x *Tier) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Tier.ProtoReflect.Descriptor instead.
+func (*Tier) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *Tier) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Tier) GetCooldownMs() int64 {
+	if x != nil {
+		return x.CooldownMs
+	}
+	return 0
+}
+
+func (x *Tier) GetQuota() uint32 {
+	if x != nil {
+		return x.Quota
+	}
+	return 0
+}
+
+func (x *Tier) GetRemaining() uint32 {
+	if x != nil {
+		return x.Remaining
+	}
+	return 0
+}
+
+func (x *Tier) GetResetMs() int64 {
+	if x != nil {
+		return x.ResetMs
+	}
+	return 0
+}
+
+// Protected reports a placement rejected because someone else changed the
+// cell too recently
+type Protected struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	X uint32 `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y uint32 `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+	// Time left before the cell may be overwritten
+	RemainingMs int64 `protobuf:"varint,3,opt,name=remaining_ms,json=remainingMs,proto3" json:"remaining_ms,omitempty"`
+}
+
+func (x *Protected) Reset() {
+	*x = Protected{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Protected) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Protected) ProtoMessage() {}
+
+func (x *Protected) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Protected.ProtoReflect.Descriptor instead.
+func (*Protected) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *Protected) GetX() uint32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *Protected) GetY() uint32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *Protected) GetRemainingMs() int64 {
+	if x != nil {
+		return x.RemainingMs
+	}
+	return 0
+}
+
+// LockedRegion is a region of the canvas only moderators may paint in
+type LockedRegion struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id     uint32  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Region *Region `protobuf:"bytes,2,opt,name=region,proto3" json:"region,omitempty"`
+	Label  string  `protobuf:"bytes,3,opt,name=label,proto3" json:"label,omitempty"`
+}
+
+func (x *LockedRegion) Reset() {
+	*x = LockedRegion{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[25]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LockedRegion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LockedRegion) ProtoMessage() {}
+
+func (x *LockedRegion) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[25]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LockedRegion.ProtoReflect.Descriptor instead.
+func (*LockedRegion) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *LockedRegion) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *LockedRegion) GetRegion() *Region {
+	if x != nil {
+		return x.Region
+	}
+	return nil
+}
+
+func (x *LockedRegion) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+// Locks carries the locked regions of the canvas, sent to new clients and
+// whenever they change
+type Locks struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Regions []*LockedRegion `protobuf:"bytes,1,rep,name=regions,proto3" json:"regions,omitempty"`
+}
+
+func (x *Locks) Reset() {
+	*x = Locks{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[26]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Locks) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Locks) ProtoMessage() {}
+
+func (x *Locks) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[26]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Locks.ProtoReflect.Descriptor instead.
+func (*Locks) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *Locks) GetRegions() []*LockedRegion {
+	if x != nil {
+		return x.Regions
+	}
+	return nil
+}
+
+// Claim reserves a region for an account until it expires, in economy mode
+type Claim struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id          uint32  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Region      *Region `protobuf:"bytes,2,opt,name=region,proto3" json:"region,omitempty"`
+	Owner       string  `protobuf:"bytes,3,opt,name=owner,proto3" json:"owner,omitempty"`
+	ExpiresAtMs int64   `protobuf:"varint,4,opt,name=expires_at_ms,json=expiresAtMs,proto3" json:"expires_at_ms,omitempty"`
+}
+
+func (x *Claim) Reset() {
+	*x = Claim{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[27]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Claim) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Claim) ProtoMessage() {}
+
+func (x *Claim) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[27]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Claim.ProtoReflect.Descriptor instead.
+func (*Claim) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *Claim) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Claim) GetRegion() *Region {
+	if x != nil {
+		return x.Region
+	}
+	return nil
+}
+
+func (x *Claim) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *Claim) GetExpiresAtMs() int64 {
+	if x != nil {
+		return x.ExpiresAtMs
+	}
+	return 0
+}
+
+// Claims carries the claims of the canvas, sent to new clients and whenever
+// they change
+type Claims struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Claims []*Claim `protobuf:"bytes,1,rep,name=claims,proto3" json:"claims,omitempty"`
+}
+
+func (x *Claims) Reset() {
+	*x = Claims{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[28]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Claims) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Claims) ProtoMessage() {}
+
+func (x *Claims) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[28]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Claims.ProtoReflect.Descriptor instead.
+func (*Claims) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *Claims) GetClaims() []*Claim {
+	if x != nil {
+		return x.Claims
+	}
+	return nil
+}
+
+// Team is the team the client's placements count for (empty for none)
+type Team struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (x *Team) Reset() {
+	*x = Team{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[29]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Team) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Team) ProtoMessage() {}
+
+func (x *Team) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[29]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Team.ProtoReflect.Descriptor instead.
+func (*Team) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *Team) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// TeamScore is the number of cells a team currently holds
+type TeamScore struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Team   string `protobuf:"bytes,1,opt,name=team,proto3" json:"team,omitempty"`
+	Pixels uint32 `protobuf:"varint,2,opt,name=pixels,proto3" json:"pixels,omitempty"`
+}
+
+func (x *TeamScore) Reset() {
+	*x = TeamScore{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[30]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TeamScore) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TeamScore) ProtoMessage() {}
+
+func (x *TeamScore) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[30]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TeamScore.ProtoReflect.Descriptor instead.
+func (*TeamScore) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *TeamScore) GetTeam() string {
+	if x != nil {
+		return x.Team
+	}
+	return ""
+}
+
+func (x *TeamScore) GetPixels() uint32 {
+	if x != nil {
+		return x.Pixels
+	}
+	return 0
+}
+
+// Teams carries the territory of every team, most cells first
+type Teams struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Scores []*TeamScore `protobuf:"bytes,1,rep,name=scores,proto3" json:"scores,omitempty"`
+}
+
+func (x *Teams) Reset() {
+	*x = Teams{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[31]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Teams) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Teams) ProtoMessage() {}
+
+func (x *Teams) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[31]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Teams.ProtoReflect.Descriptor instead.
+func (*Teams) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *Teams) GetScores() []*TeamScore {
+	if x != nil {
+		return x.Scores
+	}
+	return nil
+}
+
+// CellMeta is the text and link attached to a cell, both empty once removed
+type CellMeta struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	X    uint32 `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y    uint32 `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+	Text string `protobuf:"bytes,3,opt,name=text,proto3" json:"text,omitempty"`
+	Url  string `protobuf:"bytes,4,opt,name=url,proto3" json:"url,omitempty"`
+}
+
+func (x *CellMeta) Reset() {
+	*x = CellMeta{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[32]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CellMeta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CellMeta) ProtoMessage() {}
+
+func (x *CellMeta) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[32]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CellMeta.ProtoReflect.Descriptor instead.
+func (*CellMeta) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *CellMeta) GetX() uint32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *CellMeta) GetY() uint32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *CellMeta) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *CellMeta) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+// RegionState carries the active cells of a newly subscribed region
+type RegionState struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Region *Region `protobuf:"bytes,1,opt,name=region,proto3" json:"region,omitempty"`
+	Active []*Cell `protobuf:"bytes,2,rep,name=active,proto3" json:"active,omitempty"`
+}
+
+func (x *RegionState) Reset() {
+	*x = RegionState{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[33]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RegionState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegionState) ProtoMessage() {}
+
+func (x *RegionState) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[33]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegionState.ProtoReflect.Descriptor instead.
+func (*RegionState) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *RegionState) GetRegion() *Region {
+	if x != nil {
+		return x.Region
+	}
+	return nil
+}
+
+func (x *RegionState) GetActive() []*Cell {
+	if x != nil {
+		return x.Active
+	}
+	return nil
+}
+
+// Presence is a client connected to the canvas
+type Presence struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id   uint64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (x *Presence) Reset() {
+	*x = Presence{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[34]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Presence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Presence) ProtoMessage() {}
+
+func (x *Presence) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[34]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Presence.ProtoReflect.Descriptor instead.
+func (*Presence) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *Presence) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Presence) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// Roster lists the clients connected to the canvas, sent to new clients
+type Roster struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The receiving client
+	You   *Presence   `protobuf:"bytes,1,opt,name=you,proto3" json:"you,omitempty"`
+	Users []*Presence `protobuf:"bytes,2,rep,name=users,proto3" json:"users,omitempty"`
+}
+
+func (x *Roster) Reset() {
+	*x = Roster{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[35]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Roster) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Roster) ProtoMessage() {}
+
+func (x *Roster) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[35]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Roster.ProtoReflect.Descriptor instead.
+func (*Roster) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *Roster) GetYou() *Presence {
+	if x != nil {
+		return x.You
+	}
+	return nil
+}
+
+func (x *Roster) GetUsers() []*Presence {
+	if x != nil {
+		return x.Users
+	}
+	return nil
+}
+
+// Leave reports a client that disconnected
+type Leave struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id uint64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *Leave) Reset() {
+	*x = Leave{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[36]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Leave) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Leave) ProtoMessage() {}
+
+func (x *Leave) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[36]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Leave.ProtoReflect.Descriptor instead.
+func (*Leave) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *Leave) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+// CursorPosition is the cell under another client's pointer
+type CursorPosition struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id uint64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	X  uint32 `protobuf:"varint,2,opt,name=x,proto3" json:"x,omitempty"`
+	Y  uint32 `protobuf:"varint,3,opt,name=y,proto3" json:"y,omitempty"`
+}
+
+func (x *CursorPosition) Reset() {
+	*x = CursorPosition{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[37]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CursorPosition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CursorPosition) ProtoMessage() {}
+
+func (x *CursorPosition) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[37]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CursorPosition.ProtoReflect.Descriptor instead.
+func (*CursorPosition) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *CursorPosition) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *CursorPosition) GetX() uint32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *CursorPosition) GetY() uint32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+// Chat is a chat message posted on the canvas
+type Chat struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Connection ID of the author (0 for messages restored after a restart)
+	Id   uint64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Text string `protobuf:"bytes,3,opt,name=text,proto3" json:"text,omitempty"`
+	// Time posted, in Unix milliseconds
+	AtMs int64 `protobuf:"varint,4,opt,name=at_ms,json=atMs,proto3" json:"at_ms,omitempty"`
+	// Channel posted to, empty for the canvas-wide chat
+	Channel string `protobuf:"bytes,5,opt,name=channel,proto3" json:"channel,omitempty"`
+}
+
+func (x *Chat) Reset() {
+	*x = Chat{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[38]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Chat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Chat) ProtoMessage() {}
+
+func (x *Chat) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[38]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Chat.ProtoReflect.Descriptor instead.
+func (*Chat) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *Chat) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Chat) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Chat) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Chat) GetAtMs() int64 {
+	if x != nil {
+		return x.AtMs
+	}
+	return 0
+}
+
+func (x *Chat) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+// ChatHistory carries the latest chat messages to new clients, oldest first
+type ChatHistory struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Messages []*Chat `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+}
+
+func (x *ChatHistory) Reset() {
+	*x = ChatHistory{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[39]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ChatHistory) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatHistory) ProtoMessage() {}
+
+func (x *ChatHistory) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[39]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatHistory.ProtoReflect.Descriptor instead.
+func (*ChatHistory) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *ChatHistory) GetMessages() []*Chat {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+// Announcement is pushed to every client by an admin, e.g. a maintenance notice
+type Announcement struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Text string `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	// "info", "warning" or "critical"
+	Severity string `protobuf:"bytes,2,opt,name=severity,proto3" json:"severity,omitempty"`
+	// Seconds from at_ms until the announced event, 0 for none
+	Countdown uint32 `protobuf:"varint,3,opt,name=countdown,proto3" json:"countdown,omitempty"`
+	// Time announced, in Unix milliseconds
+	AtMs int64 `protobuf:"varint,4,opt,name=at_ms,json=atMs,proto3" json:"at_ms,omitempty"`
+}
+
+func (x *Announcement) Reset() {
+	*x = Announcement{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[40]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Announcement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Announcement) ProtoMessage() {}
+
+func (x *Announcement) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[40]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Announcement.ProtoReflect.Descriptor instead.
+func (*Announcement) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *Announcement) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Announcement) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *Announcement) GetCountdown() uint32 {
+	if x != nil {
+		return x.Countdown
+	}
+	return 0
+}
+
+func (x *Announcement) GetAtMs() int64 {
+	if x != nil {
+		return x.AtMs
+	}
+	return 0
+}
+
+// Frozen is sent when the canvas is frozen or thawed. While frozen, paint
+// operations are rejected with "canvas_frozen" errors.
+type Frozen struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Enabled bool   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	Reason  string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (x *Frozen) Reset() {
+	*x = Frozen{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[41]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Frozen) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Frozen) ProtoMessage() {}
+
+func (x *Frozen) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[41]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Frozen.ProtoReflect.Descriptor instead.
+func (*Frozen) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *Frozen) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *Frozen) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// Reset is sent when every cell of the canvas was cleared. Clients drop their
+// copy of the grid and reload it.
+type Reset struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Name the previous state was archived under
+	Archive string `protobuf:"bytes,1,opt,name=archive,proto3" json:"archive,omitempty"`
+	// Time of the reset, in Unix milliseconds
+	AtMs int64 `protobuf:"varint,2,opt,name=at_ms,json=atMs,proto3" json:"at_ms,omitempty"`
+}
+
+func (x *Reset) Reset() {
+	*x = Reset{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_proto_msgTypes[42]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Reset) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Reset) ProtoMessage() {}
+
+func (x *Reset) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_proto_msgTypes[42]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Reset.ProtoReflect.Descriptor instead.
+func (*Reset) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *Reset) GetArchive() string {
+	if x != nil {
+		return x.Archive
+	}
+	return ""
+}
+
+func (x *Reset) GetAtMs() int64 {
+	if x != nil {
+		return x.AtMs
+	}
+	return 0
+}
+
+var File_million_grids_v1_grid_proto protoreflect.FileDescriptor
+
+var file_million_grids_v1_grid_proto_rawDesc = []byte{
+	0x0a, 0x1b, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2f,
+	0x76, 0x31, 0x2f, 0x67, 0x72, 0x69, 0x64, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x10, 0x6d,
+	0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x22,
+	0xdf, 0x05, 0x0a, 0x0d, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67,
+	0x65, 0x12, 0x32, 0x0a, 0x06, 0x74, 0x6f, 0x67, 0x67, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x18, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64,
+	0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x4f, 0x70, 0x48, 0x00, 0x52, 0x06, 0x74,
+	0x6f, 0x67, 0x67, 0x6c, 0x65, 0x12, 0x2c, 0x0a, 0x03, 0x73, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x18, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69,
+	0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x4f, 0x70, 0x48, 0x00, 0x52, 0x03,
+	0x73, 0x65, 0x74, 0x12, 0x30, 0x0a, 0x05, 0x63, 0x6c, 0x65, 0x61, 0x72, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x18, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69,
+	0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x4f, 0x70, 0x48, 0x00, 0x52, 0x05,
+	0x63, 0x6c, 0x65, 0x61, 0x72, 0x12, 0x2f, 0x0a, 0x05, 0x70, 0x61, 0x69, 0x6e, 0x74, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67,
+	0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x61, 0x69, 0x6e, 0x74, 0x48, 0x00, 0x52,
+	0x05, 0x70, 0x61, 0x69, 0x6e, 0x74, 0x12, 0x38, 0x0a, 0x09, 0x73, 0x75, 0x62, 0x73, 0x63, 0x72,
+	0x69, 0x62, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x6d, 0x69, 0x6c, 0x6c,
+	0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x67,
+	0x69, 0x6f, 0x6e, 0x48, 0x00, 0x52, 0x09, 0x73, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65,
+	0x12, 0x41, 0x0a, 0x0b, 0x75, 0x6e, 0x73, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f,
+	0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x55, 0x6e, 0x73, 0x75, 0x62, 0x73, 0x63,
+	0x72, 0x69, 0x62, 0x65, 0x48, 0x00, 0x52, 0x0b, 0x75, 0x6e, 0x73, 0x75, 0x62, 0x73, 0x63, 0x72,
+	0x69, 0x62, 0x65, 0x12, 0x32, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72,
+	0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x48, 0x00, 0x52,
+	0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x12, 0x30, 0x0a, 0x04, 0x63, 0x68, 0x61, 0x74, 0x18,
+	0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f,
+	0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x68, 0x61, 0x74, 0x50, 0x6f, 0x73,
+	0x74, 0x48, 0x00, 0x52, 0x04, 0x63, 0x68, 0x61, 0x74, 0x12, 0x3e, 0x0a, 0x0c, 0x6a, 0x6f, 0x69,
+	0x6e, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x19, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e,
+	0x76, 0x31, 0x2e, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x48, 0x00, 0x52, 0x0b, 0x6a, 0x6f,
+	0x69, 0x6e, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x12, 0x40, 0x0a, 0x0d, 0x6c, 0x65, 0x61,
+	0x76, 0x65, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x19, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73,
+	0x2e, 0x76, 0x31, 0x2e, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x48, 0x00, 0x52, 0x0c, 0x6c,
+	0x65, 0x61, 0x76, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x12, 0x30, 0x0a, 0x04, 0x74,
+	0x65, 0x61, 0x6d, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x6d, 0x69, 0x6c, 0x6c,
+	0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x4a, 0x6f, 0x69,
+	0x6e, 0x54, 0x65, 0x61, 0x6d, 0x48, 0x00, 0x52, 0x04, 0x74, 0x65, 0x61, 0x6d, 0x12, 0x2c, 0x0a,
+	0x04, 0x75, 0x6e, 0x64, 0x6f, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x6d, 0x69,
+	0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x55,
+	0x6e, 0x64, 0x6f, 0x48, 0x00, 0x52, 0x04, 0x75, 0x6e, 0x64, 0x6f, 0x12, 0x3d, 0x0a, 0x07, 0x63,
+	0x61, 0x70, 0x74, 0x63, 0x68, 0x61, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x6d,
+	0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e,
+	0x43, 0x61, 0x70, 0x74, 0x63, 0x68, 0x61, 0x53, 0x6f, 0x6c, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x48,
+	0x00, 0x52, 0x07, 0x63, 0x61, 0x70, 0x74, 0x63, 0x68, 0x61, 0x42, 0x05, 0x0a, 0x03, 0x6d, 0x73,
+	0x67, 0x22, 0x49, 0x0a, 0x06, 0x43, 0x65, 0x6c, 0x6c, 0x4f, 0x70, 0x12, 0x0c, 0x0a, 0x01, 0x78,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x01, 0x78, 0x12, 0x0c, 0x0a, 0x01, 0x79, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x01, 0x79, 0x12, 0x19, 0x0a, 0x05, 0x63, 0x6f, 0x6c, 0x6f, 0x72,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x48, 0x00, 0x52, 0x05, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x88,
+	0x01, 0x01, 0x42, 0x08, 0x0a, 0x06, 0x5f, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x22, 0x35, 0x0a, 0x05,
+	0x50, 0x61, 0x69, 0x6e, 0x74, 0x12, 0x2c, 0x0a, 0x05, 0x63, 0x65, 0x6c, 0x6c, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67,
+	0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x52, 0x05, 0x63, 0x65,
+	0x6c, 0x6c, 0x73, 0x22, 0x24, 0x0a, 0x06, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x12, 0x0c, 0x0a,
+	0x01, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x01, 0x78, 0x12, 0x0c, 0x0a, 0x01, 0x79,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x01, 0x79, 0x22, 0x38, 0x0a, 0x08, 0x43, 0x68, 0x61,
+	0x74, 0x50, 0x6f, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65, 0x78, 0x74, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x78, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x68, 0x61,
+	0x6e, 0x6e, 0x65, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x68, 0x61, 0x6e,
+	0x6e, 0x65, 0x6c, 0x22, 0x1d, 0x0a, 0x07, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x12, 0x12,
+	0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x22, 0x1e, 0x0a, 0x08, 0x4a, 0x6f, 0x69, 0x6e, 0x54, 0x65, 0x61, 0x6d, 0x12, 0x12,
+	0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x22, 0x0d, 0x0a, 0x0b, 0x55, 0x6e, 0x73, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62,
+	0x65, 0x22, 0x06, 0x0a, 0x04, 0x55, 0x6e, 0x64, 0x6f, 0x22, 0x27, 0x0a, 0x0f, 0x43, 0x61, 0x70,
+	0x74, 0x63, 0x68, 0x61, 0x53, 0x6f, 0x6c, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05,
+	0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x6f, 0x6b,
+	0x65, 0x6e, 0x22, 0x48, 0x0a, 0x06, 0x52, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x12, 0x0e, 0x0a, 0x02,
+	0x78, 0x31, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x78, 0x31, 0x12, 0x0e, 0x0a, 0x02,
+	0x79, 0x31, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x79, 0x31, 0x12, 0x0e, 0x0a, 0x02,
+	0x78, 0x32, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x78, 0x32, 0x12, 0x0e, 0x0a, 0x02,
+	0x79, 0x32, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x79, 0x32, 0x22, 0x38, 0x0a, 0x04,
+	0x43, 0x65, 0x6c, 0x6c, 0x12, 0x0c, 0x0a, 0x01, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x01, 0x78, 0x12, 0x0c, 0x0a, 0x01, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x01, 0x79,
+	0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x05, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x22, 0xd3, 0x0b, 0x0a, 0x0d, 0x53, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x2c, 0x0a, 0x04, 0x69, 0x6e, 0x69, 0x74,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e,
+	0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x6e, 0x69, 0x74, 0x48, 0x00,
+	0x52, 0x04, 0x69, 0x6e, 0x69, 0x74, 0x12, 0x3c, 0x0a, 0x0a, 0x69, 0x6e, 0x69, 0x74, 0x5f, 0x63,
+	0x68, 0x75, 0x6e, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x6d, 0x69, 0x6c,
+	0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x6e,
+	0x69, 0x74, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x48, 0x00, 0x52, 0x09, 0x69, 0x6e, 0x69, 0x74, 0x43,
+	0x68, 0x75, 0x6e, 0x6b, 0x12, 0x39, 0x0a, 0x09, 0x69, 0x6e, 0x69, 0x74, 0x5f, 0x64, 0x6f, 0x6e,
+	0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f,
+	0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x6e, 0x69, 0x74, 0x44,
+	0x6f, 0x6e, 0x65, 0x48, 0x00, 0x52, 0x08, 0x69, 0x6e, 0x69, 0x74, 0x44, 0x6f, 0x6e, 0x65, 0x12,
+	0x36, 0x0a, 0x06, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x1c, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e,
+	0x76, 0x31, 0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x48, 0x00, 0x52,
+	0x06, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x12, 0x35, 0x0a, 0x05, 0x62, 0x61, 0x74, 0x63, 0x68,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e,
+	0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x55,
+	0x70, 0x64, 0x61, 0x74, 0x65, 0x48, 0x00, 0x52, 0x05, 0x62, 0x61, 0x74, 0x63, 0x68, 0x12, 0x42,
+	0x0a, 0x0c, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67,
+	0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x6f,
+	0x75, 0x6e, 0x74, 0x48, 0x00, 0x52, 0x0b, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x6f, 0x75,
+	0x6e, 0x74, 0x12, 0x35, 0x0a, 0x07, 0x70, 0x61, 0x6c, 0x65, 0x74, 0x74, 0x65, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72,
+	0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x61, 0x6c, 0x65, 0x74, 0x74, 0x65, 0x48, 0x00,
+	0x52, 0x07, 0x70, 0x61, 0x6c, 0x65, 0x74, 0x74, 0x65, 0x12, 0x2f, 0x0a, 0x05, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69,
+	0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x72, 0x72, 0x6f,
+	0x72, 0x48, 0x00, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x38, 0x0a, 0x08, 0x63, 0x6f,
+	0x6f, 0x6c, 0x64, 0x6f, 0x77, 0x6e, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x6d,
+	0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e,
+	0x43, 0x6f, 0x6f, 0x6c, 0x64, 0x6f, 0x77, 0x6e, 0x48, 0x00, 0x52, 0x08, 0x63, 0x6f, 0x6f, 0x6c,
+	0x64, 0x6f, 0x77, 0x6e, 0x12, 0x37, 0x0a, 0x06, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x18, 0x0a,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67,
+	0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x53, 0x74,
+	0x61, 0x74, 0x65, 0x48, 0x00, 0x52, 0x06, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x12, 0x32, 0x0a,
+	0x06, 0x72, 0x6f, 0x73, 0x74, 0x65, 0x72, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e,
+	0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31,
+	0x2e, 0x52, 0x6f, 0x73, 0x74, 0x65, 0x72, 0x48, 0x00, 0x52, 0x06, 0x72, 0x6f, 0x73, 0x74, 0x65,
+	0x72, 0x12, 0x30, 0x0a, 0x04, 0x6a, 0x6f, 0x69, 0x6e, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x1a, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e,
+	0x76, 0x31, 0x2e, 0x50, 0x72, 0x65, 0x73, 0x65, 0x6e, 0x63, 0x65, 0x48, 0x00, 0x52, 0x04, 0x6a,
+	0x6f, 0x69, 0x6e, 0x12, 0x2f, 0x0a, 0x05, 0x6c, 0x65, 0x61, 0x76, 0x65, 0x18, 0x0d, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x17, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69,
+	0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x65, 0x61, 0x76, 0x65, 0x48, 0x00, 0x52, 0x05, 0x6c,
+	0x65, 0x61, 0x76, 0x65, 0x12, 0x3a, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x0e,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67,
+	0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x50, 0x6f,
+	0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x48, 0x00, 0x52, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72,
+	0x12, 0x2c, 0x0a, 0x04, 0x63, 0x68, 0x61, 0x74, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16,
+	0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76,
+	0x31, 0x2e, 0x43, 0x68, 0x61, 0x74, 0x48, 0x00, 0x52, 0x04, 0x63, 0x68, 0x61, 0x74, 0x12, 0x42,
+	0x0a, 0x0c, 0x63, 0x68, 0x61, 0x74, 0x5f, 0x68, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x18, 0x10,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67,
+	0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x68, 0x61, 0x74, 0x48, 0x69, 0x73, 0x74,
+	0x6f, 0x72, 0x79, 0x48, 0x00, 0x52, 0x0b, 0x63, 0x68, 0x61, 0x74, 0x48, 0x69, 0x73, 0x74, 0x6f,
+	0x72, 0x79, 0x12, 0x3c, 0x0a, 0x08, 0x61, 0x6e, 0x6e, 0x6f, 0x75, 0x6e, 0x63, 0x65, 0x18, 0x11,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67,
+	0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x6e, 0x6e, 0x6f, 0x75, 0x6e, 0x63, 0x65,
+	0x6d, 0x65, 0x6e, 0x74, 0x48, 0x00, 0x52, 0x08, 0x61, 0x6e, 0x6e, 0x6f, 0x75, 0x6e, 0x63, 0x65,
+	0x12, 0x32, 0x0a, 0x06, 0x66, 0x72, 0x6f, 0x7a, 0x65, 0x6e, 0x18, 0x12, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x18, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73,
+	0x2e, 0x76, 0x31, 0x2e, 0x46, 0x72, 0x6f, 0x7a, 0x65, 0x6e, 0x48, 0x00, 0x52, 0x06, 0x66, 0x72,
+	0x6f, 0x7a, 0x65, 0x6e, 0x12, 0x2f, 0x0a, 0x05, 0x72, 0x65, 0x73, 0x65, 0x74, 0x18, 0x13, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72,
+	0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x73, 0x65, 0x74, 0x48, 0x00, 0x52, 0x05,
+	0x72, 0x65, 0x73, 0x65, 0x74, 0x12, 0x3b, 0x0a, 0x09, 0x70, 0x72, 0x6f, 0x74, 0x65, 0x63, 0x74,
+	0x65, 0x64, 0x18, 0x14, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69,
+	0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x72, 0x6f, 0x74,
+	0x65, 0x63, 0x74, 0x65, 0x64, 0x48, 0x00, 0x52, 0x09, 0x70, 0x72, 0x6f, 0x74, 0x65, 0x63, 0x74,
+	0x65, 0x64, 0x12, 0x2f, 0x0a, 0x05, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x18, 0x15, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x17, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64,
+	0x73, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x6f, 0x63, 0x6b, 0x73, 0x48, 0x00, 0x52, 0x05, 0x6c, 0x6f,
+	0x63, 0x6b, 0x73, 0x12, 0x2c, 0x0a, 0x04, 0x74, 0x65, 0x61, 0x6d, 0x18, 0x16, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x16, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64,
+	0x73, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x65, 0x61, 0x6d, 0x48, 0x00, 0x52, 0x04, 0x74, 0x65, 0x61,
+	0x6d, 0x12, 0x2f, 0x0a, 0x05, 0x74, 0x65, 0x61, 0x6d, 0x73, 0x18, 0x17, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x17, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73,
+	0x2e, 0x76, 0x31, 0x2e, 0x54, 0x65, 0x61, 0x6d, 0x73, 0x48, 0x00, 0x52, 0x05, 0x74, 0x65, 0x61,
+	0x6d, 0x73, 0x12, 0x30, 0x0a, 0x04, 0x6d, 0x65, 0x74, 0x61, 0x18, 0x18, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x1a, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73,
+	0x2e, 0x76, 0x31, 0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x4d, 0x65, 0x74, 0x61, 0x48, 0x00, 0x52, 0x04,
+	0x6d, 0x65, 0x74, 0x61, 0x12, 0x32, 0x0a, 0x06, 0x63, 0x6c, 0x61, 0x69, 0x6d, 0x73, 0x18, 0x19,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67,
+	0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x73, 0x48, 0x00,
+	0x52, 0x06, 0x63, 0x6c, 0x61, 0x69, 0x6d, 0x73, 0x12, 0x3d, 0x0a, 0x07, 0x63, 0x61, 0x70, 0x74,
+	0x63, 0x68, 0x61, 0x18, 0x1a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x6d, 0x69, 0x6c, 0x6c,
+	0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x61, 0x70,
+	0x74, 0x63, 0x68, 0x61, 0x41, 0x63, 0x63, 0x65, 0x70, 0x74, 0x65, 0x64, 0x48, 0x00, 0x52, 0x07,
+	0x63, 0x61, 0x70, 0x74, 0x63, 0x68, 0x61, 0x12, 0x2c, 0x0a, 0x04, 0x74, 0x69, 0x65, 0x72, 0x18,
+	0x1b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f,
+	0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x69, 0x65, 0x72, 0x48, 0x00, 0x52,
+	0x04, 0x74, 0x69, 0x65, 0x72, 0x42, 0x05, 0x0a, 0x03, 0x6d, 0x73, 0x67, 0x22, 0x9b, 0x02, 0x0a,
+	0x04, 0x49, 0x6e, 0x69, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x73, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x61, 0x6e, 0x76, 0x61, 0x73, 0x12, 0x14, 0x0a,
+	0x05, 0x77, 0x69, 0x64, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x77, 0x69,
+	0x64, 0x74, 0x68, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63,
+	0x68, 0x75, 0x6e, 0x6b, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x63, 0x68, 0x75, 0x6e,
+	0x6b, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x61, 0x6c, 0x65, 0x74, 0x74, 0x65, 0x18, 0x05, 0x20, 0x03,
+	0x28, 0x0d, 0x52, 0x07, 0x70, 0x61, 0x6c, 0x65, 0x74, 0x74, 0x65, 0x12, 0x27, 0x0a, 0x0f, 0x70,
+	0x61, 0x6c, 0x65, 0x74, 0x74, 0x65, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x0e, 0x70, 0x61, 0x6c, 0x65, 0x74, 0x74, 0x65, 0x56, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1b, 0x0a, 0x09, 0x61, 0x6e, 0x79, 0x5f, 0x63, 0x6f, 0x6c, 0x6f,
+	0x72, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x61, 0x6e, 0x79, 0x43, 0x6f, 0x6c, 0x6f,
+	0x72, 0x12, 0x16, 0x0a, 0x06, 0x66, 0x72, 0x6f, 0x7a, 0x65, 0x6e, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x06, 0x66, 0x72, 0x6f, 0x7a, 0x65, 0x6e, 0x12, 0x23, 0x0a, 0x0d, 0x66, 0x72, 0x6f,
+	0x7a, 0x65, 0x6e, 0x5f, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0c, 0x66, 0x72, 0x6f, 0x7a, 0x65, 0x6e, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x1a,
+	0x0a, 0x08, 0x66, 0x6f, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x72, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x08, 0x66, 0x6f, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x72, 0x22, 0x57, 0x0a, 0x09, 0x49, 0x6e,
+	0x69, 0x74, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x12, 0x0c, 0x0a, 0x01, 0x78, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x01, 0x78, 0x12, 0x0c, 0x0a, 0x01, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x01, 0x79, 0x12, 0x2e, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69, 0x76, 0x65, 0x18, 0x03, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72,
+	0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x52, 0x06, 0x61, 0x63, 0x74,
+	0x69, 0x76, 0x65, 0x22, 0x20, 0x0a, 0x08, 0x49, 0x6e, 0x69, 0x74, 0x44, 0x6f, 0x6e, 0x65, 0x12,
+	0x14, 0x0a, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05,
+	0x74, 0x6f, 0x74, 0x61, 0x6c, 0x22, 0x6a, 0x0a, 0x0a, 0x43, 0x65, 0x6c, 0x6c, 0x55, 0x70, 0x64,
+	0x61, 0x74, 0x65, 0x12, 0x0c, 0x0a, 0x01, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x01,
+	0x78, 0x12, 0x0c, 0x0a, 0x01, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x01, 0x79, 0x12,
+	0x16, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69, 0x76, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x06, 0x61, 0x63, 0x74, 0x69, 0x76, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x6c, 0x6f, 0x72,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x12, 0x12, 0x0a,
+	0x04, 0x74, 0x65, 0x61, 0x6d, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x61,
+	0x6d, 0x22, 0x55, 0x0a, 0x0b, 0x42, 0x61, 0x74, 0x63, 0x68, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65,
+	0x12, 0x32, 0x0a, 0x05, 0x63, 0x65, 0x6c, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x1c, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e,
+	0x76, 0x31, 0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x52, 0x05, 0x63,
+	0x65, 0x6c, 0x6c, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65, 0x61, 0x6d, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x61, 0x6d, 0x22, 0x23, 0x0a, 0x0b, 0x43, 0x6c, 0x69, 0x65,
+	0x6e, 0x74, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x58, 0x0a,
+	0x07, 0x50, 0x61, 0x6c, 0x65, 0x74, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03,
+	0x28, 0x0d, 0x52, 0x06, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x61, 0x6e,
+	0x79, 0x5f, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x61,
+	0x6e, 0x79, 0x43, 0x6f, 0x6c, 0x6f, 0x72, 0x22, 0x2d, 0x0a, 0x05, 0x45, 0x72, 0x72, 0x6f, 0x72,
+	0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x63, 0x6f, 0x64, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x73, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x03, 0x6d, 0x73, 0x67, 0x22, 0x2d, 0x0a, 0x08, 0x43, 0x6f, 0x6f, 0x6c, 0x64, 0x6f,
+	0x77, 0x6e, 0x12, 0x21, 0x0a, 0x0c, 0x72, 0x65, 0x6d, 0x61, 0x69, 0x6e, 0x69, 0x6e, 0x67, 0x5f,
+	0x6d, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x72, 0x65, 0x6d, 0x61, 0x69, 0x6e,
+	0x69, 0x6e, 0x67, 0x4d, 0x73, 0x22, 0x2f, 0x0a, 0x0f, 0x43, 0x61, 0x70, 0x74, 0x63, 0x68, 0x61,
+	0x41, 0x63, 0x63, 0x65, 0x70, 0x74, 0x65, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x72, 0x65, 0x6d, 0x61,
+	0x69, 0x6e, 0x69, 0x6e, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x72, 0x65, 0x6d,
+	0x61, 0x69, 0x6e, 0x69, 0x6e, 0x67, 0x22, 0x8a, 0x01, 0x0a, 0x04, 0x54, 0x69, 0x65, 0x72, 0x12,
+	0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x63, 0x6f, 0x6f, 0x6c, 0x64, 0x6f, 0x77, 0x6e, 0x5f,
+	0x6d, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x63, 0x6f, 0x6f, 0x6c, 0x64, 0x6f,
+	0x77, 0x6e, 0x4d, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x71, 0x75, 0x6f, 0x74, 0x61, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x05, 0x71, 0x75, 0x6f, 0x74, 0x61, 0x12, 0x1c, 0x0a, 0x09, 0x72, 0x65,
+	0x6d, 0x61, 0x69, 0x6e, 0x69, 0x6e, 0x67, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x72,
+	0x65, 0x6d, 0x61, 0x69, 0x6e, 0x69, 0x6e, 0x67, 0x12, 0x19, 0x0a, 0x08, 0x72, 0x65, 0x73, 0x65,
+	0x74, 0x5f, 0x6d, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x72, 0x65, 0x73, 0x65,
+	0x74, 0x4d, 0x73, 0x22, 0x4a, 0x0a, 0x09, 0x50, 0x72, 0x6f, 0x74, 0x65, 0x63, 0x74, 0x65, 0x64,
+	0x12, 0x0c, 0x0a, 0x01, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x01, 0x78, 0x12, 0x0c,
+	0x0a, 0x01, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x01, 0x79, 0x12, 0x21, 0x0a, 0x0c,
+	0x72, 0x65, 0x6d, 0x61, 0x69, 0x6e, 0x69, 0x6e, 0x67, 0x5f, 0x6d, 0x73, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x0b, 0x72, 0x65, 0x6d, 0x61, 0x69, 0x6e, 0x69, 0x6e, 0x67, 0x4d, 0x73, 0x22,
+	0x66, 0x0a, 0x0c, 0x4c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x52, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x12,
+	0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12,
+	0x30, 0x0a, 0x06, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x18, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e,
+	0x76, 0x31, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x72, 0x65, 0x67, 0x69, 0x6f,
+	0x6e, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x22, 0x41, 0x0a, 0x05, 0x4c, 0x6f, 0x63, 0x6b, 0x73,
+	0x12, 0x38, 0x0a, 0x07, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x1e, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64,
+	0x73, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x52, 0x65, 0x67, 0x69, 0x6f,
+	0x6e, 0x52, 0x07, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x83, 0x01, 0x0a, 0x05, 0x43,
+	0x6c, 0x61, 0x69, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x02, 0x69, 0x64, 0x12, 0x30, 0x0a, 0x06, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67,
+	0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x52, 0x06,
+	0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x12, 0x22, 0x0a, 0x0d,
+	0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x5f, 0x61, 0x74, 0x5f, 0x6d, 0x73, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x0b, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x41, 0x74, 0x4d, 0x73,
+	0x22, 0x39, 0x0a, 0x06, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x73, 0x12, 0x2f, 0x0a, 0x06, 0x63, 0x6c,
+	0x61, 0x69, 0x6d, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x6d, 0x69, 0x6c,
+	0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6c,
+	0x61, 0x69, 0x6d, 0x52, 0x06, 0x63, 0x6c, 0x61, 0x69, 0x6d, 0x73, 0x22, 0x1a, 0x0a, 0x04, 0x54,
+	0x65, 0x61, 0x6d, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x22, 0x37, 0x0a, 0x09, 0x54, 0x65, 0x61, 0x6d, 0x53,
+	0x63, 0x6f, 0x72, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65, 0x61, 0x6d, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x61, 0x6d, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x69, 0x78, 0x65,
+	0x6c, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x70, 0x69, 0x78, 0x65, 0x6c, 0x73,
+	0x22, 0x3c, 0x0a, 0x05, 0x54, 0x65, 0x61, 0x6d, 0x73, 0x12, 0x33, 0x0a, 0x06, 0x73, 0x63, 0x6f,
+	0x72, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x6d, 0x69, 0x6c, 0x6c,
+	0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x65, 0x61,
+	0x6d, 0x53, 0x63, 0x6f, 0x72, 0x65, 0x52, 0x06, 0x73, 0x63, 0x6f, 0x72, 0x65, 0x73, 0x22, 0x4c,
+	0x0a, 0x08, 0x43, 0x65, 0x6c, 0x6c, 0x4d, 0x65, 0x74, 0x61, 0x12, 0x0c, 0x0a, 0x01, 0x78, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x01, 0x78, 0x12, 0x0c, 0x0a, 0x01, 0x79, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x01, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65, 0x78, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x78, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72,
+	0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x22, 0x6f, 0x0a, 0x0b,
+	0x52, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x30, 0x0a, 0x06, 0x72,
+	0x65, 0x67, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x6d, 0x69,
+	0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52,
+	0x65, 0x67, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x12, 0x2e, 0x0a,
+	0x06, 0x61, 0x63, 0x74, 0x69, 0x76, 0x65, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e,
+	0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31,
+	0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x76, 0x65, 0x22, 0x2e, 0x0a,
+	0x08, 0x50, 0x72, 0x65, 0x73, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x22, 0x68, 0x0a,
+	0x06, 0x52, 0x6f, 0x73, 0x74, 0x65, 0x72, 0x12, 0x2c, 0x0a, 0x03, 0x79, 0x6f, 0x75, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67,
+	0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x72, 0x65, 0x73, 0x65, 0x6e, 0x63, 0x65,
+	0x52, 0x03, 0x79, 0x6f, 0x75, 0x12, 0x30, 0x0a, 0x05, 0x75, 0x73, 0x65, 0x72, 0x73, 0x18, 0x02,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67,
+	0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x72, 0x65, 0x73, 0x65, 0x6e, 0x63, 0x65,
+	0x52, 0x05, 0x75, 0x73, 0x65, 0x72, 0x73, 0x22, 0x17, 0x0a, 0x05, 0x4c, 0x65, 0x61, 0x76, 0x65,
+	0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x02, 0x69, 0x64,
+	0x22, 0x3c, 0x0a, 0x0e, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x50, 0x6f, 0x73, 0x69, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x02,
+	0x69, 0x64, 0x12, 0x0c, 0x0a, 0x01, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x01, 0x78,
+	0x12, 0x0c, 0x0a, 0x01, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x01, 0x79, 0x22, 0x6d,
+	0x0a, 0x04, 0x43, 0x68, 0x61, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65,
+	0x78, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x78, 0x74, 0x12, 0x13,
+	0x0a, 0x05, 0x61, 0x74, 0x5f, 0x6d, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x61,
+	0x74, 0x4d, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x22, 0x41, 0x0a,
+	0x0b, 0x43, 0x68, 0x61, 0x74, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x12, 0x32, 0x0a, 0x08,
+	0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16,
+	0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76,
+	0x31, 0x2e, 0x43, 0x68, 0x61, 0x74, 0x52, 0x08, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73,
+	0x22, 0x71, 0x0a, 0x0c, 0x41, 0x6e, 0x6e, 0x6f, 0x75, 0x6e, 0x63, 0x65, 0x6d, 0x65, 0x6e, 0x74,
+	0x12, 0x12, 0x0a, 0x04, 0x74, 0x65, 0x78, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x74, 0x65, 0x78, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x65, 0x76, 0x65, 0x72, 0x69, 0x74, 0x79,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x73, 0x65, 0x76, 0x65, 0x72, 0x69, 0x74, 0x79,
+	0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x64, 0x6f, 0x77, 0x6e, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x09, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x64, 0x6f, 0x77, 0x6e, 0x12, 0x13,
+	0x0a, 0x05, 0x61, 0x74, 0x5f, 0x6d, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x61,
+	0x74, 0x4d, 0x73, 0x22, 0x3a, 0x0a, 0x06, 0x46, 0x72, 0x6f, 0x7a, 0x65, 0x6e, 0x12, 0x18, 0x0a,
+	0x07, 0x65, 0x6e, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07,
+	0x65, 0x6e, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f,
+	0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x22,
+	0x36, 0x0a, 0x05, 0x52, 0x65, 0x73, 0x65, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x72, 0x63, 0x68,
+	0x69, 0x76, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x72, 0x63, 0x68, 0x69,
+	0x76, 0x65, 0x12, 0x13, 0x0a, 0x05, 0x61, 0x74, 0x5f, 0x6d, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x04, 0x61, 0x74, 0x4d, 0x73, 0x42, 0x31, 0x5a, 0x2f, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72,
+	0x69, 0x64, 0x73, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72,
+	0x6e, 0x61, 0x6c, 0x2f, 0x67, 0x72, 0x69, 0x64, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
+}
+
+var (
+	file_million_grids_v1_grid_proto_rawDescOnce sync.Once
+	file_million_grids_v1_grid_proto_rawDescData = file_million_grids_v1_grid_proto_rawDesc
+)
+
+func file_million_grids_v1_grid_proto_rawDescGZIP() []byte {
+	file_million_grids_v1_grid_proto_rawDescOnce.Do(func() {
+		file_million_grids_v1_grid_proto_rawDescData = protoimpl.X.CompressGZIP(file_million_grids_v1_grid_proto_rawDescData)
+	})
+	return file_million_grids_v1_grid_proto_rawDescData
+}
+
+var file_million_grids_v1_grid_proto_msgTypes = make([]protoimpl.MessageInfo, 43)
+var file_million_grids_v1_grid_proto_goTypes = []any{
+	(*ClientMessage)(nil),   // 0: million_grids.v1.ClientMessage
+	(*CellOp)(nil),          // 1: million_grids.v1.CellOp
+	(*Paint)(nil),           // 2: million_grids.v1.Paint
+	(*Cursor)(nil),          // 3: million_grids.v1.Cursor
+	(*ChatPost)(nil),        // 4: million_grids.v1.ChatPost
+	(*Channel)(nil),         // 5: million_grids.v1.Channel
+	(*JoinTeam)(nil),        // 6: million_grids.v1.JoinTeam
+	(*Unsubscribe)(nil),     // 7: million_grids.v1.Unsubscribe
+	(*Undo)(nil),            // 8: million_grids.v1.Undo
+	(*CaptchaSolution)(nil), // 9: million_grids.v1.CaptchaSolution
+	(*Region)(nil),          // 10: million_grids.v1.Region
+	(*Cell)(nil),            // 11: million_grids.v1.Cell
+	(*ServerMessage)(nil),   // 12: million_grids.v1.ServerMessage
+	(*Init)(nil),            // 13: million_grids.v1.Init
+	(*InitChunk)(nil),       // 14: million_grids.v1.InitChunk
+	(*InitDone)(nil),        // 15: million_grids.v1.InitDone
+	(*CellUpdate)(nil),      // 16: million_grids.v1.CellUpdate
+	(*BatchUpdate)(nil),     // 17: million_grids.v1.BatchUpdate
+	(*ClientCount)(nil),     // 18: million_grids.v1.ClientCount
+	(*Palette)(nil),         // 19: million_grids.v1.Palette
+	(*Error)(nil),           // 20: million_grids.v1.Error
+	(*Cooldown)(nil),        // 21: million_grids.v1.Cooldown
+	(*CaptchaAccepted)(nil), // 22: million_grids.v1.CaptchaAccepted
+	(*Tier)(nil),            // 23: million_grids.v1.Tier
+	(*Protected)(nil),       // 24: million_grids.v1.Protected
+	(*LockedRegion)(nil),    // 25: million_grids.v1.LockedRegion
+	(*Locks)(nil),           // 26: million_grids.v1.Locks
+	(*Claim)(nil),           // 27: million_grids.v1.Claim
+	(*Claims)(nil),          // 28: million_grids.v1.Claims
+	(*Team)(nil),            // 29: million_grids.v1.Team
+	(*TeamScore)(nil),       // 30: million_grids.v1.TeamScore
+	(*Teams)(nil),           // 31: million_grids.v1.Teams
+	(*CellMeta)(nil),        // 32: million_grids.v1.CellMeta
+	(*RegionState)(nil),     // 33: million_grids.v1.RegionState
+	(*Presence)(nil),        // 34: million_grids.v1.Presence
+	(*Roster)(nil),          // 35: million_grids.v1.Roster
+	(*Leave)(nil),           // 36: million_grids.v1.Leave
+	(*CursorPosition)(nil),  // 37: million_grids.v1.CursorPosition
+	(*Chat)(nil),            // 38: million_grids.v1.Chat
+	(*ChatHistory)(nil),     // 39: million_grids.v1.ChatHistory
+	(*Announcement)(nil),    // 40: million_grids.v1.Announcement
+	(*Frozen)(nil),          // 41: million_grids.v1.Frozen
+	(*Reset)(nil),           // 42: million_grids.v1.Reset
+}
+var file_million_grids_v1_grid_proto_depIdxs = []int32{
+	1,  // 0: million_grids.v1.ClientMessage.toggle:type_name -> million_grids.v1.CellOp
+	1,  // 1: million_grids.v1.ClientMessage.set:type_name -> million_grids.v1.CellOp
+	1,  // 2: million_grids.v1.ClientMessage.clear:type_name -> million_grids.v1.CellOp
+	2,  // 3: million_grids.v1.ClientMessage.paint:type_name -> million_grids.v1.Paint
+	10, // 4: million_grids.v1.ClientMessage.subscribe:type_name -> million_grids.v1.Region
+	7,  // 5: million_grids.v1.ClientMessage.unsubscribe:type_name -> million_grids.v1.Unsubscribe
+	3,  // 6: million_grids.v1.ClientMessage.cursor:type_name -> million_grids.v1.Cursor
+	4,  // 7: million_grids.v1.ClientMessage.chat:type_name -> million_grids.v1.ChatPost
+	5,  // 8: million_grids.v1.ClientMessage.join_channel:type_name -> million_grids.v1.Channel
+	5,  // 9: million_grids.v1.ClientMessage.leave_channel:type_name -> million_grids.v1.Channel
+	6,  // 10: million_grids.v1.ClientMessage.team:type_name -> million_grids.v1.JoinTeam
+	8,  // 11: million_grids.v1.ClientMessage.undo:type_name -> million_grids.v1.Undo
+	9,  // 12: million_grids.v1.ClientMessage.captcha:type_name -> million_grids.v1.CaptchaSolution
+	11, // 13: million_grids.v1.Paint.cells:type_name -> million_grids.v1.Cell
+	13, // 14: million_grids.v1.ServerMessage.init:type_name -> million_grids.v1.Init
+	14, // 15: million_grids.v1.ServerMessage.init_chunk:type_name -> million_grids.v1.InitChunk
+	15, // 16: million_grids.v1.ServerMessage.init_done:type_name -> million_grids.v1.InitDone
+	16, // 17: million_grids.v1.ServerMessage.update:type_name -> million_grids.v1.CellUpdate
+	17, // 18: million_grids.v1.ServerMessage.batch:type_name -> million_grids.v1.BatchUpdate
+	18, // 19: million_grids.v1.ServerMessage.client_count:type_name -> million_grids.v1.ClientCount
+	19, // 20: million_grids.v1.ServerMessage.palette:type_name -> million_grids.v1.Palette
+	20, // 21: million_grids.v1.ServerMessage.error:type_name -> million_grids.v1.Error
+	21, // 22: million_grids.v1.ServerMessage.cooldown:type_name -> million_grids.v1.Cooldown
+	33, // 23: million_grids.v1.ServerMessage.region:type_name -> million_grids.v1.RegionState
+	35, // 24: million_grids.v1.ServerMessage.roster:type_name -> million_grids.v1.Roster
+	34, // 25: million_grids.v1.ServerMessage.join:type_name -> million_grids.v1.Presence
+	36, // 26: million_grids.v1.ServerMessage.leave:type_name -> million_grids.v1.Leave
+	37, // 27: million_grids.v1.ServerMessage.cursor:type_name -> million_grids.v1.CursorPosition
+	38, // 28: million_grids.v1.ServerMessage.chat:type_name -> million_grids.v1.Chat
+	39, // 29: million_grids.v1.ServerMessage.chat_history:type_name -> million_grids.v1.ChatHistory
+	40, // 30: million_grids.v1.ServerMessage.announce:type_name -> million_grids.v1.Announcement
+	41, // 31: million_grids.v1.ServerMessage.frozen:type_name -> million_grids.v1.Frozen
+	42, // 32: million_grids.v1.ServerMessage.reset:type_name -> million_grids.v1.Reset
+	24, // 33: million_grids.v1.ServerMessage.protected:type_name -> million_grids.v1.Protected
+	26, // 34: million_grids.v1.ServerMessage.locks:type_name -> million_grids.v1.Locks
+	29, // 35: million_grids.v1.ServerMessage.team:type_name -> million_grids.v1.Team
+	31, // 36: million_grids.v1.ServerMessage.teams:type_name -> million_grids.v1.Teams
+	32, // 37: million_grids.v1.ServerMessage.meta:type_name -> million_grids.v1.CellMeta
+	28, // 38: million_grids.v1.ServerMessage.claims:type_name -> million_grids.v1.Claims
+	22, // 39: million_grids.v1.ServerMessage.captcha:type_name -> million_grids.v1.CaptchaAccepted
+	23, // 40: million_grids.v1.ServerMessage.tier:type_name -> million_grids.v1.Tier
+	11, // 41: million_grids.v1.InitChunk.active:type_name -> million_grids.v1.Cell
+	16, // 42: million_grids.v1.BatchUpdate.cells:type_name -> million_grids.v1.CellUpdate
+	10, // 43: million_grids.v1.LockedRegion.region:type_name -> million_grids.v1.Region
+	25, // 44: million_grids.v1.Locks.regions:type_name -> million_grids.v1.LockedRegion
+	10, // 45: million_grids.v1.Claim.region:type_name -> million_grids.v1.Region
+	27, // 46: million_grids.v1.Claims.claims:type_name -> million_grids.v1.Claim
+	30, // 47: million_grids.v1.Teams.scores:type_name -> million_grids.v1.TeamScore
+	10, // 48: million_grids.v1.RegionState.region:type_name -> million_grids.v1.Region
+	11, // 49: million_grids.v1.RegionState.active:type_name -> million_grids.v1.Cell
+	34, // 50: million_grids.v1.Roster.you:type_name -> million_grids.v1.Presence
+	34, // 51: million_grids.v1.Roster.users:type_name -> million_grids.v1.Presence
+	38, // 52: million_grids.v1.ChatHistory.messages:type_name -> million_grids.v1.Chat
+	53, // [53:53] is the sub-list for method output_type
+	53, // [53:53] is the sub-list for method input_type
+	53, // [53:53] is the sub-list for extension type_name
+	53, // [53:53] is the sub-list for extension extendee
+	0,  // [0:53] is the sub-list for field type_name
+}
+
+func init() { file_million_grids_v1_grid_proto_init() }
+func file_million_grids_v1_grid_proto_init() {
+	if File_million_grids_v1_grid_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_million_grids_v1_grid_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*ClientMessage); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*CellOp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*Paint); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*Cursor); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*ChatPost); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*Channel); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*JoinTeam); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*Unsubscribe); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[8].Exporter = func(v any, i int) any {
+			switch v := v.(*Undo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[9].Exporter = func(v any, i int) any {
+			switch v := v.(*CaptchaSolution); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[10].Exporter = func(v any, i int) any {
+			switch v := v.(*Region); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[11].Exporter = func(v any, i int) any {
+			switch v := v.(*Cell); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[12].Exporter = func(v any, i int) any {
+			switch v := v.(*ServerMessage); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[13].Exporter = func(v any, i int) any {
+			switch v := v.(*Init); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[14].Exporter = func(v any, i int) any {
+			switch v := v.(*InitChunk); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[15].Exporter = func(v any, i int) any {
+			switch v := v.(*InitDone); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[16].Exporter = func(v any, i int) any {
+			switch v := v.(*CellUpdate); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[17].Exporter = func(v any, i int) any {
+			switch v := v.(*BatchUpdate); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[18].Exporter = func(v any, i int) any {
+			switch v := v.(*ClientCount); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[19].Exporter = func(v any, i int) any {
+			switch v := v.(*Palette); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[20].Exporter = func(v any, i int) any {
+			switch v := v.(*Error); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[21].Exporter = func(v any, i int) any {
+			switch v := v.(*Cooldown); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[22].Exporter = func(v any, i int) any {
+			switch v := v.(*CaptchaAccepted); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[23].Exporter = func(v any, i int) any {
+			switch v := v.(*Tier); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[24].Exporter = func(v any, i int) any {
+			switch v := v.(*Protected); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[25].Exporter = func(v any, i int) any {
+			switch v := v.(*LockedRegion); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[26].Exporter = func(v any, i int) any {
+			switch v := v.(*Locks); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[27].Exporter = func(v any, i int) any {
+			switch v := v.(*Claim); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[28].Exporter = func(v any, i int) any {
+			switch v := v.(*Claims); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[29].Exporter = func(v any, i int) any {
+			switch v := v.(*Team); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[30].Exporter = func(v any, i int) any {
+			switch v := v.(*TeamScore); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[31].Exporter = func(v any, i int) any {
+			switch v := v.(*Teams); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[32].Exporter = func(v any, i int) any {
+			switch v := v.(*CellMeta); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[33].Exporter = func(v any, i int) any {
+			switch v := v.(*RegionState); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[34].Exporter = func(v any, i int) any {
+			switch v := v.(*Presence); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[35].Exporter = func(v any, i int) any {
+			switch v := v.(*Roster); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[36].Exporter = func(v any, i int) any {
+			switch v := v.(*Leave); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[37].Exporter = func(v any, i int) any {
+			switch v := v.(*CursorPosition); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[38].Exporter = func(v any, i int) any {
+			switch v := v.(*Chat); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[39].Exporter = func(v any, i int) any {
+			switch v := v.(*ChatHistory); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[40].Exporter = func(v any, i int) any {
+			switch v := v.(*Announcement); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[41].Exporter = func(v any, i int) any {
+			switch v := v.(*Frozen); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_proto_msgTypes[42].Exporter = func(v any, i int) any {
+			switch v := v.(*Reset); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_million_grids_v1_grid_proto_msgTypes[0].OneofWrappers = []any{
+		(*ClientMessage_Toggle)(nil),
+		(*ClientMessage_Set)(nil),
+		(*ClientMessage_Clear)(nil),
+		(*ClientMessage_Paint)(nil),
+		(*ClientMessage_Subscribe)(nil),
+		(*ClientMessage_Unsubscribe)(nil),
+		(*ClientMessage_Cursor)(nil),
+		(*ClientMessage_Chat)(nil),
+		(*ClientMessage_JoinChannel)(nil),
+		(*ClientMessage_LeaveChannel)(nil),
+		(*ClientMessage_Team)(nil),
+		(*ClientMessage_Undo)(nil),
+		(*ClientMessage_Captcha)(nil),
+	}
+	file_million_grids_v1_grid_proto_msgTypes[1].OneofWrappers = []any{}
+	file_million_grids_v1_grid_proto_msgTypes[12].OneofWrappers = []any{
+		(*ServerMessage_Init)(nil),
+		(*ServerMessage_InitChunk)(nil),
+		(*ServerMessage_InitDone)(nil),
+		(*ServerMessage_Update)(nil),
+		(*ServerMessage_Batch)(nil),
+		(*ServerMessage_ClientCount)(nil),
+		(*ServerMessage_Palette)(nil),
+		(*ServerMessage_Error)(nil),
+		(*ServerMessage_Cooldown)(nil),
+		(*ServerMessage_Region)(nil),
+		(*ServerMessage_Roster)(nil),
+		(*ServerMessage_Join)(nil),
+		(*ServerMessage_Leave)(nil),
+		(*ServerMessage_Cursor)(nil),
+		(*ServerMessage_Chat)(nil),
+		(*ServerMessage_ChatHistory)(nil),
+		(*ServerMessage_Announce)(nil),
+		(*ServerMessage_Frozen)(nil),
+		(*ServerMessage_Reset_)(nil),
+		(*ServerMessage_Protected)(nil),
+		(*ServerMessage_Locks)(nil),
+		(*ServerMessage_Team)(nil),
+		(*ServerMessage_Teams)(nil),
+		(*ServerMessage_Meta)(nil),
+		(*ServerMessage_Claims)(nil),
+		(*ServerMessage_Captcha)(nil),
+		(*ServerMessage_Tier)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_million_grids_v1_grid_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   43,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_million_grids_v1_grid_proto_goTypes,
+		DependencyIndexes: file_million_grids_v1_grid_proto_depIdxs,
+		MessageInfos:      file_million_grids_v1_grid_proto_msgTypes,
+	}.Build()
+	File_million_grids_v1_grid_proto = out.File
+	file_million_grids_v1_grid_proto_rawDesc = nil
+	file_million_grids_v1_grid_proto_goTypes = nil
+	file_million_grids_v1_grid_proto_depIdxs = nil
+}
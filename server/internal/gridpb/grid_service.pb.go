@@ -0,0 +1,403 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: million_grids/v1/grid_service.proto
+
+// gRPC API for backend integrations and bots, sharing the canvases served
+// over /ws. Authenticated calls send the JWT as "authorization: Bearer <token>"
+// metadata.
+
+package gridpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StreamUpdatesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Canvas name (empty selects the default canvas)
+	Canvas string `protobuf:"bytes,1,opt,name=canvas,proto3" json:"canvas,omitempty"`
+	// Only stream updates inside the region (unset streams the whole grid)
+	Region *Region `protobuf:"bytes,2,opt,name=region,proto3,oneof" json:"region,omitempty"`
+}
+
+func (x *StreamUpdatesRequest) Reset() {
+	*x = StreamUpdatesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_service_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamUpdatesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamUpdatesRequest) ProtoMessage() {}
+
+func (x *StreamUpdatesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_service_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamUpdatesRequest.ProtoReflect.Descriptor instead.
+func (*StreamUpdatesRequest) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_service_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StreamUpdatesRequest) GetCanvas() string {
+	if x != nil {
+		return x.Canvas
+	}
+	return ""
+}
+
+func (x *StreamUpdatesRequest) GetRegion() *Region {
+	if x != nil {
+		return x.Region
+	}
+	return nil
+}
+
+type PaintPixelRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Canvas name (empty selects the default canvas)
+	Canvas string `protobuf:"bytes,1,opt,name=canvas,proto3" json:"canvas,omitempty"`
+	// Types that are assignable to Op:
+	//	*PaintPixelRequest_Toggle
+	//	*PaintPixelRequest_Set
+	//	*PaintPixelRequest_Clear
+	Op isPaintPixelRequest_Op `protobuf_oneof:"op"`
+	// Solved CAPTCHA token, when the server challenges unauthenticated clients
+	Captcha string `protobuf:"bytes,5,opt,name=captcha,proto3" json:"captcha,omitempty"`
+}
+
+func (x *PaintPixelRequest) Reset() {
+	*x = PaintPixelRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_service_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PaintPixelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaintPixelRequest) ProtoMessage() {}
+
+func (x *PaintPixelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_service_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaintPixelRequest.ProtoReflect.Descriptor instead.
+func (*PaintPixelRequest) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_service_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PaintPixelRequest) GetCanvas() string {
+	if x != nil {
+		return x.Canvas
+	}
+	return ""
+}
+
+func (m *PaintPixelRequest) GetOp() isPaintPixelRequest_Op {
+	if m != nil {
+		return m.Op
+	}
+	return nil
+}
+
+func (x *PaintPixelRequest) GetToggle() *CellOp {
+	if x, ok := x.GetOp().(*PaintPixelRequest_Toggle); ok {
+		return x.Toggle
+	}
+	return nil
+}
+
+func (x *PaintPixelRequest) GetSet() *CellOp {
+	if x, ok := x.GetOp().(*PaintPixelRequest_Set); ok {
+		return x.Set
+	}
+	return nil
+}
+
+func (x *PaintPixelRequest) GetClear() *CellOp {
+	if x, ok := x.GetOp().(*PaintPixelRequest_Clear); ok {
+		return x.Clear
+	}
+	return nil
+}
+
+func (x *PaintPixelRequest) GetCaptcha() string {
+	if x != nil {
+		return x.Captcha
+	}
+	return ""
+}
+
+type isPaintPixelRequest_Op interface {
+	isPaintPixelRequest_Op()
+}
+
+type PaintPixelRequest_Toggle struct {
+	Toggle *CellOp `protobuf:"bytes,2,opt,name=toggle,proto3,oneof"`
+}
+
+type PaintPixelRequest_Set struct {
+	Set *CellOp `protobuf:"bytes,3,opt,name=set,proto3,oneof"`
+}
+
+type PaintPixelRequest_Clear struct {
+	Clear *CellOp `protobuf:"bytes,4,opt,name=clear,proto3,oneof"`
+}
+
+func (*PaintPixelRequest_Toggle) isPaintPixelRequest_Op() {}
+
+func (*PaintPixelRequest_Set) isPaintPixelRequest_Op() {}
+
+func (*PaintPixelRequest_Clear) isPaintPixelRequest_Op() {}
+
+type PaintPixelResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// State of the cell after the operation
+	Cell *CellUpdate `protobuf:"bytes,1,opt,name=cell,proto3" json:"cell,omitempty"`
+}
+
+func (x *PaintPixelResponse) Reset() {
+	*x = PaintPixelResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_million_grids_v1_grid_service_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PaintPixelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaintPixelResponse) ProtoMessage() {}
+
+func (x *PaintPixelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_million_grids_v1_grid_service_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaintPixelResponse.ProtoReflect.Descriptor instead.
+func (*PaintPixelResponse) Descriptor() ([]byte, []int) {
+	return file_million_grids_v1_grid_service_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PaintPixelResponse) GetCell() *CellUpdate {
+	if x != nil {
+		return x.Cell
+	}
+	return nil
+}
+
+var File_million_grids_v1_grid_service_proto protoreflect.FileDescriptor
+
+var file_million_grids_v1_grid_service_proto_rawDesc = []byte{
+	0x0a, 0x23, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2f,
+	0x76, 0x31, 0x2f, 0x67, 0x72, 0x69, 0x64, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x10, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67,
+	0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x1a, 0x1b, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e,
+	0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2f, 0x76, 0x31, 0x2f, 0x67, 0x72, 0x69, 0x64, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x22, 0x70, 0x0a, 0x14, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x55, 0x70,
+	0x64, 0x61, 0x74, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06,
+	0x63, 0x61, 0x6e, 0x76, 0x61, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x61,
+	0x6e, 0x76, 0x61, 0x73, 0x12, 0x35, 0x0a, 0x06, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67,
+	0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x48, 0x00,
+	0x52, 0x06, 0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x88, 0x01, 0x01, 0x42, 0x09, 0x0a, 0x07, 0x5f,
+	0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x22, 0xdf, 0x01, 0x0a, 0x11, 0x50, 0x61, 0x69, 0x6e, 0x74,
+	0x50, 0x69, 0x78, 0x65, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06,
+	0x63, 0x61, 0x6e, 0x76, 0x61, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x61,
+	0x6e, 0x76, 0x61, 0x73, 0x12, 0x32, 0x0a, 0x06, 0x74, 0x6f, 0x67, 0x67, 0x6c, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67,
+	0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x4f, 0x70, 0x48, 0x00,
+	0x52, 0x06, 0x74, 0x6f, 0x67, 0x67, 0x6c, 0x65, 0x12, 0x2c, 0x0a, 0x03, 0x73, 0x65, 0x74, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f,
+	0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x4f, 0x70, 0x48,
+	0x00, 0x52, 0x03, 0x73, 0x65, 0x74, 0x12, 0x30, 0x0a, 0x05, 0x63, 0x6c, 0x65, 0x61, 0x72, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f,
+	0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x4f, 0x70, 0x48,
+	0x00, 0x52, 0x05, 0x63, 0x6c, 0x65, 0x61, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x61, 0x70, 0x74,
+	0x63, 0x68, 0x61, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x61, 0x70, 0x74, 0x63,
+	0x68, 0x61, 0x42, 0x04, 0x0a, 0x02, 0x6f, 0x70, 0x22, 0x46, 0x0a, 0x12, 0x50, 0x61, 0x69, 0x6e,
+	0x74, 0x50, 0x69, 0x78, 0x65, 0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x30,
+	0x0a, 0x04, 0x63, 0x65, 0x6c, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x6d,
+	0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e,
+	0x43, 0x65, 0x6c, 0x6c, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x52, 0x04, 0x63, 0x65, 0x6c, 0x6c,
+	0x32, 0xbb, 0x01, 0x0a, 0x04, 0x47, 0x72, 0x69, 0x64, 0x12, 0x5a, 0x0a, 0x0d, 0x53, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x73, 0x12, 0x26, 0x2e, 0x6d, 0x69, 0x6c,
+	0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69,
+	0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x4d, 0x65, 0x73, 0x73,
+	0x61, 0x67, 0x65, 0x30, 0x01, 0x12, 0x57, 0x0a, 0x0a, 0x50, 0x61, 0x69, 0x6e, 0x74, 0x50, 0x69,
+	0x78, 0x65, 0x6c, 0x12, 0x23, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72,
+	0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x61, 0x69, 0x6e, 0x74, 0x50, 0x69, 0x78, 0x65,
+	0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x24, 0x2e, 0x6d, 0x69, 0x6c, 0x6c, 0x69,
+	0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x61, 0x69, 0x6e,
+	0x74, 0x50, 0x69, 0x78, 0x65, 0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x31,
+	0x5a, 0x2f, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6d, 0x69, 0x6c,
+	0x6c, 0x69, 0x6f, 0x6e, 0x5f, 0x67, 0x72, 0x69, 0x64, 0x73, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x67, 0x72, 0x69, 0x64, 0x70,
+	0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_million_grids_v1_grid_service_proto_rawDescOnce sync.Once
+	file_million_grids_v1_grid_service_proto_rawDescData = file_million_grids_v1_grid_service_proto_rawDesc
+)
+
+func file_million_grids_v1_grid_service_proto_rawDescGZIP() []byte {
+	file_million_grids_v1_grid_service_proto_rawDescOnce.Do(func() {
+		file_million_grids_v1_grid_service_proto_rawDescData = protoimpl.X.CompressGZIP(file_million_grids_v1_grid_service_proto_rawDescData)
+	})
+	return file_million_grids_v1_grid_service_proto_rawDescData
+}
+
+var file_million_grids_v1_grid_service_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_million_grids_v1_grid_service_proto_goTypes = []any{
+	(*StreamUpdatesRequest)(nil), // 0: million_grids.v1.StreamUpdatesRequest
+	(*PaintPixelRequest)(nil),    // 1: million_grids.v1.PaintPixelRequest
+	(*PaintPixelResponse)(nil),   // 2: million_grids.v1.PaintPixelResponse
+	(*Region)(nil),               // 3: million_grids.v1.Region
+	(*CellOp)(nil),               // 4: million_grids.v1.CellOp
+	(*CellUpdate)(nil),           // 5: million_grids.v1.CellUpdate
+	(*ServerMessage)(nil),        // 6: million_grids.v1.ServerMessage
+}
+var file_million_grids_v1_grid_service_proto_depIdxs = []int32{
+	3, // 0: million_grids.v1.StreamUpdatesRequest.region:type_name -> million_grids.v1.Region
+	4, // 1: million_grids.v1.PaintPixelRequest.toggle:type_name -> million_grids.v1.CellOp
+	4, // 2: million_grids.v1.PaintPixelRequest.set:type_name -> million_grids.v1.CellOp
+	4, // 3: million_grids.v1.PaintPixelRequest.clear:type_name -> million_grids.v1.CellOp
+	5, // 4: million_grids.v1.PaintPixelResponse.cell:type_name -> million_grids.v1.CellUpdate
+	0, // 5: million_grids.v1.Grid.StreamUpdates:input_type -> million_grids.v1.StreamUpdatesRequest
+	1, // 6: million_grids.v1.Grid.PaintPixel:input_type -> million_grids.v1.PaintPixelRequest
+	6, // 7: million_grids.v1.Grid.StreamUpdates:output_type -> million_grids.v1.ServerMessage
+	2, // 8: million_grids.v1.Grid.PaintPixel:output_type -> million_grids.v1.PaintPixelResponse
+	7, // [7:9] is the sub-list for method output_type
+	5, // [5:7] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_million_grids_v1_grid_service_proto_init() }
+func file_million_grids_v1_grid_service_proto_init() {
+	if File_million_grids_v1_grid_service_proto != nil {
+		return
+	}
+	file_million_grids_v1_grid_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_million_grids_v1_grid_service_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*StreamUpdatesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_service_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*PaintPixelRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_million_grids_v1_grid_service_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*PaintPixelResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_million_grids_v1_grid_service_proto_msgTypes[0].OneofWrappers = []any{}
+	file_million_grids_v1_grid_service_proto_msgTypes[1].OneofWrappers = []any{
+		(*PaintPixelRequest_Toggle)(nil),
+		(*PaintPixelRequest_Set)(nil),
+		(*PaintPixelRequest_Clear)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_million_grids_v1_grid_service_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_million_grids_v1_grid_service_proto_goTypes,
+		DependencyIndexes: file_million_grids_v1_grid_service_proto_depIdxs,
+		MessageInfos:      file_million_grids_v1_grid_service_proto_msgTypes,
+	}.Build()
+	File_million_grids_v1_grid_service_proto = out.File
+	file_million_grids_v1_grid_service_proto_rawDesc = nil
+	file_million_grids_v1_grid_service_proto_goTypes = nil
+	file_million_grids_v1_grid_service_proto_depIdxs = nil
+}
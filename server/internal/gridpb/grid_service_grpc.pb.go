@@ -0,0 +1,190 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: million_grids/v1/grid_service.proto
+
+// gRPC API for backend integrations and bots, sharing the canvases served
+// over /ws. Authenticated calls send the JWT as "authorization: Bearer <token>"
+// metadata.
+
+package gridpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	Grid_StreamUpdates_FullMethodName = "/million_grids.v1.Grid/StreamUpdates"
+	Grid_PaintPixel_FullMethodName    = "/million_grids.v1.Grid/PaintPixel"
+)
+
+// GridClient is the client API for Grid service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GridClient interface {
+	// StreamUpdates sends the initial state of the canvas (init, init_chunk and
+	// init_done messages) followed by every update, palette change and client
+	// count, exactly as a WebSocket client receives them
+	StreamUpdates(ctx context.Context, in *StreamUpdatesRequest, opts ...grpc.CallOption) (Grid_StreamUpdatesClient, error)
+	// PaintPixel applies a single-cell operation, subject to the same
+	// validation, bans and cooldown as WebSocket placements
+	PaintPixel(ctx context.Context, in *PaintPixelRequest, opts ...grpc.CallOption) (*PaintPixelResponse, error)
+}
+
+type gridClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGridClient(cc grpc.ClientConnInterface) GridClient {
+	return &gridClient{cc}
+}
+
+func (c *gridClient) StreamUpdates(ctx context.Context, in *StreamUpdatesRequest, opts ...grpc.CallOption) (Grid_StreamUpdatesClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Grid_ServiceDesc.Streams[0], Grid_StreamUpdates_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gridStreamUpdatesClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Grid_StreamUpdatesClient interface {
+	Recv() (*ServerMessage, error)
+	grpc.ClientStream
+}
+
+type gridStreamUpdatesClient struct {
+	grpc.ClientStream
+}
+
+func (x *gridStreamUpdatesClient) Recv() (*ServerMessage, error) {
+	m := new(ServerMessage)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *gridClient) PaintPixel(ctx context.Context, in *PaintPixelRequest, opts ...grpc.CallOption) (*PaintPixelResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PaintPixelResponse)
+	err := c.cc.Invoke(ctx, Grid_PaintPixel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GridServer is the server API for Grid service.
+// All implementations must embed UnimplementedGridServer
+// for forward compatibility
+type GridServer interface {
+	// StreamUpdates sends the initial state of the canvas (init, init_chunk and
+	// init_done messages) followed by every update, palette change and client
+	// count, exactly as a WebSocket client receives them
+	StreamUpdates(*StreamUpdatesRequest, Grid_StreamUpdatesServer) error
+	// PaintPixel applies a single-cell operation, subject to the same
+	// validation, bans and cooldown as WebSocket placements
+	PaintPixel(context.Context, *PaintPixelRequest) (*PaintPixelResponse, error)
+	mustEmbedUnimplementedGridServer()
+}
+
+// UnimplementedGridServer must be embedded to have forward compatible implementations.
+type UnimplementedGridServer struct {
+}
+
+func (UnimplementedGridServer) StreamUpdates(*StreamUpdatesRequest, Grid_StreamUpdatesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamUpdates not implemented")
+}
+func (UnimplementedGridServer) PaintPixel(context.Context, *PaintPixelRequest) (*PaintPixelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PaintPixel not implemented")
+}
+func (UnimplementedGridServer) mustEmbedUnimplementedGridServer() {}
+
+// UnsafeGridServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GridServer will
+// result in compilation errors.
+type UnsafeGridServer interface {
+	mustEmbedUnimplementedGridServer()
+}
+
+func RegisterGridServer(s grpc.ServiceRegistrar, srv GridServer) {
+	s.RegisterService(&Grid_ServiceDesc, srv)
+}
+
+func _Grid_StreamUpdates_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamUpdatesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GridServer).StreamUpdates(m, &gridStreamUpdatesServer{ServerStream: stream})
+}
+
+type Grid_StreamUpdatesServer interface {
+	Send(*ServerMessage) error
+	grpc.ServerStream
+}
+
+type gridStreamUpdatesServer struct {
+	grpc.ServerStream
+}
+
+func (x *gridStreamUpdatesServer) Send(m *ServerMessage) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Grid_PaintPixel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaintPixelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GridServer).PaintPixel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Grid_PaintPixel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GridServer).PaintPixel(ctx, req.(*PaintPixelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Grid_ServiceDesc is the grpc.ServiceDesc for Grid service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Grid_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "million_grids.v1.Grid",
+	HandlerType: (*GridServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "PaintPixel",
+			Handler:    _Grid_PaintPixel_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamUpdates",
+			Handler:       _Grid_StreamUpdates_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "million_grids/v1/grid_service.proto",
+}
@@ -0,0 +1,51 @@
+package leaderboard
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+)
+
+// Number of history records counted per transaction
+const aggregateBatchSize = 1000
+
+// Aggregator periodically counts the new pixel history into the per-hour
+// contributor totals the leaderboard is read from
+type Aggregator struct {
+	interval time.Duration
+}
+
+// NewAggregator creates an aggregator running every interval
+func NewAggregator(interval time.Duration) *Aggregator {
+	return &Aggregator{interval: interval}
+}
+
+// Run aggregates the history every interval
+func (a *Aggregator) Run() {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		a.Aggregate()
+	}
+}
+
+// Aggregate counts all the history recorded since the last run, logging failures
+func (a *Aggregator) Aggregate() {
+	total := 0
+	for {
+		n, err := db.AggregateContributions(aggregateBatchSize)
+		if err != nil {
+			slog.Error("Failed to aggregate leaderboard", "err", err)
+			return
+		}
+		total += n
+		if n < aggregateBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		slog.Debug("Aggregated leaderboard", "records", total)
+	}
+}
@@ -0,0 +1,161 @@
+// Package metrics exports the server's Prometheus metrics, and summarizes
+// its histograms for the admin endpoints.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Namespace of the server's metrics
+const Namespace = "million_grids"
+
+var (
+	// BroadcastLatency is the time from the receipt of a cell change to its
+	// update being queued on the last of its recipients, by canvas
+	BroadcastLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "broadcast",
+		Name:      "latency_seconds",
+		Help:      "Time from the receipt of a cell change to its update being queued on the last recipient.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 16),
+	}, []string{"canvas"})
+
+	// BroadcastRecipients is the number of clients each cell update is
+	// queued for, by canvas
+	BroadcastRecipients = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "broadcast",
+		Name:      "recipients",
+		Help:      "Clients each cell update is queued for.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 10),
+	}, []string{"canvas"})
+
+	// BroadcastDropped counts the broadcasts not queued on a client, by
+	// canvas and reason: "bandwidth" for clients over their bandwidth cap,
+	// "full" for clients whose send lane was full
+	BroadcastDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "broadcast",
+		Name:      "dropped_total",
+		Help:      "Broadcasts not queued on a client, by reason.",
+	}, []string{"canvas", "reason"})
+)
+
+var (
+	// MessagesReceived counts the messages received over WebSocket, by
+	// canvas and type. Messages without a known type count as invalid.
+	MessagesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "ws",
+		Name:      "messages_total",
+		Help:      "Messages received over WebSocket, by type.",
+	}, []string{"canvas", "type"})
+
+	// InvalidMessages counts the WebSocket messages that couldn't be parsed,
+	// by canvas and error code: malformed JSON or protobuf, unknown types and
+	// fields, or fields of the wrong type, which point at client bugs
+	InvalidMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "ws",
+		Name:      "invalid_messages_total",
+		Help:      "WebSocket messages that couldn't be parsed, by error code.",
+	}, []string{"canvas", "code"})
+
+	// Rejections counts the well-formed requests refused, by canvas, protocol
+	// ("ws", "http" or "grpc") and error code: cells out of bounds,
+	// disallowed colors, rate limits, cooldowns, bans and the like, which
+	// mostly point at abuse
+	Rejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "rejections_total",
+		Help:      "Requests refused by validation, moderation or rate limits, by protocol and error code.",
+	}, []string{"canvas", "protocol", "code"})
+
+	// DBWriteFailures counts the failed writes of pixel changes, by
+	// operation: "flush" for database flushes, retried later, and "wal" for
+	// appends to the write-ahead log
+	DBWriteFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "db",
+		Name:      "write_failures_total",
+		Help:      "Failed writes of pixel changes, by operation.",
+	}, []string{"op"})
+
+	// DBChangesDropped counts the pixel changes given up on after their
+	// flushes kept failing
+	DBChangesDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "db",
+		Name:      "changes_dropped_total",
+		Help:      "Pixel changes moved to the dead-letter buffer after repeated failed flushes.",
+	})
+)
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Summary estimates the distribution of a histogram's observations from its
+// buckets. Quantiles past the last bucket report its upper bound.
+type Summary struct {
+	Count uint64  `json:"count"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+}
+
+// Summarize returns the summary of a histogram of a HistogramVec
+func Summarize(o prometheus.Observer) Summary {
+	m, ok := o.(prometheus.Metric)
+	if !ok {
+		return Summary{}
+	}
+	var metric dto.Metric
+	if err := m.Write(&metric); err != nil || metric.Histogram == nil {
+		return Summary{}
+	}
+	h := metric.Histogram
+	s := Summary{Count: h.GetSampleCount()}
+	if s.Count == 0 {
+		return s
+	}
+	s.Mean = h.GetSampleSum() / float64(s.Count)
+	s.P50 = quantile(h, 0.5)
+	s.P90 = quantile(h, 0.9)
+	s.P99 = quantile(h, 0.99)
+	return s
+}
+
+// quantile interpolates the q-quantile of a histogram linearly within the
+// bucket it falls in, like PromQL's histogram_quantile
+func quantile(h *dto.Histogram, q float64) float64 {
+	rank := q * float64(h.GetSampleCount())
+	lower, below := 0.0, uint64(0)
+	for _, b := range h.GetBucket() {
+		upper, count := b.GetUpperBound(), b.GetCumulativeCount()
+		if float64(count) >= rank {
+			if count == below {
+				return upper
+			}
+			return lower + (upper-lower)*(rank-float64(below))/float64(count-below)
+		}
+		lower, below = upper, count
+	}
+	return lower
+}
+
+// CounterValue returns the current value of a counter of a CounterVec
+func CounterValue(c prometheus.Counter) float64 {
+	var metric dto.Metric
+	if err := c.Write(&metric); err != nil {
+		return 0
+	}
+	return metric.GetCounter().GetValue()
+}
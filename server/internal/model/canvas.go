@@ -0,0 +1,22 @@
+package model
+
+import "regexp"
+
+// DefaultCanvas is the name of the canvas served when a client doesn't pick one
+const DefaultCanvas = "default"
+
+const (
+	// DefaultGridSize is the width and height of canvases without configured dimensions
+	DefaultGridSize = 1000
+
+	// MaxGridDimension is the largest allowed canvas width or height
+	MaxGridDimension = 65536
+)
+
+// canvasNamePattern matches the allowed canvas names (used in URLs and as the DB partition key)
+var canvasNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// ValidCanvasName reports whether name can be used as a canvas name
+func ValidCanvasName(name string) bool {
+	return canvasNamePattern.MatchString(name)
+}
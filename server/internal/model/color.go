@@ -0,0 +1,62 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Color is a 24-bit RGB color (0xRRGGBB). It is written as "#RRGGBB" in JSON
+// and stored as an integer in the database.
+type Color uint32
+
+// White is the color of inactive cells
+const White Color = 0xFFFFFF
+
+// hexColorPattern matches colors in "#RRGGBB" form
+var hexColorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// ParseColor parses a "#RRGGBB" hex color (case-insensitive)
+func ParseColor(s string) (Color, error) {
+	s = strings.TrimSpace(s)
+	if !hexColorPattern.MatchString(s) {
+		return 0, fmt.Errorf("invalid color %q, expected #RRGGBB", s)
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid color %q: %w", s, err)
+	}
+	return Color(v), nil
+}
+
+// String returns the color in "#RRGGBB" form
+func (c Color) String() string {
+	return fmt.Sprintf("#%06X", uint32(c)&0xFFFFFF)
+}
+
+// RGBA converts the color to an opaque image color
+func (c Color) RGBA() color.RGBA {
+	return color.RGBA{R: uint8(c >> 16), G: uint8(c >> 8), B: uint8(c), A: 0xFF}
+}
+
+// MarshalJSON encodes the color as a "#RRGGBB" string
+func (c Color) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+// UnmarshalJSON decodes a "#RRGGBB" string
+func (c *Color) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseColor(s)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
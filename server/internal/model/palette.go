@@ -0,0 +1,142 @@
+package model
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// DefaultPalette defines the 7 colors allowed for pixels unless configured otherwise
+var DefaultPalette = []Color{
+	0xFF0000, // Red
+	0xFF8000, // Orange
+	0xFFFF00, // Yellow
+	0x00FF00, // Green
+	0x00FFFF, // Cyan
+	0x0000FF, // Blue
+	0xFF00FF, // Magenta
+}
+
+// paletteState is an immutable palette version, replaced as a whole on change
+type paletteState struct {
+	version int
+	colors  []Color
+	set     map[Color]bool
+}
+
+// current holds the active palette (version 0 is the configured palette, not yet stored)
+var current atomic.Pointer[paletteState]
+
+func init() {
+	current.Store(newPaletteState(0, DefaultPalette))
+}
+
+// restrictToPalette limits pixels to the palette colors; when false any 24-bit color is allowed
+var restrictToPalette = true
+
+// IsValidColor checks if a color may be used for pixels under the current policy
+func IsValidColor(color Color) bool {
+	return !restrictToPalette || current.Load().set[color]
+}
+
+// Palette returns the palette colors in display order. When any color is
+// allowed they are only suggestions.
+func Palette() []Color {
+	return current.Load().colors
+}
+
+// PaletteVersion returns the version of the active palette
+func PaletteVersion() int {
+	return current.Load().version
+}
+
+// RestrictedToPalette reports whether pixels are limited to the palette colors
+func RestrictedToPalette() bool {
+	return restrictToPalette
+}
+
+// DefaultColor returns the color used when a client doesn't provide a valid one
+func DefaultColor() Color {
+	return current.Load().colors[0]
+}
+
+// NearestColor returns the color closest to c that can be painted: c itself
+// when any color is allowed, otherwise the nearest palette color or White
+// (the color of cleared cells)
+func NearestColor(c Color) Color {
+	if !restrictToPalette {
+		return c
+	}
+	best, bestDistance := White, colorDistance(c, White)
+	for _, candidate := range current.Load().colors {
+		if d := colorDistance(c, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// colorDistance returns the squared euclidean distance of two colors in RGB space
+func colorDistance(a, b Color) int {
+	dr := int(a>>16&0xFF) - int(b>>16&0xFF)
+	dg := int(a>>8&0xFF) - int(b>>8&0xFF)
+	db := int(a&0xFF) - int(b&0xFF)
+	return dr*dr + dg*dg + db*db
+}
+
+// ParsePalette parses a list of "#RRGGBB" hex strings into palette colors
+func ParsePalette(colors []string) ([]Color, error) {
+	parsed := make([]Color, 0, len(colors))
+	for _, c := range colors {
+		color, err := ParseColor(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid palette color: %w", err)
+		}
+		parsed = append(parsed, color)
+	}
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("palette must contain at least one color")
+	}
+	return parsed, nil
+}
+
+// SetPalette replaces the configured palette. Colors must be "#RRGGBB" hex
+// strings. Must be called before serving clients.
+func SetPalette(colors []string) error {
+	parsed, err := ParsePalette(colors)
+	if err != nil {
+		return err
+	}
+	current.Store(newPaletteState(0, parsed))
+	return nil
+}
+
+// ActivatePalette makes a stored palette version the active palette. Versions
+// older than the active one are ignored; it reports whether the palette changed.
+// Safe to call while serving clients.
+func ActivatePalette(version int, colors []Color) bool {
+	next := newPaletteState(version, colors)
+	for {
+		prev := current.Load()
+		if version <= prev.version {
+			return false
+		}
+		if current.CompareAndSwap(prev, next) {
+			return true
+		}
+	}
+}
+
+// SetRestrictToPalette sets whether pixels are limited to the palette colors.
+// Must be called before serving clients.
+func SetRestrictToPalette(restrict bool) {
+	restrictToPalette = restrict
+}
+
+// newPaletteState builds a palette version with its lookup set
+func newPaletteState(version int, colors []Color) *paletteState {
+	set := make(map[Color]bool, len(colors))
+	for _, c := range colors {
+		set[c] = true
+	}
+	return &paletteState{version: version, colors: colors, set: set}
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// Pixel represents a single cell on a canvas
+type Pixel struct {
+	Canvas    string     `gorm:"primaryKey;size:64;default:'default'" json:"-"`
+	X         int        `gorm:"primaryKey;autoIncrement:false" json:"x"`
+	Y         int        `gorm:"primaryKey;autoIncrement:false" json:"y"`
+	Active    bool       `gorm:"not null;default:false" json:"a"`
+	Color     Color      `gorm:"not null;default:16777215" json:"color"`
+	CreatedBy string     `gorm:"size:64;null" json:"created_by,omitempty"`
+	ModifyAt  *time.Time `gorm:"null" json:"modify_at,omitempty"`
+	ModifyBy  string     `gorm:"size:64;null" json:"modify_by,omitempty"`
+
+	// Team of the actor that made the last change (empty outside teams)
+	Team string `gorm:"size:32;null" json:"team,omitempty"`
+
+	// Location of the client that made the last change, only recorded in the history
+	Country string `gorm:"-" json:"-"`
+	Region  string `gorm:"-" json:"-"`
+}
+
+// TableName specifies the table name for Pixel
+func (Pixel) TableName() string {
+	return "pixels"
+}
@@ -0,0 +1,198 @@
+// Package oauth logs users in with their Google or GitHub account through
+// the OAuth2 authorization code flow
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/million_grids/server/internal/config"
+)
+
+// Time allowed for each request to a provider
+const requestTimeout = 10 * time.Second
+
+// Names of the providers
+const (
+	Google = "google"
+	GitHub = "github"
+)
+
+// Account is the user a provider authenticated
+type Account struct {
+	// Stable ID of the user at the provider
+	Subject string
+
+	// Display name, empty when the user has none
+	Name string
+}
+
+// Provider is an OAuth2 provider users log in with
+type Provider struct {
+	name         string
+	clientID     string
+	clientSecret string
+
+	authURL, tokenURL, userURL string
+	scopes                     []string
+
+	// Extracts the account from the provider's user info response
+	account func(body []byte) (Account, error)
+
+	client *http.Client
+}
+
+// Providers returns the providers configured with a client ID, by name
+func Providers(cfg config.OAuthConfig) map[string]*Provider {
+	providers := make(map[string]*Provider)
+	if cfg.Google.ClientID != "" {
+		providers[Google] = &Provider{
+			name:         Google,
+			clientID:     cfg.Google.ClientID,
+			clientSecret: cfg.Google.ClientSecret,
+			authURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+			tokenURL:     "https://oauth2.googleapis.com/token",
+			userURL:      "https://openidconnect.googleapis.com/v1/userinfo",
+			scopes:       []string{"openid", "profile"},
+			account:      googleAccount,
+			client:       &http.Client{Timeout: requestTimeout},
+		}
+	}
+	if cfg.GitHub.ClientID != "" {
+		providers[GitHub] = &Provider{
+			name:         GitHub,
+			clientID:     cfg.GitHub.ClientID,
+			clientSecret: cfg.GitHub.ClientSecret,
+			authURL:      "https://github.com/login/oauth/authorize",
+			tokenURL:     "https://github.com/login/oauth/access_token",
+			userURL:      "https://api.github.com/user",
+			scopes:       []string{"read:user"},
+			account:      githubAccount,
+			client:       &http.Client{Timeout: requestTimeout},
+		}
+	}
+	return providers
+}
+
+// Name returns the name of the provider
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// AuthCodeURL returns the URL of the provider's consent page, which sends the
+// user back to redirectURI with a code and state
+func (p *Provider) AuthCodeURL(redirectURI, state string) string {
+	query := url.Values{
+		"client_id":     {p.clientID},
+		"redirect_uri":  {redirectURI},
+		"response_type": {"code"},
+		"scope":         {strings.Join(p.scopes, " ")},
+		"state":         {state},
+	}
+	return p.authURL + "?" + query.Encode()
+}
+
+// Exchange trades the code the provider sent the user back with for the
+// account of the user
+func (p *Provider) Exchange(ctx context.Context, code, redirectURI string) (Account, error) {
+	form := url.Values{
+		"client_id":     {p.clientID},
+		"client_secret": {p.clientSecret},
+		"code":          {code},
+		"grant_type":    {"authorization_code"},
+		"redirect_uri":  {redirectURI},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Account{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	body, err := p.do(req)
+	if err != nil {
+		return Account{}, fmt.Errorf("failed to exchange %s code: %w", p.name, err)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return Account{}, fmt.Errorf("failed to decode %s token: %w", p.name, err)
+	}
+	if token.AccessToken == "" {
+		return Account{}, fmt.Errorf("%s issued no access token: %s", p.name, token.Error)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, p.userURL, nil)
+	if err != nil {
+		return Account{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Accept", "application/json")
+	if body, err = p.do(req); err != nil {
+		return Account{}, fmt.Errorf("failed to fetch %s user: %w", p.name, err)
+	}
+	account, err := p.account(body)
+	if err != nil {
+		return Account{}, fmt.Errorf("failed to decode %s user: %w", p.name, err)
+	}
+	if account.Subject == "" {
+		return Account{}, fmt.Errorf("%s returned a user without an ID", p.name)
+	}
+	return account, nil
+}
+
+// do sends a request to the provider and returns the body of its successful response
+func (p *Provider) do(req *http.Request) ([]byte, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s responded %s", p.name, resp.Status)
+	}
+	return body, nil
+}
+
+// googleAccount extracts the account from Google's OpenID Connect user info
+func googleAccount(body []byte) (Account, error) {
+	var user struct {
+		Sub  string `json:"sub"`
+		Name string `json:"name"`
+	}
+	err := json.Unmarshal(body, &user)
+	return Account{Subject: user.Sub, Name: user.Name}, err
+}
+
+// githubAccount extracts the account from GitHub's user, named by its login
+// when it has no name
+func githubAccount(body []byte) (Account, error) {
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := json.Unmarshal(body, &user); err != nil {
+		return Account{}, err
+	}
+	if user.ID == 0 {
+		return Account{}, nil
+	}
+	name := user.Name
+	if name == "" {
+		name = user.Login
+	}
+	return Account{Subject: strconv.FormatInt(user.ID, 10), Name: name}, nil
+}
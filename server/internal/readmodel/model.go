@@ -0,0 +1,256 @@
+package readmodel
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/model"
+)
+
+const (
+	// Side in cells of the square blocks heatmaps count placements in
+	HeatmapBlock = 16
+
+	// Hours of placements heatmaps and leaderboards are kept for
+	WindowHours = 24
+
+	// Side in cells of the chunks cell states are allocated in
+	chunkSize = 64
+)
+
+// Model is the read model of a canvas: the counts of its active cells per
+// color, and per hour the placements in each block of cells, by contributor
+// and by team
+type Model struct {
+	width, height int
+
+	mu sync.RWMutex
+
+	// State of each cell, 0 when inactive and its color plus one otherwise,
+	// in chunks allocated on first change
+	chunks  [][]uint32
+	chunksX int
+
+	// Active cells per color
+	colors map[model.Color]int
+
+	// Placements of the last WindowHours hours, oldest first
+	hours []*hourStats
+}
+
+// hourStats counts the placements made in an hour
+type hourStats struct {
+	// Start of the hour, in UTC
+	start time.Time
+
+	// Placements per block, row by row
+	heat []int
+
+	// Placements per contributor and per team
+	contributors map[string]int
+	teams        map[string]int
+}
+
+// Heatmap counts the placements in each block of a canvas over a window
+type Heatmap struct {
+	// Side of the blocks in cells, and number of blocks across and down
+	Block   int `json:"block"`
+	Columns int `json:"columns"`
+	Rows    int `json:"rows"`
+
+	// Placements per block, row by row, and the most in any block
+	Counts []int `json:"counts"`
+	Max    int   `json:"max"`
+}
+
+// newModel creates the read model of a canvas holding its active cells
+func newModel(width, height int, active []model.Pixel) *Model {
+	m := &Model{
+		width:   width,
+		height:  height,
+		chunksX: (width + chunkSize - 1) / chunkSize,
+		colors:  make(map[model.Color]int),
+	}
+	m.chunks = make([][]uint32, m.chunksX*((height+chunkSize-1)/chunkSize))
+	for _, p := range active {
+		m.setCell(p.X, p.Y, p.Active, p.Color)
+	}
+	return m
+}
+
+// columns and rows return the number of heatmap blocks across and down
+func (m *Model) columns() int { return (m.width + HeatmapBlock - 1) / HeatmapBlock }
+func (m *Model) rows() int    { return (m.height + HeatmapBlock - 1) / HeatmapBlock }
+
+// setCell records the state of a cell and updates the color counts, m.mu must be held
+func (m *Model) setCell(x, y int, active bool, color model.Color) {
+	if x < 0 || y < 0 || x >= m.width || y >= m.height {
+		return
+	}
+	index := (y/chunkSize)*m.chunksX + x/chunkSize
+	chunk := m.chunks[index]
+	if chunk == nil {
+		if !active {
+			return
+		}
+		chunk = make([]uint32, chunkSize*chunkSize)
+		m.chunks[index] = chunk
+	}
+
+	pos := (y%chunkSize)*chunkSize + x%chunkSize
+	if prev := chunk[pos]; prev != 0 {
+		if m.colors[model.Color(prev-1)]--; m.colors[model.Color(prev-1)] == 0 {
+			delete(m.colors, model.Color(prev-1))
+		}
+	}
+	chunk[pos] = 0
+	if active {
+		chunk[pos] = uint32(color) + 1
+		m.colors[color]++
+	}
+}
+
+// apply records a placement made by contributor (empty when it has no actor).
+// Its cell state is only applied when cell is set, since the state the model
+// was created from may already include it.
+func (m *Model) apply(rec *db.PixelHistory, contributor string, cell bool, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cell {
+		m.setCell(rec.X, rec.Y, rec.Active, rec.Color)
+	}
+
+	// Cells cleared by decay aren't activity
+	if rec.Actor == db.DecayActor || rec.X < 0 || rec.Y < 0 || rec.X >= m.width || rec.Y >= m.height {
+		return
+	}
+	hour := m.hour(rec.CreatedAt.UTC().Truncate(time.Hour), now)
+	if hour == nil {
+		return
+	}
+	hour.heat[(rec.Y/HeatmapBlock)*m.columns()+rec.X/HeatmapBlock]++
+	if contributor != "" {
+		hour.contributors[contributor]++
+	}
+	if rec.Team != "" {
+		hour.teams[rec.Team]++
+	}
+}
+
+// reset clears every cell, keeping the placements made before
+func (m *Model) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.chunks)
+	clear(m.colors)
+}
+
+// hour returns the stats of the hour starting at start, adding them if
+// needed, or nil if the hour is out of the window. m.mu must be held.
+func (m *Model) hour(start, now time.Time) *hourStats {
+	oldest := now.UTC().Truncate(time.Hour).Add(-(WindowHours - 1) * time.Hour)
+	if len(m.hours) > 0 && m.hours[0].start.Before(oldest) {
+		m.hours = slices.DeleteFunc(m.hours, func(h *hourStats) bool { return h.start.Before(oldest) })
+	}
+	if start.Before(oldest) {
+		return nil
+	}
+
+	// Placements arrive about in order, so the hour is almost always the last one
+	i := len(m.hours)
+	for i > 0 && m.hours[i-1].start.After(start) {
+		i--
+	}
+	if i > 0 && m.hours[i-1].start.Equal(start) {
+		return m.hours[i-1]
+	}
+	hour := &hourStats{
+		start:        start,
+		heat:         make([]int, m.columns()*m.rows()),
+		contributors: make(map[string]int),
+		teams:        make(map[string]int),
+	}
+	m.hours = slices.Insert(m.hours, i, hour)
+	return hour
+}
+
+// since returns the hours starting from the hour of since, m.mu must be held
+func (m *Model) since(since time.Time) []*hourStats {
+	from := since.UTC().Truncate(time.Hour)
+	i, _ := slices.BinarySearchFunc(m.hours, from, func(h *hourStats, t time.Time) int { return h.start.Compare(t) })
+	return m.hours[i:]
+}
+
+// ColorCounts returns the number of active cells of each color
+func (m *Model) ColorCounts() map[model.Color]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return maps.Clone(m.colors)
+}
+
+// Heatmap returns the placements in each block since the start of the hour of since
+func (m *Model) Heatmap(since time.Time) Heatmap {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	columns, rows := m.columns(), m.rows()
+	heatmap := Heatmap{Block: HeatmapBlock, Columns: columns, Rows: rows, Counts: make([]int, columns*rows)}
+	for _, hour := range m.since(since) {
+		for i, n := range hour.heat {
+			heatmap.Counts[i] += n
+		}
+	}
+	for _, n := range heatmap.Counts {
+		heatmap.Max = max(heatmap.Max, n)
+	}
+	return heatmap
+}
+
+// TopContributors returns the contributors with the most placements since the
+// start of the hour of since, most first
+func (m *Model) TopContributors(since time.Time, limit int) []db.Contributor {
+	m.mu.RLock()
+	totals := make(map[string]int)
+	for _, hour := range m.since(since) {
+		for contributor, n := range hour.contributors {
+			totals[contributor] += n
+		}
+	}
+	m.mu.RUnlock()
+
+	top := make([]db.Contributor, 0, len(totals))
+	for contributor, n := range totals {
+		top = append(top, db.Contributor{Contributor: contributor, Placements: n})
+	}
+	slices.SortFunc(top, func(a, b db.Contributor) int {
+		return cmp.Or(cmp.Compare(b.Placements, a.Placements), cmp.Compare(a.Contributor, b.Contributor))
+	})
+	return top[:min(limit, len(top))]
+}
+
+// TopTeams returns the teams with the most placements since the start of the
+// hour of since, most first
+func (m *Model) TopTeams(since time.Time, limit int) []db.TeamScore {
+	m.mu.RLock()
+	totals := make(map[string]int)
+	for _, hour := range m.since(since) {
+		for team, n := range hour.teams {
+			totals[team] += n
+		}
+	}
+	m.mu.RUnlock()
+
+	top := make([]db.TeamScore, 0, len(totals))
+	for team, n := range totals {
+		top = append(top, db.TeamScore{Team: team, Placements: n})
+	}
+	slices.SortFunc(top, func(a, b db.TeamScore) int {
+		return cmp.Or(cmp.Compare(b.Placements, a.Placements), cmp.Compare(a.Team, b.Team))
+	})
+	return top[:min(limit, len(top))]
+}
@@ -0,0 +1,129 @@
+// Package readmodel maintains denormalized read models of every canvas (color
+// counts, heatmaps and leaderboards) from the event stream in the database,
+// so the statistics endpoints read them instead of scanning the pixels table
+// or locking the grids. They include the placements made through every
+// instance sharing the database, and trail the stream by up to the interval.
+package readmodel
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/model"
+)
+
+// Entries of the event stream read per query
+const readBatchSize = 5000
+
+// Projector tails the event stream and applies it to the read model of each canvas
+type Projector struct {
+	interval time.Duration
+
+	// Hashes actors into the contributors the leaderboard counts
+	hash func(actor string) string
+
+	// Position of the next entries to read, and the position cell states are
+	// created from, before which entries only count as placements
+	pos    db.StreamPosition
+	seeded db.StreamPosition
+
+	mu     sync.RWMutex
+	models map[string]*Model
+}
+
+// NewProjector creates a projector reading the stream every interval. The
+// placements of the last WindowHours hours are read again, for the heatmaps
+// and leaderboards to cover their whole window after a restart.
+func NewProjector(interval time.Duration) (*Projector, error) {
+	hash, err := db.ContributorHasher()
+	if err != nil {
+		return nil, err
+	}
+	head, err := db.StreamHead()
+	if err != nil {
+		return nil, err
+	}
+	start, err := db.HistoryIDBefore(time.Now().Add(-WindowHours * time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	return &Projector{
+		interval: interval,
+		hash:     hash,
+		pos:      db.StreamPosition{HistoryID: start, EventID: head.EventID},
+		seeded:   head,
+		models:   make(map[string]*Model),
+	}, nil
+}
+
+// Add creates the read model of a canvas from its active cells, which must be
+// loaded after the projector was created
+func (p *Projector) Add(canvas string, width, height int, active []model.Pixel) {
+	m := newModel(width, height, active)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.models[canvas] = m
+}
+
+// Model returns the read model of a canvas, or nil if it has none
+func (p *Projector) Model(canvas string) *Model {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.models[canvas]
+}
+
+// Run applies the stream every interval
+func (p *Projector) Run() {
+	p.Apply()
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		p.Apply()
+	}
+}
+
+// Apply reads the entries recorded since the last run and applies them to the
+// read models, logging failures
+func (p *Projector) Apply() {
+	total := 0
+	for {
+		entries, pos, err := db.ReadStream(p.pos, readBatchSize)
+		if err != nil {
+			slog.Error("Failed to read event stream for read models", "err", err)
+			return
+		}
+		now := time.Now()
+		for _, entry := range entries {
+			p.apply(entry, now)
+		}
+		p.pos = pos
+		total += len(entries)
+		if len(entries) < readBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		slog.Debug("Updated read models", "entries", total, "history_id", p.pos.HistoryID)
+	}
+}
+
+// apply applies an entry of the stream to the read model of its canvas
+func (p *Projector) apply(entry db.StreamEntry, now time.Time) {
+	switch {
+	case entry.Change != nil:
+		if m := p.Model(entry.Change.Canvas); m != nil {
+			var contributor string
+			if entry.Change.Actor != "" {
+				contributor = p.hash(entry.Change.Actor)
+			}
+			m.apply(entry.Change, contributor, entry.Change.ID > p.seeded.HistoryID, now)
+		}
+	case entry.Event.Kind == db.EventReset:
+		if m := p.Model(entry.Event.Canvas); m != nil && entry.Event.ID > p.seeded.EventID {
+			m.reset()
+		}
+	}
+}
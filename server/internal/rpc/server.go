@@ -0,0 +1,246 @@
+// Package rpc serves the Grid gRPC API on top of the hubs used by the
+// WebSocket endpoint, so updates and placements are shared between both.
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/million_grids/server/internal/auth"
+	"github.com/million_grids/server/internal/gridpb"
+	"github.com/million_grids/server/internal/metrics"
+	"github.com/million_grids/server/internal/ws"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Server implements the Grid service
+type Server struct {
+	gridpb.UnimplementedGridServer
+
+	canvases *ws.Canvases
+
+	// Validates bearer tokens (nil when authentication is disabled)
+	validator *auth.Validator
+
+	// Reject calls without a valid token
+	authRequired bool
+
+	// Proxies whose forwarding metadata is believed
+	proxies ws.TrustedProxies
+}
+
+// NewServer creates the Grid service for the canvases. validator is nil when
+// authentication is disabled.
+func NewServer(canvases *ws.Canvases, validator *auth.Validator, authRequired bool, proxies ws.TrustedProxies) *Server {
+	return &Server{canvases: canvases, validator: validator, authRequired: authRequired, proxies: proxies}
+}
+
+// StreamUpdates sends the canvas's initial state, then its broadcasts until the
+// client cancels or the server shuts down
+func (s *Server) StreamUpdates(req *gridpb.StreamUpdatesRequest, stream gridpb.Grid_StreamUpdatesServer) error {
+	hub, err := s.hub(req.Canvas)
+	if err != nil {
+		return err
+	}
+	if _, err := s.authenticate(stream.Context()); err != nil {
+		return err
+	}
+
+	var region *ws.Region
+	if req.Region != nil {
+		r := ws.Region{X1: int(req.Region.X1), Y1: int(req.Region.Y1), X2: int(req.Region.X2), Y2: int(req.Region.Y2)}
+		r = r.Clamp(hub.Grid().Width(), hub.Grid().Height())
+		if r.Empty() {
+			return status.Error(codes.InvalidArgument, "the region contains no cells")
+		}
+		region = &r
+	}
+
+	// Listen first so no update is missed while the initial state is sent
+	l := hub.Listen(region, ws.EncodingProtobuf)
+	defer hub.Unlisten(l)
+
+	frames, err := hub.InitialState(ws.EncodingProtobuf)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to encode initial state: %v", err)
+	}
+	for _, frame := range frames {
+		if err := sendFrame(stream, frame); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case frame, ok := <-l.C:
+			if !ok {
+				if hub.ShuttingDown() {
+					return status.Error(codes.Unavailable, "server restarting")
+				}
+				return status.Error(codes.ResourceExhausted, "stream fell behind the updates")
+			}
+			if err := sendFrame(stream, frame); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// PaintPixel applies a single-cell operation like a WebSocket placement
+func (s *Server) PaintPixel(ctx context.Context, req *gridpb.PaintPixelRequest) (*gridpb.PaintPixelResponse, error) {
+	hub, err := s.hub(req.Canvas)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var op string
+	var cell *gridpb.CellOp
+	switch m := req.Op.(type) {
+	case *gridpb.PaintPixelRequest_Toggle:
+		op, cell = ws.OpToggle, m.Toggle
+	case *gridpb.PaintPixelRequest_Set:
+		op, cell = ws.OpSet, m.Set
+	case *gridpb.PaintPixelRequest_Clear:
+		op, cell = ws.OpClear, m.Clear
+	default:
+		return nil, status.Error(codes.InvalidArgument, "op is required")
+	}
+
+	placement := ws.Placement{Op: op, X: int(cell.GetX()), Y: int(cell.GetY()), IP: s.clientIP(ctx)}
+	if cell.Color != nil {
+		placement.Color = ws.ColorFromProto(*cell.Color)
+	}
+	placement.Actor = hub.AnonymousActor(placement.IP)
+	placement.Anonymous = identity == nil
+	if placement.Anonymous && req.GetCaptcha() != "" {
+		if err := hub.SolveCaptcha(ctx, placement.Actor, placement.IP, req.GetCaptcha()); errors.Is(err, ws.ErrCaptchaInvalid) {
+			return nil, status.Error(codes.PermissionDenied, "the CAPTCHA token is invalid or expired")
+		} else if err != nil {
+			return nil, status.Error(codes.Unavailable, "failed to verify the CAPTCHA, try again")
+		}
+	}
+	if identity != nil {
+		placement.Actor = identity.UserID
+		placement.Moderator = identity.Moderator()
+		placement.Role = identity.Role
+		if hub.HasTeam(identity.Team) {
+			placement.Team = identity.Team
+		}
+	}
+
+	pixel, err := hub.Place(ctx, placement)
+	var rejected *ws.PlacementError
+	if errors.As(err, &rejected) {
+		metrics.Rejections.WithLabelValues(hub.Canvas(), "grpc", rejected.Code).Inc()
+	}
+	if err != nil {
+		return nil, placementStatus(err)
+	}
+	return &gridpb.PaintPixelResponse{Cell: &gridpb.CellUpdate{
+		X:      uint32(pixel.X),
+		Y:      uint32(pixel.Y),
+		Active: pixel.Active,
+		Color:  uint32(pixel.Color),
+	}}, nil
+}
+
+// hub returns the hub of a canvas, refusing calls while the server shuts down
+func (s *Server) hub(canvas string) (*ws.Hub, error) {
+	if s.canvases.ShuttingDown() {
+		return nil, status.Error(codes.Unavailable, "server restarting")
+	}
+	hub, ok := s.canvases.Get(canvas)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown canvas %q", canvas)
+	}
+	return hub, nil
+}
+
+// authenticate validates the bearer token in the call's metadata. It returns a
+// nil identity for anonymous calls when authentication is optional.
+func (s *Server) authenticate(ctx context.Context) (*auth.Identity, error) {
+	if s.validator == nil {
+		return nil, nil
+	}
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, value := range md.Get("authorization") {
+			if t, ok := strings.CutPrefix(value, "Bearer "); ok {
+				token = strings.TrimSpace(t)
+			}
+		}
+	}
+	if token == "" {
+		if s.authRequired {
+			return nil, status.Error(codes.Unauthenticated, auth.ErrNoToken.Error())
+		}
+		return nil, nil
+	}
+	identity, err := s.validator.Validate(token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return identity, nil
+}
+
+// clientIP returns the caller's address: the peer address, or the one
+// reported in X-Forwarded-For or X-Real-IP when the peer is a trusted proxy
+func (s *Server) clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	ip, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		ip = p.Addr.String()
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	var realIP string
+	if v := md.Get("x-real-ip"); len(v) > 0 {
+		realIP = v[0]
+	}
+	return s.proxies.ClientIP(ip, md.Get("x-forwarded-for"), realIP)
+}
+
+// placementStatus converts a rejected placement to a gRPC status
+func placementStatus(err error) error {
+	var rejected *ws.PlacementError
+	if !errors.As(err, &rejected) {
+		return status.Error(codes.Internal, err.Error())
+	}
+	switch rejected.Code {
+	case "cooldown", "quota_exceeded":
+		return status.Errorf(codes.ResourceExhausted, "%s (retry in %dms)", rejected.Message, rejected.RetryAfter.Milliseconds())
+	case "banned", "region_locked", "cell_claimed", "captcha_required":
+		return status.Error(codes.PermissionDenied, rejected.Message)
+	case "canvas_frozen", "read_only_replica":
+		return status.Error(codes.FailedPrecondition, rejected.Message)
+	case "write_failed":
+		return status.Error(codes.Unavailable, rejected.Message)
+	case "cell_protected":
+		return status.Errorf(codes.FailedPrecondition, "%s (retry in %dms)", rejected.Message, rejected.RetryAfter.Milliseconds())
+	default:
+		return status.Error(codes.InvalidArgument, rejected.Message)
+	}
+}
+
+// sendFrame sends an encoded ServerMessage on the stream
+func sendFrame(stream gridpb.Grid_StreamUpdatesServer, frame []byte) error {
+	var msg gridpb.ServerMessage
+	if err := proto.Unmarshal(frame, &msg); err != nil {
+		return status.Errorf(codes.Internal, "failed to decode update: %v", err)
+	}
+	return stream.Send(&msg)
+}
@@ -0,0 +1,178 @@
+// Package schedule runs timed canvas events: a canvas stays frozen until its
+// event starts, is frozen again when it ends, and its final state is archived.
+// Countdown announcements lead up to both.
+package schedule
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/million_grids/server/internal/ws"
+)
+
+// Archiver saves the named snapshots the final states of events are kept in
+type Archiver interface {
+	Archive(hub *ws.Hub, name string) error
+	Archived(canvas, name string) bool
+}
+
+// Event is a period during which a canvas is open for painting
+type Event struct {
+	// Shown in announcements, and the name of the archive of the final state
+	Name string
+
+	// Canvas the event takes place on (empty for the default canvas)
+	Canvas string
+
+	// When painting opens (zero if it is open from startup) and closes
+	Start time.Time
+	End   time.Time
+
+	// How long before the start and the end countdown announcements are sent
+	Countdowns []time.Duration
+}
+
+// Scheduler applies the start and end of events to their canvases
+type Scheduler struct {
+	canvases *ws.Canvases
+
+	// Archives the final states (nil keeps none)
+	archiver Archiver
+
+	events []Event
+}
+
+// step is an action of an event, due at a point in time
+type step struct {
+	at time.Time
+	do func()
+}
+
+// NewScheduler creates a scheduler for events, archiving their final states
+// through archiver (nil archives nothing)
+func NewScheduler(canvases *ws.Canvases, archiver Archiver, events []Event) *Scheduler {
+	events = slices.Clone(events)
+	slices.SortStableFunc(events, func(a, b Event) int { return a.Start.Compare(b.Start) })
+	return &Scheduler{canvases: canvases, archiver: archiver, events: events}
+}
+
+// Run brings each canvas to the state of its current or next event, then
+// acts on the events as they start and end. It returns once the last event
+// has ended.
+func (s *Scheduler) Run() {
+	now := time.Now()
+	var steps []step
+	for _, event := range s.events {
+		hub, ok := s.canvases.Get(event.Canvas)
+		if !ok {
+			slog.Warn("Skipping event of unknown canvas", "event", event.Name, "canvas", event.Canvas)
+			continue
+		}
+		steps = append(steps, s.plan(hub, event, now)...)
+	}
+	// Steps due at the same time run in the order of their events' start
+	slices.SortStableFunc(steps, func(a, b step) int { return a.at.Compare(b.at) })
+
+	for _, st := range steps {
+		if wait := time.Until(st.at); wait > 0 {
+			time.Sleep(wait)
+		}
+		st.do()
+	}
+}
+
+// plan returns the steps of an event still due at now. Steps whose time has
+// passed are folded into one step bringing the canvas up to date.
+func (s *Scheduler) plan(hub *ws.Hub, event Event, now time.Time) []step {
+	var steps []step
+	countdowns := func(to time.Time, format, severity string) {
+		for _, d := range event.Countdowns {
+			if at := to.Add(-d); at.After(now) {
+				text := fmt.Sprintf(format, event.Name, formatDuration(d))
+				steps = append(steps, step{at: at, do: func() {
+					announce(hub, text, severity, d)
+				}})
+			}
+		}
+	}
+
+	switch {
+	case now.Before(event.Start):
+		steps = append(steps, step{at: now, do: func() {
+			hub.SetReadOnly(true, fmt.Sprintf("%s starts at %s", event.Name, event.Start.UTC().Format(time.RFC1123)))
+		}})
+		countdowns(event.Start, "%s starts in %s", "info")
+		steps = append(steps, step{at: event.Start, do: func() { s.start(hub, event) }})
+		fallthrough
+
+	case now.Before(event.End):
+		if !now.Before(event.Start) {
+			steps = append(steps, step{at: now, do: func() { hub.SetReadOnly(false, "") }})
+		}
+		countdowns(event.End, "%s ends in %s", "warning")
+		steps = append(steps, step{at: event.End, do: func() { s.end(hub, event, true) }})
+
+	default:
+		steps = append(steps, step{at: now, do: func() { s.end(hub, event, false) }})
+	}
+	return steps
+}
+
+// start opens the canvas of an event for painting
+func (s *Scheduler) start(hub *ws.Hub, event Event) {
+	slog.Info("Event started", "event", event.Name, "canvas", hub.Canvas())
+	hub.SetReadOnly(false, "")
+	announce(hub, fmt.Sprintf("%s has started!", event.Name), "info", 0)
+}
+
+// end freezes the canvas of an event and archives its final state.
+// announced is false when the event ended while the server was down, in
+// which case the canvas is frozen quietly and archived if it wasn't already.
+func (s *Scheduler) end(hub *ws.Hub, event Event, announced bool) {
+	hub.SetReadOnly(true, fmt.Sprintf("%s is over", event.Name))
+	if announced {
+		slog.Info("Event ended", "event", event.Name, "canvas", hub.Canvas())
+		announce(hub, fmt.Sprintf("%s is over, thanks for painting!", event.Name), "warning", 0)
+	}
+
+	if s.archiver == nil {
+		slog.Warn("Not archiving event, snapshots are disabled", "event", event.Name)
+		return
+	}
+	if !announced && s.archiver.Archived(hub.Canvas(), event.Name) {
+		return
+	}
+	if err := s.archiver.Archive(hub, event.Name); err != nil {
+		slog.Error("Failed to archive event", "event", event.Name, "canvas", hub.Canvas(), "err", err)
+	}
+}
+
+// announce sends an announcement to the canvas, counting down d (0 for none)
+func announce(hub *ws.Hub, text, severity string, d time.Duration) {
+	hub.Announce(ws.AnnounceMessage{
+		Text:      text,
+		Severity:  severity,
+		Countdown: int(d.Seconds()),
+		At:        time.Now().UTC(),
+	})
+}
+
+// formatDuration spells out a countdown, e.g. "10 minutes"
+func formatDuration(d time.Duration) string {
+	units := []struct {
+		size time.Duration
+		name string
+	}{{24 * time.Hour, "day"}, {time.Hour, "hour"}, {time.Minute, "minute"}, {time.Second, "second"}}
+	for _, unit := range units {
+		if d >= unit.size && d%unit.size == 0 {
+			n := int(d / unit.size)
+			if n == 1 {
+				return "1 " + unit.name
+			}
+			return fmt.Sprintf("%d %ss", n, unit.name)
+		}
+	}
+	return d.String()
+}
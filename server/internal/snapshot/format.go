@@ -0,0 +1,172 @@
+// Package snapshot periodically writes the full state of each canvas to disk
+// so startup and time travel only replay the event stream recorded since.
+package snapshot
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// magic identifies snapshot files and the version of their format. Version 1
+// snapshots, which record no event ID, are still read.
+const (
+	magic   = "MGSNAP\x02"
+	magicV1 = "MGSNAP\x01"
+)
+
+// Snapshot is the state of a canvas at a point in time
+type Snapshot struct {
+	Canvas string
+	Width  int
+	Height int
+
+	// When the state was captured
+	TakenAt time.Time
+
+	// ID of the newest history record and canvas event reflected in the
+	// state. Replaying the event stream after them brings the state up to date.
+	HistoryID uint64
+	EventID   uint64
+
+	// Active cells of the canvas
+	Cells []model.Pixel
+}
+
+// Encode writes a snapshot in the compact binary format: the magic header
+// followed by a gzip stream of varint fields, then each active cell as the
+// varint distance from the previous one (in x*height+y order) and its 24-bit color
+func Encode(w io.Writer, s *Snapshot) error {
+	if _, err := io.WriteString(w, magic); err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(w)
+	bw := bufio.NewWriter(zw)
+
+	var buf [binary.MaxVarintLen64]byte
+	putUvarint := func(v uint64) {
+		bw.Write(buf[:binary.PutUvarint(buf[:], v)])
+	}
+
+	putUvarint(uint64(len(s.Canvas)))
+	bw.WriteString(s.Canvas)
+	putUvarint(uint64(s.Width))
+	putUvarint(uint64(s.Height))
+	bw.Write(buf[:binary.PutVarint(buf[:], s.TakenAt.UnixNano())])
+	putUvarint(s.HistoryID)
+	putUvarint(s.EventID)
+
+	positions := make([]uint64, 0, len(s.Cells))
+	colors := make(map[uint64]model.Color, len(s.Cells))
+	for _, cell := range s.Cells {
+		pos := uint64(cell.X)*uint64(s.Height) + uint64(cell.Y)
+		positions = append(positions, pos)
+		colors[pos] = cell.Color
+	}
+	slices.Sort(positions)
+
+	putUvarint(uint64(len(positions)))
+	var prev uint64
+	for _, pos := range positions {
+		putUvarint(pos - prev)
+		prev = pos
+		c := colors[pos]
+		bw.Write([]byte{byte(c >> 16), byte(c >> 8), byte(c)})
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Decode reads a snapshot written by Encode
+func Decode(r io.Reader) (*Snapshot, error) {
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot header: %w", err)
+	}
+	if string(header) != magic && string(header) != magicV1 {
+		return nil, errors.New("not a snapshot or unsupported snapshot version")
+	}
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	br := bufio.NewReader(zr)
+
+	// The first read error sticks, so fields can be read without checking each one
+	var readErr error
+	uvarint := func() uint64 {
+		if readErr != nil {
+			return 0
+		}
+		var v uint64
+		v, readErr = binary.ReadUvarint(br)
+		return v
+	}
+
+	s := &Snapshot{}
+	nameLen := uvarint()
+	if nameLen > 64 {
+		return nil, errors.New("invalid snapshot canvas name")
+	}
+	name := make([]byte, nameLen)
+	if readErr == nil {
+		_, readErr = io.ReadFull(br, name)
+	}
+	s.Canvas = string(name)
+	s.Width = int(uvarint())
+	s.Height = int(uvarint())
+	var takenAt int64
+	if readErr == nil {
+		takenAt, readErr = binary.ReadVarint(br)
+	}
+	s.TakenAt = time.Unix(0, takenAt)
+	s.HistoryID = uvarint()
+	if string(header) == magic {
+		s.EventID = uvarint()
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("failed to read snapshot header: %w", readErr)
+	}
+	if s.Width < 1 || s.Height < 1 || s.Width > model.MaxGridDimension || s.Height > model.MaxGridDimension {
+		return nil, fmt.Errorf("invalid snapshot dimensions %dx%d", s.Width, s.Height)
+	}
+
+	count := uvarint()
+	cells := uint64(s.Width) * uint64(s.Height)
+	if count > cells {
+		return nil, fmt.Errorf("snapshot has %d cells, more than fit its grid", count)
+	}
+	s.Cells = make([]model.Pixel, 0, count)
+	var pos uint64
+	var rgb [3]byte
+	for range count {
+		pos += uvarint()
+		if readErr == nil {
+			_, readErr = io.ReadFull(br, rgb[:])
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read snapshot cells: %w", readErr)
+		}
+		if pos >= cells {
+			return nil, errors.New("snapshot cell out of bounds")
+		}
+		s.Cells = append(s.Cells, model.Pixel{
+			Canvas: s.Canvas,
+			X:      int(pos / uint64(s.Height)),
+			Y:      int(pos % uint64(s.Height)),
+			Active: true,
+			Color:  model.Color(rgb[0])<<16 | model.Color(rgb[1])<<8 | model.Color(rgb[2]),
+		})
+	}
+	return s, nil
+}
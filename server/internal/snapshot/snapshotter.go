@@ -0,0 +1,185 @@
+package snapshot
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// Snapshotter periodically snapshots every canvas, materializing their event
+// streams, and restores grids from the latest snapshot plus the events
+// recorded since
+type Snapshotter struct {
+	store    *Store
+	canvases *ws.Canvases
+	interval time.Duration
+
+	// Number of snapshots kept per canvas
+	keep int
+
+	// Serializes snapshots, and the stream position of the last one per canvas
+	mu      sync.Mutex
+	lastPos map[string]db.StreamPosition
+}
+
+// NewSnapshotter creates a snapshotter writing to store every interval
+func NewSnapshotter(store *Store, canvases *ws.Canvases, interval time.Duration, keep int) *Snapshotter {
+	return &Snapshotter{
+		store:    store,
+		canvases: canvases,
+		interval: interval,
+		keep:     keep,
+		lastPos:  make(map[string]db.StreamPosition),
+	}
+}
+
+// Run snapshots all canvases every interval
+func (s *Snapshotter) Run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.TakeAll()
+	}
+}
+
+// TakeAll snapshots every canvas, logging failures
+func (s *Snapshotter) TakeAll() {
+	for _, hub := range s.canvases.Hubs() {
+		if err := s.Take(hub); err != nil {
+			slog.Error("Failed to snapshot canvas", "canvas", hub.Canvas(), "err", err)
+		}
+	}
+}
+
+// Take snapshots the grid of a hub and prunes old snapshots. Canvases whose
+// event stream hasn't moved since their last snapshot are skipped.
+func (s *Snapshotter) Take(hub *ws.Hub) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Every event in the stream up to pos must already be in the grid, so
+	// flush the queue and read the position before capturing the cells.
+	// Changes made while capturing end up after pos and are replayed on restore.
+	if err := db.FlushPending(); err != nil {
+		return err
+	}
+	pos, err := db.LatestPosition(hub.Canvas())
+	if err != nil {
+		return err
+	}
+	if last, ok := s.lastPos[hub.Canvas()]; ok && pos.HistoryID != 0 && last == pos {
+		return nil
+	}
+
+	start := time.Now()
+	snap := capture(hub, start, pos)
+	if err := s.store.Save(snap); err != nil {
+		return err
+	}
+	s.lastPos[hub.Canvas()] = pos
+	slog.Info("Snapshotted canvas", "canvas", snap.Canvas, "cells", len(snap.Cells),
+		"history_id", pos.HistoryID, "event_id", pos.EventID, "duration", time.Since(start))
+
+	return s.store.Prune(hub.Canvas(), s.keep)
+}
+
+// Replace snapshots the grid of a hub even if its event stream hasn't moved
+// since the last snapshot, so restores don't have to replay the change just made
+func (s *Snapshotter) Replace(hub *ws.Hub) error {
+	s.mu.Lock()
+	delete(s.lastPos, hub.Canvas())
+	s.mu.Unlock()
+	return s.Take(hub)
+}
+
+// Archive saves the current state of a hub's canvas as a named snapshot,
+// which is kept until it is deleted by hand. An existing archive of the same
+// name is replaced.
+func (s *Snapshotter) Archive(hub *ws.Hub, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := db.FlushPending(); err != nil {
+		return err
+	}
+	pos, err := db.LatestPosition(hub.Canvas())
+	if err != nil {
+		return err
+	}
+	snap := capture(hub, time.Now(), pos)
+	if err := s.store.SaveArchive(name, snap); err != nil {
+		return err
+	}
+	slog.Info("Archived canvas", "canvas", snap.Canvas, "archive", name, "cells", len(snap.Cells))
+	return nil
+}
+
+// Archived reports whether a canvas has an archive with the given name
+func (s *Snapshotter) Archived(canvas, name string) bool {
+	return s.store.HasArchive(canvas, name)
+}
+
+// capture returns the current state of a hub's canvas
+func capture(hub *ws.Hub, at time.Time, pos db.StreamPosition) *Snapshot {
+	grid := hub.Grid()
+	return &Snapshot{
+		Canvas:    hub.Canvas(),
+		Width:     grid.Width(),
+		Height:    grid.Height(),
+		TakenAt:   at,
+		HistoryID: pos.HistoryID,
+		EventID:   pos.EventID,
+		Cells:     grid.GetActiveCells(),
+	}
+}
+
+// Restore loads the latest snapshot of a canvas into an empty grid and
+// replays the event stream recorded after it. It reports false, leaving the grid
+// empty, if there is no usable snapshot.
+func (s *Snapshotter) Restore(canvas string, grid *ws.GridState) (bool, error) {
+	snap, err := s.store.Latest(canvas, time.Now())
+	if err != nil || snap == nil {
+		return false, err
+	}
+	if snap.Width != grid.Width() || snap.Height != grid.Height() {
+		slog.Warn("Ignoring snapshot of resized canvas", "canvas", canvas,
+			"snapshot", fmt.Sprintf("%dx%d", snap.Width, snap.Height))
+		return false, nil
+	}
+
+	grid.LoadFromDB(snap.Cells)
+	replayed, resets := 0, 0
+	pos := db.StreamPosition{HistoryID: snap.HistoryID, EventID: snap.EventID}
+	err = db.ReplayStream(canvas, 0, 0, grid.Width(), grid.Height(), pos, time.Now(), func(records []db.PixelHistory) error {
+		for _, rec := range records {
+			grid.SetCell(rec.X, rec.Y, rec.Active, rec.Color)
+		}
+		replayed += len(records)
+		return nil
+	}, func(db.CanvasEvent) error {
+		grid.Initialize()
+		resets++
+		return nil
+	})
+	if err != nil {
+		grid.Initialize()
+		return false, err
+	}
+
+	s.mu.Lock()
+	s.lastPos[canvas] = pos
+	s.mu.Unlock()
+	slog.Info("Restored canvas from snapshot", "canvas", canvas, "taken_at", snap.TakenAt,
+		"cells", len(snap.Cells), "replayed", replayed, "resets", resets)
+	return true, nil
+}
+
+// Store returns the store the snapshots are written to
+func (s *Snapshotter) Store() *Store {
+	return s.store
+}
@@ -0,0 +1,215 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// Extension of snapshot files
+	fileExt = ".snap"
+
+	// Layout of snapshot file names, which sort in the order the snapshots were taken
+	fileTimeLayout = "20060102T150405.000000000Z"
+
+	// Subdirectory of a canvas's directory holding its named archives
+	archiveDir = "archive"
+)
+
+// ValidArchiveName matches the names archives can be saved under
+var ValidArchiveName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Store keeps snapshot files in a directory, one subdirectory per canvas
+type Store struct {
+	dir string
+}
+
+// NewStore opens (creating if needed) a snapshot directory
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Save writes a snapshot. The file is written under a temporary name and
+// renamed once synced, so a crash never leaves a partial snapshot behind.
+func (s *Store) Save(snap *Snapshot) error {
+	return s.write(filepath.Join(s.dir, snap.Canvas), s.path(snap.Canvas, snap.TakenAt), snap)
+}
+
+// SaveArchive writes a snapshot as the archive of its canvas with the given
+// name, replacing any archive of the same name
+func (s *Store) SaveArchive(name string, snap *Snapshot) error {
+	if !ValidArchiveName.MatchString(name) {
+		return fmt.Errorf("invalid archive name %q", name)
+	}
+	return s.write(filepath.Join(s.dir, snap.Canvas, archiveDir), s.archivePath(snap.Canvas, name), snap)
+}
+
+// LoadArchive reads the archive of a canvas with the given name, or returns
+// nil if there is none
+func (s *Store) LoadArchive(canvas, name string) (*Snapshot, error) {
+	if !ValidArchiveName.MatchString(name) {
+		return nil, nil
+	}
+	snap, err := s.read(canvas, s.archivePath(canvas, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return snap, err
+}
+
+// HasArchive reports whether a canvas has an archive with the given name
+func (s *Store) HasArchive(canvas, name string) bool {
+	if !ValidArchiveName.MatchString(name) {
+		return false
+	}
+	_, err := os.Stat(s.archivePath(canvas, name))
+	return err == nil
+}
+
+// Archives returns the names of the archives of a canvas, sorted
+func (s *Store) Archives(canvas string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, canvas, archiveDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archives: %w", err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), fileExt); ok && !entry.IsDir() && ValidArchiveName.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// write saves a snapshot to path through a temporary file in dir
+func (s *Store) write(dir, path string, snap *Snapshot) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	f, err := os.CreateTemp(dir, "tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	err = f.Chmod(0o644)
+	if err == nil {
+		err = Encode(f, snap)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// Latest loads the newest snapshot of a canvas taken at or before at, or
+// returns nil if there is none
+func (s *Store) Latest(canvas string, at time.Time) (*Snapshot, error) {
+	times, err := s.list(canvas)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(times) - 1; i >= 0; i-- {
+		if !times[i].After(at) {
+			return s.load(canvas, times[i])
+		}
+	}
+	return nil, nil
+}
+
+// Prune deletes all but the newest keep snapshots of a canvas
+func (s *Store) Prune(canvas string, keep int) error {
+	times, err := s.list(canvas)
+	if err != nil {
+		return err
+	}
+	for _, t := range times[:max(len(times)-keep, 0)] {
+		if err := os.Remove(s.path(canvas, t)); err != nil {
+			return fmt.Errorf("failed to delete snapshot: %w", err)
+		}
+	}
+	return nil
+}
+
+// list returns the times of the snapshots of a canvas, oldest first
+func (s *Store) list(canvas string) ([]time.Time, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, canvas))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	var times []time.Time
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), fileExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		t, err := time.Parse(fileTimeLayout, name)
+		if err != nil {
+			continue
+		}
+		times = append(times, t)
+	}
+	slices.SortFunc(times, time.Time.Compare)
+	return times, nil
+}
+
+// load reads the snapshot of a canvas taken at t
+func (s *Store) load(canvas string, t time.Time) (*Snapshot, error) {
+	return s.read(canvas, s.path(canvas, t))
+}
+
+// read loads the snapshot file at path, checking it belongs to the canvas
+func (s *Store) read(canvas, path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	snap, err := Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot %s: %w", f.Name(), err)
+	}
+	if snap.Canvas != canvas {
+		return nil, fmt.Errorf("snapshot %s belongs to canvas %q", f.Name(), snap.Canvas)
+	}
+	return snap, nil
+}
+
+// path returns the file name of the snapshot of a canvas taken at t
+func (s *Store) path(canvas string, t time.Time) string {
+	return filepath.Join(s.dir, canvas, t.UTC().Format(fileTimeLayout)+fileExt)
+}
+
+// archivePath returns the file name of the archive of a canvas with the given name
+func (s *Store) archivePath(canvas, name string) string {
+	return filepath.Join(s.dir, canvas, archiveDir, name+fileExt)
+}
@@ -0,0 +1,169 @@
+package timelapse
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+)
+
+// Status is the state of a timelapse job
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+// ErrQueueFull is returned when too many timelapses are waiting to be rendered
+var ErrQueueFull = errors.New("timelapse queue is full, try again later")
+
+// JobInfo describes a timelapse job
+type JobInfo struct {
+	ID        string    `json:"id"`
+	Status    Status    `json:"status"`
+	Canvas    string    `json:"canvas"`
+	Frames    int       `json:"frames,omitempty"`
+	Size      int       `json:"size,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Set once the job is done or failed
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// job is a timelapse request and its result once rendered
+type job struct {
+	info    JobInfo
+	request Request
+	result  []byte
+}
+
+// Manager renders timelapses in the background with a fixed number of workers
+// and keeps finished results for a while so clients can download them
+type Manager struct {
+	mu    sync.Mutex
+	jobs  map[string]*job
+	queue chan *job
+
+	// How long finished jobs are kept
+	ttl time.Duration
+}
+
+// NewManager creates a manager and starts its workers. At most queueSize jobs
+// wait for a worker at once.
+func NewManager(workers, queueSize int, ttl time.Duration) *Manager {
+	m := &Manager{
+		jobs:  make(map[string]*job),
+		queue: make(chan *job, queueSize),
+		ttl:   ttl,
+	}
+	for range workers {
+		go m.work()
+	}
+	return m
+}
+
+// Submit validates a request and queues it for rendering
+func (m *Manager) Submit(req Request) (JobInfo, error) {
+	if err := req.Validate(); err != nil {
+		return JobInfo{}, err
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return JobInfo{}, err
+	}
+	j := &job{
+		info:    JobInfo{ID: id, Status: StatusPending, Canvas: req.Canvas, CreatedAt: time.Now()},
+		request: req,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+
+	select {
+	case m.queue <- j:
+	default:
+		return JobInfo{}, ErrQueueFull
+	}
+	m.jobs[id] = j
+	return j.info, nil
+}
+
+// Get returns a job and, once it is done, the rendered GIF
+func (m *Manager) Get(id string) (JobInfo, []byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return JobInfo{}, nil, false
+	}
+	return j.info, j.result, true
+}
+
+// work renders queued jobs until the process exits
+func (m *Manager) work() {
+	for j := range m.queue {
+		m.setStatus(j, StatusRunning)
+
+		// Render from the database, so pending writes must reach it first
+		var gif []byte
+		var frames int
+		err := db.FlushPending()
+		if err == nil {
+			gif, frames, err = Render(j.request)
+		}
+
+		finished := time.Now()
+		m.mu.Lock()
+		j.info.FinishedAt = &finished
+		if err != nil {
+			j.info.Status = StatusFailed
+			j.info.Error = err.Error()
+			slog.Warn("Failed to render timelapse", "id", j.info.ID, "canvas", j.info.Canvas, "error", err)
+		} else {
+			j.info.Status = StatusDone
+			j.info.Frames = frames
+			j.info.Size = len(gif)
+			j.result = gif
+			slog.Info("Rendered timelapse", "id", j.info.ID, "canvas", j.info.Canvas, "frames", frames, "bytes", len(gif))
+		}
+		m.mu.Unlock()
+	}
+}
+
+// setStatus updates the status of a job
+func (m *Manager) setStatus(j *job, status Status) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j.info.Status = status
+}
+
+// expire drops finished jobs older than the ttl. Callers must hold m.mu.
+func (m *Manager) expire() {
+	cutoff := time.Now().Add(-m.ttl)
+	for id, j := range m.jobs {
+		if j.info.FinishedAt != nil && j.info.FinishedAt.Before(cutoff) {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// newJobID returns a random job identifier
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
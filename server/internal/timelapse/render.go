@@ -0,0 +1,240 @@
+// Package timelapse renders the evolution of a canvas region from the pixel
+// history log into animated GIFs.
+package timelapse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/model"
+)
+
+const (
+	// Maximum number of frames in a timelapse
+	MaxFrames = 500
+
+	// Maximum scale factor (output pixels per cell side)
+	MaxScale = 16
+
+	// Maximum width or height of a timelapse in pixels
+	MaxDimension = 2048
+
+	// Upper bound on frame pixels (width x height x frames), bounding memory use
+	maxTotalPixels = 256 << 20
+)
+
+// Request describes a timelapse: a region of a canvas rendered from From to To,
+// one frame per Interval of canvas time
+type Request struct {
+	Canvas string `json:"canvas"`
+
+	// Half-open cell bounds [X1, X2) x [Y1, Y2)
+	X1 int `json:"x1"`
+	Y1 int `json:"y1"`
+	X2 int `json:"x2"`
+	Y2 int `json:"y2"`
+
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Interval time.Duration `json:"interval"`
+
+	// Output pixels per cell side
+	Scale int `json:"scale"`
+
+	// Display time of each frame
+	Delay time.Duration `json:"delay"`
+}
+
+// Frames returns the number of frames the timelapse renders: the state at
+// From, then one per Interval up to and including To
+func (r Request) Frames() int {
+	if r.Interval <= 0 || r.To.Before(r.From) {
+		return 0
+	}
+	steps := int64(r.To.Sub(r.From) / r.Interval)
+	if r.From.Add(time.Duration(steps) * r.Interval).Before(r.To) {
+		steps++
+	}
+	return int(min(steps+1, MaxFrames+1))
+}
+
+// Validate checks the request against the rendering limits
+func (r Request) Validate() error {
+	if r.X1 < 0 || r.Y1 < 0 || r.X2 <= r.X1 || r.Y2 <= r.Y1 {
+		return errors.New("region is empty")
+	}
+	if r.Interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+	if r.To.Before(r.From) {
+		return errors.New("to must not be before from")
+	}
+	if r.Scale < 1 || r.Scale > MaxScale {
+		return fmt.Errorf("scale must be between 1 and %d", MaxScale)
+	}
+	if r.Delay < 10*time.Millisecond {
+		return errors.New("delay must be at least 10ms")
+	}
+
+	width, height := (r.X2-r.X1)*r.Scale, (r.Y2-r.Y1)*r.Scale
+	if width > MaxDimension || height > MaxDimension {
+		return errors.New("timelapse too large, reduce the region or scale")
+	}
+	frames := r.Frames()
+	if frames > MaxFrames {
+		return fmt.Errorf("timelapse would have more than %d frames, increase the interval", MaxFrames)
+	}
+	if width*height*frames > maxTotalPixels {
+		return errors.New("timelapse too large, reduce the region, scale or number of frames")
+	}
+	return nil
+}
+
+// renderer replays the event stream onto a paletted image and collects GIF frames
+type renderer struct {
+	req    Request
+	canvas *image.Paletted
+	anim   gif.GIF
+
+	// GIF palette index per cell color
+	indexes map[model.Color]uint8
+
+	// Area changed since the last frame
+	dirty image.Rectangle
+}
+
+// Render replays the event stream of the requested region into an animated GIF and
+// returns it with its number of frames
+func Render(req Request) ([]byte, int, error) {
+	if err := req.Validate(); err != nil {
+		return nil, 0, err
+	}
+
+	// Frame boundaries: frame i shows the state as of boundaries[i]
+	frames := req.Frames()
+	boundaries := make([]time.Time, frames)
+	for i := range boundaries {
+		boundaries[i] = req.From.Add(time.Duration(i) * req.Interval)
+	}
+	boundaries[frames-1] = req.To
+
+	width, height := (req.X2-req.X1)*req.Scale, (req.Y2-req.Y1)*req.Scale
+	r := &renderer{
+		req:     req,
+		canvas:  image.NewPaletted(image.Rect(0, 0, width, height), gifPalette()),
+		indexes: make(map[model.Color]uint8),
+	}
+	// Index 0 is white, so the canvas starts out blank
+	r.dirty = r.canvas.Bounds()
+
+	// Every frame shares the palette, so it is written once as the global color table
+	r.anim.Config = image.Config{ColorModel: r.canvas.Palette, Width: width, Height: height}
+
+	next := 0
+	advance := func(at time.Time) {
+		for next < frames && at.After(boundaries[next]) {
+			r.frame()
+			next++
+		}
+	}
+	err := db.ReplayStream(req.Canvas, req.X1, req.Y1, req.X2, req.Y2, db.StreamPosition{}, req.To, func(records []db.PixelHistory) error {
+		for _, rec := range records {
+			advance(rec.CreatedAt)
+			r.apply(rec)
+		}
+		return nil
+	}, func(event db.CanvasEvent) error {
+		advance(event.CreatedAt)
+		r.clear()
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	for ; next < frames; next++ {
+		r.frame()
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, &r.anim); err != nil {
+		return nil, 0, fmt.Errorf("failed to encode timelapse: %w", err)
+	}
+	return buf.Bytes(), frames, nil
+}
+
+// apply draws a history record onto the canvas
+func (r *renderer) apply(rec db.PixelHistory) {
+	index := uint8(0)
+	if rec.Active {
+		index = r.index(rec.Color)
+	}
+
+	px, py := (rec.X-r.req.X1)*r.req.Scale, (rec.Y-r.req.Y1)*r.req.Scale
+	for dy := 0; dy < r.req.Scale; dy++ {
+		row := r.canvas.PixOffset(px, py+dy)
+		for dx := 0; dx < r.req.Scale; dx++ {
+			r.canvas.Pix[row+dx] = index
+		}
+	}
+	r.dirty = r.dirty.Union(image.Rect(px, py, px+r.req.Scale, py+r.req.Scale))
+}
+
+// clear blanks the canvas, as a reset does
+func (r *renderer) clear() {
+	clear(r.canvas.Pix)
+	r.dirty = r.canvas.Bounds()
+}
+
+// frame appends the area changed since the previous frame as a new frame drawn
+// over it, or a single unchanged pixel if nothing changed
+func (r *renderer) frame() {
+	area := r.dirty
+	if area.Empty() {
+		area = image.Rect(0, 0, 1, 1)
+	}
+
+	img := image.NewPaletted(area, r.canvas.Palette)
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		copy(img.Pix[img.PixOffset(area.Min.X, y):img.PixOffset(area.Max.X, y)],
+			r.canvas.Pix[r.canvas.PixOffset(area.Min.X, y):r.canvas.PixOffset(area.Max.X, y)])
+	}
+
+	r.anim.Image = append(r.anim.Image, img)
+	r.anim.Delay = append(r.anim.Delay, int(r.req.Delay/(10*time.Millisecond)))
+	r.anim.Disposal = append(r.anim.Disposal, gif.DisposalNone)
+	r.dirty = image.Rectangle{}
+}
+
+// index returns the GIF palette index closest to a cell color
+func (r *renderer) index(c model.Color) uint8 {
+	if index, ok := r.indexes[c]; ok {
+		return index
+	}
+	index := uint8(r.canvas.Palette.Index(c.RGBA()))
+	r.indexes[c] = index
+	return index
+}
+
+// gifPalette returns the 256 GIF colors: white, the canvas palette, then web
+// colors for approximating anything else
+func gifPalette() color.Palette {
+	colors := color.Palette{color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}}
+	for _, c := range model.Palette() {
+		if len(colors) < 256 {
+			colors = append(colors, c.RGBA())
+		}
+	}
+	for _, c := range palette.WebSafe {
+		if len(colors) < 256 {
+			colors = append(colors, c)
+		}
+	}
+	return colors
+}
@@ -0,0 +1,398 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/model"
+	"github.com/million_grids/server/internal/ws"
+)
+
+// Event types a webhook can subscribe to
+const (
+	EventPixel     = "pixel"
+	EventReset     = "reset"
+	EventMilestone = "milestone"
+)
+
+// EventTypes lists the valid event types
+var EventTypes = []string{EventPixel, EventReset, EventMilestone}
+
+const (
+	// Number of payloads queued per webhook, further events are dropped
+	queueSize = 256
+
+	// Delivery attempts per payload, and the delay before the first retry
+	// (doubled after each failure)
+	maxAttempts  = 5
+	initialRetry = time.Second
+
+	// Time allowed for each delivery request
+	deliveryTimeout = 10 * time.Second
+)
+
+// Headers carrying the payload signature and the time it was signed at. The
+// signature is "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>" keyed
+// with the webhook secret.
+const (
+	SignatureHeader = "X-Million-Grids-Signature"
+	TimestampHeader = "X-Million-Grids-Timestamp"
+)
+
+// Payload is the JSON body POSTed to webhooks
+type Payload struct {
+	Event  string    `json:"event"`
+	Canvas string    `json:"canvas"`
+	At     time.Time `json:"at"`
+
+	// Changed cells, within the webhook's region if it has one (pixel events)
+	Cells []ws.BatchCell `json:"cells,omitempty"`
+
+	// Placements reached (milestone events)
+	Placements int64 `json:"placements,omitempty"`
+}
+
+// Stats counts the deliveries of a webhook since the server started
+type Stats struct {
+	// Payloads accepted by the endpoint (2xx response)
+	Delivered int64 `json:"delivered"`
+
+	// Payloads given up on after maxAttempts
+	Failed int64 `json:"failed"`
+
+	// Attempts that were retried
+	Retries int64 `json:"retries"`
+
+	// Events dropped because the queue was full or the rate limit was exceeded
+	Dropped     int64 `json:"dropped"`
+	RateLimited int64 `json:"rate_limited"`
+
+	// Outcome of the latest attempt
+	LastStatus  int        `json:"last_status,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastAttempt *time.Time `json:"last_attempt,omitempty"`
+}
+
+// Info describes a registered webhook and its delivery stats
+type Info struct {
+	db.Webhook
+	Region *ws.Region `json:"region,omitempty"`
+	Stats  Stats      `json:"stats"`
+}
+
+// hook is a registered webhook and its delivery queue
+type hook struct {
+	config db.Webhook
+	events []string
+	queue  chan []byte
+
+	// Throttles enqueued payloads (guarded by limitMu)
+	limiter *ws.RateLimiter
+	limitMu sync.Mutex
+
+	delivered, failed, retries, dropped, rateLimited atomic.Int64
+
+	// Outcome of the latest attempt (guarded by lastMu)
+	lastStatus  int
+	lastError   string
+	lastAttempt time.Time
+	lastMu      sync.Mutex
+
+	// Closed to stop the worker
+	done chan struct{}
+}
+
+// Dispatcher delivers canvas events to the registered webhooks. It is the
+// ws.EventSink of every hub; each webhook has a queue drained by its own
+// worker, so a slow endpoint delays only its own deliveries.
+type Dispatcher struct {
+	client *http.Client
+
+	hooks map[uint]*hook
+	mu    sync.RWMutex
+}
+
+// NewDispatcher creates a dispatcher with no webhooks
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		client: &http.Client{Timeout: deliveryTimeout},
+		hooks:  make(map[uint]*hook),
+	}
+}
+
+// Load registers the webhooks stored in the database
+func (d *Dispatcher) Load() error {
+	hooks, err := db.LoadWebhooks()
+	if err != nil {
+		return err
+	}
+	for _, config := range hooks {
+		d.start(config)
+	}
+	return nil
+}
+
+// Add stores and registers a webhook, filling in its ID
+func (d *Dispatcher) Add(config *db.Webhook) error {
+	if err := db.SaveWebhook(config); err != nil {
+		return fmt.Errorf("failed to save webhook: %w", err)
+	}
+	d.mu.Lock()
+	if config.ID == 0 {
+		// Not persisted (no database), number it after the others
+		for id := range d.hooks {
+			config.ID = max(config.ID, id)
+		}
+		config.ID++
+	}
+	d.mu.Unlock()
+	d.start(*config)
+	return nil
+}
+
+// Remove deletes a webhook, reporting false if there is no such webhook.
+// Payloads still queued for it are discarded.
+func (d *Dispatcher) Remove(id uint) (bool, error) {
+	d.mu.Lock()
+	h, ok := d.hooks[id]
+	delete(d.hooks, id)
+	d.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	close(h.done)
+	if err := db.DeleteWebhook(id); err != nil {
+		return true, fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return true, nil
+}
+
+// List returns the registered webhooks, oldest first
+func (d *Dispatcher) List() []Info {
+	d.mu.RLock()
+	hooks := make([]*hook, 0, len(d.hooks))
+	for _, h := range d.hooks {
+		hooks = append(hooks, h)
+	}
+	d.mu.RUnlock()
+	slices.SortFunc(hooks, func(a, b *hook) int { return int(a.config.ID) - int(b.config.ID) })
+
+	infos := make([]Info, 0, len(hooks))
+	for _, h := range hooks {
+		info := Info{Webhook: h.config, Stats: h.stats()}
+		if r, ok := h.region(); ok {
+			info.Region = &r
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// start registers a webhook and starts its worker
+func (d *Dispatcher) start(config db.Webhook) {
+	h := &hook{
+		config: config,
+		events: strings.Split(config.Events, ","),
+		queue:  make(chan []byte, queueSize),
+		done:   make(chan struct{}),
+	}
+	if config.MaxPerMinute > 0 {
+		h.limiter = ws.NewRateLimiter(float64(config.MaxPerMinute)/60, config.MaxPerMinute)
+	}
+	d.mu.Lock()
+	d.hooks[config.ID] = h
+	d.mu.Unlock()
+	go d.work(h)
+}
+
+// PixelsChanged queues a pixel event for the webhooks watching the cells
+func (d *Dispatcher) PixelsChanged(canvas string, changed []model.Pixel) {
+	d.dispatch(EventPixel, canvas, func(h *hook) *Payload {
+		var cells []ws.BatchCell
+		region, limited := h.region()
+		for _, p := range changed {
+			if limited && !region.Contains(p.X, p.Y) {
+				continue
+			}
+			cells = append(cells, ws.NewBatchCell(p))
+		}
+		if len(cells) == 0 {
+			return nil
+		}
+		return &Payload{Cells: cells}
+	})
+}
+
+// CanvasReset queues a reset event
+func (d *Dispatcher) CanvasReset(canvas string) {
+	d.dispatch(EventReset, canvas, func(*hook) *Payload { return &Payload{} })
+}
+
+// Milestone queues a milestone event
+func (d *Dispatcher) Milestone(canvas string, placements int64) {
+	d.dispatch(EventMilestone, canvas, func(*hook) *Payload { return &Payload{Placements: placements} })
+}
+
+// dispatch queues the payload built for each webhook subscribed to the event
+// (build returns nil to skip a webhook), dropping it if the webhook is over
+// its rate or has a full queue
+func (d *Dispatcher) dispatch(event, canvas string, build func(*hook) *Payload) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	now := time.Now()
+	for _, h := range d.hooks {
+		if !slices.Contains(h.events, event) || (h.config.Canvas != "" && h.config.Canvas != canvas) {
+			continue
+		}
+		payload := build(h)
+		if payload == nil {
+			continue
+		}
+		if !h.allow() {
+			h.rateLimited.Add(1)
+			continue
+		}
+		payload.Event, payload.Canvas, payload.At = event, canvas, now
+		body, err := json.Marshal(payload)
+		if err != nil {
+			slog.Error("Failed to marshal webhook payload", "err", err)
+			continue
+		}
+		select {
+		case h.queue <- body:
+		default:
+			h.dropped.Add(1)
+		}
+	}
+}
+
+// work delivers the queued payloads of a webhook in order until it is removed
+func (d *Dispatcher) work(h *hook) {
+	for {
+		select {
+		case <-h.done:
+			return
+		case body := <-h.queue:
+			d.deliver(h, body)
+		}
+	}
+}
+
+// deliver POSTs a payload, retrying failures with exponential backoff
+func (d *Dispatcher) deliver(h *hook, body []byte) {
+	delay := initialRetry
+	for attempt := 1; ; attempt++ {
+		status, err := d.post(h, body)
+		h.record(status, err)
+		if err == nil {
+			h.delivered.Add(1)
+			return
+		}
+		if attempt == maxAttempts {
+			h.failed.Add(1)
+			slog.Warn("Giving up on webhook delivery", "webhook", h.config.ID, "attempts", attempt, "err", err)
+			return
+		}
+		h.retries.Add(1)
+		select {
+		case <-h.done:
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post sends a signed payload, returning the response status and an error
+// unless the endpoint accepted it
+func (d *Dispatcher) post(h *hook, body []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(h.config.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature header value of a payload
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// allow takes a token from the webhook's rate limit
+func (h *hook) allow() bool {
+	if h.limiter == nil {
+		return true
+	}
+	h.limitMu.Lock()
+	defer h.limitMu.Unlock()
+	return h.limiter.Allow()
+}
+
+// region returns the region the webhook's pixel events are limited to, if any
+func (h *hook) region() (ws.Region, bool) {
+	c := h.config
+	return ws.Region{X1: c.X1, Y1: c.Y1, X2: c.X2, Y2: c.Y2}, c.HasRegion
+}
+
+// record keeps the outcome of a delivery attempt
+func (h *hook) record(status int, err error) {
+	h.lastMu.Lock()
+	defer h.lastMu.Unlock()
+	h.lastStatus = status
+	h.lastError = ""
+	if err != nil {
+		h.lastError = err.Error()
+	}
+	h.lastAttempt = time.Now()
+}
+
+// stats returns a snapshot of the webhook's delivery counters
+func (h *hook) stats() Stats {
+	s := Stats{
+		Delivered:   h.delivered.Load(),
+		Failed:      h.failed.Load(),
+		Retries:     h.retries.Load(),
+		Dropped:     h.dropped.Load(),
+		RateLimited: h.rateLimited.Load(),
+	}
+	h.lastMu.Lock()
+	defer h.lastMu.Unlock()
+	if !h.lastAttempt.IsZero() {
+		at := h.lastAttempt
+		s.LastStatus, s.LastError, s.LastAttempt = h.lastStatus, h.lastError, &at
+	}
+	return s
+}
@@ -0,0 +1,90 @@
+package ws
+
+import (
+	"sync"
+	"time"
+)
+
+// activityWindow is how far back Activity remembers placements
+const activityWindow = 24 * time.Hour
+
+// Activity counts placements per minute over the last day, in total and per
+// country of the client (when known)
+type Activity struct {
+	// Ring of per-minute counts, indexed by minute modulo its length. Each
+	// bucket keeps the minute it counts so stale ones are skipped or reset.
+	buckets [activityWindow / time.Minute]activityBucket
+
+	mu sync.Mutex
+}
+
+type activityBucket struct {
+	minute int64
+	count  int
+
+	// Placements per country code, allocated for the first located placement
+	countries map[string]int
+}
+
+// NewActivity creates an empty Activity
+func NewActivity() *Activity {
+	return &Activity{}
+}
+
+// Record counts n placements made at the given time from a country (empty if unknown)
+func (a *Activity) Record(n int, country string, at time.Time) {
+	if n <= 0 {
+		return
+	}
+	minute := at.Unix() / 60
+	b := &a.buckets[minute%int64(len(a.buckets))]
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if b.minute != minute {
+		*b = activityBucket{minute: minute}
+	}
+	b.count += n
+	if country != "" {
+		if b.countries == nil {
+			b.countries = make(map[string]int)
+		}
+		b.countries[country] += n
+	}
+}
+
+// Since returns the number of placements in the window (up to a day) before
+// now, at a resolution of a minute
+func (a *Activity) Since(window time.Duration, now time.Time) int {
+	total := 0
+	a.each(window, now, func(b *activityBucket) {
+		total += b.count
+	})
+	return total
+}
+
+// CountriesSince returns the number of located placements per country code in
+// the window (up to a day) before now, at a resolution of a minute
+func (a *Activity) CountriesSince(window time.Duration, now time.Time) map[string]int {
+	countries := make(map[string]int)
+	a.each(window, now, func(b *activityBucket) {
+		for country, n := range b.countries {
+			countries[country] += n
+		}
+	})
+	return countries
+}
+
+// each calls fn with the buckets of the minutes in the window before now
+func (a *Activity) each(window time.Duration, now time.Time, fn func(*activityBucket)) {
+	current := now.Unix() / 60
+	oldest := current - int64(min(window, activityWindow)/time.Minute) + 1
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := range a.buckets {
+		if b := &a.buckets[i]; b.minute >= oldest && b.minute <= current {
+			fn(b)
+		}
+	}
+}
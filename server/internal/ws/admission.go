@@ -0,0 +1,105 @@
+package ws
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// LoadShedder refuses new connections while the server is saturated, so the
+// clients already connected keep being served instead of all degrading: when
+// the process uses more than a share of its CPUs, or the broadcast queues of a
+// canvas are filled past a share, over the last interval.
+type LoadShedder struct {
+	canvases *Canvases
+	interval time.Duration
+
+	// Shares of the CPUs and of the broadcast queues above which new
+	// connections are refused (0 disables the check)
+	maxCPU   float64
+	maxQueue float64
+
+	// Why new connections are refused, nil while they are admitted
+	reason atomic.Pointer[string]
+
+	// Connections refused since the server started
+	shed atomic.Int64
+}
+
+// NewLoadShedder creates a LoadShedder sampling the load of the process and
+// the canvases every interval
+func NewLoadShedder(canvases *Canvases, maxCPU, maxQueue float64, interval time.Duration) *LoadShedder {
+	return &LoadShedder{
+		canvases: canvases,
+		interval: interval,
+		maxCPU:   maxCPU,
+		maxQueue: maxQueue,
+	}
+}
+
+// Run samples the load every interval
+func (s *LoadShedder) Run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	last, lastCPU := time.Now(), processCPUTime()
+	for now := range ticker.C {
+		cpuTime := processCPUTime()
+		cpu := (cpuTime - lastCPU).Seconds() / now.Sub(last).Seconds() / float64(runtime.GOMAXPROCS(0))
+		last, lastCPU = now, cpuTime
+
+		var queue float64
+		for _, hub := range s.canvases.Hubs() {
+			queue = max(queue, hub.QueueDepth())
+		}
+
+		var reason string
+		switch {
+		case s.maxCPU > 0 && cpu > s.maxCPU:
+			reason = fmt.Sprintf("cpu usage at %.0f%%", cpu*100)
+		case s.maxQueue > 0 && queue > s.maxQueue:
+			reason = fmt.Sprintf("broadcast queue at %.0f%%", queue*100)
+		}
+		s.update(reason)
+	}
+}
+
+// update records why new connections are refused, logging when shedding
+// starts and ends
+func (s *LoadShedder) update(reason string) {
+	if reason == "" {
+		if s.reason.Swap(nil) != nil {
+			slog.Info("Server no longer saturated, admitting new connections", "shed", s.shed.Load())
+		}
+		return
+	}
+	if s.reason.Swap(&reason) == nil {
+		slog.Warn("Server saturated, shedding new connections", "reason", reason)
+	}
+}
+
+// Saturated reports whether a new connection must be refused, and why,
+// counting it as shed. A nil LoadShedder admits every connection.
+func (s *LoadShedder) Saturated() (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	reason := s.reason.Load()
+	if reason == nil {
+		return "", false
+	}
+	s.shed.Add(1)
+	return *reason, true
+}
+
+// processCPUTime returns the CPU time used by the process so far
+func processCPUTime() time.Duration {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
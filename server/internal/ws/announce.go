@@ -0,0 +1,48 @@
+package ws
+
+import (
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Severities of announcements, from least to most urgent
+var Severities = []string{"info", "warning", "critical"}
+
+// AnnounceMessage is pushed to every client by an admin, e.g. for maintenance
+// notices, event starts and canvas reset warnings
+type AnnounceMessage struct {
+	Type     string `json:"t"`
+	Text     string `json:"text"`
+	Severity string `json:"severity"` // One of Severities
+
+	// Seconds from At until the announced event (0 for none)
+	Countdown int `json:"countdown,omitempty"`
+
+	At time.Time `json:"at"`
+}
+
+// ValidSeverity reports whether an announcement severity is known
+func ValidSeverity(severity string) bool {
+	return slices.Contains(Severities, severity)
+}
+
+// Announce sends an announcement to all clients ahead of their queued
+// updates and publishes it to the other instances through the broker
+func (h *Hub) Announce(msg AnnounceMessage) {
+	msg.Type = "announce"
+	message, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("Failed to marshal announcement", "err", err)
+		return
+	}
+	h.enqueue(&outbound{data: message, priority: PriorityHigh})
+	slog.Info("Announcement sent", "canvas", h.config.Canvas, "severity", msg.Severity, "clients", h.ClientCount())
+
+	if h.config.Broker != nil {
+		if err := h.config.Broker.Publish(message); err != nil {
+			slog.Error("Failed to publish announcement to broker", "err", err)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package ws
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// anonymousPrefix marks the actors that are hashed IPs
+const anonymousPrefix = "ip:"
+
+// IPAnonymizer replaces the IPs anonymous changes are attributed to with keyed
+// hashes, so the database never stores them. The same salt must be used by
+// every instance and across restarts for the hashes to stay comparable.
+type IPAnonymizer struct {
+	salt []byte
+}
+
+// NewIPAnonymizer creates an anonymizer hashing with the salt
+func NewIPAnonymizer(salt string) *IPAnonymizer {
+	return &IPAnonymizer{salt: []byte(salt)}
+}
+
+// Actor returns the ID the changes of an anonymous client at ip are attributed
+// to: the IP itself, or "ip:" and its hash with an anonymizer
+func (a *IPAnonymizer) Actor(ip string) string {
+	if a == nil {
+		return ip
+	}
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(ip))
+	return anonymousPrefix + hex.EncodeToString(mac.Sum(nil))[:32]
+}
@@ -0,0 +1,131 @@
+package ws
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// bandwidth counts the bytes written to one client and paces them to an
+// optional cap, a token bucket of bytes holding up to one second of traffic.
+// The write pump records what it writes, the fan-out worker checks the bucket
+// before queueing low-priority messages.
+type bandwidth struct {
+	// Bytes per second (0 for no cap)
+	rate float64
+
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+
+	// Bytes and messages written, and low-priority messages dropped for
+	// exceeding the cap
+	bytes, messages, dropped atomic.Int64
+
+	// Broadcasts dropped as the send lane was full
+	droppedFull atomic.Int64
+}
+
+func newBandwidth(rate int64) *bandwidth {
+	return &bandwidth{rate: float64(rate), tokens: float64(rate), last: time.Now()}
+}
+
+// record counts a frame of messages written to the client, returning how long
+// to pause before writing more to stay under the cap
+func (b *bandwidth) record(bytes, messages int) time.Duration {
+	b.bytes.Add(int64(bytes))
+	b.messages.Add(int64(messages))
+	if b.rate <= 0 {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	b.tokens -= float64(bytes)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// available reports whether the client is under its cap, so low-priority
+// messages may be queued
+func (b *bandwidth) available() bool {
+	if b.rate <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	return b.tokens > 0
+}
+
+// refill adds the tokens earned since the last call, b.mu must be held
+func (b *bandwidth) refill() {
+	now := time.Now()
+	b.tokens = min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// ClientStats describes a connected client and its traffic
+type ClientStats struct {
+	ID          uint64    `json:"id"`
+	Conn        string    `json:"conn"`
+	Name        string    `json:"name"`
+	IP          string    `json:"ip"`
+	Encoding    string    `json:"encoding"`
+	ConnectedAt time.Time `json:"connected_at"`
+	LastActive  time.Time `json:"last_active"`
+
+	// Bytes and messages written to the client, messages waiting in its send
+	// lanes, and low-priority messages dropped for exceeding its bandwidth cap
+	BytesSent    int64 `json:"bytes_sent"`
+	MessagesSent int64 `json:"messages_sent"`
+	Queued       int   `json:"queued"`
+	Dropped      int64 `json:"dropped"`
+
+	// Broadcasts dropped as its send lanes were full, and messages read from
+	// the client
+	DroppedFull      int64 `json:"dropped_full"`
+	MessagesReceived int64 `json:"messages_received"`
+}
+
+// Stats returns the client's traffic
+func (c *Client) Stats() ClientStats {
+	encoding := "json"
+	if c.encoding == EncodingProtobuf {
+		encoding = "protobuf"
+	}
+	return ClientStats{
+		ID:           c.id,
+		Conn:         c.connID,
+		Name:         c.name,
+		IP:           c.ipAddress,
+		Encoding:     encoding,
+		ConnectedAt:  c.connectedAt,
+		LastActive:   time.Unix(0, c.lastActive.Load()),
+		BytesSent:    c.bandwidth.bytes.Load(),
+		MessagesSent: c.bandwidth.messages.Load(),
+		Queued:       c.queued(),
+		Dropped:      c.bandwidth.dropped.Load(),
+
+		DroppedFull:      c.bandwidth.droppedFull.Load(),
+		MessagesReceived: c.received.Load(),
+	}
+}
+
+// ClientStats returns the stats of the clients connected to the hub, by
+// connection ID
+func (h *Hub) ClientStats() []ClientStats {
+	h.mu.RLock()
+	clients := make([]ClientStats, 0, len(h.canvasRoom.members))
+	for client := range h.canvasRoom.members {
+		clients = append(clients, client.Stats())
+	}
+	h.mu.RUnlock()
+	slices.SortFunc(clients, func(a, b ClientStats) int { return cmp.Compare(a.ID, b.ID) })
+	return clients
+}
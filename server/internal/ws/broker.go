@@ -0,0 +1,213 @@
+package ws
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// Broker propagates cell updates, chat messages, announcements, locked
+// regions, templates and resets between server instances sharing one canvas
+type Broker interface {
+	// Publish sends a broadcast message to the other instances
+	Publish(message []byte) error
+
+	// Subscribe delivers messages published by other instances to handler.
+	// It blocks until the broker is closed.
+	Subscribe(handler func(message []byte)) error
+
+	// Close stops the subscription and releases the connection
+	Close() error
+}
+
+// remoteUpdate is the subset of broadcast messages applied from other instances
+type remoteUpdate struct {
+	Type   string      `json:"t"`
+	X      int         `json:"x"`
+	Y      int         `json:"y"`
+	Active int         `json:"a"`
+	Color  model.Color `json:"color"`
+	Cells  []BatchCell `json:"cells"`
+	Team   string      `json:"team"`
+
+	// Version of cell changes with multi-region replication
+	Clock  uint64 `json:"clock"`
+	Region string `json:"region"`
+
+	// Palette changes
+	Version int           `json:"version"`
+	Colors  []model.Color `json:"colors"`
+}
+
+// consumeBroker applies updates published by other instances until the broker is closed
+func (h *Hub) consumeBroker() {
+	if err := h.config.Broker.Subscribe(h.handleRemote); err != nil {
+		slog.Error("Broker subscription ended", "err", err)
+	}
+}
+
+// handleRemote applies a cell update from another instance to the local grid
+// and forwards it to local clients without publishing it again
+func (h *Hub) handleRemote(message []byte) {
+	var update remoteUpdate
+	if err := json.Unmarshal(message, &update); err != nil {
+		slog.Warn("Error parsing broker message", "err", err)
+		return
+	}
+
+	switch update.Type {
+	case "u", "b":
+		if update.Clock != 0 {
+			h.handleRemoteVersioned(update, message)
+			return
+		}
+	}
+
+	switch update.Type {
+	case "u":
+		h.grid.SetCell(update.X, update.Y, update.Active == 1, update.Color)
+		h.territory.Apply([]model.Pixel{{X: update.X, Y: update.Y, Active: update.Active == 1, Team: update.Team}})
+		h.updateTemplates([]model.Pixel{{X: update.X, Y: update.Y}})
+		h.activity.Record(1, "", time.Now())
+		h.countPlacements(1, false)
+		h.enqueue(&outbound{data: message, region: regionPtr(CellRegion(update.X, update.Y))})
+
+	case "b":
+		var bounds Region
+		pixels := make([]model.Pixel, len(update.Cells))
+		for i, cell := range update.Cells {
+			h.grid.SetCell(cell.X, cell.Y, cell.Active == 1, cell.Color)
+			bounds = bounds.Extend(cell.X, cell.Y)
+			pixels[i] = model.Pixel{X: cell.X, Y: cell.Y, Active: cell.Active == 1, Team: update.Team}
+		}
+		h.territory.Apply(pixels)
+		h.updateTemplates(pixels)
+		h.activity.Record(len(update.Cells), "", time.Now())
+		h.countPlacements(len(update.Cells), false)
+		if !bounds.Empty() {
+			h.enqueue(&outbound{data: message, region: &bounds})
+		}
+
+	case "chat":
+		var msg BroadcastChat
+		if err := json.Unmarshal(message, &msg); err != nil {
+			slog.Warn("Error parsing chat message from broker", "err", err)
+			return
+		}
+		if msg.Channel != "" {
+			h.enqueue(&outbound{data: message, room: chatRoomPrefix + msg.Channel, priority: PriorityLow})
+			return
+		}
+		h.keepChat(msg)
+		h.broadcastLow(message)
+
+	case "frozen":
+		var msg FrozenMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			slog.Warn("Error parsing read-only mode from broker", "err", err)
+			return
+		}
+		h.applyReadOnly(msg.Enabled, msg.Reason)
+
+	case "locks":
+		var msg LocksMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			slog.Warn("Error parsing locked regions from broker", "err", err)
+			return
+		}
+		h.locksMu.Lock()
+		h.applyLocks(msg.Regions)
+		h.locksMu.Unlock()
+
+	case "announce":
+		h.enqueue(&outbound{data: message, priority: PriorityHigh})
+
+	case "reset":
+		var msg ResetMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			slog.Warn("Error parsing reset from broker", "err", err)
+			return
+		}
+		h.placing.Lock()
+		h.applyReset(msg)
+		h.placing.Unlock()
+
+	case "meta":
+		if err := h.applyRemoteCellMeta(message); err != nil {
+			slog.Warn("Error parsing cell metadata from broker", "err", err)
+		}
+
+	case "claims":
+		var msg ClaimsMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			slog.Warn("Error parsing claims from broker", "err", err)
+			return
+		}
+		h.claimsMu.Lock()
+		h.applyClaims(msg.Claims)
+		h.claimsMu.Unlock()
+
+	case "template":
+		if err := h.applyRemoteTemplate(message); err != nil {
+			slog.Warn("Error parsing template from broker", "err", err)
+		}
+
+	case "template_removed":
+		var msg templateRemovedMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			slog.Warn("Error parsing template removal from broker", "err", err)
+			return
+		}
+		h.removeTemplate(msg.ID)
+
+	case "palette":
+		// Every canvas of this instance receives the change, the first one activates it
+		model.ActivatePalette(update.Version, update.Colors)
+		if update.Version == model.PaletteVersion() {
+			h.enqueue(&outbound{data: message})
+		}
+	}
+}
+
+// handleRemoteVersioned applies a versioned cell update from another instance
+// of our region to the cells it wins, and forwards it to local clients
+func (h *Hub) handleRemoteVersioned(update remoteUpdate, message []byte) {
+	cells := update.Cells
+	if update.Type == "u" {
+		cells = []BatchCell{{X: update.X, Y: update.Y, Active: update.Active, Color: update.Color}}
+	}
+	pixels := make([]model.Pixel, len(cells))
+	for i, cell := range cells {
+		pixels[i] = model.Pixel{X: cell.X, Y: cell.Y, Active: cell.Active == 1, Color: cell.Color, Team: update.Team}
+	}
+	changed := h.applyVersioned(pixels, cellVersion{Clock: update.Clock, Region: update.Region})
+	if len(changed) == 0 {
+		return
+	}
+
+	h.territory.Apply(changed)
+	h.updateTemplates(changed)
+	h.activity.Record(len(changed), "", time.Now())
+	h.countPlacements(len(changed), false)
+	var bounds Region
+	for _, p := range changed {
+		bounds = bounds.Extend(p.X, p.Y)
+	}
+
+	// Forward only the cells that changed
+	if len(changed) < len(cells) {
+		batch := make([]BatchCell, len(changed))
+		for i, p := range changed {
+			batch[i] = NewBatchCell(p)
+		}
+		message, _ = json.Marshal(BroadcastBatchUpdate{Type: "b", Cells: batch, Team: update.Team, Clock: update.Clock, Region: update.Region})
+	}
+	h.enqueue(&outbound{data: message, region: &bounds})
+}
+
+// regionPtr returns a pointer to a copy of the region
+func regionPtr(r Region) *Region {
+	return &r
+}
@@ -0,0 +1,174 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+)
+
+// Canvases is the set of named canvases served by one server, each with its
+// own grid and hub. Moderation (bans) applies to every canvas.
+type Canvases struct {
+	// Hubs keyed by canvas name
+	hubs map[string]*Hub
+
+	// Canvas names in the order they were added
+	names []string
+
+	mu sync.RWMutex
+}
+
+// NewCanvases creates an empty canvas set
+func NewCanvases() *Canvases {
+	return &Canvases{hubs: make(map[string]*Hub)}
+}
+
+// Add registers the hub under its canvas name. The first canvas added is the default.
+func (c *Canvases) Add(hub *Hub) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.hubs[hub.Canvas()]; !ok {
+		c.names = append(c.names, hub.Canvas())
+	}
+	c.hubs[hub.Canvas()] = hub
+}
+
+// Get returns the hub of a canvas. An empty name selects the default canvas.
+func (c *Canvases) Get(name string) (*Hub, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if name == "" {
+		if len(c.names) == 0 {
+			return nil, false
+		}
+		name = c.names[0]
+	}
+	hub, ok := c.hubs[name]
+	return hub, ok
+}
+
+// Hubs returns the hubs of every canvas in the order they were added
+func (c *Canvases) Hubs() []*Hub {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	hubs := make([]*Hub, len(c.names))
+	for i, name := range c.names {
+		hubs[i] = c.hubs[name]
+	}
+	return hubs
+}
+
+// ClientCount returns the number of clients connected to any canvas
+func (c *Canvases) ClientCount() int {
+	total := 0
+	for _, hub := range c.Hubs() {
+		total += hub.ClientCount()
+	}
+	return total
+}
+
+// ShuttingDown reports whether the canvases are draining connections
+func (c *Canvases) ShuttingDown() bool {
+	for _, hub := range c.Hubs() {
+		if hub.ShuttingDown() {
+			return true
+		}
+	}
+	return false
+}
+
+// Shutdown closes the clients of every canvas, see Hub.Shutdown
+func (c *Canvases) Shutdown(ctx context.Context) error {
+	hubs := c.Hubs()
+	for _, hub := range hubs {
+		hub.shuttingDown.Store(true)
+	}
+
+	errs := make([]error, len(hubs))
+	var wg sync.WaitGroup
+	for i, hub := range hubs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = hub.Shutdown(ctx)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Close stops the hub of every canvas, see Hub.Close
+func (c *Canvases) Close() {
+	for _, hub := range c.Hubs() {
+		hub.Close()
+	}
+}
+
+// CloseListeners marks every canvas as shutting down and ends the streams of
+// their listeners, so long-lived HTTP responses finish before the server
+// waits for them
+func (c *Canvases) CloseListeners() {
+	for _, hub := range c.Hubs() {
+		hub.shuttingDown.Store(true)
+		hub.closeListeners()
+	}
+}
+
+// BroadcastPalette sends the active palette to the clients of every canvas
+func (c *Canvases) BroadcastPalette() {
+	for _, hub := range c.Hubs() {
+		hub.BroadcastPalette()
+	}
+}
+
+// Announce sends an announcement to the clients of every canvas
+func (c *Canvases) Announce(msg AnnounceMessage) {
+	for _, hub := range c.Hubs() {
+		hub.Announce(msg)
+	}
+}
+
+// Ban bans a network on every canvas
+func (c *Canvases) Ban(network *net.IPNet) {
+	for _, hub := range c.Hubs() {
+		hub.Ban(network)
+	}
+}
+
+// Unban lifts a ban on every canvas
+func (c *Canvases) Unban(network *net.IPNet) {
+	for _, hub := range c.Hubs() {
+		hub.Unban(network)
+	}
+}
+
+// DisconnectUser closes the clients authenticated as the user on every canvas
+func (c *Canvases) DisconnectUser(userID, reason string) int {
+	n := 0
+	for _, hub := range c.Hubs() {
+		n += hub.DisconnectUser(userID, reason)
+	}
+	return n
+}
+
+// ShadowBan shadow-bans a network or user on every canvas
+func (c *Canvases) ShadowBan(target string) {
+	for _, hub := range c.Hubs() {
+		hub.ShadowBan(target)
+	}
+}
+
+// ShadowUnban lifts a shadow ban on every canvas
+func (c *Canvases) ShadowUnban(target string) {
+	for _, hub := range c.Hubs() {
+		hub.ShadowUnban(target)
+	}
+}
+
+// IsBanned reports whether an IP is banned. Bans are applied to every canvas,
+// so the default canvas is authoritative.
+func (c *Canvases) IsBanned(ip string) bool {
+	hub, ok := c.Get("")
+	return ok && hub.IsBanned(ip)
+}
@@ -0,0 +1,193 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Time after which the remaining placements of an idle actor are forgotten
+const captchaIdle = time.Hour
+
+// ErrCaptchaInvalid is returned for CAPTCHA tokens the provider rejects
+var ErrCaptchaInvalid = errors.New("invalid captcha token")
+
+// CaptchaVerifier checks CAPTCHA tokens solved by clients, returning an error
+// wrapping ErrCaptchaInvalid if the token is rejected, and another error if it
+// couldn't be checked
+type CaptchaVerifier interface {
+	Verify(ctx context.Context, token, ip string) error
+}
+
+// CaptchaPolicy configures the CAPTCHA challenge of unauthenticated actors,
+// as clients need to know it to render the widget
+type CaptchaPolicy struct {
+	Provider string `json:"provider"`
+	SiteKey  string `json:"site_key"`
+
+	// Whether WebSocket clients must present a solved token to connect
+	OnConnect bool `json:"on_connect"`
+
+	// Placements allowed between challenges (0 never challenges placements)
+	Placements int `json:"placements"`
+}
+
+// CaptchaSolution presents a CAPTCHA token solved by the client
+type CaptchaSolution struct {
+	Type  string `json:"type"`
+	Token string `json:"token"`
+}
+
+// CaptchaMessage is sent to a client whose CAPTCHA token was accepted
+type CaptchaMessage struct {
+	Type string `json:"t"`
+
+	// Placements allowed before the next challenge (0 when placements aren't challenged)
+	Remaining int `json:"remaining"`
+}
+
+// captchaCredit is what an actor has left until its next challenge
+type captchaCredit struct {
+	remaining int
+	last      time.Time
+}
+
+// CaptchaGate challenges unauthenticated actors every few placements,
+// counting the placements each has left since its last solved challenge.
+// Actors unknown to it start with a full allowance, unless they must solve a
+// challenge to connect.
+type CaptchaGate struct {
+	policy   CaptchaPolicy
+	verifier CaptchaVerifier
+
+	credits map[string]*captchaCredit
+
+	// Time of the last sweep of idle actors
+	lastSweep time.Time
+
+	mu sync.Mutex
+}
+
+// NewCaptchaGate creates a CaptchaGate with the given policy, checking tokens
+// with verifier (nil disables the challenge)
+func NewCaptchaGate(policy CaptchaPolicy, verifier CaptchaVerifier) *CaptchaGate {
+	return &CaptchaGate{policy: policy, verifier: verifier, credits: make(map[string]*captchaCredit)}
+}
+
+// Solve verifies a token solved by actor from the IP, renewing its
+// allowance of placements when it is accepted
+func (g *CaptchaGate) Solve(ctx context.Context, actor, ip, token string) error {
+	if err := g.verifier.Verify(ctx, token, ip); err != nil {
+		return err
+	}
+	if g.policy.Placements == 0 {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.credits[actor] = &captchaCredit{remaining: g.policy.Placements, last: time.Now()}
+	return nil
+}
+
+// Consume uses up a placement of actor, returning false if it must solve a
+// challenge first
+func (g *CaptchaGate) Consume(actor string) bool {
+	if g.verifier == nil || g.policy.Placements == 0 {
+		return true
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	g.sweep(now)
+	c := g.credits[actor]
+	if c == nil {
+		if g.policy.OnConnect {
+			return false
+		}
+		c = &captchaCredit{remaining: g.policy.Placements}
+		g.credits[actor] = c
+	}
+	if c.remaining <= 0 {
+		return false
+	}
+	c.remaining--
+	c.last = now
+	return true
+}
+
+// Remaining returns the placements actor has left before its next challenge
+func (g *CaptchaGate) Remaining(actor string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if c := g.credits[actor]; c != nil {
+		return c.remaining
+	}
+	return 0
+}
+
+// sweep periodically drops the allowances of idle actors to bound memory,
+// g.mu must be held
+func (g *CaptchaGate) sweep(now time.Time) {
+	if now.Sub(g.lastSweep) <= time.Minute {
+		return
+	}
+	for actor, c := range g.credits {
+		if now.Sub(c.last) >= captchaIdle {
+			delete(g.credits, actor)
+		}
+	}
+	g.lastSweep = now
+}
+
+// Captcha returns the CAPTCHA challenge policy of the canvas, reporting false
+// if unauthenticated actors aren't challenged
+func (h *Hub) Captcha() (CaptchaPolicy, bool) {
+	return h.captcha.policy, h.captcha.verifier != nil
+}
+
+// SolveCaptcha verifies a CAPTCHA token solved by actor from the IP, renewing
+// its allowance of placements. Tokens are rejected with an error wrapping
+// ErrCaptchaInvalid.
+func (h *Hub) SolveCaptcha(ctx context.Context, actor, ip, token string) error {
+	if h.captcha.verifier == nil {
+		return nil
+	}
+	err := h.captcha.Solve(ctx, actor, ip, token)
+	if err != nil && !errors.Is(err, ErrCaptchaInvalid) {
+		slog.Error("Failed to verify CAPTCHA", "canvas", h.config.Canvas, "err", err)
+	}
+	return err
+}
+
+// captchaError returns the error rejecting a placement by an unauthenticated
+// actor that must solve a CAPTCHA challenge first, or nil if it may place.
+// Placements by authenticated actors are never challenged.
+func (h *Hub) captchaError(actor string, anonymous bool) *PlacementError {
+	if anonymous && !h.captcha.Consume(actor) {
+		return &PlacementError{Code: "captcha_required", Message: "solve a CAPTCHA challenge to keep painting"}
+	}
+	return nil
+}
+
+// handleCaptcha verifies a CAPTCHA token solved by the client, replying with
+// its new allowance of placements or the reason it was rejected
+func (c *Client) handleCaptcha(token string) {
+	if _, enabled := c.hub.Captcha(); !enabled {
+		c.sendError("captcha_disabled", "CAPTCHA challenges are disabled")
+		return
+	}
+	if err := c.hub.SolveCaptcha(context.Background(), c.actor(), c.ipAddress, token); err != nil {
+		if errors.Is(err, ErrCaptchaInvalid) {
+			c.sendError("captcha_invalid", "the CAPTCHA token is invalid or expired")
+		} else {
+			c.sendError("captcha_unavailable", "failed to verify the CAPTCHA, try again")
+		}
+		return
+	}
+	if err := c.sendMessage(CaptchaMessage{Type: "captcha", Remaining: c.hub.captcha.Remaining(c.actor())}); err != nil {
+		c.logger.Error("Failed to send captcha message", "err", err)
+	}
+}
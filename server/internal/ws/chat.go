@@ -0,0 +1,243 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Most chat channels a client may be in at once
+const maxChatChannels = 8
+
+// validChannel matches the names of chat channels
+var validChannel = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// ChatPostMessage is a chat message sent by a client
+type ChatPostMessage struct {
+	Type    string `json:"type"`
+	Text    string `json:"text"`
+	Channel string `json:"channel,omitempty"` // Empty for the canvas-wide chat
+}
+
+// ChannelMessage joins or leaves a chat channel
+type ChannelMessage struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+}
+
+// BroadcastChat is sent to all clients, or to the members of its channel,
+// when a client posts a chat message
+type BroadcastChat struct {
+	Type    string    `json:"t"`
+	ID      uint64    `json:"id"` // Connection ID of the author (0 for messages restored after a restart)
+	Name    string    `json:"name"`
+	Text    string    `json:"text"`
+	Channel string    `json:"channel,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// ChatHistoryMessage is sent to new clients with the latest chat messages, oldest first
+type ChatHistoryMessage struct {
+	Type     string          `json:"t"`
+	Messages []BroadcastChat `json:"messages"`
+}
+
+// ChatFilter screens chat messages before they are relayed, e.g. for profanity
+type ChatFilter interface {
+	// Filter returns the text to relay, possibly altered, or false to reject
+	// the message
+	Filter(text string) (string, bool)
+}
+
+// ChatStore persists the chat messages posted through the hub
+type ChatStore interface {
+	SaveChatMessage(canvas string, msg BroadcastChat) error
+}
+
+// chatWord matches the words WordFilter compares against its list
+var chatWord = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// WordFilter is a ChatFilter masking listed words with asterisks, matched
+// case-insensitively
+type WordFilter struct {
+	words map[string]bool
+}
+
+// NewWordFilter creates a filter masking the given words
+func NewWordFilter(words []string) *WordFilter {
+	f := &WordFilter{words: make(map[string]bool, len(words))}
+	for _, word := range words {
+		f.words[strings.ToLower(word)] = true
+	}
+	return f
+}
+
+// Filter masks the listed words, it never rejects a message
+func (f *WordFilter) Filter(text string) (string, bool) {
+	return chatWord.ReplaceAllStringFunc(text, func(word string) string {
+		if !f.words[strings.ToLower(word)] {
+			return word
+		}
+		return strings.Repeat("*", utf8.RuneCountInString(word))
+	}), true
+}
+
+// handleChat validates a chat message and relays it to the canvas or the
+// channel, replying with the reason if it is rejected
+func (c *Client) handleChat(text, channel string) {
+	if c.chatLimiter == nil {
+		c.sendError("chat_disabled", "chat is disabled on this canvas")
+		return
+	}
+	if channel != "" && !c.inChannel(channel) {
+		c.sendError("not_in_channel", fmt.Sprintf("join channel %q before posting to it", channel))
+		return
+	}
+	if !c.chatLimiter.Allow() {
+		c.sendError("rate_limited", "too many chat messages, slow down")
+		return
+	}
+
+	// Control characters (including newlines) would break up the message
+	text = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, text))
+	if text == "" {
+		c.sendError("empty_message", "chat message has no text")
+		return
+	}
+	if limit := c.hub.config.ChatMaxLength; limit > 0 && utf8.RuneCountInString(text) > limit {
+		c.sendError("message_too_long", fmt.Sprintf("chat messages are limited to %d characters", limit))
+		return
+	}
+	if filter := c.hub.config.ChatFilter; filter != nil {
+		var ok bool
+		if text, ok = filter.Filter(text); !ok {
+			c.sendError("message_rejected", "chat message was rejected")
+			return
+		}
+	}
+
+	c.hub.postChat(BroadcastChat{Type: "chat", ID: c.id, Name: c.name, Text: text, Channel: channel, At: time.Now().UTC()})
+}
+
+// handleChannel joins or leaves a chat channel. Channels are rooms of the hub
+// that exist while they have members.
+func (c *Client) handleChannel(op, channel string) {
+	if c.chatLimiter == nil {
+		c.sendError("chat_disabled", "chat is disabled on this canvas")
+		return
+	}
+	if !validChannel.MatchString(channel) {
+		c.sendError("invalid_channel", "channel names are 1 to 32 lowercase letters, digits, '-' or '_'")
+		return
+	}
+
+	if op == MsgLeaveChannel {
+		c.leaveChannel(channel)
+		return
+	}
+	if !c.joinChannel(channel) {
+		c.sendError("too_many_channels", fmt.Sprintf("clients may be in at most %d channels", maxChatChannels))
+	}
+}
+
+// joinChannel adds the client to a chat channel, returning false if it is
+// already in maxChatChannels other channels
+func (c *Client) joinChannel(channel string) bool {
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.canvasRoom.members[c] {
+		return true
+	}
+	if room := h.chatRoom(channel, false); room != nil && room.members[c] {
+		return true
+	}
+	joined := 0
+	for room := range c.rooms {
+		if room.tile == nil && room != h.canvasRoom && room != h.gridRoom {
+			joined++
+		}
+	}
+	if joined >= maxChatChannels {
+		return false
+	}
+	h.join(c, h.chatRoom(channel, true))
+	return true
+}
+
+// leaveChannel removes the client from a chat channel
+func (c *Client) leaveChannel(channel string) {
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if room := h.chatRoom(channel, false); room != nil && room.members[c] {
+		h.leave(c, room)
+	}
+}
+
+// inChannel reports whether the client is in a chat channel
+func (c *Client) inChannel(channel string) bool {
+	h := c.hub
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	room := h.chatRoom(channel, false)
+	return room != nil && room.members[c]
+}
+
+// postChat broadcasts a chat message, keeping and saving it unless it was
+// posted to a channel, and publishes it to the other instances through the broker
+func (h *Hub) postChat(msg BroadcastChat) {
+	message, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("Failed to marshal chat message", "err", err)
+		return
+	}
+	if msg.Channel != "" {
+		h.enqueue(&outbound{data: message, room: chatRoomPrefix + msg.Channel, priority: PriorityLow})
+	} else {
+		h.keepChat(msg)
+		h.broadcastLow(message)
+	}
+
+	if h.config.ChatStore != nil && msg.Channel == "" {
+		if err := h.config.ChatStore.SaveChatMessage(h.config.Canvas, msg); err != nil {
+			slog.Error("Failed to save chat message", "canvas", h.config.Canvas, "err", err)
+		}
+	}
+	if h.config.Broker != nil {
+		if err := h.config.Broker.Publish(message); err != nil {
+			slog.Error("Failed to publish chat message to broker", "err", err)
+		}
+	}
+}
+
+// keepChat adds a message to the history sent to new clients, dropping the
+// oldest beyond ChatHistory
+func (h *Hub) keepChat(msg BroadcastChat) {
+	if h.config.ChatHistory <= 0 {
+		return
+	}
+	h.chatMu.Lock()
+	defer h.chatMu.Unlock()
+	h.chat = append(h.chat, msg)
+	if extra := len(h.chat) - h.config.ChatHistory; extra > 0 {
+		h.chat = append(h.chat[:0:0], h.chat[extra:]...)
+	}
+}
+
+// ChatHistory returns the latest chat messages, oldest first
+func (h *Hub) ChatHistory() []BroadcastChat {
+	h.chatMu.Lock()
+	defer h.chatMu.Unlock()
+	return append([]BroadcastChat(nil), h.chat...)
+}
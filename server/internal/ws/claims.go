@@ -0,0 +1,197 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// Claim reserves a rectangle of the canvas for an account until it expires,
+// in economy mode. Only the owner and moderators may paint inside.
+type Claim struct {
+	ID uint `json:"id"`
+	Region
+	Owner     string    `json:"owner"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ClaimsMessage carries the claims of the canvas, sent to new clients and to
+// every client when they change
+type ClaimsMessage struct {
+	Type   string  `json:"t"`
+	Claims []Claim `json:"claims"`
+}
+
+// Expired reports whether the claim no longer reserves its region
+func (c Claim) Expired(now time.Time) bool {
+	return !now.Before(c.ExpiresAt)
+}
+
+// Economy reports whether accounts may claim regions of the canvas
+func (h *Hub) Economy() bool {
+	return h.config.Economy
+}
+
+// ClaimDuration returns the time claims made by accounts reserve their region for
+func (h *Hub) ClaimDuration() time.Duration {
+	return h.config.ClaimDuration
+}
+
+// Claims returns the claims of the canvas that haven't expired, oldest first
+func (h *Hub) Claims() []Claim {
+	now := time.Now()
+	return slices.DeleteFunc(slices.Clone(*h.claims.Load()), func(c Claim) bool { return c.Expired(now) })
+}
+
+// ClaimAt returns the claim reserving a cell, reporting false if none does
+func (h *Hub) ClaimAt(x, y int) (Claim, bool) {
+	now := time.Now()
+	for _, claim := range *h.claims.Load() {
+		if claim.Contains(x, y) && !claim.Expired(now) {
+			return claim, true
+		}
+	}
+	return Claim{}, false
+}
+
+// ClaimedCells returns the number of cells claimed by an owner
+func (h *Hub) ClaimedCells(owner string) int {
+	cells := 0
+	for _, claim := range h.Claims() {
+		if claim.Owner == owner {
+			cells += (claim.X2 - claim.X1) * (claim.Y2 - claim.Y1)
+		}
+	}
+	return cells
+}
+
+// CheckClaim returns the error rejecting a claim of a region by owner under
+// the canvas's own limits, or nil if owner may claim it: it must not overlap
+// another claim and owner must stay within MaxClaimCells.
+func (h *Hub) CheckClaim(region Region, owner string) *PlacementError {
+	if rejected := h.overlapError(region); rejected != nil {
+		return rejected
+	}
+	if h.config.MaxClaimCells == 0 {
+		return &PlacementError{Code: "claim_limit", Message: "claims are granted by admins"}
+	}
+	cells := (region.X2 - region.X1) * (region.Y2 - region.Y1)
+	if held := h.ClaimedCells(owner); held+cells > h.config.MaxClaimCells {
+		return &PlacementError{Code: "claim_limit", Message: fmt.Sprintf("accounts may claim at most %d cells, you hold %d", h.config.MaxClaimCells, held)}
+	}
+	return nil
+}
+
+// AddClaim reserves a region, numbering the claim after the others if it has
+// no ID yet (when it wasn't persisted), and returns it. Claims overlapping
+// another are rejected with a *PlacementError.
+func (h *Hub) AddClaim(claim Claim) (Claim, error) {
+	h.claimsMu.Lock()
+	defer h.claimsMu.Unlock()
+
+	if rejected := h.overlapError(claim.Region); rejected != nil {
+		return Claim{}, rejected
+	}
+	claims := h.Claims()
+	if claim.ID == 0 {
+		for _, other := range *h.claims.Load() {
+			claim.ID = max(claim.ID, other.ID)
+		}
+		claim.ID++
+	}
+	h.setClaims(append(claims, claim))
+	return claim, nil
+}
+
+// RemoveClaim releases a claim by ID, reporting false if there is no such claim
+func (h *Hub) RemoveClaim(id uint) bool {
+	h.claimsMu.Lock()
+	defer h.claimsMu.Unlock()
+
+	claims := h.Claims()
+	i := slices.IndexFunc(claims, func(claim Claim) bool { return claim.ID == id })
+	if i < 0 {
+		return false
+	}
+	h.setClaims(slices.Delete(claims, i, i+1))
+	return true
+}
+
+// Claim returns a claim by ID, reporting false if there is no such claim
+func (h *Hub) Claim(id uint) (Claim, bool) {
+	claims := h.Claims()
+	i := slices.IndexFunc(claims, func(claim Claim) bool { return claim.ID == id })
+	if i < 0 {
+		return Claim{}, false
+	}
+	return claims[i], true
+}
+
+// overlapError returns the error rejecting a claim of a region overlapping
+// another claim, or nil if it overlaps none
+func (h *Hub) overlapError(region Region) *PlacementError {
+	for _, other := range h.Claims() {
+		if other.Intersects(region) {
+			return &PlacementError{Code: "region_claimed", Message: fmt.Sprintf("the region overlaps claim %d", other.ID)}
+		}
+	}
+	return nil
+}
+
+// setClaims replaces the claims, tells the clients and publishes them to the
+// other instances, h.claimsMu must be held
+func (h *Hub) setClaims(claims []Claim) {
+	message, err := h.applyClaims(claims)
+	if err != nil {
+		return
+	}
+	if h.config.Broker != nil {
+		if err := h.config.Broker.Publish(message); err != nil {
+			slog.Error("Failed to publish claims to broker", "err", err)
+		}
+	}
+}
+
+// applyClaims replaces the claims and tells the local clients, returning the
+// encoded ClaimsMessage
+func (h *Hub) applyClaims(claims []Claim) ([]byte, error) {
+	if claims == nil {
+		claims = []Claim{}
+	}
+	message, err := json.Marshal(ClaimsMessage{Type: "claims", Claims: claims})
+	if err != nil {
+		slog.Error("Failed to marshal claims", "err", err)
+		return nil, err
+	}
+	h.claims.Store(&claims)
+	slog.Info("Claims changed", "canvas", h.config.Canvas, "claims", len(claims))
+	h.enqueue(&outbound{data: message})
+	return message, nil
+}
+
+// claimedError returns the error rejecting paint operations by actor on the
+// first of the pixels claimed by another account, or nil if none is
+func (h *Hub) claimedError(pixels []model.Pixel, actor string) *PlacementError {
+	claims := *h.claims.Load()
+	if len(claims) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for _, p := range pixels {
+		for _, claim := range claims {
+			if claim.Owner != actor && claim.Contains(p.X, p.Y) && !claim.Expired(now) {
+				return &PlacementError{
+					Code:    "cell_claimed",
+					Message: fmt.Sprintf("cell (%d, %d) is claimed until %s", p.X, p.Y, claim.ExpiresAt.UTC().Format(time.RFC3339)),
+					X:       p.X,
+					Y:       p.Y,
+				}
+			}
+		}
+	}
+	return nil
+}
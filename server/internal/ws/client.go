@@ -1,12 +1,24 @@
 package ws
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
-	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/auth"
+	"github.com/million_grids/server/internal/crash"
+	"github.com/million_grids/server/internal/model"
+	"github.com/million_grids/server/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -19,71 +31,357 @@ const (
 	// Send pings to peer with this period (must be less than pongWait)
 	pingPeriod = (pongWait * 9) / 10
 
-	// Maximum message size allowed from peer
-	maxMessageSize = 512
+	// Maximum message size allowed from peer (large enough for a full paint batch)
+	maxMessageSize = 16 * 1024
+
+	// Maximum number of cells accepted in a single paint message
+	maxPaintBatch = 256
+
+	// Side length of the square regions the initial state is streamed in
+	initChunkSize = 100
+)
+
+// CloseIdleTimeout is the close code of the connections closed for sending
+// nothing for HubConfig.IdleTimeout, in the range reserved for applications.
+// The close reason is "idle_timeout"; clients should only reconnect once
+// their user is back.
+const CloseIdleTimeout = 4000
+
+// Cell operation types a client can request
+const (
+	OpToggle = "toggle" // Flip the cell
+	OpSet    = "set"    // Activate the cell with the given color
+	OpClear  = "clear"  // Deactivate the cell
+	OpPaint  = "paint"  // Activate a batch of cells atomically
 )
 
-// CellToggle represents a cell toggle message from client (now with color)
-type CellToggle struct {
+// Viewport, cursor, chat, team, undo, drawing and CAPTCHA message types a client can send
+const (
+	MsgSubscribe    = "subscribe"     // Only receive updates inside a bounding box
+	MsgUnsubscribe  = "unsubscribe"   // Receive updates for the whole grid again
+	MsgCursor       = "cursor"        // Share the pointer position with other clients
+	MsgChat         = "chat"          // Post a chat message to the canvas or a channel
+	MsgJoinChannel  = "join_channel"  // Receive the chat messages of a channel
+	MsgLeaveChannel = "leave_channel" // Stop receiving a channel's chat messages
+	MsgTeam         = "team"          // Paint for a team, or for none
+	MsgDraw         = "draw"          // Paint a drawing primitive server-side (bots only)
+	MsgUndo         = "undo"          // Revert the client's most recent placement
+	MsgCaptcha      = "captcha"       // Present a solved CAPTCHA token
+)
+
+// CellMessage represents a cell operation message from client
+type CellMessage struct {
+	Type  string `json:"type"` // One of OpToggle, OpSet, OpClear, OpPaint
 	X     int    `json:"x"`
 	Y     int    `json:"y"`
 	Color string `json:"color,omitempty"` // Hex color like "#FF0000"
+
+	// Cells to activate for OpPaint
+	Cells []PaintCell `json:"cells,omitempty"`
+}
+
+// SubscribeMessage declares the bounding box of cells visible to the client
+type SubscribeMessage struct {
+	Type string `json:"type"`
+	Region
+}
+
+// RegionMessage carries the current active cells of a newly subscribed region
+type RegionMessage struct {
+	Type string `json:"type"`
+	Region
+	Active []ActiveCell `json:"active"`
+}
+
+// PaintCell is a single cell within a paint batch
+type PaintCell struct {
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Color string `json:"color"`
 }
 
 // BroadcastCellUpdate is sent to all clients when a cell changes
 type BroadcastCellUpdate struct {
-	Type   string `json:"t"`
-	X      int    `json:"x"`
-	Y      int    `json:"y"`
-	Active int    `json:"a"`     // 0 or 1 for JSON
-	Color  string `json:"color"` // Hex color
+	Type   string      `json:"t"`
+	X      int         `json:"x"`
+	Y      int         `json:"y"`
+	Active int         `json:"a"`              // 0 or 1 for JSON
+	Color  model.Color `json:"color"`          // Hex color
+	Team   string      `json:"team,omitempty"` // Team of the actor
+
+	// Version of the change with multi-region replication
+	Clock  uint64 `json:"clock,omitempty"`
+	Region string `json:"region,omitempty"`
+}
+
+// BatchCell is a single changed cell within a batched update
+type BatchCell struct {
+	X      int         `json:"x"`
+	Y      int         `json:"y"`
+	Active int         `json:"a"`
+	Color  model.Color `json:"color"`
+}
+
+// NewBatchCell returns the batched update of a changed pixel
+func NewBatchCell(p model.Pixel) BatchCell {
+	return BatchCell{X: p.X, Y: p.Y, Active: activeInt(p.Active), Color: p.Color}
+}
+
+// BroadcastBatchUpdate is sent to all clients when several cells change at once
+type BroadcastBatchUpdate struct {
+	Type  string      `json:"t"`
+	Cells []BatchCell `json:"cells"`
+	Team  string      `json:"team,omitempty"` // Team of the actor, for every cell
+
+	// Version of the changes with multi-region replication
+	Clock  uint64 `json:"clock,omitempty"`
+	Region string `json:"region,omitempty"`
+}
+
+// PaletteMessage is sent to all clients when the palette changes
+type PaletteMessage struct {
+	Type     string        `json:"t"`
+	Version  int           `json:"version"`
+	Colors   []model.Color `json:"colors"`
+	AnyColor bool          `json:"any_color"`
+}
+
+// ErrorMessage is sent to a client whose message was rejected
+type ErrorMessage struct {
+	Type    string `json:"t"`
+	Code    string `json:"code"`
+	Message string `json:"msg"`
+}
+
+// ClientCountMessage is sent to all clients when a client connects or disconnects
+type ClientCountMessage struct {
+	Type  string `json:"t"`
+	Count int    `json:"count"`
+}
+
+// CooldownMessage is sent to a client whose placement was rejected by the cooldown
+type CooldownMessage struct {
+	Type        string `json:"t"`
+	RemainingMs int64  `json:"remaining_ms"` // Time left before the next placement is allowed
 }
 
 // ActiveCell represents an active cell in sparse format (with color)
 type ActiveCell struct {
-	X     int    `json:"x"`
-	Y     int    `json:"y"`
-	Color string `json:"color"`
+	X     int         `json:"x"`
+	Y     int         `json:"y"`
+	Color model.Color `json:"color"`
+
+	// Metadata of the cell, only sent to clients that asked for it
+	Text string `json:"text,omitempty"`
+	URL  string `json:"url,omitempty"`
 }
 
-// InitMessage represents the initial state sent to new clients (sparse format)
+// InitMessage announces the start of the initial state stream to new clients
 type InitMessage struct {
+	Type           string        `json:"type"`
+	Canvas         string        `json:"canvas"`
+	Width          int           `json:"width"`
+	Height         int           `json:"height"`
+	ChunkSize      int           `json:"chunk"`
+	Palette        []model.Color `json:"palette"`         // Palette colors
+	PaletteVersion int           `json:"palette_version"` // See PaletteMessage
+	AnyColor       bool          `json:"any_color"`       // Colors outside the palette are allowed
+
+	// Paint operations are rejected, see FrozenMessage
+	Frozen       bool   `json:"frozen"`
+	FrozenReason string `json:"frozen_reason,omitempty"`
+
+	// The server is a read-only replica: paint operations, chat and cursors
+	// are rejected whether or not the canvas is frozen
+	Follower bool `json:"follower,omitempty"`
+}
+
+// InitChunkMessage carries the active cells of one region of the initial state (sparse format)
+type InitChunkMessage struct {
 	Type   string       `json:"type"`
-	Size   int          `json:"size"`
+	X      int          `json:"x"` // Region origin
+	Y      int          `json:"y"`
 	Active []ActiveCell `json:"active"`
 }
 
+// InitDoneMessage marks the end of the initial state stream
+type InitDoneMessage struct {
+	Type  string `json:"type"`
+	Total int    `json:"total"` // Total number of active cells sent
+}
+
+// nextConnID is the last connection number assigned to a client
+var nextConnID atomic.Uint64
+
 // Client represents a WebSocket client connection
 type Client struct {
 	hub *Hub
 
+	// Number of the connection on this instance, the client's ID in the
+	// roster and chat
+	id uint64
+
+	// UUID of the connection, unique across instances and restarts, for
+	// correlating the client's log lines
+	connID string
+
+	// Logger tagged with the connection ID
+	logger *slog.Logger
+
 	// The websocket connection
 	conn *websocket.Conn
 
-	// Buffered channel of outbound messages
-	send chan []byte
+	// Outbound messages, one buffered lane per Priority, and whether the hub
+	// closed them, guarded by its mutex
+	lanes       [numPriorities]chan []byte
+	lanesClosed bool
+
+	// Encoded initial state, written before the lanes (see SendInitialState)
+	initial [][]byte
+
+	// Set once the client is disconnected for falling behind
+	evicted atomic.Bool
+
+	// Payload of the close frame sent once the hub closes the lanes (empty
+	// for none), set before closing them
+	closeMsg []byte
 
 	// Client IP address for tracking
 	ipAddress string
+
+	// When the connection was accepted
+	connectedAt time.Time
+
+	// Outbound traffic, capped to BandwidthLimit
+	bandwidth *bandwidth
+
+	// Messages read from the client, rate limited or not, and when it sent
+	// the last one (UnixNano, when it connected before)
+	received   atomic.Int64
+	lastActive atomic.Int64
+
+	// Closes the client once it was idle for IdleTimeout (nil without one),
+	// set by the read pump
+	idle *time.Timer
+
+	// Encoding of the messages to and from the client (protobuf when it
+	// negotiated SubprotocolProtobuf)
+	encoding Encoding
+
+	// Authenticated user (nil for anonymous clients)
+	identity *auth.Identity
+
+	// Name shown to the other clients in the roster
+	name string
+
+	// Team the client's placements count for (empty for none), only used by
+	// the read pump
+	team string
+
+	// Cell metadata is included in the initial state
+	withMeta bool
+
+	// Throttles inbound messages
+	limiter *RateLimiter
+
+	// Throttles relayed cursor positions (nil when cursor sharing is disabled)
+	cursorLimiter *RateLimiter
+
+	// Throttles chat messages (nil when chat is disabled)
+	chatLimiter *RateLimiter
+
+	// Viewport the client subscribed to (nil receives the whole grid)
+	viewport   *Region
+	viewportMu sync.RWMutex
+
+	// Rooms the client is in, and the region rooms its viewport spans in
+	// units of regionRoomSize (empty in the grid room), guarded by the hub's mutex
+	rooms map[*Room]bool
+	tiles Region
 }
 
-// NewClient creates a new Client instance
-func NewClient(hub *Hub, conn *websocket.Conn, ipAddress string) *Client {
-	return &Client{
-		hub:       hub,
-		conn:      conn,
-		send:      make(chan []byte, 256),
-		ipAddress: ipAddress,
+// NewClient creates a new Client instance. identity is nil for anonymous clients.
+func NewClient(hub *Hub, conn *websocket.Conn, ipAddress string, identity *auth.Identity) *Client {
+	id := nextConnID.Add(1)
+	connID := uuid.NewString()
+	encoding := EncodingJSON
+	if conn.Subprotocol() == SubprotocolProtobuf {
+		encoding = EncodingProtobuf
+	}
+	name := generateName()
+	if identity != nil && identity.Name != "" {
+		name = identity.Name
+	}
+	var team string
+	if identity != nil && hub.HasTeam(identity.Team) {
+		team = identity.Team
+	}
+	var cursorLimiter *RateLimiter
+	if hub.config.CursorRate > 0 {
+		cursorLimiter = NewRateLimiter(hub.config.CursorRate, max(1, int(hub.config.CursorRate)))
+	}
+	var chatLimiter *RateLimiter
+	if hub.config.ChatRate > 0 {
+		chatLimiter = NewRateLimiter(hub.config.ChatRate, hub.config.ChatBurst)
 	}
+	// Bots with an API key of their own rate get it instead of the default
+	limiter := NewRateLimiter(hub.config.MessageRate, hub.config.MessageBurst)
+	if identity != nil && identity.APIKey != nil && identity.APIKey.Rate > 0 {
+		limiter = NewRateLimiter(identity.APIKey.Rate, identity.APIKey.Burst)
+	}
+	c := &Client{
+		hub:           hub,
+		id:            id,
+		connID:        connID,
+		logger:        slog.With("conn", connID, "canvas", hub.Canvas()),
+		conn:          conn,
+		lanes:         newLanes(hub.config.SendBuffer),
+		ipAddress:     ipAddress,
+		connectedAt:   time.Now(),
+		bandwidth:     newBandwidth(hub.config.BandwidthLimit),
+		encoding:      encoding,
+		identity:      identity,
+		name:          name,
+		team:          team,
+		limiter:       limiter,
+		cursorLimiter: cursorLimiter,
+		chatLimiter:   chatLimiter,
+		rooms:         make(map[*Room]bool),
+	}
+	c.lastActive.Store(c.connectedAt.UnixNano())
+	return c
+}
+
+// ID returns the client's connection number
+func (c *Client) ID() uint64 {
+	return c.id
+}
+
+// ConnID returns the UUID of the client's connection its log lines are tagged with
+func (c *Client) ConnID() string {
+	return c.connID
+}
+
+// Presence returns the client's roster entry
+func (c *Client) Presence() Presence {
+	return Presence{ID: c.id, Name: c.name}
 }
 
 // readPump pumps messages from the websocket connection to the hub
 func (c *Client) readPump() {
+	defer crash.Recover("read pump", "canvas", c.hub.config.Canvas, "conn", c.connID)
 	defer func() {
 		c.hub.Unregister(c)
 		c.conn.Close()
 	}()
 
+	if timeout := c.hub.config.IdleTimeout; timeout > 0 {
+		// Armed once assigned, as checkIdle resets it
+		c.idle = time.AfterFunc(math.MaxInt64, c.checkIdle)
+		c.idle.Reset(timeout)
+		defer c.idle.Stop()
+	}
+
 	c.conn.SetReadLimit(maxMessageSize)
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.conn.SetPongHandler(func(string) error {
@@ -92,155 +390,595 @@ func (c *Client) readPump() {
 	})
 
 	for {
-		_, message, err := c.conn.ReadMessage()
+		messageType, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket error: %v", err)
+				c.logger.Warn("WebSocket error", "err", err)
 			}
 			break
 		}
 
-		log.Printf("Received message: %s", string(message))
-
-		// Parse the cell toggle (frontend sends {x, y})
-		var toggle CellToggle
-		if err := json.Unmarshal(message, &toggle); err != nil {
-			log.Printf("Error parsing message: %v", err)
+		c.logger.Debug("Message received", "bytes", len(message))
+		c.received.Add(1)
+		c.lastActive.Store(time.Now().UnixNano())
+
+		if !c.limiter.Allow() {
+			if c.limiter.Exceeded() {
+				c.logger.Warn("Disconnecting client: rate limit repeatedly exceeded")
+				c.hub.countError("rate_limit_exceeded")
+				c.conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "rate limit exceeded"),
+					time.Now().Add(writeWait))
+				break
+			}
+			c.sendError("rate_limited", "too many messages, slow down")
 			continue
 		}
 
-		log.Printf("Parsed toggle: x=%d, y=%d", toggle.X, toggle.Y)
+		// Each message starts a trace its placement's spans are part of
+		ctx := withReceipt(context.Background(), time.Now())
+		ctx, span := tracing.Tracer().Start(ctx, "ws.receive",
+			trace.WithSpanKind(trace.SpanKindServer), canvasAttr(c.hub),
+			trace.WithAttributes(attribute.String("conn", c.connID), attribute.Int("bytes", len(message))))
+		switch {
+		case c.encoding == EncodingJSON:
+			c.handleMessage(ctx, message)
+		case messageType == websocket.BinaryMessage:
+			c.handleProtobuf(ctx, message)
+		default:
+			c.sendError("invalid_message", "expected a binary ClientMessage frame")
+		}
+		span.End()
+	}
+}
+
+// handleMessage strictly parses an inbound message and routes it by type.
+// Messages without a known type, with unknown fields or with values of the
+// wrong type are rejected with an error reply.
+func (c *Client) handleMessage(ctx context.Context, message []byte) {
+	var envelope struct {
+		Type *string `json:"type"`
+	}
+	if err := json.Unmarshal(message, &envelope); err != nil {
+		e := jsonError(err)
+		c.sendError(e.code, e.message)
+		return
+	}
+	if envelope.Type == nil || *envelope.Type == "" {
+		c.sendError("missing_type", `message has no "type"`)
+		return
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("message.type", *envelope.Type))
+	c.hub.countMessage(*envelope.Type)
+
+	if c.hub.config.Follower && !followerMessages[*envelope.Type] {
+		c.sendFollowerError()
+		return
+	}
+
+	switch *envelope.Type {
+	case MsgSubscribe:
+		var msg SubscribeMessage
+		if c.decodeMessage(message, &msg) {
+			c.handleSubscribe(msg.Region)
+		}
+
+	case MsgUnsubscribe:
+		var msg struct {
+			Type string `json:"type"`
+		}
+		if c.decodeMessage(message, &msg) {
+			c.setViewport(nil)
+		}
+
+	case MsgCursor:
+		var msg CursorMessage
+		if c.decodeMessage(message, &msg) {
+			c.handleCursor(msg.X, msg.Y)
+		}
+
+	case MsgChat:
+		var msg ChatPostMessage
+		if c.decodeMessage(message, &msg) {
+			c.handleChat(msg.Text, msg.Channel)
+		}
+
+	case MsgJoinChannel, MsgLeaveChannel:
+		var msg ChannelMessage
+		if c.decodeMessage(message, &msg) {
+			c.handleChannel(msg.Type, msg.Channel)
+		}
+
+	case MsgTeam:
+		var msg TeamJoinMessage
+		if c.decodeMessage(message, &msg) {
+			c.handleTeam(msg.Team)
+		}
+
+	case MsgUndo:
+		var msg UndoMessage
+		if c.decodeMessage(message, &msg) {
+			c.handleUndo(ctx)
+		}
+
+	case MsgCaptcha:
+		var msg CaptchaSolution
+		if c.decodeMessage(message, &msg) {
+			c.handleCaptcha(msg.Token)
+		}
+
+	case MsgDraw:
+		var msg DrawMessage
+		if c.decodeMessage(message, &msg) {
+			c.handleDraw(msg.DrawRequest)
+		}
+
+	case OpToggle, OpSet, OpClear, OpPaint:
+		// Cell operation (frontend sends {type, x, y, color})
+		var msg CellMessage
+		if !c.decodeMessage(message, &msg) {
+			return
+		}
+		if msg.Cells != nil && msg.Type != OpPaint {
+			c.sendError("invalid_field", `field "cells" is only allowed with type "paint"`)
+			return
+		}
+		c.handleCellMessage(ctx, msg)
+
+	default:
+		c.sendError("unknown_type", fmt.Sprintf("unknown message type %q", *envelope.Type))
+	}
+}
+
+// decodeMessage strictly decodes a message into v, replying with an error and
+// returning false if it doesn't match the message's schema
+func (c *Client) decodeMessage(message []byte, v any) bool {
+	if e := decodeStrict(message, v); e != nil {
+		c.sendError(e.code, e.message)
+		return false
+	}
+	return true
+}
+
+// handleSubscribe restricts the client's updates to a region and sends its current state
+func (c *Client) handleSubscribe(region Region) {
+	region = region.Clamp(c.hub.grid.Width(), c.hub.grid.Height())
+	if region.Empty() {
+		c.sendError("invalid_region", "the subscribed region contains no cells")
+		return
+	}
+
+	c.setViewport(&region)
+
+	// Send the region's current state so updates missed while outside the viewport are not lost
+	cells := c.hub.grid.GetActiveCellsInRegion(region.X1, region.Y1, region.X2, region.Y2)
+	activeList := make([]ActiveCell, len(cells))
+	for i, cell := range cells {
+		activeList[i] = ActiveCell{X: cell.X, Y: cell.Y, Color: cell.Color}
+	}
+
+	if err := c.sendMessage(RegionMessage{
+		Type:   "region",
+		Region: region,
+		Active: activeList,
+	}); err != nil {
+		c.logger.Error("Failed to send region state", "err", err)
+	}
+}
+
+// setViewport replaces the client's subscribed region (nil for the whole grid)
+// and moves it to the rooms receiving the region's updates
+func (c *Client) setViewport(region *Region) {
+	c.viewportMu.Lock()
+	c.viewport = region
+	c.viewportMu.Unlock()
+
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.canvasRoom.members[c] {
+		h.placeViewport(c)
+	}
+}
+
+// Viewport returns the client's subscribed region (nil for the whole grid)
+func (c *Client) Viewport() *Region {
+	c.viewportMu.RLock()
+	defer c.viewportMu.RUnlock()
+	return c.viewport
+}
+
+// watches reports whether updates in the region should be forwarded to the client
+func (c *Client) watches(region Region) bool {
+	c.viewportMu.RLock()
+	defer c.viewportMu.RUnlock()
+	return c.viewport == nil || c.viewport.Intersects(region)
+}
+
+// handleCellMessage applies a cell operation, replying with the reason if it is rejected
+func (c *Client) handleCellMessage(ctx context.Context, msg CellMessage) {
+	if msg.Type == OpPaint {
+		c.handlePaint(ctx, msg.Cells)
+		return
+	}
 
-		// All incoming messages are cell toggles
-		c.handleCellToggle(toggle)
+	_, err := c.hub.Place(ctx, Placement{Op: msg.Type, X: msg.X, Y: msg.Y, Color: msg.Color, IP: c.ipAddress, Actor: c.actor(), Team: c.team, Moderator: c.identity.Moderator(), Anonymous: c.identity == nil, Role: c.role()})
+	var rejected *PlacementError
+	if errors.As(err, &rejected) {
+		switch rejected.Code {
+		case "cooldown":
+			c.sendCooldown(rejected.RetryAfter)
+		case "cell_protected":
+			c.sendProtected(rejected)
+		default:
+			c.sendError(rejected.Code, rejected.Message)
+		}
+		return
+	}
+	if err == nil {
+		c.sendQuota()
 	}
 }
 
-// handleCellToggle processes a cell toggle from the client
-func (c *Client) handleCellToggle(toggle CellToggle) {
-	// Validate coordinates
-	if toggle.X < 0 || toggle.X >= GridSize || toggle.Y < 0 || toggle.Y >= GridSize {
-		log.Printf("Invalid coordinates: (%d, %d)", toggle.X, toggle.Y)
+// handlePaint validates a batch of cells and applies it atomically. The whole
+// batch is rejected if any cell is out of bounds or uses a disallowed color.
+func (c *Client) handlePaint(ctx context.Context, cells []PaintCell) {
+	if rejected := c.hub.frozenError(); rejected != nil {
+		c.sendError(rejected.Code, rejected.Message)
+		return
+	}
+	if c.hub.IsBanned(c.ipAddress) {
+		c.sendError("banned", "you are banned from painting")
+		return
+	}
+	if len(cells) == 0 {
+		c.sendError("empty_batch", "paint batch has no cells")
+		return
+	}
+	if len(cells) > maxPaintBatch {
+		c.sendError("batch_too_large", fmt.Sprintf("paint batch has %d cells, the maximum is %d", len(cells), maxPaintBatch))
+		return
+	}
+
+	loc := c.hub.config.GeoIP.Lookup(c.ipAddress)
+	pixels := make([]model.Pixel, len(cells))
+	for i, cell := range cells {
+		if !c.hub.grid.InBounds(cell.X, cell.Y) {
+			c.sendError("out_of_bounds", fmt.Sprintf("cell (%d, %d) is outside the grid", cell.X, cell.Y))
+			return
+		}
+		color, err := model.ParseColor(cell.Color)
+		if err != nil || !model.IsValidColor(color) {
+			c.sendError("invalid_color", fmt.Sprintf("color %q is not allowed", cell.Color))
+			return
+		}
+		pixels[i] = model.Pixel{X: cell.X, Y: cell.Y, Active: true, Color: color, Country: loc.Country, Region: loc.Region, Team: c.team}
+	}
+	if rejected := c.hub.teamError(c.team); rejected != nil {
+		c.sendError(rejected.Code, rejected.Message)
+		return
+	}
+	if !c.identity.Moderator() {
+		if rejected := c.hub.lockedError(pixels); rejected != nil {
+			c.sendError(rejected.Code, rejected.Message)
+			return
+		}
+		if rejected := c.hub.claimedError(pixels, c.actor()); rejected != nil {
+			c.sendError(rejected.Code, rejected.Message)
+			return
+		}
+	}
+
+	if cell, remaining, protected := c.hub.protection.Check(c.actor(), pixels); protected {
+		c.sendProtected(protectedError(cell, remaining))
 		return
 	}
 
-	// Validate color - must be one of the 7 allowed colors
-	color := toggle.Color
-	if color == "" {
-		color = "#FF0000" // Default to red if no color provided
+	// A paint batch counts as a single placement
+	if rejected := c.hub.captchaError(c.actor(), c.identity == nil); rejected != nil {
+		c.sendError(rejected.Code, rejected.Message)
+		return
+	}
+	if !c.checkCooldown() {
+		return
 	}
-	if !db.IsValidColor(color) {
-		log.Printf("Invalid color: %s, defaulting to red", color)
-		color = "#FF0000"
+	if c.hub.IsShadowBanned(c.ipAddress, c.actor()) {
+		c.hub.shadowCells(pixels, c.actor())
+		return
+	}
+	if cell, remaining, protected := c.hub.protection.Claim(c.actor(), pixels); protected {
+		c.sendProtected(protectedError(cell, remaining))
+		return
 	}
 
-	// Toggle the cell with color and get new state (thread-safe)
-	newState, newColor := Grid.ToggleCell(toggle.X, toggle.Y, color)
+	// Apply, persist and broadcast all changes as batched updates
+	if _, err := c.hub.placeCells(ctx, pixels, c.actor(), c.identity.Moderator()); err != nil {
+		c.sendError(errWriteFailed.Code, errWriteFailed.Message)
+		return
+	}
+	c.sendQuota()
+}
 
-	// Get current timestamp
-	now := time.Now()
+// checkCooldown consumes a placement under the cooldown and quota of the
+// client's tier (and of its actor while quarantined), sending the remaining
+// wait time back to the client if it is still cooling down
+func (c *Client) checkCooldown() bool {
+	rejected := c.hub.paceError(Placement{IP: c.ipAddress, Actor: c.actor(), Anonymous: c.identity == nil, Role: c.role()})
+	if rejected == nil {
+		rejected = c.hub.throttleError(c.actor())
+	}
+	switch {
+	case rejected == nil:
+		return true
+	case rejected.Code == "cooldown":
+		c.sendCooldown(rejected.RetryAfter)
+	default:
+		c.sendError(rejected.Code, rejected.Message)
+	}
+	return false
+}
 
-	// Asynchronously save to database (fire-and-forget)
-	db.SavePixelAsync(db.Pixel{
-		X:         toggle.X,
-		Y:         toggle.Y,
-		Active:    newState,
-		Color:     newColor,
-		CreatedBy: c.ipAddress,
-		ModifyAt:  &now,
-		ModifyBy:  c.ipAddress,
-	})
+// sendCooldown tells the client how long it must wait before placing again
+func (c *Client) sendCooldown(remaining time.Duration) {
+	c.logger.Debug("Placement rejected by cooldown", "remaining", remaining)
+	c.hub.countError("cooldown")
+	if err := c.sendMessage(CooldownMessage{
+		Type:        "cooldown",
+		RemainingMs: remaining.Milliseconds(),
+	}); err != nil {
+		c.logger.Error("Failed to send cooldown message", "err", err)
+	}
+}
 
-	// Convert bool to int for JSON
-	activeInt := 0
-	if newState {
-		activeInt = 1
+// sendProtected tells the client the cell it tried to change is protected,
+// and for how long
+func (c *Client) sendProtected(rejected *PlacementError) {
+	c.logger.Debug("Placement rejected by overwrite protection", "x", rejected.X, "y", rejected.Y, "remaining", rejected.RetryAfter)
+	c.hub.countError(rejected.Code)
+	if err := c.sendMessage(ProtectedMessage{
+		Type:        "protected",
+		X:           rejected.X,
+		Y:           rejected.Y,
+		RemainingMs: rejected.RetryAfter.Milliseconds(),
+	}); err != nil {
+		c.logger.Error("Failed to send protection message", "err", err)
 	}
+}
 
-	// Broadcast the update to all clients (with color)
-	broadcastMsg, _ := json.Marshal(BroadcastCellUpdate{
-		Type:   "u",
-		X:      toggle.X,
-		Y:      toggle.Y,
-		Active: activeInt,
-		Color:  newColor,
-	})
-	c.hub.Broadcast(broadcastMsg)
+// actor returns the ID pixel changes are attributed to: the user ID for
+// authenticated clients, the (possibly hashed) IP address otherwise
+func (c *Client) actor() string {
+	if c.identity != nil {
+		return c.identity.UserID
+	}
+	return c.hub.AnonymousActor(c.ipAddress)
+}
+
+// role returns the role of the client's identity (empty for anonymous clients)
+func (c *Client) role() string {
+	if c.identity != nil {
+		return c.identity.Role
+	}
+	return ""
+}
 
-	log.Printf("Cell toggled: (%d, %d) -> %v, color: %s, by: %s", toggle.X, toggle.Y, newState, newColor, c.ipAddress)
+// sendError sends a structured error reply to the client
+func (c *Client) sendError(code, message string) {
+	c.logger.Debug("Message rejected", "code", code, "msg", message)
+	c.hub.countError(code)
+	if err := c.sendMessage(ErrorMessage{Type: "err", Code: code, Message: message}); err != nil {
+		c.logger.Error("Failed to send error message", "err", err)
+	}
 }
 
 // writePump pumps messages from the hub to the websocket connection
 func (c *Client) writePump() {
+	defer crash.Recover("write pump", "canvas", c.hub.config.Canvas, "conn", c.connID)
 	ticker := time.NewTicker(pingPeriod)
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
 	}()
 
+	frameType := websocket.TextMessage
+	if c.encoding == EncodingProtobuf {
+		frameType = websocket.BinaryMessage
+	}
+	for _, data := range c.initial {
+		c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := c.conn.WriteMessage(frameType, data); err != nil {
+			return
+		}
+		c.pace(len(data), 1)
+	}
+	c.initial = nil
+
 	for {
+		// Keep pinging while the lanes are busy
 		select {
-		case message, ok := <-c.send:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				// The hub closed the channel
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+		case <-ticker.C:
+			if !c.ping() {
 				return
 			}
+		default:
+		}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
+		message, ok, closed := c.dequeue()
+		if !ok && !closed {
+			// Wait for a message or the next ping
+			select {
+			case message, ok = <-c.lanes[PriorityHigh]:
+			case message, ok = <-c.lanes[PriorityNormal]:
+			case message, ok = <-c.lanes[PriorityLow]:
+			case <-ticker.C:
+				if !c.ping() {
+					return
+				}
+				continue
 			}
-			w.Write(message)
+			closed = !ok
+		}
 
-			// Add queued messages to the current websocket message
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
-			}
+		c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if closed {
+			// The hub closed the lanes
+			c.conn.WriteMessage(websocket.CloseMessage, c.closeMsg)
+			return
+		}
 
-			if err := w.Close(); err != nil {
+		// Protobuf frames hold exactly one message each
+		if c.encoding == EncodingProtobuf {
+			if err := c.conn.WriteMessage(websocket.BinaryMessage, message); err != nil {
 				return
 			}
+			c.pace(len(message), 1)
+			continue
+		}
 
-		case <-ticker.C:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-				return
+		w, err := c.conn.NextWriter(websocket.TextMessage)
+		if err != nil {
+			return
+		}
+		w.Write(message)
+		bytes, messages := len(message), 1
+
+		// Add queued messages to the current websocket message, highest
+		// priority first. Don't wait for them, the hub may take some back to
+		// make room.
+		for n := c.queued(); n > 0; n-- {
+			queued, ok, _ := c.dequeue()
+			if !ok {
+				break
 			}
+			w.Write([]byte{'\n'})
+			w.Write(queued)
+			bytes, messages = bytes+1+len(queued), messages+1
 		}
+
+		if err := w.Close(); err != nil {
+			return
+		}
+		c.pace(bytes, messages)
+	}
+}
+
+// ping sends a ping frame, reporting whether it was written
+func (c *Client) ping() bool {
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.conn.WriteMessage(websocket.PingMessage, nil) == nil
+}
+
+// pace records a frame written to the client, then waits for as long as it
+// takes to stay under the client's bandwidth cap. Meanwhile broadcasts pile
+// up in the send buffer and low-priority ones are dropped.
+func (c *Client) pace(bytes, messages int) {
+	if pause := c.bandwidth.record(bytes, messages); pause > 0 {
+		time.Sleep(pause)
 	}
 }
 
+// checkIdle closes the client if it sent nothing for IdleTimeout, or checks
+// again once it would have
+func (c *Client) checkIdle() {
+	timeout := c.hub.config.IdleTimeout
+	idle := time.Since(time.Unix(0, c.lastActive.Load()))
+	if idle < timeout {
+		c.idle.Reset(timeout - idle)
+		return
+	}
+	c.logger.Info("Disconnecting client: idle", "idle", idle)
+	c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(CloseIdleTimeout, "idle_timeout"),
+		time.Now().Add(writeWait))
+	c.conn.Close()
+}
+
 // Start begins the read and write pumps for the client
 func (c *Client) Start() {
 	go c.writePump()
 	go c.readPump()
 }
 
-// SendInitialState sends the active cells to a newly connected client (sparse format with colors)
-func (c *Client) SendInitialState() error {
-	activeCells := Grid.GetActiveCells()
+// IncludeCellMeta adds the text and link of cells to the initial state sent
+// by SendInitialState
+func (c *Client) IncludeCellMeta() {
+	c.withMeta = true
+}
 
-	// Convert to ActiveCell format for JSON (includes color)
-	activeList := make([]ActiveCell, len(activeCells))
-	for i, cell := range activeCells {
-		activeList[i] = ActiveCell{X: cell.X, Y: cell.Y, Color: cell.Color}
+// SendInitialState streams the active cells to a newly connected client in
+// initChunkSize x initChunkSize regions, framed by "init" and "init_done" messages.
+// Only regions containing active cells are sent. The locked regions, the
+// claims, the teams, the client's tier and the latest chat messages follow,
+// if there are any. It must be called before Start: the write pump writes the
+// stream before anything queued on the lanes, which it could outgrow.
+func (c *Client) SendInitialState() error {
+	for _, msg := range c.hub.initialState(c.withMeta) {
+		if err := c.queueInitial(msg); err != nil {
+			return err
+		}
+	}
+	if locks := c.hub.Locks(); len(locks) > 0 {
+		if err := c.queueInitial(LocksMessage{Type: "locks", Regions: locks}); err != nil {
+			return err
+		}
+	}
+	if claims := c.hub.Claims(); len(claims) > 0 {
+		if err := c.queueInitial(ClaimsMessage{Type: "claims", Claims: claims}); err != nil {
+			return err
+		}
+	}
+	if teams := c.hub.Teams(); len(teams) > 0 {
+		if err := c.queueInitial(TeamsMessage{Type: "teams", Scores: c.hub.TeamScores()}); err != nil {
+			return err
+		}
+		if c.team != "" {
+			if err := c.queueInitial(TeamMessage{Type: "team", Team: c.team}); err != nil {
+				return err
+			}
+		}
+	}
+	if c.hub.tiers.Enabled() {
+		if err := c.queueInitial(TierMessage{Type: "tier", TierStatus: c.tier()}); err != nil {
+			return err
+		}
 	}
+	if history := c.hub.ChatHistory(); len(history) > 0 {
+		return c.queueInitial(ChatHistoryMessage{Type: "chat_history", Messages: history})
+	}
+	return nil
+}
 
-	msg := InitMessage{
-		Type:   "init",
-		Size:   GridSize,
-		Active: activeList,
+// queueInitial encodes a message of the initial state in the client's
+// protocol and adds it to the stream the write pump starts with
+func (c *Client) queueInitial(msg any) error {
+	data, err := encodeMessage(msg, c.encoding)
+	if err != nil {
+		return err
 	}
+	c.initial = append(c.initial, data)
+	return nil
+}
 
-	data, err := json.Marshal(msg)
+// sendMessage encodes a message in the client's protocol and queues it on the
+// client's high priority lane, applying the slow client policy when the lane is
+// full. It is dropped once the client is unregistered.
+func (c *Client) sendMessage(msg interface{}) error {
+	data, err := encodeMessage(msg, c.encoding)
 	if err != nil {
 		return err
 	}
 
-	c.send <- data
+	// Holding the mutex keeps the lanes from being closed meanwhile
+	h := c.hub
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if c.lanesClosed {
+		return nil
+	}
+	select {
+	case c.lanes[PriorityHigh] <- data:
+	default:
+		h.handleSlow(c, PriorityHigh, data)
+	}
 	return nil
 }
@@ -0,0 +1,86 @@
+package ws
+
+import (
+	"errors"
+	"sync"
+)
+
+// Reasons ConnLimit refuses a connection
+var (
+	ErrTooManyFromIP = errors.New("too many connections from IP")
+	ErrServerFull    = errors.New("server full")
+)
+
+// ConnLimit caps the number of concurrent connections from the same IP, and
+// overall. One limit is shared by every hub, so it counts connections to all
+// canvases.
+type ConnLimit struct {
+	// Maximum connections per IP and overall (0 disables the limit)
+	max      int
+	maxTotal int
+
+	// Open connections per IP, and overall
+	open  map[string]int
+	total int
+
+	// Signaled when a slot is released
+	freed chan struct{}
+
+	mu sync.Mutex
+}
+
+// NewConnLimit creates a ConnLimit allowing max connections per IP and
+// maxTotal overall
+func NewConnLimit(max, maxTotal int) *ConnLimit {
+	return &ConnLimit{
+		max:      max,
+		maxTotal: maxTotal,
+		open:     make(map[string]int),
+		freed:    make(chan struct{}, 1),
+	}
+}
+
+// Acquire reserves a connection slot for the IP. It returns ErrServerFull if
+// the maximum number of connections is open overall, and ErrTooManyFromIP if
+// it is open from the IP.
+func (l *ConnLimit) Acquire(ip string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxTotal > 0 && l.total >= l.maxTotal {
+		return ErrServerFull
+	}
+	if l.max > 0 && l.open[ip] >= l.max {
+		return ErrTooManyFromIP
+	}
+	l.open[ip]++
+	l.total++
+	return nil
+}
+
+// Release frees a slot reserved by Acquire
+func (l *ConnLimit) Release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.open[ip] == 0 {
+		return
+	}
+	l.total--
+	select {
+	case l.freed <- struct{}{}:
+	default:
+	}
+	if l.open[ip] == 1 {
+		delete(l.open, ip)
+		return
+	}
+	l.open[ip]--
+}
+
+// Open returns the number of connections open overall
+func (l *ConnLimit) Open() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.total
+}
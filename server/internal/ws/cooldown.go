@@ -0,0 +1,58 @@
+package ws
+
+import (
+	"sync"
+	"time"
+)
+
+// Cooldown enforces a minimum delay between pixel placements from the same IP
+type Cooldown struct {
+	// Minimum time between placements (0 disables the cooldown)
+	period time.Duration
+
+	// Time of the last placement per IP
+	last map[string]time.Time
+
+	// Time of the last sweep of expired entries
+	lastSweep time.Time
+
+	mu sync.Mutex
+}
+
+// NewCooldown creates a Cooldown with the given period
+func NewCooldown(period time.Duration) *Cooldown {
+	return &Cooldown{
+		period: period,
+		last:   make(map[string]time.Time),
+	}
+}
+
+// Allow records a placement for the IP if its cooldown has elapsed. It returns
+// false and the remaining wait time if the IP must wait before placing again.
+func (cd *Cooldown) Allow(ip string) (bool, time.Duration) {
+	if cd.period <= 0 {
+		return true, 0
+	}
+
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	now := time.Now()
+	if last, ok := cd.last[ip]; ok {
+		if elapsed := now.Sub(last); elapsed < cd.period {
+			return false, cd.period - elapsed
+		}
+	}
+	cd.last[ip] = now
+
+	// Periodically drop entries whose cooldown has expired to bound memory
+	if now.Sub(cd.lastSweep) > time.Minute {
+		for key, t := range cd.last {
+			if now.Sub(t) >= cd.period {
+				delete(cd.last, key)
+			}
+		}
+		cd.lastSweep = now
+	}
+	return true, 0
+}
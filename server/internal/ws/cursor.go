@@ -0,0 +1,53 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CursorMessage reports the cell under the client's pointer
+type CursorMessage struct {
+	Type string `json:"type"`
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+}
+
+// BroadcastCursor relays a client's pointer position to the other clients
+// viewing the cell
+type BroadcastCursor struct {
+	Type string `json:"t"`
+	ID   uint64 `json:"id"` // Connection ID, as in the roster
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+}
+
+// handleCursor relays the client's pointer position. Positions beyond the
+// client's cursor rate are dropped without a reply, as the next one
+// supersedes them anyway.
+func (c *Client) handleCursor(x, y int) {
+	if c.cursorLimiter == nil || !c.cursorLimiter.Allow() {
+		return
+	}
+	if x < 0 || x >= c.hub.grid.Width() || y < 0 || y >= c.hub.grid.Height() {
+		c.sendError("out_of_bounds", fmt.Sprintf("cell (%d, %d) is outside the grid", x, y))
+		return
+	}
+	c.hub.relayCursor(c, x, y)
+}
+
+// relayCursor sends a pointer position to the clients watching the cell,
+// other than the one it came from. Cursors are best effort: they stay on this
+// instance, skip listeners, and are dropped rather than queued when the hub
+// or a receiving client is busy.
+func (h *Hub) relayCursor(from *Client, x, y int) {
+	message, err := json.Marshal(BroadcastCursor{Type: "cursor", ID: from.id, X: x, Y: y})
+	if err != nil {
+		from.logger.Error("Failed to marshal cursor", "err", err)
+		return
+	}
+	region := CellRegion(x, y)
+	select {
+	case h.broadcast <- &outbound{data: message, region: &region, from: from, priority: PriorityLow, ephemeral: true}:
+	default:
+	}
+}
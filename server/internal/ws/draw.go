@@ -0,0 +1,442 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// Drawing primitives
+const (
+	DrawLine = "line" // Line from (x, y) to (x2, y2)
+	DrawRect = "rect" // Rectangle with corners (x, y) and (x2, y2), outlined unless filled
+	DrawFill = "fill" // Flood fill of the cells connected to (x, y) that look like it
+	DrawText = "text" // Text in the pixel font with its top-left corner at (x, y)
+)
+
+// Drawing states
+const (
+	DrawRunning  = "running"
+	DrawDone     = "done"
+	DrawCanceled = "canceled"
+	DrawFailed   = "failed"
+)
+
+const (
+	// Largest number of cells a drawing may paint
+	maxDrawCells = 65536
+
+	// Longest text in characters, and the largest text scale
+	maxDrawTextLength = 200
+	maxDrawTextScale  = 8
+
+	// Minimum time between the batches of a drawing, so drawings don't flood
+	// the canvas when there is no cooldown
+	drawInterval = 100 * time.Millisecond
+
+	// Time finished drawings are kept for status requests
+	drawRetention = 10 * time.Minute
+)
+
+// DrawRequest is a drawing primitive to paint server-side
+type DrawRequest struct {
+	// One of DrawLine, DrawRect, DrawFill, DrawText
+	Op string `json:"op"`
+
+	X  int `json:"x"`
+	Y  int `json:"y"`
+	X2 int `json:"x2,omitempty"`
+	Y2 int `json:"y2,omitempty"`
+
+	// Fill the rectangle instead of outlining it
+	Filled bool `json:"filled,omitempty"`
+
+	// Text to draw, and the cells per font pixel (1 by default)
+	Text  string `json:"text,omitempty"`
+	Scale int    `json:"scale,omitempty"`
+
+	// "#RRGGBB" color (empty selects the default color)
+	Color string `json:"color,omitempty"`
+}
+
+// DrawStatus reports the progress of a drawing
+type DrawStatus struct {
+	ID    uint64 `json:"id"`
+	Op    string `json:"op"`
+	State string `json:"state"`
+
+	// Cells the drawing changes, those painted so far and those skipped
+	// because they were locked, claimed or protected
+	Cells   int `json:"cells"`
+	Painted int `json:"painted"`
+	Skipped int `json:"skipped"`
+
+	// Why the drawing failed
+	Error string `json:"error,omitempty"`
+}
+
+// DrawMessage is sent by a client to paint a drawing primitive
+type DrawMessage struct {
+	Type string `json:"type"`
+	DrawRequest
+}
+
+// DrawStatusMessage answers a DrawMessage with the drawing started
+type DrawStatusMessage struct {
+	Type string `json:"t"`
+	DrawStatus
+}
+
+// drawing is a drawing being painted in batches
+type drawing struct {
+	actor string
+
+	status DrawStatus
+	mu     sync.Mutex
+
+	// Time the drawing stopped (zero while running)
+	finished time.Time
+
+	// Closed to stop the drawing
+	cancel     chan struct{}
+	cancelOnce sync.Once
+}
+
+// Status returns the drawing's progress
+func (d *drawing) Status() DrawStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.status
+}
+
+// finish records the final state of the drawing
+func (d *drawing) finish(state, reason string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.status.State, d.status.Error = state, reason
+	d.finished = time.Now()
+}
+
+// wait sleeps for d, reporting false if the drawing was canceled or the hub
+// stopped meanwhile
+func (d *drawing) wait(delay time.Duration, done <-chan struct{}) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-d.cancel:
+	case <-done:
+	}
+	return false
+}
+
+// Draw starts painting a drawing primitive on behalf of the placement's actor
+// and returns its status. The drawing is painted in batches of maxPaintBatch
+// cells, each counting as one placement: batches wait for the cooldown
+// instead of being rejected. Locked, claimed and protected cells are skipped.
+// Each actor paints one drawing at a time. Rejected drawings return a
+// *PlacementError.
+func (h *Hub) Draw(req DrawRequest, p Placement) (DrawStatus, error) {
+	if rejected := h.frozenError(); rejected != nil {
+		return DrawStatus{}, rejected
+	}
+	if h.IsBanned(p.IP) {
+		return DrawStatus{}, &PlacementError{Code: "banned", Message: "you are banned from painting"}
+	}
+	if rejected := h.teamError(p.Team); rejected != nil {
+		return DrawStatus{}, rejected
+	}
+	color, ok := requestedColor(req.Color)
+	if !ok {
+		return DrawStatus{}, &PlacementError{Code: "invalid_color", Message: fmt.Sprintf("color %q is not allowed", req.Color)}
+	}
+	cells, rejected := h.drawCells(req)
+	if rejected != nil {
+		return DrawStatus{}, rejected
+	}
+
+	// Leave out the cells that already look right, so they don't use up placements
+	loc := h.config.GeoIP.Lookup(p.IP)
+	pixels := make([]model.Pixel, 0, len(cells))
+	for _, cell := range cells {
+		if h.grid.GetCell(cell[0], cell[1]) == (CellState{Active: true, Color: color}) {
+			continue
+		}
+		pixels = append(pixels, model.Pixel{X: cell[0], Y: cell[1], Active: true, Color: color, Country: loc.Country, Region: loc.Region, Team: p.Team})
+	}
+
+	h.drawingsMu.Lock()
+	defer h.drawingsMu.Unlock()
+	for id, d := range h.drawings {
+		d.mu.Lock()
+		running, expired := d.finished.IsZero(), !d.finished.IsZero() && time.Since(d.finished) > drawRetention
+		d.mu.Unlock()
+		if running && d.actor == p.Actor {
+			return DrawStatus{}, &PlacementError{Code: "drawing_in_progress", Message: fmt.Sprintf("drawing %d is still being painted", id)}
+		}
+		if expired {
+			delete(h.drawings, id)
+		}
+	}
+	h.lastDrawingID++
+	d := &drawing{
+		actor:  p.Actor,
+		status: DrawStatus{ID: h.lastDrawingID, Op: req.Op, State: DrawRunning, Cells: len(pixels)},
+		cancel: make(chan struct{}),
+	}
+	h.drawings[d.status.ID] = d
+	slog.Info("Drawing started", "canvas", h.config.Canvas, "id", d.status.ID, "op", req.Op, "cells", len(pixels), "actor", p.Actor)
+	go h.paintDrawing(d, pixels, p)
+	return d.Status(), nil
+}
+
+// Drawing returns the status of a drawing, reporting false if there is no
+// such drawing
+func (h *Hub) Drawing(id uint64) (DrawStatus, bool) {
+	h.drawingsMu.Lock()
+	d, ok := h.drawings[id]
+	h.drawingsMu.Unlock()
+	if !ok {
+		return DrawStatus{}, false
+	}
+	return d.Status(), true
+}
+
+// CancelDrawing stops a drawing of actor, leaving the cells painted so far.
+// It reports false if actor has no such drawing.
+func (h *Hub) CancelDrawing(id uint64, actor string) bool {
+	h.drawingsMu.Lock()
+	d, ok := h.drawings[id]
+	h.drawingsMu.Unlock()
+	if !ok || d.actor != actor {
+		return false
+	}
+	d.cancelOnce.Do(func() { close(d.cancel) })
+	return true
+}
+
+// paintDrawing paints the pixels of a drawing batch by batch, pacing the
+// batches by the cooldown and drawInterval
+func (h *Hub) paintDrawing(d *drawing, pixels []model.Pixel, p Placement) {
+	for start := 0; start < len(pixels); start += maxPaintBatch {
+		if start > 0 && !d.wait(drawInterval, h.done) {
+			d.finish(DrawCanceled, "")
+			return
+		}
+		for {
+			if rejected := h.frozenError(); rejected != nil {
+				d.finish(DrawFailed, rejected.Message)
+				return
+			}
+			if h.IsBanned(p.IP) {
+				d.finish(DrawFailed, "you are banned from painting")
+				return
+			}
+			rejected := h.paceError(p)
+			if rejected == nil {
+				rejected = h.throttleError(p.Actor)
+			}
+			if rejected == nil {
+				break
+			}
+			if rejected.Code != "cooldown" {
+				d.finish(DrawFailed, rejected.Message)
+				return
+			}
+			if !d.wait(rejected.RetryAfter, h.done) {
+				d.finish(DrawCanceled, "")
+				return
+			}
+		}
+
+		end := min(start+maxPaintBatch, len(pixels))
+		batch := slices.Clone(pixels[start:end])
+		if !p.Moderator {
+			batch = slices.DeleteFunc(batch, func(px model.Pixel) bool {
+				return h.lockedError([]model.Pixel{px}) != nil || h.claimedError([]model.Pixel{px}, p.Actor) != nil
+			})
+		}
+		if h.IsShadowBanned(p.IP, p.Actor) {
+			h.shadowCells(batch, p.Actor)
+		} else {
+			for len(batch) > 0 {
+				cell, _, protected := h.protection.Claim(p.Actor, batch)
+				if !protected {
+					break
+				}
+				batch = slices.DeleteFunc(batch, func(px model.Pixel) bool { return px.X == cell.X && px.Y == cell.Y })
+			}
+			h.placing.RLock()
+			changed, prior, err := h.grid.SwapCells(batch, h.journal(context.Background(), p.Actor))
+			if err != nil {
+				h.placing.RUnlock()
+				d.finish(DrawFailed, errWriteFailed.Message)
+				return
+			}
+			h.observeGrief(p.Actor, p.Moderator, changed, prior, true)
+			h.commitChanges(context.Background(), changed, p.Actor)
+			h.placing.RUnlock()
+		}
+
+		d.mu.Lock()
+		d.status.Painted += len(batch)
+		d.status.Skipped += end - start - len(batch)
+		d.mu.Unlock()
+	}
+	d.finish(DrawDone, "")
+	status := d.Status()
+	slog.Info("Drawing done", "canvas", h.config.Canvas, "id", status.ID, "painted", status.Painted, "skipped", status.Skipped)
+}
+
+// drawCells returns the cells a drawing primitive covers, rejecting primitives
+// that are invalid or too large
+func (h *Hub) drawCells(req DrawRequest) ([][2]int, *PlacementError) {
+	outOfBounds := func(x, y int) *PlacementError {
+		return &PlacementError{Code: "out_of_bounds", Message: fmt.Sprintf("cell (%d, %d) is outside the grid", x, y)}
+	}
+	tooLarge := &PlacementError{Code: "drawing_too_large", Message: fmt.Sprintf("drawings may paint at most %d cells", maxDrawCells)}
+
+	if !h.grid.InBounds(req.X, req.Y) {
+		return nil, outOfBounds(req.X, req.Y)
+	}
+	switch req.Op {
+	case DrawLine, DrawRect:
+		if !h.grid.InBounds(req.X2, req.Y2) {
+			return nil, outOfBounds(req.X2, req.Y2)
+		}
+		if req.Op == DrawLine {
+			return lineCells(req.X, req.Y, req.X2, req.Y2), nil
+		}
+		x1, x2 := min(req.X, req.X2), max(req.X, req.X2)
+		y1, y2 := min(req.Y, req.Y2), max(req.Y, req.Y2)
+		if req.Filled && (x2-x1+1)*(y2-y1+1) > maxDrawCells {
+			return nil, tooLarge
+		}
+		return rectCells(x1, y1, x2, y2, req.Filled), nil
+
+	case DrawFill:
+		cells, ok := h.fillCells(req.X, req.Y)
+		if !ok {
+			return nil, tooLarge
+		}
+		return cells, nil
+
+	case DrawText:
+		scale := req.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		if scale < 1 || scale > maxDrawTextScale {
+			return nil, &PlacementError{Code: "invalid_scale", Message: fmt.Sprintf("scale must be between 1 and %d", maxDrawTextScale)}
+		}
+		if req.Text == "" || len([]rune(req.Text)) > maxDrawTextLength {
+			return nil, &PlacementError{Code: "invalid_text", Message: fmt.Sprintf("text must be between 1 and %d characters", maxDrawTextLength)}
+		}
+		cells := slices.DeleteFunc(textCells(req.Text, req.X, req.Y, scale), func(c [2]int) bool { return !h.grid.InBounds(c[0], c[1]) })
+		if len(cells) > maxDrawCells {
+			return nil, tooLarge
+		}
+		return cells, nil
+
+	default:
+		return nil, &PlacementError{Code: "unknown_op", Message: fmt.Sprintf("unknown drawing %q", req.Op)}
+	}
+}
+
+// lineCells returns the cells of the line from (x1, y1) to (x2, y2), using
+// Bresenham's algorithm
+func lineCells(x1, y1, x2, y2 int) [][2]int {
+	dx, dy := abs(x2-x1), -abs(y2-y1)
+	sx, sy := 1, 1
+	if x1 > x2 {
+		sx = -1
+	}
+	if y1 > y2 {
+		sy = -1
+	}
+	cells := make([][2]int, 0, max(dx, -dy)+1)
+	for e := dx + dy; ; {
+		cells = append(cells, [2]int{x1, y1})
+		if x1 == x2 && y1 == y2 {
+			return cells
+		}
+		if e2 := 2 * e; e2 >= dy {
+			e += dy
+			x1 += sx
+		}
+		if e2 := 2 * e; e2 <= dx {
+			e += dx
+			y1 += sy
+		}
+	}
+}
+
+// rectCells returns the cells of the rectangle with the inclusive corners
+// (x1, y1) and (x2, y2), or of its outline
+func rectCells(x1, y1, x2, y2 int, filled bool) [][2]int {
+	var cells [][2]int
+	for y := y1; y <= y2; y++ {
+		for x := x1; x <= x2; x++ {
+			if filled || x == x1 || x == x2 || y == y1 || y == y2 {
+				cells = append(cells, [2]int{x, y})
+			}
+		}
+	}
+	return cells
+}
+
+// fillCells returns the cells connected to (x, y) horizontally or vertically
+// through cells in the same state, reporting false if there are more than
+// maxDrawCells
+func (h *Hub) fillCells(x, y int) ([][2]int, bool) {
+	target := h.grid.GetCell(x, y)
+	seen := map[[2]int]bool{{x, y}: true}
+	cells := [][2]int{{x, y}}
+	for i := 0; i < len(cells); i++ {
+		c := cells[i]
+		for _, n := range [4][2]int{{c[0] + 1, c[1]}, {c[0] - 1, c[1]}, {c[0], c[1] + 1}, {c[0], c[1] - 1}} {
+			if seen[n] || !h.grid.InBounds(n[0], n[1]) || h.grid.GetCell(n[0], n[1]) != target {
+				continue
+			}
+			if len(cells) == maxDrawCells {
+				return nil, false
+			}
+			seen[n] = true
+			cells = append(cells, n)
+		}
+	}
+	return cells, true
+}
+
+// abs returns the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// handleDraw starts painting a drawing primitive for an authenticated client,
+// replying with its status
+func (c *Client) handleDraw(req DrawRequest) {
+	if c.identity == nil {
+		c.sendError("unauthenticated", "drawing requires authentication")
+		return
+	}
+	status, err := c.hub.Draw(req, Placement{IP: c.ipAddress, Actor: c.actor(), Team: c.team, Moderator: c.identity.Moderator(), Role: c.role()})
+	var rejected *PlacementError
+	if errors.As(err, &rejected) {
+		c.sendError(rejected.Code, rejected.Message)
+		return
+	}
+	if err := c.sendMessage(DrawStatusMessage{Type: "draw", DrawStatus: status}); err != nil {
+		c.logger.Error("Failed to send drawing status", "err", err)
+	}
+}
@@ -0,0 +1,64 @@
+package ws
+
+import "github.com/million_grids/server/internal/model"
+
+// EventSink is notified of the events of a hub's canvas caused by this
+// instance. It is called on the goroutine applying the change, so it must not block.
+type EventSink interface {
+	// PixelsChanged reports cells whose state changed
+	PixelsChanged(canvas string, changed []model.Pixel)
+
+	// CanvasReset reports that every cell of the canvas was cleared
+	CanvasReset(canvas string)
+
+	// Milestone reports that the canvas reached a multiple of the milestone
+	// interval of placements
+	Milestone(canvas string, placements int64)
+}
+
+// nopEvents is the EventSink of hubs nobody observes
+type nopEvents struct{}
+
+func (nopEvents) PixelsChanged(string, []model.Pixel) {}
+func (nopEvents) CanvasReset(string)                  {}
+func (nopEvents) Milestone(string, int64)             {}
+
+// MultiSink notifies every sink in turn
+type MultiSink []EventSink
+
+func (m MultiSink) PixelsChanged(canvas string, changed []model.Pixel) {
+	for _, sink := range m {
+		sink.PixelsChanged(canvas, changed)
+	}
+}
+
+func (m MultiSink) CanvasReset(canvas string) {
+	for _, sink := range m {
+		sink.CanvasReset(canvas)
+	}
+}
+
+func (m MultiSink) Milestone(canvas string, placements int64) {
+	for _, sink := range m {
+		sink.Milestone(canvas, placements)
+	}
+}
+
+// countPlacements adds placements to the canvas total, notifying the sink if
+// a local change crosses a milestone
+func (h *Hub) countPlacements(n int, local bool) {
+	total := h.placements.Add(int64(n))
+	every := h.config.MilestoneEvery
+	if !local || every <= 0 {
+		return
+	}
+	if reached := total / every; reached > (total-int64(n))/every {
+		h.config.Events.Milestone(h.config.Canvas, reached*every)
+	}
+}
+
+// Placements returns the number of cell changes made on the canvas, since the
+// start of its history when the count was loaded from the database
+func (h *Hub) Placements() int64 {
+	return h.placements.Load()
+}
@@ -0,0 +1,195 @@
+package ws
+
+import (
+	"context"
+	"time"
+
+	"github.com/million_grids/server/internal/crash"
+	"github.com/million_grids/server/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Broadcasts queued per fan-out worker before the Run loop waits for it
+const fanoutQueueSize = 64
+
+// recipient is a client a broadcast is queued on, with the room it was routed
+// through for the room's stats
+type recipient struct {
+	client *Client
+	room   *Room
+}
+
+// fanoutJob is the share of a broadcast's recipients handled by one worker
+type fanoutJob struct {
+	message    *outbound
+	recipients []recipient
+
+	// Span of the broadcast, and when the job was dispatched (for traced
+	// messages only)
+	span       trace.SpanContext
+	dispatched time.Time
+}
+
+// fanout is the pool of workers encoding broadcasts and queueing them on the
+// clients' send lanes. Each client is handled by a single worker, so the
+// messages it receives keep the order they were broadcast in, while the
+// recipients of a broadcast are spread over every worker.
+type fanout struct {
+	hub    *Hub
+	queues []chan fanoutJob
+}
+
+func newFanout(hub *Hub, workers int) *fanout {
+	f := &fanout{hub: hub, queues: make([]chan fanoutJob, workers)}
+	for i := range f.queues {
+		f.queues[i] = make(chan fanoutJob, fanoutQueueSize)
+	}
+	return f
+}
+
+// start launches the workers, restarted if they panic
+func (f *fanout) start() {
+	for _, queue := range f.queues {
+		go crash.Supervise("fanout", func() { f.work(queue) }, "canvas", f.hub.config.Canvas)
+	}
+}
+
+// stop ends the workers once they have handled the jobs already dispatched
+func (f *fanout) stop() {
+	for _, queue := range f.queues {
+		close(queue)
+	}
+}
+
+// depth returns the share of the fullest worker queue in use
+func (f *fanout) depth() float64 {
+	var depth float64
+	for _, queue := range f.queues {
+		depth = max(depth, float64(len(queue))/float64(cap(queue)))
+	}
+	return depth
+}
+
+// batches returns an empty batch of recipients per worker
+func (f *fanout) batches() [][]recipient {
+	return make([][]recipient, len(f.queues))
+}
+
+// add appends a recipient to the batch of the worker handling its client
+func (f *fanout) add(batches [][]recipient, client *Client, room *Room) {
+	worker := client.id % uint64(len(f.queues))
+	batches[worker] = append(batches[worker], recipient{client: client, room: room})
+}
+
+// dispatch hands the batches of a broadcast to their workers, waiting for
+// workers whose queue is full. span is the broadcast's span, which the jobs
+// of traced messages are traced in.
+func (f *fanout) dispatch(message *outbound, batches [][]recipient, span trace.Span) {
+	traced := span.SpanContext().IsValid()
+	total, jobs := 0, 0
+	for _, recipients := range batches {
+		if len(recipients) > 0 {
+			total += len(recipients)
+			jobs++
+		}
+	}
+	if !message.received.IsZero() {
+		// The last job to finish measures the latency
+		message.pending.Store(int32(jobs))
+		f.hub.metrics.recipients.Observe(float64(total))
+	}
+	for worker, recipients := range batches {
+		if len(recipients) == 0 {
+			continue
+		}
+		job := fanoutJob{message: message, recipients: recipients}
+		if traced {
+			job.span, job.dispatched = span.SpanContext(), time.Now()
+		}
+		f.queues[worker] <- job
+	}
+	if traced {
+		span.SetAttributes(attribute.Int("recipients", total))
+	}
+}
+
+// traceJob starts the span of a traced broadcast's job, from its dispatch to
+// the worker until it is queued on the clients' lanes
+func (f *fanout) traceJob(job fanoutJob) trace.Span {
+	if !job.span.IsValid() {
+		return trace.SpanFromContext(context.Background())
+	}
+	ctx := trace.ContextWithSpanContext(context.Background(), job.span)
+	_, span := tracing.Tracer().Start(ctx, "hub.fanout", canvasAttr(f.hub),
+		trace.WithTimestamp(job.dispatched),
+		trace.WithAttributes(attribute.Int("recipients", len(job.recipients))))
+	return span
+}
+
+// work queues the broadcasts of a worker's jobs on their recipients' lanes.
+// Clients with a full lane are handled by the slow client policy, except for
+// low priority and ephemeral messages which are dropped. Low priority messages
+// are dropped for clients over their bandwidth cap too.
+func (f *fanout) work(queue <-chan fanoutJob) {
+	h := f.hub
+	for job := range queue {
+		span := f.traceJob(job)
+		delivered, dropped, overCap := f.deliver(job)
+		if overCap > 0 {
+			h.metrics.droppedBandwidth.Add(float64(overCap))
+		}
+		if dropped > 0 {
+			h.metrics.droppedFull.Add(float64(dropped))
+		}
+		if !job.message.received.IsZero() && job.message.pending.Add(-1) == 0 {
+			h.metrics.latency.Observe(time.Since(job.message.received).Seconds())
+		}
+		span.SetAttributes(attribute.Int("delivered", delivered), attribute.Int("dropped", dropped+overCap))
+		span.End()
+	}
+}
+
+// deliver queues a job's broadcast on its recipients' lanes, returning the
+// number of clients it was queued on, dropped for and dropped for as they
+// were over their bandwidth cap
+func (f *fanout) deliver(job fanoutJob) (delivered, dropped, overCap int) {
+	h := f.hub
+
+	// Holding the mutex keeps the send channels from being closed meanwhile
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, r := range job.recipients {
+		if !h.canvasRoom.members[r.client] {
+			// Unregistered since the broadcast was routed
+			continue
+		}
+		low := job.message.priority == PriorityLow
+		if low && !r.client.bandwidth.available() {
+			// Over its bandwidth cap, low-priority messages go first
+			r.client.bandwidth.dropped.Add(1)
+			r.room.dropped.Add(1)
+			overCap++
+			continue
+		}
+		data := job.message.frame(r.client.encoding)
+		if data == nil {
+			continue
+		}
+		select {
+		case r.client.lanes[job.message.priority] <- data:
+			r.room.delivered.Add(1)
+			delivered++
+		default:
+			if !low && !job.message.ephemeral && h.handleSlow(r.client, job.message.priority, data) {
+				r.room.delivered.Add(1)
+				delivered++
+			} else {
+				r.client.bandwidth.droppedFull.Add(1)
+				r.room.dropped.Add(1)
+				dropped++
+			}
+		}
+	}
+	return delivered, dropped, overCap
+}
@@ -0,0 +1,21 @@
+package ws
+
+// followerMessages are the client message types a follower accepts, which
+// only choose the updates the client receives
+var followerMessages = map[string]bool{
+	MsgSubscribe:    true,
+	MsgUnsubscribe:  true,
+	MsgJoinChannel:  true,
+	MsgLeaveChannel: true,
+}
+
+// followerError returns the error rejecting the writes sent to a follower
+func followerError() *PlacementError {
+	return &PlacementError{Code: "read_only_replica", Message: "this server is a read-only replica"}
+}
+
+// sendFollowerError rejects a message a follower doesn't accept
+func (c *Client) sendFollowerError() {
+	rejected := followerError()
+	c.sendError(rejected.Code, rejected.Message)
+}
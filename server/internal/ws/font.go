@@ -0,0 +1,97 @@
+package ws
+
+import "unicode"
+
+const (
+	// Size of a glyph of the pixel font, in cells
+	glyphWidth  = 3
+	glyphHeight = 5
+
+	// Distance between the origins of consecutive characters and lines
+	glyphAdvance = glyphWidth + 1
+	lineAdvance  = glyphHeight + 1
+)
+
+// font is the built-in 3x5 pixel font used to draw text, one row per string
+// with '#' for a set cell. Letters are uppercase only.
+var font = map[rune][glyphHeight]string{
+	'A':  {".#.", "#.#", "###", "#.#", "#.#"},
+	'B':  {"##.", "#.#", "##.", "#.#", "##."},
+	'C':  {".##", "#..", "#..", "#..", ".##"},
+	'D':  {"##.", "#.#", "#.#", "#.#", "##."},
+	'E':  {"###", "#..", "##.", "#..", "###"},
+	'F':  {"###", "#..", "##.", "#..", "#.."},
+	'G':  {".##", "#..", "#.#", "#.#", ".##"},
+	'H':  {"#.#", "#.#", "###", "#.#", "#.#"},
+	'I':  {"###", ".#.", ".#.", ".#.", "###"},
+	'J':  {"..#", "..#", "..#", "#.#", ".#."},
+	'K':  {"#.#", "#.#", "##.", "#.#", "#.#"},
+	'L':  {"#..", "#..", "#..", "#..", "###"},
+	'M':  {"#.#", "###", "###", "#.#", "#.#"},
+	'N':  {"##.", "#.#", "#.#", "#.#", "#.#"},
+	'O':  {".#.", "#.#", "#.#", "#.#", ".#."},
+	'P':  {"##.", "#.#", "##.", "#..", "#.."},
+	'Q':  {".#.", "#.#", "#.#", "##.", ".##"},
+	'R':  {"##.", "#.#", "##.", "#.#", "#.#"},
+	'S':  {".##", "#..", ".#.", "..#", "##."},
+	'T':  {"###", ".#.", ".#.", ".#.", ".#."},
+	'U':  {"#.#", "#.#", "#.#", "#.#", "###"},
+	'V':  {"#.#", "#.#", "#.#", "#.#", ".#."},
+	'W':  {"#.#", "#.#", "###", "###", "#.#"},
+	'X':  {"#.#", "#.#", ".#.", "#.#", "#.#"},
+	'Y':  {"#.#", "#.#", ".#.", ".#.", ".#."},
+	'Z':  {"###", "..#", ".#.", "#..", "###"},
+	'0':  {"###", "#.#", "#.#", "#.#", "###"},
+	'1':  {".#.", "##.", ".#.", ".#.", "###"},
+	'2':  {"##.", "..#", ".#.", "#..", "###"},
+	'3':  {"##.", "..#", ".#.", "..#", "##."},
+	'4':  {"#.#", "#.#", "###", "..#", "..#"},
+	'5':  {"###", "#..", "##.", "..#", "##."},
+	'6':  {".##", "#..", "###", "#.#", "###"},
+	'7':  {"###", "..#", ".#.", ".#.", ".#."},
+	'8':  {"###", "#.#", "###", "#.#", "###"},
+	'9':  {"###", "#.#", "###", "..#", "##."},
+	' ':  {"...", "...", "...", "...", "..."},
+	'!':  {".#.", ".#.", ".#.", "...", ".#."},
+	'?':  {"##.", "..#", ".#.", "...", ".#."},
+	'.':  {"...", "...", "...", "...", ".#."},
+	',':  {"...", "...", "...", ".#.", "#.."},
+	':':  {"...", ".#.", "...", ".#.", "..."},
+	'-':  {"...", "...", "###", "...", "..."},
+	'+':  {"...", ".#.", "###", ".#.", "..."},
+	'/':  {"..#", "..#", ".#.", "#..", "#.."},
+	'\'': {".#.", ".#.", "...", "...", "..."},
+}
+
+// textCells returns the cells drawing text in the pixel font with its top-left
+// corner at (x, y), each font pixel scale cells wide. Lines are separated by
+// '\n' and characters the font lacks are drawn as '?'.
+func textCells(text string, x, y, scale int) [][2]int {
+	var cells [][2]int
+	col, row := 0, 0
+	for _, r := range text {
+		if r == '\n' {
+			col, row = 0, row+1
+			continue
+		}
+		glyph, ok := font[unicode.ToUpper(r)]
+		if !ok {
+			glyph = font['?']
+		}
+		originX, originY := x+col*glyphAdvance*scale, y+row*lineAdvance*scale
+		for gy, line := range glyph {
+			for gx, bit := range line {
+				if bit != '#' {
+					continue
+				}
+				for sy := 0; sy < scale; sy++ {
+					for sx := 0; sx < scale; sx++ {
+						cells = append(cells, [2]int{originX + gx*scale + sx, originY + gy*scale + sy})
+					}
+				}
+			}
+		}
+		col++
+	}
+	return cells
+}
@@ -0,0 +1,297 @@
+package ws
+
+import (
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// Reasons an actor was quarantined
+const (
+	// Overwrote many painted cells with a single color (or cleared them)
+	GriefMassOverwrite = "mass_overwrite"
+
+	// Placed at intervals too regular for a person
+	GriefScripted = "scripted"
+)
+
+// GriefPolicy configures the detection of griefers: actors overwriting many
+// painted cells with one color within Window, or placing at intervals too
+// regular for a person. Detected actors are quarantined, throttled to one
+// placement per QuarantineCooldown for Quarantine. A zero Window disables
+// detection.
+type GriefPolicy struct {
+	Window time.Duration
+
+	// Painted cells overwritten within Window, and the share of them painted
+	// with the most used color, that make a mass overwrite
+	Overwrites int
+	ColorShare float64
+
+	// Placements whose intervals are checked for regularity (0 disables it),
+	// and the largest relative deviation of the intervals considered scripted
+	ScriptSamples int
+	ScriptJitter  float64
+
+	Quarantine         time.Duration
+	QuarantineCooldown time.Duration
+}
+
+// Quarantine is an actor the grief detection throttles, with the region it
+// was caught changing
+type Quarantine struct {
+	Actor  string    `json:"actor"`
+	Reason string    `json:"reason"`
+	Region Region    `json:"region"`
+	Until  time.Time `json:"until"`
+}
+
+// GriefReporter flags the regions changed by quarantined actors for
+// moderators to review and roll back
+type GriefReporter interface {
+	ReportGrief(canvas string, quarantine Quarantine)
+}
+
+// griefOverwrite is a painted cell an actor overwrote
+type griefOverwrite struct {
+	x, y int
+
+	// Color painted, -1 when the cell was cleared
+	color int
+
+	at time.Time
+}
+
+// griefActivity is the recent activity of an actor
+type griefActivity struct {
+	// Overwrites within the window, oldest first
+	overwrites []griefOverwrite
+
+	// Times of the latest placements, and the region they changed
+	placements []time.Time
+	placed     Region
+
+	last time.Time
+}
+
+// GriefDetector watches the placements of every actor for griefing, and
+// quarantines the actors caught. Moderators, admin changes and decay aren't
+// watched.
+type GriefDetector struct {
+	policy GriefPolicy
+
+	activity    map[string]*griefActivity
+	quarantined map[string]Quarantine
+
+	// Throttles the quarantined actors
+	cooldown *Cooldown
+
+	// Time of the last sweep of idle actors
+	lastSweep time.Time
+
+	mu sync.Mutex
+}
+
+// NewGriefDetector creates a GriefDetector with the given policy
+func NewGriefDetector(policy GriefPolicy) *GriefDetector {
+	return &GriefDetector{
+		policy:      policy,
+		activity:    make(map[string]*griefActivity),
+		quarantined: make(map[string]Quarantine),
+		cooldown:    NewCooldown(policy.QuarantineCooldown),
+	}
+}
+
+// Observe records cells changed by actor, given the states they replaced,
+// and returns the quarantine it put actor in if the change revealed griefing.
+// Paced changes (drawings) aren't checked for regular timing.
+func (g *GriefDetector) Observe(actor string, changed []model.Pixel, prior []CellState, paced bool) (Quarantine, bool) {
+	if g.policy.Window <= 0 || len(changed) == 0 {
+		return Quarantine{}, false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	g.sweep(now)
+	if q, ok := g.quarantined[actor]; ok && now.Before(q.Until) {
+		return Quarantine{}, false
+	}
+	a := g.activity[actor]
+	if a == nil {
+		a = &griefActivity{}
+		g.activity[actor] = a
+	}
+	a.last = now
+
+	for i, p := range changed {
+		if !prior[i].Active || (p.Active && p.Color == prior[i].Color) {
+			continue
+		}
+		color := -1
+		if p.Active {
+			color = int(p.Color)
+		}
+		a.overwrites = append(a.overwrites, griefOverwrite{x: p.X, y: p.Y, color: color, at: now})
+	}
+	cutoff := now.Add(-g.policy.Window)
+	a.overwrites = slices.DeleteFunc(a.overwrites, func(o griefOverwrite) bool { return o.at.Before(cutoff) })
+	if len(a.overwrites) >= g.policy.Overwrites && g.dominantShare(a.overwrites) >= g.policy.ColorShare {
+		var region Region
+		for _, o := range a.overwrites {
+			region = region.Extend(o.x, o.y)
+		}
+		return g.quarantine(actor, GriefMassOverwrite, region, now), true
+	}
+
+	if samples := g.policy.ScriptSamples; samples > 1 && !paced {
+		a.placements = append(a.placements, now)
+		for _, p := range changed {
+			a.placed = a.placed.Extend(p.X, p.Y)
+		}
+		if len(a.placements) > samples {
+			a.placements = a.placements[len(a.placements)-samples:]
+		}
+		if len(a.placements) == samples && scripted(a.placements, g.policy.ScriptJitter) {
+			return g.quarantine(actor, GriefScripted, a.placed, now), true
+		}
+	}
+	return Quarantine{}, false
+}
+
+// Throttle consumes a placement of a quarantined actor, returning false and
+// the remaining wait time if it must wait. Other actors are never throttled.
+func (g *GriefDetector) Throttle(actor string) (bool, time.Duration) {
+	g.mu.Lock()
+	q, ok := g.quarantined[actor]
+	g.mu.Unlock()
+	if !ok || !time.Now().Before(q.Until) {
+		return true, 0
+	}
+	return g.cooldown.Allow(actor)
+}
+
+// Quarantined returns the actors in quarantine, the latest caught first
+func (g *GriefDetector) Quarantined() []Quarantine {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	quarantined := make([]Quarantine, 0, len(g.quarantined))
+	for _, q := range g.quarantined {
+		if now.Before(q.Until) {
+			quarantined = append(quarantined, q)
+		}
+	}
+	slices.SortFunc(quarantined, func(a, b Quarantine) int { return b.Until.Compare(a.Until) })
+	return quarantined
+}
+
+// Release lifts the quarantine of an actor, reporting false if it wasn't in
+// quarantine
+func (g *GriefDetector) Release(actor string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	q, ok := g.quarantined[actor]
+	delete(g.quarantined, actor)
+	return ok && time.Now().Before(q.Until)
+}
+
+// quarantine puts actor in quarantine for region, forgetting its activity so
+// it starts over once released, g.mu must be held
+func (g *GriefDetector) quarantine(actor, reason string, region Region, now time.Time) Quarantine {
+	q := Quarantine{Actor: actor, Reason: reason, Region: region, Until: now.Add(g.policy.Quarantine)}
+	g.quarantined[actor] = q
+	delete(g.activity, actor)
+	return q
+}
+
+// dominantShare returns the share of overwrites painted with the most used color
+func (g *GriefDetector) dominantShare(overwrites []griefOverwrite) float64 {
+	counts := make(map[int]int)
+	most := 0
+	for _, o := range overwrites {
+		counts[o.color]++
+		most = max(most, counts[o.color])
+	}
+	return float64(most) / float64(len(overwrites))
+}
+
+// sweep periodically drops the activity of idle actors and the expired
+// quarantines to bound memory, g.mu must be held
+func (g *GriefDetector) sweep(now time.Time) {
+	if now.Sub(g.lastSweep) <= time.Minute {
+		return
+	}
+	idle := max(g.policy.Window, time.Minute)
+	for actor, a := range g.activity {
+		if now.Sub(a.last) >= idle {
+			delete(g.activity, actor)
+		}
+	}
+	for actor, q := range g.quarantined {
+		if !now.Before(q.Until) {
+			delete(g.quarantined, actor)
+		}
+	}
+	g.lastSweep = now
+}
+
+// scripted reports whether the intervals between placement times deviate
+// from their mean by less than jitter of it
+func scripted(times []time.Time, jitter float64) bool {
+	n := float64(len(times) - 1)
+	mean := float64(times[len(times)-1].Sub(times[0])) / n
+	if mean <= 0 {
+		return false
+	}
+	var variance float64
+	for i := 1; i < len(times); i++ {
+		d := float64(times[i].Sub(times[i-1])) - mean
+		variance += d * d
+	}
+	return math.Sqrt(variance/n) < jitter*mean
+}
+
+// observeGrief watches cells changed by a non-moderator actor for griefing,
+// quarantining the actor and flagging the region if it is caught
+func (h *Hub) observeGrief(actor string, moderator bool, changed []model.Pixel, prior []CellState, paced bool) {
+	if moderator {
+		return
+	}
+	q, caught := h.grief.Observe(actor, changed, prior, paced)
+	if !caught {
+		return
+	}
+	slog.Warn("Quarantined suspected griefer", "canvas", h.config.Canvas, "actor", actor, "reason", q.Reason, "region", q.Region, "until", q.Until)
+	if h.config.GriefReporter != nil {
+		h.config.GriefReporter.ReportGrief(h.config.Canvas, q)
+	}
+}
+
+// throttleError returns the error rejecting a placement by a quarantined
+// actor that must wait, which looks like the cooldown, or nil if it may place
+func (h *Hub) throttleError(actor string) *PlacementError {
+	if ok, remaining := h.grief.Throttle(actor); !ok {
+		return &PlacementError{Code: "cooldown", Message: "placing too fast, wait for the cooldown", RetryAfter: remaining}
+	}
+	return nil
+}
+
+// Quarantined returns the actors the grief detection throttles on the canvas
+func (h *Hub) Quarantined() []Quarantine {
+	return h.grief.Quarantined()
+}
+
+// ReleaseQuarantine lifts the quarantine of an actor, reporting false if it
+// wasn't in quarantine
+func (h *Hub) ReleaseQuarantine(actor string) bool {
+	if !h.grief.Release(actor) {
+		return false
+	}
+	slog.Info("Released quarantined actor", "canvas", h.config.Canvas, "actor", actor)
+	return true
+}
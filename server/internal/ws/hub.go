@@ -1,18 +1,245 @@
 package ws
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"maps"
+	"net"
+	"runtime"
+	"slices"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/million_grids/server/internal/crash"
+	"github.com/million_grids/server/internal/geoip"
+	"github.com/million_grids/server/internal/model"
+	"github.com/million_grids/server/internal/tracing"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// outbound is a message queued for broadcast, optionally limited to a region
+type outbound struct {
+	data []byte
+
+	// Region the message concerns (nil means deliver to every client)
+	region *Region
+
+	// Name of the room the message is for, instead of the whole canvas or a region
+	room string
+
+	// Client the message came from, which doesn't receive it (nil for none)
+	from *Client
+
+	// Lane of the clients' send queues the message waits in
+	priority Priority
+
+	// Skipped for clients with a full send buffer instead of disconnecting
+	// them, and not sent to listeners
+	ephemeral bool
+
+	// Span of the change the message broadcasts, and when it was queued, for
+	// the broadcast to be traced (invalid for untraced messages)
+	span   trace.SpanContext
+	queued time.Time
+
+	// When the change the message broadcasts was received, for its latency
+	// to be measured (zero for other messages), and the fan-out jobs yet to
+	// queue it
+	received time.Time
+	pending  atomic.Int32
+
+	// Protobuf encoding of data, converted for the first protobuf client
+	binary  []byte
+	convert sync.Once
+}
+
+// frame returns the message in the encoding, or nil if it has none
+func (m *outbound) frame(enc Encoding) []byte {
+	if enc == EncodingJSON {
+		return m.data
+	}
+	return m.protobuf()
+}
+
+// protobuf returns the message as a protobuf frame, or nil if it has none
+func (m *outbound) protobuf() []byte {
+	m.convert.Do(func() {
+		var err error
+		if m.binary, err = transcodeProtobuf(m.data); err != nil {
+			slog.Error("Failed to convert broadcast to protobuf", "err", err)
+		}
+	})
+	return m.binary
+}
+
+// HubConfig holds the hub's dependencies and the tunable limits applied to its clients
+type HubConfig struct {
+	// Name of the canvas served by the hub, stamped on persisted pixels
+	Canvas string
+
+	// Persists cell changes (nil persists nothing)
+	Store PixelStore
+
+	// Propagates cell updates to other instances (nil for a single instance),
+	// closed when the hub stops
+	Broker Broker
+
+	// Replicates cell changes to the servers of the other regions (nil for a
+	// single region), closed when the hub stops, and the name of our region,
+	// which breaks ties between changes made at the same time
+	Replication Broker
+	Region      string
+
+	// Minimum delay between placements from the same IP (0 disables)
+	PlacementCooldown time.Duration
+
+	// Cooldown and quota tiers of authenticated actors, the first matching
+	// applying instead of PlacementCooldown, and where their account ages
+	// are looked up (nil matches no tier with a minimum account age)
+	Tiers    []Tier
+	Accounts AccountDirectory
+
+	// Time a placed cell can't be changed by other actors (0 disables)
+	OverwriteProtection time.Duration
+
+	// Sustained inbound messages per second per client (0 disables rate limiting)
+	MessageRate float64
+
+	// Number of inbound messages a client may send in a burst
+	MessageBurst int
+
+	// Sustained cursor positions relayed per second per client (0 disables
+	// cursor sharing). Cursor messages also count against MessageRate.
+	CursorRate float64
+
+	// Outbound bytes per second per client (0 for no cap). Clients over it
+	// are paced, and low-priority messages such as cursors are dropped.
+	BandwidthLimit int64
+
+	// Number of outbound messages queued per client in each priority lane,
+	// and what happens to broadcasts when a lane is full (SlowClientClose by
+	// default)
+	SendBuffer int
+	SlowClient SlowClientPolicy
+
+	// Number of workers queueing broadcasts on the clients (0 for one per CPU)
+	FanoutWorkers int
+
+	// Per-IP connection limit, shared by every hub (nil disables). The hub
+	// releases a client's slot when it unregisters.
+	Connections *ConnLimit
+
+	// Time a client may send nothing before it is closed with
+	// CloseIdleTimeout, pongs not counting (0 disables)
+	IdleTimeout time.Duration
+
+	// Locates the IPs placements come from, for the history and the country
+	// stats (nil records no locations)
+	GeoIP *geoip.Locator
+
+	// Hashes the IPs anonymous changes are attributed to (nil stores them as is)
+	Anonymizer *IPAnonymizer
+
+	// Sustained chat messages per second per client (0 disables chat), and
+	// the number a client may send in a burst
+	ChatRate  float64
+	ChatBurst int
+
+	// Longest chat message in characters (0 for no limit)
+	ChatMaxLength int
+
+	// Screens chat messages (nil relays them as sent)
+	ChatFilter ChatFilter
+
+	// Number of latest chat messages sent to new clients (0 keeps none), the
+	// messages restored from a previous run and where new ones are saved
+	// (nil saves none)
+	ChatHistory  int
+	ChatRestored []BroadcastChat
+	ChatStore    ChatStore
+
+	// Start in read-only mode, with paint operations rejected for the reason
+	ReadOnly       bool
+	ReadOnlyReason string
+
+	// Serve the canvas as a read-only replica following the instances that
+	// publish their changes through the broker: paint operations, chat and
+	// cursors from clients are rejected, and the initial state and broadcasts
+	// are served as usual
+	Follower bool
+
+	// Regions only moderators may paint in, restored from a previous run
+	Locks []LockedRegion
+
+	// Economy mode: accounts claim regions only they may paint in, for
+	// ClaimDuration and up to MaxClaimCells cells of their own. Claims holds
+	// the claims restored from a previous run.
+	Economy       bool
+	ClaimDuration time.Duration
+	MaxClaimCells int
+	Claims        []Claim
+
+	// Teams clients may paint for (none disables teams), the cells they held
+	// in a previous run, and the interval their scores are broadcast at
+	Teams             []string
+	TeamPixels        []model.Pixel
+	TeamScoreInterval time.Duration
+
+	// Detection of griefers, and where the regions they changed are flagged
+	// (nil flags nothing)
+	Grief         GriefPolicy
+	GriefReporter GriefReporter
+
+	// CAPTCHA challenge of unauthenticated actors, and what checks the solved
+	// tokens (nil disables the challenge)
+	Captcha         CaptchaPolicy
+	CaptchaVerifier CaptchaVerifier
+
+	// Templates restored from a previous run
+	Templates []*Template
+
+	// Cell metadata restored from a previous run, and where the metadata
+	// removed when cells are painted over is persisted (nil persists nothing)
+	CellMeta      []CellMeta
+	CellMetaStore CellMetaStore
+
+	// Notified of the changes made through this instance (nil notifies nothing)
+	Events EventSink
+
+	// Number of placements already made on the canvas, and the interval of
+	// placements between milestone events (0 disables them)
+	Placements     int64
+	MilestoneEvery int64
+}
+
 // Hub maintains the set of active clients and broadcasts messages to them
 type Hub struct {
-	// Registered clients
-	clients map[*Client]bool
+	// Named rooms, including the canvas and grid rooms that every client is
+	// in and that clients receiving the updates of large viewports are in
+	rooms      map[string]*Room
+	canvasRoom *Room
+	gridRoom   *Room
+
+	// Region rooms by tile, created when a viewport spans them
+	regionRooms map[roomTile]*Room
+
+	// Registered non-WebSocket listeners (guarded by mu)
+	listeners map[*Listener]bool
 
 	// Inbound messages from the clients to broadcast
-	broadcast chan []byte
+	broadcast chan *outbound
+
+	// Workers queueing the broadcasts on their recipients, and the outcomes
+	// of the broadcasts that didn't fit
+	fanout  *fanout
+	slow    slowClients
+	metrics hubMetrics
 
 	// Register requests from the clients
 	register chan *Client
@@ -20,91 +247,573 @@ type Hub struct {
 	// Unregister requests from clients
 	unregister chan *Client
 
-	// Mutex for thread-safe access to clients map
+	// Liveness probes, received by the Run loop
+	probes chan struct{}
+
+	// Grid state shared by the hub's clients
+	grid *GridState
+
+	// Limits applied to clients
+	config HubConfig
+
+	// Per-IP placement cooldown shared by all clients
+	cooldown *Cooldown
+
+	// Cooldowns and quotas of the actors in each tier
+	tiers *Tiers
+
+	// Recently placed cells other actors can't overwrite yet
+	protection *Protection
+
+	// Cells held by each team
+	territory *Territory
+
+	// Recent placements of each actor, for undo
+	undo *UndoHistory
+
+	// Watches placements for griefing and throttles the actors caught
+	grief *GriefDetector
+
+	// Challenges unauthenticated actors every few placements
+	captcha *CaptchaGate
+
+	// Target images tracked against the grid, oldest first
+	templates   []*Template
+	templatesMu sync.Mutex
+
+	// Texts and links attached to cells
+	meta   map[cellKey]CellMeta
+	metaMu sync.Mutex
+
+	// Drawings painted for bots, running and recently finished, by ID
+	drawings      map[uint64]*drawing
+	lastDrawingID uint64
+	drawingsMu    sync.Mutex
+
+	// Placements on the canvas, by this instance and the others
+	activity   *Activity
+	placements atomic.Int64
+
+	// Latest chat messages, oldest first
+	chat   []BroadcastChat
+	chatMu sync.Mutex
+
+	// Clock and latest change of each changed cell with multi-region
+	// replication. versionsMu orders the changes of a cell with their versions.
+	clock      hybridClock
+	versions   map[cellKey]cellVersion
+	versionsMu sync.Mutex
+
+	// Set once Shutdown has been called
+	shuttingDown atomic.Bool
+
+	// Closed by Close to stop the Run loop, and by the Run loop once it has
+	// stopped
+	closing   chan struct{}
+	closeOnce sync.Once
+	done      chan struct{}
+
+	// Read-only mode, rejecting all paint operations while enabled
+	frozen atomic.Pointer[FrozenMessage]
+
+	// Held for reading while a change of the grid is applied and committed,
+	// and for writing while placements are paused for a reset
+	placing sync.RWMutex
+
+	// Regions only moderators may paint in. locksMu serializes their changes.
+	locks   atomic.Pointer[[]LockedRegion]
+	locksMu sync.Mutex
+
+	// Regions reserved for accounts in economy mode. claimsMu serializes
+	// their changes.
+	claims   atomic.Pointer[[]Claim]
+	claimsMu sync.Mutex
+
+	// Banned networks keyed by CIDR notation
+	bans   map[string]*net.IPNet
+	bansMu sync.RWMutex
+
+	// Shadow-banned networks keyed by CIDR notation, and shadow-banned users
+	shadowNetworks map[string]*net.IPNet
+	shadowUsers    map[string]bool
+	shadowMu       sync.RWMutex
+
+	// Mutex for thread-safe access to the rooms and their members
 	mu sync.RWMutex
 }
 
-// NewHub creates a new Hub instance
-func NewHub() *Hub {
-	return &Hub{
-		broadcast:  make(chan []byte, 256),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		clients:    make(map[*Client]bool),
+// NewHub creates a new Hub instance serving the given grid
+func NewHub(grid *GridState, config HubConfig) *Hub {
+	if config.Canvas == "" {
+		config.Canvas = model.DefaultCanvas
+	}
+	if config.Store == nil {
+		config.Store = nopStore{}
+	}
+	if config.SendBuffer < 1 {
+		config.SendBuffer = 256
+	}
+	if config.SlowClient == "" {
+		config.SlowClient = SlowClientClose
+	}
+	if config.FanoutWorkers < 1 {
+		config.FanoutWorkers = runtime.GOMAXPROCS(0)
+	}
+	if config.Events == nil {
+		config.Events = nopEvents{}
+	}
+	h := &Hub{
+		grid:        grid,
+		config:      config,
+		cooldown:    NewCooldown(config.PlacementCooldown),
+		tiers:       NewTiers(config.Tiers, config.Accounts),
+		protection:  NewProtection(config.OverwriteProtection),
+		territory:   NewTerritory(),
+		undo:        NewUndoHistory(),
+		grief:       NewGriefDetector(config.Grief),
+		captcha:     NewCaptchaGate(config.Captcha, config.CaptchaVerifier),
+		meta:        make(map[cellKey]CellMeta),
+		versions:    make(map[cellKey]cellVersion),
+		drawings:    make(map[uint64]*drawing),
+		activity:    NewActivity(),
+		broadcast:   make(chan *outbound, 256),
+		register:    make(chan *Client),
+		unregister:  make(chan *Client),
+		probes:      make(chan struct{}),
+		closing:     make(chan struct{}),
+		done:        make(chan struct{}),
+		canvasRoom:  newRoom(RoomCanvas, true),
+		gridRoom:    newRoom(RoomGrid, true),
+		regionRooms: make(map[roomTile]*Room),
+		listeners:   make(map[*Listener]bool),
+		bans:        make(map[string]*net.IPNet),
+
+		shadowNetworks: make(map[string]*net.IPNet),
+		shadowUsers:    make(map[string]bool),
+	}
+	h.fanout = newFanout(h, config.FanoutWorkers)
+	h.metrics = newHubMetrics(config.Canvas)
+	h.rooms = map[string]*Room{RoomCanvas: h.canvasRoom, RoomGrid: h.gridRoom}
+	h.frozen.Store(&FrozenMessage{Type: "frozen"})
+	if config.ReadOnly {
+		h.frozen.Store(&FrozenMessage{Type: "frozen", Enabled: true, Reason: config.ReadOnlyReason})
+	}
+	locks := slices.Clone(config.Locks)
+	h.locks.Store(&locks)
+	claims := slices.Clone(config.Claims)
+	h.claims.Store(&claims)
+	h.placements.Store(config.Placements)
+	h.territory.Apply(config.TeamPixels)
+	h.territory.takeChanged()
+	for _, t := range config.Templates {
+		h.addTemplate(t)
+	}
+	for _, meta := range config.CellMeta {
+		h.meta[cellKey{meta.X, meta.Y}] = meta
+	}
+	for _, msg := range config.ChatRestored {
+		h.keepChat(msg)
+	}
+	return h
+}
+
+// Run starts the hub's main loop. It returns once ctx is done or Close is
+// called, after disconnecting every client. A panic handling an event is
+// reported and the loop goes on with the next one.
+func (h *Hub) Run(ctx context.Context) {
+	defer close(h.done)
+	if h.config.Broker != nil {
+		go h.consumeBroker()
+	}
+	if h.config.Replication != nil {
+		go h.consumeReplication()
+	}
+	h.fanout.start()
+	if len(h.config.Teams) > 0 && h.config.TeamScoreInterval > 0 {
+		go h.broadcastTeamScores(h.config.TeamScoreInterval)
 	}
+
+	crash.Supervise("hub", func() { h.loop(ctx) }, "canvas", h.config.Canvas)
 }
 
-// Run starts the hub's main loop
-func (h *Hub) Run() {
+// loop handles the hub's events until ctx is done or Close is called. The
+// mutex is released by deferred calls, so a panic doesn't leave it held.
+func (h *Hub) loop(ctx context.Context) {
 	for {
 		select {
+		case <-ctx.Done():
+			h.stop()
+			return
+
+		case <-h.closing:
+			h.stop()
+			return
+
+		case <-h.probes:
+			// Answered by being received
+
 		case client := <-h.register:
-			h.mu.Lock()
-			// Check if client is already registered to prevent duplicate counting
-			alreadyRegistered := h.clients[client]
-			if !alreadyRegistered {
-				h.clients[client] = true
-			}
-			h.mu.Unlock()
-			if alreadyRegistered {
-				log.Printf("Client already registered, skipping. Total clients: %d", h.ClientCount())
+			if !h.addClient(client) {
+				client.logger.Warn("Client already registered, skipping", "clients", h.ClientCount())
 				continue
 			}
-			log.Printf("Client registered. Total clients: %d", h.ClientCount())
+			client.logger.Info("Client registered", "ip", client.ipAddress, "name", client.name, "clients", h.ClientCount())
 			h.BroadcastClientCount()
+			h.sendRoster(client)
+			h.broadcastPresence(JoinMessage{Type: "join", Presence: client.Presence()})
 
 		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-			}
-			h.mu.Unlock()
-			log.Printf("Client unregistered. Total clients: %d", h.ClientCount())
+			registered := h.removeClient(client)
+			stats := client.Stats()
+			client.logger.Info("Client unregistered", "duration", time.Since(client.connectedAt),
+				"messages_received", stats.MessagesReceived, "messages_sent", stats.MessagesSent,
+				"dropped", stats.Dropped+stats.DroppedFull, "clients", h.ClientCount())
 			h.BroadcastClientCount()
+			if registered {
+				h.broadcastPresence(LeaveMessage{Type: "leave", ID: client.id})
+			}
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					// Client's send buffer is full, schedule for removal
-					go func(c *Client) {
-						h.unregister <- c
-					}(client)
-				}
-			}
-			h.mu.RUnlock()
+			span := h.traceBroadcast(message)
+			batches := h.routeBroadcast(message)
+			h.fanout.dispatch(message, batches, span)
+			span.End()
+		}
+	}
+}
+
+// addClient adds a registering client to the canvas room and the rooms of its
+// viewport, reporting false if it was already registered
+func (h *Hub) addClient(client *Client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// Check if client is already registered to prevent duplicate counting
+	if h.canvasRoom.members[client] {
+		return false
+	}
+	h.join(client, h.canvasRoom)
+	h.placeViewport(client)
+	return true
+}
+
+// removeClient takes an unregistering client out of its rooms and closes its
+// lanes, reporting whether it was registered
+func (h *Hub) removeClient(client *Client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.canvasRoom.members[client] {
+		return false
+	}
+	h.leaveAll(client)
+	client.closeLanes()
+	if h.config.Connections != nil {
+		h.config.Connections.Release(client.ipAddress)
+	}
+	return true
+}
+
+// routeBroadcast sends a broadcast to the listeners watching it and returns
+// the batches of its recipients for the fan-out workers
+func (h *Hub) routeBroadcast(message *outbound) [][]recipient {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	batches := h.route(message)
+	for l := range h.listeners {
+		if message.ephemeral || (message.region != nil && !l.watches(*message.region)) {
+			continue
+		}
+		data := message.frame(l.encoding)
+		if data == nil {
+			continue
+		}
+		select {
+		case l.send <- data:
+		default:
+			// Listener fell behind, drop it
+			go h.Unlisten(l)
+		}
+	}
+	return batches
+}
+
+// stop disconnects every client and listener, then stops the fan-out workers
+// and the broker. It is called by the Run loop as it returns.
+func (h *Hub) stop() {
+	h.shuttingDown.Store(true)
+	h.closeListeners()
+
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	h.mu.Lock()
+	clients := len(h.canvasRoom.members)
+	for client := range h.canvasRoom.members {
+		h.leaveAll(client)
+		client.closeMsg = closeMsg
+		client.closeLanes()
+		if h.config.Connections != nil {
+			h.config.Connections.Release(client.ipAddress)
+		}
+	}
+	h.mu.Unlock()
+
+	h.fanout.stop()
+	if h.config.Broker != nil {
+		if err := h.config.Broker.Close(); err != nil {
+			slog.Error("Failed to close broker", "canvas", h.config.Canvas, "err", err)
+		}
+	}
+	if h.config.Replication != nil {
+		if err := h.config.Replication.Close(); err != nil {
+			slog.Error("Failed to close replication broker", "canvas", h.config.Canvas, "err", err)
 		}
 	}
+	slog.Info("Hub stopped", "canvas", h.config.Canvas, "clients", clients)
+}
+
+// Close stops the Run loop, disconnecting every client, and waits for it to
+// return. Run must have been started.
+func (h *Hub) Close() {
+	h.closeOnce.Do(func() { close(h.closing) })
+	<-h.done
+}
+
+// Ping checks that the Run loop is responsive, waiting until ctx expires
+func (h *Hub) Ping(ctx context.Context) error {
+	select {
+	case h.probes <- struct{}{}:
+	case <-h.done:
+		return fmt.Errorf("hub %s is stopped", h.config.Canvas)
+	case <-ctx.Done():
+		return fmt.Errorf("hub %s is not responding", h.config.Canvas)
+	}
+	if queued := len(h.broadcast); queued == cap(h.broadcast) {
+		return fmt.Errorf("hub %s broadcast queue is full", h.config.Canvas)
+	}
+	return nil
+}
+
+// QueueDepth returns the share in use of the fullest of the queues broadcasts
+// go through, which fill up when the hub can't keep up with them
+func (h *Hub) QueueDepth() float64 {
+	return max(float64(len(h.broadcast))/float64(cap(h.broadcast)), h.fanout.depth())
+}
+
+// Canvas returns the name of the canvas served by the hub
+func (h *Hub) Canvas() string {
+	return h.config.Canvas
+}
+
+// Grid returns the grid served by the hub
+func (h *Hub) Grid() *GridState {
+	return h.grid
+}
+
+// Activity returns the recent placement counts of the canvas
+func (h *Hub) Activity() *Activity {
+	return h.activity
+}
+
+// AnonymousActor returns the ID the changes of an anonymous client at ip are attributed to
+func (h *Hub) AnonymousActor(ip string) string {
+	return h.config.Anonymizer.Actor(ip)
 }
 
 // ClientCount returns the number of connected clients
 func (h *Hub) ClientCount() int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
-	return len(h.clients)
+	return len(h.canvasRoom.members)
 }
 
 // Broadcast sends a message to all connected clients
 func (h *Hub) Broadcast(message []byte) {
-	h.broadcast <- message
+	h.enqueue(&outbound{data: message})
 }
 
-// Register adds a new client to the hub
+// broadcastLow sends a low priority message to all connected clients
+func (h *Hub) broadcastLow(message []byte) {
+	h.enqueue(&outbound{data: message, priority: PriorityLow})
+}
+
+// enqueue queues a broadcast for the Run loop, dropping it once the hub has stopped
+func (h *Hub) enqueue(message *outbound) {
+	select {
+	case h.broadcast <- message:
+	case <-h.done:
+	}
+}
+
+// BroadcastRegion sends a message to the clients whose viewport intersects the
+// region and publishes it to the other instances through the broker
+func (h *Hub) BroadcastRegion(message []byte, region Region) {
+	h.broadcastRegion(context.Background(), message, region)
+}
+
+// broadcastRegion is BroadcastRegion for a change traced by ctx
+func (h *Hub) broadcastRegion(ctx context.Context, message []byte, region Region) {
+	h.enqueue(&outbound{data: message, region: &region, span: trace.SpanContextFromContext(ctx), queued: time.Now(), received: receipt(ctx)})
+
+	if h.config.Broker != nil {
+		_, span := tracing.Tracer().Start(ctx, "broker.publish", canvasAttr(h))
+		if err := h.config.Broker.Publish(message); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			slog.Error("Failed to publish update to broker", "err", err)
+		}
+		span.End()
+	}
+}
+
+// Register adds a new client to the hub. Clients registering once the hub
+// has stopped are disconnected and their connection slot released, as stop
+// does for the registered ones.
 func (h *Hub) Register(client *Client) {
-	h.register <- client
+	select {
+	case h.register <- client:
+	case <-h.done:
+		client.conn.Close()
+		if h.config.Connections != nil {
+			h.config.Connections.Release(client.ipAddress)
+		}
+	}
 }
 
-// Unregister removes a client from the hub
+// Unregister removes a client from the hub. Once the hub has stopped there is
+// nothing to remove: stop released every registered client and Register those
+// arriving later.
 func (h *Hub) Unregister(client *Client) {
-	h.unregister <- client
+	select {
+	case h.unregister <- client:
+	case <-h.done:
+	}
 }
 
 // BroadcastClientCount sends the current client count to all connected clients
 func (h *Hub) BroadcastClientCount() {
 	count := h.ClientCount()
-	message := []byte(fmt.Sprintf(`{"t":"c","count":%d}`, count))
+	message, _ := json.Marshal(ClientCountMessage{Type: "c", Count: count})
+	h.broadcastLow(message)
+}
+
+// initialState returns the messages streaming the grid to a new client: "init",
+// the active cells in initChunkSize x initChunkSize "init_chunk" regions (only
+// those containing active cells), then "init_done"
+func (h *Hub) initialState(withMeta bool) []any {
+	frozen, reason := h.ReadOnly()
+	msgs := []any{InitMessage{
+		Type:           "init",
+		Canvas:         h.Canvas(),
+		Width:          h.grid.Width(),
+		Height:         h.grid.Height(),
+		ChunkSize:      initChunkSize,
+		Palette:        model.Palette(),
+		PaletteVersion: model.PaletteVersion(),
+		AnyColor:       !model.RestrictedToPalette(),
+		Frozen:         frozen,
+		FrozenReason:   reason,
+		Follower:       h.config.Follower,
+	}}
+
+	// Group the active cells (converted to ActiveCell format for JSON) by region
+	regions := make(map[[2]int][]ActiveCell)
+	cells := h.grid.GetActiveCells()
+	var meta map[cellKey]CellMeta
+	if withMeta {
+		h.metaMu.Lock()
+		meta = maps.Clone(h.meta)
+		h.metaMu.Unlock()
+	}
+	for _, cell := range cells {
+		origin := [2]int{cell.X - cell.X%initChunkSize, cell.Y - cell.Y%initChunkSize}
+		active := ActiveCell{X: cell.X, Y: cell.Y, Color: cell.Color}
+		if m, ok := meta[cellKey{cell.X, cell.Y}]; ok {
+			active.Text, active.URL = m.Text, m.URL
+		}
+		regions[origin] = append(regions[origin], active)
+	}
+
+	// Send regions in column order, as a full scan would
+	origins := make([][2]int, 0, len(regions))
+	for origin := range regions {
+		origins = append(origins, origin)
+	}
+	sort.Slice(origins, func(i, j int) bool {
+		if origins[i][0] != origins[j][0] {
+			return origins[i][0] < origins[j][0]
+		}
+		return origins[i][1] < origins[j][1]
+	})
+
+	for _, origin := range origins {
+		msgs = append(msgs, InitChunkMessage{
+			Type:   "init_chunk",
+			X:      origin[0],
+			Y:      origin[1],
+			Active: regions[origin],
+		})
+	}
+
+	return append(msgs, InitDoneMessage{Type: "init_done", Total: len(cells)})
+}
+
+// ShuttingDown reports whether the hub is draining connections and refusing new clients
+func (h *Hub) ShuttingDown() bool {
+	return h.shuttingDown.Load()
+}
+
+// Shutdown ends the listeners' streams, sends a "server restarting" close frame
+// to every client and waits for them to disconnect. Connections still open
+// when ctx expires are closed.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.shuttingDown.Store(true)
+
+	h.closeListeners()
+
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(h.canvasRoom.members))
+	for client := range h.canvasRoom.members {
+		clients = append(clients, client)
+	}
+	h.mu.RUnlock()
+
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server restarting")
+	for _, client := range clients {
+		client.conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(writeWait))
+	}
+	slog.Info("Shutting down hub", "canvas", h.config.Canvas, "clients", len(clients))
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for h.ClientCount() > 0 {
+		select {
+		case <-ctx.Done():
+			// Force close whatever is left, the read pumps unregister them
+			for _, client := range clients {
+				client.conn.Close()
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// BroadcastPalette sends the active palette to all clients and publishes it
+// to the other instances through the broker
+func (h *Hub) BroadcastPalette() {
+	message, err := json.Marshal(PaletteMessage{
+		Type:     "palette",
+		Version:  model.PaletteVersion(),
+		Colors:   model.Palette(),
+		AnyColor: !model.RestrictedToPalette(),
+	})
+	if err != nil {
+		slog.Error("Failed to marshal palette", "err", err)
+		return
+	}
 	h.Broadcast(message)
+
+	if h.config.Broker != nil {
+		if err := h.config.Broker.Publish(message); err != nil {
+			slog.Error("Failed to publish palette to broker", "err", err)
+		}
+	}
 }
@@ -0,0 +1,52 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// testConn returns the server side of a WebSocket connection, closed with the test
+func testConn(t *testing.T) *websocket.Conn {
+	t.Helper()
+	conns := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(srv.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { peer.Close() })
+	conn := <-conns
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestRegisterAfterStopReleasesConnection(t *testing.T) {
+	limit := NewConnLimit(1, 0)
+	hub := NewHub(NewGridState(16, 16), HubConfig{Connections: limit})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	hub.Run(ctx)
+
+	const ip = "192.0.2.1"
+	if err := limit.Acquire(ip); err != nil {
+		t.Fatal(err)
+	}
+	hub.Register(NewClient(hub, testConn(t), ip, nil))
+	if err := limit.Acquire(ip); err != nil {
+		t.Errorf("slot still held after registering with a stopped hub: %v", err)
+	}
+}
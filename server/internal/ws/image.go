@@ -0,0 +1,46 @@
+package ws
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// noTarget marks the mostly transparent pixels of an image, which paint nothing
+const noTarget = model.Color(1 << 24)
+
+// quantizeImage returns the color each pixel of an image paints in row-major
+// order: its nearest paintable color, or noTarget where it is mostly transparent
+func quantizeImage(img image.Image) []model.Color {
+	bounds := img.Bounds()
+	colors := make([]model.Color, 0, bounds.Dx()*bounds.Dy())
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for px := bounds.Min.X; px < bounds.Max.X; px++ {
+			c := color.NRGBAModel.Convert(img.At(px, py)).(color.NRGBA)
+			if c.A < 0x80 {
+				colors = append(colors, noTarget)
+				continue
+			}
+			colors = append(colors, model.NearestColor(model.Color(c.R)<<16|model.Color(c.G)<<8|model.Color(c.B)))
+		}
+	}
+	return colors
+}
+
+// ImagePixels returns the cell states painting an image onto the grid with
+// its top-left corner at (x, y), in row-major order. Mostly transparent
+// pixels and those outside the grid are left out, the others become their
+// nearest paintable color, white ones clearing their cell.
+func (g *GridState) ImagePixels(img image.Image, x, y int) []model.Pixel {
+	width := img.Bounds().Dx()
+	var pixels []model.Pixel
+	for i, c := range quantizeImage(img) {
+		cx, cy := x+i%width, y+i/width
+		if c == noTarget || !g.InBounds(cx, cy) {
+			continue
+		}
+		pixels = append(pixels, model.Pixel{X: cx, Y: cy, Active: c != model.White, Color: c})
+	}
+	return pixels
+}
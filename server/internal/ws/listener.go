@@ -0,0 +1,69 @@
+package ws
+
+// Listener receives a hub's broadcasts outside of a WebSocket connection,
+// e.g. for a gRPC stream or an event stream
+type Listener struct {
+	// Encoded messages, closed when the listener falls behind, is removed with
+	// Unlisten or the hub shuts down
+	C <-chan []byte
+
+	send chan []byte
+
+	// Region the listener receives updates for (nil for the whole grid)
+	region *Region
+
+	encoding Encoding
+}
+
+// Listen registers a listener for the hub's broadcasts inside region (nil for
+// the whole grid), encoded with enc. Callers send InitialState first and must
+// call Unlisten when done.
+func (h *Hub) Listen(region *Region, enc Encoding) *Listener {
+	send := make(chan []byte, h.config.SendBuffer)
+	l := &Listener{C: send, send: send, region: region, encoding: enc}
+
+	h.mu.Lock()
+	h.listeners[l] = true
+	h.mu.Unlock()
+	return l
+}
+
+// Unlisten removes a listener and closes its channel
+func (h *Hub) Unlisten(l *Listener) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.listeners[l] {
+		delete(h.listeners, l)
+		close(l.send)
+	}
+}
+
+// closeListeners removes every listener, ending their streams
+func (h *Hub) closeListeners() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for l := range h.listeners {
+		delete(h.listeners, l)
+		close(l.send)
+	}
+}
+
+// InitialState returns the messages a new client receives, encoded with enc:
+// "init", the active cells in "init_chunk" regions, then "init_done"
+func (h *Hub) InitialState(enc Encoding) ([][]byte, error) {
+	msgs := h.initialState(false)
+	frames := make([][]byte, len(msgs))
+	for i, msg := range msgs {
+		data, err := encodeMessage(msg, enc)
+		if err != nil {
+			return nil, err
+		}
+		frames[i] = data
+	}
+	return frames, nil
+}
+
+// watches reports whether updates in the region should be forwarded to the listener
+func (l *Listener) watches(region Region) bool {
+	return l.region == nil || l.region.Intersects(region)
+}
@@ -0,0 +1,113 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// LockedRegion is a rectangle of the canvas only moderators may paint in,
+// e.g. a logo
+type LockedRegion struct {
+	ID uint `json:"id"`
+	Region
+	Label string `json:"label,omitempty"`
+}
+
+// LocksMessage carries the locked regions of the canvas, sent to new clients
+// and to every client when they change
+type LocksMessage struct {
+	Type    string         `json:"t"`
+	Regions []LockedRegion `json:"regions"`
+}
+
+// Locks returns the locked regions of the canvas, oldest first
+func (h *Hub) Locks() []LockedRegion {
+	return slices.Clone(*h.locks.Load())
+}
+
+// AddLock locks a region, numbering it after the others if it has no ID yet
+// (when it wasn't persisted), and returns it
+func (h *Hub) AddLock(lock LockedRegion) LockedRegion {
+	h.locksMu.Lock()
+	defer h.locksMu.Unlock()
+
+	locks := h.Locks()
+	if lock.ID == 0 {
+		for _, other := range locks {
+			lock.ID = max(lock.ID, other.ID)
+		}
+		lock.ID++
+	}
+	h.setLocks(append(locks, lock))
+	return lock
+}
+
+// RemoveLock unlocks a region by ID, reporting false if there is no such region
+func (h *Hub) RemoveLock(id uint) bool {
+	h.locksMu.Lock()
+	defer h.locksMu.Unlock()
+
+	locks := h.Locks()
+	i := slices.IndexFunc(locks, func(lock LockedRegion) bool { return lock.ID == id })
+	if i < 0 {
+		return false
+	}
+	h.setLocks(slices.Delete(locks, i, i+1))
+	return true
+}
+
+// setLocks replaces the locked regions, tells the clients and publishes them
+// to the other instances, h.locksMu must be held
+func (h *Hub) setLocks(locks []LockedRegion) {
+	message, err := h.applyLocks(locks)
+	if err != nil {
+		return
+	}
+	if h.config.Broker != nil {
+		if err := h.config.Broker.Publish(message); err != nil {
+			slog.Error("Failed to publish locked regions to broker", "err", err)
+		}
+	}
+}
+
+// applyLocks replaces the locked regions and tells the local clients,
+// returning the encoded LocksMessage
+func (h *Hub) applyLocks(locks []LockedRegion) ([]byte, error) {
+	if locks == nil {
+		locks = []LockedRegion{}
+	}
+	message, err := json.Marshal(LocksMessage{Type: "locks", Regions: locks})
+	if err != nil {
+		slog.Error("Failed to marshal locked regions", "err", err)
+		return nil, err
+	}
+	h.locks.Store(&locks)
+	slog.Info("Locked regions changed", "canvas", h.config.Canvas, "regions", len(locks))
+	h.enqueue(&outbound{data: message})
+	return message, nil
+}
+
+// lockedError returns the error rejecting paint operations on the first of
+// the pixels in a locked region, or nil if none is
+func (h *Hub) lockedError(pixels []model.Pixel) *PlacementError {
+	locks := *h.locks.Load()
+	if len(locks) == 0 {
+		return nil
+	}
+	for _, p := range pixels {
+		for _, lock := range locks {
+			if lock.Contains(p.X, p.Y) {
+				message := fmt.Sprintf("cell (%d, %d) is in a locked region", p.X, p.Y)
+				if lock.Label != "" {
+					message = fmt.Sprintf("cell (%d, %d) is in the locked region %q", p.X, p.Y, lock.Label)
+				}
+				return &PlacementError{Code: "region_locked", Message: message, X: p.X, Y: p.Y}
+			}
+		}
+	}
+	return nil
+}
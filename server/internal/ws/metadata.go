@@ -0,0 +1,188 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+const (
+	// Longest cell text in characters, and longest link in bytes
+	maxCellMetaText = 140
+	maxCellMetaURL  = 512
+)
+
+// CellMeta is a short text and link a painter attached to a cell, shown by
+// clients as a tooltip. It stays attached while nobody else paints the cell
+// and the cell is active.
+type CellMeta struct {
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+	Text string `json:"text,omitempty"`
+	URL  string `json:"url,omitempty"`
+
+	// Painter that attached the metadata
+	Actor string `json:"-"`
+}
+
+// CellMetaMessage tells clients the metadata of a cell changed. Metadata
+// without text or link was removed.
+type CellMetaMessage struct {
+	Type string `json:"t"`
+	CellMeta
+}
+
+// cellMetaBrokerMessage carries a CellMetaMessage to the other instances,
+// with the painter that attached the metadata
+type cellMetaBrokerMessage struct {
+	CellMetaMessage
+	Actor string `json:"actor,omitempty"`
+}
+
+// CellMetaStore persists the metadata removals made by the hub when cells are
+// painted over
+type CellMetaStore interface {
+	DeleteCellMeta(canvas string, cells [][2]int) error
+}
+
+// CellMeta returns the metadata of a cell, reporting false if it has none
+func (h *Hub) CellMeta(x, y int) (CellMeta, bool) {
+	h.metaMu.Lock()
+	defer h.metaMu.Unlock()
+	meta, ok := h.meta[cellKey{x, y}]
+	return meta, ok
+}
+
+// SetCellMeta attaches metadata to a cell, replacing any it had, and tells the
+// clients and the other instances. The text goes through the chat filter and
+// the link must be an http(s) URL. It returns the metadata attached; rejected
+// metadata returns a *PlacementError. Checking that the actor painted the
+// cell and persisting the metadata are up to the caller.
+func (h *Hub) SetCellMeta(meta CellMeta) (CellMeta, error) {
+	if !h.grid.InBounds(meta.X, meta.Y) {
+		return CellMeta{}, &PlacementError{Code: "out_of_bounds", Message: fmt.Sprintf("cell (%d, %d) is outside the grid", meta.X, meta.Y)}
+	}
+	meta.Text = strings.TrimSpace(meta.Text)
+	if meta.Text == "" && meta.URL == "" {
+		return CellMeta{}, &PlacementError{Code: "empty_meta", Message: "cell metadata needs a text or a link"}
+	}
+	if utf8.RuneCountInString(meta.Text) > maxCellMetaText {
+		return CellMeta{}, &PlacementError{Code: "text_too_long", Message: fmt.Sprintf("cell text is limited to %d characters", maxCellMetaText)}
+	}
+	if meta.URL != "" {
+		link, err := url.Parse(meta.URL)
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" || len(meta.URL) > maxCellMetaURL {
+			return CellMeta{}, &PlacementError{Code: "invalid_url", Message: fmt.Sprintf("link must be an http or https URL of at most %d bytes", maxCellMetaURL)}
+		}
+	}
+	if filter := h.config.ChatFilter; filter != nil && meta.Text != "" {
+		var ok bool
+		if meta.Text, ok = filter.Filter(meta.Text); !ok {
+			return CellMeta{}, &PlacementError{Code: "text_rejected", Message: "cell text was rejected"}
+		}
+	}
+
+	h.metaMu.Lock()
+	h.meta[cellKey{meta.X, meta.Y}] = meta
+	h.metaMu.Unlock()
+	h.broadcastCellMeta(meta)
+	return meta, nil
+}
+
+// RemoveCellMeta removes the metadata of a cell and tells the clients and the
+// other instances, reporting false if it had none
+func (h *Hub) RemoveCellMeta(x, y int) bool {
+	h.metaMu.Lock()
+	_, ok := h.meta[cellKey{x, y}]
+	delete(h.meta, cellKey{x, y})
+	h.metaMu.Unlock()
+	if ok {
+		h.broadcastCellMeta(CellMeta{X: x, Y: y})
+	}
+	return ok
+}
+
+// dropCellMeta removes the metadata of cells that were cleared or painted by
+// someone other than the painter that attached it
+func (h *Hub) dropCellMeta(changed []model.Pixel, actor string) {
+	var dropped [][2]int
+	h.metaMu.Lock()
+	if len(h.meta) > 0 {
+		for _, p := range changed {
+			key := cellKey{p.X, p.Y}
+			if meta, ok := h.meta[key]; ok && (meta.Actor != actor || !p.Active) {
+				delete(h.meta, key)
+				dropped = append(dropped, [2]int{p.X, p.Y})
+			}
+		}
+	}
+	h.metaMu.Unlock()
+	if len(dropped) == 0 {
+		return
+	}
+
+	if h.config.CellMetaStore != nil {
+		if err := h.config.CellMetaStore.DeleteCellMeta(h.config.Canvas, dropped); err != nil {
+			slog.Error("Failed to delete cell metadata", "canvas", h.config.Canvas, "cells", len(dropped), "err", err)
+		}
+	}
+	for _, cell := range dropped {
+		h.broadcastCellMeta(CellMeta{X: cell[0], Y: cell[1]})
+	}
+}
+
+// broadcastCellMeta sends a cell's metadata to the clients watching the cell
+// and to the other instances
+func (h *Hub) broadcastCellMeta(meta CellMeta) {
+	message, err := json.Marshal(CellMetaMessage{Type: "meta", CellMeta: meta})
+	if err != nil {
+		slog.Error("Failed to marshal cell metadata", "err", err)
+		return
+	}
+	h.enqueue(&outbound{data: message, region: regionPtr(CellRegion(meta.X, meta.Y))})
+
+	if h.config.Broker != nil {
+		// The other instances also need the painter, which clients don't get
+		message, _ := json.Marshal(cellMetaBrokerMessage{CellMetaMessage: CellMetaMessage{Type: "meta", CellMeta: meta}, Actor: meta.Actor})
+		if err := h.config.Broker.Publish(message); err != nil {
+			slog.Error("Failed to publish cell metadata to broker", "err", err)
+		}
+	}
+}
+
+// applyRemoteCellMeta records cell metadata changed on another instance and
+// tells the local clients
+func (h *Hub) applyRemoteCellMeta(message []byte) error {
+	var msg cellMetaBrokerMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return err
+	}
+	meta := msg.CellMeta
+	meta.Actor = msg.Actor
+	h.metaMu.Lock()
+	if meta.Text == "" && meta.URL == "" {
+		delete(h.meta, cellKey{meta.X, meta.Y})
+	} else {
+		h.meta[cellKey{meta.X, meta.Y}] = meta
+	}
+	h.metaMu.Unlock()
+
+	data, err := json.Marshal(msg.CellMetaMessage)
+	if err != nil {
+		return err
+	}
+	h.enqueue(&outbound{data: data, region: regionPtr(CellRegion(meta.X, meta.Y))})
+	return nil
+}
+
+// resetCellMeta drops the metadata of every cell, once the canvas was cleared
+func (h *Hub) resetCellMeta() {
+	h.metaMu.Lock()
+	defer h.metaMu.Unlock()
+	clear(h.meta)
+}
@@ -0,0 +1,213 @@
+package ws
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/million_grids/server/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// hubMetrics holds the Prometheus metrics of a hub
+type hubMetrics struct {
+	latency    prometheus.Observer
+	recipients prometheus.Observer
+
+	// Broadcasts dropped for clients over their bandwidth cap, and for
+	// clients with a full lane
+	droppedBandwidth prometheus.Counter
+	droppedFull      prometheus.Counter
+
+	// Messages received by type, and invalid messages and rejected
+	// requests of the WebSocket clients by code
+	messages map[string]prometheus.Counter
+	invalid  *prometheus.CounterVec
+	rejected *prometheus.CounterVec
+}
+
+// Types of the messages a client can send
+var messageTypes = []string{
+	OpToggle, OpSet, OpClear, OpPaint,
+	MsgSubscribe, MsgUnsubscribe, MsgCursor, MsgChat, MsgJoinChannel, MsgLeaveChannel,
+	MsgTeam, MsgDraw, MsgUndo, MsgCaptcha,
+}
+
+// Codes of the errors replied to messages that couldn't be parsed, as
+// opposed to requests that were refused
+var invalidMessageCodes = map[string]bool{
+	"invalid_json":    true,
+	"invalid_field":   true,
+	"unknown_field":   true,
+	"missing_type":    true,
+	"unknown_type":    true,
+	"invalid_message": true,
+}
+
+func newHubMetrics(canvas string) hubMetrics {
+	m := hubMetrics{
+		latency:          metrics.BroadcastLatency.WithLabelValues(canvas),
+		recipients:       metrics.BroadcastRecipients.WithLabelValues(canvas),
+		droppedBandwidth: metrics.BroadcastDropped.WithLabelValues(canvas, "bandwidth"),
+		droppedFull:      metrics.BroadcastDropped.WithLabelValues(canvas, "full"),
+		messages:         make(map[string]prometheus.Counter, len(messageTypes)),
+		invalid:          metrics.InvalidMessages.MustCurryWith(prometheus.Labels{"canvas": canvas}),
+		rejected:         metrics.Rejections.MustCurryWith(prometheus.Labels{"canvas": canvas, "protocol": "ws"}),
+	}
+	for _, t := range messageTypes {
+		m.messages[t] = metrics.MessagesReceived.WithLabelValues(canvas, t)
+	}
+	return m
+}
+
+// countMessage counts a message of a known type received from a client
+func (h *Hub) countMessage(messageType string) {
+	if c, ok := h.metrics.messages[messageType]; ok {
+		c.Inc()
+	}
+}
+
+// countError counts an error replied to a client, as an invalid message or a
+// rejected request depending on its code
+func (h *Hub) countError(code string) {
+	if invalidMessageCodes[code] {
+		h.metrics.invalid.WithLabelValues(code).Inc()
+	} else {
+		h.metrics.rejected.WithLabelValues(code).Inc()
+	}
+}
+
+// BroadcastStats summarizes the latency and fan-out of a hub's cell updates
+// since the server started, and the send queues of its clients
+type BroadcastStats struct {
+	// Seconds from the receipt of a change to its update being queued on
+	// the last recipient, and recipients per update
+	Latency    metrics.Summary `json:"latency"`
+	Recipients metrics.Summary `json:"recipients"`
+
+	// Broadcasts dropped for clients over their bandwidth cap, and for
+	// clients whose send lane was full
+	DroppedBandwidth int64 `json:"dropped_bandwidth"`
+	DroppedFull      int64 `json:"dropped_full"`
+
+	Queues QueueDepthStats `json:"queues"`
+}
+
+// QueueDepthStats describes the messages currently waiting in the send lanes
+// of a hub's clients
+type QueueDepthStats struct {
+	Clients int `json:"clients"`
+
+	// Messages a client's lanes hold overall
+	Capacity int `json:"capacity"`
+
+	// Messages waiting for the median client, the 99th percentile and the
+	// most backed up one
+	P50 int `json:"p50"`
+	P99 int `json:"p99"`
+	Max int `json:"max"`
+}
+
+// BroadcastStats returns the broadcast stats of the hub
+func (h *Hub) BroadcastStats() BroadcastStats {
+	depths := h.queueDepths()
+	stats := BroadcastStats{
+		Latency:          metrics.Summarize(h.metrics.latency),
+		Recipients:       metrics.Summarize(h.metrics.recipients),
+		DroppedBandwidth: int64(metrics.CounterValue(h.metrics.droppedBandwidth)),
+		DroppedFull:      int64(metrics.CounterValue(h.metrics.droppedFull)),
+		Queues:           QueueDepthStats{Clients: len(depths), Capacity: h.laneCapacity()},
+	}
+	if n := len(depths); n > 0 {
+		stats.Queues.P50 = depths[n/2]
+		stats.Queues.P99 = depths[min(n-1, n*99/100)]
+		stats.Queues.Max = depths[n-1]
+	}
+	return stats
+}
+
+// queueDepths returns the messages waiting for each client, in increasing order
+func (h *Hub) queueDepths() []int {
+	h.mu.RLock()
+	depths := make([]int, 0, len(h.canvasRoom.members))
+	for c := range h.canvasRoom.members {
+		depths = append(depths, c.queued())
+	}
+	h.mu.RUnlock()
+	slices.Sort(depths)
+	return depths
+}
+
+// laneCapacity returns the messages a client's lanes hold overall
+func (h *Hub) laneCapacity() int {
+	return int(numPriorities) * h.config.SendBuffer
+}
+
+// queueCollector exports the send queue depths of the clients as a histogram
+// per canvas, taken when the metrics are scraped rather than recorded on
+// every broadcast. Its rates are meaningless, its quantiles are those of the
+// clients connected at the time.
+type queueCollector struct {
+	canvases *Canvases
+	desc     *prometheus.Desc
+}
+
+// QueueCollector returns the collector of the send queue depths of the
+// clients of the canvases
+func QueueCollector(canvases *Canvases) prometheus.Collector {
+	return &queueCollector{
+		canvases: canvases,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(metrics.Namespace, "client", "queue_depth"),
+			"Messages waiting in the send lanes of the connected clients.",
+			[]string{"canvas"}, nil),
+	}
+}
+
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, h := range c.canvases.Hubs() {
+		depths := h.queueDepths()
+		bounds := []float64{0}
+		for bound := 1; bound < h.laneCapacity(); bound *= 2 {
+			bounds = append(bounds, float64(bound))
+		}
+		bounds = append(bounds, float64(h.laneCapacity()))
+
+		buckets := make(map[float64]uint64, len(bounds))
+		var sum float64
+		i := 0
+		for _, bound := range bounds {
+			for i < len(depths) && float64(depths[i]) <= bound {
+				sum += float64(depths[i])
+				i++
+			}
+			buckets[bound] = uint64(i)
+		}
+		ch <- prometheus.MustNewConstHistogram(c.desc, uint64(len(depths)), sum, buckets, h.Canvas())
+	}
+}
+
+// receiptKey is the context key of the time a change was received
+type receiptKey struct{}
+
+// withReceipt records in ctx when the change it carries was received, unless
+// an earlier receipt is already recorded
+func withReceipt(ctx context.Context, received time.Time) context.Context {
+	if _, ok := ctx.Value(receiptKey{}).(time.Time); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, receiptKey{}, received)
+}
+
+// receipt returns when the change carried by ctx was received, or now for
+// changes made without a receipt recorded
+func receipt(ctx context.Context) time.Time {
+	if received, ok := ctx.Value(receiptKey{}).(time.Time); ok {
+		return received
+	}
+	return time.Now()
+}
@@ -0,0 +1,185 @@
+package ws
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// FrozenMessage is sent to all clients when the canvas is frozen or thawed.
+// While frozen, paint operations are rejected with "canvas_frozen" errors.
+type FrozenMessage struct {
+	Type    string `json:"t"`
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason,omitempty"` // Shown to clients, e.g. "the event is over"
+}
+
+// SetReadOnly freezes or thaws the canvas. While frozen all paint operations
+// are rejected, and clients can still connect and receive the canvas. The
+// change is sent to the clients and published to the other instances.
+func (h *Hub) SetReadOnly(enabled bool, reason string) {
+	message, err := h.applyReadOnly(enabled, reason)
+	if err != nil {
+		return
+	}
+	if h.config.Broker != nil {
+		if err := h.config.Broker.Publish(message); err != nil {
+			slog.Error("Failed to publish read-only mode to broker", "err", err)
+		}
+	}
+}
+
+// applyReadOnly freezes or thaws the canvas and tells the local clients,
+// returning the encoded FrozenMessage
+func (h *Hub) applyReadOnly(enabled bool, reason string) ([]byte, error) {
+	if !enabled {
+		reason = ""
+	}
+	msg := FrozenMessage{Type: "frozen", Enabled: enabled, Reason: reason}
+	message, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("Failed to marshal read-only mode", "err", err)
+		return nil, err
+	}
+	h.frozen.Store(&msg)
+	slog.Info("Read-only mode changed", "canvas", h.config.Canvas, "enabled", enabled, "reason", reason)
+	h.enqueue(&outbound{data: message})
+	return message, nil
+}
+
+// ReadOnly reports whether the canvas is frozen, and why
+func (h *Hub) ReadOnly() (bool, string) {
+	msg := h.frozen.Load()
+	return msg.Enabled, msg.Reason
+}
+
+// frozenError returns the error rejecting paint operations while the canvas
+// is frozen or served by a follower, or nil if they are allowed
+func (h *Hub) frozenError() *PlacementError {
+	if h.config.Follower {
+		return followerError()
+	}
+	frozen, reason := h.ReadOnly()
+	if !frozen {
+		return nil
+	}
+	message := "the canvas is frozen"
+	if reason != "" {
+		message += ": " + reason
+	}
+	return &PlacementError{Code: "canvas_frozen", Message: message}
+}
+
+// ParseBanTarget parses an IP address or CIDR range into a network. Single
+// IPs become a /32 (or /128 for IPv6) network.
+func ParseBanTarget(target string) (*net.IPNet, error) {
+	target = strings.TrimSpace(target)
+	if strings.Contains(target, "/") {
+		_, network, err := net.ParseCIDR(target)
+		return network, err
+	}
+
+	ip := net.ParseIP(target)
+	if ip == nil {
+		return nil, &net.ParseError{Type: "IP address", Text: target}
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// Ban adds a network to the ban list and disconnects its clients
+func (h *Hub) Ban(network *net.IPNet) {
+	h.bansMu.Lock()
+	h.bans[network.String()] = network
+	h.bansMu.Unlock()
+
+	n := h.disconnectMatching(network.Contains, "banned")
+	slog.Info("Banned network", "canvas", h.config.Canvas, "target", network.String(), "disconnected", n)
+}
+
+// Unban removes a network from the ban list
+func (h *Hub) Unban(network *net.IPNet) {
+	h.bansMu.Lock()
+	delete(h.bans, network.String())
+	h.bansMu.Unlock()
+	slog.Info("Unbanned network", "canvas", h.config.Canvas, "target", network.String())
+}
+
+// IsBanned reports whether an IP falls within any banned network
+func (h *Hub) IsBanned(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	h.bansMu.RLock()
+	defer h.bansMu.RUnlock()
+	for _, network := range h.bans {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bans returns the banned networks in CIDR notation, sorted
+func (h *Hub) Bans() []string {
+	h.bansMu.RLock()
+	defer h.bansMu.RUnlock()
+
+	targets := make([]string, 0, len(h.bans))
+	for target := range h.bans {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// DisconnectIP closes every client connected from the IP with a policy violation reason
+func (h *Hub) DisconnectIP(ip, reason string) int {
+	return h.disconnectMatching(func(clientIP net.IP) bool {
+		return clientIP.Equal(net.ParseIP(ip))
+	}, reason)
+}
+
+// DisconnectUser closes every client authenticated as the user with a policy violation reason
+func (h *Hub) DisconnectUser(userID, reason string) int {
+	return h.disconnectClients(func(client *Client) bool {
+		return client.identity != nil && client.identity.UserID == userID
+	}, reason)
+}
+
+// disconnectMatching closes every client whose IP matches with a policy violation reason
+func (h *Hub) disconnectMatching(match func(net.IP) bool, reason string) int {
+	return h.disconnectClients(func(client *Client) bool {
+		ip := net.ParseIP(client.ipAddress)
+		return ip != nil && match(ip)
+	}, reason)
+}
+
+// disconnectClients closes every client that matches with a policy violation reason
+func (h *Hub) disconnectClients(match func(*Client) bool, reason string) int {
+	h.mu.RLock()
+	var targets []*Client
+	for client := range h.canvasRoom.members {
+		if match(client) {
+			targets = append(targets, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	closeMsg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason)
+	for _, client := range targets {
+		client.logger.Info("Disconnecting client", "reason", reason)
+		client.conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(writeWait))
+		client.conn.Close()
+	}
+	return len(targets)
+}
@@ -0,0 +1,169 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+	"github.com/million_grids/server/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Maximum number of cells per batched update message
+const maxBroadcastBatch = 1000
+
+// SetCells applies cell states on behalf of actor, then broadcasts the cells
+// that actually changed, which it returns. It applies nothing and returns an
+// error if the changes can't be persisted.
+func (h *Hub) SetCells(ctx context.Context, pixels []model.Pixel, actor string) ([]model.Pixel, error) {
+	ctx = withReceipt(ctx, time.Now())
+	ctx, span := tracing.Tracer().Start(ctx, "hub.set_cells", canvasAttr(h))
+	defer span.End()
+
+	h.placing.RLock()
+	defer h.placing.RUnlock()
+
+	changed, err := h.mutate(ctx, func() ([]model.Pixel, error) {
+		changed, _, err := h.grid.SwapCells(pixels, h.journal(ctx, actor))
+		return changed, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	h.commitChanges(ctx, changed, actor)
+	return changed, nil
+}
+
+// ClearRegion deactivates every active cell in the region on behalf of actor
+// and returns the number of cells cleared. Clearing the whole grid is a
+// canvas reset.
+func (h *Hub) ClearRegion(ctx context.Context, region Region, actor string) (int, error) {
+	region = region.Clamp(h.grid.Width(), h.grid.Height())
+	active := h.grid.GetActiveCellsInRegion(region.X1, region.Y1, region.X2, region.Y2)
+	for i := range active {
+		active[i].Active = false
+		active[i].Color = model.White
+	}
+	cleared, err := h.SetCells(ctx, active, actor)
+	if err != nil {
+		return 0, err
+	}
+	if region == h.grid.Bounds() {
+		h.config.Events.CanvasReset(h.config.Canvas)
+	}
+	return len(cleared), nil
+}
+
+// mutate applies cell changes to the grid in a span of the trace of ctx,
+// returning the cells that changed
+func (h *Hub) mutate(ctx context.Context, apply func() ([]model.Pixel, error)) ([]model.Pixel, error) {
+	_, span := tracing.Tracer().Start(ctx, "grid.mutate", canvasAttr(h))
+	defer span.End()
+
+	changed, err := apply()
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("cells.changed", len(changed)))
+	return changed, nil
+}
+
+// journal returns the Journal attributing cell changes to actor and
+// persisting them, before anyone sees them
+func (h *Hub) journal(ctx context.Context, actor string) Journal {
+	return func(changed []model.Pixel) error {
+		now := time.Now()
+		for i := range changed {
+			changed[i].Canvas = h.config.Canvas
+			changed[i].CreatedBy = actor
+			changed[i].ModifyAt = &now
+			changed[i].ModifyBy = actor
+		}
+		return h.config.Store.SavePixelsAsync(ctx, changed)
+	}
+}
+
+// commitChanges broadcasts cell changes already persisted through the
+// journal of actor, in a span of the trace of ctx
+func (h *Hub) commitChanges(ctx context.Context, changed []model.Pixel, actor string) {
+	if len(changed) == 0 {
+		return
+	}
+	ctx, span := tracing.Tracer().Start(ctx, "hub.commit", canvasAttr(h), trace.WithAttributes(attribute.Int("cells.changed", len(changed))))
+	defer span.End()
+
+	// Changes committed together come from one client
+	h.activity.Record(len(changed), changed[0].Country, time.Now())
+
+	h.territory.Apply(changed)
+	h.updateTemplates(changed)
+	h.dropCellMeta(changed, actor)
+
+	version := h.stampChanges(changed)
+	h.broadcastChanges(ctx, changed, version)
+	h.replicate(changed, actor, version)
+	h.config.Events.PixelsChanged(h.config.Canvas, changed)
+	h.countPlacements(len(changed), true)
+
+	if len(changed) == 1 {
+		p := changed[0]
+		slog.Debug("Cell changed", "canvas", h.config.Canvas, "x", p.X, "y", p.Y, "active", p.Active, "color", p.Color, "actor", actor)
+	} else {
+		slog.Debug("Batch applied", "canvas", h.config.Canvas, "changed", len(changed), "actor", actor)
+	}
+}
+
+// broadcastChanges sends cell changes to the clients watching them, as a single
+// cell update for one cell and as batched updates otherwise. Changes committed
+// together come from one actor, so they share a team, and with replication a
+// version, which the other instances of the region record.
+func (h *Hub) broadcastChanges(ctx context.Context, changed []model.Pixel, version cellVersion) {
+	if len(changed) == 1 {
+		p := changed[0]
+		broadcastMsg, _ := json.Marshal(BroadcastCellUpdate{
+			Type:   "u",
+			X:      p.X,
+			Y:      p.Y,
+			Active: activeInt(p.Active),
+			Color:  p.Color,
+			Team:   p.Team,
+			Clock:  version.Clock,
+			Region: version.Region,
+		})
+		h.broadcastRegion(ctx, broadcastMsg, CellRegion(p.X, p.Y))
+		return
+	}
+
+	for start := 0; start < len(changed); start += maxBroadcastBatch {
+		end := min(start+maxBroadcastBatch, len(changed))
+
+		var bounds Region
+		batch := make([]BatchCell, 0, end-start)
+		for _, p := range changed[start:end] {
+			bounds = bounds.Extend(p.X, p.Y)
+			batch = append(batch, NewBatchCell(p))
+		}
+
+		broadcastMsg, _ := json.Marshal(BroadcastBatchUpdate{
+			Type:   "b",
+			Cells:  batch,
+			Team:   changed[start].Team,
+			Clock:  version.Clock,
+			Region: version.Region,
+		})
+		h.broadcastRegion(ctx, broadcastMsg, bounds)
+	}
+}
+
+// activeInt converts an active flag to 0 or 1 for JSON
+func activeInt(active bool) int {
+	if active {
+		return 1
+	}
+	return 0
+}
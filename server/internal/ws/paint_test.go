@@ -0,0 +1,63 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// recordingStore records the changes saved through it, failing while failing is set
+type recordingStore struct {
+	saved   []model.Pixel
+	failing bool
+}
+
+func (s *recordingStore) SavePixelsAsync(_ context.Context, pixels []model.Pixel) error {
+	if s.failing {
+		return errors.New("disk full")
+	}
+	s.saved = append(s.saved, pixels...)
+	return nil
+}
+
+func TestSetCellsPersistsAttributedChanges(t *testing.T) {
+	store := &recordingStore{}
+	hub := NewHub(NewGridState(16, 16), HubConfig{Canvas: "test", Store: store})
+
+	changed, err := hub.SetCells(context.Background(), []model.Pixel{
+		{X: 1, Y: 1, Active: true, Color: 0xFF0000},
+		{X: 2, Y: 2, Active: false, Color: model.White}, // Already inactive
+	}, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 1 || len(store.saved) != 1 {
+		t.Fatalf("changed %d and saved %d cells, want 1", len(changed), len(store.saved))
+	}
+	if saved := store.saved[0]; saved.Canvas != "test" || saved.ModifyBy != "alice" || saved.ModifyAt == nil {
+		t.Errorf("saved %+v, want it attributed to alice on canvas test", saved)
+	}
+}
+
+func TestChangesNotAppliedWhenJournalFails(t *testing.T) {
+	store := &recordingStore{failing: true}
+	grid := NewGridState(16, 16)
+	hub := NewHub(grid, HubConfig{Store: store})
+	ctx := context.Background()
+
+	if _, err := hub.SetCells(ctx, []model.Pixel{{X: 1, Y: 1, Active: true, Color: 0xFF0000}}, "alice"); err == nil {
+		t.Error("SetCells succeeded with a failing store")
+	}
+	for _, op := range []string{OpToggle, OpSet} {
+		_, err := hub.Place(ctx, Placement{Op: op, X: 3, Y: 3, Color: "#FF0000", IP: "192.0.2.1", Actor: "alice"})
+		var rejected *PlacementError
+		if !errors.As(err, &rejected) || rejected.Code != "write_failed" {
+			t.Errorf("%s: got error %v, want write_failed", op, err)
+		}
+	}
+	if active := grid.ActiveCount(); active != 0 {
+		t.Errorf("%d cells active after failed writes, want 0", active)
+	}
+}
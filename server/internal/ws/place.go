@@ -0,0 +1,185 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+	"github.com/million_grids/server/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Placement is a single-cell operation requested by a client of any protocol
+type Placement struct {
+	// One of OpToggle, OpSet, OpClear
+	Op string
+
+	X, Y int
+
+	// "#RRGGBB" color (empty selects the default color, ignored by OpClear)
+	Color string
+
+	// Address the cooldown and bans apply to
+	IP string
+
+	// ID the change is attributed to
+	Actor string
+
+	// Team the change counts for (empty for none)
+	Team string
+
+	// Whether the actor may paint in locked regions
+	Moderator bool
+
+	// Whether the actor is unauthenticated, so the CAPTCHA challenge applies
+	Anonymous bool
+
+	// Role of the authenticated actor, picking its tier with its account age
+	Role string
+}
+
+// PlacementError is a placement rejected by validation, moderation or the cooldown
+type PlacementError struct {
+	// Machine-readable reason, as sent in ErrorMessage codes
+	Code    string
+	Message string
+
+	// Time left before the next placement is allowed (code "cooldown"), before
+	// the quota resets (code "quota_exceeded"), or before the cell may be
+	// overwritten (code "cell_protected")
+	RetryAfter time.Duration
+
+	// Cell the placement was rejected for (codes "cell_protected" and "region_locked")
+	X, Y int
+}
+
+func (e *PlacementError) Error() string {
+	return e.Message
+}
+
+// errWriteFailed rejects a placement that couldn't be persisted, and so wasn't applied
+var errWriteFailed = &PlacementError{Code: "write_failed", Message: "the change could not be saved, try again"}
+
+// Place validates a single-cell operation and applies it, returning the new
+// state of the cell. Rejected placements return a *PlacementError.
+func (h *Hub) Place(ctx context.Context, p Placement) (_ model.Pixel, err error) {
+	ctx = withReceipt(ctx, time.Now())
+	ctx, span := tracing.Tracer().Start(ctx, "hub.place", canvasAttr(h),
+		trace.WithAttributes(attribute.String("op", p.Op), attribute.Int("x", p.X), attribute.Int("y", p.Y)))
+	defer func() {
+		traceRejection(span, err)
+		span.End()
+	}()
+
+	if rejected := h.frozenError(); rejected != nil {
+		return model.Pixel{}, rejected
+	}
+	if h.IsBanned(p.IP) {
+		return model.Pixel{}, &PlacementError{Code: "banned", Message: "you are banned from painting"}
+	}
+
+	// Validate coordinates and color
+	if !h.grid.InBounds(p.X, p.Y) {
+		return model.Pixel{}, &PlacementError{Code: "out_of_bounds", Message: fmt.Sprintf("cell (%d, %d) is outside the grid", p.X, p.Y)}
+	}
+	color, ok := requestedColor(p.Color)
+	if !ok {
+		return model.Pixel{}, &PlacementError{Code: "invalid_color", Message: fmt.Sprintf("color %q is not allowed", p.Color)}
+	}
+	if rejected := h.teamError(p.Team); rejected != nil {
+		return model.Pixel{}, rejected
+	}
+
+	var pixel model.Pixel
+	switch p.Op {
+	case OpToggle:
+		pixel = model.Pixel{X: p.X, Y: p.Y}
+	case OpSet:
+		pixel = model.Pixel{X: p.X, Y: p.Y, Active: true, Color: color}
+	case OpClear:
+		pixel = model.Pixel{X: p.X, Y: p.Y, Active: false, Color: model.White}
+	default:
+		return model.Pixel{}, &PlacementError{Code: "unknown_type", Message: fmt.Sprintf("unknown cell operation %q", p.Op)}
+	}
+
+	if !p.Moderator {
+		if rejected := h.lockedError([]model.Pixel{pixel}); rejected != nil {
+			return model.Pixel{}, rejected
+		}
+		if rejected := h.claimedError([]model.Pixel{pixel}, p.Actor); rejected != nil {
+			return model.Pixel{}, rejected
+		}
+	}
+	if cell, remaining, protected := h.protection.Check(p.Actor, []model.Pixel{pixel}); protected {
+		return model.Pixel{}, protectedError(cell, remaining)
+	}
+	if rejected := h.captchaError(p.Actor, p.Anonymous); rejected != nil {
+		return model.Pixel{}, rejected
+	}
+	if rejected := h.paceError(p); rejected != nil {
+		return model.Pixel{}, rejected
+	}
+	if rejected := h.throttleError(p.Actor); rejected != nil {
+		return model.Pixel{}, rejected
+	}
+	// Shadow-banned paints look accepted but mustn't protect cells from others
+	shadowed := h.IsShadowBanned(p.IP, p.Actor)
+	if !shadowed {
+		if cell, remaining, protected := h.protection.Claim(p.Actor, []model.Pixel{pixel}); protected {
+			return model.Pixel{}, protectedError(cell, remaining)
+		}
+	}
+	loc := h.config.GeoIP.Lookup(p.IP)
+	pixel.Country, pixel.Region, pixel.Team = loc.Country, loc.Region, p.Team
+
+	if shadowed {
+		if p.Op == OpToggle {
+			return h.shadowToggle(pixel, color, p.Actor), nil
+		}
+		h.shadowCells([]model.Pixel{pixel}, p.Actor)
+		return pixel, nil
+	}
+
+	if p.Op == OpToggle {
+		// Toggle the cell with color and get new state (thread-safe)
+		h.placing.RLock()
+		defer h.placing.RUnlock()
+		var prior CellState
+		toggled := []model.Pixel{pixel}
+		journal := h.journal(ctx, p.Actor)
+		_, err := h.mutate(ctx, func() ([]model.Pixel, error) {
+			var err error
+			_, _, prior, err = h.grid.ToggleCell(p.X, p.Y, color, func(changed []model.Pixel) error {
+				toggled[0].Active, toggled[0].Color = changed[0].Active, changed[0].Color
+				return journal(toggled)
+			})
+			return toggled, err
+		})
+		if err != nil {
+			return model.Pixel{}, errWriteFailed
+		}
+		h.recordPlacement(p.Actor, toggled, []CellState{prior})
+		h.observeGrief(p.Actor, p.Moderator, toggled, []CellState{prior}, false)
+		h.commitChanges(ctx, toggled, p.Actor)
+		return toggled[0], nil
+	}
+	if _, err := h.placeCells(ctx, []model.Pixel{pixel}, p.Actor, p.Moderator); err != nil {
+		return model.Pixel{}, errWriteFailed
+	}
+	return pixel, nil
+}
+
+// requestedColor parses the color of a cell operation, reporting false if it
+// is not allowed. No color selects the default color.
+func requestedColor(requested string) (model.Color, bool) {
+	if requested == "" {
+		return model.DefaultColor(), true // Default to the first palette color if none provided
+	}
+	color, err := model.ParseColor(requested)
+	if err != nil || !model.IsValidColor(color) {
+		return 0, false
+	}
+	return color, true
+}
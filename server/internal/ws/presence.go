@@ -0,0 +1,97 @@
+package ws
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+)
+
+// Words display names are generated from
+var (
+	nameAdjectives = []string{
+		"Amber", "Brave", "Calm", "Clever", "Cosmic", "Curious", "Dapper", "Eager",
+		"Fuzzy", "Gentle", "Happy", "Jolly", "Lucky", "Mellow", "Nimble", "Quiet",
+		"Rapid", "Rusty", "Shiny", "Silent", "Sleepy", "Sunny", "Swift", "Witty",
+	}
+	nameAnimals = []string{
+		"Badger", "Beaver", "Falcon", "Ferret", "Fox", "Gecko", "Heron", "Koala",
+		"Lemur", "Lynx", "Marmot", "Moose", "Otter", "Owl", "Panda", "Puffin",
+		"Quokka", "Raven", "Seal", "Sloth", "Tapir", "Tiger", "Walrus", "Wombat",
+	}
+)
+
+// Presence identifies a connected client in the roster
+type Presence struct {
+	ID   uint64 `json:"id"` // Connection ID
+	Name string `json:"name"`
+}
+
+// RosterMessage is sent to a new client with the clients connected to its
+// hub, itself included
+type RosterMessage struct {
+	Type  string     `json:"t"`
+	You   Presence   `json:"you"`
+	Users []Presence `json:"users"`
+}
+
+// JoinMessage is sent to all clients when a client connects
+type JoinMessage struct {
+	Type string `json:"t"`
+	Presence
+}
+
+// LeaveMessage is sent to all clients when a client disconnects
+type LeaveMessage struct {
+	Type string `json:"t"`
+	ID   uint64 `json:"id"`
+}
+
+// generateName returns a random display name such as "SleepyOtter42"
+func generateName() string {
+	return fmt.Sprintf("%s%s%02d",
+		nameAdjectives[rand.IntN(len(nameAdjectives))],
+		nameAnimals[rand.IntN(len(nameAnimals))],
+		rand.IntN(100))
+}
+
+// Roster returns the clients connected to the hub, by connection ID. Like the
+// client count, it only covers this instance.
+func (h *Hub) Roster() []Presence {
+	h.mu.RLock()
+	users := make([]Presence, 0, len(h.canvasRoom.members))
+	for client := range h.canvasRoom.members {
+		users = append(users, client.Presence())
+	}
+	h.mu.RUnlock()
+	slices.SortFunc(users, func(a, b Presence) int { return cmp.Compare(a.ID, b.ID) })
+	return users
+}
+
+// sendRoster queues the roster on a newly registered client. It is called by
+// the Run loop so the roster matches the join and leave messages that follow.
+func (h *Hub) sendRoster(client *Client) {
+	data, err := encodeMessage(RosterMessage{Type: "roster", You: client.Presence(), Users: h.Roster()}, client.encoding)
+	if err != nil {
+		client.logger.Error("Failed to encode roster", "err", err)
+		return
+	}
+	select {
+	case client.lanes[PriorityHigh] <- data:
+	default:
+		// Don't block the hub on a client with a full lane
+		client.logger.Warn("Dropping roster, send buffer full")
+	}
+}
+
+// broadcastPresence sends a join or leave message to all connected clients
+func (h *Hub) broadcastPresence(msg any) {
+	message, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("Failed to marshal presence", "err", err)
+		return
+	}
+	h.broadcastLow(message)
+}
@@ -0,0 +1,66 @@
+package ws
+
+// Priority selects the lane of a client's send queue a message waits in. The
+// write pump empties the higher lanes first, and each lane has a buffer of its
+// own, so a burst of low-priority messages can't crowd out the others.
+type Priority int
+
+// Message priorities
+const (
+	// Cell updates and other changes of the canvas
+	PriorityNormal Priority = iota
+
+	// Region snapshots and replies to the client's messages (the initial
+	// state is written before any lane)
+	PriorityHigh
+
+	// Presence, client counts, cursors and chat. Dropped rather than handled
+	// by the slow client policy when their lane is full.
+	PriorityLow
+
+	numPriorities
+)
+
+// Order the write pump empties the lanes in
+var drainOrder = [numPriorities]Priority{PriorityHigh, PriorityNormal, PriorityLow}
+
+// newLanes creates the lanes of a send queue, size messages each
+func newLanes(size int) [numPriorities]chan []byte {
+	var lanes [numPriorities]chan []byte
+	for i := range lanes {
+		lanes[i] = make(chan []byte, size)
+	}
+	return lanes
+}
+
+// dequeue takes the highest priority message queued for the client without
+// waiting. ok is false if the lanes are empty or closed, closed is set once
+// the hub has closed them.
+func (c *Client) dequeue() (message []byte, ok, closed bool) {
+	for _, priority := range drainOrder {
+		select {
+		case message, ok = <-c.lanes[priority]:
+			return message, ok, !ok
+		default:
+		}
+	}
+	return nil, false, false
+}
+
+// queued returns the number of messages waiting in the client's lanes
+func (c *Client) queued() int {
+	n := 0
+	for _, lane := range c.lanes {
+		n += len(lane)
+	}
+	return n
+}
+
+// closeLanes closes every lane, making the write pump send a close frame. The
+// hub's mutex must be held for writing.
+func (c *Client) closeLanes() {
+	for _, lane := range c.lanes {
+		close(lane)
+	}
+	c.lanesClosed = true
+}
@@ -0,0 +1,127 @@
+package ws
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// ProtectedMessage is sent to a client whose placement was rejected because
+// someone else changed the cell too recently
+type ProtectedMessage struct {
+	Type        string `json:"t"`
+	X           int    `json:"x"`
+	Y           int    `json:"y"`
+	RemainingMs int64  `json:"remaining_ms"` // Time left before the cell may be overwritten
+}
+
+// cellKey identifies a cell of the grid
+type cellKey struct {
+	x, y int
+}
+
+// cellClaim is the latest placement on a cell
+type cellClaim struct {
+	actor string
+	at    time.Time
+}
+
+// Protection keeps freshly placed cells from being overwritten by other
+// actors for a while. Admin changes and decay bypass it.
+type Protection struct {
+	// Time a placement protects its cell (0 disables protection)
+	window time.Duration
+
+	// Latest placement per cell, while it may still protect the cell
+	claims map[cellKey]cellClaim
+
+	// Time of the last sweep of expired claims
+	lastSweep time.Time
+
+	mu sync.Mutex
+}
+
+// NewProtection creates a Protection with the given window
+func NewProtection(window time.Duration) *Protection {
+	return &Protection{
+		window: window,
+		claims: make(map[cellKey]cellClaim),
+	}
+}
+
+// Check reports the first of the pixels protected against actor, and how long
+// until it may be overwritten. It returns false if none is protected.
+func (p *Protection) Check(actor string, pixels []model.Pixel) (model.Pixel, time.Duration, bool) {
+	if p.window <= 0 {
+		return model.Pixel{}, 0, false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.check(actor, pixels, time.Now())
+}
+
+// Claim records the placement of the pixels by actor unless one of them is
+// protected against it, which is reported as by Check. Either every pixel is
+// claimed or none is.
+func (p *Protection) Claim(actor string, pixels []model.Pixel) (model.Pixel, time.Duration, bool) {
+	if p.window <= 0 {
+		return model.Pixel{}, 0, false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if pixel, remaining, protected := p.check(actor, pixels, now); protected {
+		return pixel, remaining, true
+	}
+	for _, pixel := range pixels {
+		p.claims[cellKey{pixel.X, pixel.Y}] = cellClaim{actor: actor, at: now}
+	}
+
+	// Periodically drop claims that no longer protect their cell to bound memory
+	if now.Sub(p.lastSweep) > time.Minute {
+		for key, claim := range p.claims {
+			if now.Sub(claim.at) >= p.window {
+				delete(p.claims, key)
+			}
+		}
+		p.lastSweep = now
+	}
+	return model.Pixel{}, 0, false
+}
+
+// Reset drops every claim, e.g. once the canvas was cleared
+func (p *Protection) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	clear(p.claims)
+}
+
+// check finds the first of the pixels protected against actor at now, p.mu must be held
+func (p *Protection) check(actor string, pixels []model.Pixel, now time.Time) (model.Pixel, time.Duration, bool) {
+	for _, pixel := range pixels {
+		claim, ok := p.claims[cellKey{pixel.X, pixel.Y}]
+		if !ok || claim.actor == actor {
+			continue
+		}
+		if elapsed := now.Sub(claim.at); elapsed < p.window {
+			return pixel, p.window - elapsed, true
+		}
+	}
+	return model.Pixel{}, 0, false
+}
+
+// protectedError returns the error rejecting a placement on a protected cell
+func protectedError(pixel model.Pixel, remaining time.Duration) *PlacementError {
+	return &PlacementError{
+		Code:       "cell_protected",
+		Message:    fmt.Sprintf("cell (%d, %d) was just placed by someone else, wait before overwriting it", pixel.X, pixel.Y),
+		X:          pixel.X,
+		Y:          pixel.Y,
+		RetryAfter: remaining,
+	}
+}
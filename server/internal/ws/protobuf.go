@@ -0,0 +1,365 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/million_grids/server/internal/gridpb"
+	"github.com/million_grids/server/internal/model"
+	"google.golang.org/protobuf/proto"
+)
+
+// Subprotocols a client can request with Sec-WebSocket-Protocol. Clients that
+// negotiate neither speak JSON.
+const (
+	SubprotocolJSON     = "million-grids.v1.json"
+	SubprotocolProtobuf = "million-grids.v1.protobuf" // Binary frames, see proto/million_grids/v1/grid.proto
+)
+
+// Subprotocols lists the subprotocols the upgrader accepts
+var Subprotocols = []string{SubprotocolProtobuf, SubprotocolJSON}
+
+// Encoding is the wire format of the messages sent to a client or listener
+type Encoding int
+
+const (
+	EncodingJSON     Encoding = iota
+	EncodingProtobuf          // gridpb.ServerMessage frames
+)
+
+// encodeMessage marshals a server message in the encoding
+func encodeMessage(msg any, enc Encoding) ([]byte, error) {
+	if enc == EncodingProtobuf {
+		return encodeProtobuf(msg)
+	}
+	return json.Marshal(msg)
+}
+
+// protoMessageType returns the type of the JSON message a ClientMessage is
+// the equivalent of (empty if it sets none of its fields)
+func protoMessageType(msg *gridpb.ClientMessage) string {
+	switch msg.Msg.(type) {
+	case *gridpb.ClientMessage_Toggle:
+		return OpToggle
+	case *gridpb.ClientMessage_Set:
+		return OpSet
+	case *gridpb.ClientMessage_Clear:
+		return OpClear
+	case *gridpb.ClientMessage_Paint:
+		return OpPaint
+	case *gridpb.ClientMessage_Subscribe:
+		return MsgSubscribe
+	case *gridpb.ClientMessage_Unsubscribe:
+		return MsgUnsubscribe
+	case *gridpb.ClientMessage_Cursor:
+		return MsgCursor
+	case *gridpb.ClientMessage_Chat:
+		return MsgChat
+	case *gridpb.ClientMessage_JoinChannel:
+		return MsgJoinChannel
+	case *gridpb.ClientMessage_LeaveChannel:
+		return MsgLeaveChannel
+	case *gridpb.ClientMessage_Team:
+		return MsgTeam
+	case *gridpb.ClientMessage_Undo:
+		return MsgUndo
+	case *gridpb.ClientMessage_Captcha:
+		return MsgCaptcha
+	}
+	return ""
+}
+
+// handleProtobuf parses a ClientMessage frame and routes it like its JSON equivalent
+func (c *Client) handleProtobuf(ctx context.Context, message []byte) {
+	var msg gridpb.ClientMessage
+	if err := proto.Unmarshal(message, &msg); err != nil {
+		c.sendError("invalid_message", "frame is not a valid ClientMessage")
+		return
+	}
+
+	c.hub.countMessage(protoMessageType(&msg))
+	switch msg.Msg.(type) {
+	case *gridpb.ClientMessage_Subscribe, *gridpb.ClientMessage_Unsubscribe, *gridpb.ClientMessage_JoinChannel, *gridpb.ClientMessage_LeaveChannel:
+	default:
+		if c.hub.config.Follower {
+			c.sendFollowerError()
+			return
+		}
+	}
+
+	switch m := msg.Msg.(type) {
+	case *gridpb.ClientMessage_Toggle:
+		c.handleCellMessage(ctx, cellOpMessage(OpToggle, m.Toggle))
+	case *gridpb.ClientMessage_Set:
+		c.handleCellMessage(ctx, cellOpMessage(OpSet, m.Set))
+	case *gridpb.ClientMessage_Clear:
+		c.handleCellMessage(ctx, cellOpMessage(OpClear, m.Clear))
+	case *gridpb.ClientMessage_Paint:
+		cells := make([]PaintCell, len(m.Paint.GetCells()))
+		for i, cell := range m.Paint.GetCells() {
+			cells[i] = PaintCell{X: int(cell.X), Y: int(cell.Y), Color: ColorFromProto(cell.Color)}
+		}
+		c.handleCellMessage(ctx, CellMessage{Type: OpPaint, Cells: cells})
+	case *gridpb.ClientMessage_Subscribe:
+		c.handleSubscribe(regionFromProto(m.Subscribe))
+	case *gridpb.ClientMessage_Unsubscribe:
+		c.setViewport(nil)
+	case *gridpb.ClientMessage_Cursor:
+		c.handleCursor(int(m.Cursor.GetX()), int(m.Cursor.GetY()))
+	case *gridpb.ClientMessage_Chat:
+		c.handleChat(m.Chat.GetText(), m.Chat.GetChannel())
+	case *gridpb.ClientMessage_JoinChannel:
+		c.handleChannel(MsgJoinChannel, m.JoinChannel.GetName())
+	case *gridpb.ClientMessage_LeaveChannel:
+		c.handleChannel(MsgLeaveChannel, m.LeaveChannel.GetName())
+	case *gridpb.ClientMessage_Team:
+		c.handleTeam(m.Team.GetName())
+	case *gridpb.ClientMessage_Undo:
+		c.handleUndo(ctx)
+	case *gridpb.ClientMessage_Captcha:
+		c.handleCaptcha(m.Captcha.GetToken())
+	default:
+		c.sendError("missing_type", "message sets none of its fields")
+	}
+}
+
+// cellOpMessage converts a single-cell operation to a CellMessage
+func cellOpMessage(op string, cell *gridpb.CellOp) CellMessage {
+	msg := CellMessage{Type: op, X: int(cell.GetX()), Y: int(cell.GetY())}
+	if cell.Color != nil {
+		msg.Color = ColorFromProto(*cell.Color)
+	}
+	return msg
+}
+
+// encodeProtobuf converts a server message to a ServerMessage frame
+func encodeProtobuf(msg any) ([]byte, error) {
+	var out gridpb.ServerMessage
+	switch m := msg.(type) {
+	case InitMessage:
+		out.Msg = &gridpb.ServerMessage_Init{Init: &gridpb.Init{
+			Canvas:         m.Canvas,
+			Width:          uint32(m.Width),
+			Height:         uint32(m.Height),
+			Chunk:          uint32(m.ChunkSize),
+			Palette:        colorsToProto(m.Palette),
+			PaletteVersion: uint32(m.PaletteVersion),
+			AnyColor:       m.AnyColor,
+			Frozen:         m.Frozen,
+			FrozenReason:   m.FrozenReason,
+			Follower:       m.Follower,
+		}}
+	case InitChunkMessage:
+		out.Msg = &gridpb.ServerMessage_InitChunk{InitChunk: &gridpb.InitChunk{
+			X:      uint32(m.X),
+			Y:      uint32(m.Y),
+			Active: cellsToProto(m.Active),
+		}}
+	case InitDoneMessage:
+		out.Msg = &gridpb.ServerMessage_InitDone{InitDone: &gridpb.InitDone{Total: uint32(m.Total)}}
+	case BroadcastCellUpdate:
+		out.Msg = &gridpb.ServerMessage_Update{Update: &gridpb.CellUpdate{
+			X:      uint32(m.X),
+			Y:      uint32(m.Y),
+			Active: m.Active == 1,
+			Color:  uint32(m.Color),
+			Team:   m.Team,
+		}}
+	case BroadcastBatchUpdate:
+		cells := make([]*gridpb.CellUpdate, len(m.Cells))
+		for i, cell := range m.Cells {
+			cells[i] = &gridpb.CellUpdate{X: uint32(cell.X), Y: uint32(cell.Y), Active: cell.Active == 1, Color: uint32(cell.Color)}
+		}
+		out.Msg = &gridpb.ServerMessage_Batch{Batch: &gridpb.BatchUpdate{Cells: cells, Team: m.Team}}
+	case ClientCountMessage:
+		out.Msg = &gridpb.ServerMessage_ClientCount{ClientCount: &gridpb.ClientCount{Count: uint32(m.Count)}}
+	case PaletteMessage:
+		out.Msg = &gridpb.ServerMessage_Palette{Palette: &gridpb.Palette{
+			Version:  uint32(m.Version),
+			Colors:   colorsToProto(m.Colors),
+			AnyColor: m.AnyColor,
+		}}
+	case ErrorMessage:
+		out.Msg = &gridpb.ServerMessage_Error{Error: &gridpb.Error{Code: m.Code, Msg: m.Message}}
+	case CooldownMessage:
+		out.Msg = &gridpb.ServerMessage_Cooldown{Cooldown: &gridpb.Cooldown{RemainingMs: m.RemainingMs}}
+	case CaptchaMessage:
+		out.Msg = &gridpb.ServerMessage_Captcha{Captcha: &gridpb.CaptchaAccepted{Remaining: uint32(m.Remaining)}}
+	case TierMessage:
+		out.Msg = &gridpb.ServerMessage_Tier{Tier: &gridpb.Tier{
+			Name:       m.Tier,
+			CooldownMs: m.CooldownMs,
+			Quota:      uint32(m.Quota),
+			Remaining:  uint32(m.Remaining),
+			ResetMs:    m.ResetMs,
+		}}
+	case LocksMessage:
+		regions := make([]*gridpb.LockedRegion, len(m.Regions))
+		for i, lock := range m.Regions {
+			regions[i] = &gridpb.LockedRegion{Id: uint32(lock.ID), Region: regionToProto(lock.Region), Label: lock.Label}
+		}
+		out.Msg = &gridpb.ServerMessage_Locks{Locks: &gridpb.Locks{Regions: regions}}
+	case ClaimsMessage:
+		claims := make([]*gridpb.Claim, len(m.Claims))
+		for i, claim := range m.Claims {
+			claims[i] = &gridpb.Claim{Id: uint32(claim.ID), Region: regionToProto(claim.Region), Owner: claim.Owner, ExpiresAtMs: claim.ExpiresAt.UnixMilli()}
+		}
+		out.Msg = &gridpb.ServerMessage_Claims{Claims: &gridpb.Claims{Claims: claims}}
+	case TeamMessage:
+		out.Msg = &gridpb.ServerMessage_Team{Team: &gridpb.Team{Name: m.Team}}
+	case TeamsMessage:
+		scores := make([]*gridpb.TeamScore, len(m.Scores))
+		for i, score := range m.Scores {
+			scores[i] = &gridpb.TeamScore{Team: score.Team, Pixels: uint32(score.Pixels)}
+		}
+		out.Msg = &gridpb.ServerMessage_Teams{Teams: &gridpb.Teams{Scores: scores}}
+	case CellMetaMessage:
+		out.Msg = &gridpb.ServerMessage_Meta{Meta: &gridpb.CellMeta{X: uint32(m.X), Y: uint32(m.Y), Text: m.Text, Url: m.URL}}
+	case ProtectedMessage:
+		out.Msg = &gridpb.ServerMessage_Protected{Protected: &gridpb.Protected{X: uint32(m.X), Y: uint32(m.Y), RemainingMs: m.RemainingMs}}
+	case RegionMessage:
+		out.Msg = &gridpb.ServerMessage_Region{Region: &gridpb.RegionState{
+			Region: regionToProto(m.Region),
+			Active: cellsToProto(m.Active),
+		}}
+	case RosterMessage:
+		users := make([]*gridpb.Presence, len(m.Users))
+		for i, user := range m.Users {
+			users[i] = &gridpb.Presence{Id: user.ID, Name: user.Name}
+		}
+		out.Msg = &gridpb.ServerMessage_Roster{Roster: &gridpb.Roster{
+			You:   &gridpb.Presence{Id: m.You.ID, Name: m.You.Name},
+			Users: users,
+		}}
+	case JoinMessage:
+		out.Msg = &gridpb.ServerMessage_Join{Join: &gridpb.Presence{Id: m.ID, Name: m.Name}}
+	case LeaveMessage:
+		out.Msg = &gridpb.ServerMessage_Leave{Leave: &gridpb.Leave{Id: m.ID}}
+	case BroadcastCursor:
+		out.Msg = &gridpb.ServerMessage_Cursor{Cursor: &gridpb.CursorPosition{Id: m.ID, X: uint32(m.X), Y: uint32(m.Y)}}
+	case BroadcastChat:
+		out.Msg = &gridpb.ServerMessage_Chat{Chat: chatToProto(m)}
+	case ChatHistoryMessage:
+		msgs := make([]*gridpb.Chat, len(m.Messages))
+		for i, chat := range m.Messages {
+			msgs[i] = chatToProto(chat)
+		}
+		out.Msg = &gridpb.ServerMessage_ChatHistory{ChatHistory: &gridpb.ChatHistory{Messages: msgs}}
+	case FrozenMessage:
+		out.Msg = &gridpb.ServerMessage_Frozen{Frozen: &gridpb.Frozen{Enabled: m.Enabled, Reason: m.Reason}}
+	case AnnounceMessage:
+		out.Msg = &gridpb.ServerMessage_Announce{Announce: &gridpb.Announcement{
+			Text:      m.Text,
+			Severity:  m.Severity,
+			Countdown: uint32(m.Countdown),
+			AtMs:      m.At.UnixMilli(),
+		}}
+	case ResetMessage:
+		out.Msg = &gridpb.ServerMessage_Reset_{Reset_: &gridpb.Reset{Archive: m.Archive, AtMs: m.At.UnixMilli()}}
+	default:
+		return nil, fmt.Errorf("no protobuf encoding for %T", msg)
+	}
+	return proto.Marshal(&out)
+}
+
+// transcodeProtobuf converts an encoded JSON broadcast, possibly received from
+// another instance, to a ServerMessage frame
+func transcodeProtobuf(data []byte) ([]byte, error) {
+	var envelope struct {
+		Type string `json:"t"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+
+	var msg any
+	var err error
+	switch envelope.Type {
+	case "u":
+		msg, err = decodeAs[BroadcastCellUpdate](data)
+	case "b":
+		msg, err = decodeAs[BroadcastBatchUpdate](data)
+	case "c":
+		msg, err = decodeAs[ClientCountMessage](data)
+	case "palette":
+		msg, err = decodeAs[PaletteMessage](data)
+	case "join":
+		msg, err = decodeAs[JoinMessage](data)
+	case "leave":
+		msg, err = decodeAs[LeaveMessage](data)
+	case "cursor":
+		msg, err = decodeAs[BroadcastCursor](data)
+	case "chat":
+		msg, err = decodeAs[BroadcastChat](data)
+	case "announce":
+		msg, err = decodeAs[AnnounceMessage](data)
+	case "frozen":
+		msg, err = decodeAs[FrozenMessage](data)
+	case "reset":
+		msg, err = decodeAs[ResetMessage](data)
+	case "locks":
+		msg, err = decodeAs[LocksMessage](data)
+	case "claims":
+		msg, err = decodeAs[ClaimsMessage](data)
+	case "teams":
+		msg, err = decodeAs[TeamsMessage](data)
+	case "meta":
+		msg, err = decodeAs[CellMetaMessage](data)
+	default:
+		return nil, fmt.Errorf("unknown broadcast type %q", envelope.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return encodeProtobuf(msg)
+}
+
+// chatToProto converts a chat message to its protobuf form
+func chatToProto(m BroadcastChat) *gridpb.Chat {
+	return &gridpb.Chat{Id: m.ID, Name: m.Name, Text: m.Text, AtMs: m.At.UnixMilli(), Channel: m.Channel}
+}
+
+// decodeAs unmarshals a JSON message into a T
+func decodeAs[T any](data []byte) (T, error) {
+	var msg T
+	err := json.Unmarshal(data, &msg)
+	return msg, err
+}
+
+// ColorFromProto formats a 0xRRGGBB value as the "#RRGGBB" string of the JSON
+// protocol, leaving out-of-range values unparsable so they are rejected
+func ColorFromProto(v uint32) string {
+	if v > 0xFFFFFF {
+		return fmt.Sprintf("#%X", v)
+	}
+	return model.Color(v).String()
+}
+
+// regionFromProto converts a protobuf region
+func regionFromProto(r *gridpb.Region) Region {
+	return Region{X1: int(r.GetX1()), Y1: int(r.GetY1()), X2: int(r.GetX2()), Y2: int(r.GetY2())}
+}
+
+// regionToProto converts a region to its protobuf form
+func regionToProto(r Region) *gridpb.Region {
+	return &gridpb.Region{X1: uint32(r.X1), Y1: uint32(r.Y1), X2: uint32(r.X2), Y2: uint32(r.Y2)}
+}
+
+// colorsToProto converts colors to their 0xRRGGBB values
+func colorsToProto(colors []model.Color) []uint32 {
+	out := make([]uint32, len(colors))
+	for i, c := range colors {
+		out[i] = uint32(c)
+	}
+	return out
+}
+
+// cellsToProto converts active cells to protobuf cells
+func cellsToProto(cells []ActiveCell) []*gridpb.Cell {
+	out := make([]*gridpb.Cell, len(cells))
+	for i, cell := range cells {
+		out[i] = &gridpb.Cell{X: uint32(cell.X), Y: uint32(cell.Y), Color: uint32(cell.Color)}
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package ws
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// TrustedProxies are the networks of the proxies whose X-Forwarded-For and
+// X-Real-IP headers are believed. Clients can set those headers to anything,
+// so with none trusted the address of the connection is used.
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies parses CIDRs such as "10.0.0.0/8"
+func ParseTrustedProxies(cidrs []string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+// ClientIP returns the address of the client behind a connection from remote,
+// given the X-Forwarded-For values and X-Real-IP header of its request. The
+// headers are only believed when remote is a trusted proxy, and
+// X-Forwarded-For is walked from the right so the client's own entries, to
+// the left of those the trusted proxies appended, are ignored.
+func (t TrustedProxies) ClientIP(remote string, forwardedFor []string, realIP string) string {
+	if !t.trusted(remote) {
+		return remote
+	}
+
+	var hops []string
+	for _, header := range forwardedFor {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// Unparseable entries can't be told apart from a forgery, so the
+			// last hop a trusted proxy vouched for is the client
+			return client
+		}
+		client = addr.Unmap().String()
+		if !t.trusted(client) {
+			return client
+		}
+	}
+	if len(hops) > 0 {
+		return client
+	}
+
+	if addr, err := netip.ParseAddr(strings.TrimSpace(realIP)); err == nil {
+		return addr.Unmap().String()
+	}
+	return remote
+}
+
+// trusted reports whether ip is in a trusted proxy network
+func (t TrustedProxies) trusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range t {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package ws
+
+import "testing"
+
+func TestTrustedProxiesClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name         string
+		remote       string
+		forwardedFor []string
+		realIP       string
+		want         string
+	}{
+		{"direct", "203.0.113.5", nil, "", "203.0.113.5"},
+		{"forged by a direct client", "203.0.113.5", []string{"198.51.100.1"}, "198.51.100.2", "203.0.113.5"},
+		{"through a proxy", "10.0.0.1", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"forged before a proxy", "10.0.0.1", []string{"192.0.2.9, 198.51.100.1"}, "", "198.51.100.1"},
+		{"through a chain of proxies", "10.0.0.1", []string{"198.51.100.1, 10.1.2.3"}, "", "198.51.100.1"},
+		{"several headers", "10.0.0.1", []string{"192.0.2.9", "198.51.100.1, 10.1.2.3"}, "", "198.51.100.1"},
+		{"only proxies", "10.0.0.1", []string{"10.1.2.3"}, "", "10.1.2.3"},
+		{"unparseable entry", "10.0.0.1", []string{"198.51.100.1, bogus, 10.1.2.3"}, "", "10.1.2.3"},
+		{"real ip from a proxy", "10.0.0.1", nil, "198.51.100.1", "198.51.100.1"},
+		{"invalid real ip", "10.0.0.1", nil, "bogus", "10.0.0.1"},
+		{"ipv6 proxy", "2001:db8::1", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"mapped ipv4", "10.0.0.1", []string{"::ffff:198.51.100.1"}, "", "198.51.100.1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := proxies.ClientIP(tc.remote, tc.forwardedFor, tc.realIP); got != tc.want {
+				t.Errorf("ClientIP = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNoTrustedProxies(t *testing.T) {
+	var proxies TrustedProxies
+	if got := proxies.ClientIP("10.0.0.1", []string{"198.51.100.1"}, "198.51.100.2"); got != "10.0.0.1" {
+		t.Errorf("ClientIP = %q, want the remote address", got)
+	}
+}
+
+func TestParseTrustedProxiesInvalid(t *testing.T) {
+	if _, err := ParseTrustedProxies([]string{"10.0.0.1"}); err == nil {
+		t.Error("expected an error for an address without a prefix length")
+	}
+}
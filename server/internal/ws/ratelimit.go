@@ -0,0 +1,72 @@
+package ws
+
+import "time"
+
+const (
+	// Window over which rate limit violations are counted
+	violationWindow = 10 * time.Second
+
+	// Clients exceeding the rate limit this many times within the window are disconnected
+	maxViolations = 20
+)
+
+// RateLimiter is a token bucket throttling the inbound messages of one client.
+// It is only used from the client's read pump and needs no locking.
+type RateLimiter struct {
+	// Tokens added per second (sustained rate)
+	rate float64
+
+	// Maximum number of tokens (burst size)
+	burst float64
+
+	tokens float64
+	last   time.Time
+
+	// Violations counted since windowStart
+	violations  int
+	windowStart time.Time
+}
+
+// NewRateLimiter creates a full bucket allowing rate messages per second with the given burst.
+// A non-positive rate disables limiting.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	now := time.Now()
+	return &RateLimiter{
+		rate:        rate,
+		burst:       float64(burst),
+		tokens:      float64(burst),
+		last:        now,
+		windowStart: now,
+	}
+}
+
+// Allow takes a token for an inbound message and reports whether it may be processed
+func (rl *RateLimiter) Allow() bool {
+	if rl.rate <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	rl.tokens = min(rl.burst, rl.tokens+now.Sub(rl.last).Seconds()*rl.rate)
+	rl.last = now
+
+	if rl.tokens >= 1 {
+		rl.tokens--
+		return true
+	}
+
+	if now.Sub(rl.windowStart) > violationWindow {
+		rl.violations = 0
+		rl.windowStart = now
+	}
+	rl.violations++
+	return false
+}
+
+// Exceeded reports whether the client has violated the limit too often and should be disconnected
+func (rl *RateLimiter) Exceeded() bool {
+	return rl.violations >= maxViolations
+}
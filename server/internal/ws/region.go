@@ -0,0 +1,52 @@
+package ws
+
+// Region is a half-open rectangle of cells [X1, X2) x [Y1, Y2)
+type Region struct {
+	X1 int `json:"x1"`
+	Y1 int `json:"y1"`
+	X2 int `json:"x2"`
+	Y2 int `json:"y2"`
+}
+
+// CellRegion returns the region covering the single cell (x, y)
+func CellRegion(x, y int) Region {
+	return Region{X1: x, Y1: y, X2: x + 1, Y2: y + 1}
+}
+
+// Empty reports whether the region contains no cells
+func (r Region) Empty() bool {
+	return r.X1 >= r.X2 || r.Y1 >= r.Y2
+}
+
+// Clamp returns the region restricted to the bounds of a width x height grid
+func (r Region) Clamp(width, height int) Region {
+	return Region{
+		X1: max(r.X1, 0),
+		Y1: max(r.Y1, 0),
+		X2: min(r.X2, width),
+		Y2: min(r.Y2, height),
+	}
+}
+
+// Intersects reports whether the two regions share at least one cell
+func (r Region) Intersects(o Region) bool {
+	return r.X1 < o.X2 && o.X1 < r.X2 && r.Y1 < o.Y2 && o.Y1 < r.Y2
+}
+
+// Contains reports whether the cell (x, y) lies inside the region
+func (r Region) Contains(x, y int) bool {
+	return x >= r.X1 && x < r.X2 && y >= r.Y1 && y < r.Y2
+}
+
+// Extend grows the region to include the cell (x, y)
+func (r Region) Extend(x, y int) Region {
+	if r.Empty() {
+		return CellRegion(x, y)
+	}
+	return Region{
+		X1: min(r.X1, x),
+		Y1: min(r.Y1, y),
+		X2: max(r.X2, x+1),
+		Y2: max(r.Y2, y+1),
+	}
+}
@@ -0,0 +1,191 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// Bits of a clock value counting the changes made in the same millisecond
+const clockLogicalBits = 16
+
+// hybridClock is a hybrid logical clock: its values are the wall time in
+// milliseconds shifted by clockLogicalBits, but never go backwards and always
+// exceed the values observed from other regions, so a change is always newer
+// than the changes its region had seen when it was made
+type hybridClock struct {
+	mu   sync.Mutex
+	last uint64
+}
+
+// now returns a new clock value
+func (c *hybridClock) now() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.last = max(uint64(time.Now().UnixMilli())<<clockLogicalBits, c.last+1)
+	return c.last
+}
+
+// observe moves the clock past a value received from another region
+func (c *hybridClock) observe(value uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.last = max(c.last, value)
+}
+
+// clockTime returns the wall time of a clock value
+func clockTime(value uint64) time.Time {
+	return time.UnixMilli(int64(value >> clockLogicalBits))
+}
+
+// cellVersion identifies the last change of a cell by its clock value and the
+// region it was made in. Every region applies a replicated change only to the
+// cells it is newer than the version of, so they all end up with the last
+// writer's state whatever order the changes arrive in.
+type cellVersion struct {
+	Clock  uint64
+	Region string
+}
+
+// newer reports whether v is newer than other: the later clock wins, and the
+// greater region name breaks ties
+func (v cellVersion) newer(other cellVersion) bool {
+	if v.Clock != other.Clock {
+		return v.Clock > other.Clock
+	}
+	return v.Region > other.Region
+}
+
+// replicatedChanges carries cell changes committed together in a region to
+// the other regions
+type replicatedChanges struct {
+	Region string      `json:"region"`
+	Clock  uint64      `json:"clock"`
+	Actor  string      `json:"actor,omitempty"`
+	Team   string      `json:"team,omitempty"`
+	Cells  []BatchCell `json:"cells"`
+}
+
+// stampChanges gives cell changes committed through this instance a new
+// version and returns it, or the zero version without replication. The cells
+// are set again under the versions lock, in case a change replicated since
+// they were applied overwrote them with an older state.
+func (h *Hub) stampChanges(changed []model.Pixel) cellVersion {
+	if h.config.Replication == nil {
+		return cellVersion{}
+	}
+	h.versionsMu.Lock()
+	defer h.versionsMu.Unlock()
+
+	v := cellVersion{Clock: h.clock.now(), Region: h.config.Region}
+	for _, p := range changed {
+		h.grid.SetCell(p.X, p.Y, p.Active, p.Color)
+		h.versions[cellKey{p.X, p.Y}] = v
+	}
+	return v
+}
+
+// applyVersioned applies cell states set by a change of version v to the cells
+// it is newer than the last change of, and returns those whose state changed
+func (h *Hub) applyVersioned(pixels []model.Pixel, v cellVersion) []model.Pixel {
+	h.versionsMu.Lock()
+	defer h.versionsMu.Unlock()
+
+	var changed []model.Pixel
+	for _, p := range pixels {
+		key := cellKey{p.X, p.Y}
+		if !h.grid.InBounds(p.X, p.Y) || !v.newer(h.versions[key]) {
+			continue
+		}
+		h.versions[key] = v
+		if h.grid.SetCell(p.X, p.Y, p.Active, p.Color) {
+			changed = append(changed, p)
+		}
+	}
+	return changed
+}
+
+// replicate sends cell changes committed through this instance to the other regions
+func (h *Hub) replicate(changed []model.Pixel, actor string, v cellVersion) {
+	if h.config.Replication == nil {
+		return
+	}
+	for start := 0; start < len(changed); start += maxBroadcastBatch {
+		end := min(start+maxBroadcastBatch, len(changed))
+		cells := make([]BatchCell, 0, end-start)
+		for _, p := range changed[start:end] {
+			cells = append(cells, NewBatchCell(p))
+		}
+		message, err := json.Marshal(replicatedChanges{
+			Region: v.Region,
+			Clock:  v.Clock,
+			Actor:  actor,
+			Team:   changed[start].Team,
+			Cells:  cells,
+		})
+		if err != nil {
+			slog.Error("Failed to marshal replicated changes", "err", err)
+			return
+		}
+		if err := h.config.Replication.Publish(message); err != nil {
+			slog.Error("Failed to replicate changes to other regions", "canvas", h.config.Canvas, "err", err)
+		}
+	}
+}
+
+// consumeReplication applies the changes replicated from other regions until
+// the replication broker is closed
+func (h *Hub) consumeReplication() {
+	if err := h.config.Replication.Subscribe(h.handleReplicated); err != nil {
+		slog.Error("Replication subscription ended", "canvas", h.config.Canvas, "err", err)
+	}
+}
+
+// handleReplicated applies cell changes from another region where they win,
+// persists them to this region's database and broadcasts them to local
+// clients and, through the broker, to the other instances of the region
+func (h *Hub) handleReplicated(message []byte) {
+	var msg replicatedChanges
+	if err := json.Unmarshal(message, &msg); err != nil {
+		slog.Warn("Error parsing replicated changes", "err", err)
+		return
+	}
+	// The instances of our region exchange their changes through the broker
+	if msg.Region == h.config.Region {
+		return
+	}
+	h.clock.observe(msg.Clock)
+
+	v := cellVersion{Clock: msg.Clock, Region: msg.Region}
+	pixels := make([]model.Pixel, len(msg.Cells))
+	for i, cell := range msg.Cells {
+		pixels[i] = model.Pixel{X: cell.X, Y: cell.Y, Active: cell.Active == 1, Color: cell.Color, Team: msg.Team}
+	}
+	changed := h.applyVersioned(pixels, v)
+	if len(changed) == 0 {
+		return
+	}
+
+	at := clockTime(msg.Clock)
+	for i := range changed {
+		changed[i].Canvas = h.config.Canvas
+		changed[i].CreatedBy = msg.Actor
+		changed[i].ModifyAt = &at
+		changed[i].ModifyBy = msg.Actor
+	}
+	if err := h.config.Store.SavePixelsAsync(context.Background(), changed); err != nil {
+		slog.Error("Failed to persist replicated changes", "canvas", h.config.Canvas, "region", msg.Region, "err", err)
+	}
+	h.territory.Apply(changed)
+	h.updateTemplates(changed)
+	h.dropCellMeta(changed, msg.Actor)
+	h.activity.Record(len(changed), "", time.Now())
+	h.countPlacements(len(changed), false)
+	h.broadcastChanges(context.Background(), changed, v)
+
+	slog.Debug("Applied replicated changes", "canvas", h.config.Canvas, "region", msg.Region, "changed", len(changed), "received", len(msg.Cells))
+}
@@ -0,0 +1,73 @@
+package ws
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+)
+
+// ResetMessage is sent to all clients when the canvas is reset, telling them
+// to drop their copy of the grid and reload it
+type ResetMessage struct {
+	Type    string    `json:"t"`
+	Archive string    `json:"archive,omitempty"` // Name the previous state was archived under
+	At      time.Time `json:"at"`
+}
+
+// PausePlacements freezes the canvas and waits for the changes in progress,
+// then holds off every other change of the grid until the returned function
+// is called, which restores the previous read-only mode. Resets run while
+// placements are paused, so no change is persisted after the pending writes
+// were flushed or broadcast after the reset.
+func (h *Hub) PausePlacements(reason string) (resume func()) {
+	wasFrozen, _ := h.ReadOnly()
+	if !wasFrozen {
+		h.applyReadOnly(true, reason)
+	}
+	h.placing.Lock()
+	return func() {
+		h.placing.Unlock()
+		if !wasFrozen {
+			h.applyReadOnly(false, "")
+		}
+	}
+}
+
+// Reset clears every cell of the grid, tells the clients to reload it and
+// publishes the reset to the other instances. Placements must be paused (see
+// PausePlacements). Persisting the reset is up to the caller (see
+// db.ResetCanvas); the cleared cells are not recorded in the history.
+func (h *Hub) Reset(archive string) {
+	message, err := h.applyReset(ResetMessage{Archive: archive, At: time.Now().UTC()})
+	if err != nil {
+		return
+	}
+	h.config.Events.CanvasReset(h.config.Canvas)
+	if h.config.Broker != nil {
+		if err := h.config.Broker.Publish(message); err != nil {
+			slog.Error("Failed to publish reset to broker", "err", err)
+		}
+	}
+}
+
+// applyReset clears the grid and tells the local clients, returning the
+// encoded ResetMessage
+func (h *Hub) applyReset(msg ResetMessage) ([]byte, error) {
+	msg.Type = "reset"
+	message, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("Failed to marshal reset", "err", err)
+		return nil, err
+	}
+	h.grid.Initialize()
+	h.protection.Reset()
+	h.undo.Reset()
+	h.resetCellMeta()
+	h.territory.Reset()
+	h.recheckTemplates()
+	slog.Info("Canvas reset", "canvas", h.config.Canvas, "archive", msg.Archive, "clients", h.ClientCount())
+
+	// Queued with the updates, so none made before the reset arrives after it
+	h.enqueue(&outbound{data: message})
+	return message, nil
+}
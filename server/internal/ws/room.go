@@ -0,0 +1,228 @@
+package ws
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sync/atomic"
+)
+
+// Names of the rooms every hub has
+const (
+	// Every client of the canvas
+	RoomCanvas = "canvas"
+
+	// Clients receiving the updates of the whole grid, or of a viewport
+	// spanning more than maxRegionRooms region rooms
+	RoomGrid = "grid"
+)
+
+const (
+	// Side length of the square of cells each region room covers
+	regionRoomSize = 256
+
+	// Most region rooms a viewport joins, larger viewports join the grid room
+	maxRegionRooms = 16
+
+	// Prefix of the names of chat channel rooms
+	chatRoomPrefix = "chat:"
+)
+
+// Room is a named group of a hub's clients that broadcasts are sent to. A
+// hub has a canvas room with every client, and routes region updates through
+// the grid room and the region rooms of the viewports they intersect. Chat
+// channels are rooms of their own. Membership is guarded by the hub's mutex.
+type Room struct {
+	name    string
+	members map[*Client]bool
+
+	// Cells covered by a region room (nil for the other rooms)
+	tile *roomTile
+
+	// Kept when the last member leaves
+	permanent bool
+
+	// Broadcasts routed through the room, messages queued on members and
+	// messages dropped because a member's send buffer was full
+	broadcasts, delivered, dropped atomic.Int64
+}
+
+// RoomStats describes a room's membership and traffic since it was created
+type RoomStats struct {
+	Name       string `json:"name"`
+	Members    int    `json:"members"`
+	Broadcasts int64  `json:"broadcasts"`
+	Delivered  int64  `json:"delivered"`
+	Dropped    int64  `json:"dropped"`
+}
+
+func newRoom(name string, permanent bool) *Room {
+	return &Room{name: name, members: make(map[*Client]bool), permanent: permanent}
+}
+
+// stats returns the room's stats, the hub's mutex must be held
+func (r *Room) stats() RoomStats {
+	return RoomStats{
+		Name:       r.name,
+		Members:    len(r.members),
+		Broadcasts: r.broadcasts.Load(),
+		Delivered:  r.delivered.Load(),
+		Dropped:    r.dropped.Load(),
+	}
+}
+
+// roomTile identifies a region room by its column and row
+type roomTile [2]int
+
+// tileSpan returns the region rooms a region covers, as a region in units of
+// regionRoomSize
+func tileSpan(r Region) Region {
+	return Region{
+		X1: r.X1 / regionRoomSize,
+		Y1: r.Y1 / regionRoomSize,
+		X2: (r.X2 + regionRoomSize - 1) / regionRoomSize,
+		Y2: (r.Y2 + regionRoomSize - 1) / regionRoomSize,
+	}
+}
+
+// Rooms returns the stats of the hub's rooms, by name
+func (h *Hub) Rooms() []RoomStats {
+	h.mu.RLock()
+	rooms := make([]RoomStats, 0, len(h.rooms)+len(h.regionRooms))
+	for _, room := range h.rooms {
+		rooms = append(rooms, room.stats())
+	}
+	for _, room := range h.regionRooms {
+		rooms = append(rooms, room.stats())
+	}
+	h.mu.RUnlock()
+	slices.SortFunc(rooms, func(a, b RoomStats) int { return cmp.Compare(a.Name, b.Name) })
+	return rooms
+}
+
+// join adds a client to a room, the hub's mutex must be held for writing
+func (h *Hub) join(client *Client, room *Room) {
+	room.members[client] = true
+	client.rooms[room] = true
+}
+
+// leave removes a client from a room, deleting the room if it is left empty
+// and not permanent. The hub's mutex must be held for writing.
+func (h *Hub) leave(client *Client, room *Room) {
+	delete(room.members, client)
+	delete(client.rooms, room)
+	if len(room.members) > 0 || room.permanent {
+		return
+	}
+	if room.tile != nil {
+		delete(h.regionRooms, *room.tile)
+	} else {
+		delete(h.rooms, room.name)
+	}
+}
+
+// leaveAll removes a client from every room, the hub's mutex must be held for writing
+func (h *Hub) leaveAll(client *Client) {
+	for room := range client.rooms {
+		h.leave(client, room)
+	}
+}
+
+// placeViewport moves a registered client to the rooms receiving the updates
+// of its viewport: the region rooms it spans, or the grid room. The hub's
+// mutex must be held for writing.
+func (h *Hub) placeViewport(client *Client) {
+	for room := range client.rooms {
+		if room == h.gridRoom || room.tile != nil {
+			h.leave(client, room)
+		}
+	}
+
+	viewport := client.Viewport()
+	if viewport != nil {
+		span := tileSpan(*viewport)
+		if (span.X2-span.X1)*(span.Y2-span.Y1) <= maxRegionRooms {
+			client.tiles = span
+			for col := span.X1; col < span.X2; col++ {
+				for row := span.Y1; row < span.Y2; row++ {
+					tile := roomTile{col, row}
+					room := h.regionRooms[tile]
+					if room == nil {
+						room = newRoom(fmt.Sprintf("region:%d:%d", col, row), false)
+						room.tile = &tile
+						h.regionRooms[tile] = room
+					}
+					h.join(client, room)
+				}
+			}
+			return
+		}
+	}
+	client.tiles = Region{}
+	h.join(client, h.gridRoom)
+}
+
+// chatRoom returns the room of a chat channel, creating it if create is set.
+// The hub's mutex must be held, for writing if create is set.
+func (h *Hub) chatRoom(channel string, create bool) *Room {
+	name := chatRoomPrefix + channel
+	room := h.rooms[name]
+	if room == nil && create {
+		room = newRoom(name, false)
+		h.rooms[name] = room
+	}
+	return room
+}
+
+// route returns the clients a broadcast is for, batched by fan-out worker.
+// The hub's mutex must be held. Messages for a room go to its members,
+// messages for a region to the members of the grid room and of the region
+// rooms it covers watching the region, the others to the canvas room.
+func (h *Hub) route(message *outbound) [][]recipient {
+	batches := h.fanout.batches()
+	switch {
+	case message.room != "":
+		if room := h.rooms[message.room]; room != nil {
+			h.routeRoom(batches, room, message, nil)
+		}
+
+	case message.region == nil:
+		h.routeRoom(batches, h.canvasRoom, message, nil)
+
+	default:
+		region := *message.region
+		h.routeRoom(batches, h.gridRoom, message, func(c *Client) bool { return c.watches(region) })
+
+		span := tileSpan(region)
+		for col := span.X1; col < span.X2; col++ {
+			for row := span.Y1; row < span.Y2; row++ {
+				room := h.regionRooms[roomTile{col, row}]
+				if room == nil {
+					continue
+				}
+				h.routeRoom(batches, room, message, func(c *Client) bool {
+					// A client in several of the rooms gets the message from
+					// the first one it shares with the region
+					first := roomTile{max(span.X1, c.tiles.X1), max(span.Y1, c.tiles.Y1)}
+					return first == roomTile{col, row} && c.watches(region)
+				})
+			}
+		}
+	}
+	return batches
+}
+
+// routeRoom adds the room's members accepted by accept (nil accepts all)
+// other than the broadcast's sender to the batches
+func (h *Hub) routeRoom(batches [][]recipient, room *Room, message *outbound, accept func(*Client) bool) {
+	if len(room.members) == 0 {
+		return
+	}
+	room.broadcasts.Add(1)
+	for client := range room.members {
+		if client == message.from || (accept != nil && !accept(client)) {
+			continue
+		}
+		h.fanout.add(batches, client, room)
+	}
+}
@@ -0,0 +1,70 @@
+package ws
+
+import "testing"
+
+func TestTileSpan(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		region Region
+		want   Region
+	}{
+		{"single cell", CellRegion(0, 0), Region{X1: 0, Y1: 0, X2: 1, Y2: 1}},
+		{"last cell of a room", CellRegion(255, 255), Region{X1: 0, Y1: 0, X2: 1, Y2: 1}},
+		{"first cell of the next room", CellRegion(256, 0), Region{X1: 1, Y1: 0, X2: 2, Y2: 1}},
+		{"exactly one room", Region{X1: 256, Y1: 256, X2: 512, Y2: 512}, Region{X1: 1, Y1: 1, X2: 2, Y2: 2}},
+		{"straddling rooms", Region{X1: 250, Y1: 10, X2: 260, Y2: 600}, Region{X1: 0, Y1: 0, X2: 2, Y2: 3}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tileSpan(tc.region); got != tc.want {
+				t.Errorf("tileSpan(%+v) = %+v, want %+v", tc.region, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRouteDeliversOnce(t *testing.T) {
+	hub := NewHub(NewGridState(2048, 2048), HubConfig{})
+	client := func(viewport *Region) *Client {
+		c := NewClient(hub, testConn(t), "192.0.2.1", nil)
+		c.viewport = viewport
+		hub.addClient(c)
+		return c
+	}
+	whole := client(nil)
+	// Watches 2x2 region rooms
+	corner := client(&Region{X1: 200, Y1: 200, X2: 300, Y2: 300})
+	// Watches more region rooms than a viewport joins, so is in the grid room
+	wide := client(&Region{X1: 0, Y1: 0, X2: 2048, Y2: 1100})
+	far := client(&Region{X1: 1500, Y1: 1500, X2: 1600, Y2: 1600})
+
+	for _, tc := range []struct {
+		name   string
+		region *Region
+		from   *Client
+		want   map[*Client]int
+	}{
+		{"whole canvas", nil, nil, map[*Client]int{whole: 1, corner: 1, wide: 1, far: 1}},
+		{"region spanning the rooms of a viewport", &Region{X1: 250, Y1: 250, X2: 260, Y2: 260}, nil, map[*Client]int{whole: 1, corner: 1, wide: 1}},
+		{"cell in a later room of a viewport", &Region{X1: 290, Y1: 290, X2: 291, Y2: 291}, nil, map[*Client]int{whole: 1, corner: 1, wide: 1}},
+		{"region outside the viewports", &Region{X1: 1800, Y1: 100, X2: 1900, Y2: 200}, nil, map[*Client]int{whole: 1, wide: 1}},
+		{"sender skipped", &Region{X1: 1550, Y1: 1550, X2: 1551, Y2: 1551}, far, map[*Client]int{whole: 1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hub.mu.RLock()
+			batches := hub.route(&outbound{region: tc.region, from: tc.from})
+			hub.mu.RUnlock()
+
+			got := make(map[*Client]int)
+			for _, batch := range batches {
+				for _, r := range batch {
+					got[r.client]++
+				}
+			}
+			for _, c := range []*Client{whole, corner, wide, far} {
+				if got[c] != tc.want[c] {
+					t.Errorf("client with viewport %+v got the message %d times, want %d", c.viewport, got[c], tc.want[c])
+				}
+			}
+		})
+	}
+}
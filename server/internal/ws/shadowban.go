@@ -0,0 +1,142 @@
+package ws
+
+import (
+	"log/slog"
+	"net"
+	"sort"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// ShadowBanTarget returns the canonical form of a shadow ban target: CIDR
+// notation for an IP address or CIDR range, the user ID itself otherwise
+func ShadowBanTarget(target string) string {
+	if network, err := ParseBanTarget(target); err == nil {
+		return network.String()
+	}
+	return target
+}
+
+// ShadowBan shadow-bans a network or user (a target from ShadowBanTarget): their
+// paints pass every check and are acknowledged, but only their own
+// connections see them, and they are neither persisted nor broadcast
+func (h *Hub) ShadowBan(target string) {
+	h.shadowMu.Lock()
+	if _, network, err := net.ParseCIDR(target); err == nil {
+		h.shadowNetworks[target] = network
+	} else {
+		h.shadowUsers[target] = true
+	}
+	h.shadowMu.Unlock()
+	slog.Info("Shadow-banned", "canvas", h.config.Canvas, "target", target)
+}
+
+// ShadowUnban lifts a shadow ban
+func (h *Hub) ShadowUnban(target string) {
+	h.shadowMu.Lock()
+	delete(h.shadowNetworks, target)
+	delete(h.shadowUsers, target)
+	h.shadowMu.Unlock()
+	slog.Info("Lifted shadow ban", "canvas", h.config.Canvas, "target", target)
+}
+
+// ShadowBans returns the shadow ban targets, sorted
+func (h *Hub) ShadowBans() []string {
+	h.shadowMu.RLock()
+	defer h.shadowMu.RUnlock()
+
+	targets := make([]string, 0, len(h.shadowNetworks)+len(h.shadowUsers))
+	for target := range h.shadowNetworks {
+		targets = append(targets, target)
+	}
+	for target := range h.shadowUsers {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// IsShadowBanned reports whether the paints of an actor placing from an IP
+// are shadowed, because either is shadow-banned
+func (h *Hub) IsShadowBanned(ip, actor string) bool {
+	h.shadowMu.RLock()
+	defer h.shadowMu.RUnlock()
+	if h.shadowUsers[actor] {
+		return true
+	}
+	if len(h.shadowNetworks) == 0 {
+		return false
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range h.shadowNetworks {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// shadowCells returns the cells of pixels a shadow-banned actor would change,
+// and shows them to the actor's connections only, leaving the grid as is
+func (h *Hub) shadowCells(pixels []model.Pixel, actor string) []model.Pixel {
+	var changed []model.Pixel
+	for _, p := range pixels {
+		if h.grid.GetCell(p.X, p.Y) != (CellState{Active: p.Active, Color: p.Color}) {
+			changed = append(changed, p)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	h.mu.RLock()
+	var own []*Client
+	for client := range h.canvasRoom.members {
+		if client.actor() == actor {
+			own = append(own, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range own {
+		client.sendChanges(changed)
+	}
+	slog.Debug("Shadowed cells", "canvas", h.config.Canvas, "changed", len(changed), "actor", actor)
+	return changed
+}
+
+// shadowToggle returns the state a toggle by a shadow-banned actor would give
+// a cell, like GridState.ToggleCell, showing it to the actor's connections only
+func (h *Hub) shadowToggle(pixel model.Pixel, color model.Color, actor string) model.Pixel {
+	pixel.Active, pixel.Color = !h.grid.GetCell(pixel.X, pixel.Y).Active, color
+	if !pixel.Active {
+		pixel.Color = model.White
+	}
+	h.shadowCells([]model.Pixel{pixel}, actor)
+	return pixel
+}
+
+// sendChanges sends cell changes to the client alone, encoded like the
+// broadcasts of broadcastChanges
+func (c *Client) sendChanges(changed []model.Pixel) {
+	var err error
+	if len(changed) == 1 {
+		p := changed[0]
+		err = c.sendMessage(BroadcastCellUpdate{Type: "u", X: p.X, Y: p.Y, Active: activeInt(p.Active), Color: p.Color, Team: p.Team})
+	} else {
+		for start := 0; start < len(changed) && err == nil; start += maxBroadcastBatch {
+			end := min(start+maxBroadcastBatch, len(changed))
+			batch := make([]BatchCell, 0, end-start)
+			for _, p := range changed[start:end] {
+				batch = append(batch, NewBatchCell(p))
+			}
+			err = c.sendMessage(BroadcastBatchUpdate{Type: "b", Cells: batch, Team: changed[start].Team})
+		}
+	}
+	if err != nil {
+		c.logger.Error("Failed to send cell changes", "err", err)
+	}
+}
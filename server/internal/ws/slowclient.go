@@ -0,0 +1,97 @@
+package ws
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// SlowClientPolicy decides what happens to a broadcast when a client's send
+// buffer is full
+type SlowClientPolicy string
+
+// Slow client policies
+const (
+	// Discard the oldest queued message to make room for the broadcast
+	SlowClientDropOldest SlowClientPolicy = "drop_oldest"
+
+	// Discard the broadcast, keeping the queued messages such as the initial
+	// state. The client misses the update until it reconnects.
+	SlowClientDropUpdates SlowClientPolicy = "drop_updates"
+
+	// Send a close frame telling the client it fell behind and disconnect it
+	SlowClientClose SlowClientPolicy = "close"
+)
+
+// SlowClientStats counts the outcomes of broadcasts to clients with a full
+// send buffer since the hub started
+type SlowClientStats struct {
+	Policy SlowClientPolicy `json:"policy"`
+
+	// Queued messages discarded to make room under drop_oldest
+	DroppedOldest int64 `json:"dropped_oldest"`
+
+	// Broadcasts discarded under drop_updates
+	DroppedUpdates int64 `json:"dropped_updates"`
+
+	// Clients disconnected under close
+	Closed int64 `json:"closed"`
+}
+
+// slowClients holds the counters behind SlowClientStats
+type slowClients struct {
+	droppedOldest, droppedUpdates, closed atomic.Int64
+}
+
+// SlowClients returns the hub's slow client policy and its outcomes
+func (h *Hub) SlowClients() SlowClientStats {
+	return SlowClientStats{
+		Policy:         h.config.SlowClient,
+		DroppedOldest:  h.slow.droppedOldest.Load(),
+		DroppedUpdates: h.slow.droppedUpdates.Load(),
+		Closed:         h.slow.closed.Load(),
+	}
+}
+
+// handleSlow applies the slow client policy to a broadcast that didn't fit
+// in the client's lane, reporting whether it was queued after all. The hub's
+// mutex must be held.
+func (h *Hub) handleSlow(client *Client, priority Priority, data []byte) bool {
+	lane := client.lanes[priority]
+	switch h.config.SlowClient {
+	case SlowClientDropOldest:
+		select {
+		case <-lane:
+			h.slow.droppedOldest.Add(1)
+		default:
+		}
+		select {
+		case lane <- data:
+			return true
+		default:
+			// Refilled meanwhile, the broadcast goes instead
+			h.slow.droppedOldest.Add(1)
+			return false
+		}
+
+	case SlowClientDropUpdates:
+		h.slow.droppedUpdates.Add(1)
+		return false
+
+	default:
+		if !client.evicted.CompareAndSwap(false, true) {
+			// Already on its way out
+			return false
+		}
+		h.slow.closed.Add(1)
+		client.logger.Warn("Disconnecting client: send buffer full")
+		go func() {
+			client.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "client too slow"),
+				time.Now().Add(writeWait))
+			h.Unregister(client)
+		}()
+		return false
+	}
+}
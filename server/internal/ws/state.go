@@ -2,116 +2,467 @@ package ws
 
 import (
 	"sync"
+	"sync/atomic"
 
-	"github.com/million_grids/server/internal/db"
+	"github.com/million_grids/server/internal/model"
 )
 
 const (
-	// GridSize defines the dimensions of the grid (1000x1000)
-	GridSize = 1000
+	// ChunkSize is the width and height of the blocks the grid is stored in
+	ChunkSize = 64
+
+	// activeBit marks a stored cell as active; the low 24 bits hold its color
+	activeBit = 1 << 24
+
+	// numStripes is the number of locks the chunks are spread over
+	numStripes = 64
 )
 
+// Journal durably records cell changes before the grid stores them. It is
+// called with the locks of the changed cells held, so no one sees the changes
+// before it returns, and changes it fails to record are rolled back. A nil
+// Journal records nothing.
+type Journal func(changed []model.Pixel) error
+
+// record passes the changes to the journal, if there are any and a journal
+func (j Journal) record(changed []model.Pixel) error {
+	if j == nil || len(changed) == 0 {
+		return nil
+	}
+	return j(changed)
+}
+
 // CellState holds the state of a single cell (active status and color)
 type CellState struct {
 	Active bool
-	Color  string
+	Color  model.Color
+}
+
+// inactiveCell is the state of every cell that has never been painted
+var inactiveCell = CellState{Active: false, Color: model.White}
+
+// normalized returns the state as the grid stores it: inactive cells are white
+func (s CellState) normalized() CellState {
+	if !s.Active {
+		return inactiveCell
+	}
+	return s
+}
+
+// stripe guards the chunks whose slot maps to it and indexes their active cells
+type stripe struct {
+	mu sync.RWMutex
+
+	// Positions (x*height + y) of the active cells, so listing them doesn't scan the grid
+	active map[int]struct{}
+
+	// Number of active cells of each color
+	colors map[model.Color]int
 }
 
-// GridState holds the in-memory state of the grid (active/inactive with color)
+// chunk is a ChunkSize x ChunkSize block of cells, each activeBit | color (0 = inactive)
+type chunk struct {
+	cells [ChunkSize * ChunkSize]uint32
+
+	// Number of active cells, so empty chunks can be released
+	active int
+}
+
+// GridState holds the in-memory state of the grid (active/inactive with color).
+// Cells are stored sparsely in chunks that are allocated when their first cell
+// is activated and released when their last cell is cleared, with each cell
+// packed into four bytes. Chunks are guarded by numStripes locks so writes to
+// different regions don't contend.
 type GridState struct {
-	cells [GridSize][GridSize]CellState
-	mu    sync.RWMutex
+	width  int
+	height int
+
+	// Chunks in column-major order (nil while all their cells are inactive).
+	// Slot i is guarded by stripes[i%numStripes].
+	chunks  []*chunk
+	chunksX int
+	chunksY int
+
+	stripes [numStripes]stripe
+
+	// Counter bumped by every change, and its value at the last change of each
+	// chunk slot (read without the stripe locks, see Version)
+	generation atomic.Uint64
+	versions   []atomic.Uint64
+}
+
+// NewGridState creates a width x height grid with every cell inactive
+func NewGridState(width, height int) *GridState {
+	g := &GridState{
+		width:   width,
+		height:  height,
+		chunksX: (width + ChunkSize - 1) / ChunkSize,
+		chunksY: (height + ChunkSize - 1) / ChunkSize,
+	}
+	g.versions = make([]atomic.Uint64, g.chunksX*g.chunksY)
+	g.Initialize()
+	return g
+}
+
+// Width returns the number of columns of the grid
+func (g *GridState) Width() int {
+	return g.width
+}
+
+// Height returns the number of rows of the grid
+func (g *GridState) Height() int {
+	return g.height
 }
 
-// Global grid instance
-var Grid = &GridState{}
+// Bounds returns the region covering the whole grid
+func (g *GridState) Bounds() Region {
+	return Region{X1: 0, Y1: 0, X2: g.width, Y2: g.height}
+}
+
+// InBounds reports whether (x, y) is a cell of the grid
+func (g *GridState) InBounds(x, y int) bool {
+	return x >= 0 && x < g.width && y >= 0 && y < g.height
+}
 
 // Initialize sets up the grid with all cells inactive (false)
 func (g *GridState) Initialize() {
-	g.mu.Lock()
-	defer g.mu.Unlock()
+	for i := range g.stripes {
+		g.stripes[i].mu.Lock()
+	}
+	defer func() {
+		for i := range g.stripes {
+			g.stripes[i].mu.Unlock()
+		}
+	}()
 
 	// All cells default to inactive with white color
-	for x := 0; x < GridSize; x++ {
-		for y := 0; y < GridSize; y++ {
-			g.cells[x][y] = CellState{Active: false, Color: "#FFFFFF"}
+	g.chunks = make([]*chunk, g.chunksX*g.chunksY)
+	for i := range g.stripes {
+		g.stripes[i].active = make(map[int]struct{})
+		g.stripes[i].colors = make(map[model.Color]int)
+	}
+	version := g.generation.Add(1)
+	for i := range g.versions {
+		g.versions[i].Store(version)
+	}
+}
+
+// Version returns a number that changes whenever a cell in the region changes,
+// so renderings of the region can be cached until it does. It is tracked per
+// chunk, so changes to cells near the region may change it too.
+func (g *GridState) Version(region Region) uint64 {
+	region = region.Clamp(g.width, g.height)
+	if region.Empty() {
+		return 0
+	}
+	var version uint64
+	for cx := region.X1 / ChunkSize; cx <= (region.X2-1)/ChunkSize; cx++ {
+		for cy := region.Y1 / ChunkSize; cy <= (region.Y2-1)/ChunkSize; cy++ {
+			version = max(version, g.versions[cx*g.chunksY+cy].Load())
 		}
 	}
+	return version
 }
 
 // LoadFromDB populates the grid from database pixels
-func (g *GridState) LoadFromDB(pixels []db.Pixel) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
+func (g *GridState) LoadFromDB(pixels []model.Pixel) {
 	for _, p := range pixels {
-		if p.X >= 0 && p.X < GridSize && p.Y >= 0 && p.Y < GridSize {
-			color := p.Color
-			if color == "" {
-				color = "#FFFFFF"
-			}
-			g.cells[p.X][p.Y] = CellState{Active: p.Active, Color: color}
+		if g.InBounds(p.X, p.Y) {
+			g.SetCell(p.X, p.Y, p.Active, p.Color)
 		}
 	}
 }
 
 // GetCell returns the cell state at the given coordinates
 func (g *GridState) GetCell(x, y int) CellState {
-	g.mu.RLock()
-	defer g.mu.RUnlock()
+	if !g.InBounds(x, y) {
+		return inactiveCell
+	}
+
+	st := g.stripeOf(x, y)
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return g.get(x, y)
+}
+
+// SetCell updates the cell state at the given coordinates and reports whether it changed
+func (g *GridState) SetCell(x, y int, active bool, color model.Color) bool {
+	if !g.InBounds(x, y) {
+		return false
+	}
+
+	st := g.stripeOf(x, y)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	next := CellState{Active: active, Color: color}.normalized()
+	if g.get(x, y) == next {
+		return false
+	}
+	g.set(x, y, next)
+	return true
+}
+
+// SetCells applies a batch of cell states atomically, holding the locks of
+// every stripe it touches, and returns the pixels that actually changed.
+// Callers must validate coordinates beforehand.
+func (g *GridState) SetCells(pixels []model.Pixel) []model.Pixel {
+	changed, _, _ := g.SwapCells(pixels, nil)
+	return changed
+}
+
+// SwapCells is SetCells also returning the state each changed pixel replaced,
+// recording the changes with journal before they are visible. If the journal
+// fails the grid is left unchanged and its error returned.
+func (g *GridState) SwapCells(pixels []model.Pixel, journal Journal) ([]model.Pixel, []CellState, error) {
+	// Lock the stripes in ascending order so concurrent batches can't deadlock
+	var touched [numStripes]bool
+	for _, p := range pixels {
+		if g.InBounds(p.X, p.Y) {
+			touched[g.stripeIndex(p.X, p.Y)] = true
+		}
+	}
+	for i := range touched {
+		if touched[i] {
+			g.stripes[i].mu.Lock()
+			defer g.stripes[i].mu.Unlock()
+		}
+	}
 
-	if x < 0 || x >= GridSize || y < 0 || y >= GridSize {
-		return CellState{Active: false, Color: "#FFFFFF"}
+	var changed []model.Pixel
+	var previous []CellState
+	for _, p := range pixels {
+		if !g.InBounds(p.X, p.Y) {
+			continue
+		}
+		current, next := g.get(p.X, p.Y), CellState{Active: p.Active, Color: p.Color}.normalized()
+		if current == next {
+			continue
+		}
+		g.set(p.X, p.Y, next)
+		p.Color = next.Color
+		changed = append(changed, p)
+		previous = append(previous, current)
+	}
+	if err := journal.record(changed); err != nil {
+		// Undo in reverse, in case the batch changed a cell twice
+		for i := len(changed) - 1; i >= 0; i-- {
+			g.set(changed[i].X, changed[i].Y, previous[i])
+		}
+		return nil, nil, err
 	}
-	return g.cells[x][y]
+	return changed, previous, nil
 }
 
-// SetCell updates the cell state at the given coordinates
-func (g *GridState) SetCell(x, y int, active bool, color string) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
+// CompareAndSetCell sets a cell to next if it is in state old, recording the
+// change with journal first, and reports whether it did
+func (g *GridState) CompareAndSetCell(x, y int, old, next CellState, journal Journal) (bool, error) {
+	if !g.InBounds(x, y) {
+		return false, nil
+	}
+
+	st := g.stripeOf(x, y)
+	st.mu.Lock()
+	defer st.mu.Unlock()
 
-	if x >= 0 && x < GridSize && y >= 0 && y < GridSize {
-		g.cells[x][y] = CellState{Active: active, Color: color}
+	next = next.normalized()
+	if g.get(x, y) != old.normalized() {
+		return false, nil
 	}
+	if err := journal.record([]model.Pixel{{X: x, Y: y, Active: next.Active, Color: next.Color}}); err != nil {
+		return false, err
+	}
+	g.set(x, y, next)
+	return true, nil
 }
 
-// ToggleCell toggles the cell with a color and returns the new state
-func (g *GridState) ToggleCell(x, y int, color string) (bool, string) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
+// ToggleCell toggles the cell with a color, recording the change with journal
+// first, and returns the new state and the state it replaced
+func (g *GridState) ToggleCell(x, y int, color model.Color, journal Journal) (bool, model.Color, CellState, error) {
+	if !g.InBounds(x, y) {
+		return false, model.White, CellState{}, nil
+	}
 
-	if x >= 0 && x < GridSize && y >= 0 && y < GridSize {
-		current := g.cells[x][y]
-		newActive := !current.Active
-		newColor := color
-		if !newActive {
-			// When turning off, reset to white
-			newColor = "#FFFFFF"
+	st := g.stripeOf(x, y)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	current := g.get(x, y)
+	newActive := !current.Active
+	newColor := color
+	if !newActive {
+		// When turning off, reset to white
+		newColor = model.White
+	}
+	if err := journal.record([]model.Pixel{{X: x, Y: y, Active: newActive, Color: newColor}}); err != nil {
+		return false, model.White, CellState{}, err
+	}
+	g.set(x, y, CellState{Active: newActive, Color: newColor})
+	return newActive, newColor, current, nil
+}
+
+// GetActiveCells returns a list of all active cell coordinates with colors
+// (sparse format), in no particular order. It runs in time proportional to
+// the number of active cells.
+func (g *GridState) GetActiveCells() []model.Pixel {
+	var active []model.Pixel
+	for i := range g.stripes {
+		st := &g.stripes[i]
+		st.mu.RLock()
+		for pos := range st.active {
+			x, y := pos/g.height, pos%g.height
+			active = append(active, model.Pixel{
+				X:      x,
+				Y:      y,
+				Active: true,
+				Color:  g.get(x, y).Color,
+			})
 		}
-		g.cells[x][y] = CellState{Active: newActive, Color: newColor}
-		return newActive, newColor
-	}
-	return false, "#FFFFFF"
-}
-
-// GetActiveCells returns a list of all active cell coordinates with colors (sparse format)
-func (g *GridState) GetActiveCells() []db.Pixel {
-	g.mu.RLock()
-	defer g.mu.RUnlock()
-
-	var active []db.Pixel
-	for x := 0; x < GridSize; x++ {
-		for y := 0; y < GridSize; y++ {
-			if g.cells[x][y].Active {
-				active = append(active, db.Pixel{
-					X:      x,
-					Y:      y,
-					Active: true,
-					Color:  g.cells[x][y].Color,
-				})
+		st.mu.RUnlock()
+	}
+	return active
+}
+
+// ActiveCount returns the number of active cells
+func (g *GridState) ActiveCount() int {
+	total := 0
+	for i := range g.stripes {
+		st := &g.stripes[i]
+		st.mu.RLock()
+		total += len(st.active)
+		st.mu.RUnlock()
+	}
+	return total
+}
+
+// ColorCounts returns the number of active cells of each color. It runs in
+// time proportional to the number of colors in use.
+func (g *GridState) ColorCounts() map[model.Color]int {
+	counts := make(map[model.Color]int)
+	for i := range g.stripes {
+		st := &g.stripes[i]
+		st.mu.RLock()
+		for color, n := range st.colors {
+			counts[color] += n
+		}
+		st.mu.RUnlock()
+	}
+	return counts
+}
+
+// GetActiveCellsInRegion returns the active cells with colors inside the
+// half-open rectangle [x0, x1) x [y0, y1), clamped to the grid bounds
+func (g *GridState) GetActiveCellsInRegion(x0, y0, x1, y1 int) []model.Pixel {
+	x0, y0 = max(x0, 0), max(y0, 0)
+	x1, y1 = min(x1, g.width), min(y1, g.height)
+	if x0 >= x1 || y0 >= y1 {
+		return nil
+	}
+
+	// Only visit the allocated chunks overlapping the region
+	var active []model.Pixel
+	for cx := x0 / ChunkSize; cx <= (x1-1)/ChunkSize; cx++ {
+		for cy := y0 / ChunkSize; cy <= (y1-1)/ChunkSize; cy++ {
+			slot := cx*g.chunksY + cy
+			st := &g.stripes[slot%numStripes]
+			st.mu.RLock()
+			c := g.chunks[slot]
+			if c == nil {
+				st.mu.RUnlock()
+				continue
 			}
+			baseX, baseY := cx*ChunkSize, cy*ChunkSize
+			for x := max(x0, baseX); x < min(x1, baseX+ChunkSize); x++ {
+				for y := max(y0, baseY); y < min(y1, baseY+ChunkSize); y++ {
+					if cell := c.cells[(x-baseX)*ChunkSize+(y-baseY)]; cell != 0 {
+						active = append(active, model.Pixel{
+							X:      x,
+							Y:      y,
+							Active: true,
+							Color:  model.Color(cell &^ activeBit),
+						})
+					}
+				}
+			}
+			st.mu.RUnlock()
 		}
 	}
 	return active
 }
+
+// locate returns the chunk slot and offset within the chunk of the cell (x, y)
+func (g *GridState) locate(x, y int) (int, int) {
+	slot := (x/ChunkSize)*g.chunksY + y/ChunkSize
+	offset := (x%ChunkSize)*ChunkSize + y%ChunkSize
+	return slot, offset
+}
+
+// stripeIndex returns the index of the stripe guarding the cell (x, y)
+func (g *GridState) stripeIndex(x, y int) int {
+	slot, _ := g.locate(x, y)
+	return slot % numStripes
+}
+
+// stripeOf returns the stripe guarding the cell (x, y)
+func (g *GridState) stripeOf(x, y int) *stripe {
+	return &g.stripes[g.stripeIndex(x, y)]
+}
+
+// get returns the state of an in-bounds cell. Callers must hold its stripe lock.
+func (g *GridState) get(x, y int) CellState {
+	slot, offset := g.locate(x, y)
+	c := g.chunks[slot]
+	if c == nil || c.cells[offset] == 0 {
+		return inactiveCell
+	}
+	return CellState{Active: true, Color: model.Color(c.cells[offset] &^ activeBit)}
+}
+
+// set stores the state of an in-bounds cell, allocating or releasing its chunk
+// as needed. Inactive cells don't keep a color. Callers must hold its stripe write lock.
+func (g *GridState) set(x, y int, state CellState) {
+	slot, offset := g.locate(x, y)
+	st := &g.stripes[slot%numStripes]
+	c := g.chunks[slot]
+
+	var cell uint32
+	if state.Active {
+		cell = activeBit | uint32(state.Color)&0xFFFFFF
+	}
+	if c == nil {
+		if cell == 0 {
+			return
+		}
+		c = &chunk{}
+		g.chunks[slot] = c
+	}
+
+	switch previous := c.cells[offset]; {
+	case previous == 0 && cell != 0:
+		c.active++
+		st.active[x*g.height+y] = struct{}{}
+	case previous != 0 && cell == 0:
+		c.active--
+		delete(st.active, x*g.height+y)
+	}
+	if previous := c.cells[offset]; previous != 0 {
+		st.uncount(model.Color(previous &^ activeBit))
+	}
+	if cell != 0 {
+		st.colors[state.Color&0xFFFFFF]++
+	}
+	c.cells[offset] = cell
+	g.versions[slot].Store(g.generation.Add(1))
+
+	if c.active == 0 {
+		g.chunks[slot] = nil
+	}
+}
+
+// uncount removes an active cell of the color from the stripe's color counts
+func (st *stripe) uncount(color model.Color) {
+	st.colors[color]--
+	if st.colors[color] == 0 {
+		delete(st.colors, color)
+	}
+}
@@ -0,0 +1,298 @@
+package ws
+
+import (
+	"cmp"
+	"maps"
+	"math/rand"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// Side length of the grids the benchmarks paint on
+const benchGridSize = 1024
+
+const benchColor model.Color = 0xFF0000
+
+// benchRegions returns the top-left corner of a ChunkSize x ChunkSize region
+// for each goroutine of a parallel benchmark: the same region for all when
+// overlapping, a region of their own otherwise
+func benchRegions(overlapping bool) func() (int, int) {
+	var next atomic.Int64
+	perRow := benchGridSize / ChunkSize
+	return func() (int, int) {
+		if overlapping {
+			return 0, 0
+		}
+		i := int(next.Add(1)-1) % (perRow * perRow)
+		return i % perRow * ChunkSize, i / perRow * ChunkSize
+	}
+}
+
+// benchmarkParallel runs paint from parallel goroutines, each painting random
+// cells of its region
+func benchmarkParallel(b *testing.B, overlapping bool, paint func(x, y int)) {
+	region := benchRegions(overlapping)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		x0, y0 := region()
+		r := rand.New(rand.NewSource(int64(x0*benchGridSize + y0)))
+		for pb.Next() {
+			paint(x0+r.Intn(ChunkSize), y0+r.Intn(ChunkSize))
+		}
+	})
+}
+
+func BenchmarkGridStateSetCell(b *testing.B) {
+	for _, bc := range []struct {
+		name        string
+		overlapping bool
+	}{{"disjoint", false}, {"overlapping", true}} {
+		b.Run(bc.name, func(b *testing.B) {
+			g := NewGridState(benchGridSize, benchGridSize)
+			benchmarkParallel(b, bc.overlapping, func(x, y int) {
+				g.SetCell(x, y, true, benchColor)
+			})
+		})
+	}
+}
+
+func BenchmarkGridStateToggleCell(b *testing.B) {
+	for _, bc := range []struct {
+		name        string
+		overlapping bool
+	}{{"disjoint", false}, {"overlapping", true}} {
+		b.Run(bc.name, func(b *testing.B) {
+			g := NewGridState(benchGridSize, benchGridSize)
+			benchmarkParallel(b, bc.overlapping, func(x, y int) {
+				g.ToggleCell(x, y, benchColor, nil)
+			})
+		})
+	}
+}
+
+// BenchmarkGridStateSingleLock serializes the writes through one mutex, as the
+// grid did before its lock was striped, for comparison with
+// BenchmarkGridStateSetCell/disjoint
+func BenchmarkGridStateSingleLock(b *testing.B) {
+	g := NewGridState(benchGridSize, benchGridSize)
+	var mu sync.Mutex
+	benchmarkParallel(b, false, func(x, y int) {
+		mu.Lock()
+		g.SetCell(x, y, true, benchColor)
+		mu.Unlock()
+	})
+}
+
+// BenchmarkGridStateMixed reads cells and lists the active ones while
+// writers paint, as the initial state of new clients does under load
+func BenchmarkGridStateMixed(b *testing.B) {
+	g := NewGridState(benchGridSize, benchGridSize)
+	var ops atomic.Int64
+	benchmarkParallel(b, false, func(x, y int) {
+		switch n := ops.Add(1); {
+		case n%1000 == 0:
+			g.GetActiveCellsInRegion(x-x%ChunkSize, y-y%ChunkSize, x-x%ChunkSize+ChunkSize, y-y%ChunkSize+ChunkSize)
+		case n%2 == 0:
+			g.GetCell(x, y)
+		default:
+			g.SetCell(x, y, true, benchColor)
+		}
+	})
+}
+
+func TestInactiveCellsIgnoreColor(t *testing.T) {
+	g := NewGridState(16, 16)
+	if g.SetCell(1, 1, false, 0xFF0000) {
+		t.Error("SetCell reported a change deactivating an inactive cell")
+	}
+	changed, _, err := g.SwapCells([]model.Pixel{{X: 1, Y: 1, Active: false, Color: 0xFF0000}}, nil)
+	if err != nil || len(changed) != 0 {
+		t.Errorf("SwapCells changed %v (err %v) deactivating an inactive cell", changed, err)
+	}
+
+	g.SetCell(2, 2, true, 0xFF0000)
+	changed, _, _ = g.SwapCells([]model.Pixel{{X: 2, Y: 2, Active: false, Color: 0xFF0000}}, nil)
+	if len(changed) != 1 || changed[0].Color != model.White {
+		t.Errorf("SwapCells returned %v deactivating a cell, want it white", changed)
+	}
+}
+
+// gridOp is a change applied to a grid by the table tests
+type gridOp struct {
+	op     string // "set", "toggle" or "swap"
+	x, y   int
+	active bool // For set and swap
+	color  model.Color
+}
+
+func TestGridStateChanges(t *testing.T) {
+	const red, blue model.Color = 0xFF0000, 0x0000FF
+	for _, tc := range []struct {
+		name    string
+		ops     []gridOp
+		changed []bool // Whether each op changed the grid
+		active  []model.Pixel
+		colors  map[model.Color]int
+	}{
+		{
+			name:    "set and repaint",
+			ops:     []gridOp{{op: "set", x: 1, y: 2, active: true, color: red}, {op: "set", x: 1, y: 2, active: true, color: red}, {op: "set", x: 1, y: 2, active: true, color: blue}},
+			changed: []bool{true, false, true},
+			active:  []model.Pixel{{X: 1, Y: 2, Active: true, Color: blue}},
+			colors:  map[model.Color]int{blue: 1},
+		},
+		{
+			name:    "toggle on and off",
+			ops:     []gridOp{{op: "toggle", x: 3, y: 3, color: red}, {op: "toggle", x: 4, y: 4, color: blue}, {op: "toggle", x: 3, y: 3, color: blue}},
+			changed: []bool{true, true, true},
+			active:  []model.Pixel{{X: 4, Y: 4, Active: true, Color: blue}},
+			colors:  map[model.Color]int{blue: 1},
+		},
+		{
+			name:    "swap across chunks",
+			ops:     []gridOp{{op: "swap", x: 0, y: 0, active: true, color: red}, {op: "swap", x: 200, y: 130, active: true, color: red}, {op: "swap", x: 0, y: 0, active: false}},
+			changed: []bool{true, true, true},
+			active:  []model.Pixel{{X: 200, Y: 130, Active: true, Color: red}},
+			colors:  map[model.Color]int{red: 1},
+		},
+		{
+			name:    "out of bounds",
+			ops:     []gridOp{{op: "set", x: -1, y: 0, active: true, color: red}, {op: "toggle", x: 256, y: 0, color: red}, {op: "swap", x: 0, y: 256, active: true, color: red}},
+			changed: []bool{false, false, false},
+			colors:  map[model.Color]int{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGridState(256, 256)
+			for i, op := range tc.ops {
+				var changed bool
+				switch op.op {
+				case "set":
+					changed = g.SetCell(op.x, op.y, op.active, op.color)
+				case "toggle":
+					_, _, previous, err := g.ToggleCell(op.x, op.y, op.color, nil)
+					if err != nil {
+						t.Fatal(err)
+					}
+					changed = g.InBounds(op.x, op.y) && previous != g.GetCell(op.x, op.y)
+				case "swap":
+					pixels, _, err := g.SwapCells([]model.Pixel{{X: op.x, Y: op.y, Active: op.active, Color: op.color}}, nil)
+					if err != nil {
+						t.Fatal(err)
+					}
+					changed = len(pixels) > 0
+				}
+				if changed != tc.changed[i] {
+					t.Errorf("op %d %+v changed the grid: %t, want %t", i, op, changed, tc.changed[i])
+				}
+			}
+
+			active := g.GetActiveCells()
+			slices.SortFunc(active, func(a, b model.Pixel) int { return cmp.Or(cmp.Compare(a.X, b.X), cmp.Compare(a.Y, b.Y)) })
+			if !slices.Equal(active, tc.active) {
+				t.Errorf("active cells %v, want %v", active, tc.active)
+			}
+			if count := g.ActiveCount(); count != len(tc.active) {
+				t.Errorf("ActiveCount %d, want %d", count, len(tc.active))
+			}
+			if colors := g.ColorCounts(); !maps.Equal(colors, tc.colors) {
+				t.Errorf("ColorCounts %v, want %v", colors, tc.colors)
+			}
+			for slot, c := range g.chunks {
+				if c != nil && c.active == 0 {
+					t.Errorf("chunk %d allocated without active cells", slot)
+				}
+			}
+		})
+	}
+}
+
+func TestSwapCellsReturnsReplacedStates(t *testing.T) {
+	g := NewGridState(16, 16)
+	g.SetCell(1, 1, true, 0xFF0000)
+
+	// The batch changes (1, 1) twice and leaves (2, 2) as it is
+	changed, previous, err := g.SwapCells([]model.Pixel{
+		{X: 1, Y: 1, Active: true, Color: 0x00FF00},
+		{X: 2, Y: 2, Active: false},
+		{X: 1, Y: 1, Active: false},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CellState{{Active: true, Color: 0xFF0000}, {Active: true, Color: 0x00FF00}}
+	if len(changed) != 2 || !slices.Equal(previous, want) {
+		t.Errorf("changed %v replacing %v, want 2 changes replacing %v", changed, previous, want)
+	}
+	if cell := g.GetCell(1, 1); cell != inactiveCell {
+		t.Errorf("cell (1, 1) is %+v after the batch, want inactive", cell)
+	}
+}
+
+func TestGetActiveCellsInRegion(t *testing.T) {
+	g := NewGridState(256, 256)
+	for _, p := range [][2]int{{0, 0}, {63, 63}, {64, 64}, {100, 10}, {255, 255}} {
+		g.SetCell(p[0], p[1], true, benchColor)
+	}
+	for _, tc := range []struct {
+		name           string
+		x0, y0, x1, y1 int
+		want           int
+	}{
+		{"whole grid", 0, 0, 256, 256, 5},
+		{"one chunk", 0, 0, 64, 64, 2},
+		{"across chunks", 60, 60, 70, 70, 2},
+		{"half-open", 0, 0, 63, 63, 1},
+		{"clamped", -10, -10, 1000, 1000, 5},
+		{"empty", 10, 10, 10, 20, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := g.GetActiveCellsInRegion(tc.x0, tc.y0, tc.x1, tc.y1); len(got) != tc.want {
+				t.Errorf("got %d cells %v, want %d", len(got), got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGridStateStripes(t *testing.T) {
+	// 1024 rows make 16 chunks per column, so chunk (cx, cy) is slot cx*16+cy
+	g := NewGridState(1024, 1024)
+	for _, tc := range []struct {
+		x, y   int
+		stripe int
+	}{
+		{0, 0, 0},
+		{63, 63, 0},
+		{0, 64, 1},
+		{64, 0, 16},
+		{200, 130, 50},
+		{256, 0, 0}, // Slot 64 wraps around to the first stripe
+		{1023, 1023, 63},
+	} {
+		if got := g.stripeIndex(tc.x, tc.y); got != tc.stripe {
+			t.Errorf("cell (%d, %d) in stripe %d, want %d", tc.x, tc.y, got, tc.stripe)
+		}
+	}
+
+	// Batches sharing stripes in different orders must not deadlock
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			batch := []model.Pixel{{X: 0, Y: 0, Active: true, Color: benchColor}, {X: 256, Y: 0, Active: true, Color: benchColor}, {X: 64, Y: 0, Active: true, Color: benchColor}}
+			if i%2 == 1 {
+				slices.Reverse(batch)
+			}
+			for range 100 {
+				g.SwapCells(batch, nil)
+				g.SetCells([]model.Pixel{{X: 0, Y: 0}, {X: 256, Y: 0}, {X: 64, Y: 0}})
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,22 @@
+package ws
+
+import (
+	"context"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// PixelStore persists the cell changes applied through the hub, which calls
+// SavePixelsAsync before the changes are visible. SavePixelsAsync must not
+// wait for the database; implementations typically queue writes, at most
+// syncing them to a local log first. An error means the changes weren't
+// recorded and are not applied. ctx carries the trace of the change, which
+// the write may be linked to.
+type PixelStore interface {
+	SavePixelsAsync(ctx context.Context, pixels []model.Pixel) error
+}
+
+// nopStore is the PixelStore used when the hub is created without one
+type nopStore struct{}
+
+func (nopStore) SavePixelsAsync(context.Context, []model.Pixel) error { return nil }
@@ -0,0 +1,184 @@
+package ws
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// TeamJoinMessage is sent by a client to paint for a team, or to leave its
+// team with an empty name
+type TeamJoinMessage struct {
+	Type string `json:"type"`
+	Team string `json:"team"`
+}
+
+// TeamMessage tells a client which team its placements count for (empty for none)
+type TeamMessage struct {
+	Type string `json:"t"`
+	Team string `json:"team"`
+}
+
+// TeamScore is the number of cells a team currently holds
+type TeamScore struct {
+	Team   string `json:"team"`
+	Pixels int    `json:"pixels"`
+}
+
+// TeamsMessage carries the territory of every team, sent to new clients and
+// periodically to every client while it changes
+type TeamsMessage struct {
+	Type   string      `json:"t"`
+	Scores []TeamScore `json:"scores"`
+}
+
+// Territory tracks the team holding each active cell: the team of the last
+// actor that changed it
+type Territory struct {
+	owners map[cellKey]string
+	counts map[string]int
+
+	// Set when the counts change, cleared when they are broadcast
+	changed bool
+
+	mu sync.Mutex
+}
+
+// NewTerritory creates an empty Territory
+func NewTerritory() *Territory {
+	return &Territory{
+		owners: make(map[cellKey]string),
+		counts: make(map[string]int),
+	}
+}
+
+// Apply records cell changes, giving active cells to the team that painted
+// them and releasing cleared cells
+func (t *Territory) Apply(pixels []model.Pixel) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, p := range pixels {
+		key := cellKey{p.X, p.Y}
+		owner, held := t.owners[key]
+		team := p.Team
+		if !p.Active {
+			team = ""
+		}
+		if held && owner == team {
+			continue
+		}
+		if held {
+			t.counts[owner]--
+			delete(t.owners, key)
+		}
+		if team != "" {
+			t.owners[key] = team
+			t.counts[team]++
+		}
+		t.changed = true
+	}
+}
+
+// Owner returns the team holding a cell (empty for none)
+func (t *Territory) Owner(x, y int) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.owners[cellKey{x, y}]
+}
+
+// Count returns the number of cells a team holds
+func (t *Territory) Count(team string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.counts[team]
+}
+
+// Reset releases every cell
+func (t *Territory) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.owners = make(map[cellKey]string)
+	t.counts = make(map[string]int)
+	t.changed = true
+}
+
+// takeChanged reports whether the counts changed since the last call
+func (t *Territory) takeChanged() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	changed := t.changed
+	t.changed = false
+	return changed
+}
+
+// Teams returns the names of the teams clients may join
+func (h *Hub) Teams() []string {
+	return slices.Clone(h.config.Teams)
+}
+
+// HasTeam reports whether clients may join the named team
+func (h *Hub) HasTeam(team string) bool {
+	return slices.Contains(h.config.Teams, team)
+}
+
+// TeamScores returns the cells held by every team, most first
+func (h *Hub) TeamScores() []TeamScore {
+	scores := make([]TeamScore, 0, len(h.config.Teams))
+	for _, team := range h.config.Teams {
+		scores = append(scores, TeamScore{Team: team, Pixels: h.territory.Count(team)})
+	}
+	slices.SortStableFunc(scores, func(a, b TeamScore) int { return cmp.Compare(b.Pixels, a.Pixels) })
+	return scores
+}
+
+// teamError returns the error rejecting a team the canvas doesn't have, or
+// nil if clients may join it. The empty name leaves the current team.
+func (h *Hub) teamError(team string) *PlacementError {
+	if team == "" || h.HasTeam(team) {
+		return nil
+	}
+	return &PlacementError{Code: "unknown_team", Message: fmt.Sprintf("unknown team %q", team)}
+}
+
+// handleTeam switches the team the client's placements count for, replying
+// with its team
+func (c *Client) handleTeam(team string) {
+	if rejected := c.hub.teamError(team); rejected != nil {
+		c.sendError(rejected.Code, rejected.Message)
+		return
+	}
+	c.team = team
+	c.logger.Debug("Client changed team", "team", team)
+	if err := c.sendMessage(TeamMessage{Type: "team", Team: team}); err != nil {
+		c.logger.Error("Failed to send team", "err", err)
+	}
+}
+
+// broadcastTeamScores sends the team scores to every client whenever they
+// changed in the last interval, until the hub stops
+func (h *Hub) broadcastTeamScores(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-ticker.C:
+			if !h.territory.takeChanged() {
+				continue
+			}
+			message, err := json.Marshal(TeamsMessage{Type: "teams", Scores: h.TeamScores()})
+			if err != nil {
+				slog.Error("Failed to marshal team scores", "err", err)
+				continue
+			}
+			h.broadcastLow(message)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package ws
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log/slog"
+	"slices"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+// Template is a target image mapped onto a region of the canvas, for
+// coordinating community art. Which of its cells differ from the canvas is
+// tracked as cells change.
+type Template struct {
+	ID   uint
+	Name string
+
+	// Cells the image covers
+	Region Region
+
+	// Target color of each cell in row-major order, noTarget where the image is
+	// transparent
+	target []model.Color
+
+	// Number of target cells, and the target cells that currently differ
+	total int
+	wrong map[cellKey]struct{}
+}
+
+// TemplateInfo describes a template and its progress
+type TemplateInfo struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Region
+
+	// Number of cells the template sets, and of those already right
+	Cells   int `json:"cells"`
+	Correct int `json:"correct"`
+
+	// Percentage of the cells already right
+	Completion float64 `json:"completion"`
+}
+
+// TemplateCell is a cell of the canvas that differs from its template
+type TemplateCell struct {
+	X       int         `json:"x"`
+	Y       int         `json:"y"`
+	Want    model.Color `json:"want"`
+	Current model.Color `json:"current"`
+}
+
+// templateMessage carries a new template to the other instances
+type templateMessage struct {
+	Type  string `json:"t"`
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Image []byte `json:"png"`
+}
+
+// templateRemovedMessage tells the other instances a template was removed
+type templateRemovedMessage struct {
+	Type string `json:"t"`
+	ID   uint   `json:"id"`
+}
+
+// NewTemplate maps an image onto the canvas with its top-left corner at
+// (x, y). Mostly transparent pixels are left out, the others become their
+// nearest paintable color.
+func NewTemplate(id uint, name string, x, y int, img image.Image) *Template {
+	bounds := img.Bounds()
+	t := &Template{
+		ID:     id,
+		Name:   name,
+		Region: Region{X1: x, Y1: y, X2: x + bounds.Dx(), Y2: y + bounds.Dy()},
+		target: quantizeImage(img),
+	}
+	for _, want := range t.target {
+		if want != noTarget {
+			t.total++
+		}
+	}
+	return t
+}
+
+// Image returns the target colors of the template, transparent outside it
+func (t *Template) Image() *image.NRGBA {
+	width, height := t.Region.X2-t.Region.X1, t.Region.Y2-t.Region.Y1
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i, want := range t.target {
+		if want != noTarget {
+			rgba := want.RGBA()
+			img.SetNRGBA(i%width, i/width, color.NRGBA{R: rgba.R, G: rgba.G, B: rgba.B, A: 0xFF})
+		}
+	}
+	return img
+}
+
+// EncodePNG encodes the template's image as a PNG
+func (t *Template) EncodePNG() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, t.Image()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Empty reports whether the template has no target cells
+func (t *Template) Empty() bool {
+	return t.total == 0
+}
+
+// want returns the target color of a cell of the canvas, reporting false if
+// the template doesn't cover it
+func (t *Template) want(x, y int) (model.Color, bool) {
+	if !t.Region.Contains(x, y) {
+		return 0, false
+	}
+	want := t.target[(y-t.Region.Y1)*(t.Region.X2-t.Region.X1)+x-t.Region.X1]
+	return want, want != noTarget
+}
+
+// check records whether a cell matches the template, given its current state
+func (t *Template) check(x, y int, want model.Color, state CellState) {
+	if templateColor(state) == want {
+		delete(t.wrong, cellKey{x, y})
+	} else {
+		t.wrong[cellKey{x, y}] = struct{}{}
+	}
+}
+
+// info describes the template and its progress
+func (t *Template) info() TemplateInfo {
+	correct := t.total - len(t.wrong)
+	completion := 100.0
+	if t.total > 0 {
+		completion = float64(correct) * 100 / float64(t.total)
+	}
+	return TemplateInfo{ID: t.ID, Name: t.Name, Region: t.Region, Cells: t.total, Correct: correct, Completion: completion}
+}
+
+// templateColor returns the color a cell shows, White while inactive
+func templateColor(state CellState) model.Color {
+	if !state.Active {
+		return model.White
+	}
+	return state.Color
+}
+
+// Templates returns the templates of the canvas with their progress, oldest first
+func (h *Hub) Templates() []TemplateInfo {
+	h.templatesMu.Lock()
+	defer h.templatesMu.Unlock()
+	infos := make([]TemplateInfo, len(h.templates))
+	for i, t := range h.templates {
+		infos[i] = t.info()
+	}
+	return infos
+}
+
+// Template returns a template's progress, reporting false if there is no such template
+func (h *Hub) Template(id uint) (TemplateInfo, bool) {
+	h.templatesMu.Lock()
+	defer h.templatesMu.Unlock()
+	t := h.findTemplate(id)
+	if t == nil {
+		return TemplateInfo{}, false
+	}
+	return t.info(), true
+}
+
+// TemplateImage returns a template's target image, reporting false if there
+// is no such template
+func (h *Hub) TemplateImage(id uint) (*image.NRGBA, bool) {
+	h.templatesMu.Lock()
+	defer h.templatesMu.Unlock()
+	t := h.findTemplate(id)
+	if t == nil {
+		return nil, false
+	}
+	return t.Image(), true
+}
+
+// TemplateDiff returns up to limit cells differing from a template, in row
+// order, and how many differ in total. It reports false if there is no such
+// template.
+func (h *Hub) TemplateDiff(id uint, limit int) ([]TemplateCell, int, bool) {
+	h.templatesMu.Lock()
+	defer h.templatesMu.Unlock()
+	t := h.findTemplate(id)
+	if t == nil {
+		return nil, 0, false
+	}
+	keys := make([]cellKey, 0, len(t.wrong))
+	for key := range t.wrong {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b cellKey) int {
+		return cmp.Or(cmp.Compare(a.y, b.y), cmp.Compare(a.x, b.x))
+	})
+	cells := make([]TemplateCell, 0, min(limit, len(keys)))
+	for _, key := range keys[:min(limit, len(keys))] {
+		want, _ := t.want(key.x, key.y)
+		cells = append(cells, TemplateCell{X: key.x, Y: key.y, Want: want, Current: templateColor(h.grid.GetCell(key.x, key.y))})
+	}
+	return cells, len(keys), true
+}
+
+// AddTemplate starts tracking a template, numbering it after the others if it
+// has no ID yet (when it wasn't persisted), and publishes it to the other
+// instances. It returns the template's progress.
+func (h *Hub) AddTemplate(t *Template) TemplateInfo {
+	h.templatesMu.Lock()
+	if t.ID == 0 {
+		for _, other := range h.templates {
+			t.ID = max(t.ID, other.ID)
+		}
+		t.ID++
+	}
+	h.addTemplate(t)
+	info := t.info()
+	h.templatesMu.Unlock()
+
+	slog.Info("Template added", "canvas", h.config.Canvas, "id", t.ID, "name", t.Name, "region", t.Region, "completion", info.Completion)
+	if h.config.Broker != nil {
+		h.publishTemplate(t)
+	}
+	return info
+}
+
+// RemoveTemplate stops tracking a template, reporting false if there is no
+// such template
+func (h *Hub) RemoveTemplate(id uint) bool {
+	if !h.removeTemplate(id) {
+		return false
+	}
+	slog.Info("Template removed", "canvas", h.config.Canvas, "id", id)
+	if h.config.Broker != nil {
+		message, _ := json.Marshal(templateRemovedMessage{Type: "template_removed", ID: id})
+		if err := h.config.Broker.Publish(message); err != nil {
+			slog.Error("Failed to publish template removal to broker", "err", err)
+		}
+	}
+	return true
+}
+
+// publishTemplate sends a template to the other instances
+func (h *Hub) publishTemplate(t *Template) {
+	image, err := t.EncodePNG()
+	if err != nil {
+		slog.Error("Failed to encode template", "id", t.ID, "err", err)
+		return
+	}
+	message, err := json.Marshal(templateMessage{Type: "template", ID: t.ID, Name: t.Name, X: t.Region.X1, Y: t.Region.Y1, Image: image})
+	if err != nil {
+		slog.Error("Failed to marshal template", "err", err)
+		return
+	}
+	if err := h.config.Broker.Publish(message); err != nil {
+		slog.Error("Failed to publish template to broker", "err", err)
+	}
+}
+
+// applyRemoteTemplate tracks a template added on another instance
+func (h *Hub) applyRemoteTemplate(message []byte) error {
+	var msg templateMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return err
+	}
+	img, err := png.Decode(bytes.NewReader(msg.Image))
+	if err != nil {
+		return fmt.Errorf("invalid template image: %w", err)
+	}
+	h.templatesMu.Lock()
+	defer h.templatesMu.Unlock()
+	h.addTemplate(NewTemplate(msg.ID, msg.Name, msg.X, msg.Y, img))
+	return nil
+}
+
+// addTemplate computes which cells differ from a template and tracks it,
+// replacing a template with the same ID. h.templatesMu must be held.
+func (h *Hub) addTemplate(t *Template) {
+	t.wrong = make(map[cellKey]struct{})
+	h.checkTemplate(t)
+	if i := slices.IndexFunc(h.templates, func(other *Template) bool { return other.ID == t.ID }); i >= 0 {
+		h.templates[i] = t
+		return
+	}
+	h.templates = append(h.templates, t)
+}
+
+// removeTemplate stops tracking a template, reporting false if there is no
+// such template
+func (h *Hub) removeTemplate(id uint) bool {
+	h.templatesMu.Lock()
+	defer h.templatesMu.Unlock()
+	i := slices.IndexFunc(h.templates, func(t *Template) bool { return t.ID == id })
+	if i < 0 {
+		return false
+	}
+	h.templates = slices.Delete(h.templates, i, i+1)
+	return true
+}
+
+// findTemplate returns the template with an ID (nil if there is none).
+// h.templatesMu must be held.
+func (h *Hub) findTemplate(id uint) *Template {
+	for _, t := range h.templates {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// checkTemplate compares every cell of a template with the grid.
+// h.templatesMu must be held.
+func (h *Hub) checkTemplate(t *Template) {
+	for y := t.Region.Y1; y < t.Region.Y2; y++ {
+		for x := t.Region.X1; x < t.Region.X2; x++ {
+			if want, ok := t.want(x, y); ok {
+				t.check(x, y, want, h.grid.GetCell(x, y))
+			}
+		}
+	}
+}
+
+// updateTemplates records cell changes in the templates covering them. The
+// cells are read back from the grid, so concurrent changes of a cell leave it
+// in its final state whichever order they are recorded in.
+func (h *Hub) updateTemplates(changed []model.Pixel) {
+	h.templatesMu.Lock()
+	defer h.templatesMu.Unlock()
+	if len(h.templates) == 0 {
+		return
+	}
+	for _, p := range changed {
+		for _, t := range h.templates {
+			if want, ok := t.want(p.X, p.Y); ok {
+				t.check(p.X, p.Y, want, h.grid.GetCell(p.X, p.Y))
+			}
+		}
+	}
+}
+
+// recheckTemplates compares every template with the grid again, after the
+// whole grid changed
+func (h *Hub) recheckTemplates() {
+	h.templatesMu.Lock()
+	defer h.templatesMu.Unlock()
+	for _, t := range h.templates {
+		h.checkTemplate(t)
+	}
+}
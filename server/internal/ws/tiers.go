@@ -0,0 +1,284 @@
+package ws
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultTier names the tier of actors matching no configured tier, which
+// the per-IP PlacementCooldown applies to
+const DefaultTier = "default"
+
+// Time after which an account unknown to the AccountDirectory is looked up again
+const unknownAccountTTL = time.Minute
+
+// Tier is a placement cooldown and quota applying to the authenticated actors
+// matching it, e.g. verified users painting faster
+type Tier struct {
+	Name string
+
+	// Roles the actor must have one of (empty matches any role), and the
+	// least age of its account (0 matches any account)
+	Roles         []string
+	MinAccountAge time.Duration
+
+	// Minimum delay between placements of the same actor (0 disables)
+	Cooldown time.Duration
+
+	// Placements allowed per QuotaWindow (0 for no quota)
+	Quota       int
+	QuotaWindow time.Duration
+}
+
+// AccountDirectory knows when the accounts of authenticated actors were created
+type AccountDirectory interface {
+	// AccountCreated returns the creation time of the account of a user,
+	// reporting false if it has no account
+	AccountCreated(userID string) (time.Time, bool)
+}
+
+// TierStatus is the tier of an actor and what is left of its quota
+type TierStatus struct {
+	Tier       string `json:"tier"`
+	CooldownMs int64  `json:"cooldown_ms"`
+
+	// Placements allowed per window (0 for no quota), left in the current
+	// window, and the time until the window resets
+	Quota     int   `json:"quota"`
+	Remaining int   `json:"remaining"`
+	ResetMs   int64 `json:"reset_ms"`
+}
+
+// TierMessage tells a client its tier when it connects, and its remaining
+// quota after each accepted placement while it has one
+type TierMessage struct {
+	Type string `json:"t"`
+	TierStatus
+}
+
+// tier is a configured tier with the placements of its actors
+type tier struct {
+	Tier
+	cooldown *Cooldown
+	quota    *Quota
+}
+
+// Tiers picks the tier of each actor, the first matching in order of
+// precedence, and tracks the cooldown and quota of the actors in each
+type Tiers struct {
+	tiers    []*tier
+	accounts AccountDirectory
+
+	// Whether any tier depends on the account age
+	byAge bool
+
+	// Creation times of the accounts looked up, and when accounts unknown to
+	// the directory were last looked up
+	created map[string]time.Time
+	unknown map[string]time.Time
+
+	mu sync.Mutex
+}
+
+// NewTiers creates Tiers for the configured tiers, looking accounts up in
+// accounts (nil matches no tier with a minimum account age)
+func NewTiers(tiers []Tier, accounts AccountDirectory) *Tiers {
+	t := &Tiers{accounts: accounts, created: make(map[string]time.Time), unknown: make(map[string]time.Time)}
+	for _, cfg := range tiers {
+		t.tiers = append(t.tiers, &tier{Tier: cfg, cooldown: NewCooldown(cfg.Cooldown), quota: NewQuota(cfg.Quota, cfg.QuotaWindow)})
+		t.byAge = t.byAge || cfg.MinAccountAge > 0
+	}
+	return t
+}
+
+// Enabled reports whether any tier is configured
+func (t *Tiers) Enabled() bool {
+	return len(t.tiers) > 0
+}
+
+// resolve returns the tier of an actor, or nil for the default tier.
+// Anonymous actors are always in the default tier.
+func (t *Tiers) resolve(actor, role string, anonymous bool) *tier {
+	if anonymous || len(t.tiers) == 0 {
+		return nil
+	}
+	var age time.Duration
+	if t.byAge {
+		if created, ok := t.accountCreated(actor); ok {
+			age = time.Since(created)
+		}
+	}
+	for _, tr := range t.tiers {
+		if len(tr.Roles) > 0 && !slices.Contains(tr.Roles, role) {
+			continue
+		}
+		if tr.MinAccountAge > 0 && age < tr.MinAccountAge {
+			continue
+		}
+		return tr
+	}
+	return nil
+}
+
+// accountCreated returns the creation time of the actor's account, caching
+// it since it never changes
+func (t *Tiers) accountCreated(actor string) (time.Time, bool) {
+	if t.accounts == nil {
+		return time.Time{}, false
+	}
+	t.mu.Lock()
+	created, known := t.created[actor]
+	looked, unknown := t.unknown[actor]
+	t.mu.Unlock()
+	if known {
+		return created, true
+	}
+	if unknown && time.Since(looked) < unknownAccountTTL {
+		return time.Time{}, false
+	}
+
+	// Look the account up without holding up the placements of other actors
+	created, known = t.accounts.AccountCreated(actor)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if known {
+		t.created[actor] = created
+		delete(t.unknown, actor)
+	} else {
+		t.unknown[actor] = time.Now()
+	}
+	return created, known
+}
+
+// Quota limits the placements of each actor to a number per fixed window
+// starting at its first placement
+type Quota struct {
+	// Placements allowed per window (0 disables the quota)
+	limit  int
+	window time.Duration
+
+	// Current window of each actor
+	windows map[string]*quotaWindow
+
+	// Time of the last sweep of expired windows
+	lastSweep time.Time
+
+	mu sync.Mutex
+}
+
+// quotaWindow counts the placements of an actor since the window started
+type quotaWindow struct {
+	start time.Time
+	used  int
+}
+
+// NewQuota creates a Quota allowing limit placements per window
+func NewQuota(limit int, window time.Duration) *Quota {
+	return &Quota{limit: limit, window: window, windows: make(map[string]*quotaWindow)}
+}
+
+// Take uses up a placement of the actor, returning false and the time until
+// its window resets if it has none left
+func (q *Quota) Take(actor string) (bool, time.Duration) {
+	if q.limit <= 0 {
+		return true, 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	w := q.current(actor, now)
+	if w.used >= q.limit {
+		return false, q.window - now.Sub(w.start)
+	}
+	w.used++
+
+	// Periodically drop expired windows to bound memory
+	if now.Sub(q.lastSweep) > time.Minute {
+		for key, w := range q.windows {
+			if now.Sub(w.start) >= q.window {
+				delete(q.windows, key)
+			}
+		}
+		q.lastSweep = now
+	}
+	return true, 0
+}
+
+// Remaining returns the placements the actor has left in its window, and the
+// time until the window resets (0 when it hasn't started)
+func (q *Quota) Remaining(actor string) (int, time.Duration) {
+	if q.limit <= 0 {
+		return 0, 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	w, ok := q.windows[actor]
+	now := time.Now()
+	if !ok || now.Sub(w.start) >= q.window {
+		return q.limit, 0
+	}
+	return q.limit - w.used, q.window - now.Sub(w.start)
+}
+
+// current returns the actor's window, starting a new one if it expired,
+// q.mu must be held
+func (q *Quota) current(actor string, now time.Time) *quotaWindow {
+	w, ok := q.windows[actor]
+	if !ok || now.Sub(w.start) >= q.window {
+		w = &quotaWindow{start: now}
+		q.windows[actor] = w
+	}
+	return w
+}
+
+// Tier returns the tier of an actor with the role, and what is left of its quota
+func (h *Hub) Tier(actor, role string, anonymous bool) TierStatus {
+	tr := h.tiers.resolve(actor, role, anonymous)
+	if tr == nil {
+		return TierStatus{Tier: DefaultTier, CooldownMs: h.config.PlacementCooldown.Milliseconds()}
+	}
+	status := TierStatus{Tier: tr.Name, CooldownMs: tr.Cooldown.Milliseconds(), Quota: tr.Quota}
+	if tr.Quota > 0 {
+		remaining, reset := tr.quota.Remaining(actor)
+		status.Remaining, status.ResetMs = remaining, reset.Milliseconds()
+	}
+	return status
+}
+
+// paceError consumes a placement under the cooldown and quota of the actor's
+// tier, returning the error rejecting it if the actor must wait, or nil if it
+// may place. Actors in the default tier are cooled down by IP.
+func (h *Hub) paceError(p Placement) *PlacementError {
+	tr := h.tiers.resolve(p.Actor, p.Role, p.Anonymous)
+	if tr == nil {
+		if ok, remaining := h.cooldown.Allow(p.IP); !ok {
+			return &PlacementError{Code: "cooldown", Message: "placing too fast, wait for the cooldown", RetryAfter: remaining}
+		}
+		return nil
+	}
+	if ok, remaining := tr.cooldown.Allow(p.Actor); !ok {
+		return &PlacementError{Code: "cooldown", Message: "placing too fast, wait for the cooldown", RetryAfter: remaining}
+	}
+	if ok, reset := tr.quota.Take(p.Actor); !ok {
+		return &PlacementError{Code: "quota_exceeded", Message: fmt.Sprintf("the %s tier allows %d placements per %s", tr.Name, tr.Quota, tr.QuotaWindow), RetryAfter: reset}
+	}
+	return nil
+}
+
+// tier returns the tier of the client's actor and what is left of its quota
+func (c *Client) tier() TierStatus {
+	return c.hub.Tier(c.actor(), c.role(), c.identity == nil)
+}
+
+// sendQuota tells the client what is left of its quota after a placement, if
+// its tier has one
+func (c *Client) sendQuota() {
+	if status := c.tier(); status.Quota > 0 {
+		if err := c.sendMessage(TierMessage{Type: "tier", TierStatus: status}); err != nil {
+			c.logger.Error("Failed to send tier message", "err", err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package ws
+
+import (
+	"context"
+	"errors"
+
+	"github.com/million_grids/server/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// canvasAttr labels the spans of a hub with its canvas
+func canvasAttr(h *Hub) trace.SpanStartEventOption {
+	return trace.WithAttributes(attribute.String("canvas", h.config.Canvas))
+}
+
+// traceBroadcast starts the span of a traced change's broadcast, from when it
+// was queued for the Run loop to its dispatch to the fan-out workers, so the
+// time it waited shows. Untraced messages get a span recording nothing.
+func (h *Hub) traceBroadcast(message *outbound) trace.Span {
+	if !message.span.IsValid() {
+		return trace.SpanFromContext(context.Background())
+	}
+	ctx := trace.ContextWithSpanContext(context.Background(), message.span)
+	_, span := tracing.Tracer().Start(ctx, "hub.broadcast", canvasAttr(h), trace.WithTimestamp(message.queued))
+	return span
+}
+
+// traceRejection records on a span why a placement was rejected: rejections
+// by validation or moderation are labeled with their code, other errors
+// mark the span failed
+func traceRejection(span trace.Span, err error) {
+	var rejected *PlacementError
+	switch {
+	case err == nil:
+	case errors.As(err, &rejected):
+		span.SetAttributes(attribute.String("rejected", rejected.Code))
+	default:
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
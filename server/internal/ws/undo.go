@@ -0,0 +1,209 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/million_grids/server/internal/model"
+)
+
+const (
+	// Number of placements remembered per actor
+	maxUndoDepth = 32
+
+	// Time a placement may be undone for
+	undoWindow = 10 * time.Minute
+)
+
+// UndoMessage is sent by a client to revert its most recent placement
+type UndoMessage struct {
+	Type string `json:"type"`
+}
+
+// undoEntry is a cell placed by an actor, with the state it replaced
+type undoEntry struct {
+	x, y   int
+	placed CellState
+	prior  CellState
+
+	// Team holding the cell before the placement
+	priorTeam string
+
+	at time.Time
+}
+
+// UndoHistory remembers the most recent placements of every actor so they
+// can revert them. Admin changes, drawings and decay aren't recorded.
+type UndoHistory struct {
+	// Placements per actor, oldest first
+	entries map[string][]undoEntry
+
+	// Time of the last sweep of expired placements
+	lastSweep time.Time
+
+	mu sync.Mutex
+}
+
+// NewUndoHistory creates an empty UndoHistory
+func NewUndoHistory() *UndoHistory {
+	return &UndoHistory{entries: make(map[string][]undoEntry)}
+}
+
+// Record remembers cells placed by actor, given the states they replaced
+func (u *UndoHistory) Record(actor string, placed []model.Pixel, prior []CellState, priorTeams []string) {
+	if len(placed) == 0 {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	now := time.Now()
+	entries := u.entries[actor]
+	for i, p := range placed {
+		entries = append(entries, undoEntry{
+			x:         p.X,
+			y:         p.Y,
+			placed:    CellState{Active: p.Active, Color: p.Color},
+			prior:     prior[i],
+			priorTeam: priorTeams[i],
+			at:        now,
+		})
+	}
+	if len(entries) > maxUndoDepth {
+		entries = append([]undoEntry(nil), entries[len(entries)-maxUndoDepth:]...)
+	}
+	u.entries[actor] = entries
+
+	// Periodically drop placements that can no longer be undone to bound memory
+	if now.Sub(u.lastSweep) > time.Minute {
+		for other, entries := range u.entries {
+			if now.Sub(entries[len(entries)-1].at) >= undoWindow {
+				delete(u.entries, other)
+			}
+		}
+		u.lastSweep = now
+	}
+}
+
+// Pop removes and returns the most recent placement of actor, reporting false
+// if it has none left to undo
+func (u *UndoHistory) Pop(actor string) (undoEntry, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	entries := u.entries[actor]
+	if len(entries) == 0 || time.Since(entries[len(entries)-1].at) >= undoWindow {
+		delete(u.entries, actor)
+		return undoEntry{}, false
+	}
+	entry := entries[len(entries)-1]
+	if len(entries) == 1 {
+		delete(u.entries, actor)
+	} else {
+		u.entries[actor] = entries[:len(entries)-1]
+	}
+	return entry, true
+}
+
+// Reset forgets every placement, e.g. once the canvas was cleared
+func (u *UndoHistory) Reset() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.entries = make(map[string][]undoEntry)
+}
+
+// placeCells applies cells placed by actor like SetCells, remembering them so
+// the actor can undo them and watching them for griefing
+func (h *Hub) placeCells(ctx context.Context, pixels []model.Pixel, actor string, moderator bool) ([]model.Pixel, error) {
+	h.placing.RLock()
+	defer h.placing.RUnlock()
+
+	var prior []CellState
+	changed, err := h.mutate(ctx, func() ([]model.Pixel, error) {
+		var changed []model.Pixel
+		var err error
+		changed, prior, err = h.grid.SwapCells(pixels, h.journal(ctx, actor))
+		return changed, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	h.recordPlacement(actor, changed, prior)
+	h.observeGrief(actor, moderator, changed, prior, false)
+	h.commitChanges(ctx, changed, actor)
+	return changed, nil
+}
+
+// recordPlacement remembers cells placed by actor for undo, before their
+// change is committed
+func (h *Hub) recordPlacement(actor string, changed []model.Pixel, prior []CellState) {
+	teams := make([]string, len(changed))
+	for i, p := range changed {
+		teams[i] = h.territory.Owner(p.X, p.Y)
+	}
+	h.undo.Record(actor, changed, prior, teams)
+}
+
+// Undo reverts the most recent placement of the placement's actor that still
+// stands, restoring the state the cell had before and broadcasting it like
+// any other change. Placements since overwritten by someone else are
+// dropped. It returns the restored cell; rejected undos return a
+// *PlacementError.
+func (h *Hub) Undo(ctx context.Context, p Placement) (model.Pixel, error) {
+	ctx = withReceipt(ctx, time.Now())
+	if rejected := h.frozenError(); rejected != nil {
+		return model.Pixel{}, rejected
+	}
+	if h.IsBanned(p.IP) {
+		return model.Pixel{}, &PlacementError{Code: "banned", Message: "you are banned from painting"}
+	}
+	if h.IsShadowBanned(p.IP, p.Actor) {
+		// Shadowed paints aren't recorded, and undoing earlier ones would show
+		return model.Pixel{}, &PlacementError{Code: "nothing_to_undo", Message: "no recent placement to undo"}
+	}
+	for {
+		entry, ok := h.undo.Pop(p.Actor)
+		if !ok {
+			return model.Pixel{}, &PlacementError{Code: "nothing_to_undo", Message: "no recent placement to undo"}
+		}
+		pixel := model.Pixel{X: entry.x, Y: entry.y, Active: entry.prior.Active, Color: entry.prior.Color, Team: entry.priorTeam}
+		if !p.Moderator {
+			if rejected := h.lockedError([]model.Pixel{pixel}); rejected != nil {
+				return model.Pixel{}, rejected
+			}
+			if rejected := h.claimedError([]model.Pixel{pixel}, p.Actor); rejected != nil {
+				return model.Pixel{}, rejected
+			}
+		}
+		loc := h.config.GeoIP.Lookup(p.IP)
+		pixel.Country, pixel.Region = loc.Country, loc.Region
+		restored := []model.Pixel{pixel}
+		journal := h.journal(ctx, p.Actor)
+		h.placing.RLock()
+		ok, err := h.grid.CompareAndSetCell(entry.x, entry.y, entry.placed, entry.prior, func([]model.Pixel) error {
+			return journal(restored)
+		})
+		if err != nil {
+			h.placing.RUnlock()
+			return model.Pixel{}, errWriteFailed
+		}
+		if !ok {
+			h.placing.RUnlock()
+			continue
+		}
+		h.commitChanges(ctx, restored, p.Actor)
+		h.placing.RUnlock()
+		return restored[0], nil
+	}
+}
+
+// handleUndo reverts the client's most recent placement, replying with the
+// reason if there is none left
+func (c *Client) handleUndo(ctx context.Context) {
+	_, err := c.hub.Undo(ctx, Placement{IP: c.ipAddress, Actor: c.actor(), Moderator: c.identity.Moderator()})
+	var rejected *PlacementError
+	if errors.As(err, &rejected) {
+		c.sendError(rejected.Code, rejected.Message)
+	}
+}
@@ -0,0 +1,65 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// messageError is an inbound message rejected by validation, reported to the
+// client as an ErrorMessage
+type messageError struct {
+	code    string
+	message string
+}
+
+// decodeStrict unmarshals a JSON message into v, rejecting unknown fields,
+// values of the wrong type and anything after the message
+func decodeStrict(data []byte, v any) *messageError {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return jsonError(err)
+	}
+	if dec.More() {
+		return &messageError{"invalid_json", "unexpected data after the message"}
+	}
+	return nil
+}
+
+// jsonError describes a decoding error in terms of the message's fields
+func jsonError(err error) *messageError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		if typeErr.Field == "" {
+			return &messageError{"invalid_json", "message must be a JSON object"}
+		}
+		return &messageError{"invalid_field", fmt.Sprintf("field %q must be %s", typeErr.Field, jsonKind(typeErr.Type))}
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return &messageError{"unknown_field", "unknown field " + field}
+	}
+	return &messageError{"invalid_json", "message is not valid JSON"}
+}
+
+// jsonKind names the JSON type expected for a Go type
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
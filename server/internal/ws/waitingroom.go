@@ -0,0 +1,335 @@
+package ws
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Time between the position updates sent to the waiting clients, and
+// between checks for expired tickets
+const waitingRoomInterval = time.Second
+
+// QueueMessage tells a waiting client its position in the waiting room, 1
+// being the next admitted, and how many clients are waiting
+type QueueMessage struct {
+	Type     string `json:"t"`
+	Position int    `json:"position"`
+	Waiting  int    `json:"waiting"`
+}
+
+// AdmittedMessage tells a waiting client a connection slot is reserved for
+// it: it connects to /ws with ?ticket= within ExpiresIn seconds to use it
+type AdmittedMessage struct {
+	Type      string `json:"t"`
+	Ticket    string `json:"ticket"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+// WaitingRoomStats counts the clients of the waiting room
+type WaitingRoomStats struct {
+	// Clients waiting, and admitted clients yet to connect
+	Waiting int `json:"waiting"`
+	Tickets int `json:"tickets"`
+
+	// Clients admitted, and tickets expired unused, since the server started
+	Admitted int64 `json:"admitted"`
+	Expired  int64 `json:"expired"`
+}
+
+// WaitingRoom queues the clients refused for lack of a connection slot on a
+// lightweight WebSocket, telling them their position, and admits them in
+// order as slots free up. An admitted client gets a ticket for a slot
+// reserved in its name, and new connections without one are refused while
+// clients are waiting, so nobody jumps the queue.
+type WaitingRoom struct {
+	limit *ConnLimit
+
+	// Maximum clients waiting overall and from the same IP (0 for no limit)
+	maxWaiting int
+	maxPerIP   int
+
+	// Time an admitted client has to connect
+	ticketTTL time.Duration
+
+	mu      sync.Mutex
+	queue   []*waiter
+	perIP   map[string]int
+	tickets map[string]ticket
+	stats   WaitingRoomStats
+
+	// Signaled when a client joins, for it to be admitted at once if a slot is free
+	joined chan struct{}
+}
+
+// waiter is a client in the waiting room
+type waiter struct {
+	conn *websocket.Conn
+	ip   string
+
+	// Position and number of clients waiting last sent to the client, and
+	// the messages queued for it
+	position int
+	waiting  int
+	send     chan []byte
+	admitted chan []byte
+}
+
+// ticket is a connection slot reserved for an admitted client
+type ticket struct {
+	ip      string
+	expires time.Time
+}
+
+// NewWaitingRoom creates a waiting room admitting clients as limit frees
+// connection slots, holding up to maxWaiting clients and maxPerIP from the
+// same IP, whose tickets are valid for ticketTTL
+func NewWaitingRoom(limit *ConnLimit, maxWaiting, maxPerIP int, ticketTTL time.Duration) *WaitingRoom {
+	return &WaitingRoom{
+		limit:      limit,
+		maxWaiting: maxWaiting,
+		maxPerIP:   maxPerIP,
+		ticketTTL:  ticketTTL,
+		perIP:      make(map[string]int),
+		tickets:    make(map[string]ticket),
+		joined:     make(chan struct{}, 1),
+	}
+}
+
+// Acquire reserves a connection slot for the IP, see ConnLimit.Acquire. A
+// valid ticket of the IP uses the slot reserved when it was admitted, and
+// ErrServerFull is returned without one while clients are waiting.
+func (r *WaitingRoom) Acquire(ticket, ip string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t, ok := r.tickets[ticket]; ok && t.ip == ip {
+		delete(r.tickets, ticket)
+		return nil
+	}
+	if len(r.queue) > 0 {
+		return ErrServerFull
+	}
+	return r.limit.Acquire(ip)
+}
+
+// Full reports whether the waiting room can't take another client from the IP
+func (r *WaitingRoom) Full(ip string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.full(ip)
+}
+
+// full is Full with r.mu held
+func (r *WaitingRoom) full(ip string) bool {
+	return (r.maxWaiting > 0 && len(r.queue) >= r.maxWaiting) || (r.maxPerIP > 0 && r.perIP[ip] >= r.maxPerIP)
+}
+
+// Wait queues the client of an upgraded connection until it is admitted or
+// disconnects, closing the connection if the waiting room is full
+func (r *WaitingRoom) Wait(conn *websocket.Conn, ip string) {
+	w := &waiter{
+		conn:     conn,
+		ip:       ip,
+		send:     make(chan []byte, 1),
+		admitted: make(chan []byte, 1),
+	}
+
+	r.mu.Lock()
+	if r.full(ip) {
+		r.mu.Unlock()
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "waiting room full"),
+			time.Now().Add(writeWait))
+		conn.Close()
+		return
+	}
+	r.queue = append(r.queue, w)
+	r.perIP[ip]++
+	w.position, w.waiting = len(r.queue), len(r.queue)
+	w.send <- queueMessage(w.position, w.waiting)
+	r.mu.Unlock()
+	select {
+	case r.joined <- struct{}{}:
+	default:
+	}
+
+	slog.Debug("Client waiting for a connection slot", "ip", ip, "position", w.position)
+	go w.writePump()
+	go r.readPump(w)
+}
+
+// Run admits waiting clients as connection slots free up, sends them their
+// positions and releases the slots of expired tickets
+func (r *WaitingRoom) Run() {
+	ticker := time.NewTicker(waitingRoomInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.limit.freed:
+		case <-r.joined:
+		case <-ticker.C:
+			r.expire(time.Now())
+		}
+		r.admit()
+	}
+}
+
+// admit gives tickets to the clients at the front of the queue while slots
+// are free, then sends every other client its position and the number of
+// clients waiting if they changed. Clients over their IP's connection limit
+// keep their place.
+func (r *WaitingRoom) admit() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := 0; i < len(r.queue); {
+		w := r.queue[i]
+		err := r.limit.Acquire(w.ip)
+		if errors.Is(err, ErrServerFull) {
+			break
+		}
+		if err != nil {
+			i++
+			continue
+		}
+		id, err := newTicket()
+		if err != nil {
+			r.limit.Release(w.ip)
+			slog.Error("Failed to create waiting room ticket", "err", err)
+			break
+		}
+		r.tickets[id] = ticket{ip: w.ip, expires: time.Now().Add(r.ticketTTL)}
+		r.remove(w)
+		r.stats.Admitted++
+		message, _ := json.Marshal(AdmittedMessage{Type: "admitted", Ticket: id, ExpiresIn: int(r.ticketTTL.Seconds())})
+		w.admitted <- message
+	}
+
+	for i, w := range r.queue {
+		if w.position == i+1 && w.waiting == len(r.queue) {
+			continue
+		}
+		select {
+		case w.send <- queueMessage(i+1, len(r.queue)):
+			w.position, w.waiting = i+1, len(r.queue)
+		default:
+			// Still sending the last position, retried on the next pass
+		}
+	}
+}
+
+// expire releases the slots of the tickets not used in time
+func (r *WaitingRoom) expire(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, t := range r.tickets {
+		if now.After(t.expires) {
+			delete(r.tickets, id)
+			r.limit.Release(t.ip)
+			r.stats.Expired++
+		}
+	}
+}
+
+// remove takes a client out of the queue, r.mu must be held
+func (r *WaitingRoom) remove(w *waiter) {
+	i := slices.Index(r.queue, w)
+	if i < 0 {
+		return
+	}
+	r.queue = slices.Delete(r.queue, i, i+1)
+	if r.perIP[w.ip]--; r.perIP[w.ip] <= 0 {
+		delete(r.perIP, w.ip)
+	}
+}
+
+// Stats returns the counts of the waiting room
+func (r *WaitingRoom) Stats() WaitingRoomStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.stats
+	stats.Waiting = len(r.queue)
+	stats.Tickets = len(r.tickets)
+	return stats
+}
+
+// readPump discards the messages of a waiting client until it disconnects,
+// taking it out of the queue then
+func (r *WaitingRoom) readPump(w *waiter) {
+	defer func() {
+		r.mu.Lock()
+		r.remove(w)
+		r.mu.Unlock()
+		w.conn.Close()
+	}()
+
+	w.conn.SetReadLimit(maxMessageSize)
+	w.conn.SetReadDeadline(time.Now().Add(pongWait))
+	w.conn.SetPongHandler(func(string) error {
+		w.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+	for {
+		if _, _, err := w.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// writePump sends a waiting client its positions until it is admitted, then
+// its ticket, and closes the connection
+func (w *waiter) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		w.conn.Close()
+	}()
+
+	for {
+		select {
+		case message := <-w.send:
+			w.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := w.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case message := <-w.admitted:
+			w.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := w.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+			w.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, "admitted"),
+				time.Now().Add(writeWait))
+			return
+		case <-ticker.C:
+			if err := w.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// queueMessage encodes the position of a waiting client
+func queueMessage(position, waiting int) []byte {
+	message, _ := json.Marshal(QueueMessage{Type: "queue", Position: position, Waiting: waiting})
+	return message
+}
+
+// newTicket returns a random ticket identifier
+func newTicket() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
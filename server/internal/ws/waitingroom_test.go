@@ -0,0 +1,97 @@
+package ws
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWaitingRoomAdmit(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		maxPerIP   int
+		maxTotal   int
+		open       []string // IPs holding a slot already
+		queue      []string // IPs of the waiting clients, in order
+		admitted   []string
+		waiting    []string
+		wantTicket int
+	}{
+		{"free slots admit in order", 0, 3, nil, []string{"a", "b", "c", "d"}, []string{"a", "b", "c"}, []string{"d"}, 3},
+		{"server full", 0, 2, []string{"x", "y"}, []string{"a", "b"}, nil, []string{"a", "b"}, 0},
+		{"client over its IP limit keeps its place", 1, 3, []string{"a"}, []string{"a", "b", "a"}, []string{"b"}, []string{"a", "a"}, 1},
+		{"same IP admitted up to its limit", 2, 0, nil, []string{"a", "a", "a", "b"}, []string{"a", "a", "b"}, []string{"a"}, 3},
+		{"empty queue", 0, 1, nil, nil, nil, nil, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			limit := NewConnLimit(tc.maxPerIP, tc.maxTotal)
+			for _, ip := range tc.open {
+				if err := limit.Acquire(ip); err != nil {
+					t.Fatal(err)
+				}
+			}
+			r := NewWaitingRoom(limit, 0, 0, time.Minute)
+			waiters := make([]*waiter, len(tc.queue))
+			for i, ip := range tc.queue {
+				waiters[i] = &waiter{ip: ip, position: i + 1, waiting: len(tc.queue), send: make(chan []byte, 1), admitted: make(chan []byte, 1)}
+				r.queue = append(r.queue, waiters[i])
+				r.perIP[ip]++
+			}
+
+			r.admit()
+
+			var admitted []string
+			for _, w := range waiters {
+				if len(w.admitted) > 0 {
+					admitted = append(admitted, w.ip)
+				}
+			}
+			var waiting []string
+			for i, w := range r.queue {
+				waiting = append(waiting, w.ip)
+				if w.position != i+1 || w.waiting != len(r.queue) {
+					t.Errorf("waiter %d told position %d of %d, want %d of %d", i, w.position, w.waiting, i+1, len(r.queue))
+				}
+			}
+			if !slices.Equal(admitted, tc.admitted) || !slices.Equal(waiting, tc.waiting) {
+				t.Errorf("admitted %v leaving %v waiting, want %v leaving %v", admitted, waiting, tc.admitted, tc.waiting)
+			}
+			if stats := r.Stats(); stats.Tickets != tc.wantTicket || stats.Admitted != int64(len(tc.admitted)) {
+				t.Errorf("stats %+v, want %d tickets", stats, tc.wantTicket)
+			}
+		})
+	}
+}
+
+func TestWaitingRoomTickets(t *testing.T) {
+	limit := NewConnLimit(0, 1)
+	r := NewWaitingRoom(limit, 0, 0, time.Minute)
+	w := &waiter{ip: "a", send: make(chan []byte, 1), admitted: make(chan []byte, 1)}
+	r.queue = append(r.queue, w)
+	r.perIP["a"]++
+	r.admit()
+
+	var ticket string
+	for id := range r.tickets {
+		ticket = id
+	}
+	if err := r.Acquire(ticket, "b"); err == nil {
+		t.Error("ticket accepted from another IP")
+	}
+	if err := r.Acquire(ticket, "a"); err != nil {
+		t.Errorf("ticket refused: %v", err)
+	}
+	if err := r.Acquire(ticket, "a"); err == nil {
+		t.Error("ticket accepted twice")
+	}
+
+	// An unused ticket releases its slot once expired
+	limit.Release("a")
+	r.queue = append(r.queue, &waiter{ip: "a", send: make(chan []byte, 1), admitted: make(chan []byte, 1)})
+	r.perIP["a"]++
+	r.admit()
+	r.expire(time.Now().Add(2 * time.Minute))
+	if err := limit.Acquire("c"); err != nil {
+		t.Errorf("slot of an expired ticket not released: %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+-- Upgrade pixels tables created before color and attribution were stored
+USE million_grids;
+
+ALTER TABLE pixels
+    ADD COLUMN color VARCHAR(7) NOT NULL DEFAULT '#FFFFFF' AFTER active,
+    ADD COLUMN created_by VARCHAR(45) NULL AFTER color,
+    ADD COLUMN modify_at DATETIME NULL AFTER created_by,
+    ADD COLUMN modify_by VARCHAR(45) NULL AFTER modify_at;
+
+-- Index for finding cells by creator
+CREATE INDEX idx_pixels_created_by ON pixels(created_by);
+
+-- Index for finding cells by modifier
+CREATE INDEX idx_pixels_modify_by ON pixels(modify_by);
@@ -0,0 +1,9 @@
+-- Widen attribution columns to hold authenticated user IDs as well as IP addresses
+USE million_grids;
+
+ALTER TABLE pixels
+    MODIFY COLUMN created_by VARCHAR(64) NULL,
+    MODIFY COLUMN modify_by VARCHAR(64) NULL;
+
+ALTER TABLE pixel_history
+    MODIFY COLUMN actor VARCHAR(64) NULL;
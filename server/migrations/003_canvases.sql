@@ -0,0 +1,12 @@
+-- Partition pixels and history by canvas so one server can host several grids
+USE million_grids;
+
+ALTER TABLE pixels
+    ADD COLUMN canvas VARCHAR(64) NOT NULL DEFAULT 'default' FIRST,
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (canvas, x, y);
+
+ALTER TABLE pixel_history
+    ADD COLUMN canvas VARCHAR(64) NOT NULL DEFAULT 'default' AFTER id,
+    DROP INDEX idx_pixel_history_cell,
+    ADD INDEX idx_pixel_history_cell (canvas, x, y);
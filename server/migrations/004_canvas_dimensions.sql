@@ -0,0 +1,10 @@
+-- Store the dimensions of each canvas
+USE million_grids;
+
+CREATE TABLE IF NOT EXISTS canvases (
+    name VARCHAR(64) NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,10 @@
+-- Store colors as 24-bit integers (0xRRGGBB) instead of "#RRGGBB" strings
+USE million_grids;
+
+ALTER TABLE pixels ADD COLUMN color_rgb INT UNSIGNED NOT NULL DEFAULT 16777215 AFTER color;
+UPDATE pixels SET color_rgb = CONV(SUBSTRING(color, 2), 16, 10);
+ALTER TABLE pixels DROP COLUMN color, RENAME COLUMN color_rgb TO color;
+
+ALTER TABLE pixel_history ADD COLUMN color_rgb INT UNSIGNED NOT NULL DEFAULT 16777215 AFTER color;
+UPDATE pixel_history SET color_rgb = CONV(SUBSTRING(color, 2), 16, 10);
+ALTER TABLE pixel_history DROP COLUMN color, RENAME COLUMN color_rgb TO color;
@@ -0,0 +1,9 @@
+-- Palette versions set through the admin API
+USE million_grids;
+
+CREATE TABLE IF NOT EXISTS palettes (
+    version INT NOT NULL,
+    colors TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -7,14 +7,15 @@ USE million_grids;
 
 -- Create the cells table (stores active cells only for efficiency)
 CREATE TABLE IF NOT EXISTS pixels (
+    canvas VARCHAR(64) NOT NULL DEFAULT 'default',
     x INT NOT NULL,
     y INT NOT NULL,
     active TINYINT(1) NOT NULL DEFAULT 0,
-    color VARCHAR(7) NOT NULL DEFAULT '#FFFFFF',
-    created_by VARCHAR(45) NULL,
+    color INT UNSIGNED NOT NULL DEFAULT 16777215, -- 0xRRGGBB
+    created_by VARCHAR(64) NULL,
     modify_at DATETIME NULL,
-    modify_by VARCHAR(45) NULL,
-    PRIMARY KEY (x, y)
+    modify_by VARCHAR(64) NULL,
+    PRIMARY KEY (canvas, x, y)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
 -- Index for finding all active cells quickly
@@ -25,3 +26,50 @@ CREATE INDEX idx_pixels_created_by ON pixels(created_by);
 
 -- Index for finding cells by modifier
 CREATE INDEX idx_pixels_modify_by ON pixels(modify_by);
+
+-- Append-only log of every cell change
+CREATE TABLE IF NOT EXISTS pixel_history (
+    id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    canvas VARCHAR(64) NOT NULL DEFAULT 'default',
+    x INT NOT NULL,
+    y INT NOT NULL,
+    active TINYINT(1) NOT NULL,
+    color INT UNSIGNED NOT NULL,
+    actor VARCHAR(64) NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Index for looking up the history of a single cell
+CREATE INDEX idx_pixel_history_cell ON pixel_history(canvas, x, y);
+
+-- Index for finding changes by actor
+CREATE INDEX idx_pixel_history_actor ON pixel_history(actor);
+
+-- Index for time range queries
+CREATE INDEX idx_pixel_history_created_at ON pixel_history(created_at);
+
+-- Banned IP addresses and CIDR ranges
+CREATE TABLE IF NOT EXISTS bans (
+    target VARCHAR(64) NOT NULL,
+    reason VARCHAR(255) NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (target)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Named canvases and their dimensions
+CREATE TABLE IF NOT EXISTS canvases (
+    name VARCHAR(64) NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Palette versions set through the admin API (colors are comma-separated #RRGGBB)
+CREATE TABLE IF NOT EXISTS palettes (
+    version INT NOT NULL,
+    colors TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,61 @@
+-- PostgreSQL schema (create the database first: CREATE DATABASE million_grids;)
+
+-- Create the cells table (stores active cells only for efficiency)
+CREATE TABLE IF NOT EXISTS pixels (
+    canvas VARCHAR(64) NOT NULL DEFAULT 'default',
+    x INTEGER NOT NULL,
+    y INTEGER NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT FALSE,
+    color INTEGER NOT NULL DEFAULT 16777215, -- 0xRRGGBB
+    created_by VARCHAR(64) NULL,
+    modify_at TIMESTAMPTZ NULL,
+    modify_by VARCHAR(64) NULL,
+    PRIMARY KEY (canvas, x, y)
+);
+
+-- Index for finding all active cells quickly
+CREATE INDEX IF NOT EXISTS idx_pixels_active ON pixels(active) WHERE active;
+
+-- Index for finding cells by creator
+CREATE INDEX IF NOT EXISTS idx_pixels_created_by ON pixels(created_by);
+
+-- Index for finding cells by modifier
+CREATE INDEX IF NOT EXISTS idx_pixels_modify_by ON pixels(modify_by);
+
+-- Append-only log of every cell change
+CREATE TABLE IF NOT EXISTS pixel_history (
+    id BIGSERIAL PRIMARY KEY,
+    canvas VARCHAR(64) NOT NULL DEFAULT 'default',
+    x INTEGER NOT NULL,
+    y INTEGER NOT NULL,
+    active BOOLEAN NOT NULL,
+    color INTEGER NOT NULL,
+    actor VARCHAR(64) NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_pixel_history_cell ON pixel_history(canvas, x, y);
+CREATE INDEX IF NOT EXISTS idx_pixel_history_actor ON pixel_history(actor);
+CREATE INDEX IF NOT EXISTS idx_pixel_history_created_at ON pixel_history(created_at);
+
+-- Banned IP addresses and CIDR ranges
+CREATE TABLE IF NOT EXISTS bans (
+    target VARCHAR(64) PRIMARY KEY,
+    reason VARCHAR(255) NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+-- Named canvases and their dimensions
+CREATE TABLE IF NOT EXISTS canvases (
+    name VARCHAR(64) PRIMARY KEY,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+-- Palette versions set through the admin API (colors are comma-separated #RRGGBB)
+CREATE TABLE IF NOT EXISTS palettes (
+    version INTEGER PRIMARY KEY,
+    colors TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);